@@ -0,0 +1,128 @@
+// Package bigfile crunches text files too big for memory line by line:
+// parallel processing (Process), external sorting (Sort), deduplication
+// (Dedupe) and sampling (Sample). Lines end with "\n", a trailing "\r" is
+// dropped, and the output always ends lines with "\n".
+package bigfile
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// ErrLineTooLong is returned for lines longer than WithMaxLineSize
+var ErrLineTooLong = bufio.ErrTooLong
+
+type config struct {
+	workers     int
+	chunkSize   int
+	ordered     bool
+	maxLineSize int
+	memory      int
+	tempDir     string
+	less        func(a, b []byte) bool
+	unique      bool
+}
+
+type Option = opt.Option[config]
+
+// WithWorkers is the number of goroutines of Process, GOMAXPROCS by default
+func WithWorkers(n int) Option {
+	return func(c *config) {
+		c.workers = n
+	}
+}
+
+// WithChunkSize is the number of bytes of lines Process hands to a worker at
+// once, 1MB by default. At most two chunks per worker are in memory.
+func WithChunkSize(n int) Option {
+	return func(c *config) {
+		c.chunkSize = n
+	}
+}
+
+// WithOrder makes Process write the output in the order of the input,
+// otherwise chunks are written as soon as they are done
+func WithOrder() Option {
+	return func(c *config) {
+		c.ordered = true
+	}
+}
+
+// WithMaxLineSize bounds the length of a line, 1MB by default
+func WithMaxLineSize(n int) Option {
+	return func(c *config) {
+		c.maxLineSize = n
+	}
+}
+
+// WithMemory is the number of bytes of lines Sort keeps in memory before
+// spilling a sorted run to a temporary file, 64MB by default
+func WithMemory(n int) Option {
+	return func(c *config) {
+		c.memory = n
+	}
+}
+
+// WithTempDir is where Sort spills its runs, os.TempDir() by default
+func WithTempDir(dir string) Option {
+	return func(c *config) {
+		c.tempDir = dir
+	}
+}
+
+// WithLess orders the lines of Sort, bytewise by default. Lines that are
+// neither less than the other are equal for WithUnique.
+func WithLess(less func(a, b []byte) bool) Option {
+	return func(c *config) {
+		c.less = less
+	}
+}
+
+// WithUnique makes Sort write equal lines once, like sort -u
+func WithUnique() Option {
+	return func(c *config) {
+		c.unique = true
+	}
+}
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.workers <= 0 || c.chunkSize <= 0 || c.maxLineSize <= 0 || c.memory <= 0 {
+			return errors.New("bigfile: workers and sizes must be positive")
+		}
+		return nil
+	},
+}
+
+func newConfig(opts []Option) (config, error) {
+	cfg := config{
+		workers:     runtime.GOMAXPROCS(0),
+		chunkSize:   1 << 20,
+		maxLineSize: 1 << 20,
+		memory:      64 << 20,
+		tempDir:     os.TempDir(),
+	}
+	return cfg, opt.Build(&cfg, opts, checks...)
+}
+
+func newScanner(r io.Reader, cfg config) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	initial := 64 << 10
+	if initial > cfg.maxLineSize {
+		initial = cfg.maxLineSize
+	}
+	scanner.Buffer(make([]byte, initial), cfg.maxLineSize)
+	return scanner
+}
+
+func writeLine(w *bufio.Writer, line []byte) error {
+	if _, err := w.Write(line); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
@@ -0,0 +1,143 @@
+package bigfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func numbered(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	return b.String()
+}
+
+func TestProcess(t *testing.T) {
+	double := func(line []byte) ([]byte, bool, error) {
+		n, err := strconv.Atoi(string(line))
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(strconv.Itoa(2 * n)), n%3 != 0, nil
+	}
+	var want []string
+	for i := 0; i < 1000; i++ {
+		if i%3 != 0 {
+			want = append(want, strconv.Itoa(2*i))
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"ordered", []Option{WithWorkers(4), WithChunkSize(64), WithOrder()}},
+		{"unordered", []Option{WithWorkers(4), WithChunkSize(64)}},
+		{"single worker", []Option{WithWorkers(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stats, err := Process(context.Background(), strings.NewReader(numbered(1000)), &out, double, tt.opts...)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if stats.In != 1000 || stats.Out != int64(len(want)) {
+				t.Errorf("Process() stats got = %+v", stats)
+			}
+			if tt.name == "unordered" {
+				sort.Slice(got, func(i, j int) bool { a, _ := strconv.Atoi(got[i]); b, _ := strconv.Atoi(got[j]); return a < b })
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Process() got = %v..., want %v...", got[:5], want[:5])
+			}
+		})
+	}
+
+	_, err := Process(context.Background(), strings.NewReader(numbered(100)+"x\n"+numbered(100)), &bytes.Buffer{}, double, WithChunkSize(16))
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("Process() error got = %v, want %v", err, strconv.ErrSyntax)
+	}
+	_, err = Process(context.Background(), strings.NewReader(strings.Repeat("x", 100)), &bytes.Buffer{}, double, WithMaxLineSize(10))
+	if !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("Process() error got = %v, want %v", err, ErrLineTooLong)
+	}
+}
+
+func TestSort(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(numbered(500), "\n"), "\n")
+	input := append(append([]string{}, lines...), lines[:100]...)
+	rand.New(rand.NewSource(1)).Shuffle(len(input), func(i, j int) { input[i], input[j] = input[j], input[i] })
+	text := strings.Join(input, "\r\n") + "\r\n"
+
+	sorted := append([]string{}, input...)
+	sort.Strings(sorted)
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"in memory", nil, sorted},
+		{"external", []Option{WithMemory(100)}, sorted},
+		{"unique", []Option{WithMemory(100), WithUnique()}, func() []string { s := append([]string{}, lines...); sort.Strings(s); return s }()},
+		{"numeric", []Option{WithMemory(100), WithUnique(), WithLess(func(a, b []byte) bool {
+			x, _ := strconv.Atoi(string(a))
+			y, _ := strconv.Atoi(string(b))
+			return x < y
+		})}, lines},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var out bytes.Buffer
+			if err := Sort(context.Background(), strings.NewReader(text), &out, append(tt.opts, WithTempDir(dir))...); err != nil {
+				t.Fatalf("Sort() error = %v", err)
+			}
+			if got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sort() got = %v..., want %v...", got[:5], tt.want[:5])
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("Sort() left %v temporary files", len(entries))
+			}
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	var out bytes.Buffer
+	stats, err := Dedupe(context.Background(), strings.NewReader("b\na\nb\n\nc\na\n\n"), &out)
+	if err != nil || out.String() != "b\na\n\nc\n" || stats.In != 7 || stats.Out != 4 {
+		t.Errorf("Dedupe() got = %q %+v %v", out.String(), stats, err)
+	}
+}
+
+func TestSample(t *testing.T) {
+	got, err := Sample(context.Background(), strings.NewReader(numbered(1000)), 10, rand.New(rand.NewSource(7)))
+	if err != nil || len(got) != 10 {
+		t.Fatalf("Sample() got = %v %v, want 10 lines", got, err)
+	}
+	for i := 1; i < len(got); i++ {
+		a, _ := strconv.Atoi(got[i-1])
+		b, _ := strconv.Atoi(got[i])
+		if a >= b {
+			t.Errorf("Sample() got = %v, want input order", got)
+		}
+	}
+	if got, _ := Sample(context.Background(), strings.NewReader("a\nb\n"), 5, nil); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Sample() short input got = %v", got)
+	}
+	if _, err := Sample(context.Background(), strings.NewReader(""), 0, nil); err == nil {
+		t.Errorf("Sample() zero size error = nil")
+	}
+}
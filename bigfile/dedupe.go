@@ -0,0 +1,98 @@
+package bigfile
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"hash/maphash"
+	"io"
+	"math/rand"
+	"sort"
+)
+
+// Dedupe writes the first occurrence of every line of r to w, keeping the
+// order. It remembers a 64-bit hash per distinct line instead of the line, so
+// memory grows with 8 bytes per distinct line; two distinct lines share a
+// hash with a chance of about n²/2⁶⁵ for n distinct lines. Use Sort with
+// WithUnique for exact results in bounded memory when the order can change.
+func Dedupe(ctx context.Context, r io.Reader, w io.Writer, opts ...Option) (Stats, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+	seed := maphash.MakeSeed()
+	seen := make(map[uint64]struct{})
+	scanner := newScanner(r, cfg)
+	writer := bufio.NewWriter(w)
+	var stats Stats
+	for scanner.Scan() {
+		if stats.In++; stats.In%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+		}
+		var h maphash.Hash
+		h.SetSeed(seed)
+		_, _ = h.Write(scanner.Bytes())
+		sum := h.Sum64()
+		if _, ok := seen[sum]; ok {
+			continue
+		}
+		seen[sum] = struct{}{}
+		if err := writeLine(writer, scanner.Bytes()); err != nil {
+			return stats, err
+		}
+		stats.Out++
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+	return stats, writer.Flush()
+}
+
+// Sample returns k lines of r picked uniformly at random in one pass
+// (reservoir sampling), in the order they appear. Fewer lines are returned
+// when r has less than k. A nil rnd uses a randomly seeded source.
+func Sample(ctx context.Context, r io.Reader, k int, rnd *rand.Rand, opts ...Option) ([]string, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if k <= 0 {
+		return nil, errors.New("bigfile: sample size must be positive")
+	}
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(rand.Int63()))
+	}
+	type sampled struct {
+		line  string
+		index int64
+	}
+	reservoir := make([]sampled, 0, k)
+	scanner := newScanner(r, cfg)
+	var n int64
+	for ; scanner.Scan(); n++ {
+		if n%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if len(reservoir) < k {
+			reservoir = append(reservoir, sampled{scanner.Text(), n})
+			continue
+		}
+		if j := rnd.Int63n(n + 1); j < int64(k) {
+			reservoir[j] = sampled{scanner.Text(), n}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// restore the input order
+	sort.Slice(reservoir, func(i, j int) bool { return reservoir[i].index < reservoir[j].index })
+	lines := make([]string, len(reservoir))
+	for i, s := range reservoir {
+		lines[i] = s.line
+	}
+	return lines, nil
+}
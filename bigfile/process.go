@@ -0,0 +1,150 @@
+package bigfile
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync"
+)
+
+// Mapper turns a line into an output line, keep false drops it. The line is
+// only valid during the call.
+type Mapper func(line []byte) (out []byte, keep bool, err error)
+
+// Stats counts the lines of a Process run
+type Stats struct {
+	In  int64
+	Out int64
+}
+
+type chunk struct {
+	seq   int
+	lines [][]byte
+	out   []byte
+	kept  int64
+	err   error
+}
+
+// Process applies fn to every line of r in parallel and writes the kept lines
+// to w. The first error of fn, r or w stops the run and is returned.
+func Process(ctx context.Context, r io.Reader, w io.Writer, fn Mapper, opts ...Option) (Stats, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return Stats{}, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// every chunk holds a slot from reading until it is written
+	slots := make(chan struct{}, 2*cfg.workers)
+	jobs := make(chan *chunk, cfg.workers)
+	results := make(chan *chunk, cfg.workers)
+	var stats Stats
+	var readErr error
+	go func() {
+		defer close(jobs)
+		readErr = readChunks(ctx, r, cfg, slots, jobs, &stats.In)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				c.out, c.kept, c.err = mapChunk(c.lines, fn)
+				c.lines = nil
+				results <- c
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	writer := bufio.NewWriter(w)
+	pending := make(map[int]*chunk)
+	next := 0
+	var runErr error
+	write := func(c *chunk) {
+		if runErr == nil && c.err != nil {
+			runErr = c.err
+		}
+		if runErr == nil {
+			if _, err := writer.Write(c.out); err != nil {
+				runErr = err
+			}
+			stats.Out += c.kept
+		}
+		if runErr != nil {
+			cancel()
+		}
+		<-slots
+	}
+	for c := range results {
+		if !cfg.ordered {
+			write(c)
+			continue
+		}
+		pending[c.seq] = c
+		for c, ok := pending[next]; ok; c, ok = pending[next] {
+			delete(pending, next)
+			write(c)
+			next++
+		}
+	}
+	if runErr == nil {
+		runErr = readErr
+	}
+	if runErr == nil {
+		runErr = writer.Flush()
+	}
+	return stats, runErr
+}
+
+func readChunks(ctx context.Context, r io.Reader, cfg config, slots chan struct{}, jobs chan<- *chunk, lines *int64) error {
+	scanner := newScanner(r, cfg)
+	c, size := &chunk{}, 0
+	send := func() bool {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return false
+		}
+		jobs <- c
+		c, size = &chunk{seq: c.seq + 1}, 0
+		return true
+	}
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		c.lines = append(c.lines, line)
+		*lines++
+		if size += len(line) + 1; size >= cfg.chunkSize && !send() {
+			return ctx.Err()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(c.lines) > 0 && !send() {
+		return ctx.Err()
+	}
+	return ctx.Err()
+}
+
+func mapChunk(lines [][]byte, fn Mapper) ([]byte, int64, error) {
+	var out []byte
+	var kept int64
+	for _, line := range lines {
+		mapped, keep, err := fn(line)
+		if err != nil {
+			return nil, kept, err
+		}
+		if keep {
+			out = append(append(out, mapped...), '\n')
+			kept++
+		}
+	}
+	return out, kept, nil
+}
@@ -0,0 +1,186 @@
+package bigfile
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"context"
+	"io"
+	"os"
+	"sort"
+)
+
+// Sort writes the lines of r to w in order. Runs of WithMemory bytes are
+// sorted in memory and spilled to temporary files, which are merged at the
+// end and removed.
+func Sort(ctx context.Context, r io.Reader, w io.Writer, opts ...Option) error {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return err
+	}
+	if cfg.less == nil {
+		cfg.less = func(a, b []byte) bool { return bytes.Compare(a, b) < 0 }
+	}
+	var runs []*os.File
+	defer func() {
+		for _, run := range runs {
+			_ = run.Close()
+			_ = os.Remove(run.Name())
+		}
+	}()
+
+	scanner := newScanner(r, cfg)
+	var lines [][]byte
+	size := 0
+	for scanner.Scan() {
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+		if size += len(lines[len(lines)-1]) + 1; size < cfg.memory {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		run, err := spill(lines, cfg)
+		if run != nil {
+			runs = append(runs, run)
+		}
+		if err != nil {
+			return err
+		}
+		lines, size = nil, 0
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(w)
+	out := &uniqueWriter{w: writer, cfg: cfg}
+	if runs == nil {
+		// everything fit in memory
+		sortLines(lines, cfg.less)
+		for _, line := range lines {
+			if err := out.write(line); err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	}
+	if len(lines) > 0 {
+		run, err := spill(lines, cfg)
+		if run != nil {
+			runs = append(runs, run)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := merge(ctx, runs, out, cfg); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func sortLines(lines [][]byte, less func(a, b []byte) bool) {
+	sort.SliceStable(lines, func(i, j int) bool { return less(lines[i], lines[j]) })
+}
+
+// spill writes the sorted lines to a temporary file, rewound for reading
+func spill(lines [][]byte, cfg config) (*os.File, error) {
+	sortLines(lines, cfg.less)
+	file, err := os.CreateTemp(cfg.tempDir, "bigfile-sort-*")
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(file)
+	for _, line := range lines {
+		if err := writeLine(writer, line); err != nil {
+			return file, err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return file, err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	return file, err
+}
+
+type uniqueWriter struct {
+	w       *bufio.Writer
+	cfg     config
+	last    []byte
+	written bool
+}
+
+func (u *uniqueWriter) write(line []byte) error {
+	if u.cfg.unique {
+		if u.written && !u.cfg.less(u.last, line) && !u.cfg.less(line, u.last) {
+			return nil
+		}
+		u.last = append(u.last[:0], line...)
+		u.written = true
+	}
+	return writeLine(u.w, line)
+}
+
+type runReader struct {
+	scanner *bufio.Scanner
+	line    []byte
+	index   int
+}
+
+type runHeap struct {
+	runs []*runReader
+	less func(a, b []byte) bool
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+func (h *runHeap) Less(i, j int) bool {
+	a, b := h.runs[i], h.runs[j]
+	if h.less(a.line, b.line) {
+		return true
+	}
+	// equal lines keep the order of the runs, so the sort stays stable
+	return !h.less(b.line, a.line) && a.index < b.index
+}
+func (h *runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+func (h *runHeap) Push(x any)    { h.runs = append(h.runs, x.(*runReader)) }
+func (h *runHeap) Pop() any {
+	last := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return last
+}
+
+func merge(ctx context.Context, runs []*os.File, out *uniqueWriter, cfg config) error {
+	h := &runHeap{less: cfg.less}
+	for i, run := range runs {
+		r := &runReader{scanner: newScanner(bufio.NewReader(run), cfg), index: i}
+		if r.scanner.Scan() {
+			r.line = r.scanner.Bytes()
+			h.runs = append(h.runs, r)
+		} else if err := r.scanner.Err(); err != nil {
+			return err
+		}
+	}
+	heap.Init(h)
+	for n := 0; h.Len() > 0; n++ {
+		if n%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		r := h.runs[0]
+		if err := out.write(r.line); err != nil {
+			return err
+		}
+		if r.scanner.Scan() {
+			r.line = r.scanner.Bytes()
+			heap.Fix(h, 0)
+			continue
+		}
+		if err := r.scanner.Err(); err != nil {
+			return err
+		}
+		heap.Pop(h)
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+// Package cache has Cache, an in-memory cache whose entries expire after a
+// TTL and whose least recently used entries are evicted beyond a size limit:
+//
+//	c, _ := cache.New[string, *User](cache.WithTTL(time.Minute), cache.WithMaxEntries(10000))
+//	user, err := c.GetOrLoad(ctx, id, func(ctx context.Context) (*User, error) {
+//		return db.LoadUser(ctx, id)
+//	})
+//
+// Disk is a Store of byte values kept in files, for data that should
+// outlive the process.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Stellar1999/gotool/cron"
+	"github.com/Stellar1999/gotool/errs"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Store is what components caching data accept, Cache implements it. Get
+// does not return expired entries, a zero ttl never expires.
+type Store[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	SetWithTTL(key K, value V, ttl time.Duration)
+	Delete(key K)
+	Clear()
+	Len() int
+}
+
+// Reason tells why an entry left the cache
+type Reason int
+
+const (
+	// Expired entries outlived their TTL
+	Expired Reason = iota
+	// Evicted entries were the least recently used of a full cache
+	Evicted
+)
+
+func (r Reason) String() string {
+	if r == Expired {
+		return "expired"
+	}
+	return "evicted"
+}
+
+type config struct {
+	ttl        time.Duration
+	maxEntries int
+	cleanup    time.Duration
+	now        func() time.Time
+	// onEvict is a func(K, V, Reason) of the Cache types
+	onEvict any
+}
+
+type Option = opt.Option[config]
+
+// WithTTL sets the TTL of entries added with Set, 0 by default for no expiry
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.ttl = ttl
+	}
+}
+
+// WithMaxEntries evicts the least recently used entries beyond n entries, 0
+// by default for no limit
+func WithMaxEntries(n int) Option {
+	return func(c *config) {
+		c.maxEntries = n
+	}
+}
+
+// WithCleanupInterval deletes the expired entries every d in the
+// background until Close, otherwise they are only deleted when accessed or
+// by DeleteExpired
+func WithCleanupInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.cleanup = d
+	}
+}
+
+// WithClock replaces time.Now, for tests
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// WithEvictionCallback calls fn with the entries that expire or are evicted,
+// not with those deleted or replaced. fn runs without the lock of the cache
+// and must match its key and value types.
+func WithEvictionCallback[K comparable, V any](fn func(key K, value V, reason Reason)) Option {
+	return func(c *config) {
+		c.onEvict = fn
+	}
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+func (e *entry[K, V]) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Cache is a TTL and LRU cache safe for concurrent use
+type Cache[K comparable, V any] struct {
+	cfg     config
+	onEvict func(K, V, Reason)
+
+	mu    sync.Mutex
+	items map[K]*list.Element
+	// lru holds *entry, the most recently used in front
+	lru   *list.List
+	calls map[K]*call[V]
+
+	janitor *cron.Scheduler
+
+	hits        int64
+	misses      int64
+	evictions   int64
+	expirations int64
+}
+
+// New returns an empty Cache
+func New[K comparable, V any](opts ...Option) (*Cache[K, V], error) {
+	cfg := config{now: time.Now}
+	err := opt.Build(&cfg, opts, func(c *config) error {
+		if c.ttl < 0 || c.maxEntries < 0 || c.cleanup < 0 {
+			return errors.New("cache: ttl, max entries and cleanup interval must not be negative")
+		}
+		return nil
+	}, func(c *config) error {
+		if _, ok := c.onEvict.(func(K, V, Reason)); c.onEvict != nil && !ok {
+			return errors.New("cache: eviction callback does not match the key and value types")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := &Cache[K, V]{
+		cfg:   cfg,
+		items: make(map[K]*list.Element),
+		lru:   list.New(),
+		calls: make(map[K]*call[V]),
+	}
+	c.onEvict, _ = cfg.onEvict.(func(K, V, Reason))
+	if cfg.cleanup > 0 {
+		if c.janitor, err = cron.New(); err != nil {
+			return nil, err
+		}
+		err = c.janitor.Add("cache_cleanup", cron.Every(cfg.cleanup), func(context.Context) error {
+			c.DeleteExpired()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		c.janitor.Start()
+	}
+	return c, nil
+}
+
+// Get returns the value of key unless it is missing or expired
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	el, ok := c.items[key]
+	if ok {
+		e := el.Value.(*entry[K, V])
+		if !e.expired(c.cfg.now()) {
+			c.lru.MoveToFront(el)
+			value := e.value
+			c.mu.Unlock()
+			atomic.AddInt64(&c.hits, 1)
+			return value, true
+		}
+		c.remove(el)
+		atomic.AddInt64(&c.expirations, 1)
+		c.mu.Unlock()
+		c.notify(e, Expired)
+	} else {
+		c.mu.Unlock()
+	}
+	atomic.AddInt64(&c.misses, 1)
+	var zero V
+	return zero, false
+}
+
+// Set adds or replaces the value of key with the TTL of WithTTL
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.cfg.ttl)
+}
+
+// SetWithTTL adds or replaces the value of key expiring after ttl, a zero
+// ttl never expires
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.cfg.now().Add(ttl)
+	}
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value, e.expires = value, expires
+		c.lru.MoveToFront(el)
+		c.mu.Unlock()
+		return
+	}
+	c.items[key] = c.lru.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+	var evicted *entry[K, V]
+	if c.cfg.maxEntries > 0 && c.lru.Len() > c.cfg.maxEntries {
+		evicted = c.remove(c.lru.Back())
+		atomic.AddInt64(&c.evictions, 1)
+	}
+	c.mu.Unlock()
+	if evicted != nil {
+		c.notify(evicted, Evicted)
+	}
+}
+
+// GetOrLoad returns the value of key, loading and adding it with load when
+// it is missing. Concurrent calls for the same key share one load, which
+// runs with the ctx of the first of them. Errors are returned, not cached.
+// A deadline passing while waiting for the load of another call matches
+// errs.ErrTimeout.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load func(ctx context.Context) (V, error)) (V, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+	c.mu.Lock()
+	cl, loading := c.calls[key]
+	if !loading {
+		cl = &call[V]{done: make(chan struct{})}
+		c.calls[key] = cl
+	}
+	c.mu.Unlock()
+
+	if !loading {
+		func() {
+			defer func() {
+				v := recover()
+				if v != nil {
+					cl.err = fmt.Errorf("cache: load panicked: %v", v)
+				}
+				c.mu.Lock()
+				delete(c.calls, key)
+				c.mu.Unlock()
+				close(cl.done)
+				if v != nil {
+					panic(v)
+				}
+			}()
+			cl.value, cl.err = load(ctx)
+			if cl.err == nil {
+				c.Set(key, cl.value)
+			}
+		}()
+		return cl.value, cl.err
+	}
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		var zero V
+		err := ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = errs.Mark(err, errs.ErrTimeout)
+		}
+		return zero, err
+	}
+}
+
+// Delete removes key
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Clear removes all entries
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[K]*list.Element)
+	c.lru.Init()
+}
+
+// Len returns the number of entries, counting expired ones not deleted yet
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// DeleteExpired deletes the expired entries and returns how many there were
+func (c *Cache[K, V]) DeleteExpired() int {
+	now := c.cfg.now()
+	var expired []*entry[K, V]
+	c.mu.Lock()
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if e := el.Value.(*entry[K, V]); e.expired(now) {
+			expired = append(expired, c.remove(el))
+		}
+		el = next
+	}
+	c.mu.Unlock()
+	atomic.AddInt64(&c.expirations, int64(len(expired)))
+	for _, e := range expired {
+		c.notify(e, Expired)
+	}
+	return len(expired)
+}
+
+// Close stops the cleanup of WithCleanupInterval, the cache remains usable
+func (c *Cache[K, V]) Close() {
+	if c.janitor != nil {
+		c.janitor.Stop()
+	}
+}
+
+// Stats returns the counters of the cache, register it with
+// stats.Register("users_cache", c).
+func (c *Cache[K, V]) Stats() map[string]float64 {
+	return map[string]float64{
+		"entries":           float64(c.Len()),
+		"hits_total":        float64(atomic.LoadInt64(&c.hits)),
+		"misses_total":      float64(atomic.LoadInt64(&c.misses)),
+		"evictions_total":   float64(atomic.LoadInt64(&c.evictions)),
+		"expirations_total": float64(atomic.LoadInt64(&c.expirations)),
+	}
+}
+
+// remove unlinks el, c.mu must be held
+func (c *Cache[K, V]) remove(el *list.Element) *entry[K, V] {
+	e := c.lru.Remove(el).(*entry[K, V])
+	delete(c.items, e.key)
+	return e
+}
+
+func (c *Cache[K, V]) notify(e *entry[K, V], reason Reason) {
+	if c.onEvict != nil {
+		c.onEvict(e.key, e.value, reason)
+	}
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/errs"
+)
+
+type evicted struct {
+	key    string
+	value  int
+	reason Reason
+}
+
+func TestCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	var out []evicted
+	c, err := New[string, int](
+		WithTTL(time.Minute),
+		WithMaxEntries(2),
+		WithClock(func() time.Time { return now }),
+		WithEvictionCallback(func(key string, value int, reason Reason) {
+			out = append(out, evicted{key, value, reason})
+		}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get() got = %v %v, want 1", v, ok)
+	}
+	// b is the least recently used
+	c.Set("c", 3)
+	if _, ok := c.Get("b"); ok || c.Len() != 2 {
+		t.Errorf("Get() got b after eviction, len %v", c.Len())
+	}
+	c.SetWithTTL("a", 10, 0)
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("c"); ok {
+		t.Errorf("Get() got expired entry c")
+	}
+	if v, ok := c.Get("a"); !ok || v != 10 {
+		t.Errorf("Get() got = %v %v, want 10 without expiry", v, ok)
+	}
+	want := []evicted{{"b", 2, Evicted}, {"c", 3, Expired}}
+	if len(out) != len(want) || out[0] != want[0] || out[1] != want[1] {
+		t.Errorf("evictions got = %v, want %v", out, want)
+	}
+
+	stats := c.Stats()
+	if stats["hits_total"] != 2 || stats["misses_total"] != 2 || stats["evictions_total"] != 1 || stats["expirations_total"] != 1 || stats["entries"] != 1 {
+		t.Errorf("Stats() got = %v", stats)
+	}
+
+	c.Set("d", 4)
+	c.Delete("d")
+	c.Clear()
+	if c.Len() != 0 || len(out) != 2 {
+		t.Errorf("Clear() len got = %v, evictions %v, want deletes not reported", c.Len(), out)
+	}
+
+	if _, err := New[string, int](WithEvictionCallback(func(key int, value int, reason Reason) {})); err == nil {
+		t.Errorf("New() error = nil, want mismatched callback")
+	}
+}
+
+func TestDeleteExpired(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Unix(0, 0)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	var expired int64
+	c, _ := New[int, int](WithTTL(time.Second), WithClock(clock), WithCleanupInterval(time.Millisecond),
+		WithEvictionCallback(func(int, int, Reason) { atomic.AddInt64(&expired, 1) }))
+	defer c.Close()
+	for i := 0; i < 5; i++ {
+		c.SetWithTTL(i, i, time.Duration(i)*time.Second)
+	}
+	mu.Lock()
+	now = now.Add(2 * time.Second)
+	mu.Unlock()
+	deadline := time.Now().Add(2 * time.Second)
+	for c.Len() != 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// 0 never expires, 1 and 2 are expired
+	if c.Len() != 3 || atomic.LoadInt64(&expired) != 2 {
+		t.Errorf("cleanup got len %v and %v expired, want 3 and 2", c.Len(), expired)
+	}
+	if n := c.DeleteExpired(); n != 0 {
+		t.Errorf("DeleteExpired() got = %v, want 0", n)
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c, _ := New[string, string]()
+	ctx := context.Background()
+	var loads int64
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(ctx, "k", func(ctx context.Context) (string, error) {
+				atomic.AddInt64(&loads, 1)
+				<-release
+				return "v", nil
+			})
+			if v != "v" || err != nil {
+				t.Errorf("GetOrLoad() got = %v %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if loads != 1 {
+		t.Errorf("loads got = %v, want 1", loads)
+	}
+
+	errLoad := errors.New("load failed")
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetOrLoad(ctx, "bad", func(ctx context.Context) (string, error) { return "", errLoad }); err != errLoad {
+			t.Errorf("GetOrLoad() error = %v, want %v", err, errLoad)
+		}
+	}
+	if _, ok := c.Get("bad"); ok {
+		t.Errorf("Get() got a failed load cached")
+	}
+
+	slow := make(chan struct{})
+	defer close(slow)
+	go func() {
+		_, _ = c.GetOrLoad(ctx, "slow", func(ctx context.Context) (string, error) {
+			<-slow
+			return "v", nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetOrLoad(short, "slow", nil); !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errs.ErrTimeout) {
+		t.Errorf("GetOrLoad() waiting past the deadline error = %v, want %v", err, errs.ErrTimeout)
+	}
+}
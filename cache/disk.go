@@ -0,0 +1,385 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Stellar1999/gotool/errs"
+	"github.com/Stellar1999/gotool/filex"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// diskIndexVersion changes with the format of the index file
+const diskIndexVersion = 1
+
+type diskConfig struct {
+	maxBytes int64
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+type DiskOption = opt.Option[diskConfig]
+
+// WithMaxBytes evicts the least recently used entries beyond n bytes of
+// values, 0 by default for no limit. Values larger than n are not stored.
+func WithMaxBytes(n int64) DiskOption {
+	return func(c *diskConfig) {
+		c.maxBytes = n
+	}
+}
+
+// WithDiskTTL sets the TTL of entries added with Set, 0 by default for no
+// expiry
+func WithDiskTTL(ttl time.Duration) DiskOption {
+	return func(c *diskConfig) {
+		c.ttl = ttl
+	}
+}
+
+// WithDiskClock replaces time.Now, for tests
+func WithDiskClock(now func() time.Time) DiskOption {
+	return func(c *diskConfig) {
+		c.now = now
+	}
+}
+
+type diskEntry struct {
+	// Hash is the SHA-256 of the value and names its file
+	Hash    string    `json:"hash"`
+	Size    int64     `json:"size"`
+	Expires time.Time `json:"expires,omitempty"`
+	Used    time.Time `json:"used"`
+}
+
+type diskIndex struct {
+	Version int                   `json:"version"`
+	Entries map[string]*diskEntry `json:"entries"`
+}
+
+// Disk is a Store keeping values in files under a directory, so they
+// survive the process, e.g. API responses cached by a CLI tool between runs.
+// Values are stored once per content in files named by their SHA-256, which
+// is checked on every Get: a damaged or missing file is dropped and reported
+// as a miss. An index file keeps the keys, expiry and last use of the
+// entries. Disk is safe for concurrent use by one process, processes must
+// not share a directory.
+//
+// The Store methods can't fail, a value that can't be written is simply not
+// cached.
+type Disk struct {
+	dir string
+	cfg diskConfig
+
+	mu      sync.Mutex
+	entries map[string]*diskEntry
+	// refs counts the entries of each file
+	refs  map[string]int
+	bytes int64
+
+	hits        int64
+	misses      int64
+	evictions   int64
+	expirations int64
+	corruptions int64
+	writeErrors int64
+}
+
+var _ Store[string, []byte] = (*Disk)(nil)
+
+// OpenDisk opens the cache in dir, creating it when missing. Entries of a
+// previous run are kept, an unreadable index starts an empty cache, and
+// files no entry refers to are removed. Errors of the file system match
+// errs.ErrUnavailable.
+func OpenDisk(dir string, opts ...DiskOption) (*Disk, error) {
+	cfg := diskConfig{now: time.Now}
+	err := opt.Build(&cfg, opts, func(c *diskConfig) error {
+		if c.maxBytes < 0 || c.ttl < 0 {
+			return errors.New("cache: negative max bytes or ttl")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	d := &Disk{dir: dir, cfg: cfg, entries: make(map[string]*diskEntry), refs: make(map[string]int)}
+	if err := filex.EnsureDir(d.objectsDir(), 0o755); err != nil {
+		return nil, errs.Mark(err, errs.ErrUnavailable)
+	}
+	var index diskIndex
+	if data, err := os.ReadFile(d.indexPath()); err == nil && json.Unmarshal(data, &index) == nil && index.Version == diskIndexVersion {
+		for key, e := range index.Entries {
+			if e != nil && validHash(e.Hash) {
+				d.add(key, e)
+			}
+		}
+	}
+	if err := d.removeOrphans(); err != nil {
+		return nil, errs.Mark(err, errs.ErrUnavailable)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.evict()
+	return d, errs.Mark(d.save(), errs.ErrUnavailable)
+}
+
+// Get returns the value of key unless it is missing, expired or its file is
+// damaged
+func (d *Disk) Get(key string) ([]byte, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.entries[key]
+	if !ok {
+		atomic.AddInt64(&d.misses, 1)
+		return nil, false
+	}
+	now := d.cfg.now()
+	if !e.Expires.IsZero() && !now.Before(e.Expires) {
+		d.remove(key)
+		_ = d.save()
+		atomic.AddInt64(&d.expirations, 1)
+		atomic.AddInt64(&d.misses, 1)
+		return nil, false
+	}
+	value, err := os.ReadFile(d.objectPath(e.Hash))
+	if err != nil || int64(len(value)) != e.Size || hashOf(value) != e.Hash {
+		d.dropObject(e.Hash)
+		_ = d.save()
+		atomic.AddInt64(&d.corruptions, 1)
+		atomic.AddInt64(&d.misses, 1)
+		return nil, false
+	}
+	// the last use is saved with the next change
+	e.Used = now
+	atomic.AddInt64(&d.hits, 1)
+	return value, true
+}
+
+// Set adds or replaces the value of key with the TTL of WithDiskTTL
+func (d *Disk) Set(key string, value []byte) {
+	d.SetWithTTL(key, value, d.cfg.ttl)
+}
+
+// SetWithTTL adds or replaces the value of key expiring after ttl, a zero
+// ttl never expires
+func (d *Disk) SetWithTTL(key string, value []byte, ttl time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.remove(key)
+	size := int64(len(value))
+	if d.cfg.maxBytes > 0 && size > d.cfg.maxBytes {
+		_ = d.save()
+		return
+	}
+	hash := hashOf(value)
+	if d.refs[hash] == 0 {
+		path := d.objectPath(hash)
+		err := filex.EnsureDir(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = filex.WriteFileAtomic(path, value, 0o644)
+		}
+		if err != nil {
+			atomic.AddInt64(&d.writeErrors, 1)
+			_ = d.save()
+			return
+		}
+	}
+	now := d.cfg.now()
+	e := &diskEntry{Hash: hash, Size: size, Used: now}
+	if ttl > 0 {
+		e.Expires = now.Add(ttl)
+	}
+	d.add(key, e)
+	d.evict()
+	if err := d.save(); err != nil {
+		atomic.AddInt64(&d.writeErrors, 1)
+	}
+}
+
+// Delete removes key
+func (d *Disk) Delete(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.entries[key]; ok {
+		d.remove(key)
+		_ = d.save()
+	}
+}
+
+// Clear removes all entries and their files
+func (d *Disk) Clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key := range d.entries {
+		d.remove(key)
+	}
+	_ = d.save()
+}
+
+// Len returns the number of entries, counting expired ones not deleted yet
+func (d *Disk) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.entries)
+}
+
+// Size returns the bytes taken by the values, counting shared ones once
+func (d *Disk) Size() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.bytes
+}
+
+// Close saves when the entries were last used, for the eviction order of the
+// next run. The cache remains usable. Errors match errs.ErrUnavailable.
+func (d *Disk) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return errs.Mark(d.save(), errs.ErrUnavailable)
+}
+
+// Stats returns the counters of the cache like Cache.Stats, with the bytes
+// taken, the damaged files dropped and the values that couldn't be written
+func (d *Disk) Stats() map[string]float64 {
+	d.mu.Lock()
+	entries, bytes := len(d.entries), d.bytes
+	d.mu.Unlock()
+	return map[string]float64{
+		"entries":            float64(entries),
+		"bytes":              float64(bytes),
+		"hits_total":         float64(atomic.LoadInt64(&d.hits)),
+		"misses_total":       float64(atomic.LoadInt64(&d.misses)),
+		"evictions_total":    float64(atomic.LoadInt64(&d.evictions)),
+		"expirations_total":  float64(atomic.LoadInt64(&d.expirations)),
+		"corruptions_total":  float64(atomic.LoadInt64(&d.corruptions)),
+		"write_errors_total": float64(atomic.LoadInt64(&d.writeErrors)),
+	}
+}
+
+func (d *Disk) indexPath() string {
+	return filepath.Join(d.dir, "index.json")
+}
+
+func (d *Disk) objectsDir() string {
+	return filepath.Join(d.dir, "objects")
+}
+
+// objectPath spreads the files over 256 directories
+func (d *Disk) objectPath(hash string) string {
+	return filepath.Join(d.objectsDir(), hash[:2], hash[2:])
+}
+
+// add adds e for key, d.mu must be held unless d is not shared yet
+func (d *Disk) add(key string, e *diskEntry) {
+	d.entries[key] = e
+	if d.refs[e.Hash] == 0 {
+		d.bytes += e.Size
+	}
+	d.refs[e.Hash]++
+}
+
+// remove removes key and the file no other entry refers to, d.mu must be
+// held
+func (d *Disk) remove(key string) {
+	e, ok := d.entries[key]
+	if !ok {
+		return
+	}
+	delete(d.entries, key)
+	d.refs[e.Hash]--
+	if d.refs[e.Hash] > 0 {
+		return
+	}
+	delete(d.refs, e.Hash)
+	d.bytes -= e.Size
+	_ = os.Remove(d.objectPath(e.Hash))
+}
+
+// dropObject removes a damaged file with all the entries referring to it,
+// d.mu must be held
+func (d *Disk) dropObject(hash string) {
+	for key, e := range d.entries {
+		if e.Hash == hash {
+			d.remove(key)
+		}
+	}
+}
+
+// evict removes the least recently used entries while the values take more
+// than WithMaxBytes, d.mu must be held
+func (d *Disk) evict() {
+	if d.cfg.maxBytes == 0 || d.bytes <= d.cfg.maxBytes {
+		return
+	}
+	keys := make([]string, 0, len(d.entries))
+	for key := range d.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return d.entries[keys[i]].Used.Before(d.entries[keys[j]].Used)
+	})
+	for _, key := range keys {
+		if d.bytes <= d.cfg.maxBytes {
+			return
+		}
+		d.remove(key)
+		atomic.AddInt64(&d.evictions, 1)
+	}
+}
+
+// save writes the index, d.mu must be held
+func (d *Disk) save() error {
+	data, err := json.Marshal(diskIndex{Version: diskIndexVersion, Entries: d.entries})
+	if err != nil {
+		return err
+	}
+	return filex.WriteFileAtomic(d.indexPath(), data, 0o644)
+}
+
+// removeOrphans removes the files no entry refers to, left by a crash
+// between writing a file and the index, and the entries whose file is gone
+func (d *Disk) removeOrphans() error {
+	err := filepath.WalkDir(d.objectsDir(), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(d.objectsDir(), path)
+		if err != nil {
+			return err
+		}
+		if hash := filepath.Dir(rel) + filepath.Base(rel); d.refs[hash] == 0 {
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for key, e := range d.entries {
+		if _, err := os.Stat(d.objectPath(e.Hash)); err != nil {
+			d.remove(key)
+		}
+	}
+	return nil
+}
+
+func hashOf(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
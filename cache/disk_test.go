@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/errs"
+)
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Unix(0, 0)
+	clock := WithDiskClock(func() time.Time { return now })
+	d, err := OpenDisk(dir, WithMaxBytes(10), clock)
+	if err != nil {
+		t.Fatalf("OpenDisk() error = %v", err)
+	}
+
+	d.Set("a", []byte("aaaa"))
+	now = now.Add(time.Second)
+	d.Set("b", []byte("bbbb"))
+	d.Set("same", []byte("bbbb"))
+	if d.Len() != 3 || d.Size() != 8 {
+		t.Errorf("Disk got %v entries of %v bytes, want 3 of 8 with a shared value", d.Len(), d.Size())
+	}
+	now = now.Add(time.Second)
+	if v, ok := d.Get("a"); !ok || string(v) != "aaaa" {
+		t.Errorf("Get() got = %q %v, want aaaa", v, ok)
+	}
+	// b and same are the least recently used
+	now = now.Add(time.Second)
+	d.Set("c", []byte("cccc"))
+	if _, ok := d.Get("b"); ok || d.Len() != 2 || d.Size() != 8 {
+		t.Errorf("Get() got b after eviction, %v entries of %v bytes", d.Len(), d.Size())
+	}
+	d.Set("big", make([]byte, 11))
+	if _, ok := d.Get("big"); ok {
+		t.Errorf("Get() got a value larger than WithMaxBytes")
+	}
+	d.SetWithTTL("ttl", []byte("t"), time.Minute)
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// another run finds the entries of the last one
+	d, err = OpenDisk(dir, WithMaxBytes(10), clock)
+	if err != nil {
+		t.Fatalf("OpenDisk() error = %v", err)
+	}
+	if v, ok := d.Get("c"); !ok || string(v) != "cccc" || d.Len() != 3 {
+		t.Errorf("Get() after reopening got = %q %v, %v entries", v, ok, d.Len())
+	}
+	now = now.Add(time.Minute)
+	if _, ok := d.Get("ttl"); ok {
+		t.Errorf("Get() got an expired entry")
+	}
+
+	// a damaged file is a miss and is dropped
+	if err := os.WriteFile(d.objectPath(hashOf([]byte("aaaa"))), []byte("aaab"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Get("a"); ok || d.Len() != 1 {
+		t.Errorf("Get() got a damaged value, %v entries", d.Len())
+	}
+	stats := d.Stats()
+	if stats["hits_total"] != 1 || stats["misses_total"] != 2 || stats["corruptions_total"] != 1 || stats["expirations_total"] != 1 || stats["bytes"] != 4 {
+		t.Errorf("Stats() got = %v", stats)
+	}
+
+	d.Clear()
+	objects, _ := filepath.Glob(filepath.Join(dir, "objects", "*", "*"))
+	if d.Len() != 0 || d.Size() != 0 || len(objects) != 0 {
+		t.Errorf("Clear() left %v entries, files %v", d.Len(), objects)
+	}
+}
+
+func TestOpenDiskRecovers(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("OpenDisk() error = %v", err)
+	}
+	d.Set("kept", []byte("kept"))
+	d.Set("lost", []byte("lost"))
+	if err := os.Remove(d.objectPath(hashOf([]byte("lost")))); err != nil {
+		t.Fatal(err)
+	}
+	orphan := d.objectPath(hashOf([]byte("orphan")))
+	_ = os.MkdirAll(filepath.Dir(orphan), 0o755)
+	_ = os.WriteFile(orphan, []byte("orphan"), 0o644)
+
+	d, err = OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("OpenDisk() error = %v", err)
+	}
+	if _, ok := d.Get("kept"); !ok || d.Len() != 1 {
+		t.Errorf("OpenDisk() got %v entries, want the one with a file", d.Len())
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("OpenDisk() kept the orphan file, err = %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "index.json"), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if d, err = OpenDisk(dir); err != nil || d.Len() != 0 || d.Size() != 0 {
+		t.Errorf("OpenDisk() with a damaged index got = %v, %v", d, err)
+	}
+	if _, err := OpenDisk(dir, WithMaxBytes(-1)); err == nil {
+		t.Errorf("OpenDisk() error = nil, want invalid options")
+	}
+	if _, err := OpenDisk(filepath.Join(dir, "index.json")); !errors.Is(err, errs.ErrUnavailable) {
+		t.Errorf("OpenDisk() on a file error = %v, want %v", err, errs.ErrUnavailable)
+	}
+}
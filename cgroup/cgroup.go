@@ -0,0 +1,213 @@
+// Package cgroup reads the CPU and memory limits of the container from cgroups
+// v1 or v2, so pools and parallel work can be sized to what the process may
+// use rather than to the cores and memory of the host.
+package cgroup
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ErrNoCgroup is returned when the process is not in a cgroup, e.g. not on Linux
+var ErrNoCgroup = errors.New("cgroup: no cgroup found")
+
+// fsRoot is where /proc and /sys are looked up, tests point it to a fake tree
+var fsRoot = "/"
+
+// v1 reports a huge number instead of no limit
+const v1Unlimited = 1 << 62
+
+// Limits are the limits of the cgroup of the process
+type Limits struct {
+	// Version is 1 or 2
+	Version int
+	// CPU is the CPU quota in cores, 0 without a limit
+	CPU float64
+	// Memory is the memory limit in bytes, 0 without a limit
+	Memory int64
+	// MemoryUsage is the memory in use by the cgroup when Detect ran
+	MemoryUsage int64
+}
+
+// GOMAXPROCS recommends a GOMAXPROCS for the CPU quota: the quota rounded
+// down, at least 1 and at most the number of CPUs
+func (l *Limits) GOMAXPROCS() int {
+	procs := runtime.NumCPU()
+	if l.CPU > 0 && int(l.CPU) < procs {
+		procs = int(l.CPU)
+	}
+	if procs < 1 {
+		procs = 1
+	}
+	return procs
+}
+
+// MemoryHeadroom returns the bytes left below the memory limit when Detect
+// ran, -1 without a limit
+func (l *Limits) MemoryHeadroom() int64 {
+	if l.Memory == 0 {
+		return -1
+	}
+	if headroom := l.Memory - l.MemoryUsage; headroom > 0 {
+		return headroom
+	}
+	return 0
+}
+
+// Detect reads the limits of the cgroup of the process. Limits of parent
+// cgroups apply as well, the tightest one is returned.
+func Detect() (*Limits, error) {
+	data, err := os.ReadFile(filepath.Join(fsRoot, "proc/self/cgroup"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoCgroup, err)
+	}
+	paths := parseProcCgroup(data)
+	if _, err := os.Stat(filepath.Join(fsRoot, "sys/fs/cgroup/cgroup.controllers")); err == nil {
+		return detectV2(paths[""])
+	}
+	return detectV1(paths)
+}
+
+// parseProcCgroup maps each controller to its path, "" is the v2 hierarchy
+func parseProcCgroup(data []byte) map[string]string {
+	paths := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[1] == "" {
+			paths[""] = fields[2]
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			paths[controller] = fields[2]
+		}
+	}
+	return paths
+}
+
+// dirs returns the directories of path and its parents below mount that
+// exist, the deepest first. Without a cgroup namespace the path of the
+// process may not exist in the container, then the mount itself is used.
+func dirs(mount string, path string) []string {
+	var found []string
+	for {
+		dir := filepath.Join(mount, path)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			found = append(found, dir)
+		}
+		if path == "/" || path == "." || path == "" {
+			return found
+		}
+		path = filepath.Dir(path)
+	}
+}
+
+func detectV2(path string) (*Limits, error) {
+	l := &Limits{Version: 2}
+	hierarchy := dirs(filepath.Join(fsRoot, "sys/fs/cgroup"), path)
+	if len(hierarchy) == 0 {
+		return nil, ErrNoCgroup
+	}
+	for _, dir := range hierarchy {
+		// cpu.max is "$MAX $PERIOD", $MAX is "max" without a limit
+		if fields := readFields(filepath.Join(dir, "cpu.max")); len(fields) == 2 && fields[0] != "max" {
+			l.CPU = tighter(l.CPU, quota(fields[0], fields[1]))
+		}
+		if value, ok := readInt(filepath.Join(dir, "memory.max")); ok {
+			l.Memory = tighterInt(l.Memory, value)
+		}
+		if l.MemoryUsage == 0 {
+			// the deepest cgroup with accounting
+			l.MemoryUsage, _ = readInt(filepath.Join(dir, "memory.current"))
+		}
+	}
+	return l, nil
+}
+
+func detectV1(paths map[string]string) (*Limits, error) {
+	l := &Limits{Version: 1}
+	found := false
+	for _, mount := range []string{"cpu", "cpu,cpuacct", "cpuacct,cpu"} {
+		hierarchy := dirs(filepath.Join(fsRoot, "sys/fs/cgroup", mount), paths["cpu"])
+		for _, dir := range hierarchy {
+			found = true
+			q, okQuota := readInt(filepath.Join(dir, "cpu.cfs_quota_us"))
+			p, okPeriod := readInt(filepath.Join(dir, "cpu.cfs_period_us"))
+			if okQuota && okPeriod && q > 0 && p > 0 {
+				l.CPU = tighter(l.CPU, float64(q)/float64(p))
+			}
+		}
+		if len(hierarchy) > 0 {
+			break
+		}
+	}
+	for _, dir := range dirs(filepath.Join(fsRoot, "sys/fs/cgroup/memory"), paths["memory"]) {
+		found = true
+		if value, ok := readInt(filepath.Join(dir, "memory.limit_in_bytes")); ok && value < v1Unlimited {
+			l.Memory = tighterInt(l.Memory, value)
+		}
+		if l.MemoryUsage == 0 {
+			l.MemoryUsage, _ = readInt(filepath.Join(dir, "memory.usage_in_bytes"))
+		}
+	}
+	if !found {
+		return nil, ErrNoCgroup
+	}
+	return l, nil
+}
+
+func quota(max string, period string) float64 {
+	m, err1 := strconv.ParseFloat(max, 64)
+	p, err2 := strconv.ParseFloat(period, 64)
+	if err1 != nil || err2 != nil || m <= 0 || p <= 0 {
+		return 0
+	}
+	return m / p
+}
+
+// tighter returns the smaller limit, 0 is no limit
+func tighter(a float64, b float64) float64 {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+func tighterInt(a int64, b int64) int64 {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+func readFields(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// readInt reads a single number, "max" and missing files are no value
+func readInt(path string) (int64, bool) {
+	fields := readFields(path)
+	if len(fields) != 1 {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || value < 0 {
+		return 0, false
+	}
+	return value, true
+}
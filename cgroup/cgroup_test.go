@@ -0,0 +1,150 @@
+package cgroup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+// fakeRoot writes files below a temporary root and points fsRoot to it
+func fakeRoot(t *testing.T, files map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := fsRoot
+	fsRoot = root
+	t.Cleanup(func() { fsRoot = old })
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  Limits
+	}{
+		{
+			name: "v2 with a namespace",
+			files: map[string]string{
+				"proc/self/cgroup":                   "0::/\n",
+				"sys/fs/cgroup/cgroup.controllers":   "cpu memory",
+				"sys/fs/cgroup/cpu.max":              "150000 100000\n",
+				"sys/fs/cgroup/memory.max":           "536870912\n",
+				"sys/fs/cgroup/memory.current":       "134217728\n",
+				"sys/fs/cgroup/unrelated/memory.max": "1",
+			},
+			want: Limits{Version: 2, CPU: 1.5, Memory: 512 << 20, MemoryUsage: 128 << 20},
+		},
+		{
+			name: "v2 nested, the parent is tighter",
+			files: map[string]string{
+				"proc/self/cgroup":                               "0::/kubepods/pod1/app\n",
+				"sys/fs/cgroup/cgroup.controllers":               "cpu memory",
+				"sys/fs/cgroup/kubepods/pod1/cpu.max":            "50000 100000\n",
+				"sys/fs/cgroup/kubepods/pod1/app/cpu.max":        "max 100000\n",
+				"sys/fs/cgroup/kubepods/pod1/app/memory.max":     "max\n",
+				"sys/fs/cgroup/kubepods/pod1/app/memory.current": "1024\n",
+				"sys/fs/cgroup/kubepods/memory.max":              "2048\n",
+			},
+			want: Limits{Version: 2, CPU: 0.5, Memory: 2048, MemoryUsage: 1024},
+		},
+		{
+			name: "v1 without a namespace",
+			files: map[string]string{
+				"proc/self/cgroup":                            "12:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n1:name=systemd:/docker/abc\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us":  "200000\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes":  "9223372036854771712\n",
+				"sys/fs/cgroup/memory/memory.usage_in_bytes":  "4096\n",
+			},
+			want: Limits{Version: 1, CPU: 2, Memory: 0, MemoryUsage: 4096},
+		},
+		{
+			name: "v1 without a quota",
+			files: map[string]string{
+				"proc/self/cgroup":                           "4:cpu:/\n5:memory:/\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         "-1\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us":        "100000\n",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes": "1073741824\n",
+			},
+			want: Limits{Version: 1, Memory: 1 << 30},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeRoot(t, tt.files)
+			got, err := Detect()
+			if err != nil || *got != tt.want {
+				t.Errorf("Detect() got = %+v %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+
+	fakeRoot(t, map[string]string{})
+	if _, err := Detect(); !errors.Is(err, ErrNoCgroup) {
+		t.Errorf("Detect() error got = %v, want %v", err, ErrNoCgroup)
+	}
+}
+
+func TestRecommendations(t *testing.T) {
+	tests := []struct {
+		limits   Limits
+		procs    int
+		headroom int64
+	}{
+		{limits: Limits{CPU: 0.5, Memory: 100, MemoryUsage: 40}, procs: 1, headroom: 60},
+		{limits: Limits{CPU: 1.9, Memory: 100, MemoryUsage: 140}, procs: 1, headroom: 0},
+		{limits: Limits{}, procs: runtime.NumCPU(), headroom: -1},
+		{limits: Limits{CPU: 100000}, procs: runtime.NumCPU(), headroom: -1},
+	}
+	for _, tt := range tests {
+		if got := tt.limits.GOMAXPROCS(); got != tt.procs {
+			t.Errorf("%+v GOMAXPROCS() got = %v, want %v", tt.limits, got, tt.procs)
+		}
+		if got := tt.limits.MemoryHeadroom(); got != tt.headroom {
+			t.Errorf("%+v MemoryHeadroom() got = %v, want %v", tt.limits, got, tt.headroom)
+		}
+	}
+}
+
+func TestTune(t *testing.T) {
+	fakeRoot(t, map[string]string{
+		"proc/self/cgroup":                 "0::/\n",
+		"sys/fs/cgroup/cgroup.controllers": "cpu memory",
+		"sys/fs/cgroup/cpu.max":            "100000 100000\n",
+		"sys/fs/cgroup/memory.max":         "1073741824\n",
+	})
+	t.Setenv("GOMAXPROCS", "")
+	os.Unsetenv("GOMAXPROCS")
+	t.Setenv("GOGC", "")
+	os.Unsetenv("GOGC")
+	procs := runtime.GOMAXPROCS(0)
+	gc := debug.SetGCPercent(100)
+	defer func() {
+		runtime.GOMAXPROCS(procs)
+		debug.SetGCPercent(gc)
+	}()
+
+	if _, err := Tune(WithGCPercent(50)); err != nil {
+		t.Fatalf("Tune() error = %v", err)
+	}
+	if got := runtime.GOMAXPROCS(0); got != 1 {
+		t.Errorf("GOMAXPROCS got = %v, want 1", got)
+	}
+	if got := debug.SetGCPercent(100); got != 50 {
+		t.Errorf("GOGC got = %v, want 50", got)
+	}
+	if _, err := Tune(WithMemoryLimitRatio(2)); err == nil {
+		t.Errorf("Tune() with a ratio above 1 error = nil")
+	}
+}
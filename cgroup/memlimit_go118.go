@@ -0,0 +1,8 @@
+//go:build !go1.19
+
+package cgroup
+
+// setMemoryLimit has no runtime support before Go 1.19
+func setMemoryLimit(limit int64) bool {
+	return false
+}
@@ -0,0 +1,87 @@
+package cgroup
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type config struct {
+	memoryLimitRatio float64
+	gcPercent        int
+	logger           gohttp.Logger
+}
+
+type Option = opt.Option[config]
+
+// WithMemoryLimitRatio sets the soft memory limit of the runtime to ratio of
+// the cgroup memory limit, e.g. 0.9, leaving the rest for memory the runtime
+// does not manage. It needs Go 1.19, older versions ignore it.
+func WithMemoryLimitRatio(ratio float64) Option {
+	return func(c *config) {
+		c.memoryLimitRatio = ratio
+	}
+}
+
+// WithGCPercent sets GOGC to percent when the cgroup has a memory limit, a
+// lower value trades CPU for a smaller heap
+func WithGCPercent(percent int) Option {
+	return func(c *config) {
+		c.gcPercent = percent
+	}
+}
+
+// WithLogger logs the settings Tune changed
+func WithLogger(logger gohttp.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.memoryLimitRatio < 0 || c.memoryLimitRatio > 1 {
+			return errors.New("cgroup: memory limit ratio must be between 0 and 1")
+		}
+		return nil
+	},
+}
+
+// Tune sets GOMAXPROCS to the recommendation of the CPU quota and, with the
+// options, the memory limit and GOGC of the runtime. Settings given with the
+// GOMAXPROCS, GOMEMLIMIT and GOGC environment variables are kept. Call it
+// early in main, before pools size themselves with runtime.GOMAXPROCS(0).
+func Tune(opts ...Option) (*Limits, error) {
+	cfg := config{logger: gohttp.NopLogger}
+	if err := opt.Build(&cfg, opts, checks...); err != nil {
+		return nil, err
+	}
+	l, err := Detect()
+	if err != nil {
+		return nil, err
+	}
+	if _, set := os.LookupEnv("GOMAXPROCS"); !set {
+		if procs := l.GOMAXPROCS(); procs != runtime.GOMAXPROCS(0) {
+			previous := runtime.GOMAXPROCS(procs)
+			cfg.logger.Info("GOMAXPROCS set to the CPU quota", "gomaxprocs", procs, "previous", previous, "quota", l.CPU)
+		}
+	}
+	if l.Memory == 0 {
+		return l, nil
+	}
+	if _, set := os.LookupEnv("GOMEMLIMIT"); !set && cfg.memoryLimitRatio > 0 {
+		limit := int64(float64(l.Memory) * cfg.memoryLimitRatio)
+		if setMemoryLimit(limit) {
+			cfg.logger.Info("memory limit set below the cgroup limit", "limit", limit, "cgroup_limit", l.Memory)
+		}
+	}
+	if _, set := os.LookupEnv("GOGC"); !set && cfg.gcPercent > 0 {
+		previous := debug.SetGCPercent(cfg.gcPercent)
+		cfg.logger.Info("GOGC set for the memory limit", "gogc", cfg.gcPercent, "previous", previous)
+	}
+	return l, nil
+}
@@ -0,0 +1,346 @@
+// Package cloudmeta detects the cloud an instance runs in and reads its
+// identity, tags and temporary credentials from the metadata service.
+package cloudmeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+var (
+	// ErrNotCloud is returned when no metadata service answers
+	ErrNotCloud = errors.New("cloudmeta: no metadata service found")
+	// ErrNotFound is returned for metadata the instance does not have, e.g.
+	// credentials of an instance without a role
+	ErrNotFound = errors.New("cloudmeta: metadata not found")
+	// ErrUnsupported is returned for metadata the provider does not offer
+	ErrUnsupported = errors.New("cloudmeta: not supported by the provider")
+)
+
+// Provider is a cloud with a metadata service
+type Provider string
+
+const (
+	AWS    Provider = "aws"
+	GCP    Provider = "gcp"
+	Aliyun Provider = "aliyun"
+)
+
+var defaultEndpoints = map[Provider]string{
+	AWS:    "http://169.254.169.254",
+	GCP:    "http://metadata.google.internal",
+	Aliyun: "http://100.100.100.200",
+}
+
+// Instance is the identity of the instance
+type Instance struct {
+	Provider  Provider
+	ID        string
+	Type      string
+	Region    string
+	Zone      string
+	PrivateIP string
+	Hostname  string
+	ImageID   string
+	// AccountID is the AWS account, GCP project or Aliyun owner account
+	AccountID string
+}
+
+// Credentials are the temporary credentials of the instance role. On GCP only
+// Token is set, an OAuth2 access token of the default service account.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	Token           string
+	Expiration      time.Time
+}
+
+type config struct {
+	httpClient *http.Client
+	timeout    time.Duration
+	cacheTTL   time.Duration
+	provider   Provider
+	endpoints  map[Provider]string
+}
+
+type Option = opt.Option[config]
+
+// WithHTTPClient sends metadata requests with client. It must not go through
+// a proxy, the metadata services are only reachable from the instance.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		c.httpClient = client
+	}
+}
+
+// WithTimeout bounds every metadata request and the detection, 2s by default
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithCacheTTL keeps the instance and its tags for d, 10m by default.
+// Credentials are kept until 5 minutes before they expire.
+func WithCacheTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.cacheTTL = d
+	}
+}
+
+// WithProvider skips the detection
+func WithProvider(p Provider) Option {
+	return func(c *config) {
+		c.provider = p
+	}
+}
+
+// WithEndpoint replaces the metadata service address of p, e.g. for an
+// emulator like the one of the ec2-metadata-mock project
+func WithEndpoint(p Provider, baseURL string) Option {
+	return func(c *config) {
+		c.endpoints[p] = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// metadata reads one provider's metadata service
+type metadata interface {
+	probe(ctx context.Context) error
+	instance(ctx context.Context) (*Instance, error)
+	tags(ctx context.Context) (map[string]string, error)
+	credentials(ctx context.Context) (*Credentials, error)
+}
+
+type cached[T any] struct {
+	value   T
+	expires time.Time
+}
+
+// Client reads the metadata service of the detected cloud, it is safe for
+// concurrent use
+type Client struct {
+	config   config
+	services map[Provider]metadata
+	now      func() time.Time
+
+	detectMu sync.Mutex
+	detected bool
+	provider Provider
+	err      error
+
+	mu          sync.Mutex
+	instance    cached[*Instance]
+	tags        cached[map[string]string]
+	credentials cached[*Credentials]
+}
+
+func New(opts ...Option) *Client {
+	cfg := config{timeout: 2 * time.Second, cacheTTL: 10 * time.Minute, endpoints: map[Provider]string{}}
+	opt.Apply(&cfg, opts...)
+	if cfg.httpClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		cfg.httpClient = &http.Client{Transport: transport}
+	}
+	c := &Client{config: cfg, now: time.Now}
+	endpoint := func(p Provider) string {
+		if e, ok := cfg.endpoints[p]; ok {
+			return e
+		}
+		return defaultEndpoints[p]
+	}
+	c.services = map[Provider]metadata{
+		AWS:    newAWS(c, endpoint(AWS)),
+		GCP:    &gcp{c: c, base: endpoint(GCP)},
+		Aliyun: newAliyun(c, endpoint(Aliyun)),
+	}
+	return c
+}
+
+// Detect probes the metadata services in parallel and returns the provider of
+// the first that answers, or ErrNotCloud. The result is kept.
+func (c *Client) Detect(ctx context.Context) (Provider, error) {
+	c.detectMu.Lock()
+	defer c.detectMu.Unlock()
+	if c.detected {
+		return c.provider, c.err
+	}
+	if c.config.provider != "" {
+		c.detected, c.provider = true, c.config.provider
+		return c.provider, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.config.timeout)
+	defer cancel()
+	found := make(chan Provider, len(c.services))
+	for p, service := range c.services {
+		p, service := p, service
+		go func() {
+			if service.probe(ctx) != nil {
+				p = ""
+			}
+			found <- p
+		}()
+	}
+	for range c.services {
+		if p := <-found; p != "" {
+			c.detected, c.provider = true, p
+			return p, nil
+		}
+	}
+	if err := ctx.Err(); err == context.Canceled {
+		// canceled by the caller, try again next time
+		return "", err
+	}
+	c.detected, c.err = true, ErrNotCloud
+	return "", c.err
+}
+
+func (c *Client) service(ctx context.Context) (metadata, error) {
+	p, err := c.Detect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	service, ok := c.services[p]
+	if !ok {
+		return nil, fmt.Errorf("%w: provider %q", ErrUnsupported, p)
+	}
+	return service, nil
+}
+
+// Instance returns the identity of the instance
+func (c *Client) Instance(ctx context.Context) (*Instance, error) {
+	return load(ctx, c, &c.instance, func(service metadata) (*Instance, time.Time, error) {
+		instance, err := service.instance(ctx)
+		return instance, c.now().Add(c.config.cacheTTL), err
+	})
+}
+
+// Tags returns the instance tags, on GCP the custom metadata attributes. AWS
+// only serves them when tags in instance metadata are enabled, otherwise the
+// map is empty.
+func (c *Client) Tags(ctx context.Context) (map[string]string, error) {
+	return load(ctx, c, &c.tags, func(service metadata) (map[string]string, time.Time, error) {
+		tags, err := service.tags(ctx)
+		return tags, c.now().Add(c.config.cacheTTL), err
+	})
+}
+
+// Credentials returns the temporary credentials of the instance role, they are
+// refreshed 5 minutes before they expire
+func (c *Client) Credentials(ctx context.Context) (*Credentials, error) {
+	return load(ctx, c, &c.credentials, func(service metadata) (*Credentials, time.Time, error) {
+		credentials, err := service.credentials(ctx)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return credentials, credentials.Expiration.Add(-5 * time.Minute), nil
+	})
+}
+
+func load[T any](ctx context.Context, c *Client, entry *cached[T], fetch func(service metadata) (T, time.Time, error)) (T, error) {
+	var zero T
+	c.mu.Lock()
+	if c.now().Before(entry.expires) {
+		value := entry.value
+		c.mu.Unlock()
+		return value, nil
+	}
+	c.mu.Unlock()
+	service, err := c.service(ctx)
+	if err != nil {
+		return zero, err
+	}
+	value, expires, err := fetch(service)
+	if err != nil {
+		return zero, err
+	}
+	c.mu.Lock()
+	entry.value, entry.expires = value, expires
+	c.mu.Unlock()
+	return value, nil
+}
+
+// Signer returns a SigV4 signer for service that signs with the instance
+// credentials and region, they are looked up on every request and refreshed
+// as they expire. It needs AWS.
+func (c *Client) Signer(service string) gohttp.Signer {
+	return &signer{client: c, service: service}
+}
+
+type signer struct {
+	client  *Client
+	service string
+}
+
+func (s *signer) Sign(req *http.Request, body []byte) error {
+	ctx := req.Context()
+	if p, err := s.client.Detect(ctx); err != nil {
+		return err
+	} else if p != AWS {
+		return fmt.Errorf("%w: SigV4 needs AWS, running on %s", ErrUnsupported, p)
+	}
+	credentials, err := s.client.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	instance, err := s.client.Instance(ctx)
+	if err != nil {
+		return err
+	}
+	sigV4 := &gohttp.SigV4Signer{
+		AccessKey:    credentials.AccessKeyID,
+		SecretKey:    credentials.SecretAccessKey,
+		SessionToken: credentials.Token,
+		Region:       instance.Region,
+		Service:      s.service,
+		Now:          s.client.now,
+	}
+	return sigV4.Sign(req, body)
+}
+
+// do sends a metadata request and returns the body, 404 is ErrNotFound
+func (c *Client) do(ctx context.Context, method string, url string, header http.Header) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := c.config.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, req.URL.Path)
+	case resp.StatusCode != http.StatusOK:
+		return nil, &statusError{code: resp.StatusCode, path: req.URL.Path}
+	}
+	return body, nil
+}
+
+type statusError struct {
+	code int
+	path string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("cloudmeta: %s returned status %d", e.path, e.code)
+}
@@ -0,0 +1,161 @@
+package cloudmeta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// metadataServer serves routes by path, header is required on every request
+func metadataServer(t *testing.T, header string, value string, routes map[string]string) (*httptest.Server, *int) {
+	t.Helper()
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		path := r.URL.Path
+		if r.URL.RawQuery != "" {
+			path += "?" + r.URL.RawQuery
+		}
+		if r.Method == http.MethodPut {
+			path = "PUT " + path
+		} else if header != "" && r.Header.Get(header) != value {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := routes[path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// unreachable returns the url of a closed server
+func unreachable() string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestAWS(t *testing.T) {
+	server, requests := metadataServer(t, "X-aws-ec2-metadata-token", "TOKEN", map[string]string{
+		"PUT /latest/api/token":                       "TOKEN",
+		"/latest/meta-data/instance-id":               "i-0abc",
+		"/latest/meta-data/local-hostname":            "ip-10-0-0-5.ec2.internal",
+		"/latest/meta-data/tags/instance":             "Name\nteam",
+		"/latest/meta-data/tags/instance/Name":        "web-1",
+		"/latest/meta-data/tags/instance/team":        "infra",
+		"/latest/meta-data/iam/security-credentials/": "web-role",
+		"/latest/dynamic/instance-identity/document": `{"instanceId":"i-0abc","instanceType":"t3.micro","region":"eu-west-1",
+			"availabilityZone":"eu-west-1a","privateIp":"10.0.0.5","imageId":"ami-1","accountId":"123456789012"}`,
+		"/latest/meta-data/iam/security-credentials/web-role": `{"Code":"Success","AccessKeyId":"ASIA1","SecretAccessKey":"secret",
+			"Token":"session","Expiration":"2030-01-01T00:00:00Z"}`,
+	})
+	c := New(WithEndpoint(AWS, server.URL), WithEndpoint(GCP, unreachable()), WithEndpoint(Aliyun, unreachable()))
+	ctx := context.Background()
+
+	if p, err := c.Detect(ctx); p != AWS || err != nil {
+		t.Fatalf("Detect() got = %v %v, want %v", p, err, AWS)
+	}
+	instance, err := c.Instance(ctx)
+	want := &Instance{Provider: AWS, ID: "i-0abc", Type: "t3.micro", Region: "eu-west-1", Zone: "eu-west-1a",
+		PrivateIP: "10.0.0.5", Hostname: "ip-10-0-0-5.ec2.internal", ImageID: "ami-1", AccountID: "123456789012"}
+	if err != nil || !reflect.DeepEqual(instance, want) {
+		t.Errorf("Instance() got = %+v %v, want %+v", instance, err, want)
+	}
+	before := *requests
+	if _, err := c.Instance(ctx); err != nil || *requests != before {
+		t.Errorf("Instance() sent %d requests, want the cached instance", *requests-before)
+	}
+	tags, err := c.Tags(ctx)
+	if err != nil || !reflect.DeepEqual(tags, map[string]string{"Name": "web-1", "team": "infra"}) {
+		t.Errorf("Tags() got = %v %v", tags, err)
+	}
+	credentials, err := c.Credentials(ctx)
+	if err != nil || credentials.AccessKeyID != "ASIA1" || credentials.Token != "session" || credentials.Expiration.Year() != 2030 {
+		t.Errorf("Credentials() got = %+v %v", credentials, err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://s3.eu-west-1.amazonaws.com/bucket", nil)
+	if err := c.Signer("s3").Sign(req, nil); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "Credential=ASIA1/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+		t.Errorf("Sign() Authorization got = %v", auth)
+	}
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "session" {
+		t.Errorf("Sign() X-Amz-Security-Token got = %v, want session", got)
+	}
+}
+
+func TestGCP(t *testing.T) {
+	server, _ := metadataServer(t, "Metadata-Flavor", "Google", map[string]string{
+		"/computeMetadata/v1/instance/id": "4520031799277581759",
+		"/computeMetadata/v1/instance/?recursive=true": `{"id":4520031799277581759,"machineType":"projects/42/machineTypes/e2-small",
+			"zone":"projects/42/zones/europe-west1-b","hostname":"vm.c.demo.internal","image":"projects/debian-cloud/global/images/debian-12",
+			"networkInterfaces":[{"ip":"10.132.0.2"}]}`,
+		"/computeMetadata/v1/project/project-id":                      "demo",
+		"/computeMetadata/v1/instance/attributes/?recursive=true":     `{"env":"prod"}`,
+		"/computeMetadata/v1/instance/service-accounts/default/token": `{"access_token":"ya29.x","expires_in":3599,"token_type":"Bearer"}`,
+	})
+	c := New(WithProvider(GCP), WithEndpoint(GCP, server.URL))
+	ctx := context.Background()
+
+	instance, err := c.Instance(ctx)
+	want := &Instance{Provider: GCP, ID: "4520031799277581759", Type: "e2-small", Region: "europe-west1", Zone: "europe-west1-b",
+		PrivateIP: "10.132.0.2", Hostname: "vm.c.demo.internal", ImageID: "projects/debian-cloud/global/images/debian-12", AccountID: "demo"}
+	if err != nil || !reflect.DeepEqual(instance, want) {
+		t.Errorf("Instance() got = %+v %v, want %+v", instance, err, want)
+	}
+	if tags, err := c.Tags(ctx); err != nil || tags["env"] != "prod" {
+		t.Errorf("Tags() got = %v %v", tags, err)
+	}
+	credentials, err := c.Credentials(ctx)
+	if err != nil || credentials.Token != "ya29.x" || time.Until(credentials.Expiration) < 59*time.Minute {
+		t.Errorf("Credentials() got = %+v %v", credentials, err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://storage.googleapis.com/", nil)
+	if err := c.Signer("s3").Sign(req, nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Sign() error got = %v, want %v", err, ErrUnsupported)
+	}
+}
+
+func TestAliyun(t *testing.T) {
+	// no PUT route: the token request fails and the service is read without one
+	server, _ := metadataServer(t, "", "", map[string]string{
+		"/latest/meta-data/instance-id": "i-bp1",
+		"/latest/dynamic/instance-identity/document": `{"instance-id":"i-bp1","instance-type":"ecs.g6.large","region-id":"cn-hangzhou",
+			"zone-id":"cn-hangzhou-h","private-ipv4":"172.16.0.3","image-id":"centos_7","owner-account-id":"1234"}`,
+		"/latest/meta-data/ram/security-credentials/": "",
+	})
+	c := New(WithEndpoint(Aliyun, server.URL), WithEndpoint(AWS, unreachable()), WithEndpoint(GCP, unreachable()))
+	ctx := context.Background()
+
+	instance, err := c.Instance(ctx)
+	if err != nil || instance.Provider != Aliyun || instance.Region != "cn-hangzhou" || instance.AccountID != "1234" {
+		t.Errorf("Instance() got = %+v %v", instance, err)
+	}
+	if _, err := c.Credentials(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Credentials() error got = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := c.Tags(ctx); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Tags() error got = %v, want %v", err, ErrUnsupported)
+	}
+}
+
+func TestNotCloud(t *testing.T) {
+	c := New(WithEndpoint(AWS, unreachable()), WithEndpoint(GCP, unreachable()), WithEndpoint(Aliyun, unreachable()))
+	for i := 0; i < 2; i++ {
+		if _, err := c.Instance(context.Background()); !errors.Is(err, ErrNotCloud) {
+			t.Errorf("Instance() error got = %v, want %v", err, ErrNotCloud)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package cloudmeta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenTTL is the lifetime requested for session tokens, the maximum of IMDSv2
+const tokenTTL = 6 * time.Hour
+
+// tokenService is a metadata service with the session token flow of AWS
+// IMDSv2, which Aliyun copied: a PUT returns a token that is sent with every
+// read. Services that reject the PUT are read without a token.
+type tokenService struct {
+	c           *Client
+	base        string
+	tokenHeader string
+	ttlHeader   string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (s *tokenService) get(ctx context.Context, path string) ([]byte, error) {
+	header := make(http.Header)
+	token, err := s.sessionToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		header.Set(s.tokenHeader, token)
+	}
+	return s.c.do(ctx, http.MethodGet, s.base+path, header)
+}
+
+func (s *tokenService) sessionToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.c.now().Before(s.expires) {
+		return s.token, nil
+	}
+	header := make(http.Header)
+	header.Set(s.ttlHeader, strconv.Itoa(int(tokenTTL/time.Second)))
+	token, err := s.c.do(ctx, http.MethodPut, s.base+"/latest/api/token", header)
+	var status *statusError
+	switch {
+	case errors.As(err, &status) || errors.Is(err, ErrNotFound):
+		// no token support, fall back to v1 for a while
+		s.token = ""
+	case err != nil:
+		return "", err
+	default:
+		s.token = string(token)
+	}
+	s.expires = s.c.now().Add(tokenTTL - time.Minute)
+	return s.token, nil
+}
+
+func (s *tokenService) getString(ctx context.Context, path string) (string, error) {
+	body, err := s.get(ctx, path)
+	return strings.TrimSpace(string(body)), err
+}
+
+// list reads a directory listing, one entry per line
+func (s *tokenService) list(ctx context.Context, path string) ([]string, error) {
+	body, err := s.getString(ctx, path)
+	if err != nil || body == "" {
+		return nil, err
+	}
+	return strings.Split(body, "\n"), nil
+}
+
+func (s *tokenService) getJSON(ctx context.Context, path string, v any) error {
+	body, err := s.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("cloudmeta: decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+// role returns the first role listed at path
+func (s *tokenService) role(ctx context.Context, path string) (string, error) {
+	roles, err := s.list(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	if len(roles) == 0 {
+		return "", fmt.Errorf("%w: no role attached", ErrNotFound)
+	}
+	return roles[0], nil
+}
+
+type aws struct {
+	tokenService
+}
+
+func newAWS(c *Client, base string) *aws {
+	return &aws{tokenService{c: c, base: base, tokenHeader: "X-aws-ec2-metadata-token", ttlHeader: "X-aws-ec2-metadata-token-ttl-seconds"}}
+}
+
+func (a *aws) probe(ctx context.Context) error {
+	_, err := a.get(ctx, "/latest/meta-data/instance-id")
+	return err
+}
+
+func (a *aws) instance(ctx context.Context) (*Instance, error) {
+	var doc struct {
+		InstanceID       string `json:"instanceId"`
+		InstanceType     string `json:"instanceType"`
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+		PrivateIP        string `json:"privateIp"`
+		ImageID          string `json:"imageId"`
+		AccountID        string `json:"accountId"`
+	}
+	if err := a.getJSON(ctx, "/latest/dynamic/instance-identity/document", &doc); err != nil {
+		return nil, err
+	}
+	hostname, _ := a.getString(ctx, "/latest/meta-data/local-hostname")
+	return &Instance{
+		Provider:  AWS,
+		ID:        doc.InstanceID,
+		Type:      doc.InstanceType,
+		Region:    doc.Region,
+		Zone:      doc.AvailabilityZone,
+		PrivateIP: doc.PrivateIP,
+		Hostname:  hostname,
+		ImageID:   doc.ImageID,
+		AccountID: doc.AccountID,
+	}, nil
+}
+
+func (a *aws) tags(ctx context.Context) (map[string]string, error) {
+	keys, err := a.list(ctx, "/latest/meta-data/tags/instance")
+	if errors.Is(err, ErrNotFound) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if tags[key], err = a.getString(ctx, "/latest/meta-data/tags/instance/"+key); err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+func (a *aws) credentials(ctx context.Context) (*Credentials, error) {
+	const path = "/latest/meta-data/iam/security-credentials/"
+	role, err := a.role(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Code            string
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := a.getJSON(ctx, path+role, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Code != "Success" {
+		return nil, fmt.Errorf("cloudmeta: credentials of role %s: %s", role, resp.Code)
+	}
+	return &Credentials{AccessKeyID: resp.AccessKeyID, SecretAccessKey: resp.SecretAccessKey, Token: resp.Token, Expiration: resp.Expiration}, nil
+}
+
+type aliyun struct {
+	tokenService
+}
+
+func newAliyun(c *Client, base string) *aliyun {
+	return &aliyun{tokenService{c: c, base: base, tokenHeader: "X-aliyun-ecs-metadata-token", ttlHeader: "X-aliyun-ecs-metadata-token-ttl-seconds"}}
+}
+
+func (a *aliyun) probe(ctx context.Context) error {
+	_, err := a.get(ctx, "/latest/meta-data/instance-id")
+	return err
+}
+
+func (a *aliyun) instance(ctx context.Context) (*Instance, error) {
+	var doc struct {
+		InstanceID     string `json:"instance-id"`
+		InstanceType   string `json:"instance-type"`
+		RegionID       string `json:"region-id"`
+		ZoneID         string `json:"zone-id"`
+		PrivateIPv4    string `json:"private-ipv4"`
+		ImageID        string `json:"image-id"`
+		OwnerAccountID string `json:"owner-account-id"`
+	}
+	if err := a.getJSON(ctx, "/latest/dynamic/instance-identity/document", &doc); err != nil {
+		return nil, err
+	}
+	hostname, _ := a.getString(ctx, "/latest/meta-data/hostname")
+	return &Instance{
+		Provider:  Aliyun,
+		ID:        doc.InstanceID,
+		Type:      doc.InstanceType,
+		Region:    doc.RegionID,
+		Zone:      doc.ZoneID,
+		PrivateIP: doc.PrivateIPv4,
+		Hostname:  hostname,
+		ImageID:   doc.ImageID,
+		AccountID: doc.OwnerAccountID,
+	}, nil
+}
+
+func (a *aliyun) tags(ctx context.Context) (map[string]string, error) {
+	return nil, fmt.Errorf("%w: instance tags are not in the Aliyun metadata", ErrUnsupported)
+}
+
+func (a *aliyun) credentials(ctx context.Context) (*Credentials, error) {
+	const path = "/latest/meta-data/ram/security-credentials/"
+	role, err := a.role(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Code            string
+		AccessKeyID     string `json:"AccessKeyId"`
+		AccessKeySecret string
+		SecurityToken   string
+		Expiration      time.Time
+	}
+	if err := a.getJSON(ctx, path+role, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Code != "Success" {
+		return nil, fmt.Errorf("cloudmeta: credentials of role %s: %s", role, resp.Code)
+	}
+	return &Credentials{AccessKeyID: resp.AccessKeyID, SecretAccessKey: resp.AccessKeySecret, Token: resp.SecurityToken, Expiration: resp.Expiration}, nil
+}
+
+// gcp reads the GCE metadata server, every request needs Metadata-Flavor
+type gcp struct {
+	c    *Client
+	base string
+}
+
+func (g *gcp) get(ctx context.Context, path string) ([]byte, error) {
+	header := make(http.Header)
+	header.Set("Metadata-Flavor", "Google")
+	return g.c.do(ctx, http.MethodGet, g.base+"/computeMetadata/v1/"+path, header)
+}
+
+func (g *gcp) probe(ctx context.Context) error {
+	_, err := g.get(ctx, "instance/id")
+	return err
+}
+
+func (g *gcp) instance(ctx context.Context) (*Instance, error) {
+	body, err := g.get(ctx, "instance/?recursive=true")
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		ID                json.Number `json:"id"`
+		MachineType       string      `json:"machineType"`
+		Zone              string      `json:"zone"`
+		Hostname          string      `json:"hostname"`
+		Image             string      `json:"image"`
+		NetworkInterfaces []struct {
+			IP string `json:"ip"`
+		} `json:"networkInterfaces"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("cloudmeta: decoding instance: %w", err)
+	}
+	project, err := g.get(ctx, "project/project-id")
+	if err != nil {
+		return nil, err
+	}
+	// zone and machine type are like projects/123/zones/europe-west1-b
+	zone := lastSegment(doc.Zone)
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+	instance := &Instance{
+		Provider:  GCP,
+		ID:        doc.ID.String(),
+		Type:      lastSegment(doc.MachineType),
+		Region:    region,
+		Zone:      zone,
+		Hostname:  doc.Hostname,
+		ImageID:   doc.Image,
+		AccountID: string(project),
+	}
+	if len(doc.NetworkInterfaces) > 0 {
+		instance.PrivateIP = doc.NetworkInterfaces[0].IP
+	}
+	return instance, nil
+}
+
+func (g *gcp) tags(ctx context.Context) (map[string]string, error) {
+	body, err := g.get(ctx, "instance/attributes/?recursive=true")
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, fmt.Errorf("cloudmeta: decoding attributes: %w", err)
+	}
+	return tags, nil
+}
+
+func (g *gcp) credentials(ctx context.Context) (*Credentials, error) {
+	body, err := g.get(ctx, "instance/service-accounts/default/token")
+	if err != nil {
+		return nil, err
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("cloudmeta: decoding token: %w", err)
+	}
+	return &Credentials{Token: token.AccessToken, Expiration: g.c.now().Add(time.Duration(token.ExpiresIn) * time.Second)}, nil
+}
+
+func lastSegment(s string) string {
+	return s[strings.LastIndex(s, "/")+1:]
+}
@@ -0,0 +1,187 @@
+package conctest
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultRuns         = 100
+	DefaultOpsPerWorker = 100
+
+	// SeedEnv replays a single run with the given seed
+	SeedEnv = "CONCTEST_SEED"
+)
+
+// TestingT is the subset of testing.TB used by this package
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Op is one operation on the state under test, r is the worker's seeded source
+type Op[S any] func(s S, r *rand.Rand) error
+
+// Scenario describes the state under test and the operations interleaved on it.
+// Every run calls Setup for a fresh state, starts Workers goroutines which each
+// execute OpsPerWorker randomly picked Ops, then checks Invariant.
+type Scenario[S any] struct {
+	Setup        func() S
+	Ops          []Op[S]
+	Invariant    func(s S) error
+	Teardown     func(s S)
+	Workers      int
+	OpsPerWorker int
+}
+
+type options struct {
+	runs int
+	seed int64
+}
+
+type Option func(*options)
+
+// Runs sets how many times the scenario is executed
+func Runs(n int) Option {
+	return func(o *options) {
+		o.runs = n
+	}
+}
+
+// Seed sets the seed of the first run, the following runs use seed+1, seed+2...
+func Seed(seed int64) Option {
+	return func(o *options) {
+		o.seed = seed
+	}
+}
+
+// Run executes the scenario repeatedly and reports the seed of the first
+// failing run. Op selection and scheduling hints are reproducible from the
+// seed, the actual interleaving is still up to the Go scheduler.
+func Run[S any](t TestingT, sc Scenario[S], opts ...Option) {
+	t.Helper()
+	o := options{runs: DefaultRuns, seed: time.Now().UnixNano()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if env := os.Getenv(SeedEnv); env != "" {
+		seed, err := strconv.ParseInt(env, 10, 64)
+		if err != nil {
+			t.Errorf("conctest: invalid %s %q: %v", SeedEnv, env, err)
+			return
+		}
+		o.runs, o.seed = 1, seed
+	}
+	if len(sc.Ops) == 0 {
+		t.Errorf("conctest: scenario has no ops")
+		return
+	}
+	for i := 0; i < o.runs; i++ {
+		seed := o.seed + int64(i)
+		if err := runOnce(sc, seed); err != nil {
+			t.Errorf("conctest: run %d failed: %v (reproduce with %s=%d)", i, err, SeedEnv, seed)
+			return
+		}
+	}
+}
+
+func runOnce[S any](sc Scenario[S], seed int64) error {
+	workers := sc.Workers
+	if workers <= 0 {
+		workers = len(sc.Ops)
+	}
+	opsPerWorker := sc.OpsPerWorker
+	if opsPerWorker <= 0 {
+		opsPerWorker = DefaultOpsPerWorker
+	}
+	var s S
+	if sc.Setup != nil {
+		s = sc.Setup()
+	}
+	if sc.Teardown != nil {
+		defer sc.Teardown(s)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		failed   int32
+	)
+	start := make(chan struct{})
+	for w := 0; w < workers; w++ {
+		r := rand.New(rand.NewSource(seed*int64(workers) + int64(w)))
+		wg.Add(1)
+		go func(w int, r *rand.Rand) {
+			defer wg.Done()
+			defer func() {
+				if p := recover(); p != nil {
+					errOnce.Do(func() { firstErr = fmt.Errorf("worker %d panicked: %v", w, p) })
+					atomic.StoreInt32(&failed, 1)
+				}
+			}()
+			<-start
+			for i := 0; i < opsPerWorker && atomic.LoadInt32(&failed) == 0; i++ {
+				yield(r)
+				op := r.Intn(len(sc.Ops))
+				if err := sc.Ops[op](s, r); err != nil {
+					errOnce.Do(func() { firstErr = fmt.Errorf("worker %d op %d: %w", w, op, err) })
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}(w, r)
+	}
+	close(start)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if sc.Invariant != nil {
+		if err := sc.Invariant(s); err != nil {
+			return fmt.Errorf("invariant: %w", err)
+		}
+	}
+	return nil
+}
+
+// yield perturbs scheduling between ops to shake out different interleavings
+func yield(r *rand.Rand) {
+	switch n := r.Intn(10); {
+	case n < 5:
+	case n < 9:
+		runtime.Gosched()
+	default:
+		time.Sleep(time.Duration(r.Intn(50)) * time.Microsecond)
+	}
+}
+
+// Exclusive asserts that a section is never entered by two goroutines at once,
+// catching mutual exclusion bugs the race detector cannot see.
+type Exclusive struct {
+	inside     int32
+	violations int32
+}
+
+// Enter marks the section as entered and returns the function leaving it
+func (e *Exclusive) Enter() func() {
+	if atomic.AddInt32(&e.inside, 1) > 1 {
+		atomic.AddInt32(&e.violations, 1)
+	}
+	return func() {
+		atomic.AddInt32(&e.inside, -1)
+	}
+}
+
+// Check returns an error if the section was ever entered concurrently
+func (e *Exclusive) Check() error {
+	if n := atomic.LoadInt32(&e.violations); n > 0 {
+		return fmt.Errorf("exclusive section entered concurrently %d times", n)
+	}
+	return nil
+}
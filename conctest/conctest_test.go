@@ -0,0 +1,120 @@
+package conctest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (*fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+type counter struct {
+	mu    sync.Mutex
+	n     int64
+	adds  int64
+	guard Exclusive
+}
+
+func (c *counter) add(locked bool) {
+	if locked {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+	}
+	leave := c.guard.Enter()
+	defer leave()
+	// widen the window for an overlapping add
+	runtime.Gosched()
+	atomic.AddInt64(&c.n, 1)
+	atomic.AddInt64(&c.adds, 1)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		locked  bool
+		opts    []Option
+		check   func(c *counter) error
+		wantErr string
+	}{
+		{
+			name:   "locked counter",
+			locked: true,
+			opts:   []Option{Runs(10)},
+			check: func(c *counter) error {
+				if c.n != c.adds {
+					return fmt.Errorf("n = %d, adds = %d", c.n, c.adds)
+				}
+				return c.guard.Check()
+			},
+		},
+		{
+			name:    "unlocked counter",
+			locked:  false,
+			opts:    []Option{Runs(10), Seed(42)},
+			check:   func(c *counter) error { return c.guard.Check() },
+			wantErr: "CONCTEST_SEED=",
+		},
+		{
+			name:    "broken invariant",
+			locked:  true,
+			opts:    []Option{Seed(7)},
+			check:   func(c *counter) error { return errors.New("always broken") },
+			wantErr: "run 0 failed: invariant: always broken (reproduce with CONCTEST_SEED=7)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeT{}
+			add := func(c *counter, r *rand.Rand) error {
+				c.add(tt.locked)
+				return nil
+			}
+			Run(ft, Scenario[*counter]{
+				Setup:        func() *counter { return &counter{} },
+				Ops:          []Op[*counter]{add, add},
+				Invariant:    tt.check,
+				Workers:      8,
+				OpsPerWorker: 200,
+			}, tt.opts...)
+			if tt.wantErr == "" {
+				if len(ft.errors) != 0 {
+					t.Errorf("Run() got errors = %v, want none", ft.errors)
+				}
+				return
+			}
+			if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], tt.wantErr) {
+				t.Errorf("Run() got errors = %v, want %q", ft.errors, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunSeedEnv(t *testing.T) {
+	t.Setenv(SeedEnv, "1234")
+	ft := &fakeT{}
+	runs := 0
+	Run(ft, Scenario[*counter]{
+		Setup:     func() *counter { runs++; return &counter{} },
+		Ops:       []Op[*counter]{func(c *counter, r *rand.Rand) error { return nil }},
+		Invariant: func(c *counter) error { return errors.New("fail") },
+	})
+	if runs != 1 {
+		t.Errorf("Run() got runs = %d, want 1", runs)
+	}
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "CONCTEST_SEED=1234") {
+		t.Errorf("Run() got errors = %v, want seed 1234", ft.errors)
+	}
+}
@@ -0,0 +1,352 @@
+// Package config loads settings into a struct from layers, each overriding
+// the previous: the default tags, YAML, JSON or TOML files, environment
+// variables and command line flags. "${NAME}" in the strings of files is
+// replaced with the environment variable, keeping secrets out of them. A
+// Watcher reloads the files when they change.
+//
+// Fields are named by their config tag, or by their name in snake case:
+//
+//	type Settings struct {
+//		Server struct {
+//			Addr    string        `default:":8080" usage:"listen address"`
+//			Timeout time.Duration `default:"5s"`
+//		}
+//		Token string `env:"API_TOKEN"`
+//		Tags  []string
+//	}
+//
+// With WithEnv("APP") Addr is set by APP_SERVER_ADDR and with WithFlags by
+// -server.addr. A field tagged config:"-" is left alone.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrUnknownFormat is returned for a file whose extension is not .yaml,
+	// .yml, .json or .toml
+	ErrUnknownFormat = errors.New("config: unknown file format")
+	// ErrUnsetVariable is returned when a file refers to an unset environment
+	// variable without a default
+	ErrUnsetVariable = errors.New("config: environment variable not set")
+)
+
+type source struct {
+	path     string
+	optional bool
+}
+
+type config struct {
+	files     []source
+	env       bool
+	envPrefix string
+	lookupEnv func(string) (string, bool)
+	flags     *flag.FlagSet
+	args      []string
+	validate  []func(any) error
+	interval  time.Duration
+	logger    gohttp.Logger
+}
+
+type Option = opt.Option[config]
+
+// WithFile reads the file at path, its format is told by the extension.
+// Later files override earlier ones.
+func WithFile(path string) Option {
+	return func(c *config) {
+		c.files = append(c.files, source{path: path})
+	}
+}
+
+// WithOptionalFile is WithFile for a file that may not exist
+func WithOptionalFile(path string) Option {
+	return func(c *config) {
+		c.files = append(c.files, source{path: path, optional: true})
+	}
+}
+
+// WithEnv reads environment variables named by prefix, an underscore and the
+// path of the field in upper case, or by the env tag of the field without
+// prefix. Lists are separated by commas.
+func WithEnv(prefix string) Option {
+	return func(c *config) {
+		c.env = true
+		c.envPrefix = prefix
+	}
+}
+
+// WithLookupEnv replaces os.LookupEnv for the environment layer and the
+// expansion of variables in files
+func WithLookupEnv(lookup func(name string) (string, bool)) Option {
+	return func(c *config) {
+		c.lookupEnv = lookup
+	}
+}
+
+// WithFlags defines a flag on fs for every field and parses args, unless fs
+// is already parsed. The flags are named by the field path joined by dots or
+// by the flag tag, and described by the usage tag. Flags defined before
+// with these names are used as they are. A nil fs uses flag.CommandLine
+// and os.Args[1:].
+func WithFlags(fs *flag.FlagSet, args []string) Option {
+	return func(c *config) {
+		if fs == nil {
+			fs, args = flag.CommandLine, os.Args[1:]
+		}
+		c.flags, c.args = fs, args
+	}
+}
+
+// WithValidate checks the loaded struct, given as a pointer, after its
+// Validate method when it has one
+func WithValidate(fn func(v any) error) Option {
+	return func(c *config) {
+		c.validate = append(c.validate, fn)
+	}
+}
+
+// WithReloadInterval is how often a Watcher checks the files for changes,
+// 5s by default
+func WithReloadInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.interval = d
+	}
+}
+
+// WithLogger logs the reloads of a Watcher and their failures
+func WithLogger(logger gohttp.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.interval <= 0 {
+			return errors.New("config: reload interval must be positive")
+		}
+		return nil
+	},
+}
+
+func build(opts []Option) (config, error) {
+	cfg := config{lookupEnv: os.LookupEnv, interval: 5 * time.Second, logger: gohttp.NopLogger}
+	err := opt.Build(&cfg, opts, checks...)
+	return cfg, err
+}
+
+// Validator is implemented by settings that check themselves once loaded
+type Validator interface {
+	Validate() error
+}
+
+// Load returns a new T loaded with opts, T must be a struct
+func Load[T any](opts ...Option) (*T, error) {
+	v := new(T)
+	if err := LoadInto(v, opts...); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// LoadInto loads the layers into the struct dst points to. Fields no layer
+// sets keep their value.
+func LoadInto(dst any, opts ...Option) error {
+	cfg, err := build(opts)
+	if err != nil {
+		return err
+	}
+	return cfg.load(dst)
+}
+
+func (c *config) load(dst any) error {
+	rv, err := structPointer(dst)
+	if err != nil {
+		return err
+	}
+	leaves := collectLeaves(rv.Type().Elem(), nil)
+
+	tree := make(map[string]any)
+	for _, l := range leaves {
+		if def, ok := l.field.Tag.Lookup("default"); ok {
+			setPath(tree, l.path, def)
+		}
+	}
+	for _, src := range c.files {
+		values, err := c.readFile(src)
+		if err != nil {
+			return err
+		}
+		merge(tree, values)
+	}
+	if c.env {
+		for _, l := range leaves {
+			if value, ok := c.lookupEnv(l.envName(c.envPrefix)); ok {
+				setPath(tree, l.path, value)
+			}
+		}
+	}
+	if c.flags != nil {
+		if err := c.parseFlags(leaves, tree); err != nil {
+			return err
+		}
+	}
+
+	if err := decodeStruct(rv.Elem(), tree, ""); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if v, ok := dst.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, validate := range c.validate {
+		if err := validate(dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *config) readFile(src source) (map[string]any, error) {
+	data, err := os.ReadFile(src.path)
+	if err != nil {
+		if src.optional && errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	values := make(map[string]any)
+	switch ext := strings.ToLower(filepath.Ext(src.path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".json":
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.UseNumber()
+		err = dec.Decode(&values)
+	case ".toml":
+		values, err = parseTOML(data)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, src.path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", src.path, err)
+	}
+	expanded, err := c.expand(values)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", src.path, err)
+	}
+	m, _ := expanded.(map[string]any)
+	return m, nil
+}
+
+// expand replaces "${NAME}" and "${NAME:-default}" in the strings of v
+func (c *config) expand(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return expandString(v, c.lookupEnv)
+	case map[string]any:
+		for k, e := range v {
+			expanded, err := c.expand(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = expanded
+		}
+	case []any:
+		for i, e := range v {
+			expanded, err := c.expand(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	}
+	return v, nil
+}
+
+func expandString(s string, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			break
+		}
+		b.WriteString(s[:start])
+		name, def, hasDefault := strings.Cut(s[start+2:start+end], ":-")
+		value, ok := lookup(name)
+		switch {
+		case ok:
+			b.WriteString(value)
+		case hasDefault:
+			b.WriteString(def)
+		default:
+			return "", fmt.Errorf("%w: %s", ErrUnsetVariable, name)
+		}
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return b.String(), nil
+}
+
+// merge copies src into dst, merging the maps both have
+func merge(dst map[string]any, src map[string]any) {
+	for k, v := range src {
+		k = existingKey(dst, k)
+		if sub, ok := v.(map[string]any); ok {
+			if existing, ok := dst[k].(map[string]any); ok {
+				merge(existing, sub)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// setPath sets value at path in tree, replacing what is in the way
+func setPath(tree map[string]any, path []string, value any) {
+	for _, key := range path[:len(path)-1] {
+		key = existingKey(tree, key)
+		sub, ok := tree[key].(map[string]any)
+		if !ok {
+			sub = make(map[string]any)
+			tree[key] = sub
+		}
+		tree = sub
+	}
+	tree[existingKey(tree, path[len(path)-1])] = value
+}
+
+// existingKey returns the key of m equal to key ignoring case, or key, so
+// layers spelling a name differently set the same field
+func existingKey(m map[string]any, key string) string {
+	if _, ok := m[key]; ok {
+		return key
+	}
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return key
+}
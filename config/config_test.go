@@ -0,0 +1,310 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type database struct {
+	URL      string `config:"url"`
+	Password string
+	Pool     int `default:"4"`
+}
+
+type settings struct {
+	Server struct {
+		Addr    string        `default:":8080" usage:"listen address"`
+		Timeout time.Duration `default:"5s"`
+		Debug   bool
+	}
+	Database *database
+	Token    string `env:"API_TOKEN"`
+	Tags     []string
+	Limits   map[string]int
+	Ratio    float64
+	Internal string `config:"-"`
+}
+
+func (s *settings) Validate() error {
+	if s.Ratio > 1 {
+		return errors.New("ratio above 1")
+	}
+	return nil
+}
+
+func env(vars map[string]string) Option {
+	return WithLookupEnv(func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	})
+}
+
+func write(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLayers(t *testing.T) {
+	yamlFile := write(t, "base.yaml", `
+server:
+  addr: ":9000"
+  timeout: 10s
+database:
+  url: postgres://db
+  password: ${DB_PASSWORD}
+tags: [a, b]
+limits:
+  read: 10
+ratio: 0.5
+`)
+	jsonFile := write(t, "override.json", `{"Server": {"debug": true}, "limits": {"write": 3}, "tags": ["c"]}`)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	s, err := Load[settings](
+		WithFile(yamlFile),
+		WithFile(jsonFile),
+		WithOptionalFile(filepath.Join(t.TempDir(), "missing.toml")),
+		WithEnv("APP"),
+		env(map[string]string{"DB_PASSWORD": "s3cret", "APP_SERVER_TIMEOUT": "1m", "API_TOKEN": "tok", "APP_DATABASE_POOL": "8"}),
+		WithFlags(fs, []string{"-server.addr", ":7000", "-server.debug=false"}),
+	)
+	if err != nil {
+		t.Fatalf("Load() got = %v", err)
+	}
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"flag over file", s.Server.Addr, ":7000"},
+		{"env over file", s.Server.Timeout, time.Minute},
+		{"bool flag", s.Server.Debug, false},
+		{"expanded secret", s.Database.Password, "s3cret"},
+		{"config tag", s.Database.URL, "postgres://db"},
+		{"env over default", s.Database.Pool, 8},
+		{"env tag", s.Token, "tok"},
+		{"later file", s.Tags, []string{"c"}},
+		{"merged maps", s.Limits, map[string]int{"read": 10, "write": 3}},
+		{"float", s.Ratio, 0.5},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("Load() %v got = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if f := fs.Lookup("server.addr"); f == nil || f.Usage != "listen address" || f.DefValue != ":8080" {
+		t.Errorf("WithFlags() defined flag got = %+v", f)
+	}
+}
+
+func TestDefaultsAndEnv(t *testing.T) {
+	s := settings{Internal: "kept"}
+	err := LoadInto(&s, WithEnv(""), env(map[string]string{"TAGS": "x, y", "LIMITS": "a=1,b=2", "INTERNAL": "no"}))
+	if err != nil {
+		t.Fatalf("LoadInto() got = %v", err)
+	}
+	if s.Server.Addr != ":8080" || s.Server.Timeout != 5*time.Second || s.Database == nil || s.Database.Pool != 4 {
+		t.Errorf("LoadInto() defaults got = %+v %+v", s.Server, s.Database)
+	}
+	if !reflect.DeepEqual(s.Tags, []string{"x", "y"}) || !reflect.DeepEqual(s.Limits, map[string]int{"a": 1, "b": 2}) || s.Internal != "kept" {
+		t.Errorf("LoadInto() env got = %v %v %q", s.Tags, s.Limits, s.Internal)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want error
+	}{
+		{"unknown format", []Option{WithFile(write(t, "c.ini", "a=1"))}, ErrUnknownFormat},
+		{"unset variable", []Option{WithFile(write(t, "c.yaml", "token: ${NOPE}")), env(nil)}, ErrUnsetVariable},
+		{"missing file", []Option{WithFile(filepath.Join(t.TempDir(), "none.yaml"))}, os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		if _, err := Load[settings](tt.opts...); !errors.Is(err, tt.want) {
+			t.Errorf("Load(%v) got = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if _, err := Load[settings](WithFile(write(t, "c.yaml", "ratio: 2"))); err == nil || err.Error() != "ratio above 1" {
+		t.Errorf("Load() Validate got = %v", err)
+	}
+	failed := errors.New("custom")
+	if _, err := Load[settings](WithValidate(func(any) error { return failed })); err != failed {
+		t.Errorf("Load() WithValidate got = %v, want %v", err, failed)
+	}
+	if _, err := Load[settings](WithFile(write(t, "c.yaml", "server: {timeout: soon}"))); err == nil || !strings.HasPrefix(err.Error(), "config: server.timeout: time: ") {
+		t.Errorf("Load() bad value got = %v", err)
+	}
+	if s, err := Load[settings](WithFile(write(t, "c.yaml", "token: ${NOPE:-fallback}")), env(nil)); err != nil || s.Token != "fallback" {
+		t.Errorf("Load() variable default got = %v", err)
+	}
+	if err := LoadInto(settings{}); err == nil {
+		t.Errorf("LoadInto() non pointer got = nil, want an error")
+	}
+}
+
+func TestTOML(t *testing.T) {
+	doc := `
+# comment
+title = "TOML \"test\" \u00e9"
+path = 'C:\Users'
+int = 1_000
+hex = 0xff
+neg = -3
+float = 6.5e-1
+inf = -inf
+yes = true
+date = 1979-05-27T07:32:00Z
+local = 1979-05-27
+list = [1, 2,
+  3, # trailing
+]
+inline = { a = 1, b.c = "x" }
+"quoted key" = 1
+multi = """
+one \
+  two"""
+lit = '''
+raw\n'''
+
+[server]
+addr = ":80" # comment
+
+[server.tls]
+enabled = false
+
+[[users]]
+name = "ann"
+
+[[users]]
+name = "bob"
+roles = ["admin"]
+`
+	got, err := parseTOML([]byte(doc))
+	if err != nil {
+		t.Fatalf("parseTOML() got = %v", err)
+	}
+	want := map[string]any{
+		"title":      "TOML \"test\" é",
+		"path":       `C:\Users`,
+		"int":        int64(1000),
+		"hex":        int64(255),
+		"neg":        int64(-3),
+		"float":      0.65,
+		"inf":        math.Inf(-1),
+		"yes":        true,
+		"date":       time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		"local":      "1979-05-27",
+		"list":       []any{int64(1), int64(2), int64(3)},
+		"inline":     map[string]any{"a": int64(1), "b": map[string]any{"c": "x"}},
+		"quoted key": int64(1),
+		"multi":      "one two",
+		"lit":        `raw\n`,
+		"server":     map[string]any{"addr": ":80", "tls": map[string]any{"enabled": false}},
+		"users":      []any{map[string]any{"name": "ann"}, map[string]any{"name": "bob", "roles": []any{"admin"}}},
+	}
+	for k, w := range want {
+		if g := got[k]; !reflect.DeepEqual(g, w) {
+			t.Errorf("parseTOML() %v got = %#v, want %#v", k, g, w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("parseTOML() got %v keys, want %v", len(got), len(want))
+	}
+
+	bad := []string{"a = 1\na = 2", "[t]\n[t]", "a = 01", "a = \"open", "a = 1 b", "a = [1 2]", "= 1"}
+	for _, doc := range bad {
+		if _, err := parseTOML([]byte(doc)); err == nil {
+			t.Errorf("parseTOML(%q) got = nil, want an error", doc)
+		}
+	}
+
+	type user struct {
+		Name  string
+		Roles []string
+	}
+	var s struct {
+		Server struct{ Addr string }
+		Users  []user
+	}
+	if err := LoadInto(&s, WithFile(write(t, "c.toml", doc))); err != nil || s.Server.Addr != ":80" || len(s.Users) != 2 || s.Users[1].Roles[0] != "admin" {
+		t.Errorf("LoadInto() toml got = %+v %v", s, err)
+	}
+}
+
+type recorder struct {
+	msgs []string
+}
+
+func (r *recorder) Debug(msg string, keysAndValues ...any) {}
+func (r *recorder) Info(msg string, keysAndValues ...any)  { r.msgs = append(r.msgs, msg) }
+func (r *recorder) Warn(msg string, keysAndValues ...any)  {}
+func (r *recorder) Error(msg string, keysAndValues ...any) { r.msgs = append(r.msgs, msg) }
+
+func TestWatcher(t *testing.T) {
+	path := write(t, "c.yaml", "token: one")
+	w, err := NewWatcher[settings](WithFile(path), WithReloadInterval(5*time.Millisecond))
+	if err != nil || w.Get().Token != "one" {
+		t.Fatalf("NewWatcher() got = %v", err)
+	}
+	changes := make(chan [2]string, 4)
+	w.OnChange(func(prev *settings, next *settings) {
+		changes <- [2]string{prev.Token, next.Token}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Watch(ctx)
+
+	// a different size is noticed whatever the resolution of modification times
+	if err := os.WriteFile(path, []byte("token: second"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-changes:
+		if got != [2]string{"one", "second"} || w.Get().Token != "second" {
+			t.Errorf("Watch() change got = %v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Watch() did not reload")
+	}
+
+	if err := os.WriteFile(path, []byte("ratio: 5"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reload(); err == nil || w.Get().Token != "second" {
+		t.Errorf("Reload() invalid got = %v, want an error and the settings kept", err)
+	}
+	if err := w.Reload(); err == nil {
+		t.Errorf("Reload() got = nil")
+	}
+	select {
+	case got := <-changes:
+		t.Errorf("OnChange() called for a failed reload with %v", got)
+	default:
+	}
+}
+
+func TestWatcherLogger(t *testing.T) {
+	logs := &recorder{}
+	path := write(t, "c.json", `{"token": "a"}`)
+	w, _ := NewWatcher[settings](WithFile(path), WithLogger(logs))
+	_ = os.WriteFile(path, []byte(`{"token": "b"}`), 0o644)
+	_ = w.Reload()
+	_ = w.Reload()
+	_ = os.WriteFile(path, []byte(`{`), 0o644)
+	_ = w.Reload()
+	if !reflect.DeepEqual(logs.msgs, []string{"config reloaded", "config reload failed"}) {
+		t.Errorf("WithLogger() got = %v", logs.msgs)
+	}
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Stellar1999/gotool/stringx"
+)
+
+var (
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType    = reflect.TypeOf(time.Duration(0))
+	timeType        = reflect.TypeOf(time.Time{})
+)
+
+func structPointer(dst any) (reflect.Value, error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("config: destination must be a non-nil struct pointer, got %T", dst)
+	}
+	return rv, nil
+}
+
+// leaf is a field set by a single value, as opposed to the structs holding
+// other fields
+type leaf struct {
+	path  []string
+	field reflect.StructField
+}
+
+// envName is the env tag or the prefixed path, e.g. APP_SERVER_ADDR
+func (l leaf) envName(prefix string) string {
+	if name := l.field.Tag.Get("env"); name != "" {
+		return name
+	}
+	parts := append([]string(nil), l.path...)
+	if prefix != "" {
+		parts = append([]string{prefix}, parts...)
+	}
+	return strings.ToUpper(strings.Join(parts, "_"))
+}
+
+// flagName is the flag tag or the path joined by dots, e.g. server.addr
+func (l leaf) flagName() string {
+	if name := l.field.Tag.Get("flag"); name != "" {
+		return name
+	}
+	return strings.Join(l.path, ".")
+}
+
+// key names a field in the layers
+func key(f reflect.StructField) (string, bool) {
+	name := f.Tag.Get("config")
+	if name == "-" || !f.IsExported() {
+		return "", false
+	}
+	if name == "" {
+		name = stringx.CamelToSnake(f.Name)
+	}
+	return name, true
+}
+
+// nested reports whether the fields of t are loaded one by one
+func nested(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !reflect.PtrTo(t).Implements(textUnmarshaler)
+}
+
+func collectLeaves(t reflect.Type, path []string) []leaf {
+	var leaves []leaf
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := key(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous && nested(f.Type) && f.Tag.Get("config") == "" {
+			leaves = append(leaves, collectLeaves(indirect(f.Type), path)...)
+			continue
+		}
+		p := append(append([]string(nil), path...), name)
+		if nested(f.Type) {
+			leaves = append(leaves, collectLeaves(indirect(f.Type), p)...)
+			continue
+		}
+		leaves = append(leaves, leaf{path: p, field: f})
+	}
+	return leaves
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// lookup returns the value of key in m, ignoring case when it is not there
+// as spelled
+func lookup(m map[string]any, key string) (any, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func decodeStruct(v reflect.Value, tree map[string]any, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := key(f)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		if f.Anonymous && nested(f.Type) && f.Tag.Get("config") == "" {
+			if err := decodeStruct(allocate(fv), tree, path); err != nil {
+				return err
+			}
+			continue
+		}
+		raw, ok := lookup(tree, name)
+		if !ok {
+			continue
+		}
+		p := name
+		if path != "" {
+			p = path + "." + name
+		}
+		if nested(f.Type) {
+			m, ok := raw.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: cannot load %T into %v", p, raw, f.Type)
+			}
+			if err := decodeStruct(allocate(fv), m, p); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setValue(fv, raw); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// allocate returns the struct v holds, allocating it for a nil pointer
+func allocate(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return v.Elem()
+	}
+	return v
+}
+
+// scalar formats numbers without exponent and times as RFC 3339, the
+// strings the parsers below take
+func scalar(raw any) string {
+	switch raw := raw.(type) {
+	case string:
+		return raw
+	case float64:
+		return strconv.FormatFloat(raw, 'f', -1, 64)
+	case time.Time:
+		return raw.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(raw)
+}
+
+func setValue(v reflect.Value, raw any) error {
+	if raw == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	t := v.Type()
+	switch {
+	case t == timeType:
+		if tm, ok := raw.(time.Time); ok {
+			v.Set(reflect.ValueOf(tm))
+			return nil
+		}
+	case t == durationType:
+		d, err := time.ParseDuration(scalar(raw))
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(textUnmarshaler) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(scalar(raw)))
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(t.Elem())
+		if err := setValue(elem.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Interface:
+		rv := reflect.ValueOf(raw)
+		if !rv.Type().AssignableTo(t) {
+			return fmt.Errorf("cannot assign %T to %v", raw, t)
+		}
+		v.Set(rv)
+	case reflect.String:
+		v.SetString(scalar(raw))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(scalar(raw))
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(scalar(raw), 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(scalar(raw), 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(scalar(raw), t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		return setSlice(v, raw)
+	case reflect.Map:
+		return setMap(v, raw)
+	case reflect.Struct:
+		m, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("cannot load %T into %v", raw, t)
+		}
+		return decodeStruct(v, m, "")
+	default:
+		return fmt.Errorf("unsupported type %v", t)
+	}
+	return nil
+}
+
+// setSlice takes a list, or a comma separated string as set by environment
+// variables and flags
+func setSlice(v reflect.Value, raw any) error {
+	var items []any
+	switch raw := raw.(type) {
+	case []any:
+		items = raw
+	case string:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(raw))
+			return nil
+		}
+		if strings.TrimSpace(raw) != "" {
+			for _, item := range strings.Split(raw, ",") {
+				items = append(items, strings.TrimSpace(item))
+			}
+		}
+	default:
+		return fmt.Errorf("cannot load %T into %v", raw, v.Type())
+	}
+	out := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := setValue(out.Index(i), item); err != nil {
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+	v.Set(out)
+	return nil
+}
+
+// setMap takes a map, or "k=v" pairs separated by commas
+func setMap(v reflect.Value, raw any) error {
+	m, ok := raw.(map[string]any)
+	if s, isString := raw.(string); isString {
+		m, ok = make(map[string]any), true
+		for _, pair := range strings.Split(s, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			k, val, found := strings.Cut(pair, "=")
+			if !found {
+				return errors.New("map entries must be written key=value")
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(val)
+		}
+	}
+	if !ok {
+		return fmt.Errorf("cannot load %T into %v", raw, v.Type())
+	}
+	t := v.Type()
+	out := reflect.MakeMapWithSize(t, len(m))
+	for k, item := range m {
+		kv := reflect.New(t.Key()).Elem()
+		if err := setValue(kv, k); err != nil {
+			return fmt.Errorf("key %q: %w", k, err)
+		}
+		ev := reflect.New(t.Elem()).Elem()
+		if err := setValue(ev, item); err != nil {
+			return fmt.Errorf("[%s]: %w", k, err)
+		}
+		out.SetMapIndex(kv, ev)
+	}
+	v.Set(out)
+	return nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"flag"
+	"reflect"
+)
+
+// flagValue records the string of a flag defined for a field, the field
+// is set from it like from an environment variable
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *flagValue) Set(s string) error {
+	f.value = s
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// parseFlags defines the missing flags, parses the arguments once and sets
+// the flags given on the command line in tree
+func (c *config) parseFlags(leaves []leaf, tree map[string]any) error {
+	byName := make(map[string]leaf, len(leaves))
+	for _, l := range leaves {
+		name := l.flagName()
+		byName[name] = l
+		if c.flags.Lookup(name) != nil {
+			continue
+		}
+		value := &flagValue{isBool: l.field.Type.Kind() == reflect.Bool}
+		value.value = l.field.Tag.Get("default")
+		c.flags.Var(value, name, l.field.Tag.Get("usage"))
+	}
+	if !c.flags.Parsed() {
+		if err := c.flags.Parse(c.args); err != nil {
+			return err
+		}
+	}
+	c.flags.Visit(func(f *flag.Flag) {
+		if l, ok := byName[f.Name]; ok {
+			setPath(tree, l.path, f.Value.String())
+		}
+	})
+	return nil
+}
@@ -0,0 +1,506 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// tomlParser reads the TOML files of the configuration: tables, arrays of
+// tables, dotted keys, strings, numbers, booleans, dates, arrays and inline
+// tables. Dates become time.Time, local dates and times without offset are
+// kept as strings.
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+	root map[string]any
+	// defined tracks the tables declared by a header, they may not repeat
+	defined map[string]bool
+}
+
+func parseTOML(data []byte) (map[string]any, error) {
+	p := &tomlParser{s: string(data), line: 1, root: make(map[string]any), defined: make(map[string]bool)}
+	if err := p.parse(); err != nil {
+		return nil, fmt.Errorf("toml: line %d: %w", p.line, err)
+	}
+	return p.root, nil
+}
+
+func (p *tomlParser) parse() error {
+	current := p.root
+	for {
+		p.skipBlank(true)
+		if p.pos >= len(p.s) {
+			return nil
+		}
+		var err error
+		if p.s[p.pos] == '[' {
+			current, err = p.parseHeader()
+		} else {
+			err = p.parseKeyValue(current)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// skipBlank skips spaces, tabs and comments, and newlines when asked
+func (p *tomlParser) skipBlank(newlines bool) {
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		case newlines && (c == '\n' || c == '\r'):
+			if c == '\n' {
+				p.line++
+			}
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) endOfLine() error {
+	p.skipBlank(false)
+	if p.pos >= len(p.s) {
+		return nil
+	}
+	if strings.HasPrefix(p.s[p.pos:], "\r\n") || p.s[p.pos] == '\n' {
+		return nil
+	}
+	return fmt.Errorf("unexpected %q after value", p.s[p.pos])
+}
+
+func (p *tomlParser) parseHeader() (map[string]any, error) {
+	array := strings.HasPrefix(p.s[p.pos:], "[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	p.skipBlank(false)
+	keys, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.s[p.pos:], closing) {
+		return nil, fmt.Errorf("missing %s after table name", closing)
+	}
+	p.pos += len(closing)
+
+	table := p.root
+	for _, k := range keys[:len(keys)-1] {
+		if table, err = descend(table, k); err != nil {
+			return nil, err
+		}
+	}
+	last := keys[len(keys)-1]
+	if array {
+		list, ok := table[last].([]any)
+		if _, exists := table[last]; exists && !ok {
+			return nil, fmt.Errorf("%s is not an array of tables", strings.Join(keys, "."))
+		}
+		next := make(map[string]any)
+		table[last] = append(list, next)
+		return next, nil
+	}
+	name := strings.Join(keys, "\x00")
+	if p.defined[name] {
+		return nil, fmt.Errorf("table %s defined twice", strings.Join(keys, "."))
+	}
+	p.defined[name] = true
+	return descend(table, last)
+}
+
+// descend returns the table at key of table, creating it, or the last table
+// of an array of tables
+func descend(table map[string]any, key string) (map[string]any, error) {
+	switch v := table[key].(type) {
+	case nil:
+		next := make(map[string]any)
+		table[key] = next
+		return next, nil
+	case map[string]any:
+		return v, nil
+	case []any:
+		if len(v) > 0 {
+			if last, ok := v[len(v)-1].(map[string]any); ok {
+				return last, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("key %s is not a table", key)
+}
+
+func (p *tomlParser) parseKeyValue(table map[string]any) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+		return fmt.Errorf("missing = after key %s", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipBlank(false)
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys[:len(keys)-1] {
+		if table, err = descend(table, k); err != nil {
+			return err
+		}
+	}
+	last := keys[len(keys)-1]
+	if _, exists := table[last]; exists {
+		return fmt.Errorf("key %s defined twice", strings.Join(keys, "."))
+	}
+	table[last] = value
+	return nil
+}
+
+// parseKey reads bare, quoted and dotted keys and the blanks after them
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipBlank(false)
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("missing key")
+		}
+		var k string
+		switch p.s[p.pos] {
+		case '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		case '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && isBareKeyChar(p.s[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, fmt.Errorf("unexpected %q in key", p.s[p.pos])
+			}
+			k = p.s[start:p.pos]
+		}
+		keys = append(keys, k)
+		p.skipBlank(false)
+		if p.pos < len(p.s) && p.s[p.pos] == '.' {
+			p.pos++
+			continue
+		}
+		return keys, nil
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (any, error) {
+	if p.pos >= len(p.s) {
+		return nil, fmt.Errorf("missing value")
+	}
+	switch p.s[p.pos] {
+	case '"':
+		if strings.HasPrefix(p.s[p.pos:], `"""`) {
+			return p.parseMultilineBasicString()
+		}
+		return p.parseBasicString()
+	case '\'':
+		if strings.HasPrefix(p.s[p.pos:], "'''") {
+			return p.parseMultilineLiteralString()
+		}
+		return p.parseLiteralString()
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(",]}#\r\n", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	token := strings.TrimRight(p.s[start:p.pos], " \t")
+	// trailing blanks belong to the line, not the value
+	p.pos = start + len(token)
+	return parseTOMLScalar(token)
+}
+
+func parseTOMLScalar(token string) (any, error) {
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	case "":
+		return nil, fmt.Errorf("missing value")
+	}
+	digits := strings.ReplaceAll(token, "_", "")
+	// leading zeros are not allowed, "0" itself is
+	unsigned := strings.TrimLeft(digits, "+-")
+	if n, err := strconv.ParseInt(digits, 10, 64); err == nil && (unsigned == "0" || !strings.HasPrefix(unsigned, "0")) {
+		return n, nil
+	}
+	for _, prefix := range []string{"0x", "0o", "0b"} {
+		if strings.HasPrefix(digits, prefix) {
+			if n, err := strconv.ParseInt(digits, 0, 64); err == nil {
+				return n, nil
+			}
+		}
+	}
+	if strings.ContainsAny(digits, ".eE") && !strings.ContainsAny(digits, "xX:") {
+		if f, err := strconv.ParseFloat(digits, 64); err == nil {
+			return f, nil
+		}
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05Z07:00", "2006-01-02 15:04:05.999999999Z07:00"} {
+		if t, err := time.Parse(layout, token); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02", "15:04:05"} {
+		if _, err := time.Parse(layout, strings.SplitN(token, ".", 2)[0]); err == nil {
+			return token, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid value %q", token)
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\n':
+			return "", fmt.Errorf("newline in string")
+		case '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func (p *tomlParser) parseMultilineBasicString() (string, error) {
+	p.pos += 3
+	p.skipNewline()
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		if strings.HasPrefix(p.s[p.pos:], `"""`) {
+			// up to two quotes may end the content
+			for strings.HasPrefix(p.s[p.pos+1:], `"""`) && p.pos+3 < len(p.s) {
+				b.WriteByte('"')
+				p.pos++
+			}
+			p.pos += 3
+			return b.String(), nil
+		}
+		c := p.s[p.pos]
+		switch {
+		case c == '\\' && p.lineEndingBackslash():
+			p.skipBlank(true)
+		case c == '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// lineEndingBackslash reports whether the backslash at pos is followed by
+// blanks up to the end of the line, it then trims the following whitespace
+func (p *tomlParser) lineEndingBackslash() bool {
+	i := p.pos + 1
+	for i < len(p.s) && (p.s[i] == ' ' || p.s[i] == '\t' || p.s[i] == '\r') {
+		i++
+	}
+	if i < len(p.s) && p.s[i] == '\n' {
+		p.pos = i
+		return true
+	}
+	return false
+}
+
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+	if p.pos+1 >= len(p.s) {
+		return fmt.Errorf("unterminated escape")
+	}
+	c := p.s[p.pos+1]
+	p.pos += 2
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.s) {
+			return fmt.Errorf("short unicode escape")
+		}
+		code, err := strconv.ParseUint(p.s[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return fmt.Errorf("invalid unicode escape %q", p.s[p.pos:p.pos+n])
+		}
+		b.WriteRune(rune(code))
+		p.pos += n
+	default:
+		return fmt.Errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.s[p.pos:], "'\n")
+	if end < 0 || p.s[p.pos+end] != '\'' {
+		return "", fmt.Errorf("unterminated string")
+	}
+	s := p.s[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+func (p *tomlParser) parseMultilineLiteralString() (string, error) {
+	p.pos += 3
+	p.skipNewline()
+	end := strings.Index(p.s[p.pos:], "'''")
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string")
+	}
+	for p.pos+end+3 < len(p.s) && p.s[p.pos+end+3] == '\'' {
+		end++
+	}
+	s := p.s[p.pos : p.pos+end]
+	p.line += strings.Count(s, "\n")
+	p.pos += end + 3
+	return s, nil
+}
+
+// skipNewline drops the newline right after the opening quotes
+func (p *tomlParser) skipNewline() {
+	if strings.HasPrefix(p.s[p.pos:], "\r\n") {
+		p.pos += 2
+		p.line++
+	} else if strings.HasPrefix(p.s[p.pos:], "\n") {
+		p.pos++
+		p.line++
+	}
+}
+
+func (p *tomlParser) parseArray() ([]any, error) {
+	p.pos++
+	list := []any{}
+	for {
+		p.skipBlank(true)
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		if p.s[p.pos] == ']' {
+			p.pos++
+			return list, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+		p.skipBlank(true)
+		if p.pos < len(p.s) && p.s[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.s) && p.s[p.pos] == ']' {
+			p.pos++
+			return list, nil
+		}
+		return nil, fmt.Errorf("missing , in array")
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (map[string]any, error) {
+	p.pos++
+	table := make(map[string]any)
+	p.skipBlank(false)
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unterminated inline table")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, fmt.Errorf("missing , in inline table")
+		}
+	}
+}
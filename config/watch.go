@@ -0,0 +1,121 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Watcher holds settings reloaded when their files change. Every reload
+// loads a new T, the values returned by Get are never modified.
+type Watcher[T any] struct {
+	cfg       config
+	mu        sync.RWMutex
+	current   *T
+	stamps    map[string]stamp
+	callbacks []func(prev *T, next *T)
+}
+
+// stamp tells whether a file changed since the last load
+type stamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// NewWatcher loads the settings like Load, call Watch to reload them on
+// changes
+func NewWatcher[T any](opts ...Option) (*Watcher[T], error) {
+	cfg, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher[T]{cfg: cfg}
+	stamps := w.stat()
+	current := new(T)
+	if err := cfg.load(current); err != nil {
+		return nil, err
+	}
+	w.current, w.stamps = current, stamps
+	return w, nil
+}
+
+// Get returns the current settings
+func (w *Watcher[T]) Get() *T {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// OnChange calls fn after every reload that changed the settings
+func (w *Watcher[T]) OnChange(fn func(prev *T, next *T)) {
+	w.mu.Lock()
+	w.callbacks = append(w.callbacks, fn)
+	w.mu.Unlock()
+}
+
+// Reload loads the settings again, e.g. on SIGHUP to pick up changed
+// environment variables. On failure the current settings are kept.
+func (w *Watcher[T]) Reload() error {
+	stamps := w.stat()
+	next := new(T)
+	if err := w.cfg.load(next); err != nil {
+		w.mu.Lock()
+		w.stamps = stamps
+		w.mu.Unlock()
+		w.cfg.logger.Error("config reload failed", "err", err)
+		return err
+	}
+	w.mu.Lock()
+	old := w.current
+	w.stamps = stamps
+	changed := !reflect.DeepEqual(old, next)
+	if changed {
+		w.current = next
+	}
+	callbacks := append([]func(*T, *T){}, w.callbacks...)
+	w.mu.Unlock()
+	if changed {
+		w.cfg.logger.Info("config reloaded")
+		for _, fn := range callbacks {
+			fn(old, next)
+		}
+	}
+	return nil
+}
+
+// Watch checks the files every reload interval until ctx is done and
+// reloads when one changed, appeared or disappeared
+func (w *Watcher[T]) Watch(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.mu.RLock()
+			last := w.stamps
+			w.mu.RUnlock()
+			if !reflect.DeepEqual(last, w.stat()) {
+				// failures are logged and retried at the next change
+				_ = w.Reload()
+			}
+		}
+	}
+}
+
+func (w *Watcher[T]) stat() map[string]stamp {
+	stamps := make(map[string]stamp, len(w.cfg.files))
+	for _, src := range w.cfg.files {
+		info, err := os.Stat(src.path)
+		if err != nil {
+			stamps[src.path] = stamp{}
+			continue
+		}
+		stamps[src.path] = stamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+	}
+	return stamps
+}
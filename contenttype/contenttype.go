@@ -0,0 +1,81 @@
+// Package contenttype builds and matches Content-Type values:
+//
+//	req.Header.Set("Content-Type", contenttype.JSON.WithCharset("utf-8"))
+//	if contenttype.JSON.Is(resp.Header.Get("Content-Type")) {
+package contenttype
+
+import (
+	"mime"
+	"strings"
+)
+
+// Type is a media type without parameters, e.g. "application/json"
+type Type string
+
+const (
+	JSON        Type = "application/json"
+	NDJSON      Type = "application/x-ndjson"
+	XML         Type = "application/xml"
+	YAML        Type = "application/yaml"
+	Protobuf    Type = "application/x-protobuf"
+	Form        Type = "application/x-www-form-urlencoded"
+	Multipart   Type = "multipart/form-data"
+	OctetStream Type = "application/octet-stream"
+	ProblemJSON Type = "application/problem+json"
+	EventStream Type = "text/event-stream"
+	Text        Type = "text/plain"
+	HTML        Type = "text/html"
+	CSV         Type = "text/csv"
+)
+
+func (t Type) String() string {
+	return string(t)
+}
+
+// WithCharset returns the Content-Type value of t with a charset parameter
+func (t Type) WithCharset(charset string) string {
+	return t.With(map[string]string{"charset": charset})
+}
+
+// With returns the Content-Type value of t with params, e.g. the boundary of
+// Multipart, t alone for invalid ones
+func (t Type) With(params map[string]string) string {
+	if value := mime.FormatMediaType(string(t), params); value != "" {
+		return value
+	}
+	return string(t)
+}
+
+// Is reports whether the Content-Type value matches t, ignoring case and
+// parameters
+func (t Type) Is(value string) bool {
+	mediaType, _, ok := Parse(value)
+	return ok && mediaType == Type(strings.ToLower(string(t)))
+}
+
+// Suffix returns the structured syntax suffix of t, e.g. "json" for
+// "application/problem+json", "" without one
+func (t Type) Suffix() string {
+	_, sub, _ := strings.Cut(string(t), "/")
+	if i := strings.LastIndexByte(sub, '+'); i >= 0 {
+		return sub[i+1:]
+	}
+	return ""
+}
+
+// Parse splits a Content-Type value into its lower case media type and its
+// parameters
+func Parse(value string) (Type, map[string]string, bool) {
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return "", nil, false
+	}
+	return Type(mediaType), params, true
+}
+
+// Charset returns the charset parameter of a Content-Type value, "" without
+// one
+func Charset(value string) string {
+	_, params, _ := Parse(value)
+	return strings.ToLower(params["charset"])
+}
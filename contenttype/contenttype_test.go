@@ -0,0 +1,52 @@
+package contenttype
+
+import "testing"
+
+func TestWithCharset(t *testing.T) {
+	if got := JSON.WithCharset("utf-8"); got != "application/json; charset=utf-8" {
+		t.Errorf("WithCharset() got = %v, want %v", got, "application/json; charset=utf-8")
+	}
+	if got := Multipart.With(map[string]string{"boundary": "a b"}); got != `multipart/form-data; boundary="a b"` {
+		t.Errorf("With() got = %v, want %v", got, `multipart/form-data; boundary="a b"`)
+	}
+	if got := Text.With(map[string]string{"bad key": "x"}); got != "text/plain" {
+		t.Errorf("With() got = %v, want %v", got, "text/plain")
+	}
+}
+
+func TestIs(t *testing.T) {
+	tests := []struct {
+		t     Type
+		value string
+		want  bool
+	}{
+		{JSON, "application/json", true},
+		{JSON, "Application/JSON; charset=UTF-8", true},
+		{JSON, "application/problem+json", false},
+		{HTML, "text/plain", false},
+		{HTML, "", false},
+	}
+	for _, tt := range tests {
+		if got := tt.t.Is(tt.value); got != tt.want {
+			t.Errorf("%v.Is(%q) got = %v, want %v", tt.t, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSuffix(t *testing.T) {
+	if got := ProblemJSON.Suffix(); got != "json" {
+		t.Errorf("Suffix() got = %v, want %v", got, "json")
+	}
+	if got := JSON.Suffix(); got != "" {
+		t.Errorf("Suffix() got = %v, want empty", got)
+	}
+}
+
+func TestCharset(t *testing.T) {
+	if got := Charset("text/html; charset=ISO-8859-1"); got != "iso-8859-1" {
+		t.Errorf("Charset() got = %v, want %v", got, "iso-8859-1")
+	}
+	if got := Charset("text/html"); got != "" {
+		t.Errorf("Charset() got = %v, want empty", got)
+	}
+}
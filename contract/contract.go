@@ -0,0 +1,182 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Contract is the shared definition of the interactions between a consumer and a provider
+type Contract struct {
+	Name         string        `json:"name" yaml:"name"`
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Interaction is one request and the response the provider answers with
+type Interaction struct {
+	Description string   `json:"description" yaml:"description"`
+	Request     Request  `json:"request" yaml:"request"`
+	Response    Response `json:"response" yaml:"response"`
+}
+
+// Request lists what a request must carry, Query and Headers are matched as subsets
+// and Body as a JSON document whose objects may hold extra fields.
+type Request struct {
+	Method  string            `json:"method" yaml:"method"`
+	Path    string            `json:"path" yaml:"path"`
+	Query   map[string]string `json:"query,omitempty" yaml:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body    any               `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Response is what the provider answers, matched the same way as Request
+type Response struct {
+	Status  int               `json:"status" yaml:"status"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body    any               `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Load reads a contract from a .json, .yaml or .yml file
+func Load(path string) (Contract, error) {
+	var c Contract
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &c)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &c)
+	default:
+		err = fmt.Errorf("contract: unsupported file type %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return c, err
+	}
+	return c, c.validate()
+}
+
+func (c Contract) validate() error {
+	for i, interaction := range c.Interactions {
+		if interaction.Request.Method == "" || interaction.Request.Path == "" {
+			return fmt.Errorf("contract: interaction %d (%s) needs a method and a path", i, interaction.Description)
+		}
+		if interaction.Response.Status == 0 {
+			return fmt.Errorf("contract: interaction %d (%s) needs a response status", i, interaction.Description)
+		}
+	}
+	return nil
+}
+
+// TestingT is the subset of testing.TB used by this package
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// matchSubset checks every expected key is present in actual with the same value
+func matchSubset(kind string, expected, actual map[string]string) error {
+	keys := make([]string, 0, len(expected))
+	for k := range expected {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if got, ok := actual[k]; !ok || got != expected[k] {
+			return fmt.Errorf("%s %q got = %q, want %q", kind, k, got, expected[k])
+		}
+	}
+	return nil
+}
+
+// matchBody compares JSON documents, objects in actual may hold fields expected does not list
+func matchBody(expected, actual any) error {
+	if expected == nil {
+		return nil
+	}
+	want, err := normalize(expected)
+	if err != nil {
+		return err
+	}
+	got, err := normalize(actual)
+	if err != nil {
+		return err
+	}
+	if !matchValue(want, got) {
+		wantJSON, _ := json.Marshal(want)
+		gotJSON, _ := json.Marshal(got)
+		return fmt.Errorf("body got = %s, want %s", gotJSON, wantJSON)
+	}
+	return nil
+}
+
+func matchValue(want, got any) bool {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range w {
+			if !matchValue(v, g[k]) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !matchValue(w[i], g[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(want, got)
+	}
+}
+
+// normalize turns Go and YAML values into their generic JSON form, raw bytes are decoded as JSON
+func normalize(v any) (any, error) {
+	var data []byte
+	switch b := v.(type) {
+	case []byte:
+		data = b
+	case json.RawMessage:
+		data = b
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		// not JSON, compare as text
+		return string(data), nil
+	}
+	return out, nil
+}
+
+func flatten(values map[string][]string) map[string]string {
+	flat := make(map[string]string, len(values))
+	for k, v := range values {
+		if len(v) > 0 {
+			flat[k] = v[0]
+		}
+	}
+	return flat
+}
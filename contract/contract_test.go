@@ -0,0 +1,91 @@
+package contract
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (*fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestLoad(t *testing.T) {
+	c, err := Load("testdata/users.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if c.Name != "users" || len(c.Interactions) != 3 {
+		t.Errorf("Load() got = %+v, want 3 users interactions", c)
+	}
+	if _, err := Load("testdata/missing.toml"); err == nil {
+		t.Errorf("Load() error = nil, want error")
+	}
+}
+
+func TestServerAndVerifyProvider(t *testing.T) {
+	c, err := Load("testdata/users.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	server := NewServer(c)
+	defer server.Close()
+
+	VerifyProvider(t, c, server.URL)
+	server.Verify(t)
+}
+
+func TestVerifyProviderMismatch(t *testing.T) {
+	c, err := Load("testdata/users.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/1":
+			_, _ = w.Write([]byte(`{"id":1,"name":"mallory","extra":true}`))
+		case "/users":
+			w.Header().Set("Location", "/users/2")
+			_, _ = w.Write([]byte(`{"id":2}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer provider.Close()
+
+	ft := &fakeT{}
+	VerifyProvider(ft, c, provider.URL)
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], `"get user"`) {
+		t.Errorf("VerifyProvider() got errors = %v, want one for get user", ft.errors)
+	}
+}
+
+func TestServerVerify(t *testing.T) {
+	c, err := Load("testdata/users.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	server := NewServer(c)
+	defer server.Close()
+
+	code, _, _, _ := gohttp.Post(server.URL+"/users", nil, nil, map[string]string{"name": "eve"})
+	if code != http.StatusInternalServerError {
+		t.Errorf("Post() got = %v, want %v", code, http.StatusInternalServerError)
+	}
+	ft := &fakeT{}
+	server.Verify(ft)
+	// one unmatched request plus three interactions never requested
+	if len(ft.errors) != 4 {
+		t.Errorf("Verify() got errors = %v, want 4", ft.errors)
+	}
+}
@@ -0,0 +1,110 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Server is a mock provider that answers only the requests the contract describes
+type Server struct {
+	*httptest.Server
+
+	contract   Contract
+	mu         sync.Mutex
+	hits       []int
+	mismatches []string
+}
+
+// NewServer starts a mock provider for c, Close it when done
+func NewServer(c Contract) *Server {
+	s := &Server{
+		contract: c,
+		hits:     make([]int, len(c.Interactions)),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var reasons []string
+	for i, interaction := range s.contract.Interactions {
+		if err := matchRequest(interaction.Request, r, body); err != nil {
+			reasons = append(reasons, interaction.Description+": "+err.Error())
+			continue
+		}
+		s.mu.Lock()
+		s.hits[i]++
+		s.mu.Unlock()
+		writeResponse(w, interaction.Response)
+		return
+	}
+	mismatch := fmt.Sprintf("no interaction matches %s %s (%s)", r.Method, r.URL.RequestURI(), strings.Join(reasons, "; "))
+	s.mu.Lock()
+	s.mismatches = append(s.mismatches, mismatch)
+	s.mu.Unlock()
+	http.Error(w, mismatch, http.StatusInternalServerError)
+}
+
+// Verify reports requests that matched no interaction and interactions that were never exercised
+func (s *Server) Verify(t TestingT) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, mismatch := range s.mismatches {
+		t.Errorf("contract %s: %s", s.contract.Name, mismatch)
+	}
+	for i, hits := range s.hits {
+		if hits == 0 {
+			t.Errorf("contract %s: interaction %q was never requested", s.contract.Name, s.contract.Interactions[i].Description)
+		}
+	}
+}
+
+func matchRequest(expected Request, r *http.Request, body []byte) error {
+	if !strings.EqualFold(expected.Method, r.Method) {
+		return fmt.Errorf("method got = %s, want %s", r.Method, expected.Method)
+	}
+	if expected.Path != r.URL.Path {
+		return fmt.Errorf("path got = %s, want %s", r.URL.Path, expected.Path)
+	}
+	if err := matchSubset("query", expected.Query, flatten(r.URL.Query())); err != nil {
+		return err
+	}
+	if err := matchSubset("header", canonicalHeaders(expected.Headers), flatten(r.Header)); err != nil {
+		return err
+	}
+	return matchBody(expected.Body, body)
+}
+
+func writeResponse(w http.ResponseWriter, response Response) {
+	for k, v := range response.Headers {
+		w.Header().Set(k, v)
+	}
+	var body []byte
+	switch b := response.Body.(type) {
+	case nil:
+	case string:
+		body = []byte(b)
+	default:
+		body, _ = json.Marshal(b)
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+	}
+	w.WriteHeader(response.Status)
+	_, _ = w.Write(body)
+}
+
+func canonicalHeaders(headers map[string]string) map[string]string {
+	canonical := make(map[string]string, len(headers))
+	for k, v := range headers {
+		canonical[http.CanonicalHeaderKey(k)] = v
+	}
+	return canonical
+}
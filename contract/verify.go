@@ -0,0 +1,70 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+// VerifyProvider replays every interaction against baseURL through the http
+// package and reports responses that break the contract. The http package
+// drops the body of non-200 responses, so only their status and headers are checked.
+func VerifyProvider(t TestingT, c Contract, baseURL string) {
+	t.Helper()
+	for _, interaction := range c.Interactions {
+		if err := verifyInteraction(interaction, strings.TrimSuffix(baseURL, "/")); err != nil {
+			t.Errorf("contract %s: interaction %q: %v", c.Name, interaction.Description, err)
+		}
+	}
+}
+
+func verifyInteraction(interaction Interaction, baseURL string) error {
+	ctx := context.Background()
+	req := interaction.Request
+	url := baseURL + req.Path
+
+	var (
+		code   int
+		header http.Header
+		data   any
+		err    error
+	)
+	switch strings.ToUpper(req.Method) {
+	case http.MethodGet:
+		code, header, data, err = gohttp.GetWithContext(ctx, url, req.Headers, req.Query)
+	case http.MethodPost:
+		code, header, data, err = gohttp.PostWithContext(ctx, url, req.Headers, req.Query, req.Body)
+	case http.MethodPut:
+		code, header, data, err = gohttp.PutWithContext(ctx, url, req.Headers, req.Query, req.Body)
+	case http.MethodPatch:
+		code, header, data, err = gohttp.PatchWithContext(ctx, url, req.Headers, req.Query, req.Body)
+	case http.MethodDelete:
+		code, header, data, err = gohttp.DeleteWithContext(ctx, url, req.Headers, req.Query, nil)
+	default:
+		return fmt.Errorf("unsupported method %s", req.Method)
+	}
+	if code <= 0 {
+		return err
+	}
+
+	expected := interaction.Response
+	if code != expected.Status {
+		return fmt.Errorf("status got = %d, want %d", code, expected.Status)
+	}
+	if err := matchSubset("header", canonicalHeaders(expected.Headers), flatten(header)); err != nil {
+		return err
+	}
+	if code == http.StatusOK {
+		if s, ok := expected.Body.(string); ok {
+			if got := string(data.([]byte)); got != s {
+				return fmt.Errorf("body got = %q, want %q", got, s)
+			}
+			return nil
+		}
+		return matchBody(expected.Body, data)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+// Package convert converts loosely typed values, such as the any values the
+// http client decodes, to the types code expects: scalars with ToString,
+// ToInt, ToFloat and ToBool, structs to maps and back, deep copies and
+// pointers to values.
+//
+//	_, _, data, err := http.Get(url, nil, nil)
+//	user := data.(map[string]any)
+//	id, err := convert.ToInt(user["id"]) // 42 from 42.0, "42" or json.Number("42")
+package convert
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Stellar1999/gotool/mathx"
+)
+
+// ErrInvalid is returned for values that have no sensible conversion
+var ErrInvalid = errors.New("convert: invalid conversion")
+
+func invalid(v any, to string) error {
+	return fmt.Errorf("%w: %T %v to %s", ErrInvalid, v, v, to)
+}
+
+// indirect follows pointers and interfaces, a nil one gives an invalid Value
+func indirect(v any) reflect.Value {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// ToString converts v to a string: strings and []byte as they are, numbers
+// in their shortest decimal form, times as RFC 3339, and values implementing
+// fmt.Stringer, error or encoding.TextMarshaler with their method. nil is "".
+func ToString(v any) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return x, nil
+	case []byte:
+		return string(x), nil
+	case json.Number:
+		return string(x), nil
+	case time.Time:
+		return x.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return x.String(), nil
+	case error:
+		return x.Error(), nil
+	case encoding.TextMarshaler:
+		text, err := x.MarshalText()
+		return string(text), err
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "", nil
+		}
+		return ToString(rv.Elem().Interface())
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	}
+	return "", invalid(v, "string")
+}
+
+// ToInt64 converts v to an int64: integers that fit it, floats without a
+// fraction, numeric strings such as " 42 " or "42.0", and booleans as 0 and
+// 1. nil and "" are 0. Durations give their nanoseconds.
+func ToInt64(v any) (int64, error) {
+	switch x := v.(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		return ToInt64(string(x))
+	}
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return 0, nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mathx.Convert[int64](rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) {
+			return 0, invalid(v, "int64")
+		}
+		return mathx.FromFloat[int64](f)
+	case reflect.String:
+		s := strings.TrimSpace(rv.String())
+		if s == "" {
+			return 0, nil
+		}
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return ToInt64(f)
+		}
+	}
+	return 0, invalid(v, "int64")
+}
+
+// ToInt converts v to an int like ToInt64 does
+func ToInt(v any) (int, error) {
+	n, err := ToInt64(v)
+	if err != nil {
+		return 0, err
+	}
+	return mathx.Convert[int](n)
+}
+
+// ToFloat converts v to a float64: numbers, numeric strings and booleans as
+// 0 and 1. nil and "" are 0.
+func ToFloat(v any) (float64, error) {
+	switch x := v.(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		return ToFloat(string(x))
+	case mathx.Decimal:
+		return x.Float64(), nil
+	}
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return 0, nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		s := strings.TrimSpace(rv.String())
+		if s == "" {
+			return 0, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, invalid(v, "float64")
+}
+
+// ToBool converts v to a bool: numbers are true unless 0, strings such as
+// "true", "1", "yes", "on" and "y" are true and "false", "0", "no", "off",
+// "n" and "" false, in any case. nil is false.
+func ToBool(v any) (bool, error) {
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return false, nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() != 0, nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() != 0, nil
+	case reflect.String:
+		switch strings.ToLower(strings.TrimSpace(rv.String())) {
+		case "true", "1", "yes", "on", "y", "t":
+			return true, nil
+		case "false", "0", "no", "off", "n", "f", "":
+			return false, nil
+		}
+	}
+	return false, invalid(v, "bool")
+}
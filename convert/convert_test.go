@@ -0,0 +1,275 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/mathx"
+)
+
+type level int
+
+func TestScalars(t *testing.T) {
+	when := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	n := 7
+	strs := []struct {
+		v    any
+		want string
+	}{
+		{nil, ""},
+		{"a", "a"},
+		{[]byte("b"), "b"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{1.5, "1.5"},
+		{1e21, "1000000000000000000000"},
+		{float32(0.1), "0.1"},
+		{true, "true"},
+		{json.Number("12"), "12"},
+		{when, "2024-05-01T12:00:00Z"},
+		{time.Second, "1s"},
+		{errors.New("oops"), "oops"},
+		{mathx.NewDecimal(1050, 2), "10.50"},
+		{&n, "7"},
+		{(*int)(nil), ""},
+		{level(3), "3"},
+	}
+	for _, tt := range strs {
+		if got, err := ToString(tt.v); err != nil || got != tt.want {
+			t.Errorf("ToString(%#v) got = %q %v, want %q", tt.v, got, err, tt.want)
+		}
+	}
+	if _, err := ToString([]int{1}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("ToString([]int) error got = %v, want %v", err, ErrInvalid)
+	}
+
+	ints := []struct {
+		v    any
+		want int
+		ok   bool
+	}{
+		{nil, 0, true},
+		{42.0, 42, true},
+		{42.5, 0, false},
+		{" 42 ", 42, true},
+		{"42.0", 42, true},
+		{"", 0, true},
+		{"4x", 0, false},
+		{true, 1, true},
+		{json.Number("-3"), -3, true},
+		{uint64(1 << 63), 0, false},
+		{level(2), 2, true},
+		{&n, 7, true},
+		{1e300, 0, false},
+	}
+	for _, tt := range ints {
+		got, err := ToInt(tt.v)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ToInt(%#v) got = %v %v, want %v", tt.v, got, err, tt.want)
+		}
+	}
+
+	floats := []struct {
+		v    any
+		want float64
+		ok   bool
+	}{
+		{"2.5", 2.5, true},
+		{3, 3, true},
+		{false, 0, true},
+		{json.Number("1e3"), 1000, true},
+		{mathx.NewDecimal(5, 1), 0.5, true},
+		{"x", 0, false},
+	}
+	for _, tt := range floats {
+		got, err := ToFloat(tt.v)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ToFloat(%#v) got = %v %v, want %v", tt.v, got, err, tt.want)
+		}
+	}
+
+	bools := []struct {
+		v    any
+		want bool
+		ok   bool
+	}{
+		{"YES", true, true},
+		{"off", false, true},
+		{" 1 ", true, true},
+		{"", false, true},
+		{0.0, false, true},
+		{-1, true, true},
+		{nil, false, true},
+		{"maybe", false, false},
+	}
+	for _, tt := range bools {
+		got, err := ToBool(tt.v)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ToBool(%#v) got = %v %v, want %v", tt.v, got, err, tt.want)
+		}
+	}
+}
+
+type Base struct {
+	ID      int       `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty" db:"postal_code"`
+}
+
+type account struct {
+	Base
+	Name     string             `json:"name"`
+	Email    *string            `json:"email,omitempty"`
+	Age      uint8              `json:"age"`
+	Active   bool               `json:"active"`
+	Balance  mathx.Decimal      `json:"balance"`
+	Timeout  time.Duration      `json:"timeout"`
+	Home     address            `json:"home"`
+	Others   []address          `json:"others"`
+	Tags     []string           `json:"tags"`
+	Limits   map[string]float64 `json:"limits"`
+	Secret   string             `json:"-"`
+	internal int
+}
+
+func TestStructToMap(t *testing.T) {
+	when := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	a := account{
+		Base:    Base{ID: 1, Created: when},
+		Name:    "ann",
+		Age:     41,
+		Balance: mathx.NewDecimal(1050, 2),
+		Home:    address{City: "Oslo"},
+		Others:  []address{{City: "Rome", Zip: "00100"}},
+		Tags:    []string{"a"},
+		Secret:  "s",
+	}
+	got, err := StructToMap(&a)
+	if err != nil {
+		t.Fatalf("StructToMap() error = %v", err)
+	}
+	want := map[string]any{
+		"id":      1,
+		"created": when,
+		"name":    "ann",
+		"age":     uint8(41),
+		"active":  false,
+		"balance": mathx.NewDecimal(1050, 2),
+		"timeout": time.Duration(0),
+		"home":    map[string]any{"city": "Oslo"},
+		"others":  []any{map[string]any{"city": "Rome", "zip": "00100"}},
+		"tags":    []string{"a"},
+		"limits":  map[string]float64(nil),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StructToMap() got = %#v, want %#v", got, want)
+	}
+	home, _ := StructToMap(a.Others[0], WithTag("db"))
+	if !reflect.DeepEqual(home, map[string]any{"City": "Rome", "postal_code": "00100"}) {
+		t.Errorf("StructToMap(WithTag) got = %v", home)
+	}
+	if _, err := StructToMap(3); !errors.Is(err, ErrInvalid) {
+		t.Errorf("StructToMap(3) error got = %v, want %v", err, ErrInvalid)
+	}
+}
+
+func TestMapToStruct(t *testing.T) {
+	var decoded any
+	_ = json.Unmarshal([]byte(`{
+		"id": 7, "created": "2024-05-01T00:00:00Z", "NAME": "bob", "email": "b@x.io",
+		"age": "41", "active": "yes", "balance": 10.5, "timeout": "1m30s",
+		"home": {"city": "Oslo", "zip": 150}, "others": [{"city": "Rome"}],
+		"tags": ["a", 2], "limits": {"cpu": "1.5"}, "secret": "s", "unknown": 1
+	}`), &decoded)
+	var a account
+	if err := MapToStruct(decoded.(map[string]any), &a); err != nil {
+		t.Fatalf("MapToStruct() error = %v", err)
+	}
+	when := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	want := account{
+		Base:    Base{ID: 7, Created: when},
+		Name:    "bob",
+		Email:   Ptr("b@x.io"),
+		Age:     41,
+		Active:  true,
+		Balance: mathx.MustParseDecimal("10.5"),
+		Timeout: 90 * time.Second,
+		Home:    address{City: "Oslo", Zip: "150"},
+		Others:  []address{{City: "Rome"}},
+		Tags:    []string{"a", "2"},
+		Limits:  map[string]float64{"cpu": 1.5},
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("MapToStruct() got = %+v, want %+v", a, want)
+	}
+
+	errorsTests := []struct {
+		m    map[string]any
+		want string
+	}{
+		{map[string]any{"age": 300}, "field age: mathx: overflow"},
+		{map[string]any{"age": -1}, "field age: mathx: overflow"},
+		{map[string]any{"home": map[string]any{"city": []int{1}}}, "field home.city: convert: invalid conversion"},
+		{map[string]any{"others": []any{1}}, "field others[0]: convert: invalid conversion"},
+		{map[string]any{"timeout": "soon"}, "field timeout:"},
+	}
+	for _, tt := range errorsTests {
+		err := MapToStruct(tt.m, &account{})
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("MapToStruct(%v) error got = %v, want %v", tt.m, err, tt.want)
+		}
+	}
+	if err := MapToStruct(nil, account{}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("MapToStruct() non pointer error got = %v, want %v", err, ErrInvalid)
+	}
+}
+
+type node struct {
+	Name     string
+	Children []*node
+	Parent   *node
+	Meta     map[string]any
+	hidden   *int
+}
+
+func TestDeepCopy(t *testing.T) {
+	n := 1
+	root := &node{Name: "root", Meta: map[string]any{"tags": []string{"a"}}, hidden: &n}
+	child := &node{Name: "child", Parent: root}
+	root.Children = []*node{child}
+
+	cp := DeepCopy(root)
+	if cp == root || cp.Children[0] == child || cp.Children[0].Parent != cp {
+		t.Errorf("DeepCopy() got shared pointers or a broken cycle")
+	}
+	cp.Meta["tags"].([]string)[0] = "changed"
+	cp.Children[0].Name = "changed"
+	if root.Meta["tags"].([]string)[0] != "a" || child.Name != "child" {
+		t.Errorf("DeepCopy() changes reached the original")
+	}
+	if cp.hidden != &n {
+		t.Errorf("DeepCopy() unexported field got = %v, want it shared", cp.hidden)
+	}
+	if got := DeepCopy([]int(nil)); got != nil {
+		t.Errorf("DeepCopy(nil) got = %v", got)
+	}
+	arr := DeepCopy([2][]int{{1}, {2}})
+	if arr[1][0] != 2 {
+		t.Errorf("DeepCopy(array) got = %v", arr)
+	}
+}
+
+func TestPtr(t *testing.T) {
+	p := Ptr(3)
+	if *p != 3 || Val(p, 9) != 3 || Val[int](nil, 9) != 9 {
+		t.Errorf("Ptr() and Val() got wrong result")
+	}
+}
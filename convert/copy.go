@@ -0,0 +1,77 @@
+package convert
+
+import "reflect"
+
+// DeepCopy returns a copy of v sharing no memory with it through pointers,
+// slices, maps and interfaces, e.g. before changing a response cached for
+// other callers. Pointers shared inside v are still shared in the copy and
+// cycles are kept. Unexported struct fields, channels and functions are
+// copied shallowly.
+func DeepCopy[T any](v T) T {
+	rv := reflect.ValueOf(&v).Elem()
+	out := reflect.New(rv.Type()).Elem()
+	deepCopy(out, rv, make(map[uintptr]reflect.Value))
+	return out.Interface().(T)
+}
+
+// deepCopy sets dst to a copy of src, seen maps pointers to their copies
+func deepCopy(dst reflect.Value, src reflect.Value, seen map[uintptr]reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return
+		}
+		if p, ok := seen[src.Pointer()]; ok && p.Type() == src.Type() {
+			dst.Set(p)
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		seen[src.Pointer()] = p
+		deepCopy(p.Elem(), src.Elem(), seen)
+		dst.Set(p)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		elem := reflect.New(src.Elem().Type()).Elem()
+		deepCopy(elem, src.Elem(), seen)
+		dst.Set(elem)
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		out := reflect.MakeSlice(src.Type(), src.Len(), src.Cap())
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(out.Index(i), src.Index(i), seen)
+		}
+		dst.Set(out)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(dst.Index(i), src.Index(i), seen)
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		out := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			k := reflect.New(iter.Key().Type()).Elem()
+			deepCopy(k, iter.Key(), seen)
+			v := reflect.New(iter.Value().Type()).Elem()
+			deepCopy(v, iter.Value(), seen)
+			out.SetMapIndex(k, v)
+		}
+		dst.Set(out)
+	case reflect.Struct:
+		// unexported fields can only be copied with the whole struct
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if src.Type().Field(i).IsExported() {
+				deepCopy(dst.Field(i), src.Field(i), seen)
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,15 @@
+package convert
+
+// Ptr returns a pointer to v, e.g. for optional fields of payloads:
+// Patch{Name: convert.Ptr("new")}
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Val returns the value p points to, or def when p is nil
+func Val[T any](p *T, def T) T {
+	if p == nil {
+		return def
+	}
+	return *p
+}
@@ -0,0 +1,343 @@
+package convert
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Stellar1999/gotool/mathx"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+var (
+	jsonMarshaler   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType    = reflect.TypeOf(time.Duration(0))
+)
+
+type config struct {
+	tag string
+}
+
+type Option = opt.Option[config]
+
+// WithTag names fields by another struct tag than json, e.g. "yaml" or "db".
+// Fields without the tag keep their Go name.
+func WithTag(tag string) Option {
+	return func(c *config) {
+		c.tag = tag
+	}
+}
+
+// field is a struct field with the name it has in maps
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// fields lists the fields of t named by tag. The fields of embedded structs
+// are promoted unless t has a field of the same name.
+func fields(t reflect.Type, tag string) []field {
+	var out []field
+	var promoted []field
+	seen := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		value := f.Tag.Get(tag)
+		if value == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(value, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for _, inner := range fields(f.Type, tag) {
+				inner.index = append([]int{i}, inner.index...)
+				promoted = append(promoted, inner)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		seen[name] = true
+		out = append(out, field{name: name, index: []int{i}, omitEmpty: strings.Contains(","+options+",", ",omitempty,")})
+	}
+	for _, f := range promoted {
+		if !seen[f.name] {
+			seen[f.name] = true
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// StructToMap converts the struct v points to, or v itself, to a map keyed by
+// the json names of its fields. Nested structs, and slices and maps of them,
+// become maps too, but for types encoding themselves such as time.Time.
+// omitempty fields with a zero value are left out.
+func StructToMap(v any, opts ...Option) (map[string]any, error) {
+	cfg := opt.Apply(&config{tag: "json"}, opts...)
+	rv := indirect(v)
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T to map, want a struct", ErrInvalid, v)
+	}
+	return structToMap(rv, cfg), nil
+}
+
+func structToMap(rv reflect.Value, cfg *config) map[string]any {
+	out := make(map[string]any)
+	for _, f := range fields(rv.Type(), cfg.tag) {
+		fv := rv.FieldByIndex(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		out[f.name] = toMapValue(fv, cfg)
+	}
+	return out
+}
+
+// toMapValue returns rv as a map value, converting the structs it holds
+func toMapValue(rv reflect.Value, cfg *config) any {
+	t := rv.Type()
+	if t.Implements(jsonMarshaler) || t.Implements(textMarshaler) {
+		return rv.Interface()
+	}
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return toMapValue(rv.Elem(), cfg)
+	case reflect.Struct:
+		return structToMap(rv, cfg)
+	case reflect.Slice, reflect.Array:
+		if !holdsStructs(t.Elem()) || rv.Kind() == reflect.Slice && rv.IsNil() {
+			return rv.Interface()
+		}
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = toMapValue(rv.Index(i), cfg)
+		}
+		return out
+	case reflect.Map:
+		if !holdsStructs(t.Elem()) || t.Key().Kind() != reflect.String || rv.IsNil() {
+			return rv.Interface()
+		}
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = toMapValue(iter.Value(), cfg)
+		}
+		return out
+	}
+	return rv.Interface()
+}
+
+// holdsStructs reports whether values of t may hold structs to convert
+func holdsStructs(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return !t.Implements(jsonMarshaler) && !t.Implements(textMarshaler)
+	case reflect.Interface:
+		return true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return holdsStructs(t.Elem())
+	}
+	return false
+}
+
+// MapToStruct sets the fields of the struct dst points to from m, matching
+// keys to the json names of the fields exactly or else case-insensitively.
+// Values are converted as ToString, ToInt and the others do, nested maps
+// fill nested structs, strings fill types implementing
+// encoding.TextUnmarshaler and durations. Keys without a field are ignored.
+func MapToStruct(m map[string]any, dst any, opts ...Option) error {
+	cfg := opt.Apply(&config{tag: "json"}, opts...)
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: map to %T, want a pointer to a struct", ErrInvalid, dst)
+	}
+	return assignStruct(rv.Elem(), reflect.ValueOf(m), "", cfg)
+}
+
+func assignStruct(dst reflect.Value, m reflect.Value, path string, cfg *config) error {
+	fs := fields(dst.Type(), cfg.tag)
+	iter := m.MapRange()
+	for iter.Next() {
+		key := fmt.Sprint(iter.Key().Interface())
+		f, ok := lookup(fs, key)
+		if !ok {
+			continue
+		}
+		if err := assign(dst.FieldByIndex(f.index), iter.Value().Interface(), join(path, f.name), cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup finds the field named key like encoding/json does
+func lookup(fs []field, key string) (field, bool) {
+	for _, f := range fs {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fs {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// assign sets dst from src, converting it to the type of dst
+func assign(dst reflect.Value, src any, path string, cfg *config) error {
+	fail := func(err error) error {
+		return fmt.Errorf("field %s: %w", path, err)
+	}
+	t := dst.Type()
+	if src == nil {
+		dst.Set(reflect.Zero(t))
+		return nil
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(t) {
+		dst.Set(sv)
+		return nil
+	}
+	if pt := reflect.PtrTo(t); pt.Implements(jsonUnmarshaler) {
+		data, err := json.Marshal(src)
+		if err == nil {
+			err = json.Unmarshal(data, dst.Addr().Interface())
+		}
+		if err != nil {
+			return fail(err)
+		}
+		return nil
+	} else if pt.Implements(textUnmarshaler) {
+		s, err := ToString(src)
+		if err == nil {
+			err = dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		}
+		if err != nil {
+			return fail(err)
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(t.Elem())
+		if err := assign(p.Elem(), src, path, cfg); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	case reflect.String:
+		s, err := ToString(src)
+		if err != nil {
+			return fail(err)
+		}
+		dst.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, err := ToBool(src)
+		if err != nil {
+			return fail(err)
+		}
+		dst.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s, ok := src.(string); ok && t == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fail(err)
+			}
+			dst.SetInt(int64(d))
+			return nil
+		}
+		n, err := ToInt64(src)
+		if err == nil && dst.OverflowInt(n) {
+			err = fmt.Errorf("%w: %v does not fit %v", mathx.ErrOverflow, n, t)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		dst.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := ToInt64(src)
+		if err == nil && (n < 0 || dst.OverflowUint(uint64(n))) {
+			err = fmt.Errorf("%w: %v does not fit %v", mathx.ErrOverflow, n, t)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		dst.SetUint(uint64(n))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := ToFloat(src)
+		if err != nil {
+			return fail(err)
+		}
+		dst.SetFloat(f)
+		return nil
+	case reflect.Struct:
+		if sv.Kind() == reflect.Map {
+			return assignStruct(dst, sv, path, cfg)
+		}
+		if sv.Kind() == reflect.Struct || sv.Kind() == reflect.Ptr && sv.Elem().Kind() == reflect.Struct {
+			// another struct type converts through its map
+			return assignStruct(dst, reflect.ValueOf(structToMap(reflect.Indirect(sv), cfg)), path, cfg)
+		}
+	case reflect.Slice:
+		if sv.Kind() == reflect.Slice || sv.Kind() == reflect.Array {
+			out := reflect.MakeSlice(t, sv.Len(), sv.Len())
+			for i := 0; i < sv.Len(); i++ {
+				if err := assign(out.Index(i), sv.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i), cfg); err != nil {
+					return err
+				}
+			}
+			dst.Set(out)
+			return nil
+		}
+	case reflect.Map:
+		if sv.Kind() == reflect.Map {
+			out := reflect.MakeMapWithSize(t, sv.Len())
+			iter := sv.MapRange()
+			for iter.Next() {
+				k, v := reflect.New(t.Key()).Elem(), reflect.New(t.Elem()).Elem()
+				key := fmt.Sprint(iter.Key().Interface())
+				if err := assign(k, iter.Key().Interface(), join(path, key), cfg); err != nil {
+					return err
+				}
+				if err := assign(v, iter.Value().Interface(), join(path, key), cfg); err != nil {
+					return err
+				}
+				out.SetMapIndex(k, v)
+			}
+			dst.Set(out)
+			return nil
+		}
+	}
+	return fail(invalid(src, t.String()))
+}
@@ -0,0 +1,423 @@
+// Package crashreport sends errors and panics to a Sentry compatible server.
+package crashreport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	mrand "math/rand"
+	gourl "net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// ErrDSN is returned for DSNs that are not like https://key@host/project
+var ErrDSN = errors.New("crashreport: invalid dsn")
+
+// Level is the severity of an event or breadcrumb
+type Level string
+
+const (
+	LevelDebug   Level = "debug"
+	LevelInfo    Level = "info"
+	LevelWarning Level = "warning"
+	LevelError   Level = "error"
+	LevelFatal   Level = "fatal"
+)
+
+// Breadcrumb is a trail entry sent along with the next events
+type Breadcrumb struct {
+	Time     time.Time
+	Category string
+	Level    Level
+	Message  string
+	Data     map[string]any
+}
+
+type config struct {
+	client         *gohttp.Client
+	release        string
+	environment    string
+	serverName     string
+	tags           map[string]string
+	sampleRate     float64
+	rateLimit      int
+	ratePer        time.Duration
+	maxBreadcrumbs int
+	logger         gohttp.Logger
+	redactor       gohttp.Redactor
+}
+
+type Option = opt.Option[config]
+
+// WithClient sends events with client instead of a new gohttp.Client. When the
+// Reporter is the logger of client, a failed send is captured again.
+func WithClient(client *gohttp.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithRelease tags events with the release, e.g. a version or commit
+func WithRelease(release string) Option {
+	return func(c *config) {
+		c.release = release
+	}
+}
+
+// WithEnvironment tags events with the environment, e.g. "production"
+func WithEnvironment(environment string) Option {
+	return func(c *config) {
+		c.environment = environment
+	}
+}
+
+// WithServerName overrides the host name sent with events
+func WithServerName(name string) Option {
+	return func(c *config) {
+		c.serverName = name
+	}
+}
+
+// WithTags adds tags to every event
+func WithTags(tags map[string]string) Option {
+	return func(c *config) {
+		c.tags = tags
+	}
+}
+
+// WithSampleRate sends only the given fraction of events, 1 by default
+func WithSampleRate(rate float64) Option {
+	return func(c *config) {
+		c.sampleRate = rate
+	}
+}
+
+// WithRateLimit sends at most n events per period, the rest is dropped
+func WithRateLimit(n int, per time.Duration) Option {
+	return func(c *config) {
+		c.rateLimit = n
+		c.ratePer = per
+	}
+}
+
+// WithMaxBreadcrumbs keeps the last n breadcrumbs, 100 by default
+func WithMaxBreadcrumbs(n int) Option {
+	return func(c *config) {
+		c.maxBreadcrumbs = n
+	}
+}
+
+// WithRedactor hides secrets in events before they are sent, messages,
+// exceptions and breadcrumb data included, e.g. with a *redact.Policy
+func WithRedactor(r gohttp.Redactor) Option {
+	return func(c *config) {
+		c.redactor = r
+	}
+}
+
+// WithLogger forwards the log calls of the Reporter to logger
+func WithLogger(logger gohttp.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.sampleRate < 0 || c.sampleRate > 1 {
+			return errors.New("crashreport: sample rate must be between 0 and 1")
+		}
+		if c.rateLimit < 0 || (c.rateLimit > 0 && c.ratePer <= 0) {
+			return errors.New("crashreport: rate limit needs a positive count and period")
+		}
+		return nil
+	},
+}
+
+// Reporter captures errors and panics. It also implements the logger interface
+// of the http package: every log call becomes a breadcrumb and errors logged
+// with an "err" value are captured.
+type Reporter struct {
+	config   config
+	endpoint string
+	dsn      string
+	auth     string
+	random   func() float64
+	now      func() time.Time
+
+	mu           sync.Mutex
+	breadcrumbs  []Breadcrumb
+	windowStart  time.Time
+	windowCount  int
+	blockedUntil time.Time
+
+	sent        int64
+	sampledOut  int64
+	rateLimited int64
+	failed      int64
+}
+
+// New creates a Reporter for dsn, the format is https://<key>@<host>/<project>
+func New(dsn string, opts ...Option) (*Reporter, error) {
+	cfg := config{sampleRate: 1, maxBreadcrumbs: 100}
+	if err := opt.Build(&cfg, opts, checks...); err != nil {
+		return nil, err
+	}
+	if cfg.client == nil {
+		// a failed send must not come back as an error log
+		cfg.client = gohttp.NewClient(gohttp.WithLogger(gohttp.NopLogger))
+	}
+	if cfg.serverName == "" {
+		cfg.serverName, _ = os.Hostname()
+	}
+	u, err := gourl.Parse(dsn)
+	if err != nil || u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrDSN, dsn)
+	}
+	path := strings.Trim(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	project := path[slash+1:]
+	if project == "" {
+		return nil, fmt.Errorf("%w: %q has no project", ErrDSN, dsn)
+	}
+	prefix := ""
+	if slash >= 0 {
+		prefix = "/" + path[:slash]
+	}
+	auth := "Sentry sentry_version=7, sentry_client=gotool-crashreport/1.0, sentry_key=" + u.User.Username()
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	return &Reporter{
+		config:   cfg,
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, project),
+		dsn:      dsn,
+		auth:     auth,
+		random:   mrand.Float64,
+		now:      time.Now,
+	}, nil
+}
+
+// AddBreadcrumb records b, the oldest breadcrumb is dropped when the trail is full
+func (r *Reporter) AddBreadcrumb(b Breadcrumb) {
+	if b.Time.IsZero() {
+		b.Time = r.now()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.breadcrumbs = append(r.breadcrumbs, b)
+	if over := len(r.breadcrumbs) - r.config.maxBreadcrumbs; over > 0 {
+		r.breadcrumbs = append(r.breadcrumbs[:0], r.breadcrumbs[over:]...)
+	}
+}
+
+// CaptureError sends err with the stack of the caller and its wrapped causes,
+// it returns the event id or "" when the event was sampled out or rate limited
+func (r *Reporter) CaptureError(ctx context.Context, err error) (string, error) {
+	if err == nil {
+		return "", nil
+	}
+	return r.capture(ctx, LevelError, "", exceptions(err, callers(3)))
+}
+
+// CaptureMessage sends a message event without stack trace
+func (r *Reporter) CaptureMessage(ctx context.Context, level Level, message string) (string, error) {
+	return r.capture(ctx, level, message, nil)
+}
+
+// Recover captures a panic as a fatal event and panics again, use it deferred:
+//
+//	defer reporter.Recover(ctx)
+func (r *Reporter) Recover(ctx context.Context) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	err, ok := value.(error)
+	if !ok {
+		err = fmt.Errorf("%v", value)
+	}
+	excs := exceptions(err, callers(4))
+	excs[len(excs)-1].Mechanism = &mechanism{Type: "panic", Handled: false}
+	_, _ = r.capture(ctx, LevelFatal, "", excs)
+	panic(value)
+}
+
+// Stats implements stats.Stats
+func (r *Reporter) Stats() map[string]float64 {
+	return map[string]float64{
+		"sent_total":         float64(atomic.LoadInt64(&r.sent)),
+		"sampled_out_total":  float64(atomic.LoadInt64(&r.sampledOut)),
+		"rate_limited_total": float64(atomic.LoadInt64(&r.rateLimited)),
+		"failed_total":       float64(atomic.LoadInt64(&r.failed)),
+	}
+}
+
+func (r *Reporter) capture(ctx context.Context, level Level, message string, excs []exception) (string, error) {
+	if r.random() >= r.config.sampleRate {
+		atomic.AddInt64(&r.sampledOut, 1)
+		return "", nil
+	}
+	if !r.allow() {
+		atomic.AddInt64(&r.rateLimited, 1)
+		return "", nil
+	}
+	e := r.newEvent(level, message, excs)
+	resp, err := r.config.client.NewRequest(gohttp.POST, r.endpoint).
+		WithContext(ctx).
+		RawBody(r.envelope(e)).
+		Header("Content-Type", "application/x-sentry-envelope").
+		Header("X-Sentry-Auth", r.auth).
+		Send()
+	if resp != nil && resp.StatusCode == 429 {
+		r.block(resp.Header.Get("Retry-After"))
+	}
+	if err != nil {
+		atomic.AddInt64(&r.failed, 1)
+		return "", fmt.Errorf("crashreport: sending event %s: %w", e.EventID, err)
+	}
+	atomic.AddInt64(&r.sent, 1)
+	return e.EventID, nil
+}
+
+// allow applies the configured rate limit and the one asked for by the server
+func (r *Reporter) allow() bool {
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Before(r.blockedUntil) {
+		return false
+	}
+	if r.config.rateLimit == 0 {
+		return true
+	}
+	if now.Sub(r.windowStart) >= r.config.ratePer {
+		r.windowStart, r.windowCount = now, 0
+	}
+	if r.windowCount >= r.config.rateLimit {
+		return false
+	}
+	r.windowCount++
+	return true
+}
+
+// block stops sending for the Retry-After seconds of a 429, 60s when it is missing
+func (r *Reporter) block(retryAfter string) {
+	seconds, err := strconv.ParseFloat(retryAfter, 64)
+	if err != nil || seconds <= 0 || math.IsInf(seconds, 0) {
+		seconds = 60
+	}
+	r.mu.Lock()
+	r.blockedUntil = r.now().Add(time.Duration(seconds * float64(time.Second)))
+	r.mu.Unlock()
+}
+
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   float64           `json:"timestamp"`
+	Level       Level             `json:"level"`
+	Platform    string            `json:"platform"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Message     *message          `json:"message,omitempty"`
+	Exception   *exceptionList    `json:"exception,omitempty"`
+	Breadcrumbs *breadcrumbList   `json:"breadcrumbs,omitempty"`
+}
+
+type message struct {
+	Formatted string `json:"formatted"`
+}
+
+type exceptionList struct {
+	Values []exception `json:"values"`
+}
+
+type breadcrumbList struct {
+	Values []breadcrumb `json:"values"`
+}
+
+type breadcrumb struct {
+	Timestamp float64        `json:"timestamp"`
+	Category  string         `json:"category,omitempty"`
+	Level     Level          `json:"level,omitempty"`
+	Message   string         `json:"message,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+func (r *Reporter) newEvent(level Level, msg string, excs []exception) *event {
+	e := &event{
+		EventID:     newEventID(),
+		Timestamp:   unixSeconds(r.now()),
+		Level:       level,
+		Platform:    "go",
+		Release:     r.config.release,
+		Environment: r.config.environment,
+		ServerName:  r.config.serverName,
+		Tags:        r.config.tags,
+	}
+	if msg != "" {
+		e.Message = &message{Formatted: msg}
+	}
+	if len(excs) > 0 {
+		e.Exception = &exceptionList{Values: excs}
+	}
+	r.mu.Lock()
+	if len(r.breadcrumbs) > 0 {
+		e.Breadcrumbs = &breadcrumbList{}
+		for _, b := range r.breadcrumbs {
+			e.Breadcrumbs.Values = append(e.Breadcrumbs.Values, breadcrumb{
+				Timestamp: unixSeconds(b.Time),
+				Category:  b.Category,
+				Level:     b.Level,
+				Message:   b.Message,
+				Data:      b.Data,
+			})
+		}
+	}
+	r.mu.Unlock()
+	return e
+}
+
+// envelope renders the envelope of a single event: a header line, an item
+// header line and the event
+func (r *Reporter) envelope(e *event) []byte {
+	header, _ := json.Marshal(map[string]string{"event_id": e.EventID, "dsn": r.dsn})
+	body, err := json.Marshal(e)
+	if err != nil {
+		// breadcrumb data that cannot be encoded
+		e.Breadcrumbs = nil
+		body, _ = json.Marshal(e)
+	}
+	if r.config.redactor != nil {
+		body = r.config.redactor.Body(body)
+	}
+	item, _ := json.Marshal(map[string]any{"type": "event", "length": len(body)})
+	out := make([]byte, 0, len(header)+len(item)+len(body)+3)
+	out = append(append(out, header...), '\n')
+	out = append(append(out, item...), '\n')
+	return append(append(out, body...), '\n')
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
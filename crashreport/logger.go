@@ -0,0 +1,65 @@
+package crashreport
+
+import (
+	"context"
+	"fmt"
+)
+
+func (r *Reporter) Debug(msg string, keysAndValues ...any) {
+	r.log(LevelDebug, msg, keysAndValues)
+	if r.config.logger != nil {
+		r.config.logger.Debug(msg, keysAndValues...)
+	}
+}
+
+func (r *Reporter) Info(msg string, keysAndValues ...any) {
+	r.log(LevelInfo, msg, keysAndValues)
+	if r.config.logger != nil {
+		r.config.logger.Info(msg, keysAndValues...)
+	}
+}
+
+func (r *Reporter) Warn(msg string, keysAndValues ...any) {
+	r.log(LevelWarning, msg, keysAndValues)
+	if r.config.logger != nil {
+		r.config.logger.Warn(msg, keysAndValues...)
+	}
+}
+
+// Error records a breadcrumb and captures the error logged under the "err"
+// key, the event is sent in the background so logging does not block
+func (r *Reporter) Error(msg string, keysAndValues ...any) {
+	data := r.log(LevelError, msg, keysAndValues)
+	if r.config.logger != nil {
+		r.config.logger.Error(msg, keysAndValues...)
+	}
+	if err, ok := data["err"].(error); ok {
+		excs := exceptions(err, callers(3))
+		go func() {
+			_, _ = r.capture(context.Background(), LevelError, msg, excs)
+		}()
+	}
+}
+
+// log records a breadcrumb and returns its data with errors left as they are
+func (r *Reporter) log(level Level, msg string, keysAndValues []any) map[string]any {
+	var data, crumbData map[string]any
+	if len(keysAndValues) > 0 {
+		data = make(map[string]any, len(keysAndValues)/2)
+		crumbData = make(map[string]any, len(keysAndValues)/2)
+		for i := 0; i < len(keysAndValues); i += 2 {
+			key := fmt.Sprint(keysAndValues[i])
+			var value any = "<missing>"
+			if i+1 < len(keysAndValues) {
+				value = keysAndValues[i+1]
+			}
+			data[key] = value
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			crumbData[key] = value
+		}
+	}
+	r.AddBreadcrumb(Breadcrumb{Category: "log", Level: level, Message: msg, Data: crumbData})
+	return data
+}
@@ -0,0 +1,93 @@
+package crashreport
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Module     string      `json:"module,omitempty"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+	Mechanism  *mechanism  `json:"mechanism,omitempty"`
+}
+
+type mechanism struct {
+	Type    string `json:"type"`
+	Handled bool   `json:"handled"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// exceptions lists err and its wrapped causes root cause first, as Sentry
+// expects, the stack goes to err itself
+func exceptions(err error, pcs []uintptr) []exception {
+	var excs []exception
+	for e := err; e != nil && len(excs) < 10; e = errors.Unwrap(e) {
+		excs = append([]exception{{Type: fmt.Sprintf("%T", e), Value: e.Error()}}, excs...)
+	}
+	excs[len(excs)-1].Stacktrace = newStacktrace(pcs)
+	return excs
+}
+
+// callers returns the program counters above skip frames
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 64)
+	return pcs[:runtime.Callers(skip, pcs)]
+}
+
+// newStacktrace converts pcs, innermost first, to frames oldest first
+func newStacktrace(pcs []uintptr) *stacktrace {
+	if len(pcs) == 0 {
+		return nil
+	}
+	var frames []frame
+	iter := runtime.CallersFrames(pcs)
+	for {
+		f, more := iter.Next()
+		module, function := splitFunction(f.Function)
+		frames = append([]frame{{
+			Function: function,
+			Module:   module,
+			Filename: filepath.Base(f.File),
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    module == "main" || (module != "" && !isStdlib(module)),
+		}}, frames...)
+		if !more {
+			break
+		}
+	}
+	return &stacktrace{Frames: frames}
+}
+
+// splitFunction splits "github.com/a/b.(*T).M" into "github.com/a/b" and "(*T).M"
+func splitFunction(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+2+dot:]
+}
+
+// isStdlib reports whether the first path element has no dot, like runtime or net/http
+func isStdlib(module string) bool {
+	first, _, _ := strings.Cut(module, "/")
+	return !strings.Contains(first, ".")
+}
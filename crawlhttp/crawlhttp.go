@@ -0,0 +1,272 @@
+// Package crawlhttp fetches pages politely for crawlers and scrapers, on top
+// of a gohttp.Client: it caps the concurrent requests per host, spaces them
+// by a crawl delay, honors robots.txt and backs off when a host answers 429.
+//
+//	c, err := crawlhttp.New(client,
+//		crawlhttp.WithHostConcurrency(2),
+//		crawlhttp.WithCrawlDelay(500*time.Millisecond),
+//		crawlhttp.WithRobots("examplebot"))
+//	...
+//	resp, err := c.Get(ctx, "https://example.com/products?page=2")
+//	if errors.Is(err, crawlhttp.ErrDisallowed) {
+//		// robots.txt asks not to crawl it
+//	}
+package crawlhttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	gourl "net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/Stellar1999/gotool/retry"
+)
+
+// ErrDisallowed is returned for urls the robots.txt of their host disallows
+var ErrDisallowed = errors.New("crawlhttp: disallowed by robots.txt")
+
+const (
+	// robotsTTL is how long a robots.txt is used, RFC 9309 asks for at most a day
+	robotsTTL = 24 * time.Hour
+	// robotsRetry is how long a failing robots.txt disallows the host
+	robotsRetry = time.Minute
+)
+
+type config struct {
+	concurrency int
+	delay       time.Duration
+	agent       string
+	retries     int
+	backoff     retry.Strategy
+}
+
+type Option = opt.Option[config]
+
+// WithHostConcurrency is how many requests a host gets at once, 2 by default
+func WithHostConcurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// WithCrawlDelay spaces the requests to a host by d, 1s by default. A longer
+// Crawl-delay of robots.txt wins.
+func WithCrawlDelay(d time.Duration) Option {
+	return func(c *config) {
+		c.delay = d
+	}
+}
+
+// WithRobots fetches the robots.txt of every host and follows its rules for
+// the product token agent, e.g. "examplebot", or else for *. Send the token
+// in the User-Agent of the client too, see gohttp.WithUserAgent.
+func WithRobots(agent string) Option {
+	return func(c *config) {
+		c.agent = agent
+	}
+}
+
+// WithRetries sends a request again up to n times after a 429 or a 503,
+// waiting for its Retry-After or else by backoff, 3 times by default with
+// exponential delays from 1s up to 1min. The whole host waits meanwhile.
+func WithRetries(n int, backoff retry.Strategy) Option {
+	return func(c *config) {
+		c.retries, c.backoff = n, backoff
+	}
+}
+
+// Crawler sends GET requests with the politeness of its options, safe for
+// concurrent use
+type Crawler struct {
+	client *gohttp.Client
+	cfg    config
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+// host is the state of one scheme and host
+type host struct {
+	slots chan struct{}
+
+	mu   sync.Mutex
+	next time.Time
+	// robots are the rules of the robots.txt until robotsExpire
+	robots       *robots
+	robotsExpire time.Time
+	robotsFetch  sync.Mutex
+}
+
+// New returns a Crawler sending with client, the default Client of the http
+// package when nil
+func New(client *gohttp.Client, opts ...Option) (*Crawler, error) {
+	cfg := config{concurrency: 2, delay: time.Second, retries: 3, backoff: retry.Capped(retry.Exponential(time.Second, 2, 0.2), time.Minute)}
+	err := opt.Build(&cfg, opts, func(c *config) error {
+		if c.concurrency <= 0 || c.delay < 0 || c.retries < 0 || c.backoff == nil {
+			return errors.New("crawlhttp: invalid concurrency, delay or retries")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Crawler{client: client, cfg: cfg, hosts: make(map[string]*host)}, nil
+}
+
+// Get fetches url once the politeness rules allow it. Like
+// gohttp.Request.Send, any status but 200 fails with a *gohttp.StatusError
+// along with the response.
+func (c *Crawler) Get(ctx context.Context, url string) (*gohttp.Response, error) {
+	u, err := gourl.Parse(url)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("crawlhttp: invalid url %q", url)
+	}
+	h := c.host(u)
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-h.slots }()
+
+	delay := c.cfg.delay
+	if c.cfg.agent != "" {
+		rules, err := c.robots(ctx, h, u)
+		if err != nil {
+			return nil, err
+		}
+		if !rules.allowed(u.RequestURI()) {
+			return nil, fmt.Errorf("%w: %s", ErrDisallowed, url)
+		}
+		if rules.crawlDelay > delay {
+			delay = rules.crawlDelay
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		if err := h.wait(ctx, delay); err != nil {
+			return nil, err
+		}
+		resp, err := c.newRequest(ctx, url).Send()
+		if resp == nil || resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable || attempt > c.cfg.retries {
+			return resp, err
+		}
+		h.backOff(retryAfter(resp.Header.Get("Retry-After"), c.cfg.backoff(attempt)))
+	}
+}
+
+func (c *Crawler) newRequest(ctx context.Context, url string) *gohttp.Request {
+	if c.client != nil {
+		return c.client.NewRequest(gohttp.GET, url).WithContext(ctx)
+	}
+	return gohttp.NewRequest(gohttp.GET, url).WithContext(ctx)
+}
+
+func (c *Crawler) host(u *gourl.URL) *host {
+	key := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h, ok := c.hosts[key]
+	if !ok {
+		h = &host{slots: make(chan struct{}, c.cfg.concurrency)}
+		c.hosts[key] = h
+	}
+	return h
+}
+
+// robots returns the rules of the robots.txt of the host of u, fetching it
+// when unknown or expired like any request to the host, within a slot of it
+// and after the crawl delay. A missing robots.txt allows everything, a
+// failing one disallows everything for a while. Without an answer, the ctx
+// ended or the host is unreachable, the error is returned and nothing is
+// kept.
+func (c *Crawler) robots(ctx context.Context, h *host, u *gourl.URL) (*robots, error) {
+	h.robotsFetch.Lock()
+	defer h.robotsFetch.Unlock()
+	h.mu.Lock()
+	rules, expire := h.robots, h.robotsExpire
+	h.mu.Unlock()
+	if rules != nil && time.Now().Before(expire) {
+		return rules, nil
+	}
+
+	delay := c.cfg.delay
+	if rules != nil && rules.crawlDelay > delay {
+		delay = rules.crawlDelay
+	}
+	if err := h.wait(ctx, delay); err != nil {
+		return nil, err
+	}
+	ttl := robotsTTL
+	resp, err := c.newRequest(ctx, u.Scheme+"://"+u.Host+"/robots.txt").Send()
+	switch {
+	case err == nil:
+		rules = parseRobots(bytes.NewReader(resp.Body), c.cfg.agent)
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case resp == nil:
+		return nil, fmt.Errorf("crawlhttp: fetching robots.txt: %w", err)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		rules = allowAll
+	default:
+		rules, ttl = disallowAll, robotsRetry
+	}
+	h.mu.Lock()
+	h.robots, h.robotsExpire = rules, time.Now().Add(ttl)
+	h.mu.Unlock()
+	return rules, nil
+}
+
+// wait waits for the turn of a request to the host, delay after the last one
+func (h *host) wait(ctx context.Context, delay time.Duration) error {
+	h.mu.Lock()
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(delay)
+	h.mu.Unlock()
+
+	timer := time.NewTimer(start.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backOff holds the requests to the host for d
+func (h *host) backOff(d time.Duration) {
+	h.mu.Lock()
+	if until := time.Now().Add(d); until.After(h.next) {
+		h.next = until
+	}
+	h.mu.Unlock()
+}
+
+// retryAfter returns the delay of a Retry-After header, in seconds or as a
+// date, or def without a valid one
+func retryAfter(header string, def time.Duration) time.Duration {
+	if header == "" {
+		return def
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return def
+}
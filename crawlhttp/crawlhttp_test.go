@@ -0,0 +1,355 @@
+package crawlhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/retry"
+)
+
+func TestParseRobots(t *testing.T) {
+	const txt = `# comment
+User-agent: otherbot
+Disallow: /
+
+User-agent: examplebot
+User-agent: anotherbot
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: *
+Disallow: /tmp
+`
+	tests := []struct {
+		name    string
+		agent   string
+		path    string
+		allowed bool
+	}{
+		{"no rule", "examplebot", "/index.html", true},
+		{"disallowed", "examplebot", "/private/x", false},
+		{"longest allow", "examplebot", "/private/public/x", true},
+		{"end anchor", "examplebot", "/docs/a.pdf", false},
+		{"end anchor miss", "examplebot", "/docs/a.pdf?x=1", true},
+		{"case insensitive agent", "ExampleBot", "/private", false},
+		{"second agent of group", "anotherbot", "/private", false},
+		{"named group only", "examplebot", "/tmp", true},
+		{"wildcard", "unknownbot", "/tmp/x", false},
+		{"wildcard allows", "unknownbot", "/private", true},
+		{"robots.txt", "otherbot", "/robots.txt", true},
+		{"disallow all", "otherbot", "/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRobots(strings.NewReader(txt), tt.agent).allowed(tt.path); got != tt.allowed {
+				t.Errorf("allowed(%q) got = %v, want %v", tt.path, got, tt.allowed)
+			}
+		})
+	}
+	if got := parseRobots(strings.NewReader(txt), "examplebot").crawlDelay; got != 2*time.Second {
+		t.Errorf("crawlDelay got = %v, want %v", got, 2*time.Second)
+	}
+	if got := parseRobots(strings.NewReader(txt), "unknownbot").crawlDelay; got != 0 {
+		t.Errorf("crawlDelay got = %v, want 0", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/anything", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish/", "/fish", false},
+		{"/*.php", "/a/b.php?q=1", true},
+		{"/*.php$", "/a/b.php", true},
+		{"/*.php$", "/a/b.phps", false},
+		{"/a*b*c", "/axxbyyc", true},
+		{"/a*b*c", "/axxcyyb", false},
+		{"/exact$", "/exact", true},
+		{"/exact$", "/exact/", false},
+	}
+	for _, tt := range tests {
+		if got := match(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("match(%q, %q) got = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCrawlerRobots(t *testing.T) {
+	var robotsFetches int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			atomic.AddInt64(&robotsFetches, 1)
+			_, _ = w.Write([]byte("User-agent: testbot\nDisallow: /private\n"))
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c, err := New(nil, WithRobots("testbot"), WithCrawlDelay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	resp, err := c.Get(ctx, srv.URL+"/public")
+	if err != nil || resp.String() != "ok" {
+		t.Fatalf("Get() got = %v, %v, want ok", resp, err)
+	}
+	if _, err := c.Get(ctx, srv.URL+"/private/page"); !errors.Is(err, ErrDisallowed) {
+		t.Errorf("Get() error = %v, want %v", err, ErrDisallowed)
+	}
+	if got := atomic.LoadInt64(&robotsFetches); got != 1 {
+		t.Errorf("robots.txt fetches got = %d, want 1", got)
+	}
+}
+
+func TestCrawlerRobotsUnavailable(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		allowed bool
+	}{
+		{"missing", http.StatusNotFound, true},
+		{"server error", http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/robots.txt" {
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = w.Write([]byte("ok"))
+			}))
+			defer srv.Close()
+			c, err := New(nil, WithRobots("testbot"), WithCrawlDelay(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Get(context.Background(), srv.URL+"/page")
+			if got := !errors.Is(err, ErrDisallowed); got != tt.allowed {
+				t.Errorf("Get() allowed got = %v, want %v, error %v", got, tt.allowed, err)
+			}
+		})
+	}
+}
+
+func TestCrawlerRobotsCanceled(t *testing.T) {
+	release := make(chan struct{})
+	var fetches int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			if atomic.AddInt64(&fetches, 1) == 1 {
+				<-release
+			}
+			_, _ = w.Write([]byte("User-agent: testbot\nDisallow: /private\n"))
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c, err := New(nil, WithRobots("testbot"), WithCrawlDelay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Get(ctx, srv.URL+"/page"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	resp, err := c.Get(context.Background(), srv.URL+"/page")
+	if err != nil || resp.String() != "ok" {
+		t.Errorf("Get() after a canceled robots.txt got = %v, %v, want ok", resp, err)
+	}
+	if got := atomic.LoadInt64(&fetches); got != 2 {
+		t.Errorf("robots.txt fetches got = %d, want 2", got)
+	}
+}
+
+func TestCrawlerRobotsDelay(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	const delay = 50 * time.Millisecond
+	c, err := New(nil, WithRobots("testbot"), WithCrawlDelay(delay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(context.Background(), srv.URL+"/page"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(starts) != 2 {
+		t.Fatalf("requests got = %d, want robots.txt and the page", len(starts))
+	}
+	// allow for the timer and scheduling granularity
+	if got := starts[1].Sub(starts[0]); got < delay-10*time.Millisecond {
+		t.Errorf("spacing after robots.txt got = %v, want at least %v", got, delay)
+	}
+}
+
+func TestCrawlerHostConcurrency(t *testing.T) {
+	var active, peak int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&active, 1)
+		defer atomic.AddInt64(&active, -1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	c, err := New(nil, WithHostConcurrency(2), WithCrawlDelay(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get(context.Background(), srv.URL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt64(&peak); got != 2 {
+		t.Errorf("concurrent requests got = %d, want 2", got)
+	}
+}
+
+func TestCrawlerCrawlDelay(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	const delay = 50 * time.Millisecond
+	c, err := New(nil, WithHostConcurrency(3), WithCrawlDelay(delay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = c.Get(context.Background(), srv.URL)
+		}()
+	}
+	wg.Wait()
+	if len(starts) != 3 {
+		t.Fatalf("requests got = %d, want 3", len(starts))
+	}
+	// allow for the timer and scheduling granularity
+	if got := starts[2].Sub(starts[0]); got < 2*delay-10*time.Millisecond {
+		t.Errorf("spacing got = %v, want at least %v", got, 2*delay)
+	}
+}
+
+func TestCrawlerRetryAfter(t *testing.T) {
+	var calls int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c, err := New(nil, WithCrawlDelay(0), WithRetries(2, retry.Constant(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err != nil || resp.String() != "ok" {
+		t.Fatalf("Get() got = %v, %v, want ok", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 10*time.Second {
+		t.Errorf("Get() took %v, want the Retry-After of 1s", elapsed)
+	}
+	if got := atomic.LoadInt64(&calls); got != 2 {
+		t.Errorf("calls got = %d, want 2", got)
+	}
+}
+
+func TestCrawlerGivesUp(t *testing.T) {
+	var calls int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c, err := New(nil, WithCrawlDelay(0), WithRetries(2, retry.Constant(time.Millisecond)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Get() got = %v, %v, want a 429", resp, err)
+	}
+	if got := atomic.LoadInt64(&calls); got != 3 {
+		t.Errorf("calls got = %d, want 3", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", time.Minute},
+		{"3", 3 * time.Second},
+		{"-1", time.Minute},
+		{"soon", time.Minute},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header, time.Minute); got != tt.want {
+			t.Errorf("retryAfter(%q) got = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New(nil, WithHostConcurrency(0)); err == nil {
+		t.Error("New() error = nil, want an error")
+	}
+}
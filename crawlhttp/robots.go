@@ -0,0 +1,132 @@
+package crawlhttp
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robots are the rules of a robots.txt for one user agent, RFC 9309
+type robots struct {
+	rules []rule
+	// crawlDelay is the Crawl-delay extension, 0 without one
+	crawlDelay time.Duration
+}
+
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+var (
+	allowAll    = &robots{}
+	disallowAll = &robots{rules: []rule{{pattern: "/"}}}
+)
+
+type group struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// parseRobots returns the rules of the robots.txt r for the product token
+// agent: those of the groups naming it, or else of the groups for *
+func parseRobots(r io.Reader, agent string) *robots {
+	var groups []*group
+	var current *group
+	inAgents := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "allow", "disallow":
+			// an empty disallow allows everything, like no rule
+			if current != nil && value != "" {
+				current.rules = append(current.rules, rule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); current != nil && err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+		inAgents = false
+	}
+
+	agent = strings.ToLower(agent)
+	out := &robots{}
+	for _, wildcard := range []bool{false, true} {
+		matched := false
+		for _, g := range groups {
+			for _, a := range g.agents {
+				if a == agent && !wildcard || a == "*" && wildcard {
+					matched = true
+					out.rules = append(out.rules, g.rules...)
+					if g.crawlDelay > out.crawlDelay {
+						out.crawlDelay = g.crawlDelay
+					}
+					break
+				}
+			}
+		}
+		if matched {
+			break
+		}
+	}
+	return out
+}
+
+// allowed reports whether path, with its query, may be crawled: the longest
+// matching rule decides, allow wins a tie
+func (r *robots) allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	allow, longest := true, -1
+	for _, rule := range r.rules {
+		if !match(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > longest || n == longest && rule.allow {
+			allow, longest = rule.allow, n
+		}
+	}
+	return allow
+}
+
+// match matches path against a pattern of robots.txt, with * for any
+// characters and a final $ for the end of the path
+func match(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return !anchored || rest == ""
+}
@@ -0,0 +1,439 @@
+// Package cron runs jobs on cron schedules or at intervals, in the
+// background of a service:
+//
+//	s, _ := cron.New(cron.WithErrorHandler(func(job string, err error) {
+//		logger.Error("job failed", "job", job, "error", err)
+//	}))
+//	_ = s.Add("report", cron.MustParse("0 7 * * MON-FRI"), sendReport)
+//	_ = s.Add("refresh", cron.Every(time.Minute), refresh, cron.WithJitter(10*time.Second))
+//	s.Start()
+//	defer s.Stop()
+//
+// A panicking job is recovered and reported as an error. By default a job
+// whose previous run has not returned yet skips its turn, see WithOverlap.
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/Stellar1999/gotool/randx"
+)
+
+var (
+	// ErrDuplicateJob is returned by Add for a name already in use
+	ErrDuplicateJob = errors.New("cron: duplicate job")
+	// ErrStopped is returned by Add once the scheduler stopped
+	ErrStopped = errors.New("cron: scheduler stopped")
+)
+
+// Job is the work of a scheduled job. ctx is canceled when the job is
+// removed, the scheduler stops or the run times out.
+type Job func(ctx context.Context) error
+
+// Overlap chooses what happens when a job is due while it still runs
+type Overlap int
+
+const (
+	// Skip drops the run
+	Skip Overlap = iota
+	// Queue runs it once the running one returns. A run already waiting
+	// absorbs further ones, so a slow job runs back to back instead of
+	// piling up.
+	Queue
+)
+
+type config struct {
+	location *time.Location
+	onError  func(job string, err error)
+}
+
+type Option = opt.Option[config]
+
+// WithLocation evaluates schedules in loc, time.Local by default
+func WithLocation(loc *time.Location) Option {
+	return func(c *config) {
+		c.location = loc
+	}
+}
+
+// WithErrorHandler receives the errors and recovered panics of jobs, which
+// are written to the standard log package by default
+func WithErrorHandler(fn func(job string, err error)) Option {
+	return func(c *config) {
+		c.onError = fn
+	}
+}
+
+type jobConfig struct {
+	jitter     time.Duration
+	overlap    Overlap
+	timeout    time.Duration
+	runOnStart bool
+}
+
+type JobOption = opt.Option[jobConfig]
+
+// WithJitter delays each run by up to d at random, so instances of a service
+// don't all run a job at the same moment
+func WithJitter(d time.Duration) JobOption {
+	return func(c *jobConfig) {
+		c.jitter = d
+	}
+}
+
+// WithOverlap sets what happens when the job is due while it still runs,
+// Skip by default
+func WithOverlap(o Overlap) JobOption {
+	return func(c *jobConfig) {
+		c.overlap = o
+	}
+}
+
+// WithTimeout cancels the ctx of each run after d
+func WithTimeout(d time.Duration) JobOption {
+	return func(c *jobConfig) {
+		c.timeout = d
+	}
+}
+
+// WithRunOnStart also runs the job once as soon as it is scheduled
+func WithRunOnStart() JobOption {
+	return func(c *jobConfig) {
+		c.runOnStart = true
+	}
+}
+
+var jobChecks = []opt.Check[jobConfig]{
+	func(c *jobConfig) error {
+		if c.jitter < 0 || c.timeout < 0 {
+			return errors.New("cron: jitter and timeout must not be negative")
+		}
+		if c.overlap != Skip && c.overlap != Queue {
+			return fmt.Errorf("cron: unknown overlap policy %d", c.overlap)
+		}
+		return nil
+	},
+}
+
+// JobInfo describes a scheduled job
+type JobInfo struct {
+	Name string
+	// Next is when the job runs next, zero when it is not scheduled
+	Next    time.Time
+	LastRun time.Time
+	LastErr error
+	Runs    int
+}
+
+type entry struct {
+	name     string
+	schedule Schedule
+	job      Job
+	cfg      jobConfig
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	removed chan struct{}
+	// running holds a token while the job runs
+	running chan struct{}
+	queued  int32
+
+	mu      sync.Mutex
+	next    time.Time
+	lastRun time.Time
+	lastErr error
+	runs    int
+}
+
+// Scheduler runs jobs on their schedules, it is safe for concurrent use
+type Scheduler struct {
+	cfg    config
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	jobs     map[string]*entry
+	started  bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	stopped  bool
+
+	loops sync.WaitGroup
+	runs  sync.WaitGroup
+
+	runsTotal int64
+	failures  int64
+	panics    int64
+	skipped   int64
+}
+
+// New returns a Scheduler, jobs run once it is started
+func New(opts ...Option) (*Scheduler, error) {
+	cfg := config{location: time.Local}
+	err := opt.Build(&cfg, opts, func(c *config) error {
+		if c.location == nil {
+			return errors.New("cron: location must not be nil")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.onError == nil {
+		cfg.onError = func(job string, err error) {
+			log.Printf("cron: job %s: %v", job, err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		cfg:    cfg,
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   make(map[string]*entry),
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+// Add schedules job under name, it starts right away on a started Scheduler
+func (s *Scheduler) Add(name string, schedule Schedule, job Job, opts ...JobOption) error {
+	if name == "" || schedule == nil || job == nil {
+		return errors.New("cron: a job needs a name, schedule and function")
+	}
+	cfg := jobConfig{}
+	if err := opt.Build(&cfg, opts, jobChecks...); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return ErrStopped
+	}
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, name)
+	}
+	e := &entry{
+		name:     name,
+		schedule: schedule,
+		job:      job,
+		cfg:      cfg,
+		removed:  make(chan struct{}),
+		running:  make(chan struct{}, 1),
+	}
+	e.ctx, e.cancel = context.WithCancel(s.ctx)
+	s.jobs[name] = e
+	if s.started {
+		s.loops.Add(1)
+		go s.loop(e)
+	}
+	return nil
+}
+
+// Remove unschedules the job and cancels its running ctx, it reports whether
+// the job existed
+func (s *Scheduler) Remove(name string) bool {
+	s.mu.Lock()
+	e, ok := s.jobs[name]
+	delete(s.jobs, name)
+	s.mu.Unlock()
+	if ok {
+		close(e.removed)
+		e.cancel()
+	}
+	return ok
+}
+
+// Start starts running the jobs, a stopped Scheduler does not start again
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+	for _, e := range s.jobs {
+		s.loops.Add(1)
+		go s.loop(e)
+	}
+}
+
+// Stop stops scheduling runs, cancels the ctx of the running ones and waits
+// for them to return
+func (s *Scheduler) Stop() {
+	s.halt()
+	s.cancel()
+	s.runs.Wait()
+}
+
+// Drain stops scheduling runs and waits for the running ones to finish.
+// When ctx is done first their ctx is canceled, Drain waits for them to
+// return and returns ctx.Err().
+func (s *Scheduler) Drain(ctx context.Context) error {
+	s.halt()
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// halt ends the scheduling loops, no run starts after it returns
+func (s *Scheduler) halt() {
+	s.stopOnce.Do(func() {
+		s.mu.Lock()
+		s.stopped = true
+		close(s.stop)
+		s.mu.Unlock()
+	})
+	s.loops.Wait()
+}
+
+// Jobs describes the scheduled jobs by name
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.Lock()
+	entries := make([]*entry, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		entries = append(entries, e)
+	}
+	s.mu.Unlock()
+	infos := make([]JobInfo, len(entries))
+	for i, e := range entries {
+		e.mu.Lock()
+		infos[i] = JobInfo{Name: e.name, Next: e.next, LastRun: e.lastRun, LastErr: e.lastErr, Runs: e.runs}
+		e.mu.Unlock()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Stats returns the counters of the scheduler, register it with
+// stats.Register("cron", s).
+func (s *Scheduler) Stats() map[string]float64 {
+	s.mu.Lock()
+	jobs := len(s.jobs)
+	s.mu.Unlock()
+	return map[string]float64{
+		"jobs":           float64(jobs),
+		"runs_total":     float64(atomic.LoadInt64(&s.runsTotal)),
+		"failures_total": float64(atomic.LoadInt64(&s.failures)),
+		"panics_total":   float64(atomic.LoadInt64(&s.panics)),
+		"skipped_total":  float64(atomic.LoadInt64(&s.skipped)),
+	}
+}
+
+func (s *Scheduler) loop(e *entry) {
+	defer s.loops.Done()
+	if e.cfg.runOnStart {
+		s.trigger(e)
+	}
+	var timer *time.Timer
+	for {
+		now := time.Now().In(s.cfg.location)
+		next := e.schedule.Next(now)
+		e.mu.Lock()
+		e.next = next
+		e.mu.Unlock()
+		if next.IsZero() {
+			return
+		}
+		delay := next.Sub(now)
+		if e.cfg.jitter > 0 {
+			delay += time.Duration(randx.Float64() * float64(e.cfg.jitter))
+		}
+		if timer == nil {
+			timer = time.NewTimer(delay)
+			defer timer.Stop()
+		} else {
+			timer.Reset(delay)
+		}
+		select {
+		case <-s.stop:
+			return
+		case <-e.removed:
+			return
+		case <-timer.C:
+			s.trigger(e)
+		}
+	}
+}
+
+// trigger starts a run of e unless its overlap policy drops it
+func (s *Scheduler) trigger(e *entry) {
+	if e.cfg.overlap == Queue {
+		if !atomic.CompareAndSwapInt32(&e.queued, 0, 1) {
+			atomic.AddInt64(&s.skipped, 1)
+			return
+		}
+		s.runs.Add(1)
+		go func() {
+			defer s.runs.Done()
+			select {
+			case e.running <- struct{}{}:
+			case <-e.ctx.Done():
+				atomic.StoreInt32(&e.queued, 0)
+				return
+			}
+			atomic.StoreInt32(&e.queued, 0)
+			defer func() { <-e.running }()
+			s.run(e)
+		}()
+		return
+	}
+	select {
+	case e.running <- struct{}{}:
+	default:
+		atomic.AddInt64(&s.skipped, 1)
+		return
+	}
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		defer func() { <-e.running }()
+		s.run(e)
+	}()
+}
+
+func (s *Scheduler) run(e *entry) {
+	ctx := e.ctx
+	if e.cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.cfg.timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				atomic.AddInt64(&s.panics, 1)
+				err = fmt.Errorf("cron: job %s panicked: %v\n%s", e.name, p, debug.Stack())
+			}
+		}()
+		return e.job(ctx)
+	}()
+	atomic.AddInt64(&s.runsTotal, 1)
+	e.mu.Lock()
+	e.lastRun, e.lastErr = start, err
+	e.runs++
+	e.mu.Unlock()
+	if err != nil {
+		atomic.AddInt64(&s.failures, 1)
+		s.cfg.onError(e.name, err)
+	}
+}
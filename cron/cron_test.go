@@ -0,0 +1,221 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 30, 15, 500, time.UTC) // a Wednesday
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"* * * * *", []string{"2024-01-31T10:31:00Z", "2024-01-31T10:32:00Z"}},
+		{"*/20 * * * * *", []string{"2024-01-31T10:30:20Z", "2024-01-31T10:30:40Z", "2024-01-31T10:31:00Z"}},
+		{"0 9 * * MON-FRI", []string{"2024-02-01T09:00:00Z", "2024-02-02T09:00:00Z", "2024-02-05T09:00:00Z"}},
+		{"0 0 29 2 *", []string{"2024-02-29T00:00:00Z", "2028-02-29T00:00:00Z"}},
+		{"0 0 31 * *", []string{"2024-03-31T00:00:00Z", "2024-05-31T00:00:00Z"}},
+		{"15,45 10-11 * * *", []string{"2024-01-31T10:45:00Z", "2024-01-31T11:15:00Z", "2024-01-31T11:45:00Z", "2024-02-01T10:15:00Z"}},
+		{"0 12 1 * sun", []string{"2024-02-01T12:00:00Z", "2024-02-04T12:00:00Z"}},
+		{"0 12 ? * 7", []string{"2024-02-04T12:00:00Z", "2024-02-11T12:00:00Z"}},
+		{"30 5/10 * * * *", []string{"2024-01-31T10:35:30Z", "2024-01-31T10:45:30Z"}},
+		{"@hourly", []string{"2024-01-31T11:00:00Z", "2024-01-31T12:00:00Z"}},
+		{"@every 90s", []string{"2024-01-31T10:31:45.0000005Z", "2024-01-31T10:33:15.0000005Z"}},
+		{"0 0 30 2 *", []string{"0001-01-01T00:00:00Z"}},
+		{"CRON_TZ=America/New_York 0 9 * * *", []string{"2024-01-31T09:00:00-05:00"}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.expr, err)
+			continue
+		}
+		at := base
+		for _, want := range tt.want {
+			at = s.Next(at)
+			if got := at.Format(time.RFC3339Nano); got != want {
+				t.Errorf("Parse(%q) Next got = %v, want %v", tt.expr, got, want)
+				break
+			}
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * * * * *", "*/0 * * * *", "5-1 * * * *", "@often", "@every -1s", "TZ=Nowhere/City * * * * *", "* * * FOO *"} {
+		if _, err := Parse(expr); !errors.Is(err, ErrInvalidSpec) {
+			t.Errorf("Parse(%q) error got = %v, want %v", expr, err, ErrInvalidSpec)
+		}
+	}
+}
+
+func TestParseDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	s := MustParse("30 2 * * *")
+	// 02:30 does not exist on 2024-03-31, clocks jump from 02:00 to 03:00
+	got := s.Next(time.Date(2024, 3, 30, 12, 0, 0, 0, loc))
+	if got.Day() != 1 || got.Month() != time.April || got.Hour() != 2 {
+		t.Errorf("Next() over DST got = %v", got)
+	}
+	hourly := MustParse("0 * * * *")
+	prev := time.Date(2024, 10, 27, 0, 30, 0, 0, loc)
+	for i := 0; i < 5; i++ {
+		next := hourly.Next(prev)
+		if !next.After(prev) || next.Sub(prev) > time.Hour {
+			t.Errorf("Next() after %v got = %v", prev, next)
+		}
+		prev = next
+	}
+}
+
+func newScheduler(t *testing.T, errs chan<- error) *Scheduler {
+	t.Helper()
+	s, err := New(WithErrorHandler(func(job string, err error) {
+		if errs != nil {
+			errs <- err
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestScheduler(t *testing.T) {
+	errs := make(chan error, 10)
+	s := newScheduler(t, errs)
+	var runs int32
+	if err := s.Add("count", Every(5*time.Millisecond), func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}, WithRunOnStart()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("count", Every(time.Second), func(ctx context.Context) error { return nil }); !errors.Is(err, ErrDuplicateJob) {
+		t.Errorf("Add() duplicate error got = %v, want %v", err, ErrDuplicateJob)
+	}
+	if err := s.Add("panic", Every(5*time.Millisecond), func(ctx context.Context) error { panic("boom") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("bad", Every(time.Second), nil); err == nil {
+		t.Errorf("Add() nil job error = nil")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt32(&runs); got != 0 {
+		t.Errorf("runs before Start got = %v", got)
+	}
+	s.Start()
+	time.Sleep(50 * time.Millisecond)
+	s.Stop()
+	got := atomic.LoadInt32(&runs)
+	if got < 3 {
+		t.Errorf("runs got = %v, want several", got)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if again := atomic.LoadInt32(&runs); again != got {
+		t.Errorf("runs after Stop got = %v, want %v", again, got)
+	}
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "job panic panicked: boom") {
+			t.Errorf("panic error got = %v", err)
+		}
+	default:
+		t.Errorf("panic not reported")
+	}
+	stats := s.Stats()
+	if stats["jobs"] != 2 || stats["panics_total"] < 1 || stats["runs_total"] < float64(got) {
+		t.Errorf("Stats() got = %v", stats)
+	}
+	jobs := s.Jobs()
+	if len(jobs) != 2 || jobs[0].Name != "count" || jobs[0].Runs != int(got) || jobs[1].LastErr == nil {
+		t.Errorf("Jobs() got = %+v", jobs)
+	}
+	if err := s.Add("late", Every(time.Second), func(ctx context.Context) error { return nil }); !errors.Is(err, ErrStopped) {
+		t.Errorf("Add() after Stop error got = %v, want %v", err, ErrStopped)
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	for _, overlap := range []Overlap{Skip, Queue} {
+		s := newScheduler(t, nil)
+		var mu sync.Mutex
+		var running, maxRunning, runs int
+		_ = s.Add("slow", Every(2*time.Millisecond), func(ctx context.Context) error {
+			mu.Lock()
+			running++
+			runs++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			time.Sleep(15 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		}, WithOverlap(overlap))
+		s.Start()
+		time.Sleep(70 * time.Millisecond)
+		if err := s.Drain(context.Background()); err != nil {
+			t.Errorf("Drain() error = %v", err)
+		}
+		mu.Lock()
+		if maxRunning != 1 || runs < 2 || running != 0 {
+			t.Errorf("overlap %v got max %v running, %v runs, %v left", overlap, maxRunning, runs, running)
+		}
+		mu.Unlock()
+		if s.Stats()["skipped_total"] == 0 {
+			t.Errorf("overlap %v skipped got = 0", overlap)
+		}
+	}
+	if err := newScheduler(t, nil).Add("x", Every(time.Second), func(ctx context.Context) error { return nil }, WithOverlap(Overlap(7))); err == nil {
+		t.Errorf("Add() unknown overlap error = nil")
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	errs := make(chan error, 10)
+	s := newScheduler(t, errs)
+	started := make(chan string, 10)
+	block := func(name string) Job {
+		return func(ctx context.Context) error {
+			started <- name
+			<-ctx.Done()
+			return ctx.Err()
+		}
+	}
+	_ = s.Add("timeout", Every(time.Hour), block("timeout"), WithRunOnStart(), WithTimeout(10*time.Millisecond))
+	_ = s.Add("removed", Every(time.Hour), block("removed"), WithRunOnStart())
+	_ = s.Add("drained", Every(time.Hour), block("drained"), WithRunOnStart())
+	s.Start()
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	if err := <-errs; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WithTimeout() error got = %v", err)
+	}
+	if !s.Remove("removed") || s.Remove("removed") {
+		t.Errorf("Remove() got wrong result")
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Remove() run error got = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain() error got = %v", err)
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Drain() run error got = %v", err)
+	}
+	s.Stop()
+}
@@ -0,0 +1,265 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSpec is returned for cron expressions that can't be parsed
+var ErrInvalidSpec = errors.New("cron: invalid spec")
+
+// Schedule gives the times a job runs
+type Schedule interface {
+	// Next returns the first time after t the job runs, the zero time when
+	// it never runs again
+	Next(t time.Time) time.Time
+}
+
+// ScheduleFunc adapts a function to Schedule
+type ScheduleFunc func(t time.Time) time.Time
+
+func (f ScheduleFunc) Next(t time.Time) time.Time {
+	return f(t)
+}
+
+type every time.Duration
+
+// Every runs a job every d, the first time d after it is added. A d below 1ns
+// never runs.
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+func (e every) Next(t time.Time) time.Time {
+	if e <= 0 {
+		return time.Time{}
+	}
+	return t.Add(time.Duration(e))
+}
+
+// spec is a parsed cron expression, each field a bit set of its values
+type spec struct {
+	second, minute, hour, dom, month, dow uint64
+	// a day of month or week written as * or ? matches every day, else a day
+	// matching either runs
+	domAny, dowAny bool
+	loc            *time.Location
+}
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is Sunday too
+	dows = bounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Parse parses a cron expression of five fields, minute hour day-of-month
+// month day-of-week, or six with seconds first. Fields take *, ?, values,
+// ranges such as 1-5, steps such as */15 or 10-40/10, lists of these, and
+// month and day names such as JAN and MON. Descriptors such as @daily,
+// @hourly and "@every 90s" work too.
+//
+// Times are in the location of the time given to Next, or in the one of a
+// "CRON_TZ=Europe/Paris " or "TZ=... " prefix.
+func Parse(expr string) (Schedule, error) {
+	s := strings.TrimSpace(expr)
+	var loc *time.Location
+	if strings.HasPrefix(s, "TZ=") || strings.HasPrefix(s, "CRON_TZ=") {
+		tz, rest, _ := strings.Cut(s, " ")
+		_, name, _ := strings.Cut(tz, "=")
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidSpec, expr, err)
+		}
+		loc, s = l, strings.TrimSpace(rest)
+	}
+	if strings.HasPrefix(s, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(s, "@every ")))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("%w: %q: want a positive duration", ErrInvalidSpec, expr)
+		}
+		return Every(interval), nil
+	}
+	if strings.HasPrefix(s, "@") {
+		full, ok := descriptors[strings.ToLower(s)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q: unknown descriptor", ErrInvalidSpec, expr)
+		}
+		s = full
+	}
+
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("%w: %q: want 5 or 6 fields, got %d", ErrInvalidSpec, expr, len(fields))
+	}
+	sp := &spec{loc: loc}
+	for i, f := range []struct {
+		dst *uint64
+		b   bounds
+	}{{&sp.second, seconds}, {&sp.minute, minutes}, {&sp.hour, hours}, {&sp.dom, doms}, {&sp.month, months}, {&sp.dow, dows}} {
+		bits, err := parseField(fields[i], f.b)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidSpec, expr, err)
+		}
+		*f.dst = bits
+	}
+	// Sunday as 7 is Sunday as 0
+	if sp.dow&(1<<7) != 0 {
+		sp.dow = sp.dow&^(1<<7) | 1
+	}
+	sp.domAny = fields[3][0] == '*' || fields[3][0] == '?'
+	sp.dowAny = fields[5][0] == '*' || fields[5][0] == '?'
+	return sp, nil
+}
+
+// MustParse is like Parse but panics on errors, for constant expressions
+func MustParse(expr string) Schedule {
+	s, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseField returns the bit set of a comma separated field
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step %q", part)
+			}
+		}
+		var lo, hi int
+		switch {
+		case rng == "*" || rng == "?":
+			lo, hi = b.min, b.max
+		default:
+			loText, hiText, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = value(loText, b); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = value(hiText, b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// 5/15 is 5-max/15
+				hi = b.max
+			}
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("bad range %q", part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func value(s string, b bounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, b.min, b.max)
+	}
+	return v, nil
+}
+
+func (s *spec) Next(t time.Time) time.Time {
+	if s.loc != nil {
+		t = t.In(s.loc)
+	}
+	loc := t.Location()
+	// the first whole second after t
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Year() > yearLimit {
+			return time.Time{}
+		}
+	}
+	for !s.dayMatches(t) {
+		month := t.Month()
+		t = time.Date(t.Year(), month, t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Month() != month {
+			goto wrap
+		}
+	}
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		day := t.Day()
+		// whole hours of absolute time, so a DST change doesn't go back
+		t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+		if t.Day() != day {
+			goto wrap
+		}
+	}
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		hour := t.Hour()
+		t = t.Add(time.Minute - time.Duration(t.Second())*time.Second)
+		if t.Hour() != hour {
+			goto wrap
+		}
+	}
+	for s.second&(1<<uint(t.Second())) == 0 {
+		minute := t.Minute()
+		t = t.Add(time.Second)
+		if t.Minute() != minute {
+			goto wrap
+		}
+	}
+	return t
+}
+
+func (s *spec) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,142 @@
+package cryptox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Parameters of the keys derived from passwords, about 32 MB and 100 ms for
+// scrypt, the second recommendation of RFC 9106 for Argon2id
+const (
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	argon2Passes  = 3
+	argon2Memory  = 64 << 10
+	argon2Threads = 4
+	saltSize      = 16
+	keySize       = 32
+	nonceSize     = 12
+)
+
+var (
+	// ErrDecrypt is returned for a ciphertext that is not authentic: a wrong
+	// key or password, or tampered or truncated data
+	ErrDecrypt = errors.New("cryptox: message authentication failed")
+	// ErrKeySize is returned for AES keys other than 16, 24 or 32 bytes
+	ErrKeySize = errors.New("cryptox: AES key must be 16, 24 or 32 bytes")
+)
+
+// Encrypt seals plaintext with AES-GCM and key of 16, 24 or 32 bytes. The
+// ciphertext starts with the random 12 bytes nonce.
+func Encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize, nonceSize+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens a ciphertext of Encrypt, ErrDecrypt when it is not authentic
+func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < nonceSize+gcm.Overhead() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := gcm.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("%w, got %d", ErrKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// DeriveKey derives a 32 bytes AES key from password and salt with scrypt,
+// the salt should be random and at least 16 bytes
+func DeriveKey(password []byte, salt []byte) ([]byte, error) {
+	return Scrypt(password, salt, scryptN, scryptR, scryptP, keySize)
+}
+
+// DeriveKeyArgon2id derives a 32 bytes AES key from password and salt with
+// Argon2id, 3 passes over 64 MiB in 4 lanes. The salt should be random and at
+// least 16 bytes.
+func DeriveKeyArgon2id(password []byte, salt []byte) ([]byte, error) {
+	return Argon2id(password, salt, argon2Passes, argon2Memory, argon2Threads, keySize)
+}
+
+type passwordConfig struct {
+	derive func(password []byte, salt []byte) ([]byte, error)
+}
+
+type PasswordOption = opt.Option[passwordConfig]
+
+// WithArgon2id derives the key with DeriveKeyArgon2id rather than the
+// scrypt of DeriveKey, a ciphertext sealed with it is opened with it too
+func WithArgon2id() PasswordOption {
+	return func(c *passwordConfig) {
+		c.derive = DeriveKeyArgon2id
+	}
+}
+
+func passwordKey(password []byte, salt []byte, opts []PasswordOption) ([]byte, error) {
+	cfg := opt.Apply(&passwordConfig{derive: DeriveKey}, opts...)
+	return cfg.derive(password, salt)
+}
+
+// EncryptWithPassword seals plaintext with AES-256-GCM and a key derived
+// from password by DeriveKey, or DeriveKeyArgon2id with WithArgon2id. The
+// ciphertext starts with the random 16 bytes salt, followed by what Encrypt
+// returns.
+func EncryptWithPassword(password []byte, plaintext []byte, opts ...PasswordOption) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := passwordKey(password, salt, opts)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := Encrypt(key, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return append(salt, sealed...), nil
+}
+
+// DecryptWithPassword opens a ciphertext of EncryptWithPassword sealed with
+// the same options, ErrDecrypt when the password is wrong or the data not
+// authentic
+func DecryptWithPassword(password []byte, ciphertext []byte, opts ...PasswordOption) ([]byte, error) {
+	if len(ciphertext) < saltSize {
+		return nil, ErrDecrypt
+	}
+	key, err := passwordKey(password, ciphertext[:saltSize], opts)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(key, ciphertext[saltSize:])
+}
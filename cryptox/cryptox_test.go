@@ -0,0 +1,151 @@
+package cryptox
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	data := []byte("abc")
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"MD5", MD5(data), "900150983cd24fb0d6963f7d28e17f72"},
+		{"SHA1", SHA1(data), "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{"SHA256", SHA256(data), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"SHA512", SHA512(data), "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%v() got = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "f")
+	_ = os.WriteFile(path, data, 0o600)
+	if got, err := HashFile(crypto.SHA256, path); err != nil || got != tests[2].want {
+		t.Errorf("HashFile() got = %v %v", got, err)
+	}
+	if _, err := HashFile(crypto.SHA256, path+".missing"); err == nil {
+		t.Errorf("HashFile() error = nil for a missing file")
+	}
+	if got, _ := HashReader(crypto.MD5, strings.NewReader("abc")); got != tests[0].want {
+		t.Errorf("HashReader() got = %v", got)
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	// RFC 4231 test case 2
+	mac := HMAC(crypto.SHA256, []byte("Jefe"), []byte("what do ya want for nothing?"))
+	if got := hex.EncodeToString(mac); got != "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843" {
+		t.Errorf("HMAC() got = %v", got)
+	}
+	if !VerifyHMAC(crypto.SHA256, []byte("Jefe"), []byte("what do ya want for nothing?"), mac) {
+		t.Errorf("VerifyHMAC() got = false, want true")
+	}
+	if VerifyHMAC(crypto.SHA256, []byte("jefe"), []byte("what do ya want for nothing?"), mac) {
+		t.Errorf("VerifyHMAC() got = true for another key")
+	}
+	if !EqualString("token", "token") || EqualString("token", "tokem") || Equal([]byte("a"), []byte("ab")) {
+		t.Errorf("Equal() got wrong result")
+	}
+}
+
+func TestScrypt(t *testing.T) {
+	// RFC 7914 section 12
+	tests := []struct {
+		password, salt string
+		N, r, p        int
+		want           string
+	}{
+		{"", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+		{"password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+	}
+	for _, tt := range tests {
+		key, err := Scrypt([]byte(tt.password), []byte(tt.salt), tt.N, tt.r, tt.p, 64)
+		if got := hex.EncodeToString(key); err != nil || got != tt.want {
+			t.Errorf("Scrypt(%q, %q) got = %v %v, want %v", tt.password, tt.salt, got, err, tt.want)
+		}
+	}
+	if _, err := Scrypt(nil, nil, 1000, 1, 1, 32); err == nil {
+		t.Errorf("Scrypt() error = nil for N not a power of two")
+	}
+}
+
+func TestArgon2id(t *testing.T) {
+	// vectors of the reference implementation
+	tests := []struct {
+		passes  uint32
+		memory  uint32
+		threads uint8
+		want    string
+	}{
+		{1, 64, 1, "655ad15eac652dc59f7170a7332bf49b8469be1fdb9c28bb"},
+		{2, 64, 2, "350ac37222f436ccb5c0972f1ebd3bf6b958bf2071841362"},
+	}
+	for _, tt := range tests {
+		key, err := Argon2id([]byte("password"), []byte("somesalt"), tt.passes, tt.memory, tt.threads, 24)
+		if got := hex.EncodeToString(key); err != nil || got != tt.want {
+			t.Errorf("Argon2id(%d, %d, %d) got = %v %v, want %v", tt.passes, tt.memory, tt.threads, got, err, tt.want)
+		}
+	}
+	if _, err := Argon2id(nil, nil, 1, 64, 0, 32); err == nil {
+		t.Errorf("Argon2id() error = nil for no threads")
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	plaintext := []byte("attack at dawn")
+	sealed, err := Encrypt(key, plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	again, _ := Encrypt(key, plaintext)
+	if bytes.Equal(sealed, again) {
+		t.Errorf("Encrypt() got the same ciphertext twice, want a random nonce")
+	}
+	if got, err := Decrypt(key, sealed); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt() got = %q %v", got, err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := Decrypt(key, sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Decrypt() tampered error = %v, want %v", err, ErrDecrypt)
+	}
+	if _, err := Decrypt(key, sealed[:5]); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Decrypt() truncated error = %v, want %v", err, ErrDecrypt)
+	}
+	if _, err := Encrypt(key[:10], plaintext); !errors.Is(err, ErrKeySize) {
+		t.Errorf("Encrypt() error = %v, want %v", err, ErrKeySize)
+	}
+
+	sealed, err = EncryptWithPassword([]byte("hunter2"), plaintext)
+	if err != nil {
+		t.Fatalf("EncryptWithPassword() error = %v", err)
+	}
+	if got, err := DecryptWithPassword([]byte("hunter2"), sealed); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("DecryptWithPassword() got = %q %v", got, err)
+	}
+	if _, err := DecryptWithPassword([]byte("hunter3"), sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("DecryptWithPassword() error = %v, want %v", err, ErrDecrypt)
+	}
+
+	sealed, err = EncryptWithPassword([]byte("hunter2"), plaintext, WithArgon2id())
+	if err != nil {
+		t.Fatalf("EncryptWithPassword(WithArgon2id()) error = %v", err)
+	}
+	if got, err := DecryptWithPassword([]byte("hunter2"), sealed, WithArgon2id()); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("DecryptWithPassword(WithArgon2id()) got = %q %v", got, err)
+	}
+	if _, err := DecryptWithPassword([]byte("hunter2"), sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("DecryptWithPassword() of an Argon2id ciphertext error = %v, want %v", err, ErrDecrypt)
+	}
+}
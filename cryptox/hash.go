@@ -0,0 +1,87 @@
+// Package cryptox has one-line helpers over the standard crypto packages:
+// hex digests, HMAC, AES-GCM encryption with keys or passwords and constant
+// time comparison. Keys are derived from passwords with scrypt or Argon2id of
+// golang.org/x/crypto.
+package cryptox
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// MD5 returns the hex MD5 digest of data, for checksums only
+func MD5(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SHA1 returns the hex SHA-1 digest of data, for checksums only
+func SHA1(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SHA256 returns the hex SHA-256 digest of data
+func SHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SHA512 returns the hex SHA-512 digest of data
+func SHA512(data []byte) string {
+	sum := sha512.Sum512(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashReader returns the hex digest of what r yields with h, e.g.
+// crypto.SHA256
+func HashReader(h crypto.Hash, r io.Reader) (string, error) {
+	hash := h.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// HashFile returns the hex digest of the file at path with h, reading it as
+// a stream
+func HashFile(h crypto.Hash, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return HashReader(h, f)
+}
+
+// HMAC returns the HMAC of data with key and h, e.g. crypto.SHA256
+func HMAC(h crypto.Hash, key []byte, data []byte) []byte {
+	mac := hmac.New(h.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// VerifyHMAC reports whether mac is the HMAC of data with key and h, in
+// constant time
+func VerifyHMAC(h crypto.Hash, key []byte, data []byte, mac []byte) bool {
+	return hmac.Equal(HMAC(h, key, data), mac)
+}
+
+// Equal compares a and b in time independent of their content, for secrets
+// such as tokens
+func Equal(a []byte, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// EqualString is Equal for strings
+func EqualString(a string, b string) bool {
+	return Equal([]byte(a), []byte(b))
+}
@@ -0,0 +1,29 @@
+package cryptox
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Scrypt derives a keyLen bytes key from password and salt as RFC 7914
+// specifies. N is the CPU and memory cost, a power of two above 1, the
+// memory used is 128*N*r bytes.
+func Scrypt(password []byte, salt []byte, N int, r int, p int, keyLen int) ([]byte, error) {
+	key, err := scrypt.Key(password, salt, N, r, p, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("cryptox: %w", err)
+	}
+	return key, nil
+}
+
+// Argon2id derives a keyLen bytes key from password and salt with Argon2id
+// as RFC 9106 specifies, in passes over memory KiB split in threads lanes
+func Argon2id(password []byte, salt []byte, passes uint32, memory uint32, threads uint8, keyLen uint32) ([]byte, error) {
+	if passes < 1 || threads < 1 || keyLen < 1 || memory < 8*uint32(threads) {
+		return nil, errors.New("cryptox: argon2id parameters out of range")
+	}
+	return argon2.IDKey(password, salt, passes, memory, threads, keyLen), nil
+}
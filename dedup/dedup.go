@@ -0,0 +1,256 @@
+// Package dedup protects handlers from duplicate submissions, like a form
+// posted twice by a double click. Middleware fingerprints unsafe requests by
+// method, url, body and user and either rejects the duplicates arriving
+// within a window or answers them with the response of the first request.
+package dedup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Mode is what happens to duplicates
+type Mode int
+
+const (
+	// Reject answers duplicates with 409 Conflict
+	Reject Mode = iota
+	// Coalesce waits for the first request and replays its response
+	Coalesce
+)
+
+type config struct {
+	store        Store
+	mode         Mode
+	window       time.Duration
+	user         func(r *http.Request) string
+	maxBody      int64
+	wait         time.Duration
+	pollInterval time.Duration
+}
+
+type Option = opt.Option[config]
+
+// WithStore records the fingerprints in store, a MemoryStore by default
+func WithStore(store Store) Option {
+	return func(c *config) {
+		c.store = store
+	}
+}
+
+// WithMode selects what happens to duplicates, Reject by default
+func WithMode(mode Mode) Option {
+	return func(c *config) {
+		c.mode = mode
+	}
+}
+
+// WithWindow is how long after the first request a duplicate is caught, 10s
+// by default
+func WithWindow(d time.Duration) Option {
+	return func(c *config) {
+		c.window = d
+	}
+}
+
+// WithUser identifies the user sending a request, by default the
+// Authorization header or, without one, the client address
+func WithUser(user func(r *http.Request) string) Option {
+	return func(c *config) {
+		c.user = user
+	}
+}
+
+// WithMaxBody is the largest body fingerprinted and response recorded, 1MB
+// by default. Requests with larger bodies pass unchecked.
+func WithMaxBody(n int64) Option {
+	return func(c *config) {
+		c.maxBody = n
+	}
+}
+
+// WithWait bounds how long Coalesce waits for the first request, 30s by
+// default, and how often it asks the store for its response, 50ms by default
+func WithWait(wait time.Duration, pollInterval time.Duration) Option {
+	return func(c *config) {
+		c.wait = wait
+		c.pollInterval = pollInterval
+	}
+}
+
+func defaultUser(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		return auth
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Fingerprint hashes method, url, body and user of a request
+func Fingerprint(r *http.Request, body []byte, user string) string {
+	h := sha256.New()
+	for _, part := range []string{r.Method, r.URL.RequestURI(), user} {
+		_, _ = io.WriteString(h, part)
+		_, _ = h.Write([]byte{0})
+	}
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Middleware catches duplicates of POST, PUT, PATCH and DELETE requests.
+// Duplicates get the X-Duplicate-Request header. Responses of 5xx release
+// the fingerprint, so the request can be retried. Store failures let the
+// request through unchecked.
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	cfg := opt.Apply(&config{
+		mode:         Reject,
+		window:       10 * time.Second,
+		user:         defaultUser,
+		maxBody:      1 << 20,
+		wait:         30 * time.Second,
+		pollInterval: 50 * time.Millisecond,
+	}, opts...)
+	if cfg.store == nil {
+		cfg.store = NewMemoryStore()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+			body, err := io.ReadAll(io.LimitReader(r.Body, cfg.maxBody+1))
+			if err != nil {
+				http.Error(w, "reading request body failed", http.StatusBadRequest)
+				return
+			}
+			if int64(len(body)) > cfg.maxBody {
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+				next.ServeHTTP(w, r)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			key := Fingerprint(r, body, cfg.user(r))
+			resp, first, err := cfg.store.Begin(ctx, key, time.Now().Add(cfg.window))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !first {
+				duplicate(w, r, cfg, key, resp)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK, max: cfg.maxBody}
+			completed := false
+			defer func() {
+				// a panicking handler releases the fingerprint as well
+				if !completed || rec.status >= 500 {
+					_ = cfg.store.Release(ctx, key)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			completed = true
+			if rec.status < 500 {
+				recorded := &Response{Status: rec.status, Header: w.Header().Clone(), Body: rec.body.Bytes(), Truncated: rec.truncated}
+				_ = cfg.store.Finish(ctx, key, recorded)
+			}
+		})
+	}
+}
+
+func duplicate(w http.ResponseWriter, r *http.Request, cfg *config, key string, resp *Response) {
+	w.Header().Set("X-Duplicate-Request", "true")
+	if cfg.mode == Reject {
+		http.Error(w, "duplicate request", http.StatusConflict)
+		return
+	}
+	if resp == nil {
+		timeout := time.NewTimer(cfg.wait)
+		defer timeout.Stop()
+		ticker := time.NewTicker(cfg.pollInterval)
+		defer ticker.Stop()
+		for resp == nil {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-timeout.C:
+				http.Error(w, "duplicate request still in progress", http.StatusConflict)
+				return
+			case <-ticker.C:
+			}
+			var first bool
+			var err error
+			// Begin of a released or expired key claims it, the request then
+			// runs as a first one would, so give it back
+			resp, first, err = cfg.store.Begin(r.Context(), key, time.Now().Add(cfg.window))
+			if err != nil {
+				http.Error(w, "duplicate request", http.StatusConflict)
+				return
+			}
+			if first {
+				_ = cfg.store.Release(r.Context(), key)
+				http.Error(w, "duplicate request failed, retry", http.StatusConflict)
+				return
+			}
+		}
+	}
+	if resp.Truncated {
+		http.Error(w, "duplicate request", http.StatusConflict)
+		return
+	}
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("X-Duplicate-Request", "true")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+// recorder keeps the status and up to max bytes of the body
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	max         int64
+	truncated   bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	if !r.truncated {
+		if int64(r.body.Len()+len(p)) > r.max {
+			r.truncated = true
+			r.body.Reset()
+		} else {
+			r.body.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
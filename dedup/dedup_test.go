@@ -0,0 +1,187 @@
+package dedup
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func send(handler http.Handler, method string, body string, auth string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/orders", strings.NewReader(body))
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestReject(t *testing.T) {
+	var calls int32
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	}))
+
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		auth      string
+		want      int
+		duplicate bool
+	}{
+		{"first", http.MethodPost, "a", "ann", http.StatusCreated, false},
+		{"duplicate", http.MethodPost, "a", "ann", http.StatusConflict, true},
+		{"other body", http.MethodPost, "b", "ann", http.StatusCreated, false},
+		{"other user", http.MethodPost, "a", "bob", http.StatusCreated, false},
+		{"other method", http.MethodPut, "a", "ann", http.StatusCreated, false},
+		{"safe method", http.MethodGet, "", "ann", http.StatusCreated, false},
+		{"safe method again", http.MethodGet, "", "ann", http.StatusCreated, false},
+	}
+	for _, tt := range tests {
+		w := send(handler, tt.method, tt.body, tt.auth)
+		if w.Code != tt.want {
+			t.Errorf("%v status got = %v, want %v", tt.name, w.Code, tt.want)
+		}
+		if got := w.Header().Get("X-Duplicate-Request") == "true"; got != tt.duplicate {
+			t.Errorf("%v X-Duplicate-Request got = %v, want %v", tt.name, got, tt.duplicate)
+		}
+	}
+	if calls != 6 {
+		t.Errorf("handler calls got = %v, want 6", calls)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	handler := Middleware(WithMode(Coalesce), WithWait(time.Second, time.Millisecond))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Header().Set("Location", "/orders/1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, "order 1")
+	}))
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 3)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = send(handler, http.MethodPost, "a", "ann")
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	duplicates := 0
+	for _, w := range responses {
+		if w.Code != http.StatusCreated || w.Body.String() != "order 1" || w.Header().Get("Location") != "/orders/1" {
+			t.Errorf("response got = %v %q %v", w.Code, w.Body.String(), w.Header())
+		}
+		if w.Header().Get("X-Duplicate-Request") == "true" {
+			duplicates++
+		}
+	}
+	if calls != 1 || duplicates != 2 {
+		t.Errorf("handler calls got = %v, duplicates %v, want 1 and 2", calls, duplicates)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	status := int32(http.StatusServiceUnavailable)
+	handler := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	if w := send(handler, http.MethodPost, "a", ""); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first status got = %v", w.Code)
+	}
+	atomic.StoreInt32(&status, http.StatusOK)
+	if w := send(handler, http.MethodPost, "a", ""); w.Code != http.StatusOK {
+		t.Errorf("retry after 5xx status got = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	panics := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	for i := 0; i < 2; i++ {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("request %v got = no panic, want the handler to run", i)
+				}
+			}()
+			send(panics, http.MethodPost, "a", "")
+		}()
+	}
+}
+
+func TestWindow(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	handler := Middleware(WithStore(store), WithWindow(time.Second))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	if w := send(handler, http.MethodPost, "a", ""); w.Code != http.StatusOK {
+		t.Fatalf("first status got = %v", w.Code)
+	}
+	if w := send(handler, http.MethodPost, "a", ""); w.Code != http.StatusConflict {
+		t.Errorf("duplicate status got = %v, want %v", w.Code, http.StatusConflict)
+	}
+	now = now.Add(2 * time.Second)
+	if w := send(handler, http.MethodPost, "a", ""); w.Code != http.StatusOK {
+		t.Errorf("after window status got = %v, want %v", w.Code, http.StatusOK)
+	}
+}
+
+func TestMaxBody(t *testing.T) {
+	var got string
+	handler := Middleware(WithMaxBody(4))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = string(body)
+	}))
+	for i := 0; i < 2; i++ {
+		if w := send(handler, http.MethodPost, "too long", ""); w.Code != http.StatusOK || got != "too long" {
+			t.Errorf("large body got = %v %q, want it passed unchecked", w.Code, got)
+		}
+	}
+
+	// a response too large to record can't be replayed
+	large := Middleware(WithMaxBody(4), WithMode(Coalesce))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "a long response")
+	}))
+	send(large, http.MethodPost, "a", "")
+	if w := send(large, http.MethodPost, "a", ""); w.Code != http.StatusConflict {
+		t.Errorf("duplicate of large response status got = %v, want %v", w.Code, http.StatusConflict)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Begin(ctx context.Context, key string, expires time.Time) (*Response, bool, error) {
+	return nil, false, io.ErrUnexpectedEOF
+}
+
+func (failingStore) Finish(ctx context.Context, key string, resp *Response) error { return nil }
+
+func (failingStore) Release(ctx context.Context, key string) error { return nil }
+
+func TestStoreFailure(t *testing.T) {
+	handler := Middleware(WithStore(failingStore{}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 2; i++ {
+		if w := send(handler, http.MethodPost, "a", ""); w.Code != http.StatusOK {
+			t.Errorf("store failure status got = %v, want %v", w.Code, http.StatusOK)
+		}
+	}
+}
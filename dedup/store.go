@@ -0,0 +1,81 @@
+package dedup
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Response is the response of the first request, replayed to its duplicates
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// Truncated is set when the body was too large to record
+	Truncated bool
+}
+
+// Store records the fingerprints of requests, share one between instances
+// to catch duplicates hitting different instances
+type Store interface {
+	// Begin claims key until expires. It returns true for a new key, or false
+	// and the response recorded for key, nil while the first request runs.
+	Begin(ctx context.Context, key string, expires time.Time) (*Response, bool, error)
+	// Finish records the response of the first request of key
+	Finish(ctx context.Context, key string, resp *Response) error
+	// Release forgets key, so a failed request can be sent again
+	Release(ctx context.Context, key string) error
+}
+
+type entry struct {
+	resp    *Response
+	expires time.Time
+}
+
+// MemoryStore keeps the fingerprints in memory, for a single instance
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*entry), now: time.Now}
+}
+
+func (s *MemoryStore) Begin(ctx context.Context, key string, expires time.Time) (*Response, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.After(s.nextSweep) {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+	if e, ok := s.entries[key]; ok && !now.After(e.expires) {
+		return e.resp, false, nil
+	}
+	s.entries[key] = &entry{expires: expires}
+	return nil, true, nil
+}
+
+func (s *MemoryStore) Finish(ctx context.Context, key string, resp *Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		e.resp = resp
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
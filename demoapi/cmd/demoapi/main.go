@@ -0,0 +1,56 @@
+// Command demoapi serves a mock REST API generated from a schema file:
+//
+//	demoapi -schema shop.yaml -addr :8080 -seed 1
+//
+// See package demoapi for the schema and the endpoints.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Stellar1999/gotool/demoapi"
+)
+
+func main() {
+	schemaPath := flag.String("schema", "schema.yaml", "schema file, YAML or JSON")
+	addr := flag.String("addr", ":8080", "listen address")
+	seed := flag.Int64("seed", 0, "seed of the generated data, random when 0")
+	perPage := flag.Int("per-page", 20, "default page size")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	flag.Parse()
+
+	schema, err := demoapi.LoadSchema(*schemaPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts := []demoapi.Option{demoapi.WithPerPage(*perPage), demoapi.WithLatency(*latency)}
+	if *seed != 0 {
+		opts = append(opts, demoapi.WithSeed(*seed))
+	}
+	handler, err := demoapi.New(schema, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := &http.Server{Addr: *addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdown)
+	}()
+	log.Printf("serving %s on %s", *schemaPath, *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,376 @@
+// Package demoapi serves a mock REST API over generated records, a sandbox for
+// frontends to develop against before the real backend exists. Each resource
+// of the Schema gets CRUD endpoints:
+//
+//	GET    /users              list, with paging, filtering and sorting
+//	POST   /users              create, the id is assigned
+//	GET    /users/{id}         read
+//	PUT    /users/{id}         replace
+//	PATCH  /users/{id}         merge the given fields
+//	DELETE /users/{id}         delete
+//
+// Lists take page and per_page, sort=field or sort=-field for descending
+// order, q to search the string fields and field=value to filter. They
+// answer with a JSON array, the X-Total-Count header and a Link header to
+// the next page, as the http package's Paginate follows by default.
+//
+// Changes live in memory until the server stops.
+package demoapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type config struct {
+	seed    int64
+	perPage int
+	latency time.Duration
+}
+
+type Option = opt.Option[config]
+
+// WithSeed makes the generated records reproducible, the same seed gives the
+// same data. The current time is used by default.
+func WithSeed(seed int64) Option {
+	return func(c *config) {
+		c.seed = seed
+	}
+}
+
+// WithPerPage is the page size of lists without per_page, 20 by default
+func WithPerPage(n int) Option {
+	return func(c *config) {
+		c.perPage = n
+	}
+}
+
+// WithLatency delays every response by d, to see loading states
+func WithLatency(d time.Duration) Option {
+	return func(c *config) {
+		c.latency = d
+	}
+}
+
+// Server is an http.Handler serving the resources of a Schema
+type Server struct {
+	schema *Schema
+	cfg    config
+
+	mu          sync.RWMutex
+	collections map[string]*collection
+}
+
+type collection struct {
+	records []map[string]any
+	nextID  int64
+}
+
+// New generates the records of schema
+func New(schema *Schema, opts ...Option) (*Server, error) {
+	cfg := config{seed: time.Now().UnixNano(), perPage: 20}
+	if err := opt.Build(&cfg, opts); err != nil {
+		return nil, err
+	}
+	if err := schema.validate(); err != nil {
+		return nil, err
+	}
+	r := rand.New(rand.NewSource(cfg.seed))
+	s := &Server{schema: schema, cfg: cfg, collections: make(map[string]*collection)}
+	for _, name := range schema.names() {
+		resource := schema.Resources[name]
+		count := resource.Count
+		if count == 0 {
+			count = 10
+		}
+		fields := make([]string, 0, len(resource.Fields))
+		for field := range resource.Fields {
+			fields = append(fields, field)
+		}
+		// map order is random, fields are generated in order to honor the seed
+		sort.Strings(fields)
+		c := &collection{nextID: 1}
+		for i := 0; i < count; i++ {
+			record := map[string]any{"id": c.nextID}
+			for _, field := range fields {
+				if field != "id" {
+					record[field] = fake(r, resource.Fields[field])
+				}
+			}
+			c.records = append(c.records, record)
+			c.nextID++
+		}
+		s.collections[name] = c
+	}
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.latency > 0 {
+		select {
+		case <-time.After(s.cfg.latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, s.schema.names())
+		return
+	}
+	name, id, hasID := strings.Cut(path, "/")
+	if _, ok := s.collections[name]; !ok || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, "no such resource")
+		return
+	}
+	if !hasID {
+		switch r.Method {
+		case http.MethodGet:
+			s.list(w, r, name)
+		case http.MethodPost:
+			s.create(w, r, name)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+	recordID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "no such record")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.read(w, name, recordID)
+	case http.MethodPut, http.MethodPatch:
+		s.update(w, r, name, recordID, r.Method == http.MethodPatch)
+	case http.MethodDelete:
+		s.delete(w, name, recordID)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, name string) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(query.Get("per_page"))
+	if perPage < 1 {
+		perPage = s.cfg.perPage
+	}
+
+	s.mu.RLock()
+	var matches []map[string]any
+	for _, record := range s.collections[name].records {
+		if matchRecord(record, query) {
+			matches = append(matches, copyRecord(record))
+		}
+	}
+	s.mu.RUnlock()
+
+	if field := query.Get("sort"); field != "" {
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		sort.SliceStable(matches, func(i, j int) bool {
+			if desc {
+				return less(matches[j][field], matches[i][field])
+			}
+			return less(matches[i][field], matches[j][field])
+		})
+	}
+
+	total := len(matches)
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if end < total {
+		next := *r.URL
+		values := next.Query()
+		values.Set("page", strconv.Itoa(page+1))
+		values.Set("per_page", strconv.Itoa(perPage))
+		next.RawQuery = values.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+	writeJSON(w, http.StatusOK, append([]map[string]any{}, matches[start:end]...))
+}
+
+// reserved query parameters, the others filter
+var listParams = map[string]bool{"page": true, "per_page": true, "sort": true, "q": true}
+
+func matchRecord(record map[string]any, query url.Values) bool {
+	for field, values := range query {
+		if listParams[field] {
+			continue
+		}
+		if fmt.Sprint(record[field]) != values[0] {
+			return false
+		}
+	}
+	q := strings.ToLower(query.Get("q"))
+	if q == "" {
+		return true
+	}
+	for _, value := range record {
+		if s, ok := value.(string); ok && strings.Contains(strings.ToLower(s), q) {
+			return true
+		}
+	}
+	return false
+}
+
+// less orders numbers, strings and bools, missing values first
+func less(a any, b any) bool {
+	switch x := a.(type) {
+	case nil:
+		return b != nil
+	case string:
+		y, ok := b.(string)
+		return ok && x < y
+	case bool:
+		y, ok := b.(bool)
+		return ok && !x && y
+	}
+	x, okX := number(a)
+	y, okY := number(b)
+	return okX && okY && x < y
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func (s *Server) read(w http.ResponseWriter, name string, id int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c := s.collections[name]
+	if i := c.find(id); i >= 0 {
+		writeJSON(w, http.StatusOK, copyRecord(c.records[i]))
+		return
+	}
+	writeError(w, http.StatusNotFound, "no such record")
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request, name string) {
+	record, ok := decodeRecord(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.collections[name]
+	record["id"] = c.nextID
+	c.nextID++
+	c.records = append(c.records, record)
+	w.Header().Set("Location", "/"+name+"/"+strconv.FormatInt(record["id"].(int64), 10))
+	writeJSON(w, http.StatusCreated, copyRecord(record))
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request, name string, id int64, merge bool) {
+	record, ok := decodeRecord(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.collections[name]
+	i := c.find(id)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "no such record")
+		return
+	}
+	if merge {
+		for field, value := range record {
+			c.records[i][field] = value
+		}
+	} else {
+		c.records[i] = record
+	}
+	c.records[i]["id"] = id
+	writeJSON(w, http.StatusOK, copyRecord(c.records[i]))
+}
+
+func (s *Server) delete(w http.ResponseWriter, name string, id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.collections[name]
+	i := c.find(id)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "no such record")
+		return
+	}
+	c.records = append(c.records[:i], c.records[i+1:]...)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *collection) find(id int64) int {
+	// records are kept in id order
+	i := sort.Search(len(c.records), func(i int) bool { return c.records[i]["id"].(int64) >= id })
+	if i < len(c.records) && c.records[i]["id"].(int64) == id {
+		return i
+	}
+	return -1
+}
+
+func decodeRecord(w http.ResponseWriter, r *http.Request) (map[string]any, bool) {
+	var record map[string]any
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.UseNumber()
+	if err := decoder.Decode(&record); err != nil || record == nil {
+		writeError(w, http.StatusBadRequest, "body must be a JSON object")
+		return nil, false
+	}
+	for field, value := range record {
+		// numbers are stored like generated ones, so they sort and filter alike
+		if n, ok := value.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				record[field] = i
+			} else {
+				record[field], _ = n.Float64()
+			}
+		}
+	}
+	return record, true
+}
+
+func copyRecord(record map[string]any) map[string]any {
+	out := make(map[string]any, len(record))
+	for field, value := range record {
+		out[field] = value
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
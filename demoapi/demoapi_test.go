@@ -0,0 +1,190 @@
+package demoapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+const shop = `
+resources:
+  users:
+    count: 45
+    fields:
+      name: name
+      email: email
+      age: {type: int, min: 18, max: 90}
+      role: {type: enum, values: [admin, member]}
+      joined: date
+  orders:
+    count: 3
+    fields:
+      total: {type: float, min: 1, max: 500}
+      paid: bool
+`
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	schema, err := ParseSchema([]byte(shop))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	api, err := New(schema, WithSeed(1))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParseSchema(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"resources": {"tags": {"fields": {"label": "word", "weight": {"type": "int", "max": 5}}}}}`))
+	if err != nil {
+		t.Fatalf("ParseSchema() JSON error = %v", err)
+	}
+	want := map[string]Field{"label": {Type: TypeWord}, "weight": {Type: TypeInt, Max: 5}}
+	if got := schema.Resources["tags"].Fields; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSchema() fields got = %v, want %v", got, want)
+	}
+
+	tests := []string{
+		`resources: {}`,
+		`resources: {a: {fields: {x: nope}}}`,
+		`resources: {a: {fields: {x: {type: enum}}}}`,
+		`resources: {a: {fields: {x: {type: int, min: 5, max: 1}}}}`,
+		`resources: [`,
+	}
+	for _, tt := range tests {
+		if _, err := ParseSchema([]byte(tt)); !errors.Is(err, ErrSchema) {
+			t.Errorf("ParseSchema(%q) error got = %v, want %v", tt, err, ErrSchema)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	server := newServer(t)
+	client := gohttp.NewClient()
+
+	var users []map[string]any
+	pager := client.Paginate(context.Background(), server.URL+"/users?per_page=10")
+	for pager.Next() {
+		var page []map[string]any
+		if err := pager.Page().Response.JSON(&page); err != nil {
+			t.Fatalf("JSON() error = %v", err)
+		}
+		users = append(users, page...)
+	}
+	if err := pager.Err(); err != nil || len(users) != 45 || pager.Page().Number != 5 {
+		t.Fatalf("Paginate() got = %v users, %v, want 45 on 5 pages", len(users), err)
+	}
+	for i, user := range users {
+		age := user["age"].(float64)
+		if user["id"].(float64) != float64(i+1) || age < 18 || age > 90 || !strings.Contains(user["email"].(string), "@example.") {
+			t.Errorf("user got = %v", user)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		check func(users []map[string]any) bool
+	}{
+		{"filter", "?role=admin&per_page=100", func(users []map[string]any) bool {
+			for _, u := range users {
+				if u["role"] != "admin" {
+					return false
+				}
+			}
+			return len(users) > 0
+		}},
+		{"sort desc", "?sort=-age&per_page=100", func(users []map[string]any) bool {
+			for i := 1; i < len(users); i++ {
+				if users[i-1]["age"].(float64) < users[i]["age"].(float64) {
+					return false
+				}
+			}
+			return len(users) == 45
+		}},
+		{"search", "?q=" + strings.ToLower(strings.Fields(users[0]["name"].(string))[1]), func(users []map[string]any) bool {
+			return len(users) > 0
+		}},
+		{"last page", "?page=5&per_page=10", func(users []map[string]any) bool {
+			return len(users) == 5 && users[0]["id"].(float64) == 41
+		}},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + "/users" + tt.query)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		var got []map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		_ = resp.Body.Close()
+		if !tt.check(got) {
+			t.Errorf("%v got = %v", tt.name, got)
+		}
+	}
+}
+
+func TestRecords(t *testing.T) {
+	server := newServer(t)
+	client := gohttp.NewClient()
+	url := server.URL + "/orders/{id}"
+
+	// the client reports statuses other than 200 as errors, with the response
+	resp, err := client.NewRequest(gohttp.POST, server.URL+"/orders").Body(map[string]any{"total": 12, "paid": false}).Send()
+	var created map[string]any
+	if !isStatus(err, http.StatusCreated) {
+		t.Fatalf("POST error got = %v, want 201", err)
+	}
+	_ = resp.JSON(&created)
+	if created["id"] != float64(4) || created["total"] != float64(12) || resp.Header.Get("Location") != "/orders/4" {
+		t.Errorf("POST got = %v %v, want id 4", created, resp.Header)
+	}
+
+	resp, err = client.NewRequest(gohttp.PATCH, url).PathParam("id", "2").Body(map[string]any{"paid": true, "note": "gift"}).Send()
+	var order map[string]any
+	if err != nil {
+		t.Fatalf("PATCH error = %v", err)
+	}
+	_ = resp.JSON(&order)
+	if order["paid"] != true || order["note"] != "gift" || order["total"] == nil || order["id"] != float64(2) {
+		t.Errorf("PATCH got = %v", order)
+	}
+
+	resp, err = client.NewRequest(gohttp.PUT, url).PathParam("id", "2").Body(map[string]any{"total": 1.5}).Send()
+	if err != nil {
+		t.Fatalf("PUT error = %v", err)
+	}
+	order = nil
+	_ = resp.JSON(&order)
+	if !reflect.DeepEqual(order, map[string]any{"id": float64(2), "total": 1.5}) {
+		t.Errorf("PUT got = %v", order)
+	}
+
+	if _, err := client.NewRequest(gohttp.DELETE, url).PathParam("id", "2").Send(); !isStatus(err, http.StatusNoContent) {
+		t.Fatalf("DELETE error got = %v, want 204", err)
+	}
+	if _, err := client.NewRequest(gohttp.GET, url).PathParam("id", "2").Send(); !isStatus(err, http.StatusNotFound) {
+		t.Errorf("GET deleted error got = %v, want 404", err)
+	}
+	if _, err := client.NewRequest(gohttp.GET, server.URL+"/nope").Send(); !isStatus(err, http.StatusNotFound) {
+		t.Errorf("GET unknown resource error got = %v, want 404", err)
+	}
+	if _, err := client.NewRequest(gohttp.POST, server.URL+"/orders").RawBody([]byte("[1]")).Send(); !isStatus(err, http.StatusBadRequest) {
+		t.Errorf("POST array error got = %v, want 400", err)
+	}
+}
+
+func isStatus(err error, code int) bool {
+	var statusErr *gohttp.StatusError
+	return errors.As(err, &statusErr) && statusErr.Code == code
+}
@@ -0,0 +1,71 @@
+package demoapi
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+var (
+	firstNames = []string{"Ada", "Alan", "Anna", "Ben", "Clara", "David", "Elena", "Felix", "Grace", "Hugo", "Ines", "James", "Kim", "Leo", "Maya", "Nina", "Omar", "Paula", "Ravi", "Sara", "Tom", "Uma", "Victor", "Wen", "Yusuf", "Zoe"}
+	lastNames  = []string{"Adams", "Baker", "Chen", "Diaz", "Evans", "Fischer", "Garcia", "Hopper", "Ito", "Jensen", "Khan", "Lovelace", "Martin", "Novak", "Okafor", "Patel", "Rossi", "Silva", "Turing", "Weber"}
+	words      = []string{"alpha", "amber", "bridge", "cloud", "delta", "ember", "forest", "garden", "harbor", "island", "jade", "lake", "meadow", "nova", "orbit", "pepper", "quartz", "river", "stone", "tiger", "urban", "valley", "willow", "zenith"}
+	domains    = []string{"example.com", "example.org", "example.net"}
+)
+
+// epoch is where generated dates start, they span the 3 years after it
+var epoch = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fake generates a value for spec
+func fake(r *rand.Rand, spec Field) any {
+	switch spec.Type {
+	case TypeInt:
+		min, max := bounds(spec)
+		return int64(min) + r.Int63n(int64(max)-int64(min)+1)
+	case TypeFloat:
+		min, max := bounds(spec)
+		return math.Round((min+r.Float64()*(max-min))*100) / 100
+	case TypeBool:
+		return r.Intn(2) == 1
+	case TypeString, TypeWord:
+		return pick(r, words)
+	case TypeSentence:
+		n := 4 + r.Intn(8)
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = pick(r, words)
+		}
+		sentence := strings.Join(parts, " ") + "."
+		return strings.ToUpper(sentence[:1]) + sentence[1:]
+	case TypeName:
+		return pick(r, firstNames) + " " + pick(r, lastNames)
+	case TypeEmail:
+		return strings.ToLower(pick(r, firstNames)+"."+pick(r, lastNames)) + fmt.Sprintf("%d@", r.Intn(100)) + pick(r, domains)
+	case TypeURL:
+		return "https://" + pick(r, words) + "." + pick(r, domains) + "/" + pick(r, words)
+	case TypeDate:
+		return epoch.Add(time.Duration(r.Int63n(int64(3 * 365 * 24 * time.Hour)))).Truncate(time.Second).Format(time.RFC3339)
+	case TypeUUID:
+		var b [16]byte
+		_, _ = r.Read(b[:])
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case TypeEnum:
+		return pick(r, spec.Values)
+	}
+	return nil
+}
+
+func bounds(spec Field) (float64, float64) {
+	if spec.Min == 0 && spec.Max == 0 {
+		return 0, 1000
+	}
+	return spec.Min, spec.Max
+}
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.Intn(len(values))]
+}
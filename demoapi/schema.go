@@ -0,0 +1,129 @@
+package demoapi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrSchema is returned for invalid schemas
+var ErrSchema = errors.New("demoapi: invalid schema")
+
+// Field types of the generated records
+const (
+	TypeInt      = "int"
+	TypeFloat    = "float"
+	TypeBool     = "bool"
+	TypeString   = "string"
+	TypeWord     = "word"
+	TypeSentence = "sentence"
+	TypeName     = "name"
+	TypeEmail    = "email"
+	TypeURL      = "url"
+	TypeDate     = "date"
+	TypeUUID     = "uuid"
+	TypeEnum     = "enum"
+)
+
+// Schema describes the resources served, in YAML or JSON:
+//
+//	resources:
+//	  users:
+//	    count: 50
+//	    fields:
+//	      name: name
+//	      email: email
+//	      age: {type: int, min: 18, max: 90}
+//	      role: {type: enum, values: [admin, member]}
+//
+// Every record gets an integer "id" counting from 1.
+type Schema struct {
+	Resources map[string]Resource `yaml:"resources"`
+}
+
+// Resource is a collection of records served under /<name>
+type Resource struct {
+	// Count is how many records are generated, 10 by default
+	Count  int              `yaml:"count"`
+	Fields map[string]Field `yaml:"fields"`
+}
+
+// Field describes the values generated for a field. In the schema a field is
+// either its type or an object with the type and its bounds.
+type Field struct {
+	Type string `yaml:"type"`
+	// Min and Max bound int and float values, 0 to 1000 by default
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+	// Values are picked from by enum fields
+	Values []string `yaml:"values"`
+}
+
+func (f *Field) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&f.Type)
+	}
+	type plain Field
+	return node.Decode((*plain)(f))
+}
+
+// ParseSchema parses a schema in YAML or JSON
+func ParseSchema(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := yaml.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSchema, err)
+	}
+	if err := schema.validate(); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// LoadSchema reads the schema at path
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchema(data)
+}
+
+func (s *Schema) validate() error {
+	if len(s.Resources) == 0 {
+		return fmt.Errorf("%w: no resources", ErrSchema)
+	}
+	for name, resource := range s.Resources {
+		if resource.Count < 0 {
+			return fmt.Errorf("%w: %s: negative count", ErrSchema, name)
+		}
+		for field, spec := range resource.Fields {
+			switch spec.Type {
+			case TypeInt, TypeFloat:
+				if spec.Max != 0 && spec.Max < spec.Min {
+					return fmt.Errorf("%w: %s.%s: max below min", ErrSchema, name, field)
+				}
+			case TypeEnum:
+				if len(spec.Values) == 0 {
+					return fmt.Errorf("%w: %s.%s: enum without values", ErrSchema, name, field)
+				}
+			case TypeBool, TypeString, TypeWord, TypeSentence, TypeName, TypeEmail, TypeURL, TypeDate, TypeUUID:
+			default:
+				return fmt.Errorf("%w: %s.%s: unknown type %q", ErrSchema, name, field, spec.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// names returns the resource names in order
+func (s *Schema) names() []string {
+	names := make([]string, 0, len(s.Resources))
+	for name := range s.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
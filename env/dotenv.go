@@ -0,0 +1,171 @@
+package env
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Load sets the variables of the .env files at paths, ".env" when none are
+// given. Variables already set keep their value, so the real environment
+// overrides the files, and earlier files override later ones. A missing file
+// is not an error.
+func Load(paths ...string) error {
+	if len(paths) == 0 {
+		paths = []string{".env"}
+	}
+	for _, path := range paths {
+		vars, err := Read(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for k, v := range vars {
+			if _, ok := os.LookupEnv(k); ok {
+				continue
+			}
+			if err := os.Setenv(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Read parses the .env file at path
+func Read(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vars, err := ParseDotenv(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// ParseDotenv parses KEY=VALUE lines. Lines may start with "export ", # starts
+// a comment outside quotes, values in single quotes are taken literally and
+// values in double quotes may span lines and contain \n, \t, \" and \\
+// escapes. Unquoted and double quoted values expand ${VAR} and $VAR with the
+// variables above them, or else the environment.
+func ParseDotenv(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	expand := func(s string) string {
+		return os.Expand(s, func(key string) string {
+			if key == "$" {
+				// $$, or \$ in double quotes
+				return "$"
+			}
+			if v, ok := vars[key]; ok {
+				return v
+			}
+			return os.Getenv(key)
+		})
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for n := 0; n < len(lines); n++ {
+		lineNo := n + 1
+		line := strings.TrimSpace(lines[n])
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validKey(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quote", lineNo)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			// a quoted value continues on the following lines until its quote closes
+			raw := value[1:]
+			for {
+				s, closed := unquote(raw)
+				if closed {
+					value = expand(s)
+					break
+				}
+				n++
+				if n == len(lines) {
+					return nil, fmt.Errorf("line %d: unterminated double quote", lineNo)
+				}
+				raw += "\n" + lines[n]
+			}
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+			value = expand(value)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// unquote reads s up to its closing double quote, replacing escapes, and
+// reports whether the quote was found
+func unquote(s string) (string, bool) {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			return b.String(), true
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(s[i])
+			case '$':
+				// kept as $$ for expand to leave a $
+				b.WriteString("$$")
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false
+}
+
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && (r >= '0' && r <= '9' || r == '.')) {
+			return false
+		}
+	}
+	return true
+}
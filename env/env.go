@@ -0,0 +1,237 @@
+// Package env reads typed values from environment variables:
+//
+//	timeout := env.Get("HTTP_TIMEOUT", 10*time.Second)
+//
+// A Loader collects every missing or invalid variable, so a service reports
+// all of its configuration problems at startup at once:
+//
+//	l := env.NewLoader(env.WithPrefix("APP_"))
+//	addr := env.Required[string](l, "ADDR")
+//	maxBody := env.Optional(l, "MAX_BODY", 10*env.MiB)
+//	hosts := env.Optional(l, "HOSTS", []string{"localhost"})
+//	if err := l.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Values are parsed by type: booleans take true, false, 1, 0, yes, no, on and
+// off, durations are written like 1m30s, Size like 10MiB, slices are
+// separated by commas and types implementing encoding.TextUnmarshaler parse
+// themselves.
+package env
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Stellar1999/gotool/convert"
+	"github.com/Stellar1999/gotool/errorx"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+var (
+	// ErrMissing is returned for a required variable that is not set
+	ErrMissing = errors.New("env: variable not set")
+	// ErrInvalid is returned for a value that can't be parsed as its type
+	ErrInvalid = errors.New("env: invalid value")
+)
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	sizeType        = reflect.TypeOf(Size(0))
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Get returns the value of the variable key as a T, or def when it is unset,
+// empty or invalid. Use Lookup or a Loader to report invalid values.
+func Get[T any](key string, def T) T {
+	v, ok, err := Lookup[T](key)
+	if !ok || err != nil {
+		return def
+	}
+	return v
+}
+
+// Lookup returns the value of the variable key as a T and whether it is set
+// to a non-empty value
+func Lookup[T any](key string) (T, bool, error) {
+	return lookup[T](os.LookupEnv, key)
+}
+
+func lookup[T any](lookupEnv func(string) (string, bool), key string) (T, bool, error) {
+	var v T
+	s, ok := lookupEnv(key)
+	if !ok || strings.TrimSpace(s) == "" {
+		return v, false, nil
+	}
+	v, err := Parse[T](s)
+	if err != nil {
+		return v, true, fmt.Errorf("%w: %s: %v", ErrInvalid, key, err)
+	}
+	return v, true, nil
+}
+
+// Parse parses s as a T the way Get does
+func Parse[T any](s string) (T, error) {
+	var v T
+	rv := reflect.ValueOf(&v).Elem()
+	err := parse(rv, s)
+	return v, err
+}
+
+func parse(v reflect.Value, s string) error {
+	t := v.Type()
+	if reflect.PtrTo(t).Implements(textUnmarshaler) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	s = strings.TrimSpace(s)
+	switch t {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case sizeType:
+		size, err := ParseSize(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(size))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := convert.ToBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		if s != "" {
+			items = strings.Split(s, ",")
+		}
+		out := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := parse(out.Index(i), item); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		v.Set(out)
+	case reflect.Ptr:
+		p := reflect.New(t.Elem())
+		if err := parse(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+	default:
+		return fmt.Errorf("unsupported type %v", t)
+	}
+	return nil
+}
+
+type config struct {
+	prefix string
+	lookup func(key string) (string, bool)
+}
+
+type Option = opt.Option[config]
+
+// WithPrefix prepends prefix to the keys a Loader reads, e.g. "APP_"
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// WithLookup reads variables with fn instead of os.LookupEnv, for tests
+func WithLookup(fn func(key string) (string, bool)) Option {
+	return func(c *config) {
+		c.lookup = fn
+	}
+}
+
+// Loader reads variables and collects their problems for Err
+type Loader struct {
+	cfg  config
+	errs []error
+}
+
+// NewLoader returns a Loader reading the environment
+func NewLoader(opts ...Option) *Loader {
+	return &Loader{cfg: *opt.Apply(&config{lookup: os.LookupEnv}, opts...)}
+}
+
+// Required returns the variable key as a T, recording ErrMissing when it is
+// unset or empty and ErrInvalid when it can't be parsed
+func Required[T any](l *Loader, key string) T {
+	v, ok, err := lookup[T](l.cfg.lookup, l.cfg.prefix+key)
+	switch {
+	case err != nil:
+		l.errs = append(l.errs, err)
+	case !ok:
+		l.errs = append(l.errs, fmt.Errorf("%w: %s", ErrMissing, l.cfg.prefix+key))
+	}
+	return v
+}
+
+// Optional returns the variable key as a T, or def when it is unset or
+// empty. Invalid values record ErrInvalid and return def.
+func Optional[T any](l *Loader, key string, def T) T {
+	v, ok, err := lookup[T](l.cfg.lookup, l.cfg.prefix+key)
+	if err != nil {
+		l.errs = append(l.errs, err)
+		return def
+	}
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// Err returns the problems of all variables read so far joined in an
+// errorx.MultiError, nil when there were none
+func (l *Loader) Err() error {
+	return errorx.Join(l.errs...)
+}
+
+// Snapshot saves the environment and returns a function restoring it, for
+// tests changing variables: defer env.Snapshot()()
+func Snapshot() (restore func()) {
+	saved := os.Environ()
+	return func() {
+		os.Clearenv()
+		for _, kv := range saved {
+			k, v, _ := strings.Cut(kv, "=")
+			_ = os.Setenv(k, v)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package env
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/errorx"
+)
+
+func TestGet(t *testing.T) {
+	defer Snapshot()()
+	os.Setenv("ENV_TEST_INT", " 42 ")
+	os.Setenv("ENV_TEST_BOOL", "yes")
+	os.Setenv("ENV_TEST_DURATION", "1m30s")
+	os.Setenv("ENV_TEST_SIZE", "1.5MiB")
+	os.Setenv("ENV_TEST_LIST", "a, b,c")
+	os.Setenv("ENV_TEST_PORTS", "80,443")
+	os.Setenv("ENV_TEST_ADDR", "10.0.0.1")
+	os.Setenv("ENV_TEST_BAD", "x")
+	os.Setenv("ENV_TEST_EMPTY", "")
+
+	if got := Get("ENV_TEST_INT", 0); got != 42 {
+		t.Errorf("Get() int got = %v, want 42", got)
+	}
+	if got := Get("ENV_TEST_BOOL", false); !got {
+		t.Errorf("Get() bool got = %v, want true", got)
+	}
+	if got := Get("ENV_TEST_DURATION", time.Second); got != 90*time.Second {
+		t.Errorf("Get() duration got = %v, want 1m30s", got)
+	}
+	if got := Get("ENV_TEST_SIZE", Size(0)); got != 3*MiB/2 {
+		t.Errorf("Get() size got = %v, want 1.5MiB", got)
+	}
+	if got := Get[[]string]("ENV_TEST_LIST", nil); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Get() []string got = %q", got)
+	}
+	if got := Get[[]uint16]("ENV_TEST_PORTS", nil); !reflect.DeepEqual(got, []uint16{80, 443}) {
+		t.Errorf("Get() []uint16 got = %v", got)
+	}
+	if got := Get("ENV_TEST_ADDR", netip.Addr{}); got.String() != "10.0.0.1" {
+		t.Errorf("Get() TextUnmarshaler got = %v", got)
+	}
+	for _, key := range []string{"ENV_TEST_BAD", "ENV_TEST_EMPTY", "ENV_TEST_UNSET"} {
+		if got := Get(key, 7); got != 7 {
+			t.Errorf("Get(%q) got = %v, want the default 7", key, got)
+		}
+	}
+
+	if _, ok, err := Lookup[int]("ENV_TEST_BAD"); !ok || !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "ENV_TEST_BAD") {
+		t.Errorf("Lookup() got = %v %v, want ErrInvalid naming the variable", ok, err)
+	}
+	if _, ok, err := Lookup[int]("ENV_TEST_UNSET"); ok || err != nil {
+		t.Errorf("Lookup() unset got = %v %v", ok, err)
+	}
+}
+
+func TestLoader(t *testing.T) {
+	vars := map[string]string{"APP_ADDR": ":8080", "APP_WORKERS": "many", "APP_RETRIES": "3"}
+	l := NewLoader(WithPrefix("APP_"), WithLookup(func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}))
+	addr := Required[string](l, "ADDR")
+	retries := Optional(l, "RETRIES", 1)
+	timeout := Optional(l, "TIMEOUT", 5*time.Second)
+	_ = Required[int](l, "WORKERS")
+	_ = Required[string](l, "TOKEN")
+	if addr != ":8080" || retries != 3 || timeout != 5*time.Second {
+		t.Errorf("Loader got = %v %v %v", addr, retries, timeout)
+	}
+
+	err := l.Err()
+	var multi *errorx.MultiError
+	if !errors.As(err, &multi) || len(multi.Errs) != 2 {
+		t.Fatalf("Err() got = %v, want the 2 problems", err)
+	}
+	if !errors.Is(multi.Errs[0], ErrInvalid) || !strings.Contains(multi.Errs[0].Error(), "APP_WORKERS") {
+		t.Errorf("Err() got = %v, want ErrInvalid for APP_WORKERS", multi.Errs[0])
+	}
+	if !errors.Is(multi.Errs[1], ErrMissing) || !strings.Contains(multi.Errs[1].Error(), "APP_TOKEN") {
+		t.Errorf("Err() got = %v, want ErrMissing for APP_TOKEN", multi.Errs[1])
+	}
+	if err := NewLoader().Err(); err != nil {
+		t.Errorf("Err() got = %v, want nil", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Size
+	}{
+		{"512", 512},
+		{"512B", 512},
+		{"10KB", 10000},
+		{"10k", 10000},
+		{"1.5GiB", 3 * GiB / 2},
+		{"64 Mi", 64 * MiB},
+		{"2tb", 2 * TB},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) got = %v %v, want %v", tt.in, int64(got), err, int64(tt.want))
+		}
+	}
+	for _, in := range []string{"", "MB", "10XB", "-1", "1.2.3K", "99999999TiB"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) got no error", in)
+		}
+	}
+	if s := (3 * GiB / 2).String(); s != "1.5GiB" {
+		t.Errorf("String() got = %v, want 1.5GiB", s)
+	}
+	if s := Size(100).String(); s != "100B" {
+		t.Errorf("String() got = %v, want 100B", s)
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	defer Snapshot()()
+	os.Setenv("ENV_TEST_HOME", "/home/app")
+	in := `# settings
+export NAME=app
+HOST = localhost # the host
+URL=http://${HOST}:8080
+DATA=$ENV_TEST_HOME/data
+LITERAL='$HOST # kept'
+QUOTED="say \"hi\"\tnow \$HOST"
+MULTI="line one
+line two"
+EMPTY=
+`
+	got, err := ParseDotenv(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ParseDotenv() error = %v", err)
+	}
+	want := map[string]string{
+		"NAME":    "app",
+		"HOST":    "localhost",
+		"URL":     "http://localhost:8080",
+		"DATA":    "/home/app/data",
+		"LITERAL": "$HOST # kept",
+		"QUOTED":  "say \"hi\"\tnow $HOST",
+		"MULTI":   "line one\nline two",
+		"EMPTY":   "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDotenv() got = %q, want %q", got, want)
+	}
+
+	for _, in := range []string{"NOVALUE", "1KEY=x", "A='open", "A=\"open\nstill"} {
+		if _, err := ParseDotenv(strings.NewReader(in)); err == nil {
+			t.Errorf("ParseDotenv(%q) got no error", in)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defer Snapshot()()
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.env")
+	second := filepath.Join(dir, "second.env")
+	os.WriteFile(first, []byte("ENV_TEST_A=first\n"), 0o600)
+	os.WriteFile(second, []byte("ENV_TEST_A=second\nENV_TEST_B=second\nENV_TEST_C=second\n"), 0o600)
+	os.Setenv("ENV_TEST_C", "env")
+
+	if err := Load(first, second, filepath.Join(dir, "missing.env")); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for key, want := range map[string]string{"ENV_TEST_A": "first", "ENV_TEST_B": "second", "ENV_TEST_C": "env"} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("Load() %v got = %q, want %q", key, got, want)
+		}
+	}
+
+	os.WriteFile(first, []byte("bad line\n"), 0o600)
+	if err := Load(first); err == nil || !strings.Contains(err.Error(), "first.env") {
+		t.Errorf("Load() got = %v, want an error naming the file", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	os.Setenv("ENV_TEST_KEPT", "before")
+	defer os.Unsetenv("ENV_TEST_KEPT")
+	restore := Snapshot()
+	os.Setenv("ENV_TEST_KEPT", "after")
+	os.Setenv("ENV_TEST_ADDED", "x")
+	os.Unsetenv("PATH")
+	restore()
+	if got := os.Getenv("ENV_TEST_KEPT"); got != "before" {
+		t.Errorf("Snapshot() restored %q, want before", got)
+	}
+	if _, ok := os.LookupEnv("ENV_TEST_ADDED"); ok {
+		t.Errorf("Snapshot() kept a variable set after it")
+	}
+	if os.Getenv("PATH") == "" {
+		t.Errorf("Snapshot() didn't restore PATH")
+	}
+}
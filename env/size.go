@@ -0,0 +1,77 @@
+package env
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size is a number of bytes, read from values such as 512, 10KB, 1.5GiB or
+// 64Mi. Units with an i are powers of 1024, the others of 1000.
+type Size int64
+
+// Size units
+const (
+	B   Size = 1
+	KB  Size = 1000
+	MB       = 1000 * KB
+	GB       = 1000 * MB
+	TB       = 1000 * GB
+	KiB Size = 1024
+	MiB      = 1024 * KiB
+	GiB      = 1024 * MiB
+	TiB      = 1024 * GiB
+)
+
+var sizeUnits = map[string]Size{
+	"": B, "b": B,
+	"k": KB, "kb": KB, "m": MB, "mb": MB, "g": GB, "gb": GB, "t": TB, "tb": TB,
+	"ki": KiB, "kib": KiB, "mi": MiB, "mib": MiB, "gi": GiB, "gib": GiB, "ti": TiB, "tib": TiB,
+}
+
+// ParseSize parses a size such as "10MB" or "1.5GiB", the unit is case
+// insensitive and may follow a space
+func ParseSize(s string) (Size, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("%q is not a size such as 512, 10KB or 1.5GiB", s)
+	}
+	bytes := n * float64(unit)
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q overflows", s)
+	}
+	return Size(bytes), nil
+}
+
+// String formats s with the largest binary unit dividing it, "1.5GiB" or
+// "512B"
+func (s Size) String() string {
+	for _, u := range []struct {
+		size Size
+		name string
+	}{{TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}} {
+		if s >= u.size || s <= -u.size {
+			return strconv.FormatFloat(float64(s)/float64(u.size), 'f', -1, 64) + u.name
+		}
+	}
+	return strconv.FormatInt(int64(s), 10) + "B"
+}
+
+// UnmarshalText parses text with ParseSize, so Size works in config files too
+func (s *Size) UnmarshalText(text []byte) error {
+	v, err := ParseSize(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
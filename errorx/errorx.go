@@ -0,0 +1,383 @@
+// Package errorx adds codes, user safe messages and stack traces to errors.
+// The code classifies an error for callers and decides the HTTP status of an
+// API response, the user message is what the response shows while Error()
+// keeps the internal details for the logs:
+//
+//	var ErrNoUser = errorx.New(errorx.NotFound, "user missing")
+//
+//	if err != nil {
+//		return errorx.Wrap(err, errorx.Unavailable, "load user "+id,
+//			errorx.WithUserMessage("the user service is down, try again later"))
+//	}
+//
+// The lookups CodeOf, StatusOf and UserMessageOf search the whole chain, so
+// errors of other packages take part by implementing Coder, StatusCoder or
+// UserMessager.
+package errorx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/Stellar1999/gotool/errs"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Code classifies errors, independently of their message
+type Code string
+
+const (
+	Internal           Code = "internal"
+	InvalidArgument    Code = "invalid_argument"
+	Unauthenticated    Code = "unauthenticated"
+	PermissionDenied   Code = "permission_denied"
+	NotFound           Code = "not_found"
+	Conflict           Code = "conflict"
+	FailedPrecondition Code = "failed_precondition"
+	RateLimited        Code = "rate_limited"
+	Canceled           Code = "canceled"
+	Timeout            Code = "timeout"
+	Unimplemented      Code = "unimplemented"
+	Unavailable        Code = "unavailable"
+)
+
+var codeStatus = map[Code]int{
+	Internal:           http.StatusInternalServerError,
+	InvalidArgument:    http.StatusBadRequest,
+	Unauthenticated:    http.StatusUnauthorized,
+	PermissionDenied:   http.StatusForbidden,
+	NotFound:           http.StatusNotFound,
+	Conflict:           http.StatusConflict,
+	FailedPrecondition: http.StatusPreconditionFailed,
+	RateLimited:        http.StatusTooManyRequests,
+	// the nginx status for requests the client gave up
+	Canceled:      499,
+	Timeout:       http.StatusGatewayTimeout,
+	Unimplemented: http.StatusNotImplemented,
+	Unavailable:   http.StatusServiceUnavailable,
+}
+
+var codeMessage = map[Code]string{
+	Internal:           "internal error",
+	InvalidArgument:    "invalid argument",
+	Unauthenticated:    "authentication required",
+	PermissionDenied:   "permission denied",
+	NotFound:           "not found",
+	Conflict:           "conflict",
+	FailedPrecondition: "precondition failed",
+	RateLimited:        "too many requests",
+	Canceled:           "request canceled",
+	Timeout:            "timeout",
+	Unimplemented:      "not implemented",
+	Unavailable:        "service unavailable",
+}
+
+// HTTPStatus is the status answering an error with the code, 500 for
+// unknown codes
+func (c Code) HTTPStatus() int {
+	if status, ok := codeStatus[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// CodeFromStatus classifies an HTTP status, "" for statuses below 400
+func CodeFromStatus(status int) Code {
+	switch {
+	case status < 400:
+		return ""
+	case status == http.StatusRequestTimeout:
+		return Timeout
+	case status == http.StatusGone:
+		return NotFound
+	}
+	for code, s := range codeStatus {
+		if s == status {
+			return code
+		}
+	}
+	if status < 500 {
+		return InvalidArgument
+	}
+	return Internal
+}
+
+// Coder is implemented by errors carrying a code
+type Coder interface {
+	ErrorCode() Code
+}
+
+// StatusCoder is implemented by errors choosing their HTTP status rather
+// than deriving it from their code
+type StatusCoder interface {
+	HTTPStatus() int
+}
+
+// UserMessager is implemented by errors with a message fit for end users
+type UserMessager interface {
+	UserMessage() string
+}
+
+// Error is an error with a code, see New and Wrap
+type Error struct {
+	code    Code
+	msg     string
+	user    string
+	status  int
+	details map[string]any
+	cause   error
+	stack   []uintptr
+}
+
+type Option = opt.Option[Error]
+
+// WithUserMessage sets the message shown to users, by default a generic
+// message of the code
+func WithUserMessage(msg string) Option {
+	return func(e *Error) {
+		e.user = msg
+	}
+}
+
+// WithStatus overrides the HTTP status derived from the code
+func WithStatus(status int) Option {
+	return func(e *Error) {
+		e.status = status
+	}
+}
+
+// WithDetail adds a detail sent with the error in API responses, e.g. the
+// field of an invalid argument. Details must be safe to show to users.
+func WithDetail(key string, value any) Option {
+	return func(e *Error) {
+		if e.details == nil {
+			e.details = make(map[string]any)
+		}
+		e.details[key] = value
+	}
+}
+
+// New returns an error with code and the internal message msg, recording the
+// stack of the caller
+func New(code Code, msg string, opts ...Option) *Error {
+	e := opt.Apply(&Error{code: code, msg: msg}, opts...)
+	e.stack = callers()
+	return e
+}
+
+// Newf is New with a formatted message, %w is not supported, use Wrap
+func Newf(code Code, format string, args ...any) *Error {
+	e := &Error{code: code, msg: fmt.Sprintf(format, args...)}
+	e.stack = callers()
+	return e
+}
+
+// Wrap returns err with code and the internal message msg prefixed, nil
+// when err is nil. An empty code keeps the code of err.
+func Wrap(err error, code Code, msg string, opts ...Option) error {
+	if err == nil {
+		return nil
+	}
+	if code == "" {
+		code = CodeOf(err)
+	}
+	e := opt.Apply(&Error{code: code, msg: msg, cause: err}, opts...)
+	e.stack = callers()
+	return e
+}
+
+// Wrapf is Wrap with a formatted message
+func Wrapf(err error, code Code, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	if code == "" {
+		code = CodeOf(err)
+	}
+	e := &Error{code: code, msg: fmt.Sprintf(format, args...), cause: err}
+	e.stack = callers()
+	return e
+}
+
+func callers() []uintptr {
+	pcs := make([]uintptr, 32)
+	// skip runtime.Callers, callers and the constructor
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// Error returns the internal message followed by the wrapped error
+func (e *Error) Error() string {
+	switch {
+	case e.cause == nil:
+		return e.msg
+	case e.msg == "":
+		return e.cause.Error()
+	}
+	return e.msg + ": " + e.cause.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches the errs class of the code, e.g. errors.Is(err, errs.ErrNotFound)
+// holds for a NotFound error
+func (e *Error) Is(target error) bool {
+	kind := codeKind(e.code)
+	return kind != nil && target == kind
+}
+
+func codeKind(code Code) error {
+	switch code {
+	case NotFound:
+		return errs.ErrNotFound
+	case Conflict:
+		return errs.ErrConflict
+	case RateLimited:
+		return errs.ErrRateLimited
+	case Timeout:
+		return errs.ErrTimeout
+	case Unavailable:
+		return errs.ErrUnavailable
+	}
+	return nil
+}
+
+func (e *Error) ErrorCode() Code {
+	return e.code
+}
+
+// UserMessage is the message of WithUserMessage, or the one of the wrapped
+// error, or a generic message of the code
+func (e *Error) UserMessage() string {
+	if e.user != "" {
+		return e.user
+	}
+	var inner UserMessager
+	if errors.As(e.cause, &inner) {
+		return inner.UserMessage()
+	}
+	return defaultMessage(e.code)
+}
+
+// HTTPStatus is the status of WithStatus or of the code
+func (e *Error) HTTPStatus() int {
+	if e.status != 0 {
+		return e.status
+	}
+	return e.code.HTTPStatus()
+}
+
+// Details returns the details of WithDetail, the map must not be modified
+func (e *Error) Details() map[string]any {
+	return e.details
+}
+
+// StackTrace returns the frames where the error was created
+func (e *Error) StackTrace() []runtime.Frame {
+	frames := runtime.CallersFrames(e.stack)
+	var out []runtime.Frame
+	for {
+		frame, more := frames.Next()
+		out = append(out, frame)
+		if !more {
+			return out
+		}
+	}
+}
+
+// Format prints the message for %s and %v, and adds the stack traces of the
+// chain for %+v
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		_, _ = io.WriteString(s, e.Error())
+		for err := error(e); err != nil; err = errors.Unwrap(err) {
+			if x, ok := err.(*Error); ok {
+				writeStack(s, x)
+			}
+		}
+	case verb == 'q':
+		_, _ = io.WriteString(s, strconv.Quote(e.Error()))
+	default:
+		_, _ = io.WriteString(s, e.Error())
+	}
+}
+
+func writeStack(w io.Writer, e *Error) {
+	var b strings.Builder
+	b.WriteString("\n[" + string(e.code) + "] " + e.msg)
+	for _, frame := range e.StackTrace() {
+		fmt.Fprintf(&b, "\n\t%s\n\t\t%s:%d", frame.Function, frame.File, frame.Line)
+	}
+	_, _ = io.WriteString(w, b.String())
+}
+
+// CodeOf returns the code of the first Coder in the chain of err. Without
+// one the errs classes and context errors are mapped, other errors are
+// Internal. A nil err has no code.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var coder Coder
+	if errors.As(err, &coder) {
+		return coder.ErrorCode()
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, errs.ErrTimeout):
+		return Timeout
+	case errors.Is(err, errs.ErrNotFound):
+		return NotFound
+	case errors.Is(err, errs.ErrConflict):
+		return Conflict
+	case errors.Is(err, errs.ErrRateLimited):
+		return RateLimited
+	case errors.Is(err, errs.ErrCircuitOpen), errors.Is(err, errs.ErrUnavailable):
+		return Unavailable
+	}
+	return Internal
+}
+
+// StatusOf returns the status of the first StatusCoder in the chain of err,
+// or the status of its code. A nil err is 200.
+func StatusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var sc StatusCoder
+	if errors.As(err, &sc) {
+		return sc.HTTPStatus()
+	}
+	return CodeOf(err).HTTPStatus()
+}
+
+// UserMessageOf returns the message of the first UserMessager in the chain
+// of err, or a generic message of its code. The internal message of err is
+// never shown.
+func UserMessageOf(err error) string {
+	if err == nil {
+		return ""
+	}
+	var um UserMessager
+	if errors.As(err, &um) {
+		return um.UserMessage()
+	}
+	return defaultMessage(CodeOf(err))
+}
+
+func defaultMessage(code Code) string {
+	if msg, ok := codeMessage[code]; ok {
+		return msg
+	}
+	return codeMessage[Internal]
+}
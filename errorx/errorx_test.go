@@ -0,0 +1,154 @@
+package errorx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Stellar1999/gotool/errs"
+)
+
+var errNoUser = New(NotFound, "user missing")
+
+type remoteError struct{}
+
+func (remoteError) Error() string       { return "remote says no" }
+func (remoteError) ErrorCode() Code     { return PermissionDenied }
+func (remoteError) HTTPStatus() int     { return http.StatusTeapot }
+func (remoteError) UserMessage() string { return "ask the admin" }
+
+func TestWrap(t *testing.T) {
+	base := errors.New("sql: no rows")
+	err := Wrap(base, NotFound, "load user 7", WithUserMessage("no such user"), WithDetail("id", 7))
+	if err.Error() != "load user 7: sql: no rows" {
+		t.Errorf("Error() got = %q", err.Error())
+	}
+	if !errors.Is(err, base) || !errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrConflict) {
+		t.Errorf("errors.Is() got wrong results for %v", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Details()["id"] != 7 {
+		t.Errorf("errors.As() got = %v", e)
+	}
+	if Wrap(nil, Internal, "x") != nil || Wrapf(nil, Internal, "x") != nil {
+		t.Errorf("Wrap(nil) got = non-nil, want nil")
+	}
+
+	outer := Wrapf(fmt.Errorf("handler: %w", errNoUser), "", "request %d", 3)
+	if CodeOf(outer) != NotFound || !errors.Is(outer, errNoUser) || outer.Error() != "request 3: handler: user missing" {
+		t.Errorf("Wrapf() keeping the code got = %v %v", CodeOf(outer), outer)
+	}
+}
+
+func TestLookups(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    Code
+		status  int
+		message string
+	}{
+		{"nil", nil, "", 200, ""},
+		{"plain", errors.New("boom"), Internal, 500, "internal error"},
+		{"canceled", fmt.Errorf("x: %w", context.Canceled), Canceled, 499, "request canceled"},
+		{"deadline", context.DeadlineExceeded, Timeout, 504, "timeout"},
+		{"errs class", errs.Mark(errors.New("x"), errs.ErrRateLimited), RateLimited, 429, "too many requests"},
+		{"errorx", New(InvalidArgument, "bad id", WithUserMessage("id must be a number")), InvalidArgument, 400, "id must be a number"},
+		{"status override", New(Unavailable, "down", WithStatus(502)), Unavailable, 502, "service unavailable"},
+		{"inner user message", Wrap(New(Conflict, "dup", WithUserMessage("already taken")), Internal, "save"), Internal, 500, "already taken"},
+		{"interfaces", fmt.Errorf("call: %w", remoteError{}), PermissionDenied, 418, "ask the admin"},
+	}
+	for _, tt := range tests {
+		if got := CodeOf(tt.err); got != tt.code {
+			t.Errorf("CodeOf(%v) got = %v, want %v", tt.name, got, tt.code)
+		}
+		if got := StatusOf(tt.err); got != tt.status {
+			t.Errorf("StatusOf(%v) got = %v, want %v", tt.name, got, tt.status)
+		}
+		if got := UserMessageOf(tt.err); got != tt.message {
+			t.Errorf("UserMessageOf(%v) got = %q, want %q", tt.name, got, tt.message)
+		}
+	}
+
+	statuses := map[int]Code{200: "", 304: "", 400: InvalidArgument, 404: NotFound, 408: Timeout, 410: NotFound, 418: InvalidArgument, 429: RateLimited, 502: Internal, 503: Unavailable}
+	for status, want := range statuses {
+		if got := CodeFromStatus(status); got != want {
+			t.Errorf("CodeFromStatus(%v) got = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestStack(t *testing.T) {
+	err := Wrap(New(Internal, "inner"), Unavailable, "outer")
+	var e *Error
+	errors.As(err, &e)
+	if frames := e.StackTrace(); len(frames) == 0 || !strings.HasSuffix(frames[0].Function, "errorx.TestStack") {
+		t.Errorf("StackTrace() got = %v, want the caller first", frames)
+	}
+	verbose := fmt.Sprintf("%+v", err)
+	if !strings.HasPrefix(verbose, "outer: inner\n[unavailable] outer\n") || !strings.Contains(verbose, "[internal] inner") || !strings.Contains(verbose, "errorx_test.go:") {
+		t.Errorf("Format(%%+v) got = %v", verbose)
+	}
+	if got := fmt.Sprintf("%v|%s|%q", err, err, err); got != `outer: inner|outer: inner|"outer: inner"` {
+		t.Errorf("Format() got = %v", got)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if Join(nil, nil) != nil {
+		t.Errorf("Join(nil) got = non-nil, want nil")
+	}
+	a := New(InvalidArgument, "bad name")
+	b := fmt.Errorf("db: %w", errs.ErrTimeout)
+	var err error
+	for _, e := range []error{a, nil, b} {
+		err = Append(err, e)
+	}
+	err = Join(err, errors.New("third"))
+	var multi *MultiError
+	if !errors.As(err, &multi) || len(multi.Errs) != 3 {
+		t.Fatalf("Join() got = %#v, want 3 flattened errors", err)
+	}
+	if err.Error() != "3 errors: bad name; db: timeout; third" {
+		t.Errorf("Error() got = %q", err.Error())
+	}
+	var e *Error
+	if !errors.Is(err, errs.ErrTimeout) || !errors.As(err, &e) || e != a {
+		t.Errorf("errors.Is/As() through MultiError got wrong results")
+	}
+	if CodeOf(err) != Timeout || StatusOf(err) != 504 {
+		t.Errorf("CodeOf() got = %v, want the code with the highest status", CodeOf(err))
+	}
+	if single := Join(a); single.Error() != "bad name" {
+		t.Errorf("Join() single got = %q", single.Error())
+	}
+	if verbose := fmt.Sprintf("%+v", err); !strings.HasPrefix(verbose, "3 errors:\n  1. bad name\n    [invalid_argument] bad name") {
+		t.Errorf("Format(%%+v) got = %v", verbose)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	err := Wrap(errors.New("pq: connection refused at 10.0.0.5"), Unavailable, "query", WithDetail("retry_after", 5))
+	data, _ := json.Marshal(err)
+	if string(data) != `{"code":"unavailable","message":"service unavailable","details":{"retry_after":5}}` {
+		t.Errorf("MarshalJSON() got = %s", data)
+	}
+
+	joined := Join(New(InvalidArgument, "name", WithUserMessage("name is required")), New(InvalidArgument, "age"))
+	data, _ = json.Marshal(joined)
+	want := `{"code":"invalid_argument","message":"name is required","errors":[{"code":"invalid_argument","message":"name is required"},{"code":"invalid_argument","message":"invalid argument"}]}`
+	if string(data) != want {
+		t.Errorf("MarshalJSON() multi got = %s, want %s", data, want)
+	}
+
+	rec := httptest.NewRecorder()
+	WriteJSON(rec, New(NotFound, "user 7 not in shard 3"))
+	if rec.Code != 404 || rec.Header().Get("Content-Type") != "application/json" || strings.Contains(rec.Body.String(), "shard") {
+		t.Errorf("WriteJSON() got = %v %v %s", rec.Code, rec.Header(), rec.Body)
+	}
+}
@@ -0,0 +1,105 @@
+package errorx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MultiError aggregates errors, errors.Is and errors.As look through all of
+// them
+type MultiError struct {
+	Errs []error
+}
+
+// Join returns the non-nil errs as a *MultiError, nil when there are none.
+// Joined MultiErrors are flattened.
+func Join(errs ...error) error {
+	var out []error
+	for _, err := range errs {
+		switch e := err.(type) {
+		case nil:
+		case *MultiError:
+			out = append(out, e.Errs...)
+		default:
+			out = append(out, err)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return &MultiError{Errs: out}
+}
+
+// Append joins err with more, the usual way to collect errors in a loop
+func Append(err error, more ...error) error {
+	return Join(append([]error{err}, more...)...)
+}
+
+// Error is the single error, or their count and messages separated by
+// semicolons
+func (e *MultiError) Error() string {
+	if len(e.Errs) == 1 {
+		return e.Errs[0].Error()
+	}
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return strconv.Itoa(len(e.Errs)) + " errors: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors for Go versions whose errors package walks them
+func (e *MultiError) Unwrap() []error {
+	return e.Errs
+}
+
+func (e *MultiError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *MultiError) As(target any) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorCode is the code shared by all errors, or the code of the first one
+// whose status is the highest
+func (e *MultiError) ErrorCode() Code {
+	code := CodeOf(e.Errs[0])
+	for _, err := range e.Errs[1:] {
+		if c := CodeOf(err); c.HTTPStatus() > code.HTTPStatus() {
+			code = c
+		}
+	}
+	return code
+}
+
+// HTTPStatus is the status of ErrorCode
+func (e *MultiError) HTTPStatus() int {
+	return e.ErrorCode().HTTPStatus()
+}
+
+// Format lists the errors one per line for %+v, with their stack traces
+func (e *MultiError) Format(s fmt.State, verb rune) {
+	if verb != 'v' || !s.Flag('+') {
+		_, _ = io.WriteString(s, e.Error())
+		return
+	}
+	fmt.Fprintf(s, "%d errors:", len(e.Errs))
+	for i, err := range e.Errs {
+		item := strings.ReplaceAll(fmt.Sprintf("%+v", err), "\n", "\n    ")
+		fmt.Fprintf(s, "\n  %d. %s", i+1, item)
+	}
+}
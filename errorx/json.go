@@ -0,0 +1,59 @@
+package errorx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Response is the body of an API error response, it only holds what is safe
+// to show to users
+type Response struct {
+	Code    Code           `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+	// Errors lists the joined errors of a *MultiError
+	Errors []Response `json:"errors,omitempty"`
+}
+
+// ToResponse describes err for users, with the user message and details of
+// its chain, never the internal message
+func ToResponse(err error) Response {
+	var multi *MultiError
+	if errors.As(err, &multi) && len(multi.Errs) > 1 {
+		resp := Response{Code: multi.ErrorCode(), Message: UserMessageOf(err)}
+		for _, e := range multi.Errs {
+			resp.Errors = append(resp.Errors, ToResponse(e))
+		}
+		return resp
+	}
+	resp := Response{Code: CodeOf(err), Message: UserMessageOf(err)}
+	var e *Error
+	if errors.As(err, &e) {
+		resp.Details = e.details
+	}
+	return resp
+}
+
+// MarshalJSON encodes the Response of the error
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ToResponse(e))
+}
+
+// MarshalJSON encodes the Response of the errors
+func (e *MultiError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ToResponse(e))
+}
+
+// WriteJSON answers with the Response of err and its status, see StatusOf
+func WriteJSON(w http.ResponseWriter, err error) {
+	body, marshalErr := json.Marshal(ToResponse(err))
+	if marshalErr != nil {
+		// details that cannot be encoded are dropped
+		body, _ = json.Marshal(Response{Code: CodeOf(err), Message: UserMessageOf(err)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(StatusOf(err))
+	_, _ = w.Write(append(body, '\n'))
+}
@@ -0,0 +1,73 @@
+package errs
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Failure classes shared by the toolkit, test for them with errors.Is
+var (
+	ErrTimeout     = errors.New("timeout")
+	ErrRateLimited = errors.New("rate limited")
+	ErrCircuitOpen = errors.New("circuit open")
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	// ErrUnavailable is a component that can't serve for now, e.g. closed,
+	// full or with its storage failing
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// kinds lists the classes in the order Kind checks them
+var kinds = []error{ErrTimeout, ErrRateLimited, ErrCircuitOpen, ErrNotFound, ErrConflict, ErrUnavailable}
+
+type marked struct {
+	err  error
+	kind error
+}
+
+func (e *marked) Error() string {
+	return e.err.Error()
+}
+
+func (e *marked) Unwrap() error {
+	return e.err
+}
+
+func (e *marked) Is(target error) bool {
+	return target == e.kind
+}
+
+// Mark returns err classified as kind, the message and the wrapped chain are kept
+func Mark(err error, kind error) error {
+	if err == nil || kind == nil {
+		return err
+	}
+	return &marked{err: err, kind: kind}
+}
+
+// Kind returns the class of err, nil when it has none
+func Kind(err error) error {
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// FromStatus maps an HTTP status code to its class, nil for codes without one
+func FromStatus(code int) error {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return ErrTimeout
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusNotFound, http.StatusGone:
+		return ErrNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrConflict
+	case http.StatusServiceUnavailable:
+		return ErrUnavailable
+	}
+	return nil
+}
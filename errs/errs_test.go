@@ -0,0 +1,59 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMarkAndKind(t *testing.T) {
+	base := errors.New("dial tcp: i/o timeout")
+	tests := []struct {
+		name     string
+		err      error
+		wantKind error
+		wantMsg  string
+		wantBase bool
+	}{
+		{name: "marked", err: Mark(base, ErrTimeout), wantKind: ErrTimeout, wantMsg: base.Error(), wantBase: true},
+		{name: "wrapped mark", err: fmt.Errorf("get user: %w", Mark(base, ErrNotFound)), wantKind: ErrNotFound, wantMsg: "get user: " + base.Error(), wantBase: true},
+		{name: "sentinel", err: fmt.Errorf("cache: %w", ErrConflict), wantKind: ErrConflict, wantMsg: "cache: conflict"},
+		{name: "unclassified", err: base, wantKind: nil, wantMsg: base.Error(), wantBase: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Kind(tt.err); got != tt.wantKind {
+				t.Errorf("Kind() got = %v, want %v", got, tt.wantKind)
+			}
+			if got := tt.err.Error(); got != tt.wantMsg {
+				t.Errorf("Error() got = %v, want %v", got, tt.wantMsg)
+			}
+			if got := errors.Is(tt.err, base); got != tt.wantBase {
+				t.Errorf("errors.Is(err, base) got = %v, want %v", got, tt.wantBase)
+			}
+		})
+	}
+	if Mark(nil, ErrTimeout) != nil {
+		t.Errorf("Mark(nil) got = non-nil, want nil")
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		want error
+	}{
+		{code: 404, want: ErrNotFound},
+		{code: 409, want: ErrConflict},
+		{code: 412, want: ErrConflict},
+		{code: 429, want: ErrRateLimited},
+		{code: 503, want: ErrUnavailable},
+		{code: 504, want: ErrTimeout},
+		{code: 500, want: nil},
+	}
+	for _, tt := range tests {
+		if got := FromStatus(tt.code); got != tt.want {
+			t.Errorf("FromStatus(%d) got = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
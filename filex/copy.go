@@ -0,0 +1,116 @@
+package filex
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type copyConfig struct {
+	progress func(copied int64, total int64)
+}
+
+type CopyOption = opt.Option[copyConfig]
+
+// WithProgress calls fn as bytes are copied with the bytes copied so far and
+// the total to copy
+func WithProgress(fn func(copied int64, total int64)) CopyOption {
+	return func(c *copyConfig) {
+		c.progress = fn
+	}
+}
+
+// progressWriter reports the bytes written through it
+type progressWriter struct {
+	w      io.Writer
+	copied *int64
+	total  int64
+	report func(copied int64, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	*p.copied += int64(n)
+	if p.report != nil {
+		p.report(*p.copied, p.total)
+	}
+	return n, err
+}
+
+// CopyFile copies the file src to dst with its permissions, replacing dst
+func CopyFile(src string, dst string, opts ...CopyOption) error {
+	cfg := opt.Apply(&copyConfig{}, opts...)
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	var copied int64
+	return copyFile(src, dst, info.Mode().Perm(), &progressWriter{copied: &copied, total: info.Size(), report: cfg.progress})
+}
+
+func copyFile(src string, dst string, perm os.FileMode, progress *progressWriter) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	progress.w = out
+	if _, err := io.Copy(progress, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// CopyDir copies the directory tree src into dst, creating it, with the
+// permissions of the files and directories. Symlinks are copied as links.
+// The progress counts the bytes of all files.
+func CopyDir(src string, dst string, opts ...CopyOption) error {
+	cfg := opt.Apply(&copyConfig{}, opts...)
+	var total int64
+	if cfg.progress != nil {
+		err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+			total += fileSize(d)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	var copied int64
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(path, target, info.Mode().Perm(), &progressWriter{copied: &copied, total: total, report: cfg.progress})
+		}
+		// sockets, devices and pipes are skipped
+		return nil
+	})
+}
@@ -0,0 +1,164 @@
+// Package filex has file and directory helpers: existence checks, atomic
+// writes, line reading, checksums, copying with progress, zip archives and
+// a filtered directory walker.
+package filex
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Stellar1999/gotool/cryptox"
+)
+
+var (
+	// ErrStop stops EachLine and Walk without an error when returned by their
+	// callback
+	ErrStop = errors.New("filex: stop")
+	// ErrChecksumMismatch is returned by VerifyChecksum
+	ErrChecksumMismatch = errors.New("filex: checksum mismatch")
+)
+
+// Exists reports whether path exists, following symlinks
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// IsDir reports whether path is a directory, following symlinks
+func IsDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// EnsureDir creates path and its missing parents with perm, an existing
+// directory is fine
+func EnsureDir(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// WriteFileAtomic writes data to path so readers see either the old or the
+// new content, never a partial file. See WriteAtomic.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return WriteAtomic(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WriteAtomic writes a temporary file next to path with write, syncs it and
+// renames it over path. On failure path is left as it was.
+func WriteAtomic(path string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// persist the rename, not every platform can sync a directory
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
+
+// ReadLines returns the lines of the file at path without their line
+// endings, use EachLine for files too big for memory
+func ReadLines(path string) ([]string, error) {
+	var lines []string
+	err := EachLine(path, func(n int, line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	return lines, err
+}
+
+// EachLine calls fn with every line of the file at path and its number,
+// counting from 1, reading one line at a time whatever its length. "\n" and
+// "\r\n" end lines. An error of fn stops reading and is returned, except
+// ErrStop.
+func EachLine(path string, fn func(n int, line string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 64*1024)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+			if fnErr := fn(n, string(line)); fnErr != nil {
+				if fnErr == ErrStop {
+					return nil
+				}
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Checksum returns the hex digest of the file at path with h, e.g.
+// crypto.SHA256
+func Checksum(path string, h crypto.Hash) (string, error) {
+	return cryptox.HashFile(h, path)
+}
+
+// VerifyChecksum checks the file at path against the hex digest want,
+// failing with ErrChecksumMismatch
+func VerifyChecksum(path string, h crypto.Hash, want string) error {
+	got, err := Checksum(path, h)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(got, want) {
+		return fmt.Errorf("%w: %s is %s, want %s", ErrChecksumMismatch, path, got, want)
+	}
+	return nil
+}
+
+// fileSize returns the size of regular files, 0 for others
+func fileSize(d fs.DirEntry) int64 {
+	if !d.Type().IsRegular() {
+		return 0
+	}
+	info, err := d.Info()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,233 @@
+package filex
+
+import (
+	"archive/zip"
+	"crypto"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func write(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "conf.json")
+	if Exists(path) {
+		t.Errorf("Exists() got = true before the write")
+	}
+	if err := EnsureDir(filepath.Dir(path), 0o755); err != nil || !IsDir(filepath.Dir(path)) {
+		t.Fatalf("EnsureDir() got = %v", err)
+	}
+	if err := WriteFileAtomic(path, []byte("v1"), 0o600); err != nil {
+		t.Fatalf("WriteFileAtomic() got = %v", err)
+	}
+	if err := WriteFileAtomic(path, []byte("v2"), 0o600); err != nil {
+		t.Fatalf("WriteFileAtomic() got = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(data) != "v2" || info.Mode().Perm() != 0o600 || !Exists(path) || IsDir(path) {
+		t.Errorf("WriteFileAtomic() got = %q %v", data, info.Mode())
+	}
+
+	failed := errors.New("boom")
+	err := WriteAtomic(path, 0o600, func(w io.Writer) error {
+		_, _ = w.Write([]byte("partial"))
+		return failed
+	})
+	data, _ = os.ReadFile(path)
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if err != failed || string(data) != "v2" || len(entries) != 1 {
+		t.Errorf("WriteAtomic() failure got = %v %q %v entries, want the old file and no temporary", err, data, len(entries))
+	}
+}
+
+func TestLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	long := strings.Repeat("x", 200*1024)
+	write(t, path, "one\r\ntwo\n"+long+"\nlast")
+	lines, err := ReadLines(path)
+	if err != nil || !reflect.DeepEqual(lines, []string{"one", "two", long, "last"}) {
+		t.Errorf("ReadLines() got = %v lines %v", len(lines), err)
+	}
+
+	var seen []int
+	err = EachLine(path, func(n int, line string) error {
+		seen = append(seen, n)
+		if n == 2 {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(seen, []int{1, 2}) {
+		t.Errorf("EachLine() ErrStop got = %v %v", seen, err)
+	}
+	failed := errors.New("bad line")
+	if err := EachLine(path, func(int, string) error { return failed }); err != failed {
+		t.Errorf("EachLine() error got = %v, want %v", err, failed)
+	}
+	if _, err := ReadLines(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadLines() missing got = %v", err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	write(t, path, "abc")
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got, err := Checksum(path, crypto.SHA256); got != want || err != nil {
+		t.Errorf("Checksum() got = %v %v, want %v", got, err, want)
+	}
+	if err := VerifyChecksum(path, crypto.SHA256, strings.ToUpper(want)); err != nil {
+		t.Errorf("VerifyChecksum() got = %v", err)
+	}
+	if err := VerifyChecksum(path, crypto.SHA256, "00"); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("VerifyChecksum() got = %v, want %v", err, ErrChecksumMismatch)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	src := t.TempDir()
+	write(t, filepath.Join(src, "a.txt"), "hello")
+	write(t, filepath.Join(src, "sub", "b.txt"), "world!")
+	if err := os.Chmod(filepath.Join(src, "a.txt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	var last, total int64
+	err := CopyDir(src, dst, WithProgress(func(copied int64, n int64) {
+		last, total = copied, n
+	}))
+	if err != nil {
+		t.Fatalf("CopyDir() got = %v", err)
+	}
+	if last != 11 || total != 11 {
+		t.Errorf("CopyDir() progress got = %v/%v, want 11/11", last, total)
+	}
+	data, _ := os.ReadFile(filepath.Join(dst, "sub", "b.txt"))
+	info, _ := os.Stat(filepath.Join(dst, "a.txt"))
+	link, _ := os.Readlink(filepath.Join(dst, "link"))
+	if string(data) != "world!" || info.Mode().Perm() != 0o600 || link != "a.txt" {
+		t.Errorf("CopyDir() got = %q %v %q", data, info.Mode(), link)
+	}
+
+	var calls int
+	target := filepath.Join(dst, "c.txt")
+	if err := CopyFile(filepath.Join(src, "a.txt"), target, WithProgress(func(int64, int64) { calls++ })); err != nil || calls == 0 {
+		t.Errorf("CopyFile() got = %v after %v progress calls", err, calls)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "hello" {
+		t.Errorf("CopyFile() got = %q", data)
+	}
+}
+
+func TestZip(t *testing.T) {
+	src := t.TempDir()
+	write(t, filepath.Join(src, "a.txt"), "hello")
+	write(t, filepath.Join(src, "sub", "deep", "b.txt"), "world")
+	archive := filepath.Join(t.TempDir(), "out.zip")
+	if err := Zip(src, archive); err != nil {
+		t.Fatalf("Zip() got = %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), "x")
+	if err := Unzip(archive, dst); err != nil {
+		t.Fatalf("Unzip() got = %v", err)
+	}
+	got, _ := Find(dst)
+	for i := range got {
+		got[i], _ = filepath.Rel(dst, got[i])
+	}
+	want := []string{"a.txt", filepath.Join("sub", "deep", "b.txt")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unzip() files got = %v, want %v", got, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "sub", "deep", "b.txt")); string(data) != "world" {
+		t.Errorf("Unzip() got = %q", data)
+	}
+
+	evil := filepath.Join(t.TempDir(), "evil.zip")
+	f, _ := os.Create(evil)
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("ok.txt")
+	_, _ = w.Write([]byte("fine"))
+	w, _ = zw.Create("../../escape.txt")
+	_, _ = w.Write([]byte("bad"))
+	_ = zw.Close()
+	_ = f.Close()
+	out := filepath.Join(t.TempDir(), "evil")
+	if err := Unzip(evil, out); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("Unzip() got = %v, want %v", err, ErrUnsafePath)
+	}
+	if Exists(filepath.Join(out, "ok.txt")) {
+		t.Errorf("Unzip() wrote entries of an unsafe archive")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"main.go", "main_test.go", "README.md", "vendor/x/x.go", "pkg/a.go", "pkg/testdata/in.json", "pkg/testdata/deep/out.json"} {
+		write(t, filepath.Join(root, filepath.FromSlash(name)), name)
+	}
+	tests := []struct {
+		name string
+		opts []WalkOption
+		want []string
+	}{
+		{"all", nil, []string{"README.md", "main.go", "main_test.go", "pkg/a.go", "pkg/testdata/deep/out.json", "pkg/testdata/in.json", "vendor/x/x.go"}},
+		{"include base name", []WalkOption{WithInclude("*.go"), WithExclude("vendor/**", "*_test.go")}, []string{"main.go", "pkg/a.go"}},
+		{"double star", []WalkOption{WithInclude("**/testdata/*.json")}, []string{"pkg/testdata/in.json"}},
+		{"double star depth", []WalkOption{WithInclude("pkg/**/*.json")}, []string{"pkg/testdata/deep/out.json", "pkg/testdata/in.json"}},
+		{"exclude dir", []WalkOption{WithExclude("testdata"), WithInclude("pkg/**")}, []string{"pkg/a.go"}},
+	}
+	for _, tt := range tests {
+		paths, err := Find(root, tt.opts...)
+		got := make([]string, len(paths))
+		for i, p := range paths {
+			rel, _ := filepath.Rel(root, p)
+			got[i] = filepath.ToSlash(rel)
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Find(%v) got = %v %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	var dirs int
+	err := Walk(root, func(path string, d fs.DirEntry) error {
+		if d.IsDir() {
+			dirs++
+		}
+		return nil
+	}, WithDirs(), WithExclude("vendor"))
+	if err != nil || dirs != 4 {
+		t.Errorf("Walk() WithDirs got = %v dirs %v, want 4", dirs, err)
+	}
+	var visited int
+	err = Walk(root, func(string, fs.DirEntry) error {
+		visited++
+		return ErrStop
+	})
+	if err != nil || visited != 1 {
+		t.Errorf("Walk() ErrStop got = %v %v", visited, err)
+	}
+	if err := Walk(root, nil, WithInclude("[")); err == nil {
+		t.Errorf("Walk() bad pattern got = nil, want an error")
+	}
+}
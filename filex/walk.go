@@ -0,0 +1,154 @@
+package filex
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type walkConfig struct {
+	include []string
+	exclude []string
+	dirs    bool
+}
+
+type WalkOption = opt.Option[walkConfig]
+
+// WithInclude only visits files matching one of patterns, see Walk
+func WithInclude(patterns ...string) WalkOption {
+	return func(c *walkConfig) {
+		c.include = append(c.include, patterns...)
+	}
+}
+
+// WithExclude skips files and directories matching one of patterns, the
+// content of an excluded directory is not read
+func WithExclude(patterns ...string) WalkOption {
+	return func(c *walkConfig) {
+		c.exclude = append(c.exclude, patterns...)
+	}
+}
+
+// WithDirs also calls the walk function for directories, which the include
+// patterns do not filter
+func WithDirs() WalkOption {
+	return func(c *walkConfig) {
+		c.dirs = true
+	}
+}
+
+var walkChecks = []opt.Check[walkConfig]{
+	func(c *walkConfig) error {
+		for _, pattern := range append(append([]string(nil), c.include...), c.exclude...) {
+			for _, part := range strings.Split(pattern, "/") {
+				if _, err := path.Match(part, ""); err != nil {
+					return fmt.Errorf("filex: pattern %q: %w", pattern, err)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// Walk calls fn for the files under root in lexical order, with their path
+// and entry. Patterns use path.Match syntax against the slash separated path
+// relative to root: a pattern without "/" matches the base name at any depth,
+// e.g. "*.go", others the whole path where "**" matches any number of
+// directories, e.g. "vendor/**" or "**/testdata/*.json". An error of fn stops
+// the walk and is returned, except ErrStop and fs.SkipDir.
+func Walk(root string, fn func(path string, d fs.DirEntry) error, opts ...WalkOption) error {
+	var cfg walkConfig
+	if err := opt.Build(&cfg, opts, walkChecks...); err != nil {
+		return err
+	}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			if cfg.dirs {
+				return fn(p, d)
+			}
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if matchAny(cfg.exclude, rel) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if cfg.dirs {
+				return fn(p, d)
+			}
+			return nil
+		}
+		if len(cfg.include) > 0 && !matchAny(cfg.include, rel) {
+			return nil
+		}
+		return fn(p, d)
+	})
+	if err == ErrStop {
+		return nil
+	}
+	return err
+}
+
+// Find returns the paths of the files Walk visits with opts
+func Find(root string, opts ...WalkOption) ([]string, error) {
+	var paths []string
+	err := Walk(root, func(path string, d fs.DirEntry) error {
+		paths = append(paths, path)
+		return nil
+	}, opts...)
+	return paths, err
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// match matches rel against pattern as described on Walk, the patterns were
+// validated when the options were built
+func match(pattern string, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchParts(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchParts(pattern []string, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(parts); i >= 0; i-- {
+				if matchParts(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
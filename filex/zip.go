@@ -0,0 +1,153 @@
+package filex
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafePath is returned by Unzip for an entry that would be written
+// outside the destination, or a symlink
+var ErrUnsafePath = errors.New("filex: unsafe path in archive")
+
+// Zip archives the file or directory tree src into the zip file dst, written
+// atomically. Entries are relative to src, a directory itself is not part of
+// the names.
+func Zip(src string, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return WriteAtomic(dst, 0o644, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+		var err error
+		if info.IsDir() {
+			err = zipDir(zw, src)
+		} else {
+			err = zipFile(zw, src, filepath.Base(src), info)
+		}
+		if err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+func zipDir(zw *zip.Writer, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		switch {
+		case d.IsDir():
+			header, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			header.Name = name + "/"
+			_, err = zw.CreateHeader(header)
+			return err
+		case d.Type().IsRegular():
+			return zipFile(zw, path, name, info)
+		}
+		// symlinks and special files are left out, Unzip refuses links anyway
+		return nil
+	})
+}
+
+func zipFile(zw *zip.Writer, path string, name string, info fs.FileInfo) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Unzip extracts the zip file src into the directory dst, creating it. An
+// entry escaping dst, e.g. "../x" or an absolute name, fails the whole
+// extraction with ErrUnsafePath before anything is written.
+func Unzip(src string, dst string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	targets := make([]string, len(zr.File))
+	for i, f := range zr.File {
+		if f.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s is a symlink", ErrUnsafePath, f.Name)
+		}
+		target := filepath.Join(dst, filepath.FromSlash(f.Name))
+		rel, err := filepath.Rel(dst, target)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) ||
+			filepath.IsAbs(f.Name) || strings.HasPrefix(f.Name, "/") {
+			return fmt.Errorf("%w: %s", ErrUnsafePath, f.Name)
+		}
+		targets[i] = target
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	for i, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(targets[i], 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := unzipFile(f, targets[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unzipFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	perm := f.Mode().Perm()
+	if perm == 0 {
+		perm = 0o644
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,248 @@
+// Package graphql sends GraphQL operations with the http package, so they go
+// through the hooks, signing and settings of a gohttp.Client.
+//
+//	var out struct {
+//		User struct{ Name string } `json:"user"`
+//	}
+//	err := graphql.Query(ctx, endpoint, `query($id: ID!) { user(id: $id) { name } }`, map[string]any{"id": 7}, &out)
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Location is a position in the operation text
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is one entry of the errors of a response
+type Error struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Path) == 0 {
+		return "graphql: " + e.Message
+	}
+	path := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		path[i] = toString(p)
+	}
+	return "graphql: " + strings.Join(path, ".") + ": " + e.Message
+}
+
+// Code returns extensions.code, e.g. "UNAUTHENTICATED", or ""
+func (e *Error) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// Errors are the errors of a response. The data that could be resolved is
+// still decoded, so a response with Errors may be partially usable.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// HasCode reports whether any error has extensions.code code
+func (e Errors) HasCode(code string) bool {
+	for _, err := range e {
+		if err.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNoData is returned for a response without data and errors
+var ErrNoData = errors.New("graphql: response without data")
+
+type config struct {
+	client    *gohttp.Client
+	persisted bool
+}
+
+type Option = opt.Option[config]
+
+// WithClient sends the operations with client instead of the default Client
+// of the http package
+func WithClient(client *gohttp.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithPersistedQueries sends the SHA-256 hash of the query instead of its
+// text, the query is only sent when the server doesn't know the hash yet.
+// This is the automatic persisted queries protocol of Apollo.
+func WithPersistedQueries() Option {
+	return func(c *config) {
+		c.persisted = true
+	}
+}
+
+// Client sends operations to one endpoint
+type Client struct {
+	endpoint string
+	cfg      config
+}
+
+// New creates a Client for endpoint
+func New(endpoint string, opts ...Option) (*Client, error) {
+	var cfg config
+	if err := opt.Build(&cfg, opts); err != nil {
+		return nil, err
+	}
+	return &Client{endpoint: endpoint, cfg: cfg}, nil
+}
+
+// Query sends a query to endpoint with the default Client of the http
+// package and decodes the data into out
+func Query(ctx context.Context, endpoint string, query string, variables map[string]any, out any) error {
+	return (&Client{endpoint: endpoint}).Query(ctx, query, variables, out)
+}
+
+// Mutate sends a mutation to endpoint, like Query
+func Mutate(ctx context.Context, endpoint string, mutation string, variables map[string]any, out any) error {
+	return (&Client{endpoint: endpoint}).Mutate(ctx, mutation, variables, out)
+}
+
+// Query sends a query and decodes the data into out, which may be nil. It
+// fails with Errors for a response with errors, out holds the partial data
+// then.
+func (c *Client) Query(ctx context.Context, query string, variables map[string]any, out any) error {
+	return c.Do(ctx, query, "", variables, out)
+}
+
+// Mutate sends a mutation, like Query
+func (c *Client) Mutate(ctx context.Context, mutation string, variables map[string]any, out any) error {
+	return c.Do(ctx, mutation, "", variables, out)
+}
+
+type request struct {
+	Query         string         `json:"query,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors Errors          `json:"errors"`
+}
+
+// Do sends an operation, operationName selects one of several operations in
+// the document and may be empty
+func (c *Client) Do(ctx context.Context, document string, operationName string, variables map[string]any, out any) error {
+	req := request{Query: document, OperationName: operationName, Variables: variables}
+	if c.cfg.persisted {
+		req.Query = ""
+		req.Extensions = map[string]any{"persistedQuery": map[string]any{"version": 1, "sha256Hash": hash(document)}}
+		resp, err := c.send(ctx, req)
+		if err != nil {
+			return err
+		}
+		if !resp.Errors.HasCode("PERSISTED_QUERY_NOT_FOUND") && !hasMessage(resp.Errors, "PersistedQueryNotFound") {
+			return decode(resp, out)
+		}
+		// registers the query for the hash
+		req.Query = document
+	}
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	return decode(resp, out)
+}
+
+func (c *Client) send(ctx context.Context, req request) (*response, error) {
+	var httpReq *gohttp.Request
+	if c.cfg.client != nil {
+		httpReq = c.cfg.client.NewRequest(gohttp.POST, c.endpoint)
+	} else {
+		httpReq = gohttp.NewRequest(gohttp.POST, c.endpoint)
+	}
+	httpResp, err := httpReq.WithContext(ctx).
+		Header("Accept", "application/graphql-response+json, application/json").
+		Body(req).
+		Send()
+	if err != nil {
+		// servers answer invalid operations with 400 and the errors
+		var statusErr *gohttp.StatusError
+		if errors.As(err, &statusErr) {
+			var resp response
+			if json.Unmarshal(statusErr.Body, &resp) == nil && len(resp.Errors) > 0 {
+				return &resp, nil
+			}
+		}
+		return nil, err
+	}
+	var resp response
+	if err := json.Unmarshal(httpResp.Body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func decode(resp *response, out any) error {
+	hasData := len(resp.Data) > 0 && string(resp.Data) != "null"
+	if hasData && out != nil {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return err
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+	if !hasData {
+		return ErrNoData
+	}
+	return nil
+}
+
+func hash(document string) string {
+	sum := sha256.Sum256([]byte(document))
+	return hex.EncodeToString(sum[:])
+}
+
+func hasMessage(errs Errors, message string) bool {
+	for _, err := range errs {
+		if err.Message == message {
+			return true
+		}
+	}
+	return false
+}
+
+func toString(v any) string {
+	switch p := v.(type) {
+	case string:
+		return p
+	case float64:
+		return strconv.FormatFloat(p, 'f', -1, 64)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
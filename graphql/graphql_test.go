@@ -0,0 +1,124 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+// server is a fake GraphQL endpoint answering with the response of the query
+type server struct {
+	mu        sync.Mutex
+	requests  []request
+	persisted map[string]string
+	responses map[string]string
+	status    int
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	if pq, ok := req.Extensions["persistedQuery"].(map[string]any); ok {
+		h := pq["sha256Hash"].(string)
+		if req.Query == "" {
+			req.Query, ok = s.persisted[h]
+			if !ok {
+				_, _ = w.Write([]byte(`{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`))
+				return
+			}
+		}
+		s.persisted[h] = req.Query
+	}
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+	}
+	_, _ = w.Write([]byte(s.responses[req.Query]))
+}
+
+func TestQuery(t *testing.T) {
+	fake := &server{responses: map[string]string{
+		"{ user { name } }":         `{"data":{"user":{"name":"ann"}}}`,
+		"{ user { name friends } }": `{"data":{"user":{"name":"ann","friends":null}},"errors":[{"message":"boom","path":["user","friends",0],"locations":[{"line":1,"column":16}],"extensions":{"code":"INTERNAL"}}]}`,
+		"mutation { ping }":         `{"data":{"ping":true}}`,
+		"{ nothing }":               `{"data":null}`,
+	}}
+	endpoint := httptest.NewServer(fake)
+	defer endpoint.Close()
+	ctx := context.Background()
+
+	var out struct {
+		User struct {
+			Name string `json:"name"`
+		} `json:"user"`
+	}
+	if err := Query(ctx, endpoint.URL, "{ user { name } }", map[string]any{"id": 1}, &out); err != nil || out.User.Name != "ann" {
+		t.Errorf("Query() got = %v %v, want ann", out, err)
+	}
+	if got := fake.requests[0].Variables["id"]; got != float64(1) {
+		t.Errorf("variables got = %v, want 1", got)
+	}
+
+	out.User.Name = ""
+	err := Query(ctx, endpoint.URL, "{ user { name friends } }", nil, &out)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || !errs.HasCode("INTERNAL") || errs[0].Locations[0].Column != 16 {
+		t.Fatalf("Query() error got = %#v", err)
+	}
+	if err.Error() != "graphql: user.friends.0: boom" || out.User.Name != "ann" {
+		t.Errorf("Query() got = %v %v, want the error and partial data", out, err)
+	}
+
+	var ping map[string]bool
+	if err := Mutate(ctx, endpoint.URL, "mutation { ping }", nil, &ping); err != nil || !ping["ping"] {
+		t.Errorf("Mutate() got = %v %v", ping, err)
+	}
+	if err := Query(ctx, endpoint.URL, "{ nothing }", nil, nil); !errors.Is(err, ErrNoData) {
+		t.Errorf("Query() error got = %v, want %v", err, ErrNoData)
+	}
+
+	fake.status = http.StatusBadRequest
+	fake.responses["{ bad"] = `{"errors":[{"message":"Syntax Error"}]}`
+	if err := Query(ctx, endpoint.URL, "{ bad", nil, nil); !errors.As(err, &errs) || errs[0].Message != "Syntax Error" {
+		t.Errorf("Query() 400 error got = %v, want the GraphQL errors", err)
+	}
+	fake.status = http.StatusBadGateway
+	var statusErr *gohttp.StatusError
+	if err := Query(ctx, endpoint.URL, "{ user { name } }", nil, nil); !errors.As(err, &statusErr) {
+		t.Errorf("Query() 502 error got = %v, want a *StatusError", err)
+	}
+}
+
+func TestPersistedQueries(t *testing.T) {
+	fake := &server{persisted: map[string]string{}, responses: map[string]string{
+		"{ user { name } }": `{"data":{"user":{"name":"ann"}}}`,
+	}}
+	endpoint := httptest.NewServer(fake)
+	defer endpoint.Close()
+
+	client, _ := New(endpoint.URL, WithPersistedQueries(), WithClient(gohttp.NewClient()))
+	for i := 0; i < 2; i++ {
+		var out map[string]any
+		if err := client.Query(context.Background(), "{ user { name } }", nil, &out); err != nil || out["user"] == nil {
+			t.Fatalf("Query() got = %v %v", out, err)
+		}
+	}
+	// the first query registers the hash, the second sends the hash alone
+	queries := []string{"", "{ user { name } }", ""}
+	if len(fake.requests) != len(queries) {
+		t.Fatalf("requests got = %v, want %v", len(fake.requests), len(queries))
+	}
+	for i, want := range queries {
+		if got := fake.requests[i].Query; got != want {
+			t.Errorf("request %v query got = %q, want %q", i, got, want)
+		}
+	}
+}
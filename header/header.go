@@ -0,0 +1,85 @@
+// Package header names the common HTTP headers, so they are not spelled out
+// as strings around the code:
+//
+//	token := header.Authorization.Get(req.Header)
+//	header.RetryAfter.Set(w.Header(), "30")
+package header
+
+import "net/http"
+
+// Name is a header name in its canonical form
+type Name string
+
+const (
+	Accept             Name = "Accept"
+	AcceptEncoding     Name = "Accept-Encoding"
+	AcceptLanguage     Name = "Accept-Language"
+	Authorization      Name = "Authorization"
+	CacheControl       Name = "Cache-Control"
+	Connection         Name = "Connection"
+	ContentDisposition Name = "Content-Disposition"
+	ContentEncoding    Name = "Content-Encoding"
+	ContentLength      Name = "Content-Length"
+	ContentRange       Name = "Content-Range"
+	ContentType        Name = "Content-Type"
+	Cookie             Name = "Cookie"
+	Date               Name = "Date"
+	ETag               Name = "Etag"
+	Expires            Name = "Expires"
+	Host               Name = "Host"
+	IdempotencyKey     Name = "Idempotency-Key"
+	IfMatch            Name = "If-Match"
+	IfModifiedSince    Name = "If-Modified-Since"
+	IfNoneMatch        Name = "If-None-Match"
+	LastModified       Name = "Last-Modified"
+	Link               Name = "Link"
+	Location           Name = "Location"
+	Origin             Name = "Origin"
+	ProxyAuthorization Name = "Proxy-Authorization"
+	Range              Name = "Range"
+	Referer            Name = "Referer"
+	RetryAfter         Name = "Retry-After"
+	SetCookie          Name = "Set-Cookie"
+	TraceParent        Name = "Traceparent"
+	TransferEncoding   Name = "Transfer-Encoding"
+	UserAgent          Name = "User-Agent"
+	Vary               Name = "Vary"
+	WWWAuthenticate    Name = "Www-Authenticate"
+	XForwardedFor      Name = "X-Forwarded-For"
+	XRequestID         Name = "X-Request-Id"
+)
+
+func (n Name) String() string {
+	return string(n)
+}
+
+// Get returns the first value of n in h
+func (n Name) Get(h http.Header) string {
+	return h.Get(string(n))
+}
+
+// Values returns all the values of n in h
+func (n Name) Values(h http.Header) []string {
+	return h.Values(string(n))
+}
+
+// Set replaces the values of n in h
+func (n Name) Set(h http.Header, value string) {
+	h.Set(string(n), value)
+}
+
+// Add appends a value of n to h
+func (n Name) Add(h http.Header, value string) {
+	h.Add(string(n), value)
+}
+
+// Del removes n from h
+func (n Name) Del(h http.Header) {
+	h.Del(string(n))
+}
+
+// In reports whether h has n
+func (n Name) In(h http.Header) bool {
+	_, ok := h[http.CanonicalHeaderKey(string(n))]
+	return ok
+}
@@ -0,0 +1,38 @@
+package header
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestNameCanonical(t *testing.T) {
+	for _, n := range []Name{Accept, ContentType, ETag, IdempotencyKey, TraceParent, WWWAuthenticate, XRequestID} {
+		if got := http.CanonicalHeaderKey(string(n)); got != string(n) {
+			t.Errorf("CanonicalHeaderKey(%q) got = %v, want %v", n, got, n)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	h := http.Header{}
+	if RetryAfter.In(h) {
+		t.Errorf("In() got = true, want false")
+	}
+	RetryAfter.Set(h, "30")
+	if got := h.Get("retry-after"); got != "30" {
+		t.Errorf("Set() got = %v, want %v", got, "30")
+	}
+	Vary.Add(h, "Accept")
+	Vary.Add(h, "Origin")
+	if got := Vary.Values(h); !reflect.DeepEqual(got, []string{"Accept", "Origin"}) {
+		t.Errorf("Values() got = %v, want %v", got, []string{"Accept", "Origin"})
+	}
+	if got := Vary.Get(h); got != "Accept" {
+		t.Errorf("Get() got = %v, want %v", got, "Accept")
+	}
+	Vary.Del(h)
+	if Vary.In(h) || !RetryAfter.In(h) {
+		t.Errorf("Del() got = %v", h)
+	}
+}
@@ -0,0 +1,214 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithBandwidthLimit caps the request and response bodies of all requests
+// of the client together to bytesPerSec, so background jobs don't saturate
+// the network of a shared host. Bodies are read in bursts of up to a tenth
+// of a second of data. A limit that is not positive disables it.
+func WithBandwidthLimit(bytesPerSec int64) Option {
+	return func(c *Client) {
+		c.bandwidth = nil
+		if bytesPerSec > 0 {
+			c.bandwidth = newByteBucket(float64(bytesPerSec), time.Now)
+		}
+	}
+}
+
+// byteBucket is a token bucket of bytes. Reads take their bytes after they
+// are done and may leave it in debt, the next read waits for it to be paid.
+type byteBucket struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newByteBucket(rate float64, now func() time.Time) *byteBucket {
+	burst := rate / 10
+	return &byteBucket{rate: rate, burst: burst, now: now, tokens: burst, last: now()}
+}
+
+// take takes n bytes and waits until the bucket is out of debt
+func (b *byteBucket) take(ctx context.Context, n int) error {
+	b.mu.Lock()
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	debt := -b.tokens
+	b.mu.Unlock()
+	if debt <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(debt / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return contextError(ctx.Err())
+	}
+}
+
+// throttledBody reads a body through a byteBucket
+type throttledBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	bucket *byteBucket
+}
+
+func (t *throttledBody) Read(p []byte) (int, error) {
+	// a read larger than a burst would go into debt for long at once
+	if limit := int(t.bucket.burst); limit > 0 && len(p) > limit {
+		p = p[:limit]
+	}
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		if waitErr := t.bucket.take(t.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// applyBandwidth wraps the transport below hedging, so every copy is limited
+func (c *Client) applyBandwidth() {
+	if c.bandwidth == nil {
+		return
+	}
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient := *c.httpClient
+	httpClient.Transport = &bandwidthTransport{base: base, bucket: c.bandwidth}
+	c.httpClient = &httpClient
+}
+
+type bandwidthTransport struct {
+	base   http.RoundTripper
+	bucket *byteBucket
+}
+
+func (t *bandwidthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(ctx)
+		req.Body = &throttledBody{ReadCloser: req.Body, ctx: ctx, bucket: t.bucket}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if resp != nil && resp.Body != nil {
+		resp.Body = &throttledBody{ReadCloser: resp.Body, ctx: ctx, bucket: t.bucket}
+	}
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *bandwidthTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// speedWindow is the span of the last reads InstantSpeed is measured over
+const speedWindow = time.Second
+
+// Transfer tells how fast a response body was read
+type Transfer struct {
+	// Bytes is the size of the body read
+	Bytes int64
+	// Duration is the time from the response headers to the end of the body
+	Duration time.Duration
+	// AvgSpeed is Bytes per second over Duration
+	AvgSpeed float64
+	// InstantSpeed is the bytes per second over the last second of the
+	// transfer, AvgSpeed for shorter transfers
+	InstantSpeed float64
+}
+
+type transferRecorderKey struct{}
+
+// transferRecorder measures the response body of the last attempt of a
+// Request
+type transferRecorder struct {
+	mu       sync.Mutex
+	start    time.Time
+	bytes    int64
+	samples  []transferSample
+	transfer Transfer
+}
+
+type transferSample struct {
+	at    time.Time
+	bytes int64
+}
+
+func withTransferRecorder(ctx context.Context) (context.Context, *transferRecorder) {
+	rec := &transferRecorder{}
+	return context.WithValue(ctx, transferRecorderKey{}, rec), rec
+}
+
+// meter has rec measure the reads of resp.Body, when ctx carries one
+func meter(ctx context.Context, resp *http.Response) {
+	rec, _ := ctx.Value(transferRecorderKey{}).(*transferRecorder)
+	if rec == nil || resp == nil || resp.Body == nil {
+		return
+	}
+	rec.mu.Lock()
+	rec.start, rec.bytes, rec.samples, rec.transfer = time.Now(), 0, nil, Transfer{}
+	rec.mu.Unlock()
+	resp.Body = &meteredBody{ReadCloser: resp.Body, rec: rec}
+}
+
+func (r *transferRecorder) add(n int) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bytes += int64(n)
+	r.samples = append(r.samples, transferSample{at: now, bytes: r.bytes})
+	// keep one sample older than the window to measure from
+	for len(r.samples) > 2 && now.Sub(r.samples[1].at) >= speedWindow {
+		r.samples = r.samples[1:]
+	}
+	r.transfer.Bytes = r.bytes
+	r.transfer.Duration = now.Sub(r.start)
+	if secs := r.transfer.Duration.Seconds(); secs > 0 {
+		r.transfer.AvgSpeed = float64(r.bytes) / secs
+	}
+	r.transfer.InstantSpeed = r.transfer.AvgSpeed
+	if first := r.samples[0]; now.Sub(r.start) > speedWindow && now.After(first.at) {
+		r.transfer.InstantSpeed = float64(r.bytes-first.bytes) / now.Sub(first.at).Seconds()
+	}
+}
+
+func (r *transferRecorder) get() Transfer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.transfer
+}
+
+type meteredBody struct {
+	io.ReadCloser
+	rec *transferRecorder
+}
+
+func (m *meteredBody) Read(p []byte) (int, error) {
+	n, err := m.ReadCloser.Read(p)
+	if n > 0 {
+		m.rec.add(n)
+	}
+	return n, err
+}
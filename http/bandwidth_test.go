@@ -0,0 +1,66 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithBandwidthLimit(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 50_000)
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(payload)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(WithBandwidthLimit(100_000), WithLogger(NopLogger))
+
+	start := time.Now()
+	resp, err := client.NewRequest(GET, server.URL).Send()
+	elapsed := time.Since(start)
+	if err != nil || len(resp.Body) != len(payload) {
+		t.Fatalf("Send() got = %v %v", resp, err)
+	}
+	// a tenth of a second of data is the burst
+	if elapsed < 300*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Send() download took %v, want about 400ms at 100kB/s", elapsed)
+	}
+	tr := resp.Transfer
+	if tr.Bytes != int64(len(payload)) || tr.Duration < 300*time.Millisecond || tr.AvgSpeed < 50_000 || tr.AvgSpeed > 150_000 || tr.InstantSpeed != tr.AvgSpeed {
+		t.Errorf("Response.Transfer got = %+v, want about 100kB/s", tr)
+	}
+
+	start = time.Now()
+	if _, err := client.NewRequest(POST, server.URL).RawBody(payload).Send(); err != nil || received != len(payload) {
+		t.Fatalf("Send() upload got = %v, %v bytes", err, received)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Send() upload took %v, want about 400ms at 100kB/s", elapsed)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	resp, err := NewClient(WithLogger(NopLogger)).NewRequest(GET, server.URL).Send()
+	if err != nil || resp.Transfer.Bytes != 5 || resp.Transfer.AvgSpeed <= 0 {
+		t.Errorf("Response.Transfer got = %+v %v, want 5 bytes measured", resp.Transfer, err)
+	}
+
+	rec := &transferRecorder{start: time.Now().Add(-3 * time.Second)}
+	rec.samples = []transferSample{{at: time.Now().Add(-2 * time.Second), bytes: 0}, {at: time.Now().Add(-1500 * time.Millisecond), bytes: 1000}}
+	rec.bytes = 1000
+	rec.add(9000)
+	if tr := rec.get(); tr.AvgSpeed > 4000 || tr.InstantSpeed < 5000 {
+		t.Errorf("transferRecorder got = %+v, want the instant speed of the last second", tr)
+	}
+}
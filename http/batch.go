@@ -0,0 +1,192 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/Stellar1999/gotool/pool"
+)
+
+// ErrBatchItemTooLarge is returned when a single item exceeds the byte limit
+var ErrBatchItemTooLarge = errors.New("batch item exceeds the byte limit")
+
+type batchConfig struct {
+	maxItems    int
+	maxBytes    int
+	concurrency int
+	header      map[string]string
+}
+
+type BatchOption = opt.Option[batchConfig]
+
+// WithMaxItems limits the number of items per request
+func WithMaxItems(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.maxItems = n
+	}
+}
+
+// WithMaxBytes limits the encoded size of each request body
+func WithMaxBytes(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.maxBytes = n
+	}
+}
+
+// WithConcurrency sets how many chunks are sent at once, 4 by default
+func WithConcurrency(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithBatchHeader sets the headers of every chunk request
+func WithBatchHeader(header map[string]string) BatchOption {
+	return func(c *batchConfig) {
+		c.header = header
+	}
+}
+
+var batchChecks = []opt.Check[batchConfig]{
+	func(c *batchConfig) error {
+		if c.maxItems <= 0 && c.maxBytes <= 0 {
+			return errors.New("batch: WithMaxItems or WithMaxBytes is required")
+		}
+		if c.concurrency <= 0 {
+			return errors.New("batch: concurrency must be positive")
+		}
+		return nil
+	},
+}
+
+// BatchChunk is the outcome of one chunk request, Offset and Count locate its items
+type BatchChunk struct {
+	Offset     int
+	Count      int
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Err        error
+}
+
+// BatchError lists the failed chunks, it unwraps to the first failure
+type BatchError struct {
+	Failed []BatchChunk
+}
+
+func (e *BatchError) Error() string {
+	msgs := make([]string, 0, len(e.Failed))
+	for _, chunk := range e.Failed {
+		msgs = append(msgs, fmt.Sprintf("items %d-%d: %v", chunk.Offset, chunk.Offset+chunk.Count-1, chunk.Err))
+	}
+	return fmt.Sprintf("batch: %d chunks failed: %s", len(e.Failed), strings.Join(msgs, "; "))
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Failed[0].Err
+}
+
+// PostBatch posts items as JSON arrays split under the item and byte limits.
+// Chunks are returned in item order, when some fail the error is a *BatchError
+// and the successful chunks are still returned. A nil client uses the default Client.
+func PostBatch[T any](ctx context.Context, client *Client, url string, items []T, opts ...BatchOption) ([]BatchChunk, error) {
+	if client == nil {
+		client = defaultClient
+	}
+	cfg := batchConfig{concurrency: 4}
+	if err := opt.Build(&cfg, opts, batchChecks...); err != nil {
+		return nil, err
+	}
+	encoded := make([]json.RawMessage, len(items))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("batch: encode item %d: %w", i, err)
+		}
+		encoded[i] = data
+	}
+	chunks, err := splitBatch(encoded, cfg.maxItems, cfg.maxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// every chunk fits the queue, the pool only bounds the requests in flight
+	workers, err := pool.New(pool.WithSize(cfg.concurrency), pool.WithQueueSize(len(chunks)))
+	if err != nil {
+		return nil, err
+	}
+	results := make([]BatchChunk, len(chunks))
+	futures := make([]*pool.Future[struct{}], len(chunks))
+	for i, chunk := range chunks {
+		results[i] = BatchChunk{Offset: chunk.offset, Count: len(chunk.items)}
+		result, items := &results[i], chunk.items
+		futures[i], err = workers.Submit(ctx, func(ctx context.Context) error {
+			code, header, data, err := client.PostWithContext(ctx, url, cfg.header, nil, items)
+			result.StatusCode, result.Header, result.Err = code, header, err
+			result.Body, _ = data.([]byte)
+			return err
+		})
+		if err != nil {
+			result.StatusCode, result.Err = -1, err
+		}
+	}
+	workers.Close()
+	for i, f := range futures {
+		if f == nil {
+			continue
+		}
+		// chunks whose ctx was done before they were sent are not run
+		if _, err := f.Wait(context.Background()); err != nil && results[i].Err == nil {
+			results[i].StatusCode, results[i].Err = -1, err
+		}
+	}
+
+	var failed []BatchChunk
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	if len(failed) > 0 {
+		return results, &BatchError{Failed: failed}
+	}
+	return results, nil
+}
+
+type batch struct {
+	offset int
+	items  []json.RawMessage
+}
+
+// splitBatch packs items greedily, a JSON array costs 2 bytes plus a comma between items
+func splitBatch(items []json.RawMessage, maxItems int, maxBytes int) ([]batch, error) {
+	var batches []batch
+	current := batch{}
+	size := 2
+	for i, item := range items {
+		if maxBytes > 0 && len(item)+2 > maxBytes {
+			return nil, fmt.Errorf("%w: item %d is %d bytes", ErrBatchItemTooLarge, i, len(item))
+		}
+		itemSize := len(item)
+		if len(current.items) > 0 {
+			itemSize++
+		}
+		full := (maxItems > 0 && len(current.items) == maxItems) || (maxBytes > 0 && size+itemSize > maxBytes)
+		if full {
+			batches = append(batches, current)
+			current = batch{offset: i}
+			size, itemSize = 2, len(item)
+		}
+		current.items = append(current.items, item)
+		size += itemSize
+	}
+	if len(current.items) > 0 {
+		batches = append(batches, current)
+	}
+	return batches, nil
+}
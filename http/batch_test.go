@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSplitBatch(t *testing.T) {
+	items := []json.RawMessage{[]byte(`1`), []byte(`22`), []byte(`333`), []byte(`4444`), []byte(`5`)}
+	tests := []struct {
+		name     string
+		maxItems int
+		maxBytes int
+		want     []int
+		wantErr  error
+	}{
+		{name: "items", maxItems: 2, want: []int{2, 2, 1}},
+		{name: "bytes", maxBytes: 8, want: []int{2, 1, 2}},
+		{name: "both", maxItems: 1, maxBytes: 100, want: []int{1, 1, 1, 1, 1}},
+		{name: "too large", maxBytes: 5, wantErr: ErrBatchItemTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitBatch(items, tt.maxItems, tt.maxBytes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("splitBatch() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitBatch() got %d batches, want %v", len(got), tt.want)
+			}
+			offset := 0
+			for i, b := range got {
+				if len(b.items) != tt.want[i] || b.offset != offset {
+					t.Errorf("splitBatch() batch %d got = %d items at %d, want %d at %d", i, len(b.items), b.offset, tt.want[i], offset)
+				}
+				if body, _ := json.Marshal(b.items); tt.maxBytes > 0 && len(body) > tt.maxBytes {
+					t.Errorf("splitBatch() batch %d is %d bytes, want at most %d", i, len(body), tt.maxBytes)
+				}
+				offset += len(b.items)
+			}
+		})
+	}
+}
+
+func TestPostBatch(t *testing.T) {
+	var inFlight, maxInFlight int64
+	var mu sync.Mutex
+	var received []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			m := atomic.LoadInt64(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt64(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		body, _ := io.ReadAll(r.Body)
+		var items []int
+		_ = json.Unmarshal(body, &items)
+		mu.Lock()
+		received = append(received, items...)
+		mu.Unlock()
+		if items[0] == 6 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"accepted":` + strconv.Itoa(len(items)) + `}`))
+	}))
+	defer server.Close()
+
+	items := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	chunks, err := PostBatch(context.Background(), NewClient(WithLogger(NopLogger)), server.URL, items, WithMaxItems(3), WithConcurrency(2))
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 || batchErr.Failed[0].Offset != 6 {
+		t.Fatalf("PostBatch() error = %v, want one failed chunk at offset 6", err)
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusBadRequest {
+		t.Errorf("PostBatch() error got = %v, want to unwrap to StatusError 400", err)
+	}
+	if len(chunks) != 4 || string(chunks[0].Body) != `{"accepted":3}` || chunks[3].Count != 1 {
+		t.Errorf("PostBatch() chunks got = %+v, want 4 chunks in order", chunks)
+	}
+	if len(received) != len(items) {
+		t.Errorf("PostBatch() server received %v, want all items", received)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("PostBatch() concurrency got = %v, want at most 2", maxInFlight)
+	}
+
+	if _, err := PostBatch(context.Background(), nil, server.URL, items); err == nil {
+		t.Errorf("PostBatch() without limits error = nil, want error")
+	}
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// BudgetHeader is the header carrying the remaining budget of a request in
+// milliseconds, for WithBudgetHeader and BudgetFromHeader
+const BudgetHeader = "X-Request-Timeout-Ms"
+
+type budgetKey struct{}
+
+// budget is the time left for a logical operation
+type budget struct {
+	deadline time.Time
+}
+
+// WithBudget gives the requests sent with ctx total time together, retries
+// and hedges included: ctx gets a deadline after total, each retry attempt
+// gets its share of the time left and hedges are sent early enough to fit
+// in it. With WithBudgetHeader the time left is sent along, so downstream
+// services can give up when the caller will. An earlier deadline of ctx
+// shortens the budget.
+func WithBudget(ctx context.Context, total time.Duration) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(total)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	ctx = context.WithValue(ctx, budgetKey{}, &budget{deadline: deadline})
+	return context.WithDeadline(ctx, deadline)
+}
+
+// BudgetFromContext returns the time left of the budget of ctx
+func BudgetFromContext(ctx context.Context) (time.Duration, bool) {
+	b, ok := ctx.Value(budgetKey{}).(*budget)
+	if !ok {
+		return 0, false
+	}
+	return time.Until(b.deadline), true
+}
+
+// BudgetFromHeader starts a budget from the header name of an incoming
+// request, e.g. BudgetHeader sent by a client using WithBudgetHeader. ctx is
+// returned as is when the header is missing or invalid.
+func BudgetFromHeader(ctx context.Context, header http.Header, name string) (context.Context, context.CancelFunc) {
+	ms, err := strconv.ParseInt(header.Get(name), 10, 64)
+	if err != nil || ms < 0 {
+		return ctx, func() {}
+	}
+	return WithBudget(ctx, time.Duration(ms)*time.Millisecond)
+}
+
+// WithBudgetHeader sends the time left of the budget of a request in the
+// header name, BudgetHeader when name is empty. Requests without a budget
+// don't get the header.
+func WithBudgetHeader(name string) Option {
+	return func(c *Client) {
+		if name == "" {
+			name = BudgetHeader
+		}
+		c.budgetHeader = name
+	}
+}
+
+// withBudgetHeader returns req with the time left until the deadline of ctx
+// in the budget header, req itself is not changed
+func (c *Client) withBudgetHeader(ctx context.Context, req *http.Request) *http.Request {
+	if c.budgetHeader == "" {
+		return req
+	}
+	if _, ok := ctx.Value(budgetKey{}).(*budget); !ok {
+		return req
+	}
+	// the deadline of an attempt may come before that of the budget
+	deadline, _ := ctx.Deadline()
+	ms := time.Until(deadline).Milliseconds()
+	if ms < 0 {
+		ms = 0
+	}
+	out := new(http.Request)
+	*out = *req
+	out.Header = req.Header.Clone()
+	if out.Header == nil {
+		out.Header = make(http.Header)
+	}
+	out.Header.Set(c.budgetHeader, strconv.FormatInt(ms, 10))
+	return out
+}
+
+// budgetAttempt returns the ctx of a retry attempt, with a deadline after
+// its share of the budget when the attempts left are known
+func budgetAttempt(ctx context.Context, attemptsLeft int) (context.Context, context.CancelFunc) {
+	left, ok := BudgetFromContext(ctx)
+	if !ok || attemptsLeft <= 1 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, left/time.Duration(attemptsLeft))
+}
+
+// budgetHedgeDelay shortens delay so all maxExtra hedges are sent within the
+// budget of ctx
+func budgetHedgeDelay(ctx context.Context, delay time.Duration, maxExtra int) time.Duration {
+	left, ok := BudgetFromContext(ctx)
+	if !ok {
+		return delay
+	}
+	if share := left / time.Duration(maxExtra+1); share < delay {
+		return share
+	}
+	return delay
+}
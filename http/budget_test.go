@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/retry"
+)
+
+func TestWithBudget(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	budgetCtx, cancelBudget := WithBudget(ctx, time.Hour)
+	defer cancelBudget()
+	left, ok := BudgetFromContext(budgetCtx)
+	if !ok || left > 100*time.Millisecond {
+		t.Errorf("BudgetFromContext() got = %v %v, want the earlier deadline of ctx", left, ok)
+	}
+	if _, ok := BudgetFromContext(ctx); ok {
+		t.Errorf("BudgetFromContext() got a budget for a plain context")
+	}
+
+	header := http.Header{BudgetHeader: {"abc"}}
+	if got, _ := BudgetFromHeader(ctx, header, BudgetHeader); got != ctx {
+		t.Errorf("BudgetFromHeader() started a budget from an invalid header")
+	}
+}
+
+func TestBudgetRetry(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first attempt hangs until it is canceled
+		if atomic.AddInt64(&requests, 1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithRetry(retry.Attempts(3), retry.Backoff(retry.Constant(0))), WithLogger(NopLogger))
+	ctx, cancel := WithBudget(context.Background(), 600*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	resp, err := client.NewRequest(GET, server.URL).WithContext(ctx).Send()
+	if err != nil || resp.String() != "ok" {
+		t.Fatalf("Send() got = %v %v, want the second attempt to answer", resp, err)
+	}
+	// the first attempt got a third of the budget
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > 400*time.Millisecond {
+		t.Errorf("Send() took %v, want about 200ms", elapsed)
+	}
+}
+
+func TestBudgetHedging(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&requests, 1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte("fast"))
+	}))
+	defer server.Close()
+
+	// a delay of an hour would never hedge, the budget sends the copy in time
+	client := NewClient(WithHedging(time.Hour, 1), WithLogger(NopLogger))
+	ctx, cancel := WithBudget(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	resp, err := client.NewRequest(GET, server.URL).WithContext(ctx).Send()
+	if err != nil || resp.String() != "fast" {
+		t.Errorf("Send() got = %v %v, want the hedge to answer", resp, err)
+	}
+}
+
+func TestBudgetHeader(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Deadline")
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBudgetHeader("X-Deadline"), WithLogger(NopLogger))
+	ctx, cancel := WithBudget(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := client.NewRequest(GET, server.URL).WithContext(ctx).Send(); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	ms, err := strconv.Atoi(<-received)
+	if err != nil || ms <= 1900 || ms > 2000 {
+		t.Errorf("WithBudgetHeader() sent %v %v, want about 2000", ms, err)
+	}
+
+	if _, err := client.NewRequest(GET, server.URL).Send(); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got := <-received; got != "" {
+		t.Errorf("WithBudgetHeader() sent %q without a budget", got)
+	}
+
+	// the receiving side picks the budget up again
+	header := http.Header{BudgetHeader: {"300"}}
+	inbound, cancel := BudgetFromHeader(context.Background(), header, BudgetHeader)
+	defer cancel()
+	deadline, ok := inbound.Deadline()
+	if left, budgeted := BudgetFromContext(inbound); !ok || !budgeted || left > 300*time.Millisecond || time.Until(deadline) < 250*time.Millisecond {
+		t.Errorf("BudgetFromHeader() got = %v %v %v", left, budgeted, deadline)
+	}
+}
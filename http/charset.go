@@ -0,0 +1,52 @@
+package http
+
+import (
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// xmlEncoding matches the encoding of the XML declaration up to its value
+var xmlEncoding = regexp.MustCompile(`^(\s*<\?xml[^>]*?\sencoding\s*=\s*)("[^"]*"|'[^']*')`)
+
+// decodeCharset returns body converted to UTF-8 from the charset of its
+// Content-Type, along with its MIME type and the canonical name of the
+// charset. Without a Content-Type both are sniffed from the body. Bodies in
+// unknown charsets or that fail to convert are returned as they are. The
+// encoding of the declaration of a converted XML body is changed to UTF-8, so
+// encoding/xml reads it.
+func decodeCharset(header http.Header, body []byte) ([]byte, string, string) {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		if len(body) == 0 {
+			return body, "", ""
+		}
+		contentType = http.DetectContentType(body)
+	}
+	mimeType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return body, "", ""
+	}
+	name := strings.ToLower(strings.TrimSpace(params["charset"]))
+	if name == "" {
+		return body, mimeType, ""
+	}
+	enc, canonical := charset.Lookup(name)
+	if enc == nil {
+		return body, mimeType, name
+	}
+	if canonical == "utf-8" {
+		return body, mimeType, canonical
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body, mimeType, canonical
+	}
+	if mimeType == "application/xml" || mimeType == "text/xml" || strings.HasSuffix(mimeType, "+xml") {
+		decoded = xmlEncoding.ReplaceAll(decoded, []byte(`${1}"UTF-8"`))
+	}
+	return decoded, mimeType, canonical
+}
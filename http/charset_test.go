@@ -0,0 +1,55 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseCharset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gbk":
+			w.Header().Set("Content-Type", "application/json; charset=GBK")
+			_, _ = w.Write([]byte("{\"greeting\":\"\xc4\xe3\xba\xc3\"}"))
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/plain; charset=iso-8859-1")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("caf\xe9"))
+		case "/sniffed":
+			w.Header()["Content-Type"] = nil
+			_, _ = w.Write([]byte("<html><body>hi</body></html>"))
+		case "/xml":
+			w.Header().Set("Content-Type", "application/xml; charset=iso-8859-1")
+			_, _ = w.Write([]byte("<?xml version=\"1.0\" encoding='ISO-8859-1'?>\n<codecItem><name>caf\xe9</name><count>1</count></codecItem>"))
+		case "/unknown":
+			w.Header().Set("Content-Type", "text/plain; charset=x-made-up")
+			_, _ = w.Write([]byte("raw\xff"))
+		}
+	}))
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+
+	resp, err := client.NewRequest(GET, server.URL+"/gbk").Send()
+	var v struct{ Greeting string }
+	if err != nil || resp.JSON(&v) != nil || v.Greeting != "你好" || resp.MIMEType != "application/json" || resp.Charset != "gbk" {
+		t.Errorf("Send() GBK got = %q %v %v %v, want 你好", resp.Body, resp.MIMEType, resp.Charset, err)
+	}
+	resp, err = client.NewRequest(GET, server.URL+"/latin1").Send()
+	if err == nil || resp.String() != "café" || resp.MIMEType != "text/plain" || resp.Charset != "windows-1252" {
+		t.Errorf("Send() latin1 got = %q %v %v, want café", resp.Body, resp.MIMEType, resp.Charset)
+	}
+	resp, err = client.NewRequest(GET, server.URL+"/sniffed").Send()
+	if err != nil || resp.MIMEType != "text/html" || resp.Charset != "utf-8" {
+		t.Errorf("Send() sniffed got = %v %v %v, want text/html", resp.MIMEType, resp.Charset, err)
+	}
+	resp, err = client.NewRequest(GET, server.URL+"/xml").Send()
+	var item codecItem
+	if err != nil || resp.Decode(&item) != nil || item.Name != "café" {
+		t.Errorf("Send() XML got = %q %v, want café", resp.Body, err)
+	}
+	resp, err = client.NewRequest(GET, server.URL+"/unknown").Send()
+	if err != nil || resp.String() != "raw\xff" || resp.Charset != "x-made-up" {
+		t.Errorf("Send() unknown charset got = %q %v %v, want the body as is", resp.Body, resp.Charset, err)
+	}
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/Stellar1999/gotool/retry"
+)
+
+// Client sends requests with its own http.Client and settings,
+// the package level functions use a default Client.
+type Client struct {
+	httpClient    *http.Client
+	logger        Logger
+	dump          bool
+	redactHeaders map[string]bool
+	redactor      Redactor
+	headers       http.Header
+	stats         clientStats
+	codec         Codec
+	bodyValidator func(any) error
+	tuning        transportTuning
+	pool          connPool
+	protocols     protocols
+	dns           *dnsCache
+	rateLimit     rateLimiting
+	bandwidth     *byteBucket
+	hedging       hedging
+	failover      failoverConfig
+	singleflight  *singleflight
+	lifecycle     lifecycle
+
+	maxResponseBytes int64
+	retryOpts        []retry.Option
+	budgetHeader     string
+	har              *harRecorder
+	harMaxBody       int
+
+	allowedHosts    []string
+	blockPrivateIPs bool
+	redirectPolicy  func(req *http.Request, via []*http.Request) error
+}
+
+type Option = opt.Option[Client]
+
+var defaultClient = NewClient()
+
+// NewClient creates a Client, by default it logs through the package logger set by SetLogger
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient:    createHTTPClient(),
+		redactHeaders: defaultRedactHeaders(),
+		headers:       http.Header{"User-Agent": {DefaultUserAgent}},
+		harMaxBody:    defaultHARMaxBody,
+	}
+	own := c.httpClient.Transport
+	opt.Apply(c, opts...)
+	c.applyTransportTuning(own)
+	c.applyRedirectPolicy()
+	c.applyHostGuard()
+	c.applyProtocols()
+	c.applyBandwidth()
+	c.applyRateLimit()
+	c.applyHAR()
+	c.applyFailover()
+	c.applyHedging()
+	c.applySingleflight()
+	return c
+}
+
+// WithHTTPClient replaces the underlying http.Client
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithTransport replaces the transport of the underlying http.Client
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = transport
+	}
+}
+
+func (c *Client) Get(url string, header map[string]string, parameter map[string]string) (int, http.Header, any, error) {
+	return c.GetWithContext(context.Background(), url, header, parameter)
+}
+
+func (c *Client) GetWithContext(ctx context.Context, url string, header map[string]string, parameter map[string]string) (int, http.Header, any, error) {
+	return c.send(ctx, GET, url, header, parameter, nil)
+}
+
+func (c *Client) Post(url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
+	return c.PostWithContext(context.Background(), url, header, parameter, body)
+}
+
+func (c *Client) PostWithContext(ctx context.Context, url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
+	return c.send(ctx, POST, url, header, parameter, body)
+}
+
+func (c *Client) Patch(url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
+	return c.PatchWithContext(context.Background(), url, header, parameter, body)
+}
+
+func (c *Client) PatchWithContext(ctx context.Context, url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
+	return c.send(ctx, PATCH, url, header, parameter, body)
+}
+
+func (c *Client) Put(url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
+	return c.PutWithContext(context.Background(), url, header, parameter, body)
+}
+
+func (c *Client) PutWithContext(ctx context.Context, url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
+	return c.send(ctx, PUT, url, header, parameter, body)
+}
+
+func (c *Client) Delete(url string, header map[string]string, parameter map[string]string) (int, http.Header, any, error) {
+	return c.DeleteWithContext(context.Background(), url, header, parameter, nil)
+}
+
+func (c *Client) DeleteWithContext(ctx context.Context, url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
+	return c.send(ctx, DELETE, url, header, parameter, body)
+}
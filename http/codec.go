@@ -0,0 +1,120 @@
+package http
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/Stellar1999/gotool/contenttype"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrCodec is returned for values a codec cannot handle
+var ErrCodec = errors.New("codec")
+
+// Codec encodes request bodies and decodes response bodies
+type Codec interface {
+	// ContentType is sent as the Content-Type of encoded bodies
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	JSONCodec     Codec = jsonCodec{}
+	XMLCodec      Codec = xmlCodec{}
+	YAMLCodec     Codec = yamlCodec{}
+	ProtobufCodec Codec = protobufCodec{}
+)
+
+// media types negotiated by Response.Decode, the structured syntax suffixes
+// +json, +xml and +yaml are matched as well
+var codecsByMediaType = map[string]Codec{
+	"application/json":       JSONCodec,
+	"text/json":              JSONCodec,
+	"application/xml":        XMLCodec,
+	"text/xml":               XMLCodec,
+	"application/yaml":       YAMLCodec,
+	"application/x-yaml":     YAMLCodec,
+	"text/yaml":              YAMLCodec,
+	"application/protobuf":   ProtobufCodec,
+	"application/x-protobuf": ProtobufCodec,
+}
+
+// WithCodec encodes request bodies with codec instead of JSON, it is also used
+// to decode responses without a known Content-Type
+func WithCodec(codec Codec) Option {
+	return func(c *Client) {
+		c.codec = codec
+	}
+}
+
+// CodecFor returns the built-in codec for a Content-Type header value
+func CodecFor(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	if codec, ok := codecsByMediaType[mediaType]; ok {
+		return codec, true
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return JSONCodec, true
+	case strings.HasSuffix(mediaType, "+xml"):
+		return XMLCodec, true
+	case strings.HasSuffix(mediaType, "+yaml"):
+		return YAMLCodec, true
+	}
+	return nil, false
+}
+
+func (c *Client) getCodec() Codec {
+	if c.codec == nil {
+		return JSONCodec
+	}
+	return c.codec
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                { return string(contenttype.JSON) }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string                { return string(contenttype.XML) }
+func (xmlCodec) Marshal(v any) ([]byte, error)      { return xml.Marshal(v) }
+func (xmlCodec) Unmarshal(data []byte, v any) error { return xml.Unmarshal(data, v) }
+
+type yamlCodec struct{}
+
+func (yamlCodec) ContentType() string                { return string(contenttype.YAML) }
+func (yamlCodec) Marshal(v any) ([]byte, error)      { return yaml.Marshal(v) }
+func (yamlCodec) Unmarshal(data []byte, v any) error { return yaml.Unmarshal(data, v) }
+
+// protobufCodec only handles proto.Message values
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string { return string(contenttype.Protobuf) }
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T is not a proto.Message", ErrCodec, v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T is not a proto.Message", ErrCodec, v)
+	}
+	return proto.Unmarshal(data, m)
+}
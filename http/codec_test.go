@@ -0,0 +1,116 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type codecItem struct {
+	Name  string `json:"name" xml:"name" yaml:"name"`
+	Count int    `json:"count" xml:"count" yaml:"count"`
+}
+
+func TestCodecFor(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        Codec
+		wantOK      bool
+	}{
+		{"application/json; charset=utf-8", JSONCodec, true},
+		{"application/problem+json", JSONCodec, true},
+		{"text/xml", XMLCodec, true},
+		{"application/atom+xml", XMLCodec, true},
+		{"application/x-yaml", YAMLCodec, true},
+		{"application/x-protobuf", ProtobufCodec, true},
+		{"text/plain", nil, false},
+		{"", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			got, ok := CodecFor(tt.contentType)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("CodecFor() got = %v %v, want %v %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec, XMLCodec, YAMLCodec} {
+		t.Run(codec.ContentType(), func(t *testing.T) {
+			data, err := codec.Marshal(codecItem{Name: "a", Count: 2})
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var got codecItem
+			if err := codec.Unmarshal(data, &got); err != nil || got != (codecItem{Name: "a", Count: 2}) {
+				t.Errorf("Unmarshal() got = %v %v, want %v", got, err, codecItem{Name: "a", Count: 2})
+			}
+		})
+	}
+	t.Run("protobuf", func(t *testing.T) {
+		data, err := ProtobufCodec.Marshal(wrapperspb.String("hello"))
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		got := &wrapperspb.StringValue{}
+		if err := ProtobufCodec.Unmarshal(data, got); err != nil || got.GetValue() != "hello" {
+			t.Errorf("Unmarshal() got = %v %v, want hello", got.GetValue(), err)
+		}
+		if _, err := ProtobufCodec.Marshal(codecItem{}); !errors.Is(err, ErrCodec) {
+			t.Errorf("Marshal() error got = %v, want %v", err, ErrCodec)
+		}
+	})
+}
+
+func TestRequestCodec(t *testing.T) {
+	var gotContentType string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotContentType = r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write([]byte("name: b\ncount: 3\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithCodec(XMLCodec))
+	resp, err := client.NewRequest(POST, server.URL).Body(codecItem{Name: "a", Count: 1}).Send()
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if gotContentType != "application/xml" || string(gotBody) != "<codecItem><name>a</name><count>1</count></codecItem>" {
+		t.Errorf("Send() got = %v %s, want application/xml body", gotContentType, gotBody)
+	}
+	var got codecItem
+	if err := resp.Decode(&got); err != nil || got != (codecItem{Name: "b", Count: 3}) {
+		t.Errorf("Decode() got = %v %v, want %v", got, err, codecItem{Name: "b", Count: 3})
+	}
+
+	// per request codec, the response has no content type so the request codec decodes it
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotContentType = r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+		data, _ := proto.Marshal(wrapperspb.Int64(42))
+		w.Header()["Content-Type"] = nil
+		_, _ = w.Write(data)
+	})
+	resp, err = client.NewRequest(PUT, server.URL).WithCodec(ProtobufCodec).Body(wrapperspb.String("x")).Send()
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	sent := &wrapperspb.StringValue{}
+	if gotContentType != "application/x-protobuf" || proto.Unmarshal(gotBody, sent) != nil || sent.GetValue() != "x" {
+		t.Errorf("Send() got = %v %v, want protobuf body", gotContentType, sent)
+	}
+	value := &wrapperspb.Int64Value{}
+	if err := resp.Decode(value); err != nil || value.GetValue() != 42 {
+		t.Errorf("Decode() got = %v %v, want 42", value.GetValue(), err)
+	}
+}
@@ -0,0 +1,168 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrPreconditionFailed matches the PreconditionError of 412 responses
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrMissingETag is returned by PutIfMatch for an empty etag, e.g. from a
+// GetWithETag on a server sending no ETag header, rather than sending an
+// unconditional update
+var ErrMissingETag = errors.New("missing etag")
+
+// PreconditionError is returned for 412 Precondition Failed, ETag is the current
+// version reported by the server, if any, to retry the update against.
+// It unwraps to the *StatusError and matches ErrPreconditionFailed and
+// errs.ErrConflict.
+type PreconditionError struct {
+	*StatusError
+	ETag string
+}
+
+func (e *PreconditionError) Unwrap() error {
+	return e.StatusError
+}
+
+func (e *PreconditionError) Is(target error) bool {
+	return target == ErrPreconditionFailed
+}
+
+// GetWithETag decodes the resource at url into out, when not nil, and
+// returns its ETag for a later PutIfMatch, with the default Client
+func GetWithETag(ctx context.Context, url string, out any) (string, error) {
+	return defaultClient.GetWithETag(ctx, url, out)
+}
+
+// PutIfMatch replaces the resource at url with body unless it changed since
+// etag, with the default Client. See Client.PutIfMatch.
+func PutIfMatch(ctx context.Context, url string, etag string, body any) (*Response, error) {
+	return defaultClient.PutIfMatch(ctx, url, etag, body)
+}
+
+// GetWithETag decodes the resource at url into out, when not nil, and
+// returns its ETag for a later PutIfMatch, "" when the server sent none
+func (c *Client) GetWithETag(ctx context.Context, url string, out any) (string, error) {
+	resp, err := c.NewRequest(GET, url).WithContext(ctx).Send()
+	if err != nil {
+		return "", err
+	}
+	if out != nil {
+		if err := resp.Decode(out); err != nil {
+			return "", err
+		}
+	}
+	return resp.ETag(), nil
+}
+
+// PutIfMatch replaces the resource at url with body unless it changed since
+// etag. An update lost to another writer fails with a PreconditionError
+// matching ErrPreconditionFailed, the read-modify-write starts over:
+//
+//	for {
+//		var doc Doc
+//		etag, err := client.GetWithETag(ctx, url, &doc)
+//		if err != nil {
+//			return err
+//		}
+//		doc.Count++
+//		_, err = client.PutIfMatch(ctx, url, etag, doc)
+//		if !errors.Is(err, http.ErrPreconditionFailed) {
+//			return err
+//		}
+//	}
+//
+// An empty etag fails with ErrMissingETag without sending anything.
+func (c *Client) PutIfMatch(ctx context.Context, url string, etag string, body any) (*Response, error) {
+	if etag == "" {
+		return nil, ErrMissingETag
+	}
+	return c.NewRequest(PUT, url).WithContext(ctx).IfMatch(etag).Body(body).Send()
+}
+
+// ETag returns the ETag header of the response
+func (r *Response) ETag() string {
+	return r.Header.Get("ETag")
+}
+
+// IfMatch makes the request conditional on the resource still having etag,
+// an empty etag sets no header
+func (r *Request) IfMatch(etag string) *Request {
+	if etag != "" {
+		r.header.Set("If-Match", etag)
+	}
+	return r
+}
+
+// IfNoneMatch makes the request conditional on the resource having changed
+// from etag, an empty etag sets no header
+func (r *Request) IfNoneMatch(etag string) *Request {
+	if etag != "" {
+		r.header.Set("If-None-Match", etag)
+	}
+	return r
+}
+
+// ETagTracker is a Hook remembering the ETag last seen for each url and
+// attaching it as If-Match to PUT, PATCH and DELETE requests on that url that
+// carry no If-Match yet. A 412 response replaces the remembered ETag with the
+// current one so the next attempt is made against the latest version.
+type ETagTracker struct {
+	mu    sync.Mutex
+	etags map[string]string
+}
+
+func NewETagTracker() *ETagTracker {
+	return &ETagTracker{etags: make(map[string]string)}
+}
+
+type etagURLKey struct{}
+
+// ETag returns the ETag remembered for url
+func (t *ETagTracker) ETag(url string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	etag, ok := t.etags[url]
+	return etag, ok
+}
+
+// Forget drops the ETag remembered for url
+func (t *ETagTracker) Forget(url string) {
+	t.mu.Lock()
+	delete(t.etags, url)
+	t.mu.Unlock()
+}
+
+func (t *ETagTracker) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	url := req.URL.String()
+	switch req.Method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		if req.Header.Get("If-Match") == "" {
+			if etag, ok := t.ETag(url); ok {
+				req.Header.Set("If-Match", etag)
+			}
+		}
+	}
+	return context.WithValue(ctx, etagURLKey{}, url), nil
+}
+
+func (t *ETagTracker) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	url, _ := ctx.Value(etagURLKey{}).(string)
+	if url == "" || respHeader == nil {
+		return ctx, nil
+	}
+	etag := respHeader.Get("ETag")
+	switch {
+	case etag != "" && (respCode/100 == 2 || respCode == http.StatusPreconditionFailed || respCode == http.StatusNotModified):
+		t.mu.Lock()
+		t.etags[url] = etag
+		t.mu.Unlock()
+	case respCode == http.StatusNotFound || respCode == http.StatusGone:
+		t.Forget(url)
+	}
+	return ctx, nil
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/Stellar1999/gotool/errs"
+)
+
+// versionedServer stores one document and rejects updates against stale ETags
+func versionedServer() *httptest.Server {
+	var mu sync.Mutex
+	version, doc := 1, `{"n":0}`
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		etag := `"v` + strconv.Itoa(version) + `"`
+		w.Header().Set("ETag", etag)
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(doc))
+		case http.MethodPut:
+			if r.Header.Get("If-Match") != etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			version, doc = version+1, string(body)
+			w.Header().Set("ETag", `"v`+strconv.Itoa(version)+`"`)
+			_, _ = w.Write(body)
+		}
+	}))
+}
+
+func TestPreconditionError(t *testing.T) {
+	server := versionedServer()
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+
+	resp, err := client.NewRequest(GET, server.URL).Send()
+	if err != nil || resp.ETag() != `"v1"` {
+		t.Fatalf("Send() got = %v %v, want ETag v1", resp, err)
+	}
+	if _, err := client.NewRequest(PUT, server.URL).IfMatch(resp.ETag()).Body(map[string]int{"n": 1}).Send(); err != nil {
+		t.Fatalf("Send() update error = %v", err)
+	}
+
+	_, err = client.NewRequest(PUT, server.URL).IfMatch(resp.ETag()).Body(map[string]int{"n": 2}).Send()
+	var preErr *PreconditionError
+	if !errors.As(err, &preErr) || preErr.ETag != `"v2"` {
+		t.Fatalf("Send() stale update error = %v, want PreconditionError with v2", err)
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusPreconditionFailed || !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("Send() stale update error = %v, want StatusError 412 and errs.ErrConflict", err)
+	}
+	if _, err := client.NewRequest(PUT, server.URL).IfMatch(preErr.ETag).Body(map[string]int{"n": 2}).Send(); err != nil {
+		t.Errorf("Send() retry with latest ETag error = %v", err)
+	}
+}
+
+func TestETagTracker(t *testing.T) {
+	server := versionedServer()
+	defer server.Close()
+
+	tracker := NewETagTracker()
+	defer AddHook(tracker).Remove()
+	client := NewClient(WithLogger(NopLogger))
+
+	tests := []struct {
+		name     string
+		method   RequestMethodType
+		wantErr  bool
+		wantETag string
+	}{
+		{name: "get captures", method: GET, wantETag: `"v1"`},
+		{name: "put attaches If-Match", method: PUT, wantETag: `"v2"`},
+		{name: "put again uses the new ETag", method: PUT, wantETag: `"v3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := client.NewRequest(tt.method, server.URL)
+			if tt.method == PUT {
+				req.Body(map[string]string{"name": tt.name})
+			}
+			if _, err := req.Send(); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if got, _ := tracker.ETag(server.URL); got != tt.wantETag {
+				t.Errorf("ETag() got = %v, want %v", got, tt.wantETag)
+			}
+		})
+	}
+
+	// a concurrent writer moves the version, the 412 refreshes the tracked ETag
+	if _, err := client.NewRequest(PUT, server.URL).IfMatch(`"v3"`).Body(1).Send(); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	tracker.etags[server.URL] = `"v3"`
+	if _, err := client.NewRequest(PUT, server.URL).Body(2).Send(); !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("Send() stale error = %v, want conflict", err)
+	}
+	if _, err := client.NewRequest(PUT, server.URL).Body(2).Send(); err != nil {
+		t.Errorf("Send() after refresh error = %v", err)
+	}
+}
+
+func TestPutIfMatch(t *testing.T) {
+	server := versionedServer()
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+	ctx := context.Background()
+
+	var doc struct{ N int }
+	etag, err := client.GetWithETag(ctx, server.URL, &doc)
+	if err != nil || etag != `"v1"` || doc.N != 0 {
+		t.Fatalf("GetWithETag() got = %v %v %v, want v1", etag, doc, err)
+	}
+	// another writer updates the document first
+	if _, err := client.PutIfMatch(ctx, server.URL, etag, map[string]int{"N": 5}); err != nil {
+		t.Fatalf("PutIfMatch() error = %v", err)
+	}
+	_, err = client.PutIfMatch(ctx, server.URL, etag, map[string]int{"N": doc.N + 1})
+	var preErr *PreconditionError
+	if !errors.Is(err, ErrPreconditionFailed) || !errors.As(err, &preErr) || preErr.ETag != `"v2"` {
+		t.Fatalf("PutIfMatch() stale error = %v, want ErrPreconditionFailed with v2", err)
+	}
+
+	if etag, err = client.GetWithETag(ctx, server.URL, &doc); err != nil || doc.N != 5 {
+		t.Fatalf("GetWithETag() got = %v %v %v, want N 5", etag, doc, err)
+	}
+	resp, err := client.PutIfMatch(ctx, server.URL, etag, map[string]int{"N": doc.N + 1})
+	if err != nil || resp.ETag() != `"v3"` {
+		t.Errorf("PutIfMatch() got = %v %v, want v3", resp, err)
+	}
+	if errors.Is(&StatusError{Code: http.StatusConflict}, ErrPreconditionFailed) {
+		t.Errorf("StatusError 409 matches ErrPreconditionFailed")
+	}
+}
+
+func TestPutIfMatchMissingETag(t *testing.T) {
+	var puts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			puts++
+		}
+		// no ETag header
+		_, _ = w.Write([]byte(`{"n":0}`))
+	}))
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+	ctx := context.Background()
+
+	etag, err := client.GetWithETag(ctx, server.URL, nil)
+	if err != nil || etag != "" {
+		t.Fatalf("GetWithETag() got = %q %v, want no ETag", etag, err)
+	}
+	if _, err := client.PutIfMatch(ctx, server.URL, etag, map[string]int{"n": 1}); !errors.Is(err, ErrMissingETag) {
+		t.Errorf("PutIfMatch() error = %v, want %v", err, ErrMissingETag)
+	}
+	if puts != 0 {
+		t.Errorf("PutIfMatch() sent %d requests, want none", puts)
+	}
+
+	req, err := client.NewRequest(PUT, server.URL).IfMatch("").IfNoneMatch("").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := req.Header["If-Match"]; ok {
+		t.Errorf("IfMatch(\"\") got header %q, want none", req.Header.Get("If-Match"))
+	}
+	if _, ok := req.Header["If-None-Match"]; ok {
+		t.Errorf("IfNoneMatch(\"\") got header %q, want none", req.Header.Get("If-None-Match"))
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// AsCurl renders the request as a curl command, with the client default
+// headers, to reproduce it outside the program. Sensitive headers are
+// redacted like in dumps, see WithRedactHeaders and WithRedactor.
+func (r *Request) AsCurl() (string, error) {
+	httpRequest, err := r.Build()
+	if err != nil {
+		return "", err
+	}
+	return r.client.curl(r.client.withDefaultHeaders(httpRequest))
+}
+
+// CurlHook logs every request as a curl command at debug level, install it
+// with AddHook
+type CurlHook struct {
+	client *Client
+}
+
+// NewCurlHook takes the logging and redaction options of a Client, i.e.
+// WithLogger, WithRedactHeaders and WithRedactor, other options are ignored
+func NewCurlHook(opts ...Option) *CurlHook {
+	return &CurlHook{client: opt.Apply(&Client{redactHeaders: defaultRedactHeaders()}, opts...)}
+}
+
+func (h *CurlHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	command, err := h.client.curl(req)
+	if err != nil {
+		h.client.getLogger().Warn("rendering curl command failed", "err", err)
+		return ctx, nil
+	}
+	h.client.getLogger().Debug("http request as curl", "curl", command)
+	return ctx, nil
+}
+
+func (h *CurlHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	return ctx, nil
+}
+
+// curl renders req as a curl command, redacting it like dumps
+func (c *Client) curl(req *http.Request) (string, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return "", err
+	}
+	body = c.redactBody(body)
+
+	var b strings.Builder
+	binary := len(body) > 0 && !utf8.Valid(body)
+	if binary {
+		// bytes that can't be quoted are piped in
+		b.WriteString("echo ")
+		b.WriteString(base64.StdEncoding.EncodeToString(body))
+		b.WriteString(" | base64 -d | ")
+	}
+	b.WriteString("curl")
+	switch req.Method {
+	case "", http.MethodGet:
+	case http.MethodHead:
+		b.WriteString(" --head")
+	default:
+		b.WriteString(" -X ")
+		b.WriteString(req.Method)
+	}
+	b.WriteByte(' ')
+	b.WriteString(shellQuote(c.redactText(req.URL.String())))
+
+	header := c.redactHeader(req.Header)
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			b.WriteString(" -H ")
+			b.WriteString(shellQuote(name + ": " + value))
+		}
+	}
+	switch {
+	case binary:
+		b.WriteString(" --data-binary @-")
+	case len(body) > 0:
+		b.WriteString(" --data-raw ")
+		b.WriteString(shellQuote(string(body)))
+	}
+	return b.String(), nil
+}
+
+// shellQuote quotes s for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAsCurl(t *testing.T) {
+	client := NewClient(WithUserAgent("tool/1"), WithRedactHeaders("X-Token"))
+	tests := []struct {
+		name    string
+		request *Request
+		want    string
+	}{
+		{
+			"get",
+			client.NewRequest(GET, "https://api.example.com/users/{id}").PathParam("id", "7").Query("q", "a b"),
+			`curl 'https://api.example.com/users/7?q=a+b' -H 'User-Agent: tool/1'`,
+		},
+		{
+			"post json",
+			client.NewRequest(POST, "https://api.example.com/notes").Header("Authorization", "Bearer secret").Header("X-Token", "t").Body(map[string]string{"text": "it's"}),
+			`curl -X POST 'https://api.example.com/notes' -H 'Authorization: [REDACTED]' -H 'Content-Type: application/json' -H 'User-Agent: tool/1' -H 'X-Token: [REDACTED]' --data-raw '{"text":"it'\''s"}'`,
+		},
+		{
+			"binary",
+			client.NewRequest(PUT, "https://api.example.com/blob").RawBody([]byte{0xff, 0x00}),
+			`echo /wA= | base64 -d | curl -X PUT 'https://api.example.com/blob' -H 'User-Agent: tool/1' --data-binary @-`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.request.AsCurl()
+		if err != nil || got != tt.want {
+			t.Errorf("AsCurl() %v got = %v %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := client.NewRequest(GET, "https://api.example.com/{id}").AsCurl(); err == nil {
+		t.Errorf("AsCurl() missing path parameter error = nil")
+	}
+}
+
+func TestCurlHook(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	logger := &recordLogger{}
+	handle := AddHook(NewCurlHook(WithLogger(logger)))
+	defer handle.Remove()
+	if _, _, _, err := Post(server.URL, map[string]string{"Cookie": "session=1"}, nil, map[string]int{"n": 1}); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if body != `{"n":1}` {
+		t.Errorf("request body got = %q, want it left for sending", body)
+	}
+	want := `DEBUG http request as curl curl=curl -X POST '` + server.URL + `' -H 'Cookie: [REDACTED]'`
+	if len(logger.entries) != 1 || !strings.HasPrefix(logger.entries[0], want) || !strings.HasSuffix(logger.entries[0], `--data-raw '{"n":1}'`) {
+		t.Errorf("log got = %v, want %v...", logger.entries, want)
+	}
+}
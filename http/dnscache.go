@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Stellar1999/gotool/cache"
+)
+
+// dnsNegativeTTL is how long a failed lookup is cached, at most the cache ttl
+const dnsNegativeTTL = 5 * time.Second
+
+// WithDNSCache caches host lookups of the dialer for ttl, keeping at most
+// maxEntries hosts, 0 means no limit, by evicting the least recently used.
+// Failed lookups are cached for a few seconds. An expired entry is still
+// used for another ttl while it is resolved again in the background.
+// FlushDNS drops entries.
+func WithDNSCache(ttl time.Duration, maxEntries int) Option {
+	return func(c *Client) {
+		c.dns = newDNSCache(ttl, maxEntries)
+	}
+}
+
+// FlushDNS drops the cached lookups of hosts, or all of them without hosts
+func (c *Client) FlushDNS(hosts ...string) {
+	if c.dns != nil {
+		c.dns.flush(hosts...)
+	}
+}
+
+type dnsEntry struct {
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+	// refreshing is guarded by dnsCache.mu
+	refreshing bool
+}
+
+type dnsCache struct {
+	ttl     time.Duration
+	resolve func(ctx context.Context, host string) ([]net.IPAddr, error)
+	now     func() time.Time
+
+	// entries keeps lookups for another ttl after they expire, to be used
+	// while they are resolved again
+	entries cache.Store[string, *dnsEntry]
+	mu      sync.Mutex
+
+	hits   int64
+	misses int64
+}
+
+func newDNSCache(ttl time.Duration, maxEntries int) *dnsCache {
+	d := &dnsCache{
+		ttl:     ttl,
+		resolve: net.DefaultResolver.LookupIPAddr,
+		now:     time.Now,
+	}
+	if maxEntries < 0 {
+		maxEntries = 0
+	}
+	// the clock is looked up on use so tests can replace d.now
+	d.entries, _ = cache.New[string, *dnsEntry](cache.WithMaxEntries(maxEntries), cache.WithClock(func() time.Time { return d.now() }))
+	return d
+}
+
+// lookup has the signature of net.Resolver.LookupIPAddr
+func (d *dnsCache) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+	if e, ok := d.entries.Get(host); ok {
+		atomic.AddInt64(&d.hits, 1)
+		if d.now().Before(e.expires) {
+			return e.addrs, e.err
+		}
+		d.mu.Lock()
+		refreshing := e.refreshing
+		e.refreshing = true
+		d.mu.Unlock()
+		if !refreshing {
+			go d.refresh(host)
+		}
+		return e.addrs, nil
+	}
+	atomic.AddInt64(&d.misses, 1)
+	addrs, err := d.resolve(ctx, host)
+	if err != nil && ctx.Err() != nil {
+		// the caller gave up, that says nothing about the host
+		return nil, err
+	}
+	d.store(host, addrs, err)
+	return addrs, err
+}
+
+// refresh resolves a stale entry again, a failure keeps it until it is too old
+func (d *dnsCache) refresh(host string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	addrs, err := d.resolve(ctx, host)
+	if err == nil {
+		d.store(host, addrs, nil)
+		return
+	}
+	if e, ok := d.entries.Get(host); ok {
+		d.mu.Lock()
+		e.refreshing = false
+		d.mu.Unlock()
+	}
+}
+
+// store caches a lookup, failures are not used stale
+func (d *dnsCache) store(host string, addrs []net.IPAddr, err error) {
+	if d.ttl <= 0 {
+		return
+	}
+	ttl, keep := d.ttl, 2*d.ttl
+	if err != nil {
+		if ttl > dnsNegativeTTL {
+			ttl = dnsNegativeTTL
+		}
+		keep = ttl
+	}
+	d.entries.SetWithTTL(host, &dnsEntry{addrs: addrs, err: err, expires: d.now().Add(ttl)}, keep)
+}
+
+func (d *dnsCache) flush(hosts ...string) {
+	if len(hosts) == 0 {
+		d.entries.Clear()
+		return
+	}
+	for _, host := range hosts {
+		d.entries.Delete(host)
+	}
+}
+
+// dial resolves the host of addr with the cache and dials the addresses in turn
+func (d *dnsCache) dial(next func(ctx context.Context, network string, addr string) (net.Conn, error)) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return next(ctx, network, addr)
+		}
+		addrs, err := d.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		var dialErr error
+		for _, ip := range addrs {
+			conn, err := next(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeResolver counts lookups and answers from addrs, unknown hosts fail
+type fakeResolver struct {
+	mu      sync.Mutex
+	addrs   map[string]string
+	lookups map[string]int
+}
+
+func (r *fakeResolver) resolve(_ context.Context, host string) ([]net.IPAddr, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups[host]++
+	addr, ok := r.addrs[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return []net.IPAddr{{IP: net.ParseIP(addr)}}, nil
+}
+
+func (r *fakeResolver) count(host string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookups[host]
+}
+
+func TestDNSCache(t *testing.T) {
+	resolver := &fakeResolver{addrs: map[string]string{"a.test": "10.0.0.1", "b.test": "10.0.0.2", "c.test": "10.0.0.3"}, lookups: map[string]int{}}
+	now := time.Unix(0, 0)
+	cache := newDNSCache(time.Minute, 2)
+	cache.resolve = resolver.resolve
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if addrs, err := cache.lookup(ctx, "a.test"); err != nil || addrs[0].IP.String() != "10.0.0.1" {
+			t.Fatalf("lookup() got = %v %v", addrs, err)
+		}
+	}
+	if got := resolver.count("a.test"); got != 1 {
+		t.Errorf("lookups got = %v, want 1", got)
+	}
+
+	// failures are cached for dnsNegativeTTL
+	for i := 0; i < 2; i++ {
+		if _, err := cache.lookup(ctx, "missing.test"); err == nil {
+			t.Errorf("lookup() error got = nil, want an error")
+		}
+	}
+	if got := resolver.count("missing.test"); got != 1 {
+		t.Errorf("negative lookups got = %v, want 1", got)
+	}
+	now = now.Add(dnsNegativeTTL)
+	_, _ = cache.lookup(ctx, "missing.test")
+	if got := resolver.count("missing.test"); got != 2 {
+		t.Errorf("negative lookups after ttl got = %v, want 2", got)
+	}
+
+	// the stale address is returned while it is resolved again
+	now = now.Add(time.Minute)
+	resolver.mu.Lock()
+	resolver.addrs["a.test"] = "10.0.0.9"
+	resolver.mu.Unlock()
+	if addrs, _ := cache.lookup(ctx, "a.test"); addrs[0].IP.String() != "10.0.0.1" {
+		t.Errorf("stale lookup() got = %v, want 10.0.0.1", addrs)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for resolver.count("a.test") != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	for time.Now().Before(deadline) {
+		if addrs, _ := cache.lookup(ctx, "a.test"); addrs[0].IP.String() == "10.0.0.9" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if addrs, _ := cache.lookup(ctx, "a.test"); addrs[0].IP.String() != "10.0.0.9" {
+		t.Errorf("refreshed lookup() got = %v, want 10.0.0.9", addrs)
+	}
+
+	// at most 2 entries, the least recently used goes
+	now = now.Add(time.Second)
+	_, _ = cache.lookup(ctx, "b.test")
+	_, _ = cache.lookup(ctx, "c.test")
+	_, hasA := cache.entries.Get("a.test")
+	size := cache.entries.Len()
+	if size != 2 || hasA {
+		t.Errorf("entries got = %v (a.test %v), want 2 without a.test", size, hasA)
+	}
+
+	cache.flush("b.test")
+	_, _ = cache.lookup(ctx, "b.test")
+	if got := resolver.count("b.test"); got != 2 {
+		t.Errorf("lookups after flush got = %v, want 2", got)
+	}
+}
+
+func TestWithDNSCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	resolver := &fakeResolver{addrs: map[string]string{"api.test": "127.0.0.1"}, lookups: map[string]int{}}
+	client := NewClient(WithDNSCache(time.Minute, 100), WithDisableKeepAlives(), WithLogger(NopLogger))
+	client.dns.resolve = resolver.resolve
+	for i := 0; i < 3; i++ {
+		if _, err := client.NewRequest(GET, "http://api.test:"+port+"/").Send(); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if got := resolver.count("api.test"); got != 1 {
+		t.Errorf("lookups got = %v, want 1", got)
+	}
+	if stats := client.Stats(); stats["dns_hits"] != 2 || stats["dns_misses"] != 1 {
+		t.Errorf("Stats() got = %v, want 2 hits and 1 miss", stats)
+	}
+
+	client.FlushDNS()
+	_, err := client.NewRequest(GET, "http://unknown.test:"+port+"/").Send()
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || resolver.count("api.test") != 1 {
+		t.Errorf("Send() error got = %v, want a DNS error", err)
+	}
+}
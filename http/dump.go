@@ -0,0 +1,113 @@
+package http
+
+import (
+	"io"
+	"net/http"
+)
+
+const redacted = "[REDACTED]"
+
+// headers hidden from dumps unless WithRedactHeaders adds more
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+func defaultRedactHeaders() map[string]bool {
+	headers := make(map[string]bool, len(sensitiveHeaders))
+	for _, name := range sensitiveHeaders {
+		headers[name] = true
+	}
+	return headers
+}
+
+// SetDump turns request/response dumps of the package functions on or off
+func SetDump(enabled bool) {
+	defaultClient.dump = enabled
+}
+
+// WithDump logs every request and response at debug level, sensitive headers are redacted
+func WithDump() Option {
+	return func(c *Client) {
+		c.dump = true
+	}
+}
+
+// Redactor hides secrets in dumps, *redact.Policy implements it
+type Redactor interface {
+	Header(header http.Header) http.Header
+	Body(body []byte) []byte
+	Text(s string) string
+}
+
+// WithRedactor applies r to the url, headers and bodies of dumps, on top of
+// the headers hidden by WithRedactHeaders
+func WithRedactor(r Redactor) Option {
+	return func(c *Client) {
+		c.redactor = r
+	}
+}
+
+// WithRedactHeaders adds headers whose values are hidden in dumps
+func WithRedactHeaders(names ...string) Option {
+	return func(c *Client) {
+		for _, name := range names {
+			c.redactHeaders[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+func (c *Client) dumpRequest(httpRequest *http.Request) {
+	var body []byte
+	if httpRequest.GetBody != nil {
+		if reader, err := httpRequest.GetBody(); err == nil {
+			body, _ = io.ReadAll(reader)
+			_ = reader.Close()
+		}
+	}
+	c.getLogger().Debug("http request",
+		"method", httpRequest.Method,
+		"url", c.redactText(httpRequest.URL.String()),
+		"header", c.redactHeader(httpRequest.Header),
+		"body", string(c.redactBody(body)),
+	)
+}
+
+func (c *Client) dumpResponse(httpRequest *http.Request, code int, header http.Header, data any, err error) {
+	body, _ := data.([]byte)
+	c.getLogger().Debug("http response",
+		"method", httpRequest.Method,
+		"url", c.redactText(httpRequest.URL.String()),
+		"code", code,
+		"header", c.redactHeader(header),
+		"body", string(c.redactBody(body)),
+		"err", err,
+	)
+}
+
+// redactHeader copies header with the sensitive values replaced
+func (c *Client) redactHeader(header http.Header) http.Header {
+	out := make(http.Header, len(header))
+	for k, v := range header {
+		if c.redactHeaders[http.CanonicalHeaderKey(k)] {
+			out[k] = []string{redacted}
+			continue
+		}
+		out[k] = v
+	}
+	if c.redactor != nil {
+		out = c.redactor.Header(out)
+	}
+	return out
+}
+
+func (c *Client) redactText(s string) string {
+	if c.redactor == nil {
+		return s
+	}
+	return c.redactor.Text(s)
+}
+
+func (c *Client) redactBody(body []byte) []byte {
+	if c.redactor == nil || len(body) == 0 {
+		return body
+	}
+	return c.redactor.Body(body)
+}
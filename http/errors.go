@@ -0,0 +1,57 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+
+	"github.com/Stellar1999/gotool/errorx"
+	"github.com/Stellar1999/gotool/errs"
+)
+
+// StatusError is returned for responses other than 200 OK, errors.Is matches
+// it against the errs classes, e.g. errors.Is(err, errs.ErrNotFound) for a 404.
+type StatusError struct {
+	Code int
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	return "remote error, url: code " + strconv.Itoa(e.Code) + ", response body: " + string(e.Body)
+}
+
+func (e *StatusError) Is(target error) bool {
+	kind := errs.FromStatus(e.Code)
+	return kind != nil && target == kind
+}
+
+// ErrorCode classifies the status for errorx, e.g. errorx.NotFound for a 404.
+// Statuses below 400 are unexpected answers, errorx.Internal.
+func (e *StatusError) ErrorCode() errorx.Code {
+	if code := errorx.CodeFromStatus(e.Code); code != "" {
+		return code
+	}
+	return errorx.Internal
+}
+
+// contextError returns the error of a done ctx as is, errors.Is(err,
+// errs.ErrTimeout) holds for deadlines as for other timeouts
+func contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errs.Mark(err, errs.ErrTimeout)
+	}
+	return err
+}
+
+// classifyTransportError marks timeouts so errors.Is(err, errs.ErrTimeout) holds
+func classifyTransportError(err error) error {
+	var netErr net.Error
+	if errors.Is(err, errs.ErrTimeout) {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return errs.Mark(err, errs.ErrTimeout)
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/errorx"
+	"github.com/Stellar1999/gotool/errs"
+)
+
+func TestErrorClasses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/conflict":
+			w.WriteHeader(http.StatusConflict)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("oops"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithLogger(NopLogger), WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}))
+	tests := []struct {
+		path     string
+		wantKind error
+		wantCode errorx.Code
+	}{
+		{path: "/missing", wantKind: errs.ErrNotFound, wantCode: errorx.NotFound},
+		{path: "/busy", wantKind: errs.ErrRateLimited, wantCode: errorx.RateLimited},
+		{path: "/conflict", wantKind: errs.ErrConflict, wantCode: errorx.Conflict},
+		{path: "/slow", wantKind: errs.ErrTimeout, wantCode: errorx.Timeout},
+		{path: "/broken", wantKind: nil, wantCode: errorx.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, _, _, err := client.Get(server.URL+tt.path, nil, nil)
+			if err == nil {
+				t.Fatalf("Get() error = nil, want error")
+			}
+			if got := errs.Kind(err); got != tt.wantKind {
+				t.Errorf("errs.Kind() got = %v, want %v", got, tt.wantKind)
+			}
+			if got := errorx.CodeOf(err); got != tt.wantCode {
+				t.Errorf("errorx.CodeOf() got = %v, want %v", got, tt.wantCode)
+			}
+		})
+	}
+
+	_, _, _, err := client.Get(server.URL+"/broken", nil, nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusInternalServerError || string(statusErr.Body) != "oops" {
+		t.Errorf("Get() error = %#v, want StatusError 500 oops", err)
+	}
+	if got, want := err.Error(), "remote error, url: code 500, response body: oops"; got != want {
+		t.Errorf("Error() got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,277 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	gourl "net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithFailover sends the requests for primary, a base URL such as
+// https://api.example.com/v1, to the first of secondaries once the primary
+// fails with a transport error or a failover status, see
+// WithFailoverStatuses. The failed request is sent again to the secondary
+// when its body allows it. Requests stay on the secondary, the next one
+// taking over when it fails too, while the primary is probed in the
+// background; they return to the primary once it answers a probe. Installed
+// hooks implementing FailoverHook are told of every switch, Stats counts
+// the switches as failovers_total.
+func WithFailover(primary string, secondaries ...string) Option {
+	return func(c *Client) {
+		c.failover.targets = append([]string{primary}, secondaries...)
+	}
+}
+
+// WithFailoverStatuses are the statuses that make WithFailover leave a base
+// URL, 502, 503 and 504 by default
+func WithFailoverStatuses(codes ...int) Option {
+	return func(c *Client) {
+		c.failover.statuses = codes
+	}
+}
+
+// WithFailoverProbe probes the primary of WithFailover with a GET of path
+// every interval while requests go to a secondary, "/" every 10s by
+// default. The primary is healthy again once it answers without a transport
+// error, a failover status or a 5xx.
+func WithFailoverProbe(interval time.Duration, path string) Option {
+	return func(c *Client) {
+		c.failover.probeInterval, c.failover.probePath = interval, path
+	}
+}
+
+// FailoverEvent tells which base URL requests moved to
+type FailoverEvent struct {
+	From string
+	To   string
+	// Err made the client leave From, nil when it returns to the primary
+	Err error
+}
+
+// FailoverHook is a Hook that is also told when WithFailover switches base
+// URLs. OnFailover must not block.
+type FailoverHook interface {
+	Hook
+	OnFailover(e FailoverEvent)
+}
+
+type failoverConfig struct {
+	targets       []string
+	statuses      []int
+	probeInterval time.Duration
+	probePath     string
+	transport     *failoverTransport
+}
+
+// applyFailover wraps the transport below hedging, so every copy fails over
+func (c *Client) applyFailover() {
+	cfg := &c.failover
+	if len(cfg.targets) < 2 {
+		return
+	}
+	t := &failoverTransport{
+		statuses:      map[int]bool{},
+		probeInterval: cfg.probeInterval,
+		probePath:     cfg.probePath,
+		logger:        c.getLogger,
+		stop:          make(chan struct{}),
+	}
+	for _, target := range cfg.targets {
+		u, err := gourl.Parse(strings.TrimSuffix(target, "/"))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			c.getLogger().Error("invalid failover url, failover disabled", "url", target, "err", err)
+			return
+		}
+		t.targets = append(t.targets, u)
+	}
+	statuses := cfg.statuses
+	if statuses == nil {
+		statuses = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	for _, code := range statuses {
+		t.statuses[code] = true
+	}
+	if t.probeInterval <= 0 {
+		t.probeInterval = 10 * time.Second
+	}
+	if t.probePath == "" {
+		t.probePath = "/"
+	}
+	t.base = c.httpClient.Transport
+	if t.base == nil {
+		t.base = http.DefaultTransport
+	}
+	cfg.transport = t
+	httpClient := *c.httpClient
+	httpClient.Transport = t
+	c.httpClient = &httpClient
+}
+
+type failoverTransport struct {
+	base          http.RoundTripper
+	targets       []*gourl.URL
+	statuses      map[int]bool
+	probeInterval time.Duration
+	probePath     string
+	logger        func() Logger
+	stop          chan struct{}
+	stopOnce      sync.Once
+
+	mu      sync.Mutex
+	active  int
+	probing bool
+
+	failovers int64
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.underPrimary(req.URL) {
+		return t.base.RoundTrip(req)
+	}
+	t.mu.Lock()
+	active := t.active
+	t.mu.Unlock()
+	attempt := t.retarget(req, active)
+	for tries := 1; ; tries++ {
+		resp, err := t.base.RoundTrip(attempt)
+		failure := err
+		if err == nil && t.statuses[resp.StatusCode] {
+			failure = &StatusError{Code: resp.StatusCode}
+		}
+		if failure == nil || req.Context().Err() != nil {
+			return resp, err
+		}
+		active = t.fail(active, failure)
+		if tries >= len(t.targets) {
+			return resp, err
+		}
+		next, ok := replayable(t.retarget(req, active))
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+		attempt = next
+	}
+}
+
+// underPrimary reports whether u is the primary base URL or below it
+func (t *failoverTransport) underPrimary(u *gourl.URL) bool {
+	primary := t.targets[0]
+	if !strings.EqualFold(u.Scheme, primary.Scheme) || !strings.EqualFold(u.Host, primary.Host) {
+		return false
+	}
+	return u.Path == primary.Path || strings.HasPrefix(u.Path, primary.Path+"/")
+}
+
+// retarget returns req moved from the primary to the target at index
+func (t *failoverTransport) retarget(req *http.Request, index int) *http.Request {
+	if index == 0 {
+		return req
+	}
+	primary, target := t.targets[0], t.targets[index]
+	u := *req.URL
+	u.Scheme, u.Host = target.Scheme, target.Host
+	u.Path = target.Path + strings.TrimPrefix(req.URL.Path, primary.Path)
+	u.RawPath = ""
+	if req.URL.RawPath != "" {
+		u.RawPath = target.EscapedPath() + strings.TrimPrefix(req.URL.RawPath, primary.EscapedPath())
+	}
+	out := req.WithContext(req.Context())
+	out.URL, out.Host = &u, ""
+	return out
+}
+
+// fail moves requests off the target at index after err, unless another
+// request did already, and returns the target to try next
+func (t *failoverTransport) fail(index int, err error) int {
+	t.mu.Lock()
+	if t.active != index {
+		next := t.active
+		t.mu.Unlock()
+		return next
+	}
+	next := index + 1
+	if next >= len(t.targets) {
+		// the primary only comes back through the probe
+		next = 1
+	}
+	t.active = next
+	startProbe := !t.probing
+	t.probing = true
+	t.mu.Unlock()
+
+	if next != index {
+		t.notify(FailoverEvent{From: t.targets[index].String(), To: t.targets[next].String(), Err: err})
+	}
+	if startProbe {
+		go t.probe()
+	}
+	return next
+}
+
+// probe checks the primary every probeInterval until it is healthy
+func (t *failoverTransport) probe() {
+	ticker := time.NewTicker(t.probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+		if !t.healthy() {
+			continue
+		}
+		t.mu.Lock()
+		from := t.active
+		t.active, t.probing = 0, false
+		t.mu.Unlock()
+		t.notify(FailoverEvent{From: t.targets[from].String(), To: t.targets[0].String()})
+		return
+	}
+}
+
+func (t *failoverTransport) healthy() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), t.probeInterval)
+	defer cancel()
+	u := *t.targets[0]
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimPrefix(t.probePath, "/")
+	u.RawPath = ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode < 500 && !t.statuses[resp.StatusCode]
+}
+
+func (t *failoverTransport) notify(e FailoverEvent) {
+	atomic.AddInt64(&t.failovers, 1)
+	t.logger().Warn("http failover", "from", e.From, "to", e.To, "err", e.Err)
+	for _, hook := range hooks() {
+		if h, ok := hook.(FailoverHook); ok {
+			h.OnFailover(e)
+		}
+	}
+}
+
+// close stops probing the primary
+func (t *failoverTransport) close() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *failoverTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
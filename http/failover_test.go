@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failoverRecorder records the events of WithFailover
+type failoverRecorder struct {
+	mu     sync.Mutex
+	events []FailoverEvent
+}
+
+func (h *failoverRecorder) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *failoverRecorder) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *failoverRecorder) OnFailover(e FailoverEvent) {
+	h.mu.Lock()
+	h.events = append(h.events, e)
+	h.mu.Unlock()
+}
+
+func (h *failoverRecorder) get() []FailoverEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]FailoverEvent(nil), h.events...)
+}
+
+func TestWithFailover(t *testing.T) {
+	var down int32 = 1
+	var primaryHits int64
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&primaryHits, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("primary " + r.URL.RequestURI()))
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secondary " + r.URL.RequestURI()))
+	}))
+	defer secondary.Close()
+	rec := &failoverRecorder{}
+	handle := AddHook(rec)
+	defer handle.Remove()
+	client := NewClient(WithFailover(primary.URL+"/v1", secondary.URL+"/api/"), WithFailoverProbe(20*time.Millisecond, "/health"), WithLogger(NopLogger))
+	defer client.Close(context.Background())
+
+	resp, err := client.NewRequest(POST, primary.URL+"/v1/orders").Query("q", "1").Body(map[string]int{"n": 1}).Send()
+	if err != nil || resp.String() != "secondary /api/orders?q=1" {
+		t.Fatalf("Send() got = %q %v, want the secondary to answer", resp.Body, err)
+	}
+	hits := atomic.LoadInt64(&primaryHits)
+	resp, err = client.NewRequest(GET, primary.URL+"/v1/orders").Send()
+	if err != nil || resp.String() != "secondary /api/orders" {
+		t.Errorf("Send() got = %q %v, want to stay on the secondary", resp.Body, err)
+	}
+	if resp, err := client.NewRequest(GET, primary.URL+"/other").Send(); err == nil {
+		t.Errorf("Send() outside the base url got = %q, want the primary answering", resp.Body)
+	}
+
+	atomic.StoreInt32(&down, 0)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(rec.get()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if atomic.LoadInt64(&primaryHits) <= hits+1 {
+		t.Errorf("WithFailover() did not probe the primary")
+	}
+	resp, err = client.NewRequest(GET, primary.URL+"/v1/orders").Send()
+	if err != nil || resp.String() != "primary /v1/orders" {
+		t.Errorf("Send() got = %q %v, want the primary back", resp.Body, err)
+	}
+
+	events := rec.get()
+	if len(events) != 2 || events[0].From != primary.URL+"/v1" || events[0].To != secondary.URL+"/api" || events[0].Err == nil ||
+		events[1].To != primary.URL+"/v1" || events[1].Err != nil {
+		t.Errorf("OnFailover() got = %+v", events)
+	}
+	if got := client.Stats()["failovers_total"]; got != 2 {
+		t.Errorf("Stats() failovers_total got = %v, want 2", got)
+	}
+}
@@ -0,0 +1,224 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	gourl "net/url"
+	"strings"
+	"sync"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// hop-by-hop headers are meant for a single connection and not forwarded
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+type forwardConfig struct {
+	stripPrefix string
+	rewrite     func(out *http.Request)
+}
+
+type ForwardOption = opt.Option[forwardConfig]
+
+// WithStripPrefix removes prefix from the path of the incoming request
+// before it is joined to the target path
+func WithStripPrefix(prefix string) ForwardOption {
+	return func(c *forwardConfig) {
+		c.stripPrefix = prefix
+	}
+}
+
+// WithRewrite changes the outgoing request before it is sent, e.g. to add
+// credentials of the upstream or drop incoming headers
+func WithRewrite(rewrite func(out *http.Request)) ForwardOption {
+	return func(c *forwardConfig) {
+		c.rewrite = rewrite
+	}
+}
+
+// Forward forwards an incoming server request with the default Client
+func Forward(ctx context.Context, incoming *http.Request, target string, opts ...ForwardOption) (*http.Response, error) {
+	return defaultClient.Forward(ctx, incoming, target, opts...)
+}
+
+// Forward sends an incoming server request to target, a base url like
+// "http://users:8080/api" the incoming path and query are appended to. The
+// body is streamed, hop-by-hop headers are dropped and X-Forwarded-For,
+// X-Forwarded-Host and X-Forwarded-Proto are set. Redirects of the upstream
+// are returned rather than followed.
+//
+// The upstream response is returned whatever its status, write it back with
+// WriteResponse, which closes its body. The client hooks run with nil
+// response data since the body is not read.
+func (c *Client) Forward(ctx context.Context, incoming *http.Request, target string, opts ...ForwardOption) (*http.Response, error) {
+	var cfg forwardConfig
+	if err := opt.Build(&cfg, opts); err != nil {
+		return nil, err
+	}
+	out, err := forwardRequest(ctx, incoming, target, cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := *c.httpClient
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return c.doStream(ctx, &client, out)
+}
+
+// doStream sends req through the hooks like doOnce but returns the response
+// with its body unread, the hooks see nil response data and the timings up
+// to the response headers. The request stays in flight for Close until the
+// body is closed. The Timeout of client is not applied, it would cut the
+// body off while it is read, only ctx bounds the request.
+func (c *Client) doStream(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	if err := c.lifecycle.enter(); err != nil {
+		return nil, err
+	}
+	left := false
+	defer func() {
+		if !left {
+			c.lifecycle.leave()
+		}
+	}()
+	x, err := c.begin(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	x.stream = true
+	x.req = x.req.WithContext(withStream(x.req.Context()))
+	untimed := *client
+	untimed.Timeout = 0
+	resp, err := x.send(&untimed)
+	if err != nil {
+		err = classifyTransportError(err)
+		c.getLogger().Error("sending request failed", "url", c.redactText(x.req.URL.String()), "err", err)
+		_, _, _, err = x.finish(-1, nil, nil, err)
+		return nil, err
+	}
+	if _, _, _, err := x.finish(resp.StatusCode, resp.Header, nil, nil); err != nil {
+		_ = resp.Body.Close()
+		x.tracer.release()
+		return nil, err
+	}
+	resp.Body = &leaveOnClose{ReadCloser: c.limitBody(resp.Body), leave: func() {
+		x.tracer.release()
+		c.lifecycle.leave()
+	}}
+	left = true
+	return resp, nil
+}
+
+type leaveOnClose struct {
+	io.ReadCloser
+	once  sync.Once
+	leave func()
+}
+
+func (b *leaveOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.leave)
+	return err
+}
+
+// forwardRequest builds the outgoing request for incoming
+func forwardRequest(ctx context.Context, incoming *http.Request, target string, cfg forwardConfig) (*http.Request, error) {
+	base, err := gourl.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	u := *base
+	path := strings.TrimPrefix(incoming.URL.Path, cfg.stripPrefix)
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	u.Path = strings.TrimSuffix(base.Path, "/") + path
+	u.RawPath = ""
+	switch {
+	case base.RawQuery == "":
+		u.RawQuery = incoming.URL.RawQuery
+	case incoming.URL.RawQuery != "":
+		u.RawQuery = base.RawQuery + "&" + incoming.URL.RawQuery
+	}
+
+	body := incoming.Body
+	if incoming.ContentLength == 0 {
+		body = nil
+	}
+	out, err := http.NewRequestWithContext(ctx, incoming.Method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	out.ContentLength = incoming.ContentLength
+	out.Header = incoming.Header.Clone()
+	if out.Header == nil {
+		out.Header = make(http.Header)
+	}
+	for _, name := range strings.Split(out.Header.Get("Connection"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			out.Header.Del(name)
+		}
+	}
+	for _, name := range hopHeaders {
+		out.Header.Del(name)
+	}
+
+	if ip, _, err := net.SplitHostPort(incoming.RemoteAddr); err == nil {
+		if prior := out.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+			ip = strings.Join(prior, ", ") + ", " + ip
+		}
+		out.Header.Set("X-Forwarded-For", ip)
+	}
+	out.Header.Set("X-Forwarded-Host", incoming.Host)
+	proto := "http"
+	if incoming.TLS != nil {
+		proto = "https"
+	}
+	out.Header.Set("X-Forwarded-Proto", proto)
+	if cfg.rewrite != nil {
+		cfg.rewrite(out)
+	}
+	return out, nil
+}
+
+// WriteResponse writes an upstream response returned by Forward to w and
+// closes its body. Hop-by-hop headers are dropped and the body is flushed as
+// it arrives, so streamed responses like server-sent events pass through.
+func WriteResponse(w http.ResponseWriter, resp *http.Response) error {
+	defer resp.Body.Close()
+	header := w.Header()
+	for name, values := range resp.Header {
+		header[name] = append([]string(nil), values...)
+	}
+	for _, name := range strings.Split(resp.Header.Get("Connection"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			header.Del(name)
+		}
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+	w.WriteHeader(resp.StatusCode)
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return writeErr
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
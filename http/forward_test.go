@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForward(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Seen", r.Method+" "+r.URL.RequestURI()+" "+string(body))
+		w.Header().Set("X-Forwarded", r.Header.Get("X-Forwarded-For")+"|"+r.Header.Get("X-Forwarded-Host")+"|"+r.Header.Get("X-Forwarded-Proto"))
+		w.Header().Set("X-Hop", r.Header.Get("X-Hop")+r.Header.Get("Keep-Alive"))
+		w.Header().Set("X-Upstream-Key", r.Header.Get("X-Upstream-Key"))
+		if r.URL.Path == "/api/moved" {
+			http.Redirect(w, r, "/api/elsewhere", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+	defer upstream.Close()
+
+	client := NewClient()
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := client.Forward(r.Context(), r, upstream.URL+"/api?v=2", WithStripPrefix("/gw"), WithRewrite(func(out *http.Request) {
+			out.Header.Set("X-Upstream-Key", "k")
+		}))
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_ = WriteResponse(w, resp)
+	}))
+	defer gateway.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, gateway.URL+"/gw/users?id=7", strings.NewReader("payload"))
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	req.Header.Set("Connection", "X-Hop")
+	req.Header.Set("X-Hop", "dropped")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	host := strings.TrimPrefix(gateway.URL, "http://")
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"status", resp.Status, "201 Created"},
+		{"body", string(body), "created"},
+		{"request", resp.Header.Get("X-Seen"), "POST /api/users?v=2&id=7 payload"},
+		{"forwarded", resp.Header.Get("X-Forwarded"), "10.0.0.1, 127.0.0.1|" + host + "|http"},
+		{"hop-by-hop", resp.Header.Get("X-Hop"), ""},
+		{"rewrite", resp.Header.Get("X-Upstream-Key"), "k"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%v got = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = noRedirect.Get(gateway.URL + "/gw/moved")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/api/elsewhere" {
+		t.Errorf("redirect got = %v %v, want it passed through", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if client.InFlight() != 0 {
+		t.Errorf("InFlight() got = %v, want 0 after the bodies are closed", client.InFlight())
+	}
+}
+
+func TestForwardStreaming(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = w.Write([]byte("second\n"))
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	incoming := httptest.NewRequest(http.MethodGet, "/events", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := NewClient().Forward(ctx, incoming, upstream.URL)
+	if err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	defer resp.Body.Close()
+	buf := make([]byte, 6)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil || string(buf) != "first\n" {
+		t.Errorf("Forward() first chunk got = %q %v, want it before the stream ends", buf, err)
+	}
+}
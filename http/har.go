@@ -0,0 +1,398 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// defaultHARMaxBody is the default of WithHARMaxBody
+const defaultHARMaxBody = 1 << 20
+
+// WithHARRecorder records every exchange on the wire into a HAR 1.2 log
+// written to w, for browser devtools or to share with an API vendor: each
+// attempt of a retry, each copy of a hedged request, each redirect and the
+// streamed requests of Request.Stream and Forward get their own entry. An
+// entry is written once its response body is read or closed, so the entries
+// are not kept in memory; Close ends the log, which is incomplete JSON until
+// then. w is written under a lock, a slow w slows the requests. Headers, urls
+// and bodies are redacted like dumps, see WithRedactHeaders and WithRedactor.
+func WithHARRecorder(w io.Writer) Option {
+	return func(c *Client) {
+		c.har = &harRecorder{w: w}
+	}
+}
+
+// WithHARMaxBody cuts the bodies recorded by WithHARRecorder at n bytes, 1MiB
+// by default. 0 records no bodies.
+func WithHARMaxBody(n int) Option {
+	return func(c *Client) {
+		c.harMaxBody = n
+	}
+}
+
+// harRecorder writes the entries of the log as they complete
+type harRecorder struct {
+	w io.Writer
+
+	mu      sync.Mutex
+	entries int
+	closed  bool
+	// err is the first write error, nothing is written after it
+	err error
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// harTimings are in milliseconds, -1 for phases that didn't happen
+type harTimings struct {
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harExchange is one exchange on the wire
+type harExchange struct {
+	start   time.Time
+	tracer  *tracer
+	req     *http.Request
+	reqBody *harCapture
+	resp    *http.Response
+	body    *harCapture
+	err     error
+}
+
+// applyHAR wraps the transport below failover and hedging, so every attempt
+// is recorded
+func (c *Client) applyHAR() {
+	if c.har == nil {
+		return
+	}
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient := *c.httpClient
+	httpClient.Transport = &harTransport{base: base, client: c}
+	c.httpClient = &httpClient
+}
+
+type harTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.client
+	x := &harExchange{start: time.Now(), tracer: newTracer(nil)}
+	// the copy made for the trace can take the capturing body
+	req = x.tracer.withRequest(req)
+	if req.Body != nil && req.Body != http.NoBody {
+		x.reqBody = &harCapture{ReadCloser: req.Body, max: c.harMaxBody}
+		req.Body = x.reqBody
+	}
+	x.req = req
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		x.err = err
+		c.recordHAR(x)
+		return resp, err
+	}
+	x.resp = resp
+	x.body = &harCapture{ReadCloser: resp.Body, max: c.harMaxBody, done: func() { c.recordHAR(x) }}
+	resp.Body = x.body
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *harTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// harCapture keeps the first max bytes read from a body and calls done once
+// at its end or when it is closed
+type harCapture struct {
+	io.ReadCloser
+	max  int
+	done func()
+	once sync.Once
+
+	mu   sync.Mutex
+	data []byte
+	size int
+}
+
+func (b *harCapture) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	b.size += n
+	if keep := b.max - len(b.data); keep > 0 {
+		if keep > n {
+			keep = n
+		}
+		b.data = append(b.data, p[:keep]...)
+	}
+	b.mu.Unlock()
+	if err != nil {
+		b.end()
+	}
+	return n, err
+}
+
+func (b *harCapture) Close() error {
+	err := b.ReadCloser.Close()
+	b.end()
+	return err
+}
+
+func (b *harCapture) end() {
+	b.once.Do(func() {
+		if b.done != nil {
+			b.done()
+		}
+	})
+}
+
+// captured returns the bytes kept and the size read
+func (b *harCapture) captured() ([]byte, int) {
+	if b == nil {
+		return nil, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data, b.size
+}
+
+// recordHAR writes the entry of x to the log of WithHARRecorder
+func (c *Client) recordHAR(x *harExchange) {
+	t := x.tracer.done()
+	reqBody, reqSize := x.reqBody.captured()
+	if x.req.ContentLength > 0 {
+		reqSize = int(x.req.ContentLength)
+	}
+	body, size := x.body.captured()
+	code, proto, header := 0, "HTTP/1.1", http.Header{}
+	if x.resp != nil {
+		code, header = x.resp.StatusCode, x.resp.Header
+		if x.resp.Proto != "" {
+			proto = x.resp.Proto
+		}
+	}
+	reqHeader := c.redactHeader(x.req.Header)
+	e := harEntry{
+		StartedDateTime: x.start.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Time:            millis(t.Total),
+		Request: harRequest{
+			Method:      x.req.Method,
+			URL:         c.redactText(x.req.URL.String()),
+			HTTPVersion: proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(reqHeader),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    reqSize,
+		},
+		Response: harResponse{
+			Status:      code,
+			StatusText:  http.StatusText(code),
+			HTTPVersion: proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(c.redactHeader(header)),
+			RedirectURL: header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    size,
+		},
+		Timings: harTimings{DNS: -1, Connect: -1, SSL: -1},
+	}
+	for name, values := range x.req.URL.Query() {
+		for _, v := range values {
+			e.Request.QueryString = append(e.Request.QueryString, harNameValue{Name: name, Value: c.redactText(v)})
+		}
+	}
+	if reqSize > 0 {
+		text, _, _ := harBody(c.redactBody(reqBody), reqSize, c.harMaxBody)
+		e.Request.PostData = &harPostData{MimeType: reqHeader.Get("Content-Type"), Text: text}
+	}
+	e.Response.Content = harContent{Size: size, MimeType: header.Get("Content-Type")}
+	e.Response.Content.Text, e.Response.Content.Encoding, e.Response.Content.Comment = harBody(c.redactBody(body), size, c.harMaxBody)
+	if x.err != nil {
+		e.Comment = x.err.Error()
+	}
+
+	if t.DNSLookup > 0 {
+		e.Timings.DNS = millis(t.DNSLookup)
+	}
+	if t.Connect > 0 {
+		// HAR counts the handshake in the connect time as well
+		e.Timings.Connect = millis(t.Connect + t.TLSHandshake)
+	}
+	if t.TLSHandshake > 0 {
+		e.Timings.SSL = millis(t.TLSHandshake)
+	}
+	if t.TTFB > 0 {
+		// the connection setup is part of the time to the first byte
+		if wait := t.TTFB - t.DNSLookup - t.Connect - t.TLSHandshake; wait > 0 {
+			e.Timings.Wait = millis(wait)
+		}
+		e.Timings.Receive = millis(t.Total - t.TTFB)
+	} else {
+		e.Timings.Wait = millis(t.Total)
+	}
+	if host, _, err := net.SplitHostPort(t.RemoteAddr); err == nil {
+		e.ServerIPAddress = host
+		e.Connection = t.RemoteAddr
+	}
+	c.har.add(e)
+}
+
+// add writes e, starting the log with the first entry
+func (h *harRecorder) add(e harEntry) {
+	data, err := json.Marshal(e)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed || h.err != nil {
+		return
+	}
+	if err == nil && h.entries == 0 {
+		err = h.begin()
+	}
+	sep := ",\n    "
+	if h.entries == 0 {
+		sep = "\n    "
+	}
+	if err == nil {
+		_, err = io.WriteString(h.w, sep+string(data))
+	}
+	h.entries++
+	h.err = err
+}
+
+// begin writes the start of the log, h.mu must be held
+func (h *harRecorder) begin() error {
+	creator, err := json.Marshal(harCreator{Name: "gotool", Version: DefaultUserAgent})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(h.w, `{"log": {"version": "1.2", "creator": `+string(creator)+`, "pages": [], "entries": [`)
+	return err
+}
+
+// write ends the log once, later entries are dropped
+func (h *harRecorder) write() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return h.err
+	}
+	h.closed = true
+	if h.err == nil && h.entries == 0 {
+		h.err = h.begin()
+	}
+	if h.err == nil {
+		_, h.err = io.WriteString(h.w, "\n]}}\n")
+	}
+	return h.err
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]harNameValue, 0, len(header))
+	for _, name := range names {
+		for _, v := range header[name] {
+			out = append(out, harNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+// harBody returns the first bytes of a body of size bytes, cut at maxBody, as
+// text, or base64 for binary bodies, with a comment when it was cut
+func harBody(body []byte, size int, maxBody int) (text string, encoding string, comment string) {
+	if len(body) > maxBody {
+		body = body[:maxBody]
+	}
+	if size > len(body) {
+		comment = "cut at " + strconv.Itoa(len(body)) + " bytes"
+	}
+	if utf8.Valid(body) {
+		return string(body), "", comment
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64", comment
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
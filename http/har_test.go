@@ -0,0 +1,181 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// harLog is the document written by WithHARRecorder
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Pages   []struct{} `json:"pages"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+func TestWithHARRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/binary":
+			_, _ = w.Write([]byte{0xff, 0x00, 0xfe})
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("no such thing"))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":1,"name":"a long enough name"}`))
+		}
+	}))
+	defer server.Close()
+	var out bytes.Buffer
+	client := NewClient(WithHARRecorder(&out), WithHARMaxBody(20), WithLogger(NopLogger))
+
+	if _, err := client.NewRequest(POST, server.URL+"/users").Query("dry", "1").Header("Authorization", "Bearer secret").Body(map[string]int{"n": 1}).Send(); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	_, _ = client.NewRequest(GET, server.URL+"/binary").Send()
+	_, _ = client.NewRequest(GET, server.URL+"/missing").Send()
+	// entries are written as they complete, not kept until Close
+	if n := strings.Count(out.String(), `"startedDateTime"`); n != 3 {
+		t.Errorf("WithHARRecorder() wrote %d entries before Close, want 3", n)
+	}
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var doc harLog
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("HAR %q: %v", out.String(), err)
+	}
+	if doc.Log.Version != "1.2" || len(doc.Log.Entries) != 3 {
+		t.Fatalf("HAR log got version %v with %v entries, want 1.2 with 3", doc.Log.Version, len(doc.Log.Entries))
+	}
+	post := doc.Log.Entries[0]
+	if post.Request.Method != "POST" || post.Request.PostData == nil || post.Request.PostData.Text != `{"n":1}` ||
+		len(post.Request.QueryString) != 1 || post.Request.QueryString[0] != (harNameValue{"dry", "1"}) {
+		t.Errorf("HAR request got = %+v", post.Request)
+	}
+	for _, h := range post.Request.Headers {
+		if h.Name == "Authorization" && h.Value != redacted {
+			t.Errorf("HAR request header Authorization got = %q, want it redacted", h.Value)
+		}
+	}
+	content := post.Response.Content
+	if post.Response.Status != 200 || content.Size != 36 || content.Text != `{"id":1,"name":"a lo` || content.Comment == "" || content.MimeType != "application/json" {
+		t.Errorf("HAR response got = %+v, want the body cut at 20 bytes", post.Response)
+	}
+	if post.Time <= 0 || post.Timings.Wait < 0 || post.ServerIPAddress != "127.0.0.1" || !strings.HasPrefix(post.StartedDateTime, "20") {
+		t.Errorf("HAR timings got = %v %+v %v %v", post.Time, post.Timings, post.ServerIPAddress, post.StartedDateTime)
+	}
+	if binary := doc.Log.Entries[1].Response.Content; binary.Encoding != "base64" || binary.Text != "/wD+" {
+		t.Errorf("HAR binary body got = %+v, want base64", binary)
+	}
+	if missing := doc.Log.Entries[2].Response; missing.Status != 404 || missing.Content.Text != "no such thing" {
+		t.Errorf("HAR 404 got = %+v", missing)
+	}
+}
+
+func TestWithHARRecorderHedged(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&calls, 1) == 1 {
+			// the first copy is slow, the hedge wins
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("hedged"))
+	}))
+	defer server.Close()
+	var out bytes.Buffer
+	client := NewClient(WithHARRecorder(&out), WithHedging(20*time.Millisecond, 1), WithLogger(NopLogger))
+
+	resp, err := client.NewRequest(GET, server.URL).Send()
+	if err != nil || resp.String() != "hedged" {
+		t.Fatalf("Send() got = %v %v, want hedged", resp, err)
+	}
+	// the canceled copy is recorded once its request returns
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		client.har.mu.Lock()
+		n := client.har.entries
+		client.har.mu.Unlock()
+		if n == 2 {
+			break
+		}
+	}
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	var doc harLog
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("HAR %q: %v", out.String(), err)
+	}
+	if len(doc.Log.Entries) != 2 {
+		t.Fatalf("HAR entries got = %d, want the request and its hedge", len(doc.Log.Entries))
+	}
+	var won, canceled int
+	for _, e := range doc.Log.Entries {
+		switch {
+		case e.Response.Status == 200 && e.Response.Content.Text == "hedged":
+			won++
+		case e.Comment != "" || e.Response.Status == 0 || e.Response.Status == 200:
+			canceled++
+		}
+	}
+	if won != 1 || canceled != 1 {
+		t.Errorf("HAR entries got = %+v, want one winner and one canceled copy", doc.Log.Entries)
+	}
+}
+
+func TestWithHARRecorderStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("streamed body"))
+	}))
+	defer server.Close()
+	var out bytes.Buffer
+	client := NewClient(WithHARRecorder(&out), WithLogger(NopLogger))
+
+	resp, err := client.NewRequest(GET, server.URL+"/download").Stream()
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	var doc harLog
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("HAR %q: %v", out.String(), err)
+	}
+	if len(doc.Log.Entries) != 1 {
+		t.Fatalf("HAR entries got = %d, want 1", len(doc.Log.Entries))
+	}
+	if e := doc.Log.Entries[0]; !strings.HasSuffix(e.Request.URL, "/download") || e.Response.Content.Text != "streamed body" {
+		t.Errorf("HAR entry got = %+v", e)
+	}
+}
+
+func TestWithHARRecorderEmpty(t *testing.T) {
+	var out bytes.Buffer
+	client := NewClient(WithHARRecorder(&out))
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	var doc harLog
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil || doc.Log.Entries == nil || len(doc.Log.Entries) != 0 {
+		t.Errorf("HAR got = %q %v, want an empty log", out.String(), err)
+	}
+}
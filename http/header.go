@@ -0,0 +1,170 @@
+package http
+
+import (
+	"encoding/base64"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/Stellar1999/gotool/idgen"
+)
+
+const modulePath = "github.com/Stellar1999/gotool"
+
+// DefaultUserAgent is sent by clients without WithUserAgent, it names the
+// module version to help debugging on the server side, e.g.
+// "gotool/v1.4.0 (go1.21.0; linux/amd64)"
+var DefaultUserAgent = "gotool/" + moduleVersion() + " (" + runtime.Version() + "; " + runtime.GOOS + "/" + runtime.GOARCH + ")"
+
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
+
+// Headers builds request headers, keeping every value of repeated names unlike
+// the map[string]string taken by the package functions. Use it with
+// Request.Headers or as client defaults with WithHeaders:
+//
+//	NewHeaders().BearerToken(token).Accept("application/json").Add("X-Tag", "a").Add("X-Tag", "b")
+type Headers http.Header
+
+// NewHeaders returns empty Headers
+func NewHeaders() Headers {
+	return make(Headers)
+}
+
+// HeadersFromMap converts the headers of the package functions
+func HeadersFromMap(header map[string]string) Headers {
+	return Headers(mapHeader2netHeader(header))
+}
+
+// Set replaces the values of name
+func (h Headers) Set(name string, value string) Headers {
+	http.Header(h).Set(name, value)
+	return h
+}
+
+// Add appends a value to name
+func (h Headers) Add(name string, value string) Headers {
+	http.Header(h).Add(name, value)
+	return h
+}
+
+// Del removes name
+func (h Headers) Del(name string) Headers {
+	http.Header(h).Del(name)
+	return h
+}
+
+// BearerToken sets the Authorization header to "Bearer token"
+func (h Headers) BearerToken(token string) Headers {
+	return h.Set("Authorization", "Bearer "+token)
+}
+
+// BasicAuth sets the Authorization header for HTTP basic authentication
+func (h Headers) BasicAuth(username string, password string) Headers {
+	return h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+}
+
+// ContentType sets the Content-Type header, it wins over the codec content type
+func (h Headers) ContentType(contentType string) Headers {
+	return h.Set("Content-Type", contentType)
+}
+
+// Accept sets the Accept header, several types are joined as a list
+func (h Headers) Accept(mediaTypes ...string) Headers {
+	h.Del("Accept")
+	for _, mediaType := range mediaTypes {
+		h.Add("Accept", mediaType)
+	}
+	return h
+}
+
+// UserAgent sets the User-Agent header
+func (h Headers) UserAgent(userAgent string) Headers {
+	return h.Set("User-Agent", userAgent)
+}
+
+// IdempotencyKey sets the Idempotency-Key header to key, or to a new random
+// UUID when key is empty
+func (h Headers) IdempotencyKey(key string) Headers {
+	if key == "" {
+		key = idgen.UUIDv4()
+	}
+	return h.Set("Idempotency-Key", key)
+}
+
+// Header returns a copy as http.Header
+func (h Headers) Header() http.Header {
+	return http.Header(h).Clone()
+}
+
+// WithHeaders sets default headers sent with every request of the client,
+// including those of the package style methods. Request headers win: a
+// default is only added when the request has no value for its name.
+func WithHeaders(h Headers) Option {
+	return func(c *Client) {
+		for name, values := range h {
+			c.headers[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+}
+
+// WithUserAgent replaces DefaultUserAgent, an empty ua sends the User-Agent
+// of net/http
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		if ua == "" {
+			c.headers.Del("User-Agent")
+			return
+		}
+		c.headers.Set("User-Agent", ua)
+	}
+}
+
+// SetDefaultHeader sets a default header of the package functions, like
+// WithHeaders does for a Client. Call it at start up, an empty value removes
+// the default.
+func SetDefaultHeader(name string, value string) {
+	if value == "" {
+		defaultClient.headers.Del(name)
+		return
+	}
+	defaultClient.headers.Set(name, value)
+}
+
+// withDefaultHeaders returns req with the client defaults merged under its
+// own headers, req itself is not changed
+func (c *Client) withDefaultHeaders(req *http.Request) *http.Request {
+	if len(c.headers) == 0 {
+		return req
+	}
+	// hooks like signers see and may change the merged headers
+	merged := req.Header.Clone()
+	if merged == nil {
+		merged = make(http.Header)
+	}
+	for name, values := range c.headers {
+		if len(merged.Values(name)) == 0 {
+			merged[name] = append([]string(nil), values...)
+		}
+	}
+	out := new(http.Request)
+	*out = *req
+	out.Header = merged
+	return out
+}
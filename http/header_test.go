@@ -0,0 +1,117 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHeaders(t *testing.T) {
+	h := NewHeaders().
+		BasicAuth("ann", "pw").
+		ContentType("text/plain").
+		Accept("application/json", "text/*").
+		UserAgent("tool/1").
+		Add("X-Tag", "a").
+		Add("x-tag", "b")
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Authorization", []string{"Basic YW5uOnB3"}},
+		{"Content-Type", []string{"text/plain"}},
+		{"Accept", []string{"application/json", "text/*"}},
+		{"User-Agent", []string{"tool/1"}},
+		{"X-Tag", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := h.Header().Values(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Header() %v got = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := h.BearerToken("t").Header().Get("Authorization"); got != "Bearer t" {
+		t.Errorf("BearerToken() got = %v", got)
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first := NewHeaders().IdempotencyKey("").Header().Get("Idempotency-Key")
+	second := NewHeaders().IdempotencyKey("").Header().Get("Idempotency-Key")
+	if !uuid.MatchString(first) || first == second {
+		t.Errorf("IdempotencyKey() got = %v %v, want distinct UUIDs", first, second)
+	}
+	if got := NewHeaders().IdempotencyKey("order-1").Header().Get("Idempotency-Key"); got != "order-1" {
+		t.Errorf("IdempotencyKey() got = %v, want order-1", got)
+	}
+}
+
+func TestWithHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := NewClient(WithHeaders(NewHeaders().Set("X-Env", "prod").Add("X-Tag", "default").Accept("application/json")))
+	_, err := client.NewRequest(GET, server.URL).Headers(NewHeaders().Add("X-Tag", "a").Add("X-Tag", "b")).Send()
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got.Get("X-Env") != "prod" || got.Get("Accept") != "application/json" || !reflect.DeepEqual(got.Values("X-Tag"), []string{"a", "b"}) {
+		t.Errorf("request header got = %v, want defaults under request values", got)
+	}
+
+	if _, _, _, err := client.Get(server.URL, map[string]string{"X-Env": "dev"}, nil); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Get("X-Env") != "dev" || got.Get("X-Tag") != "default" {
+		t.Errorf("Get() header got = %v", got)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		client *Client
+		header map[string]string
+		want   string
+	}{
+		{"default", NewClient(), nil, DefaultUserAgent},
+		{"client", NewClient(WithUserAgent("billing/2.0")), nil, "billing/2.0"},
+		{"request", NewClient(WithUserAgent("billing/2.0")), map[string]string{"User-Agent": "cli"}, "cli"},
+		{"net/http", NewClient(WithUserAgent("")), nil, "Go-http-client/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := tt.client.Get(server.URL, tt.header, nil); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("User-Agent got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if !strings.HasPrefix(DefaultUserAgent, "gotool/") || !strings.Contains(DefaultUserAgent, runtime.GOOS) {
+		t.Errorf("DefaultUserAgent got = %v", DefaultUserAgent)
+	}
+
+	SetDefaultHeader("X-Service", "billing")
+	defer SetDefaultHeader("X-Service", "")
+	var service string
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		service = r.Header.Get("X-Service")
+	}))
+	defer echo.Close()
+	if _, _, _, err := Get(echo.URL, nil, nil); err != nil || service != "billing" {
+		t.Errorf("Get() X-Service got = %v %v, want billing", service, err)
+	}
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithHedging sends up to maxExtra copies of a request when no response came
+// within delay, one more after every delay. The first response wins and the
+// other requests are canceled. Only idempotent requests are hedged and only
+// when their body can be sent again. Within a WithBudget, the delay shrinks
+// so all copies are sent in time. The ctx passed to Hook.After carries a
+// HedgeInfo, Stats counts hedges and the requests they won.
+func WithHedging(delay time.Duration, maxExtra int) Option {
+	return func(c *Client) {
+		c.hedging = hedging{delay: delay, maxExtra: maxExtra}
+	}
+}
+
+type hedging struct {
+	delay    time.Duration
+	maxExtra int
+}
+
+// HedgeInfo tells how a request was hedged
+type HedgeInfo struct {
+	// Extra is the number of copies sent in addition to the request
+	Extra int
+	// Won is true when a copy answered first
+	Won bool
+}
+
+type hedgeInfoKey struct{}
+
+// HedgeInfoFromContext returns how the request the context belongs to was
+// hedged. It is available in the ctx passed to Hook.After with WithHedging.
+func HedgeInfoFromContext(ctx context.Context) (HedgeInfo, bool) {
+	info, ok := ctx.Value(hedgeInfoKey{}).(*HedgeInfo)
+	if !ok {
+		return HedgeInfo{}, false
+	}
+	return *info, true
+}
+
+// applyHedging wraps the transport, after the protocols so hedges use them too
+func (c *Client) applyHedging() {
+	if c.hedging.delay <= 0 || c.hedging.maxExtra <= 0 {
+		return
+	}
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient := *c.httpClient
+	httpClient.Transport = &hedgeTransport{base: base, hedging: c.hedging, stats: &c.stats}
+	c.httpClient = &httpClient
+}
+
+type hedgeTransport struct {
+	base    http.RoundTripper
+	hedging hedging
+	stats   *clientStats
+}
+
+type hedgeResult struct {
+	resp    *http.Response
+	err     error
+	attempt int
+}
+
+func (t *hedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req.Method) {
+		return t.base.RoundTrip(req)
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return t.base.RoundTrip(req)
+	}
+	info, _ := req.Context().Value(hedgeInfoKey{}).(*HedgeInfo)
+	results := make(chan hedgeResult, t.hedging.maxExtra+1)
+	var cancels []context.CancelFunc
+	launch := func(r *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		attempt := len(cancels) - 1
+		go func() {
+			resp, err := t.base.RoundTrip(r.WithContext(ctx))
+			results <- hedgeResult{resp: resp, err: err, attempt: attempt}
+		}()
+	}
+	launch(req)
+	pending := 1
+	delay := budgetHedgeDelay(req.Context(), t.hedging.delay, t.hedging.maxExtra)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var firstErr error
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				for i, cancel := range cancels {
+					if i != r.attempt {
+						cancel()
+					}
+				}
+				go drainHedges(results, pending)
+				if r.attempt > 0 {
+					atomic.AddInt64(&t.stats.hedgeWins, 1)
+					if info != nil {
+						info.Won = true
+					}
+				}
+				// the context of the winner lives as long as its body
+				r.resp.Body = &cancelOnClose{ReadCloser: r.resp.Body, cancel: cancels[r.attempt]}
+				return r.resp, nil
+			}
+			cancels[r.attempt]()
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if pending == 0 {
+				return nil, firstErr
+			}
+		case <-timer.C:
+			if len(cancels) > t.hedging.maxExtra {
+				continue
+			}
+			hedge, ok := replayable(req)
+			if !ok {
+				continue
+			}
+			launch(hedge)
+			pending++
+			atomic.AddInt64(&t.stats.hedges, 1)
+			if info != nil {
+				info.Extra++
+			}
+			timer.Reset(delay)
+		}
+	}
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *hedgeTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// drainHedges closes the responses of the requests that lost
+func drainHedges(results chan hedgeResult, pending int) {
+	for ; pending > 0; pending-- {
+		if r := <-results; r.resp != nil {
+			_ = r.resp.Body.Close()
+		}
+	}
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.cancel)
+	return err
+}
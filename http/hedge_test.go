@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithHedging(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first request hangs until it is canceled
+		if atomic.AddInt64(&requests, 1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte("fast"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithHedging(20*time.Millisecond, 2), WithLogger(NopLogger))
+	start := time.Now()
+	resp, err := client.NewRequest(GET, server.URL).Send()
+	if err != nil || resp.String() != "fast" {
+		t.Fatalf("Send() got = %v %v, want the hedged response", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send() took %v, want the hedge to answer", elapsed)
+	}
+	stats := client.Stats()
+	if stats["hedges_total"] != 1 || stats["hedge_wins_total"] != 1 {
+		t.Errorf("Stats() got = %v, want 1 hedge that won", stats)
+	}
+
+	// POST is not idempotent and waits for its only request
+	atomic.StoreInt64(&requests, 1)
+	if _, err := client.NewRequest(POST, server.URL).RawBody([]byte("x")).Send(); err != nil {
+		t.Fatalf("Send() POST error = %v", err)
+	}
+	if got := atomic.LoadInt64(&requests); got != 2 {
+		t.Errorf("POST requests got = %v, want 2", got)
+	}
+}
+
+func TestHedgingFastResponse(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithHedging(time.Second, 1), WithLogger(NopLogger))
+	for i := 0; i < 3; i++ {
+		if _, err := client.NewRequest(GET, server.URL).Send(); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if got := atomic.LoadInt64(&requests); got != 3 || client.Stats()["hedges_total"] != 0 {
+		t.Errorf("requests got = %v, want 3 without hedges", got)
+	}
+}
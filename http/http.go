@@ -2,15 +2,13 @@ package http
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	gourl "net/url"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -30,18 +28,71 @@ const (
 	DELETE RequestMethodType = "DELETE"
 )
 
-var httpClient = createHTTPClient()
-
+// Hook runs around the requests of all clients, see AddHook. After runs for
+// every hook whose Before succeeded, also when a later hook fails in Before
+// or After, so it can always close what Before opened.
 type Hook interface {
 	Before(ctx context.Context, req *http.Request) (context.Context, error)
 	After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error)
 }
 
-// global hook
-var globalHttpHook []Hook
+// hookEntry is one registration, the same Hook can be added more than once
+type hookEntry struct {
+	hook Hook
+}
+
+// globalHooks is replaced on every change, senders iterate a snapshot
+var globalHooks struct {
+	mu      sync.RWMutex
+	entries []*hookEntry
+}
+
+// HookHandle removes the hook it was returned for
+type HookHandle struct {
+	entry *hookEntry
+}
+
+// AddHook registers a hook for the requests of all clients, it runs after
+// the hooks added before. It is safe for concurrent use.
+func AddHook(httpHook Hook) *HookHandle {
+	entry := &hookEntry{hook: httpHook}
+	globalHooks.mu.Lock()
+	defer globalHooks.mu.Unlock()
+	entries := globalHooks.entries
+	globalHooks.entries = append(entries[:len(entries):len(entries)], entry)
+	return &HookHandle{entry: entry}
+}
+
+// Remove unregisters the hook, requests already sent still run it. Removing
+// twice does nothing.
+func (h *HookHandle) Remove() {
+	globalHooks.mu.Lock()
+	defer globalHooks.mu.Unlock()
+	for i, entry := range globalHooks.entries {
+		if entry == h.entry {
+			globalHooks.entries = append(append([]*hookEntry{}, globalHooks.entries[:i]...), globalHooks.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// ClearHooks removes all hooks, e.g. between tests
+func ClearHooks() {
+	globalHooks.mu.Lock()
+	defer globalHooks.mu.Unlock()
+	globalHooks.entries = nil
+}
 
-func AddHook(httpHook Hook) {
-	globalHttpHook = append(globalHttpHook, httpHook)
+// hooks returns the registered hooks in order
+func hooks() []Hook {
+	globalHooks.mu.RLock()
+	entries := globalHooks.entries
+	globalHooks.mu.RUnlock()
+	list := make([]Hook, len(entries))
+	for i, entry := range entries {
+		list[i] = entry.hook
+	}
+	return list
 }
 
 // createHTTPClient for connection re-use
@@ -65,7 +116,7 @@ func createHTTPClient() *http.Client {
 
 // SetHTTPClient this method use to init http client
 func SetHTTPClient(client *http.Client) {
-	httpClient = client
+	defaultClient.httpClient = client
 }
 
 func Get(url string, header map[string]string, parameter map[string]string) (int, http.Header, any, error) {
@@ -73,8 +124,7 @@ func Get(url string, header map[string]string, parameter map[string]string) (int
 }
 
 func GetWithContext(ctx context.Context, url string, header map[string]string, parameter map[string]string) (int, http.Header, any, error) {
-	// resolve url
-	return send(ctx, GET, url, header, parameter, nil)
+	return defaultClient.GetWithContext(ctx, url, header, parameter)
 }
 
 func Post(url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
@@ -82,7 +132,7 @@ func Post(url string, header map[string]string, parameter map[string]string, bod
 }
 
 func PostWithContext(ctx context.Context, url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
-	return send(ctx, POST, url, header, parameter, body)
+	return defaultClient.PostWithContext(ctx, url, header, parameter, body)
 }
 
 func Patch(url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
@@ -90,7 +140,7 @@ func Patch(url string, header map[string]string, parameter map[string]string, bo
 }
 
 func PatchWithContext(ctx context.Context, url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
-	return send(ctx, PATCH, url, header, parameter, body)
+	return defaultClient.PatchWithContext(ctx, url, header, parameter, body)
 }
 
 func Put(url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
@@ -98,7 +148,7 @@ func Put(url string, header map[string]string, parameter map[string]string, body
 }
 
 func PutWithContext(ctx context.Context, url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
-	return send(ctx, PUT, url, header, parameter, body)
+	return defaultClient.PutWithContext(ctx, url, header, parameter, body)
 }
 
 func Delete(url string, header map[string]string, parameter map[string]string) (int, http.Header, any, error) {
@@ -106,10 +156,10 @@ func Delete(url string, header map[string]string, parameter map[string]string) (
 }
 
 func DeleteWithContext(ctx context.Context, url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
-	return send(ctx, DELETE, url, header, parameter, body)
+	return defaultClient.DeleteWithContext(ctx, url, header, parameter, body)
 }
 
-func send(ctx context.Context, method RequestMethodType, url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
+func (c *Client) send(ctx context.Context, method RequestMethodType, url string, header map[string]string, parameter map[string]string, body any) (int, http.Header, any, error) {
 	// resolve url
 	url, err := resolveUrlWithParameter(url, parameter)
 	if err != nil {
@@ -117,42 +167,168 @@ func send(ctx context.Context, method RequestMethodType, url string, header map[
 	}
 	var httpRequest *http.Request
 	if method == POST || method == PUT || method == PATCH {
-		bytes, _ := json.Marshal(body)
+		bytes, _ := c.getCodec().Marshal(body)
 		payload := strings.NewReader(string(bytes))
-		httpRequest, err = http.NewRequest(string(method), url, payload)
+		httpRequest, err = http.NewRequestWithContext(ctx, string(method), url, payload)
 	} else {
-		httpRequest, err = http.NewRequest(string(method), url, nil)
+		httpRequest, err = http.NewRequestWithContext(ctx, string(method), url, nil)
 	}
 	if err != nil {
-		log.Printf("NewRequest error(%v)\n", err)
+		c.getLogger().Error("new request failed", "method", method, "url", url, "err", err)
 		return -1, nil, nil, err
 	}
 
 	if header != nil {
 		httpRequest.Header = mapHeader2netHeader(header)
 	}
-	return do(ctx, httpRequest)
+	if c.codec != nil && httpRequest.Body != nil && httpRequest.Header.Get("Content-Type") == "" {
+		httpRequest.Header.Set("Content-Type", c.codec.ContentType())
+	}
+	return c.do(ctx, httpRequest)
+}
+
+func (c *Client) do(ctx context.Context, httpRequest *http.Request) (int, http.Header, any, error) {
+	if c.retryOpts != nil {
+		return c.doRetry(ctx, httpRequest)
+	}
+	return c.doOnce(ctx, httpRequest)
+}
+
+func (c *Client) doOnce(ctx context.Context, httpRequest *http.Request) (int, http.Header, any, error) {
+	if err := c.lifecycle.enter(); err != nil {
+		return -1, nil, nil, err
+	}
+	defer c.lifecycle.leave()
+	x, err := c.begin(ctx, httpRequest)
+	if err != nil {
+		return -1, nil, nil, err
+	}
+	client := c.httpClient
+	if ctx.Value(untimedKey{}) != nil {
+		untimed := *client
+		untimed.Timeout = 0
+		client = &untimed
+	}
+	resp, err := x.send(client)
+	// transport errors go through the After hooks as well, so hooks can close what Before opened
+	rspCode, rspHead, rspData, err := c.doParseResponse(resp, err)
+	return x.finish(rspCode, rspHead, rspData, err)
 }
 
-func do(ctx context.Context, httpRequest *http.Request) (int, http.Header, any, error) {
-	for _, hook := range globalHttpHook {
+type untimedKey struct{}
+
+// withoutTimeout marks the ctx of a request whose body takes as long as it
+// takes, like an upload, the Timeout of the http.Client does not apply to it
+func withoutTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, untimedKey{}, true)
+}
+
+// exchange is a request on its way through the client, the steps doOnce and
+// doStream share
+type exchange struct {
+	c   *Client
+	ctx context.Context
+	req *http.Request
+	// hooks are the hooks run Before, the same run After even when one is
+	// removed meanwhile
+	hooks  []Hook
+	tracer *tracer
+	hedge  *HedgeInfo
+	// stream keeps the connection counted active until the body is closed
+	stream bool
+}
+
+// begin adds the default and budget headers to req and runs the Before
+// hooks. The request runs with the ctx of the hooks, e.g. carrying their
+// span.
+func (c *Client) begin(ctx context.Context, httpRequest *http.Request) (*exchange, error) {
+	if err := c.checkHost(httpRequest.URL); err != nil {
+		return nil, err
+	}
+	httpRequest = c.withBudgetHeader(ctx, c.withDefaultHeaders(httpRequest))
+	x := &exchange{c: c, hooks: hooks()}
+	for i, hook := range x.hooks {
 		_ctx, err := hook.Before(ctx, httpRequest)
-		ctx = _ctx
 		if err != nil {
-			return -1, nil, nil, err
+			// the hooks before it may have opened something, e.g. a span
+			_, _, _, _ = c.afterHooks(ctx, x.hooks[:i], -1, nil, nil, err)
+			return nil, err
 		}
+		ctx = _ctx
 	}
-	resp, err := httpClient.Do(httpRequest)
+	x.ctx, x.req = ctx, httpRequest.WithContext(ctx)
+	if err := ctx.Err(); err != nil {
+		_, _, _, err = c.afterHooks(ctx, x.hooks, -1, nil, nil, contextError(err))
+		return nil, err
+	}
+	if c.dump {
+		c.dumpRequest(x.req)
+	}
+	c.stats.begin()
+	if c.hedging.maxExtra > 0 {
+		x.hedge = &HedgeInfo{}
+		x.req = x.req.WithContext(context.WithValue(ctx, hedgeInfoKey{}, x.hedge))
+	}
+	x.tracer = newTracer(&c.pool)
+	return x, nil
+}
+
+// send sends the request with client. On error the response, if any, is
+// closed, errors of a canceled or timed out ctx become context errors.
+func (x *exchange) send(client *http.Client) (*http.Response, error) {
+	resp, err := client.Do(x.tracer.withRequest(x.req))
 	if err != nil {
-		return -1, nil, nil, err
+		// a failing CheckRedirect returns the redirect response along with the error
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+		if x.ctx.Err() != nil {
+			// canceled or timed out by the caller rather than by the transport
+			return nil, contextError(x.ctx.Err())
+		}
+		return nil, err
 	}
-	rspCode, rspHead, rspData, err := doParseResponse(resp, err)
-	for _, hook := range globalHttpHook {
-		_ctx, err := hook.After(ctx, rspCode, rspHead, rspData, err)
-		ctx = _ctx
-		if err != nil {
-			return -1, nil, nil, err
+	meter(x.ctx, resp)
+	return resp, nil
+}
+
+// finish counts, dumps and traces the outcome and runs the After hooks with
+// it
+func (x *exchange) finish(rspCode int, rspHead http.Header, rspData any, err error) (int, http.Header, any, error) {
+	c := x.c
+	c.stats.end(err)
+	if c.dump {
+		c.dumpResponse(x.req, rspCode, rspHead, rspData, err)
+	}
+	var info TraceInfo
+	if x.stream && err == nil {
+		info = x.tracer.stop()
+	} else {
+		info = x.tracer.done()
+	}
+	ctx := contextWithTraceInfo(x.ctx, info)
+	if x.hedge != nil {
+		ctx = context.WithValue(ctx, hedgeInfoKey{}, x.hedge)
+	}
+	return c.afterHooks(ctx, x.hooks, rspCode, rspHead, rspData, err)
+}
+
+// afterHooks runs After for all of hookList, even when one fails, and returns
+// the first error of a hook instead of the outcome
+func (c *Client) afterHooks(ctx context.Context, hookList []Hook, rspCode int, rspHead http.Header, rspData any, err error) (int, http.Header, any, error) {
+	var firstErr error
+	for _, hook := range hookList {
+		_ctx, hookErr := hook.After(ctx, rspCode, rspHead, rspData, err)
+		if hookErr != nil {
+			if firstErr == nil {
+				firstErr = hookErr
+			}
+			continue
 		}
+		ctx = _ctx
+	}
+	if firstErr != nil {
+		return -1, nil, nil, firstErr
 	}
 	return rspCode, rspHead, rspData, err
 }
@@ -170,33 +346,49 @@ func resolveUrlWithParameter(urlString string, parameters map[string]string) (st
 	return url.String(), err
 }
 
-func doParseResponse(httpResponse *http.Response, err error) (int, http.Header, any, error) {
-	if err != nil && httpResponse == nil {
-		log.Printf("Error sending request to API endpoint. %+v", err)
-		return -1, nil, nil, err
+func (c *Client) doParseResponse(httpResponse *http.Response, err error) (int, http.Header, any, error) {
+	if err != nil {
+		// a failing CheckRedirect returns the redirect response along with the error
+		if httpResponse != nil {
+			_ = httpResponse.Body.Close()
+		}
+		c.getLogger().Error("sending request failed", "err", err)
+		return -1, nil, nil, classifyTransportError(err)
 	} else {
 		if httpResponse == nil {
-			log.Printf("httpResponse is nil\n")
+			c.getLogger().Warn("http response is nil")
 			return -1, nil, nil, nil
 		}
 		defer func(Body io.ReadCloser) {
 			err := Body.Close()
 			if err != nil {
-				log.Printf("Body close error(%v)", err)
+				c.getLogger().Warn("closing response body failed", "err", err)
 			}
 		}(httpResponse.Body)
 
 		code := httpResponse.StatusCode
 		headers := httpResponse.Header
 		if code != http.StatusOK {
-			body, _ := io.ReadAll(httpResponse.Body)
-			return code, headers, nil, errors.New("remote error, url: code " + strconv.Itoa(code) + ", response body: " + string(body))
+			// the error body is cut at the limit rather than failing
+			body, _ := io.ReadAll(c.limitBody(httpResponse.Body))
+			statusErr := &StatusError{Code: code, Body: body}
+			if code == http.StatusPreconditionFailed {
+				return code, headers, nil, &PreconditionError{StatusError: statusErr, ETag: headers.Get("ETag")}
+			}
+			return code, headers, nil, statusErr
 		}
 
 		// We have seen inconsistencies even when we get 200 OK response
-		body, err := io.ReadAll(httpResponse.Body)
+		body, err := c.readBody(httpResponse)
+		if errors.Is(err, ErrResponseTooLarge) {
+			c.getLogger().Error("response body too large", "err", err)
+			return code, headers, nil, err
+		}
+		if err != nil && httpResponse.Request != nil && httpResponse.Request.Context().Err() != nil {
+			return code, headers, nil, contextError(httpResponse.Request.Context().Err())
+		}
 		if err != nil {
-			log.Printf("Couldn't parse response body(%v)", err)
+			c.getLogger().Error("reading response body failed", "err", err)
 			return code, headers, nil, errors.New("Couldn't parse response body, err: " + err.Error())
 		}
 
@@ -2,10 +2,17 @@ package http
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/Stellar1999/gotool/errs"
 )
 
 func Test_resolveUrlWithParameter(t *testing.T) {
@@ -123,3 +130,219 @@ func TestGet(t *testing.T) {
 		})
 	}
 }
+
+// countHook counts the requests it sees
+type countHook struct {
+	n *int64
+}
+
+func (h countHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	atomic.AddInt64(h.n, 1)
+	return ctx, nil
+}
+
+func (h countHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	return ctx, nil
+}
+
+func TestHookRemove(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+
+	var n int64
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handle := AddHook(countHook{n: &n})
+			_, _, _, _ = client.Get(server.URL, nil, nil)
+			handle.Remove()
+			handle.Remove()
+		}()
+	}
+	wg.Wait()
+	before := atomic.LoadInt64(&n)
+	if before < 10 {
+		t.Errorf("hook calls got = %v, want at least 10", before)
+	}
+	_, _, _, _ = client.Get(server.URL, nil, nil)
+	if got := atomic.LoadInt64(&n); got != before {
+		t.Errorf("hook calls after Remove got = %v, want %v", got, before)
+	}
+
+	registered := hooks()
+	AddHook(countHook{n: &n})
+	ClearHooks()
+	_, _, _, _ = client.Get(server.URL, nil, nil)
+	if got := atomic.LoadInt64(&n); got != before || len(hooks()) != 0 {
+		t.Errorf("hook calls after ClearHooks got = %v, want %v", got, before)
+	}
+	for _, hook := range registered {
+		AddHook(hook)
+	}
+}
+
+type ctxKey struct{}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// valueHook puts a value into the ctx of every request
+type valueHook struct{}
+
+func (valueHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	return context.WithValue(ctx, ctxKey{}, "from hook"), nil
+}
+
+func (valueHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	return ctx, nil
+}
+
+func TestContextCancel(t *testing.T) {
+	var hits int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		if r.URL.Path == "/body" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+
+	tests := []struct {
+		name    string
+		path    string
+		timeout bool
+		want    error
+	}{
+		{name: "canceled", path: "/", want: context.Canceled},
+		{name: "canceled reading the body", path: "/body", want: context.Canceled},
+		{name: "deadline", path: "/", timeout: true, want: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.timeout {
+				ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+			} else {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+			defer cancel()
+			start := time.Now()
+			_, _, _, err := client.GetWithContext(ctx, server.URL+tt.path, nil, nil)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("GetWithContext() error got = %v, want %v", err, tt.want)
+			}
+			if tt.timeout && !errors.Is(err, errs.ErrTimeout) {
+				t.Errorf("GetWithContext() error got = %v, want %v", err, errs.ErrTimeout)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("GetWithContext() took %v, want it aborted", elapsed)
+			}
+		})
+	}
+
+	before := atomic.LoadInt64(&hits)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, _, err := client.PostWithContext(ctx, server.URL, nil, nil, map[string]int{"a": 1}); err != context.Canceled {
+		t.Errorf("PostWithContext() error got = %v, want %v", err, context.Canceled)
+	}
+	if got := atomic.LoadInt64(&hits); got != before {
+		t.Errorf("requests with a canceled ctx got = %v, want none sent", got-before)
+	}
+
+	var seen any
+	hooked := NewClient(WithLogger(NopLogger), WithTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		seen = req.Context().Value(ctxKey{})
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})))
+	defer AddHook(valueHook{}).Remove()
+	if _, _, _, err := hooked.GetWithContext(context.Background(), "http://example.test", nil, nil); err != nil || seen != "from hook" {
+		t.Errorf("GetWithContext() request ctx value got = %v %v, want from hook", seen, err)
+	}
+}
+
+// pairHook records its Before and After calls, Before fails with err
+type pairHook struct {
+	name  string
+	err   error
+	calls *[]string
+}
+
+func (h pairHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	*h.calls = append(*h.calls, h.name+".Before")
+	return ctx, h.err
+}
+
+func (h pairHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	*h.calls = append(*h.calls, h.name+".After")
+	return ctx, h.err
+}
+
+func TestHookFailureRunsAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+	errHook := errors.New("hook failed")
+
+	var calls []string
+	first := AddHook(pairHook{name: "first", calls: &calls})
+	second := AddHook(pairHook{name: "second", err: errHook, calls: &calls})
+	third := AddHook(pairHook{name: "third", calls: &calls})
+	_, _, _, err := client.Get(server.URL, nil, nil)
+	if !errors.Is(err, errHook) {
+		t.Errorf("Get() error = %v, want %v", err, errHook)
+	}
+	if want := []string{"first.Before", "second.Before", "first.After"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("hook calls got = %v, want %v", calls, want)
+	}
+
+	calls = nil
+	if _, err := client.NewRequest(GET, server.URL).Stream(); !errors.Is(err, errHook) {
+		t.Errorf("Stream() error = %v, want %v", err, errHook)
+	}
+	if want := []string{"first.Before", "second.Before", "first.After"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Stream() hook calls got = %v, want %v", calls, want)
+	}
+	second.Remove()
+	third.Remove()
+
+	// a failing After doesn't keep the hooks after it from running
+	second = AddHook(afterErrHook{err: errHook})
+	third = AddHook(pairHook{name: "third", calls: &calls})
+	calls = nil
+	_, _, _, err = client.Get(server.URL, nil, nil)
+	if !errors.Is(err, errHook) {
+		t.Errorf("Get() error = %v, want %v", err, errHook)
+	}
+	if want := []string{"first.Before", "third.Before", "first.After", "third.After"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("hook calls got = %v, want %v", calls, want)
+	}
+	first.Remove()
+	second.Remove()
+	third.Remove()
+}
+
+// afterErrHook fails in After
+type afterErrHook struct {
+	err error
+}
+
+func (h afterErrHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h afterErrHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	return ctx, h.err
+}
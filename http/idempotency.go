@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Stellar1999/gotool/idgen"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type idempotencyConfig struct {
+	methods map[string]bool
+	derived bool
+}
+
+type IdempotencyOption = opt.Option[idempotencyConfig]
+
+// WithIdempotentMethods sets the methods that get a key, POST by default
+func WithIdempotentMethods(methods ...RequestMethodType) IdempotencyOption {
+	return func(c *idempotencyConfig) {
+		c.methods = make(map[string]bool, len(methods))
+		for _, method := range methods {
+			c.methods[string(method)] = true
+		}
+	}
+}
+
+// WithDerivedKeys derives the key from the method, url and body, so sending
+// the same request again reuses its key without an IdempotencyScope. Two
+// deliberate identical requests then share the key too and the server
+// answers the second with the outcome of the first.
+func WithDerivedKeys() IdempotencyOption {
+	return func(c *idempotencyConfig) {
+		c.derived = true
+	}
+}
+
+// IdempotencyHook sets an Idempotency-Key header on requests without one,
+// so a server can recognize a request sent again after a timeout or failure
+// and skip running it twice. Install it with AddHook. The key is, in order:
+//
+//   - the key of the context set by WithIdempotencyKey
+//   - derived from the request with WithDerivedKeys
+//   - the key generated for the same request before in its IdempotencyScope
+//   - a new random UUID
+type IdempotencyHook struct {
+	config idempotencyConfig
+}
+
+func NewIdempotencyHook(opts ...IdempotencyOption) *IdempotencyHook {
+	h := &IdempotencyHook{config: idempotencyConfig{methods: map[string]bool{http.MethodPost: true}}}
+	opt.Apply(&h.config, opts...)
+	return h
+}
+
+type idempotencyKey struct{}
+
+type idempotencyScopeKey struct{}
+
+// idempotencyScope remembers the keys generated for requests by fingerprint
+type idempotencyScope struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+// WithIdempotencyKey returns ctx making the IdempotencyHook send key, e.g. an
+// order id, with the requests sent with it
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyScope returns ctx for one logical operation: a request sent
+// again with it gets the key generated the first time, a different request
+// gets its own. Retry loops send their attempts within a scope.
+func IdempotencyScope(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(idempotencyScopeKey{}).(*idempotencyScope); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyScopeKey{}, &idempotencyScope{keys: make(map[string]string)})
+}
+
+func (h *IdempotencyHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	if !h.config.methods[req.Method] || req.Header.Get("Idempotency-Key") != "" {
+		return ctx, nil
+	}
+	if key, ok := ctx.Value(idempotencyKey{}).(string); ok && key != "" {
+		req.Header.Set("Idempotency-Key", key)
+		return ctx, nil
+	}
+	scope, scoped := ctx.Value(idempotencyScopeKey{}).(*idempotencyScope)
+	if !h.config.derived && !scoped {
+		req.Header.Set("Idempotency-Key", idgen.UUIDv4())
+		return ctx, nil
+	}
+	body, err := requestBody(req)
+	if err != nil {
+		return ctx, err
+	}
+	fingerprint := requestFingerprint(req, body)
+	if h.config.derived {
+		req.Header.Set("Idempotency-Key", fingerprint)
+		return ctx, nil
+	}
+	scope.mu.Lock()
+	key, ok := scope.keys[fingerprint]
+	if !ok {
+		key = idgen.UUIDv4()
+		scope.keys[fingerprint] = key
+	}
+	scope.mu.Unlock()
+	req.Header.Set("Idempotency-Key", key)
+	return ctx, nil
+}
+
+func (h *IdempotencyHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	return ctx, nil
+}
+
+// requestFingerprint hashes method, url and body into a UUID
+func requestFingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s %s\n", req.Method, req.URL.String())
+	_, _ = hash.Write(body)
+	b := hash.Sum(nil)[:16]
+	// the layout of a name based version 5 UUID
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestIdempotencyHook(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		mu.Unlock()
+	}))
+	defer server.Close()
+	send := func(ctx context.Context, method RequestMethodType, body any) string {
+		t.Helper()
+		keys = nil
+		if _, err := NewRequest(method, server.URL).WithContext(ctx).Body(body).Send(); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		return keys[0]
+	}
+
+	handle := AddHook(NewIdempotencyHook())
+	ctx := context.Background()
+	first, second := send(ctx, POST, 1), send(ctx, POST, 1)
+	if len(first) != 36 || first == second {
+		t.Errorf("Idempotency-Key got = %v %v, want a new key per request", first, second)
+	}
+	if got := send(ctx, PUT, 1); got != "" {
+		t.Errorf("PUT Idempotency-Key got = %v, want none", got)
+	}
+	if got := send(WithIdempotencyKey(ctx, "order-7"), POST, 1); got != "order-7" {
+		t.Errorf("Idempotency-Key got = %v, want order-7", got)
+	}
+	keys = nil
+	if _, err := NewRequest(POST, server.URL).Header("Idempotency-Key", "mine").Send(); err != nil || keys[0] != "mine" {
+		t.Errorf("Idempotency-Key got = %v, want the header of the request", keys)
+	}
+
+	scope := IdempotencyScope(ctx)
+	attempt1, attempt2, other := send(scope, POST, 1), send(scope, POST, 1), send(scope, POST, 2)
+	if attempt1 != attempt2 || attempt1 == other {
+		t.Errorf("scoped Idempotency-Key got = %v %v %v, want the key reused for the same request", attempt1, attempt2, other)
+	}
+	handle.Remove()
+
+	handle = AddHook(NewIdempotencyHook(WithDerivedKeys(), WithIdempotentMethods(POST, PATCH)))
+	defer handle.Remove()
+	derived1, derived2, patched := send(ctx, POST, 1), send(ctx, POST, 1), send(ctx, PATCH, 1)
+	if derived1 != derived2 || derived1 == patched || derived1[14] != '5' {
+		t.Errorf("derived Idempotency-Key got = %v %v %v", derived1, derived2, patched)
+	}
+}
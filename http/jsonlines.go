@@ -0,0 +1,90 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Stellar1999/gotool/contenttype"
+)
+
+// maxJSONLine is the longest record StreamJSONLines decodes
+const maxJSONLine = 16 << 20
+
+// StreamJSONLines gets url with the default Client and decodes the
+// newline delimited JSON (NDJSON, JSON Lines) response record by record as it
+// arrives. See SendJSONLines.
+func StreamJSONLines[T any](ctx context.Context, url string, header map[string]string, parameter map[string]string) (<-chan T, <-chan error) {
+	r := NewRequest(GET, url).WithContext(ctx).Headers(HeadersFromMap(header))
+	for key, value := range parameter {
+		r.Query(key, value)
+	}
+	return SendJSONLines[T](r)
+}
+
+// SendJSONLines sends r and decodes its newline delimited JSON response into
+// records of type T, one per line, blank lines skipped. Only one record is
+// held at a time whatever the size of the response, but note that
+// WithMaxResponseBytes limits the whole stream. The records channel is closed
+// at the end of the response, when decoding fails or the context of r is
+// done; the error channel then yields the error, if any, and is closed too:
+//
+//	rows, errc := http.StreamJSONLines[Event](ctx, url, nil, nil)
+//	for row := range rows {
+//		...
+//	}
+//	if err := <-errc; err != nil {
+//		return err
+//	}
+//
+// Stop early by canceling the context, the response is closed and the error
+// channel yields the context error. An Accept header for NDJSON is sent
+// unless r has one.
+func SendJSONLines[T any](r *Request) (<-chan T, <-chan error) {
+	out := make(chan T)
+	errc := make(chan error, 1)
+	if r.header.Get("Accept") == "" {
+		r.header.Set("Accept", string(contenttype.NDJSON))
+	}
+	ctx := r.ctx
+	resp, err := r.Stream()
+	if err != nil {
+		errc <- err
+		close(out)
+		close(errc)
+		return out, errc
+	}
+	go func() {
+		defer close(errc)
+		defer close(out)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64<<10), maxJSONLine)
+		for line := 1; scanner.Scan(); line++ {
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+			var record T
+			if err := json.Unmarshal(data, &record); err != nil {
+				errc <- fmt.Errorf("%w: json lines: line %d: %v", ErrCodec, line, err)
+				return
+			}
+			select {
+			case out <- record:
+			case <-ctx.Done():
+				errc <- contextError(ctx.Err())
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			if ctx.Err() != nil {
+				err = contextError(ctx.Err())
+			}
+			errc <- err
+		}
+	}()
+	return out, errc
+}
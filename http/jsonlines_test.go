@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type jsonLine struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestStreamJSONLines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/x-ndjson" || r.URL.Query().Get("since") != "1" || r.Header.Get("X-Tenant") != "a" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte("{\"id\":1,\"name\":\"a\"}\n\n{\"id\":2,\"name\":\"b\"}\r\n{\"id\":3}"))
+	}))
+	defer server.Close()
+
+	rows, errc := StreamJSONLines[jsonLine](context.Background(), server.URL, map[string]string{"X-Tenant": "a"}, map[string]string{"since": "1"})
+	var got []jsonLine
+	for row := range rows {
+		got = append(got, row)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("StreamJSONLines() error = %v", err)
+	}
+	want := []jsonLine{{1, "a"}, {2, "b"}, {3, ""}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamJSONLines() got = %v, want %v", got, want)
+	}
+}
+
+func TestStreamJSONLinesErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("{\"id\":1}\nnot json\n{\"id\":3}\n"))
+	}))
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+
+	rows, errc := SendJSONLines[jsonLine](client.NewRequest(GET, server.URL+"/missing"))
+	if _, open := <-rows; open {
+		t.Error("SendJSONLines() got a record, want none")
+	}
+	var statusErr *StatusError
+	if err := <-errc; !errors.As(err, &statusErr) || statusErr.Code != http.StatusNotFound {
+		t.Errorf("SendJSONLines() error = %v, want a 404", err)
+	}
+
+	rows, errc = SendJSONLines[jsonLine](client.NewRequest(GET, server.URL))
+	n := 0
+	for range rows {
+		n++
+	}
+	if err := <-errc; !errors.Is(err, ErrCodec) {
+		t.Errorf("SendJSONLines() error = %v, want %v", err, ErrCodec)
+	}
+	if n != 1 {
+		t.Errorf("SendJSONLines() got = %d records, want 1", n)
+	}
+}
+
+func TestStreamJSONLinesCancel(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		flusher := w.(http.Flusher)
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "{\"id\":%d}\n", i); err != nil {
+				return
+			}
+			flusher.Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows, errc := StreamJSONLines[jsonLine](ctx, server.URL, nil, nil)
+	for row := range rows {
+		if row.ID == 2 {
+			cancel()
+		}
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("StreamJSONLines() error = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("the response was not closed")
+	}
+}
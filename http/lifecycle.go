@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClientClosed is returned for requests sent after Client.Close
+var ErrClientClosed = errors.New("http: client closed")
+
+// lifecycle counts the requests in c.do so Close can wait for them
+type lifecycle struct {
+	mu     sync.Mutex
+	closed bool
+	active int
+	// idle is closed when the client is closed and no request is active
+	idle chan struct{}
+}
+
+func (l *lifecycle) enter() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClientClosed
+	}
+	l.active++
+	return nil
+}
+
+func (l *lifecycle) leave() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if l.closed && l.active == 0 {
+		close(l.idle)
+	}
+}
+
+// InFlight returns the number of requests being sent, the handshakes of Dial
+// included but not the open websockets
+func (c *Client) InFlight() int {
+	c.lifecycle.mu.Lock()
+	defer c.lifecycle.mu.Unlock()
+	return c.lifecycle.active
+}
+
+// Close stops the client: new requests fail with ErrClientClosed, the ones in
+// flight are waited for until ctx is done and the idle connections are
+// closed. It returns ctx.Err() when requests were still in flight. Websockets
+// opened with Dial are not closed. Closing again waits again. The log of
+// WithHARRecorder is written by the first Close, the primary of WithFailover
+// is no longer probed.
+func (c *Client) Close(ctx context.Context) error {
+	l := &c.lifecycle
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		l.idle = make(chan struct{})
+		if l.active == 0 {
+			close(l.idle)
+		}
+	}
+	idle := l.idle
+	l.mu.Unlock()
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c.httpClient.CloseIdleConnections()
+	if c.failover.transport != nil {
+		c.failover.transport.close()
+	}
+	if c.har != nil {
+		if harErr := c.har.write(); err == nil {
+			err = harErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+
+	done := make(chan error)
+	go func() {
+		_, err := client.NewRequest(GET, server.URL).Send()
+		done <- err
+	}()
+	for deadline := time.Now().Add(2 * time.Second); client.InFlight() != 1 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if got := client.InFlight(); got != 1 {
+		t.Fatalf("InFlight() got = %v, want 1", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close() error got = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := client.NewRequest(GET, server.URL).Send(); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Send() after Close error got = %v, want %v", err, ErrClientClosed)
+	}
+	if _, err := client.Dial(context.Background(), "ws"+server.URL[4:], nil); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Dial() after Close error got = %v, want %v", err, ErrClientClosed)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Send() in flight error = %v", err)
+	}
+	if err := client.Close(context.Background()); err != nil || client.InFlight() != 0 {
+		t.Errorf("Close() got = %v, in flight %v", err, client.InFlight())
+	}
+	if got := client.PoolStats().Idle; got != 0 {
+		t.Errorf("PoolStats().Idle after Close got = %v, want 0", got)
+	}
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger is a leveled logger taking alternating key-value pairs after the message
+type Logger interface {
+	Debug(msg string, keysAndValues ...any)
+	Info(msg string, keysAndValues ...any)
+	Warn(msg string, keysAndValues ...any)
+	Error(msg string, keysAndValues ...any)
+}
+
+// global logger, used by clients without WithLogger
+var globalLogger Logger = stdLogger{}
+
+// SetLogger replaces the package logger, which writes all but DEBUG lines to
+// the standard log package by default
+func SetLogger(logger Logger) {
+	globalLogger = logger
+}
+
+// WithLogger sets the logger of a Client
+func WithLogger(logger Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+func (c *Client) getLogger() Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return globalLogger
+}
+
+// NopLogger discards everything
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+// NewStdLogger returns the default logger, writing "LEVEL msg key=value ..."
+// lines with the standard log package. DEBUG lines are dropped unless debug,
+// SetLogger(NewStdLogger(true)) turns them on for the package.
+func NewStdLogger(debug bool) Logger {
+	return stdLogger{debug: debug}
+}
+
+type stdLogger struct {
+	debug bool
+}
+
+func (l stdLogger) Debug(msg string, keysAndValues ...any) {
+	if l.debug {
+		stdPrint("DEBUG", msg, keysAndValues)
+	}
+}
+func (stdLogger) Info(msg string, keysAndValues ...any)  { stdPrint("INFO", msg, keysAndValues) }
+func (stdLogger) Warn(msg string, keysAndValues ...any)  { stdPrint("WARN", msg, keysAndValues) }
+func (stdLogger) Error(msg string, keysAndValues ...any) { stdPrint("ERROR", msg, keysAndValues) }
+
+func stdPrint(level string, msg string, keysAndValues []any) {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		b.WriteByte(' ')
+		if i+1 < len(keysAndValues) {
+			fmt.Fprintf(&b, "%v=%v", keysAndValues[i], keysAndValues[i+1])
+		} else {
+			fmt.Fprintf(&b, "%v=<missing>", keysAndValues[i])
+		}
+	}
+	log.Print(b.String())
+}
+
+// SugaredLogger is the subset of *zap.SugaredLogger used by NewZapLogger
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...any)
+	Infow(msg string, keysAndValues ...any)
+	Warnw(msg string, keysAndValues ...any)
+	Errorw(msg string, keysAndValues ...any)
+}
+
+// NewZapLogger adapts a zap sugared logger, e.g. NewZapLogger(zapLogger.Sugar())
+func NewZapLogger(logger SugaredLogger) Logger {
+	return zapLogger{logger: logger}
+}
+
+type zapLogger struct {
+	logger SugaredLogger
+}
+
+func (l zapLogger) Debug(msg string, keysAndValues ...any) { l.logger.Debugw(msg, keysAndValues...) }
+func (l zapLogger) Info(msg string, keysAndValues ...any)  { l.logger.Infow(msg, keysAndValues...) }
+func (l zapLogger) Warn(msg string, keysAndValues ...any)  { l.logger.Warnw(msg, keysAndValues...) }
+func (l zapLogger) Error(msg string, keysAndValues ...any) { l.logger.Errorw(msg, keysAndValues...) }
@@ -0,0 +1,19 @@
+//go:build go1.21
+
+package http
+
+import "log/slog"
+
+// NewSlogLogger adapts a *slog.Logger
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Debug(msg string, keysAndValues ...any) { l.logger.Debug(msg, keysAndValues...) }
+func (l slogLogger) Info(msg string, keysAndValues ...any)  { l.logger.Info(msg, keysAndValues...) }
+func (l slogLogger) Warn(msg string, keysAndValues ...any)  { l.logger.Warn(msg, keysAndValues...) }
+func (l slogLogger) Error(msg string, keysAndValues ...any) { l.logger.Error(msg, keysAndValues...) }
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordLogger keeps every entry as "LEVEL msg k=v ..."
+type recordLogger struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *recordLogger) record(level string, msg string, keysAndValues []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := level + " " + msg
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		entry += fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1])
+	}
+	l.entries = append(l.entries, entry)
+}
+
+func (l *recordLogger) Debug(msg string, keysAndValues ...any) { l.record("DEBUG", msg, keysAndValues) }
+func (l *recordLogger) Info(msg string, keysAndValues ...any)  { l.record("INFO", msg, keysAndValues) }
+func (l *recordLogger) Warn(msg string, keysAndValues ...any)  { l.record("WARN", msg, keysAndValues) }
+func (l *recordLogger) Error(msg string, keysAndValues ...any) { l.record("ERROR", msg, keysAndValues) }
+
+func TestWithDump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	logger := &recordLogger{}
+	client := NewClient(WithLogger(logger), WithDump(), WithRedactHeaders("x-token"))
+	header := map[string]string{"Authorization": "Bearer secret", "X-Token": "secret", "X-Trace": "visible"}
+	if _, _, _, err := client.Post(server.URL, header, nil, map[string]int{"a": 1}); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+
+	if len(logger.entries) != 2 {
+		t.Fatalf("dump entries got = %v, want 2", logger.entries)
+	}
+	tests := []struct {
+		name     string
+		entry    string
+		contains []string
+	}{
+		{
+			name:     "request",
+			entry:    logger.entries[0],
+			contains: []string{"DEBUG http request", "method=POST", "Authorization:[[REDACTED]]", "X-Token:[[REDACTED]]", "X-Trace:[visible]", `body={"a":1}`},
+		},
+		{
+			name:     "response",
+			entry:    logger.entries[1],
+			contains: []string{"DEBUG http response", "code=200", "Set-Cookie:[[REDACTED]]", `body={"ok":true}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if strings.Contains(tt.entry, "secret") {
+				t.Errorf("dump leaks secret: %v", tt.entry)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(tt.entry, want) {
+					t.Errorf("dump got = %v, want to contain %v", tt.entry, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	defer SetLogger(globalLogger)
+	logger := &recordLogger{}
+	SetLogger(logger)
+
+	_, _, _, _ = NewClient().Get("http://127.0.0.1:1", nil, nil)
+	if len(logger.entries) != 1 || !strings.HasPrefix(logger.entries[0], "ERROR sending request failed err=") {
+		t.Errorf("package logger got = %v, want one sending error", logger.entries)
+	}
+	_, _, _, _ = NewClient(WithLogger(NopLogger)).Get("http://127.0.0.1:1", nil, nil)
+	if len(logger.entries) != 1 {
+		t.Errorf("package logger got = %v, want WithLogger to take precedence", logger.entries)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	flags := log.Flags()
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	}()
+	globalLogger.Debug("websocket connected", "url", "ws://a")
+	globalLogger.Warn("closing failed", "err", "boom", "dangling")
+	if got, want := buf.String(), "WARN closing failed err=boom dangling=<missing>\n"; got != want {
+		t.Errorf("stdLogger got = %q, want %q", got, want)
+	}
+	buf.Reset()
+	NewStdLogger(true).Debug("websocket connected", "url", "ws://a")
+	if got, want := buf.String(), "DEBUG websocket connected url=ws://a\n"; got != want {
+		t.Errorf("NewStdLogger(true).Debug() got = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,260 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	gourl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// PageStrategy returns the url of the page after resp, an empty url ends the pagination
+type PageStrategy func(resp *Response) (string, error)
+
+type pageConfig struct {
+	strategy PageStrategy
+	header   map[string]string
+	maxPages int
+}
+
+type PageOption = opt.Option[pageConfig]
+
+// WithPageStrategy sets how the next page is found, LinkHeader by default
+func WithPageStrategy(strategy PageStrategy) PageOption {
+	return func(c *pageConfig) {
+		c.strategy = strategy
+	}
+}
+
+// WithPageHeader adds headers to every page request
+func WithPageHeader(header map[string]string) PageOption {
+	return func(c *pageConfig) {
+		c.header = header
+	}
+}
+
+// WithMaxPages stops after n pages, there is no limit by default
+func WithMaxPages(n int) PageOption {
+	return func(c *pageConfig) {
+		c.maxPages = n
+	}
+}
+
+var linkNextPattern = regexp.MustCompile(`<([^>]*)>[^,]*;\s*rel="?([^",]*)"?`)
+
+// LinkHeader follows the RFC 5988 Link header entry with rel="next"
+func LinkHeader() PageStrategy {
+	return func(resp *Response) (string, error) {
+		for _, link := range resp.Header.Values("Link") {
+			for _, match := range linkNextPattern.FindAllStringSubmatch(link, -1) {
+				for _, rel := range strings.Fields(match[2]) {
+					if rel == "next" {
+						return resolveReference(resp.FinalURL, match[1])
+					}
+				}
+			}
+		}
+		return "", nil
+	}
+}
+
+// CursorField reads the cursor at the dotted field of the JSON body, e.g.
+// "meta.next_cursor", and requests it as the query parameter param. A missing,
+// null or empty cursor ends the pagination.
+func CursorField(field string, param string) PageStrategy {
+	return func(resp *Response) (string, error) {
+		value, err := jsonField(resp.Body, field)
+		if err != nil || value == nil {
+			return "", err
+		}
+		cursor := fmt.Sprint(value)
+		if f, ok := value.(float64); ok {
+			cursor = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		if cursor == "" {
+			return "", nil
+		}
+		return setQuery(resp.FinalURL, param, cursor)
+	}
+}
+
+// PageNumber increments the query parameter param, starting at 1 when the
+// first url does not set it. The pagination ends on a page whose items, the
+// JSON array at the dotted itemsField or the body itself when empty, are empty.
+func PageNumber(param string, itemsField string) PageStrategy {
+	return func(resp *Response) (string, error) {
+		n, err := countItems(resp.Body, itemsField)
+		if err != nil || n == 0 {
+			return "", err
+		}
+		page, err := queryInt(resp.FinalURL, param, 1)
+		if err != nil {
+			return "", err
+		}
+		return setQuery(resp.FinalURL, param, strconv.Itoa(page+1))
+	}
+}
+
+// Offset advances the query parameter param by the number of items on the
+// page, found like in PageNumber. An empty page ends the pagination.
+func Offset(param string, itemsField string) PageStrategy {
+	return func(resp *Response) (string, error) {
+		n, err := countItems(resp.Body, itemsField)
+		if err != nil || n == 0 {
+			return "", err
+		}
+		offset, err := queryInt(resp.FinalURL, param, 0)
+		if err != nil {
+			return "", err
+		}
+		return setQuery(resp.FinalURL, param, strconv.Itoa(offset+n))
+	}
+}
+
+// Page is one fetched page, Number starts at 1
+type Page struct {
+	Number   int
+	URL      string
+	Response *Response
+}
+
+// Pager fetches pages one by one, like bufio.Scanner:
+//
+//	pager := http.Paginate(ctx, url)
+//	for pager.Next() {
+//		page := pager.Page()
+//		...
+//	}
+//	err := pager.Err()
+type Pager struct {
+	client *Client
+	ctx    context.Context
+	config pageConfig
+	next   string
+	page   Page
+	err    error
+}
+
+// Paginate walks the pages starting at firstURL with the default Client
+func Paginate(ctx context.Context, firstURL string, opts ...PageOption) *Pager {
+	return defaultClient.Paginate(ctx, firstURL, opts...)
+}
+
+// Paginate walks the pages starting at firstURL. Every page is sent like a
+// Request, through the hooks and settings of c, and the walk stops when ctx is done.
+func (c *Client) Paginate(ctx context.Context, firstURL string, opts ...PageOption) *Pager {
+	p := &Pager{client: c, ctx: ctx, next: firstURL}
+	p.config.strategy = LinkHeader()
+	p.err = opt.Build(&p.config, opts)
+	return p
+}
+
+// Next fetches the next page, it returns false at the end or on an error
+func (p *Pager) Next() bool {
+	if p.err != nil || p.next == "" {
+		return false
+	}
+	if p.config.maxPages > 0 && p.page.Number >= p.config.maxPages {
+		return false
+	}
+	if p.err = p.ctx.Err(); p.err != nil {
+		return false
+	}
+	req := p.client.NewRequest(GET, p.next).WithContext(p.ctx)
+	for k, v := range p.config.header {
+		req.Header(k, v)
+	}
+	resp, err := req.Send()
+	if err != nil {
+		p.err = err
+		return false
+	}
+	p.page = Page{Number: p.page.Number + 1, URL: p.next, Response: resp}
+	if p.next, err = p.config.strategy(resp); err != nil {
+		p.err = fmt.Errorf("finding the page after %s: %w", p.page.URL, err)
+	}
+	return true
+}
+
+// Page returns the page fetched by the last Next
+func (p *Pager) Page() Page {
+	return p.page
+}
+
+// Err returns the error that stopped the pagination, nil at a normal end
+func (p *Pager) Err() error {
+	return p.err
+}
+
+func resolveReference(base string, ref string) (string, error) {
+	b, err := gourl.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := gourl.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(r).String(), nil
+}
+
+func setQuery(rawURL string, param string, value string) (string, error) {
+	u, err := gourl.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	values := u.Query()
+	values.Set(param, value)
+	u.RawQuery = values.Encode()
+	return u.String(), nil
+}
+
+func queryInt(rawURL string, param string, fallback int) (int, error) {
+	u, err := gourl.Parse(rawURL)
+	if err != nil {
+		return 0, err
+	}
+	value := u.Query().Get(param)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// jsonField returns the value at the dotted path of a JSON object, nil when it is missing
+func jsonField(body []byte, path string) (any, error) {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return value, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		value = object[key]
+	}
+	return value, nil
+}
+
+func countItems(body []byte, itemsField string) (int, error) {
+	value, err := jsonField(body, itemsField)
+	if err != nil {
+		return 0, err
+	}
+	if value == nil {
+		return 0, nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return 0, fmt.Errorf("items at %q are not a JSON array", itemsField)
+	}
+	return len(items), nil
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func collectPages(t *testing.T, pager *Pager) []string {
+	t.Helper()
+	var bodies []string
+	for pager.Next() {
+		bodies = append(bodies, pager.Page().Response.String())
+	}
+	return bodies
+}
+
+func TestPaginate(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/link":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page < 2 {
+				w.Header().Set("Link", fmt.Sprintf(`</link?page=%d>; rel="next", </link?page=2>; rel="last"`, page+1))
+			}
+			fmt.Fprintf(w, `["page%d"]`, page)
+		case "/cursor":
+			switch r.URL.Query().Get("cursor") {
+			case "":
+				fmt.Fprint(w, `{"items":["a"],"meta":{"next":"c2"}}`)
+			case "c2":
+				fmt.Fprint(w, `{"items":["b"],"meta":{"next":null}}`)
+			}
+		case "/page":
+			page, err := strconv.Atoi(r.URL.Query().Get("page"))
+			if err != nil {
+				page = 1
+			}
+			start, end := (page-1)*2, page*2
+			if start > len(items) {
+				start = len(items)
+			}
+			if end > len(items) {
+				end = len(items)
+			}
+			data, _ := json.Marshal(items[start:end])
+			fmt.Fprintf(w, `{"data":%s}`, data)
+		case "/offset":
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			end := offset + 2
+			if end > len(items) {
+				end = len(items)
+			}
+			if offset > len(items) {
+				offset = len(items)
+			}
+			data, _ := json.Marshal(items[offset:end])
+			_, _ = w.Write(data)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		url  string
+		opts []PageOption
+		want []string
+	}{
+		{
+			name: "link header",
+			url:  server.URL + "/link",
+			want: []string{`["page0"]`, `["page1"]`, `["page2"]`},
+		},
+		{
+			name: "cursor",
+			url:  server.URL + "/cursor",
+			opts: []PageOption{WithPageStrategy(CursorField("meta.next", "cursor"))},
+			want: []string{`{"items":["a"],"meta":{"next":"c2"}}`, `{"items":["b"],"meta":{"next":null}}`},
+		},
+		{
+			name: "page number",
+			url:  server.URL + "/page",
+			opts: []PageOption{WithPageStrategy(PageNumber("page", "data"))},
+			want: []string{`{"data":["a","b"]}`, `{"data":["c","d"]}`, `{"data":["e"]}`, `{"data":[]}`},
+		},
+		{
+			name: "offset",
+			url:  server.URL + "/offset",
+			opts: []PageOption{WithPageStrategy(Offset("offset", ""))},
+			want: []string{`["a","b"]`, `["c","d"]`, `["e"]`, `[]`},
+		},
+		{
+			name: "max pages",
+			url:  server.URL + "/link",
+			opts: []PageOption{WithMaxPages(2)},
+			want: []string{`["page0"]`, `["page1"]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pager := Paginate(context.Background(), tt.url, tt.opts...)
+			got := collectPages(t, pager)
+			if err := pager.Err(); err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Paginate() got = %v %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginateStops(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Link", `<?page=2>; rel="next"`)
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	pager := Paginate(context.Background(), server.URL)
+	if got := collectPages(t, pager); len(got) != 1 {
+		t.Errorf("Paginate() got = %v pages, want 1", len(got))
+	}
+	var statusErr *StatusError
+	if !errors.As(pager.Err(), &statusErr) || statusErr.Code != http.StatusInternalServerError {
+		t.Errorf("Err() got = %v, want status 500", pager.Err())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pager = Paginate(ctx, server.URL)
+	if !pager.Next() {
+		t.Fatalf("Next() got = false, want true")
+	}
+	cancel()
+	if pager.Next() || !errors.Is(pager.Err(), context.Canceled) {
+		t.Errorf("Next() after cancel got err = %v, want %v", pager.Err(), context.Canceled)
+	}
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// how long a host that failed h2c or HTTP/3 is sent HTTP/1.1 or HTTP/2 over TLS
+const protocolFallbackTTL = 5 * time.Minute
+
+// WithHTTP2 enables HTTP/2 over TLS, negotiated with ALPN, so servers without
+// it still get HTTP/1.1. With priorKnowledge http:// urls use cleartext
+// HTTP/2 (h2c) without upgrade, like gRPC gateways expect. When a server
+// rejects h2c the request is retried with HTTP/1.1 and the host is sent
+// HTTP/1.1 for a while.
+func WithHTTP2(priorKnowledge bool) Option {
+	return func(c *Client) {
+		c.protocols.http2 = true
+		c.protocols.h2c = priorKnowledge
+	}
+}
+
+// WithHTTP3 is experimental: https urls are sent with rt first, typically a
+// *http3.RoundTripper of github.com/quic-go/quic-go, which is not bundled as it
+// needs a newer Go than this module. When rt fails the request is retried
+// with the regular transport and the host is not tried with rt for a while.
+func WithHTTP3(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.protocols.http3 = rt
+	}
+}
+
+type protocols struct {
+	http2 bool
+	h2c   bool
+	http3 http.RoundTripper
+}
+
+// applyProtocols wraps the transport last, after tuning and the host guard
+// have been applied to it, so the h2c connections are dialed the same way
+func (c *Client) applyProtocols() {
+	p := c.protocols
+	if !p.http2 && p.http3 == nil {
+		return
+	}
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	pt := &protocolTransport{base: base, http3: p.http3}
+	if transport, ok := base.(*http.Transport); ok && p.http2 {
+		transport = transport.Clone()
+		transport.ForceAttemptHTTP2 = true
+		if _, err := http2.ConfigureTransports(transport); err != nil {
+			c.getLogger().Warn("configuring HTTP/2 failed", "err", err)
+		}
+		pt.base = transport
+		if p.h2c {
+			dial := transport.DialContext
+			if dial == nil {
+				dial = (&net.Dialer{}).DialContext
+			}
+			pt.h2c = &http2.Transport{
+				AllowHTTP: true,
+				DialTLSContext: func(ctx context.Context, network string, addr string, _ *tls.Config) (net.Conn, error) {
+					return dial(ctx, network, addr)
+				},
+			}
+		}
+	} else if p.http2 {
+		c.getLogger().Warn("WithHTTP2 needs a *http.Transport, the transport is used as is")
+	}
+	httpClient := *c.httpClient
+	httpClient.Transport = pt
+	c.httpClient = &httpClient
+}
+
+// protocolTransport routes requests to h2c or HTTP/3 and falls back to base
+type protocolTransport struct {
+	base  http.RoundTripper
+	h2c   *http2.Transport
+	http3 http.RoundTripper
+	// host -> time until which the preferred protocol is skipped
+	fallbacks sync.Map
+}
+
+func (t *protocolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var preferred http.RoundTripper
+	switch {
+	case req.URL.Scheme == "http" && t.h2c != nil:
+		preferred = t.h2c
+	case req.URL.Scheme == "https" && t.http3 != nil:
+		preferred = t.http3
+	}
+	if preferred == nil || t.skip(req.URL.Host) {
+		return t.base.RoundTrip(req)
+	}
+	resp, err := preferred.RoundTrip(req)
+	if err == nil || req.Context().Err() != nil {
+		return resp, err
+	}
+	retry, ok := replayable(req)
+	if !ok {
+		return nil, err
+	}
+	t.fallbacks.Store(req.URL.Host, time.Now().Add(protocolFallbackTTL))
+	return t.base.RoundTrip(retry)
+}
+
+func (t *protocolTransport) skip(host string) bool {
+	until, ok := t.fallbacks.Load(host)
+	if !ok {
+		return false
+	}
+	if time.Now().After(until.(time.Time)) {
+		t.fallbacks.Delete(host)
+		return false
+	}
+	return true
+}
+
+// CloseIdleConnections closes the idle connections of all protocols
+func (t *protocolTransport) CloseIdleConnections() {
+	for _, rt := range []http.RoundTripper{t.base, t.http3} {
+		if closer, ok := rt.(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+		}
+	}
+	if t.h2c != nil {
+		t.h2c.CloseIdleConnections()
+	}
+}
+
+// replayable returns a copy of req with a fresh body, requests whose body cannot be read again are not
+func replayable(req *http.Request) (*http.Request, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	retry := req.Clone(req.Context())
+	retry.Body = body
+	return retry, true
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func protoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strconv.Itoa(r.ProtoMajor)))
+	})
+}
+
+func TestWithHTTP2(t *testing.T) {
+	h2cServer := httptest.NewServer(h2c.NewHandler(protoHandler(), &http2.Server{}))
+	defer h2cServer.Close()
+	h1Server := httptest.NewServer(protoHandler())
+	defer h1Server.Close()
+
+	tests := []struct {
+		name string
+		opts []Option
+		url  string
+		want string
+	}{
+		{name: "h2c prior knowledge", opts: []Option{WithHTTP2(true)}, url: h2cServer.URL, want: "2"},
+		{name: "without prior knowledge", opts: []Option{WithHTTP2(false)}, url: h2cServer.URL, want: "1"},
+		{name: "fallback to HTTP/1.1", opts: []Option{WithHTTP2(true)}, url: h1Server.URL, want: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(append(tt.opts, WithLogger(NopLogger))...)
+			for i := 0; i < 2; i++ {
+				resp, err := client.NewRequest(POST, tt.url).Body(map[string]int{"n": i}).Send()
+				if err != nil || resp.String() != tt.want {
+					t.Fatalf("Send() got = %v %v, want HTTP/%v", resp, err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+type failingRoundTripper struct {
+	calls int
+}
+
+func (f *failingRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	f.calls++
+	return nil, errors.New("no quic")
+}
+
+func TestWithHTTP3Fallback(t *testing.T) {
+	server := httptest.NewTLSServer(protoHandler())
+	defer server.Close()
+
+	h3 := &failingRoundTripper{}
+	client := NewClient(WithTransport(server.Client().Transport), WithHTTP3(h3), WithLogger(NopLogger))
+	for i := 0; i < 2; i++ {
+		resp, err := client.NewRequest(GET, server.URL).Send()
+		if err != nil || resp.String() != "1" {
+			t.Fatalf("Send() got = %v %v, want the fallback response", resp, err)
+		}
+	}
+	if h3.calls != 1 {
+		t.Errorf("HTTP/3 calls got = %v, want 1 before falling back", h3.calls)
+	}
+}
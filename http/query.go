@@ -0,0 +1,136 @@
+package http
+
+import (
+	"fmt"
+	gourl "net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// EncodeQuery encodes the exported fields of a struct (or pointer to one) as
+// query parameters. The field name is taken from the `query:"name"` tag, "-"
+// skips a field and ",omitempty" drops zero values. Slices become repeated keys,
+// time.Time uses the `layout:"..."` tag or RFC 3339 and embedded structs are flattened.
+func EncodeQuery(v any) (gourl.Values, error) {
+	values := make(gourl.Values)
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return values, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("query: want a struct, got %T", v)
+	}
+	return values, encodeStruct(values, rv)
+}
+
+func encodeStruct(values gourl.Values, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("query")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		fv := rv.Field(i)
+		if field.Anonymous && name == "" {
+			for fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+				if err := encodeStruct(values, fv); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if options == "omitempty" && fv.IsZero() {
+			continue
+		}
+		if err := encodeValue(values, name, fv, field.Tag.Get("layout")); err != nil {
+			return fmt.Errorf("query: field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func encodeValue(values gourl.Values, name string, fv reflect.Value, layout string) error {
+	for fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+	}
+	if fv.Type() == timeType {
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		values.Add(name, fv.Interface().(time.Time).Format(layout))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8 {
+			values.Add(name, string(fv.Bytes()))
+			return nil
+		}
+		for i := 0; i < fv.Len(); i++ {
+			if err := encodeValue(values, name, fv.Index(i), layout); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.String:
+		values.Add(name, fv.String())
+	case reflect.Bool:
+		values.Add(name, strconv.FormatBool(fv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		values.Add(name, strconv.FormatInt(fv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		values.Add(name, strconv.FormatUint(fv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		values.Add(name, strconv.FormatFloat(fv.Float(), 'f', -1, fv.Type().Bits()))
+	default:
+		if s, ok := fv.Interface().(fmt.Stringer); ok {
+			values.Add(name, s.String())
+			return nil
+		}
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// WithQueryValues adds multi-value query parameters, url.Values fits as well
+func (r *Request) WithQueryValues(values map[string][]string) *Request {
+	for key, vs := range values {
+		r.query[key] = append(r.query[key], vs...)
+	}
+	return r
+}
+
+// WithQueryStruct adds the query parameters encoded from v by EncodeQuery,
+// an encoding error is returned by URL, Build and Send
+func (r *Request) WithQueryStruct(v any) *Request {
+	values, err := EncodeQuery(v)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	return r.WithQueryValues(values)
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+type Paging struct {
+	Page  int `query:"page,omitempty"`
+	Limit int `query:"limit"`
+}
+
+type searchQuery struct {
+	Paging
+	Term     string    `query:"q"`
+	Tags     []string  `query:"tag"`
+	IDs      []int64   `query:"id"`
+	Active   bool      `query:"active"`
+	Score    *float64  `query:"score"`
+	Since    time.Time `query:"since" layout:"2006-01-02"`
+	Until    time.Time `query:"until,omitempty"`
+	Internal string    `query:"-"`
+	Raw      string
+	hidden   string
+}
+
+func TestEncodeQuery(t *testing.T) {
+	score := 0.5
+	tests := []struct {
+		name    string
+		v       any
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "all kinds",
+			v: &searchQuery{
+				Paging: Paging{Limit: 10},
+				Term:   "go lang",
+				Tags:   []string{"a", "b"},
+				IDs:    []int64{1, 2},
+				Active: true,
+				Score:  &score,
+				Since:  time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+				Raw:    "x",
+				hidden: "y",
+			},
+			want: "Raw=x&active=true&id=1&id=2&limit=10&q=go+lang&score=0.5&since=2024-03-01&tag=a&tag=b",
+		},
+		{
+			name: "omitempty and time default",
+			v:    searchQuery{Paging: Paging{Page: 2}, Until: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+			want: "Raw=&active=false&limit=0&page=2&q=&since=2024-03-01&until=2024-03-01T12%3A00%3A00Z",
+		},
+		{name: "nil pointer", v: (*searchQuery)(nil), want: ""},
+		{name: "not a struct", v: map[string]string{}, wantErr: true},
+		{name: "unsupported field", v: struct{ M map[string]int }{M: map[string]int{}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeQuery(tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncodeQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Encode() != tt.want {
+				t.Errorf("EncodeQuery() got = %v, want %v", got.Encode(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestWithQuery(t *testing.T) {
+	got, err := NewRequest(GET, "https://api.example.com/search?q=go").
+		WithQueryValues(url.Values{"tag": {"a", "b"}}).
+		WithQueryValues(map[string][]string{"tag": {"c"}}).
+		WithQueryStruct(Paging{Limit: 5}).
+		URL()
+	if want := "https://api.example.com/search?limit=5&q=go&tag=a&tag=b&tag=c"; err != nil || got != want {
+		t.Errorf("URL() got = %v %v, want %v", got, err, want)
+	}
+	if _, err := NewRequest(GET, "https://api.example.com").WithQueryStruct(42).URL(); err == nil {
+		t.Errorf("URL() error = nil, want the WithQueryStruct error")
+	}
+}
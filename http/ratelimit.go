@@ -0,0 +1,101 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/Stellar1999/gotool/errs"
+	"github.com/Stellar1999/gotool/ratelimit"
+)
+
+// WithRateLimit makes every request, including retries and hedges, wait for
+// l before it is sent. A request that l can never allow fails with an error
+// matching errs.ErrRateLimited and ratelimit.ErrLimitExceeded.
+func WithRateLimit(l ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.rateLimit.global = l
+	}
+}
+
+// WithHostRateLimit gives every host a token bucket of rate r holding burst
+// tokens, on top of WithRateLimit. Buckets of hosts idle for 10 minutes are
+// dropped. A rate or burst that is not positive disables it. Stats counts the
+// requests that waited as rate_limit_waits_total.
+func WithHostRateLimit(r ratelimit.Rate, burst int) Option {
+	return func(c *Client) {
+		c.rateLimit.hostRate, c.rateLimit.hostBurst = r, burst
+	}
+}
+
+type rateLimiting struct {
+	global    ratelimit.Limiter
+	hostRate  ratelimit.Rate
+	hostBurst int
+	perHost   *ratelimit.PerKeyLimiter[string]
+}
+
+func (r *rateLimiting) enabled() bool {
+	return r.global != nil || r.perHost != nil
+}
+
+// applyRateLimit wraps the transport below hedging, so every copy is limited
+func (c *Client) applyRateLimit() {
+	rl := &c.rateLimit
+	if rl.hostRate.N > 0 && rl.hostRate.Per > 0 && rl.hostBurst > 0 {
+		rate, burst := rl.hostRate, rl.hostBurst
+		rl.perHost, _ = ratelimit.NewPerKeyLimiter(func(string) ratelimit.Limiter {
+			bucket, _ := ratelimit.NewTokenBucket(rate, burst)
+			return bucket
+		})
+	}
+	if !rl.enabled() {
+		return
+	}
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient := *c.httpClient
+	httpClient.Transport = &rateLimitTransport{base: base, limits: rl, stats: &c.stats}
+	c.httpClient = &httpClient
+}
+
+type rateLimitTransport struct {
+	base   http.RoundTripper
+	limits *rateLimiting
+	stats  *clientStats
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limiters := make([]ratelimit.Limiter, 0, 2)
+	if t.limits.global != nil {
+		limiters = append(limiters, t.limits.global)
+	}
+	if t.limits.perHost != nil {
+		limiters = append(limiters, t.limits.perHost.Limiter(req.URL.Host))
+	}
+	waited := false
+	for _, l := range limiters {
+		if l.Allow() {
+			continue
+		}
+		waited = true
+		if err := l.Wait(req.Context()); err != nil {
+			if req.Context().Err() != nil {
+				return nil, contextError(req.Context().Err())
+			}
+			return nil, errs.Mark(err, errs.ErrRateLimited)
+		}
+	}
+	if waited {
+		atomic.AddInt64(&t.stats.rateLimitWaits, 1)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *rateLimitTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/errs"
+	"github.com/Stellar1999/gotool/ratelimit"
+)
+
+func TestHostRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer other.Close()
+
+	client := NewClient(WithLogger(NopLogger), WithHostRateLimit(ratelimit.PerSecond(20), 2))
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, _, _, err := client.Get(server.URL, nil, nil); err != nil {
+			t.Fatalf("Get() got = %v", err)
+		}
+	}
+	// the burst goes at once, the 2 others wait 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Get() 4 requests took %v, want about 100ms", elapsed)
+	}
+	start = time.Now()
+	if _, _, _, err := client.Get(other.URL, nil, nil); err != nil || time.Since(start) > 40*time.Millisecond {
+		t.Errorf("Get() other host got = %v after %v, want its own bucket", err, time.Since(start))
+	}
+	if got := client.Stats()["rate_limit_waits_total"]; got != 2 {
+		t.Errorf("Stats() rate_limit_waits_total got = %v, want 2", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	full, _ := ratelimit.NewLeakyBucket(ratelimit.PerMinute(1), 0)
+	client := NewClient(WithLogger(NopLogger), WithRateLimit(full))
+	if _, _, _, err := client.Get(server.URL, nil, nil); err != nil {
+		t.Fatalf("Get() got = %v", err)
+	}
+	_, _, _, err := client.Get(server.URL, nil, nil)
+	if !errors.Is(err, errs.ErrRateLimited) || !errors.Is(err, ratelimit.ErrLimitExceeded) {
+		t.Errorf("Get() over the limit got = %v, want %v", err, ratelimit.ErrLimitExceeded)
+	}
+
+	slow, _ := ratelimit.NewTokenBucket(ratelimit.PerMinute(1), 1)
+	slow.Allow()
+	client = NewClient(WithLogger(NopLogger), WithRateLimit(slow))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, _, err := client.GetWithContext(ctx, server.URL, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() waiting past the deadline got = %v", err)
+	}
+	if _, ok := NewClient().Stats()["rate_limit_waits_total"]; ok {
+		t.Errorf("Stats() without rate limits got rate_limit_waits_total")
+	}
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrTooManyRedirects is returned when WithMaxRedirects is exceeded
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+const defaultMaxRedirects = 10
+
+// Redirect is one 30x hop: the url that answered and its status
+type Redirect struct {
+	URL        string
+	StatusCode int
+}
+
+// WithNoRedirect returns 30x responses as they are, they fail with a *StatusError
+// carrying the Location header
+func WithNoRedirect() Option {
+	return func(c *Client) {
+		c.redirectPolicy = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+}
+
+// WithMaxRedirects follows at most n redirects, 10 by default
+func WithMaxRedirects(n int) Option {
+	return func(c *Client) {
+		c.redirectPolicy = maxRedirects(n)
+	}
+}
+
+// WithRedirectPolicy decides on each redirect like http.Client.CheckRedirect:
+// req is the upcoming request and via the requests made so far
+func WithRedirectPolicy(policy func(req *http.Request, via []*http.Request) error) Option {
+	return func(c *Client) {
+		c.redirectPolicy = policy
+	}
+}
+
+func maxRedirects(n int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > n {
+			return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, n)
+		}
+		return nil
+	}
+}
+
+// applyRedirectPolicy installs the policy on a copy of the http.Client,
+// wrapped so the hops of a Request are recorded
+func (c *Client) applyRedirectPolicy() {
+	policy := c.redirectPolicy
+	httpClient := *c.httpClient
+	if policy == nil {
+		policy = httpClient.CheckRedirect
+	}
+	if policy == nil {
+		policy = maxRedirects(defaultMaxRedirects)
+	}
+	httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := policy(req, via); err != nil {
+			return err
+		}
+		if rec, ok := req.Context().Value(redirectRecorderKey{}).(*redirectRecorder); ok {
+			rec.add(via[len(via)-1].URL.String(), req.Response.StatusCode, req.URL.String())
+		}
+		return nil
+	}
+	c.httpClient = &httpClient
+}
+
+type redirectRecorderKey struct{}
+
+// redirectRecorder collects the hops of one request
+type redirectRecorder struct {
+	mu    sync.Mutex
+	hops  []Redirect
+	final string
+}
+
+func (r *redirectRecorder) add(url string, code int, next string) {
+	r.mu.Lock()
+	r.hops = append(r.hops, Redirect{URL: url, StatusCode: code})
+	r.final = next
+	r.mu.Unlock()
+}
+
+func withRedirectRecorder(ctx context.Context) (context.Context, *redirectRecorder) {
+	rec := &redirectRecorder{}
+	return context.WithValue(ctx, redirectRecorderKey{}, rec), rec
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/b", http.StatusMovedPermanently) })
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/c", http.StatusFound) })
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("done")) })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		opts          []Option
+		wantCode      int
+		wantFinal     string
+		wantRedirects []Redirect
+		wantErr       error
+	}{
+		{
+			name:      "followed",
+			wantCode:  http.StatusOK,
+			wantFinal: server.URL + "/c",
+			wantRedirects: []Redirect{
+				{URL: server.URL + "/a", StatusCode: http.StatusMovedPermanently},
+				{URL: server.URL + "/b", StatusCode: http.StatusFound},
+			},
+		},
+		{
+			name:      "no redirect",
+			opts:      []Option{WithNoRedirect()},
+			wantCode:  http.StatusMovedPermanently,
+			wantFinal: server.URL + "/a",
+			wantErr:   &StatusError{},
+		},
+		{
+			name:    "max redirects",
+			opts:    []Option{WithMaxRedirects(1)},
+			wantErr: ErrTooManyRedirects,
+		},
+		{
+			name: "policy",
+			opts: []Option{WithRedirectPolicy(func(req *http.Request, via []*http.Request) error {
+				if req.URL.Path == "/c" {
+					return http.ErrUseLastResponse
+				}
+				return nil
+			})},
+			wantCode:      http.StatusFound,
+			wantFinal:     server.URL + "/b",
+			wantRedirects: []Redirect{{URL: server.URL + "/a", StatusCode: http.StatusMovedPermanently}},
+			wantErr:       &StatusError{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(append(tt.opts, WithLogger(NopLogger))...)
+			resp, err := client.NewRequest(GET, server.URL+"/a").Send()
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("Send() error = %v", err)
+				}
+			case *StatusError:
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) {
+					t.Fatalf("Send() error = %v, want a StatusError", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("Send() error = %v, want %v", err, want)
+				}
+				return
+			}
+			if resp.StatusCode != tt.wantCode || resp.FinalURL != tt.wantFinal {
+				t.Errorf("Send() got = %v %v, want %v %v", resp.StatusCode, resp.FinalURL, tt.wantCode, tt.wantFinal)
+			}
+			if len(resp.Redirects) != len(tt.wantRedirects) {
+				t.Fatalf("Redirects got = %v, want %v", resp.Redirects, tt.wantRedirects)
+			}
+			for i := range resp.Redirects {
+				if resp.Redirects[i] != tt.wantRedirects[i] {
+					t.Errorf("Redirects[%d] got = %v, want %v", i, resp.Redirects[i], tt.wantRedirects[i])
+				}
+			}
+		})
+	}
+}
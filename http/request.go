@@ -0,0 +1,284 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	gourl "net/url"
+	"regexp"
+)
+
+// ErrPathParam is returned for path templates with missing or unknown parameters
+var ErrPathParam = errors.New("path parameter")
+
+var pathParamPattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// Request builds a request step by step. The url may hold path parameters like
+// /users/{id}/posts/{postID}, their values are escaped on substitution.
+type Request struct {
+	client     *Client
+	ctx        context.Context
+	method     RequestMethodType
+	url        string
+	pathParams map[string]string
+	query      gourl.Values
+	header     http.Header
+	body       any
+	hasBody    bool
+	rawBody    []byte
+	codec      Codec
+	err        error
+}
+
+// Response is the outcome of a Request
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	// Body is converted to UTF-8 from Charset, so it may differ in length from
+	// the Content-Length of Header. Only Send converts, the bodies of
+	// Request.Stream, SendJSONLines and StatusError.Body are as received.
+	Body []byte
+	// FinalURL is the url that answered after following redirects
+	FinalURL string
+	// Redirects are the 30x hops followed on the way, in order
+	Redirects []Redirect
+	// RequestID is the ID the RequestIDHook sent, "" without the hook
+	RequestID string
+	// Transfer tells how fast the body was read
+	Transfer Transfer
+	// MIMEType is the media type of the Content-Type, or sniffed from the
+	// body without one, like "text/html"
+	MIMEType string
+	// Charset is the canonical name of the charset of the Content-Type, the
+	// WHATWG one browsers use, e.g. "windows-1252" for iso-8859-1
+	Charset string
+
+	codec Codec
+}
+
+// NewRequest starts a request sent with the default Client
+func NewRequest(method RequestMethodType, url string) *Request {
+	return defaultClient.NewRequest(method, url)
+}
+
+// NewRequest starts a request sent with c
+func (c *Client) NewRequest(method RequestMethodType, url string) *Request {
+	return &Request{
+		client:     c,
+		ctx:        context.Background(),
+		method:     method,
+		url:        url,
+		pathParams: make(map[string]string),
+		query:      make(gourl.Values),
+		header:     make(http.Header),
+	}
+}
+
+// WithContext sets the context of the request
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// PathParam sets the value of the {name} placeholder
+func (r *Request) PathParam(name string, value string) *Request {
+	r.pathParams[name] = value
+	return r
+}
+
+// PathParams sets several placeholder values
+func (r *Request) PathParams(params map[string]string) *Request {
+	for name, value := range params {
+		r.pathParams[name] = value
+	}
+	return r
+}
+
+// Query adds a query parameter, repeated keys are kept
+func (r *Request) Query(key string, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// Header adds a request header
+func (r *Request) Header(key string, value string) *Request {
+	r.header.Add(key, value)
+	return r
+}
+
+// Headers adds all values of h to the request headers
+func (r *Request) Headers(h Headers) *Request {
+	for name, values := range h {
+		for _, value := range values {
+			r.header.Add(name, value)
+		}
+	}
+	return r
+}
+
+// Body sets the request body, encoded with the codec of the request
+func (r *Request) Body(body any) *Request {
+	r.body = body
+	r.hasBody = true
+	r.rawBody = nil
+	return r
+}
+
+// WithBodyValidator checks the bodies set with Request.Body before they are
+// encoded, e.g. with validate.Struct. A failure is returned by Build and Send
+// as is and nothing is sent. Raw bodies are not checked.
+func WithBodyValidator(fn func(body any) error) Option {
+	return func(c *Client) {
+		c.bodyValidator = fn
+	}
+}
+
+// RawBody sets the request body as is, without encoding
+func (r *Request) RawBody(body []byte) *Request {
+	r.rawBody = body
+	r.hasBody = false
+	return r
+}
+
+// WithCodec encodes the body with codec instead of the client codec, which is
+// JSON unless the client was created WithCodec
+func (r *Request) WithCodec(codec Codec) *Request {
+	r.codec = codec
+	return r
+}
+
+func (r *Request) getCodec() Codec {
+	if r.codec != nil {
+		return r.codec
+	}
+	return r.client.getCodec()
+}
+
+// URL renders the url with the path parameters substituted and the query added
+func (r *Request) URL() (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	var missing []string
+	used := make(map[string]bool, len(r.pathParams))
+	rendered := pathParamPattern.ReplaceAllStringFunc(r.url, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := r.pathParams[name]
+		if !ok {
+			missing = append(missing, name)
+			return placeholder
+		}
+		used[name] = true
+		return gourl.PathEscape(value)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: missing values for %v in %q", ErrPathParam, missing, r.url)
+	}
+	for name := range r.pathParams {
+		if !used[name] {
+			return "", fmt.Errorf("%w: %q is not in %q", ErrPathParam, name, r.url)
+		}
+	}
+	u, err := gourl.Parse(rendered)
+	if err != nil {
+		return "", err
+	}
+	if len(r.query) > 0 {
+		values := u.Query()
+		for key, vs := range r.query {
+			values[key] = append(values[key], vs...)
+		}
+		u.RawQuery = values.Encode()
+	}
+	return u.String(), nil
+}
+
+// Build returns the *http.Request that Send would send
+func (r *Request) Build() (*http.Request, error) {
+	url, err := r.URL()
+	if err != nil {
+		return nil, err
+	}
+	var payload []byte
+	switch {
+	case r.rawBody != nil:
+		payload = r.rawBody
+	case r.hasBody:
+		if r.client.bodyValidator != nil {
+			if err := r.client.bodyValidator(r.body); err != nil {
+				return nil, err
+			}
+		}
+		if payload, err = r.getCodec().Marshal(r.body); err != nil {
+			return nil, err
+		}
+	}
+	var httpRequest *http.Request
+	if payload != nil {
+		httpRequest, err = http.NewRequestWithContext(r.ctx, string(r.method), url, bytes.NewReader(payload))
+	} else {
+		httpRequest, err = http.NewRequestWithContext(r.ctx, string(r.method), url, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header = r.header.Clone()
+	if r.hasBody && httpRequest.Header.Get("Content-Type") == "" {
+		httpRequest.Header.Set("Content-Type", r.getCodec().ContentType())
+	}
+	return httpRequest, nil
+}
+
+// Send sends the request through the client hooks. Like the package functions
+// it fails with a *StatusError on non 200 responses, the Response is returned
+// in that case too, holding the error body.
+func (r *Request) Send() (*Response, error) {
+	httpRequest, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+	ctx, redirects := withRedirectRecorder(r.ctx)
+	ctx, requestID := withRequestIDRecorder(ctx)
+	ctx, transfer := withTransferRecorder(ctx)
+	code, header, data, err := r.client.do(ctx, httpRequest.WithContext(ctx))
+	if code == -1 {
+		return nil, err
+	}
+	resp := &Response{StatusCode: code, Header: header, FinalURL: httpRequest.URL.String(), Redirects: redirects.hops, RequestID: requestID.get(), Transfer: transfer.get(), codec: r.getCodec()}
+	if redirects.final != "" {
+		resp.FinalURL = redirects.final
+	}
+	resp.Body, _ = data.([]byte)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		resp.Body = statusErr.Body
+	}
+	resp.Body, resp.MIMEType, resp.Charset = decodeCharset(header, resp.Body)
+	return resp, err
+}
+
+// JSON decodes the response body into v
+func (r *Response) JSON(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Decode decodes the response body with the codec matching its Content-Type,
+// falling back to the codec of the request
+func (r *Response) Decode(v any) error {
+	codec, ok := CodecFor(r.Header.Get("Content-Type"))
+	if !ok {
+		codec = r.codec
+	}
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return codec.Unmarshal(r.Body, v)
+}
+
+// String returns the response body as a string
+func (r *Response) String() string {
+	return string(r.Body)
+}
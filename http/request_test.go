@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Stellar1999/gotool/validate"
+)
+
+func TestRequestURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *Request
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "path params",
+			req:  NewRequest(GET, "https://api.example.com/users/{id}/posts/{postID}").PathParam("id", "42").PathParam("postID", "7"),
+			want: "https://api.example.com/users/42/posts/7",
+		},
+		{
+			name: "escaped",
+			req:  NewRequest(GET, "https://api.example.com/files/{name}").PathParam("name", "a b/../c?d"),
+			want: "https://api.example.com/files/a%20b%2F..%2Fc%3Fd",
+		},
+		{
+			name: "query",
+			req:  NewRequest(GET, "https://api.example.com/search?q=go").Query("tag", "a").Query("tag", "b"),
+			want: "https://api.example.com/search?q=go&tag=a&tag=b",
+		},
+		{
+			name:    "missing param",
+			req:     NewRequest(GET, "https://api.example.com/users/{id}"),
+			wantErr: true,
+		},
+		{
+			name:    "unknown param",
+			req:     NewRequest(GET, "https://api.example.com/users").PathParams(map[string]string{"id": "1"}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.req.URL()
+			if tt.wantErr {
+				if !errors.Is(err, ErrPathParam) {
+					t.Errorf("URL() error got = %v, want %v", err, ErrPathParam)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("URL() got = %v %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"no such user"}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.EscapedPath() + `","body":` + string(body) + `}`))
+	}))
+	defer server.Close()
+
+	resp, err := NewClient().NewRequest(POST, server.URL+"/users/{id}").
+		WithContext(context.Background()).
+		PathParam("id", "a/b").
+		Body(map[string]int{"n": 1}).
+		Send()
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	var got struct {
+		Path string
+		Body map[string]int
+	}
+	if err := resp.JSON(&got); err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	if got.Path != "/users/a%2Fb" || got.Body["n"] != 1 || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Send() got = %+v %v, want escaped path and JSON body", got, resp.Header)
+	}
+
+	resp, err = NewRequest(GET, server.URL+"/missing").Send()
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || resp == nil || resp.StatusCode != http.StatusNotFound || resp.String() != `{"error":"no such user"}` {
+		t.Errorf("Send() got = %+v %v, want 404 response with body", resp, err)
+	}
+}
+
+func TestRequestBodyValidator(t *testing.T) {
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+	}))
+	defer server.Close()
+
+	type user struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	client := NewClient(WithLogger(NopLogger), WithBodyValidator(validate.Struct))
+	_, err := client.NewRequest(POST, server.URL).Body(user{Email: "nope"}).Send()
+	var errs validate.Errors
+	if !errors.As(err, &errs) || errs[0].Field != "email" || sent != 0 {
+		t.Errorf("Send() invalid body got = %v after %v requests, want validate.Errors and nothing sent", err, sent)
+	}
+	if _, err := client.NewRequest(POST, server.URL).Body(user{Email: "a@example.com"}).Send(); err != nil || sent != 1 {
+		t.Errorf("Send() valid body got = %v after %v requests", err, sent)
+	}
+	if _, err := client.NewRequest(POST, server.URL).RawBody([]byte("{}")).Send(); err != nil || sent != 2 {
+		t.Errorf("Send() raw body got = %v, want it not validated", err)
+	}
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/Stellar1999/gotool/idgen"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// RequestIDHeader carries the ID correlating a request across services
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns ctx carrying id, the RequestIDHook sends it
+// with the requests sent with ctx
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID of ctx, "" without one. Server
+// code gets the ID of the incoming request there, e.g. from the RequestID
+// middleware of httpserver, and the After hooks the ID a request was sent
+// with.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type requestIDConfig struct {
+	header   string
+	generate func() string
+}
+
+type RequestIDOption = opt.Option[requestIDConfig]
+
+// WithRequestIDHeader sends the ID in header instead of RequestIDHeader
+func WithRequestIDHeader(header string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.header = header
+	}
+}
+
+// WithRequestIDGenerator makes new IDs with fn instead of idgen.UUIDv7
+func WithRequestIDGenerator(fn func() string) RequestIDOption {
+	return func(c *requestIDConfig) {
+		c.generate = fn
+	}
+}
+
+// RequestIDHook sets a request ID header on requests without one, so a
+// request can be followed through the logs of every service it reaches.
+// Install it with AddHook. The ID is the one of the context, set by
+// ContextWithRequestID or a server middleware, or else a new one shared by
+// the retries of the request. Response.RequestID tells which ID was sent.
+type RequestIDHook struct {
+	config requestIDConfig
+}
+
+func NewRequestIDHook(opts ...RequestIDOption) *RequestIDHook {
+	h := &RequestIDHook{config: requestIDConfig{header: RequestIDHeader, generate: idgen.UUIDv7}}
+	opt.Apply(&h.config, opts...)
+	return h
+}
+
+func (h *RequestIDHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	rec, _ := ctx.Value(requestIDRecorderKey{}).(*requestIDRecorder)
+	id := req.Header.Get(h.config.header)
+	if id == "" {
+		id = RequestIDFromContext(ctx)
+	}
+	if id == "" && rec != nil {
+		id = rec.get()
+	}
+	if id == "" {
+		id = h.config.generate()
+	}
+	req.Header.Set(h.config.header, id)
+	if rec != nil {
+		rec.set(id)
+	}
+	return ContextWithRequestID(ctx, id), nil
+}
+
+func (h *RequestIDHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	return ctx, nil
+}
+
+type requestIDRecorderKey struct{}
+
+// requestIDRecorder keeps the ID a Request was sent with, for its Response
+// and its retries
+type requestIDRecorder struct {
+	mu sync.Mutex
+	id string
+}
+
+func (r *requestIDRecorder) get() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.id
+}
+
+func (r *requestIDRecorder) set(id string) {
+	r.mu.Lock()
+	r.id = id
+	r.mu.Unlock()
+}
+
+func withRequestIDRecorder(ctx context.Context) (context.Context, *requestIDRecorder) {
+	rec := &requestIDRecorder{}
+	return context.WithValue(ctx, requestIDRecorderKey{}, rec), rec
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Stellar1999/gotool/retry"
+)
+
+func TestRequestIDHook(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	var calls int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get(RequestIDHeader))
+		mu.Unlock()
+		if r.URL.Path == "/flaky" && atomic.AddInt64(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	handle := AddHook(NewRequestIDHook())
+	defer handle.Remove()
+	client := NewClient(WithRetry(retry.Backoff(retry.Constant(0))), WithLogger(NopLogger))
+
+	resp, err := client.NewRequest(GET, server.URL).Send()
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(resp.RequestID) != 36 || seen[0] != resp.RequestID {
+		t.Errorf("Response.RequestID got = %q, server saw %q", resp.RequestID, seen[0])
+	}
+
+	ctx := ContextWithRequestID(context.Background(), "inbound-1")
+	resp, err = client.NewRequest(GET, server.URL).WithContext(ctx).Send()
+	if err != nil || resp.RequestID != "inbound-1" || seen[1] != "inbound-1" {
+		t.Errorf("Send() got = %v %v, server saw %q, want the ID of the context", resp, err, seen[1])
+	}
+
+	resp, err = client.NewRequest(GET, server.URL).Header(RequestIDHeader, "explicit").Send()
+	if err != nil || resp.RequestID != "explicit" || seen[2] != "explicit" {
+		t.Errorf("Send() got = %v %v, want the ID set on the request kept", resp, err)
+	}
+
+	resp, err = client.NewRequest(GET, server.URL+"/flaky").Send()
+	if err != nil || len(seen) != 5 || seen[3] != seen[4] || seen[4] != resp.RequestID {
+		t.Errorf("Send() retried with IDs %q, want one ID for all attempts", seen[3:])
+	}
+}
+
+func TestRequestIDHookOptions(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Correlation-Id")
+	}))
+	defer server.Close()
+	handle := AddHook(NewRequestIDHook(WithRequestIDHeader("X-Correlation-Id"), WithRequestIDGenerator(func() string { return "fixed" })))
+	defer handle.Remove()
+
+	var after string
+	afterHandle := AddHook(afterHook(func(ctx context.Context) { after = RequestIDFromContext(ctx) }))
+	defer afterHandle.Remove()
+
+	if _, err := NewClient().NewRequest(GET, server.URL).Send(); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got != "fixed" || after != "fixed" {
+		t.Errorf("RequestIDHook sent %q, After saw %q, want fixed", got, after)
+	}
+}
+
+// afterHook calls its func with the ctx of After
+type afterHook func(ctx context.Context)
+
+func (h afterHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h afterHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	h(ctx)
+	return ctx, nil
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Stellar1999/gotool/ratelimit"
+	"github.com/Stellar1999/gotool/retry"
+	"github.com/Stellar1999/gotool/status"
+)
+
+// WithRetry sends a request again when IsRetryable holds for its error,
+// with the attempts and delays of opts on top of the retry defaults; pass
+// retry.RetryIf to change which errors are retried. Only idempotent requests
+// are retried, and POST or PATCH requests carrying an Idempotency-Key header
+// or covered by an installed IdempotencyHook. Requests whose body cannot be
+// sent again are not retried. Each attempt runs the hooks, and the attempts
+// share an IdempotencyScope so they send the same Idempotency-Key. Within a
+// WithBudget, each attempt gets an equal share of the time left.
+func WithRetry(opts ...retry.Option) Option {
+	return func(c *Client) {
+		c.retryOpts = append([]retry.Option{retry.RetryIf(IsRetryable)}, opts...)
+	}
+}
+
+// IsRetryable reports whether sending a request again may succeed after err:
+// for transport errors, timeouts, 429 and 5xx responses but 501. Errors of
+// the request itself or of the client configuration are not retryable.
+func IsRetryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return status.IsRetryable(statusErr.Code)
+	}
+	for _, permanent := range []error{context.Canceled, ErrClientClosed, ErrForbiddenHost, ErrTooManyRedirects, ErrResponseTooLarge, ErrCodec, ErrPathParam, ratelimit.ErrLimitExceeded} {
+		if errors.Is(err, permanent) {
+			return false
+		}
+	}
+	return err != nil
+}
+
+// doRetry sends req with doOnce until it succeeds or retry gives up
+func (c *Client) doRetry(ctx context.Context, req *http.Request) (int, http.Header, any, error) {
+	if !retriable(req) {
+		return c.doOnce(ctx, req)
+	}
+	ctx = IdempotencyScope(ctx)
+	var code int
+	var header http.Header
+	var data any
+	var err error
+	attempt := req
+	attemptsLeft := retry.MaxAttempts(c.retryOpts...)
+	retryErr := retry.Do(ctx, func() error {
+		if attempt == nil {
+			next, ok := replayable(req)
+			if !ok {
+				return retry.Unrecoverable(err)
+			}
+			attempt = next
+		}
+		attemptCtx, cancel := budgetAttempt(ctx, attemptsLeft)
+		defer cancel()
+		attemptsLeft--
+		code, header, data, err = c.doOnce(attemptCtx, attempt)
+		attempt = nil
+		return err
+	}, c.retryOpts...)
+	if retryErr != err {
+		// ctx was done between attempts
+		return -1, nil, nil, contextError(retryErr)
+	}
+	return code, header, data, err
+}
+
+// retriable reports whether sending req twice is safe
+func retriable(req *http.Request) bool {
+	if idempotent(req.Method) || req.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	for _, hook := range hooks() {
+		if h, ok := hook.(*IdempotencyHook); ok && h.config.methods[req.Method] {
+			return true
+		}
+	}
+	return false
+}
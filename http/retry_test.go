@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/retry"
+)
+
+func TestWithRetry(t *testing.T) {
+	var mu sync.Mutex
+	var failures int
+	var bodies, keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	reset := func(n int) {
+		failures, bodies, keys = n, nil, nil
+	}
+
+	client := NewClient(WithLogger(NopLogger), WithRetry(retry.Attempts(3), retry.Backoff(retry.Constant(time.Millisecond))))
+	reset(2)
+	resp, err := client.NewRequest(PUT, server.URL).Body("x").Send()
+	if err != nil || resp.String() != "ok" || len(bodies) != 3 || bodies[2] != `"x"` {
+		t.Errorf("Send() got = %v %v after %q, want ok after 3 attempts with the body", resp, err, bodies)
+	}
+
+	reset(5)
+	resp, err = client.NewRequest(GET, server.URL).Send()
+	if !errors.As(err, new(*StatusError)) || resp.StatusCode != http.StatusServiceUnavailable || len(bodies) != 3 {
+		t.Errorf("Send() got = %v %v after %v attempts, want the last 503 after 3", resp, err, len(bodies))
+	}
+
+	// POST is only retried when the attempts share an Idempotency-Key
+	reset(1)
+	if _, err := client.NewRequest(POST, server.URL).Body(1).Send(); err == nil || len(bodies) != 1 {
+		t.Errorf("POST got = %v after %v attempts, want one", err, len(bodies))
+	}
+	handle := AddHook(NewIdempotencyHook())
+	defer handle.Remove()
+	reset(1)
+	if _, err := client.NewRequest(POST, server.URL).Body(1).Send(); err != nil || len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("POST got = %v with keys %q, want 2 attempts with one key", err, keys)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	slow := NewClient(WithLogger(NopLogger), WithRetry(retry.Backoff(retry.Constant(time.Hour))))
+	reset(1)
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := slow.NewRequest(GET, server.URL).WithContext(ctx).Send(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Send() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&StatusError{Code: http.StatusTooManyRequests}, true},
+		{&StatusError{Code: http.StatusBadGateway}, true},
+		{&StatusError{Code: http.StatusNotImplemented}, false},
+		{&StatusError{Code: http.StatusNotFound}, false},
+		{&PreconditionError{StatusError: &StatusError{Code: http.StatusPreconditionFailed}}, false},
+		{errors.New("connection reset by peer"), true},
+		{contextError(context.DeadlineExceeded), true},
+		{context.Canceled, false},
+		{ErrForbiddenHost, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) got = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigV4Signer(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	signer := &SigV4Signer{
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:    "us-east-1",
+		Service:   "service",
+		Now:       func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+	if err := signer.Sign(req, nil); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Sign() Authorization got = %v, want %v", got, want)
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+	now := func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	sign := func(method, url, body string, header map[string]string) string {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		signer := &HMACSigner{KeyID: "k1", Secret: []byte("secret"), SignedHeaders: []string{"X-Tenant"}, Now: now}
+		if err := signer.Sign(req, []byte(body)); err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		return req.Header.Get("Authorization")
+	}
+	base := sign("POST", "https://api.example.com/a?b=2&a=1", `{"x":1}`, map[string]string{"X-Tenant": "t1"})
+	if !strings.HasPrefix(base, "HMAC-SHA256 KeyId=k1, SignedHeaders=host;x-date;x-tenant, Signature=") {
+		t.Fatalf("Sign() Authorization got = %v", base)
+	}
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		header map[string]string
+		same   bool
+	}{
+		{name: "query order", method: "POST", url: "https://api.example.com/a?a=1&b=2", body: `{"x":1}`, header: map[string]string{"X-Tenant": "t1"}, same: true},
+		{name: "header spaces", method: "POST", url: "https://api.example.com/a?a=1&b=2", body: `{"x":1}`, header: map[string]string{"X-Tenant": "  t1 "}, same: true},
+		{name: "body", method: "POST", url: "https://api.example.com/a?a=1&b=2", body: `{"x":2}`, header: map[string]string{"X-Tenant": "t1"}},
+		{name: "signed header", method: "POST", url: "https://api.example.com/a?a=1&b=2", body: `{"x":1}`, header: map[string]string{"X-Tenant": "t2"}},
+		{name: "method", method: "PUT", url: "https://api.example.com/a?a=1&b=2", body: `{"x":1}`, header: map[string]string{"X-Tenant": "t1"}},
+		{name: "unsigned header", method: "POST", url: "https://api.example.com/a?a=1&b=2", body: `{"x":1}`, header: map[string]string{"X-Tenant": "t1", "X-Other": "o"}, same: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sign(tt.method, tt.url, tt.body, tt.header)
+			if (got == base) != tt.same {
+				t.Errorf("Sign() got = %v, base %v, want same %v", got, base, tt.same)
+			}
+		})
+	}
+}
+
+func TestSigningHook(t *testing.T) {
+	var gotBody, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotAuth = string(body), r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	defer AddHook(NewSigningHook(&HMACSigner{KeyID: "k1", Secret: []byte("secret")})).Remove()
+
+	if _, _, _, err := NewClient().PostWithContext(context.Background(), server.URL, nil, nil, map[string]int{"a": 1}); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if gotBody != `{"a":1}` || !strings.HasPrefix(gotAuth, "HMAC-SHA256 KeyId=k1") {
+		t.Errorf("signed request got body %q auth %q", gotBody, gotAuth)
+	}
+}
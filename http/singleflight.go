@@ -0,0 +1,145 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Stellar1999/gotool/syncx"
+)
+
+// singleflightHeaders always take part in the key, responses differ per caller
+var singleflightHeaders = []string{"Authorization", "Cookie", "Accept", "Accept-Encoding", "Range"}
+
+// WithSingleflight lets concurrent identical GET and HEAD requests share one
+// upstream call, every caller gets its own copy of the response. Requests are
+// identical when their url and the Authorization, Cookie, Accept,
+// Accept-Encoding, Range and varyHeaders headers match. The shared response
+// is read in full within WithMaxResponseBytes, streamed requests of
+// Request.Stream are never shared. Stats counts the requests that were
+// answered by another one as singleflight_shared_total.
+func WithSingleflight(varyHeaders ...string) Option {
+	return func(c *Client) {
+		c.singleflight = &singleflight{headers: append(append([]string{}, singleflightHeaders...), varyHeaders...)}
+	}
+}
+
+type singleflight struct {
+	headers []string
+}
+
+// applySingleflight wraps the transport last, so a shared call is hedged once
+func (c *Client) applySingleflight() {
+	if c.singleflight == nil {
+		return
+	}
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient := *c.httpClient
+	httpClient.Transport = &singleflightTransport{
+		base:    base,
+		headers: c.singleflight.headers,
+		stats:   &c.stats,
+		client:  c,
+	}
+	c.httpClient = &httpClient
+}
+
+// flightResult is a response read in full, so every caller gets a copy
+type flightResult struct {
+	resp *http.Response
+	body []byte
+}
+
+type singleflightTransport struct {
+	base    http.RoundTripper
+	headers []string
+	stats   *clientStats
+	client  *Client
+	group   syncx.Singleflight[*flightResult]
+}
+
+type streamKey struct{}
+
+// withStream marks the ctx of a request whose body is read by the caller
+func withStream(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamKey{}, true)
+}
+
+func (t *singleflightTransport) key(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteByte(' ')
+	b.WriteString(req.URL.String())
+	for _, name := range t.headers {
+		b.WriteByte('\n')
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(req.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+func (t *singleflightTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead || req.Context().Value(streamKey{}) != nil {
+		return t.base.RoundTrip(req)
+	}
+	result, shared, err := t.group.Do(req.Context(), t.key(req), func() (*flightResult, error) {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		var body []byte
+		if resp.StatusCode == http.StatusOK {
+			body, err = t.client.readBody(resp)
+		} else {
+			// error bodies are cut at the limit rather than failing
+			body, err = io.ReadAll(t.client.limitBody(resp.Body))
+			if errors.Is(err, ErrResponseTooLarge) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &flightResult{resp: resp, body: body}, nil
+	})
+	if shared && errors.Is(err, context.Canceled) && req.Context().Err() == nil {
+		// the caller that sent the request gave up, this one did not
+		return t.base.RoundTrip(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		atomic.AddInt64(&t.stats.shared, 1)
+	}
+	return result.copy(req), nil
+}
+
+// copy returns the response with its own header and body for req
+func (c *flightResult) copy(req *http.Request) *http.Response {
+	resp := *c.resp
+	resp.Header = c.resp.Header.Clone()
+	resp.Trailer = c.resp.Trailer.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(c.body))
+	if req.Method != http.MethodHead {
+		resp.ContentLength = int64(len(c.body))
+	}
+	resp.Request = req
+	return &resp
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *singleflightTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithSingleflight(t *testing.T) {
+	var requests int64
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		<-release
+		w.Header().Set("X-Tenant", r.Header.Get("X-Tenant"))
+		_, _ = w.Write([]byte("config"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithSingleflight("X-Tenant"), WithLogger(NopLogger))
+	send := func(tenant string) (*Response, error) {
+		return client.NewRequest(GET, server.URL+"/config").Header("X-Tenant", tenant).Send()
+	}
+
+	var wg sync.WaitGroup
+	responses := make([]*Response, 6)
+	for i := range responses {
+		wg.Add(1)
+		tenant := "a"
+		if i == 5 {
+			tenant = "b"
+		}
+		go func(i int) {
+			defer wg.Done()
+			resp, err := send(tenant)
+			if err != nil {
+				t.Errorf("Send() error = %v", err)
+				return
+			}
+			responses[i] = resp
+		}(i)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt64(&requests); got != 2 {
+		t.Errorf("upstream requests got = %v, want one per tenant", got)
+	}
+	for i, resp := range responses {
+		want := "a"
+		if i == 5 {
+			want = "b"
+		}
+		if resp == nil || resp.String() != "config" || resp.Header.Get("X-Tenant") != want {
+			t.Errorf("response %d got = %+v, want tenant %v", i, resp, want)
+		}
+	}
+	if got := client.Stats()["singleflight_shared_total"]; got != 4 {
+		t.Errorf("Stats() singleflight_shared_total got = %v, want 4", got)
+	}
+
+	// later requests are sent again
+	if _, err := send("a"); err != nil || atomic.LoadInt64(&requests) != 3 {
+		t.Errorf("Send() after the flight got = %v requests, %v", atomic.LoadInt64(&requests), err)
+	}
+}
+
+func TestWithSingleflightLimits(t *testing.T) {
+	var requests int64
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	unblock := func() { releaseOnce.Do(func() { close(release) }) }
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if r.URL.Path == "/missing" {
+			http.Error(w, strings.Repeat("e", 100), http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/stream" {
+			// the rest of the body waits for the caller to read the start
+			_, _ = w.Write([]byte(strings.Repeat("x", 5)))
+			w.(http.Flusher).Flush()
+			<-release
+		}
+		_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+	defer unblock()
+	client := NewClient(WithSingleflight(), WithMaxResponseBytes(10), WithLogger(NopLogger))
+
+	if _, err := client.NewRequest(GET, server.URL).Send(); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Send() error = %v, want %v", err, ErrResponseTooLarge)
+	}
+	var statusErr *StatusError
+	if _, err := client.NewRequest(GET, server.URL+"/missing").Send(); !errors.As(err, &statusErr) || len(statusErr.Body) != 10 {
+		t.Errorf("Send() error = %v, want a 404 with the body cut at 10 bytes", err)
+	}
+
+	// streamed bodies are read by the caller, not buffered by the flight
+	resp, err := client.NewRequest(GET, server.URL+"/stream").Stream()
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	defer resp.Body.Close()
+	start := make([]byte, 5)
+	if _, err := io.ReadFull(resp.Body, start); err != nil {
+		t.Fatalf("Stream() body error = %v", err)
+	}
+	unblock()
+	rest, err := io.ReadAll(resp.Body)
+	body := append(start, rest...)
+	if !errors.Is(err, ErrResponseTooLarge) || len(body) != 10 {
+		t.Errorf("Stream() body got = %d bytes %v, want 10 and %v", len(body), err, ErrResponseTooLarge)
+	}
+	if got := client.Stats()["singleflight_shared_total"]; got != 0 {
+		t.Errorf("Stats() singleflight_shared_total got = %v, want 0", got)
+	}
+}
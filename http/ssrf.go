@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	gourl "net/url"
+	"strings"
+)
+
+// ErrForbiddenHost is returned when a request targets a host outside the
+// allowlist or an address blocked by WithBlockPrivateIPs
+var ErrForbiddenHost = errors.New("forbidden host")
+
+// 100.64.0.0/10 is shared carrier-grade NAT space, net.IP.IsPrivate leaves it out
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// WithAllowedHosts only lets requests and redirects reach the given hosts.
+// A pattern is a host name like "api.example.com" or "*.example.com", which
+// matches subdomains but not example.com itself.
+func WithAllowedHosts(patterns ...string) Option {
+	return func(c *Client) {
+		for _, pattern := range patterns {
+			c.allowedHosts = append(c.allowedHosts, strings.ToLower(pattern))
+		}
+	}
+}
+
+// WithBlockPrivateIPs refuses connections to loopback, private, link-local,
+// unspecified and multicast addresses. Addresses are checked after DNS
+// resolution and the checked address is dialed, so DNS rebinding cannot slip
+// through. Behind a proxy the proxy address is checked.
+func WithBlockPrivateIPs() Option {
+	return func(c *Client) {
+		c.blockPrivateIPs = true
+	}
+}
+
+// applyHostGuard installs the checks on a copy of the http.Client, it runs
+// after all options so the order of WithTransport and the guard options does not matter
+func (c *Client) applyHostGuard() {
+	if len(c.allowedHosts) == 0 && !c.blockPrivateIPs {
+		return
+	}
+	httpClient := *c.httpClient
+	if len(c.allowedHosts) > 0 {
+		// set by applyRedirectPolicy
+		next := httpClient.CheckRedirect
+		httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if err := c.checkHost(req.URL); err != nil {
+				return err
+			}
+			return next(req, via)
+		}
+	}
+	if c.blockPrivateIPs {
+		transport, ok := httpClient.Transport.(*http.Transport)
+		if httpClient.Transport == nil {
+			transport, ok = http.DefaultTransport.(*http.Transport)
+		}
+		if ok {
+			transport = transport.Clone()
+			lookup := net.DefaultResolver.LookupIPAddr
+			if c.dns != nil {
+				lookup = c.dns.lookup
+			}
+			transport.DialContext = guardDial(transport.DialContext, lookup)
+			httpClient.Transport = transport
+		} else {
+			c.getLogger().Warn("WithBlockPrivateIPs needs a *http.Transport, private addresses are not blocked")
+		}
+	}
+	c.httpClient = &httpClient
+}
+
+// checkHost matches the url host against the allowlist
+func (c *Client) checkHost(u *gourl.URL) error {
+	if len(c.allowedHosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range c.allowedHosts {
+		if pattern == host || (strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not allowed", ErrForbiddenHost, host)
+}
+
+// guardDial resolves the address itself, rejects forbidden ips and dials a checked one
+func guardDial(next func(ctx context.Context, network string, addr string) (net.Conn, error), lookup func(ctx context.Context, host string) ([]net.IPAddr, error)) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if next == nil {
+		next = (&net.Dialer{}).DialContext
+	}
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if forbiddenIP(ip.IP) {
+				return nil, fmt.Errorf("%w: %s resolves to %s", ErrForbiddenHost, host, ip.IP)
+			}
+		}
+		var dialErr error
+		for _, ip := range ips {
+			conn, err := next(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+}
+
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestForbiddenIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "127.0.0.1", want: true},
+		{ip: "10.1.2.3", want: true},
+		{ip: "172.16.0.1", want: true},
+		{ip: "192.168.1.1", want: true},
+		{ip: "169.254.169.254", want: true},
+		{ip: "100.64.0.1", want: true},
+		{ip: "0.0.0.0", want: true},
+		{ip: "::1", want: true},
+		{ip: "fd00::1", want: true},
+		{ip: "fe80::1", want: true},
+		{ip: "::ffff:127.0.0.1", want: true},
+		{ip: "8.8.8.8", want: false},
+		{ip: "2606:4700::1111", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := forbiddenIP(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("forbiddenIP() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithAllowedHosts(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	// the redirect goes to the same server under the name localhost
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(target.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	tests := []struct {
+		name     string
+		patterns []string
+		url      string
+		wantErr  bool
+	}{
+		{name: "allowed", patterns: []string{"127.0.0.1"}, url: target.URL},
+		{name: "not allowed", patterns: []string{"api.example.com"}, url: target.URL, wantErr: true},
+		{name: "redirect not allowed", patterns: []string{"127.0.0.1"}, url: redirect.URL, wantErr: true},
+		{name: "redirect allowed", patterns: []string{"127.0.0.1", "localhost"}, url: redirect.URL},
+		{name: "wildcard", patterns: []string{"*.example.com"}, url: "http://example.com/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(WithLogger(NopLogger), WithAllowedHosts(tt.patterns...))
+			_, _, _, err := client.Get(tt.url, nil, nil)
+			if got := errors.Is(err, ErrForbiddenHost); got != tt.wantErr {
+				t.Errorf("Get() error = %v, want forbidden %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithBlockPrivateIPs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "default transport", opts: []Option{WithBlockPrivateIPs()}},
+		{name: "transport set after the guard", opts: []Option{WithBlockPrivateIPs(), WithTransport(&http.Transport{})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(append(tt.opts, WithLogger(NopLogger))...)
+			if _, _, _, err := client.Get(server.URL, nil, nil); !errors.Is(err, ErrForbiddenHost) {
+				t.Errorf("Get() error = %v, want %v", err, ErrForbiddenHost)
+			}
+		})
+	}
+	if _, _, _, err := NewClient().Get(server.URL, nil, nil); err != nil {
+		t.Errorf("Get() without guard error = %v", err)
+	}
+}
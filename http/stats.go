@@ -0,0 +1,72 @@
+package http
+
+import "sync/atomic"
+
+// clientStats counts the requests going through a Client
+type clientStats struct {
+	requests int64
+	failures int64
+	inFlight int64
+	// hedges counts the copies sent by WithHedging, hedgeWins those answering first
+	hedges    int64
+	hedgeWins int64
+	// shared counts the requests answered by an identical one with WithSingleflight
+	shared int64
+	// rateLimitWaits counts the requests delayed by WithRateLimit or WithHostRateLimit
+	rateLimitWaits int64
+}
+
+func (s *clientStats) begin() {
+	atomic.AddInt64(&s.requests, 1)
+	atomic.AddInt64(&s.inFlight, 1)
+}
+
+func (s *clientStats) end(err error) {
+	atomic.AddInt64(&s.inFlight, -1)
+	if err != nil {
+		atomic.AddInt64(&s.failures, 1)
+	}
+}
+
+// Stats implements stats.Stats. Failures count transport errors and non-200
+// responses, the conns_ values are those of PoolStats. With WithDNSCache
+// dns_hits and dns_misses count the lookups, with WithHedging hedges_total
+// and hedge_wins_total the copies sent and the requests they won, with
+// WithSingleflight singleflight_shared_total the requests sharing a call,
+// with rate limits rate_limit_waits_total the requests that waited, with
+// WithFailover failovers_total the switches of base URL.
+func (c *Client) Stats() map[string]float64 {
+	pool := c.PoolStats()
+	stats := map[string]float64{
+		"requests_total": float64(atomic.LoadInt64(&c.stats.requests)),
+		"failures_total": float64(atomic.LoadInt64(&c.stats.failures)),
+		"in_flight":      float64(atomic.LoadInt64(&c.stats.inFlight)),
+		"conns_open":     float64(pool.Open),
+		"conns_active":   float64(pool.Active),
+		"conns_idle":     float64(pool.Idle),
+	}
+	if c.hedging.maxExtra > 0 {
+		stats["hedges_total"] = float64(atomic.LoadInt64(&c.stats.hedges))
+		stats["hedge_wins_total"] = float64(atomic.LoadInt64(&c.stats.hedgeWins))
+	}
+	if c.singleflight != nil {
+		stats["singleflight_shared_total"] = float64(atomic.LoadInt64(&c.stats.shared))
+	}
+	if c.rateLimit.enabled() {
+		stats["rate_limit_waits_total"] = float64(atomic.LoadInt64(&c.stats.rateLimitWaits))
+	}
+	if c.failover.transport != nil {
+		stats["failovers_total"] = float64(atomic.LoadInt64(&c.failover.transport.failovers))
+	}
+	if c.dns != nil {
+		stats["dns_hits"] = float64(atomic.LoadInt64(&c.dns.hits))
+		stats["dns_misses"] = float64(atomic.LoadInt64(&c.dns.misses))
+	}
+	return stats
+}
+
+// Stats returns the counters of the client behind the package functions,
+// register it with stats.Register("http", stats.Func(http.Stats)).
+func Stats() map[string]float64 {
+	return defaultClient.Stats()
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithLogger(NopLogger))
+	_, _, _, _ = client.Get(server.URL, nil, nil)
+	_, _, _, _ = client.Get(server.URL+"/fail", nil, nil)
+	_, _, _, _ = client.Get("http://127.0.0.1:1", nil, nil)
+
+	want := map[string]float64{"requests_total": 3, "failures_total": 2, "in_flight": 0}
+	got := client.Stats()
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Stats()[%s] got = %v, want %v", k, got[k], v)
+		}
+	}
+}
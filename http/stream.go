@@ -0,0 +1,102 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrResponseTooLarge is returned once a response body exceeds the limit set
+// by WithMaxResponseBytes
+var ErrResponseTooLarge = errors.New("response too large")
+
+// WithMaxResponseBytes stops reading response bodies after n bytes and fails
+// the request with ErrResponseTooLarge, so a misbehaving server can't exhaust
+// the memory of the client. Bodies of error responses are cut to n bytes
+// instead. It applies to streamed bodies as well, 0 means no limit.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *Client) {
+		c.maxResponseBytes = n
+	}
+}
+
+func tooLarge(max int64) error {
+	return fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, max)
+}
+
+// limitBody returns body failing after maxResponseBytes
+func (c *Client) limitBody(body io.ReadCloser) io.ReadCloser {
+	if c.maxResponseBytes <= 0 {
+		return body
+	}
+	return &limitedBody{ReadCloser: body, remaining: c.maxResponseBytes, max: c.maxResponseBytes}
+}
+
+// readBody reads the body of resp within maxResponseBytes, a larger
+// Content-Length fails without reading
+func (c *Client) readBody(resp *http.Response) ([]byte, error) {
+	if c.maxResponseBytes > 0 && resp.ContentLength > c.maxResponseBytes {
+		return nil, tooLarge(c.maxResponseBytes)
+	}
+	return io.ReadAll(c.limitBody(resp.Body))
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	max       int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, tooLarge(b.max)
+	}
+	// one byte more than allowed tells a body at the limit from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = -1
+		return n, tooLarge(b.max)
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// Stream sends the request like Send but returns the response with its body
+// unread, for downloads and other large responses. Close the body, the
+// request counts as in flight for Client.Close until then. Non 200 responses
+// fail with a *StatusError like Send, their body is read and the response is
+// returned too, but for 206 answering a request with a Range header. The
+// hooks run with nil response data. The timeout of the http.Client does not
+// apply while the body is read, bound it with the ctx of WithContext.
+func (r *Request) Stream() (*http.Response, error) {
+	httpRequest, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.doStream(r.ctx, r.client.httpClient, httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	partial := resp.StatusCode == http.StatusPartialContent && httpRequest.Header.Get("Range") != ""
+	if resp.StatusCode != http.StatusOK && !partial {
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		statusErr := &StatusError{Code: resp.StatusCode, Body: body}
+		if resp.StatusCode == http.StatusPreconditionFailed {
+			return resp, &PreconditionError{StatusError: statusErr, ETag: resp.Header.Get("ETag")}
+		}
+		return resp, statusErr
+	}
+	if c := r.client; c.maxResponseBytes > 0 && resp.ContentLength > c.maxResponseBytes {
+		_ = resp.Body.Close()
+		return nil, tooLarge(c.maxResponseBytes)
+	}
+	return resp, nil
+}
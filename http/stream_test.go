@@ -0,0 +1,213 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxResponseBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		if r.URL.Query().Get("chunked") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(size))
+		}
+		if status, _ := strconv.Atoi(r.URL.Query().Get("status")); status != 0 {
+			w.WriteHeader(status)
+		}
+		_, _ = io.WriteString(w, strings.Repeat("x", size))
+	}))
+	defer server.Close()
+	client := NewClient(WithMaxResponseBytes(16))
+
+	tests := []struct {
+		name    string
+		query   map[string]string
+		wantLen int
+		wantErr error
+	}{
+		{"within limit", map[string]string{"size": "16"}, 16, nil},
+		{"content length over limit", map[string]string{"size": "17"}, 0, ErrResponseTooLarge},
+		{"chunked over limit", map[string]string{"size": "1000", "chunked": "1"}, 0, ErrResponseTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, data, err := client.Get(server.URL, nil, tt.query)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get() error got = %v, want %v", err, tt.wantErr)
+			}
+			body, _ := data.([]byte)
+			if len(body) != tt.wantLen {
+				t.Errorf("Get() body length got = %v, want %v", len(body), tt.wantLen)
+			}
+		})
+	}
+
+	_, _, _, err := client.Get(server.URL, nil, map[string]string{"size": "1000", "status": "500"})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || len(statusErr.Body) != 16 {
+		t.Errorf("Get() error got = %v, want a *StatusError with the body cut at 16 bytes", err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.Error(w, "no such file", http.StatusNotFound)
+			return
+		}
+		for i := 0; i < 4; i++ {
+			_, _ = io.WriteString(w, "chunk\n")
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	resp, err := client.NewRequest(GET, server.URL+"/file").Stream()
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if client.InFlight() != 1 {
+		t.Errorf("InFlight() got = %v, want 1 until the body is closed", client.InFlight())
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil || string(body) != strings.Repeat("chunk\n", 4) {
+		t.Errorf("Stream() body got = %q %v", body, err)
+	}
+	if client.InFlight() != 0 {
+		t.Errorf("InFlight() got = %v after Close, want 0", client.InFlight())
+	}
+
+	resp, err = client.NewRequest(GET, server.URL+"/missing").Stream()
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusNotFound || resp == nil {
+		t.Fatalf("Stream() error got = %v, want a *StatusError and the response", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), "no such file") {
+		t.Errorf("Stream() error body got = %q", body)
+	}
+
+	limited := NewClient(WithMaxResponseBytes(10))
+	resp, err = limited.NewRequest(GET, server.URL+"/file").Stream()
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err = io.ReadAll(resp.Body)
+	if !errors.Is(err, ErrResponseTooLarge) || len(body) != 10 {
+		t.Errorf("Stream() limited body got = %q %v, want 10 bytes and %v", body, err, ErrResponseTooLarge)
+	}
+}
+
+func TestStreamInstrumented(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(BudgetHeader) == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = io.WriteString(w, "head")
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = io.WriteString(w, "tail")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var trace TraceInfo
+	var traced bool
+	handle := AddHook(afterHook(func(ctx context.Context) {
+		trace, traced = TraceInfoFromContext(ctx)
+	}))
+	defer handle.Remove()
+
+	client := NewClient(WithBudgetHeader(""), WithLogger(NopLogger))
+	ctx, cancel := WithBudget(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := client.NewRequest(GET, server.URL).WithContext(ctx).Stream()
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if !traced || trace.TTFB <= 0 || trace.RemoteAddr == "" {
+		t.Errorf("TraceInfoFromContext() got = %+v %v, want the timings to the headers", trace, traced)
+	}
+	if got := client.PoolStats().Active; got != 1 {
+		t.Errorf("PoolStats().Active got = %v, want 1 until the body is closed", got)
+	}
+	_ = resp.Body.Close()
+	if got := client.PoolStats().Active; got != 0 {
+		t.Errorf("PoolStats().Active got = %v after Close, want 0", got)
+	}
+}
+
+// cancelHook cancels the request in Before
+type cancelHook struct{}
+
+func (cancelHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	return ctx, nil
+}
+
+func (cancelHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	return ctx, nil
+}
+
+func TestStreamOutlastsTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 10; i++ {
+			_, _ = fmt.Fprintf(w, "{\"id\":%d}\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+	client.httpClient.Timeout = 60 * time.Millisecond
+
+	resp, err := client.NewRequest(GET, server.URL).Stream()
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil || strings.Count(string(body), "\n") != 10 {
+		t.Errorf("Stream() got = %q %v, want 10 lines", body, err)
+	}
+
+	rows, errc := SendJSONLines[jsonLine](client.NewRequest(GET, server.URL))
+	n := 0
+	for range rows {
+		n++
+	}
+	if err := <-errc; err != nil || n != 10 {
+		t.Errorf("SendJSONLines() got = %d records %v, want 10", n, err)
+	}
+}
+
+func TestStreamCanceledByHook(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+	handle := AddHook(cancelHook{})
+	defer handle.Remove()
+
+	client := NewClient(WithLogger(NopLogger))
+	if _, err := client.NewRequest(GET, server.URL).Stream(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream() error = %v, want %v", err, context.Canceled)
+	}
+	if calls != 0 {
+		t.Errorf("Stream() sent %d requests, want none", calls)
+	}
+}
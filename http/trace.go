@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// TraceInfo holds the per-phase timings of a single request.
+// DNSLookup, Connect and TLSHandshake are zero when a pooled connection is reused.
+type TraceInfo struct {
+	DNSLookup    time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// TTFB is the time from sending the request to the first response byte
+	TTFB time.Duration
+	// Total is the time from sending the request to reading the whole body
+	Total      time.Duration
+	ConnReused bool
+	RemoteAddr string
+}
+
+type traceInfoKey struct{}
+
+// TraceInfoFromContext returns the timings of the request the context belongs to.
+// It is available in the ctx passed to Hook.After.
+func TraceInfoFromContext(ctx context.Context) (TraceInfo, bool) {
+	info, ok := ctx.Value(traceInfoKey{}).(TraceInfo)
+	return info, ok
+}
+
+func contextWithTraceInfo(ctx context.Context, info TraceInfo) context.Context {
+	return context.WithValue(ctx, traceInfoKey{}, info)
+}
+
+// tracer records httptrace events, callbacks may fire from several goroutines
+type tracer struct {
+	mu sync.Mutex
+
+	start, dnsStart, connectStart, tlsStart time.Time
+	info                                    TraceInfo
+	// pool is told about the connection the request got
+	pool    *connPool
+	gotConn bool
+}
+
+func newTracer(pool *connPool) *tracer {
+	return &tracer{start: time.Now(), pool: pool}
+}
+
+// withRequest attaches the tracer to httpRequest
+func (t *tracer) withRequest(httpRequest *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.info.DNSLookup = time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			if err == nil {
+				t.info.Connect = time.Since(t.connectStart)
+			}
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.info.TLSHandshake = time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotConn: func(connInfo httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.info.ConnReused = connInfo.Reused
+			if t.pool != nil && !t.gotConn {
+				t.gotConn = true
+				t.pool.gotConn(connInfo.Reused)
+			}
+			if addr := connInfo.Conn.RemoteAddr(); addr != nil {
+				t.info.RemoteAddr = addr.String()
+			}
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.info.TTFB = time.Since(t.start)
+			t.mu.Unlock()
+		},
+	}
+	return httpRequest.WithContext(httptrace.WithClientTrace(httpRequest.Context(), trace))
+}
+
+// done stops the clock and returns the collected timings
+func (t *tracer) done() TraceInfo {
+	info := t.stop()
+	t.release()
+	return info
+}
+
+// stop stops the clock and returns the collected timings, the connection
+// stays counted active until release
+func (t *tracer) stop() TraceInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.info.Total = time.Since(t.start)
+	return t.info
+}
+
+// release tells the pool the request is done with its connection
+func (t *tracer) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.gotConn {
+		t.gotConn = false
+		t.pool.putConn()
+	}
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// traceHook keeps the TraceInfo seen by the last After call
+type traceHook struct {
+	info *TraceInfo
+}
+
+func (traceHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h traceHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	if info, ok := TraceInfoFromContext(ctx); ok {
+		*h.info = info
+	}
+	return ctx, nil
+}
+
+func TestTraceInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var info TraceInfo
+	defer AddHook(traceHook{info: &info}).Remove()
+
+	tests := []struct {
+		name           string
+		wantConnReused bool
+	}{
+		{name: "new connection", wantConnReused: false},
+		{name: "reused connection", wantConnReused: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := Get(server.URL, nil, nil); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if info.ConnReused != tt.wantConnReused {
+				t.Errorf("TraceInfo.ConnReused got = %v, want %v", info.ConnReused, tt.wantConnReused)
+			}
+			if info.TTFB < 20*time.Millisecond {
+				t.Errorf("TraceInfo.TTFB got = %v, want >= 20ms", info.TTFB)
+			}
+			if info.Total < info.TTFB {
+				t.Errorf("TraceInfo.Total got = %v, want >= TTFB %v", info.Total, info.TTFB)
+			}
+			if info.RemoteAddr != server.Listener.Addr().String() {
+				t.Errorf("TraceInfo.RemoteAddr got = %v, want %v", info.RemoteAddr, server.Listener.Addr().String())
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// transportTuning holds the transport settings of the options below, zero values keep the transport setting
+type transportTuning struct {
+	maxConnsPerHost       int
+	disableKeepAlives     bool
+	forceHTTP2            bool
+	dialTimeout           time.Duration
+	tlsHandshakeTimeout   time.Duration
+	expectContinueTimeout time.Duration
+	unixSocket            string
+	dialer                func(ctx context.Context, network string, addr string) (net.Conn, error)
+}
+
+func (t transportTuning) isSet() bool {
+	return t.maxConnsPerHost > 0 || t.disableKeepAlives || t.forceHTTP2 || t.dialTimeout > 0 ||
+		t.tlsHandshakeTimeout > 0 || t.expectContinueTimeout > 0 || t.unixSocket != "" || t.dialer != nil
+}
+
+// WithMaxConnsPerHost limits the connections per host, dialing, active and idle, 0 means no limit
+func WithMaxConnsPerHost(n int) Option {
+	return func(c *Client) {
+		c.tuning.maxConnsPerHost = n
+	}
+}
+
+// WithDisableKeepAlives uses every connection for a single request
+func WithDisableKeepAlives() Option {
+	return func(c *Client) {
+		c.tuning.disableKeepAlives = true
+	}
+}
+
+// WithForceHTTP2 tries HTTP/2 over TLS even though the transport has a custom dialer
+func WithForceHTTP2() Option {
+	return func(c *Client) {
+		c.tuning.forceHTTP2 = true
+	}
+}
+
+// WithDialTimeout bounds connecting to a host, 30s by default
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.tuning.dialTimeout = d
+	}
+}
+
+// WithTLSHandshakeTimeout bounds the TLS handshake
+func WithTLSHandshakeTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.tuning.tlsHandshakeTimeout = d
+	}
+}
+
+// WithExpectContinueTimeout bounds the wait for 100 Continue on requests with
+// an "Expect: 100-continue" header
+func WithExpectContinueTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.tuning.expectContinueTimeout = d
+	}
+}
+
+// WithUnixSocket connects to the unix socket at path for every request,
+// whatever the host of the url, like Docker daemons expect:
+//
+//	client := NewClient(WithUnixSocket("/var/run/docker.sock"))
+//	client.NewRequest(GET, "http://docker/v1.43/containers/json").Send()
+func WithUnixSocket(path string) Option {
+	return func(c *Client) {
+		c.tuning.unixSocket = path
+	}
+}
+
+// WithDialer connects to hosts with dial, e.g. to resolve names with a custom
+// resolver or to go through a tunnel. WithDialTimeout has no effect on it.
+func WithDialer(dial func(ctx context.Context, network string, addr string) (net.Conn, error)) Option {
+	return func(c *Client) {
+		c.tuning.dialer = dial
+	}
+}
+
+// PoolStats describes the connections of a Client. Active counts the
+// requests holding a connection, with HTTP/2 several share one.
+type PoolStats struct {
+	Open   int
+	Active int
+	Idle   int
+	// Dialed and Reused count new and reused connections since the client was created
+	Dialed int64
+	Reused int64
+}
+
+// connPool counts connections dialed by the transport and requests using them
+type connPool struct {
+	open   int64
+	active int64
+	dialed int64
+	reused int64
+}
+
+func (p *connPool) dial(next func(ctx context.Context, network string, addr string) (net.Conn, error)) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&p.open, 1)
+		atomic.AddInt64(&p.dialed, 1)
+		return &pooledConn{Conn: conn, pool: p}, nil
+	}
+}
+
+func (p *connPool) gotConn(reused bool) {
+	atomic.AddInt64(&p.active, 1)
+	if reused {
+		atomic.AddInt64(&p.reused, 1)
+	}
+}
+
+func (p *connPool) putConn() {
+	atomic.AddInt64(&p.active, -1)
+}
+
+// pooledConn reports its Close to the pool once
+type pooledConn struct {
+	net.Conn
+	pool *connPool
+	once sync.Once
+}
+
+func (c *pooledConn) Close() error {
+	c.once.Do(func() { atomic.AddInt64(&c.pool.open, -1) })
+	return c.Conn.Close()
+}
+
+// PoolStats returns the current connection counts. Open and Idle are only
+// tracked for a *http.Transport.
+func (c *Client) PoolStats() PoolStats {
+	s := PoolStats{
+		Open:   int(atomic.LoadInt64(&c.pool.open)),
+		Active: int(atomic.LoadInt64(&c.pool.active)),
+		Dialed: atomic.LoadInt64(&c.pool.dialed),
+		Reused: atomic.LoadInt64(&c.pool.reused),
+	}
+	if s.Idle = s.Open - s.Active; s.Idle < 0 {
+		s.Idle = 0
+	}
+	return s
+}
+
+// applyTransportTuning applies the tuning options and the connection tracking.
+// It runs after all options, a transport given with WithTransport or
+// WithHTTPClient is cloned rather than changed.
+func (c *Client) applyTransportTuning(own http.RoundTripper) {
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if c.httpClient.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok {
+		if c.tuning.isSet() || c.dns != nil {
+			c.getLogger().Warn("transport tuning needs a *http.Transport, the options are ignored")
+		}
+		return
+	}
+	if transport != own {
+		transport = transport.Clone()
+	}
+	t := c.tuning
+	if t.maxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = t.maxConnsPerHost
+	}
+	if t.disableKeepAlives {
+		transport.DisableKeepAlives = true
+	}
+	if t.forceHTTP2 {
+		transport.ForceAttemptHTTP2 = true
+	}
+	if t.dialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: t.dialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	}
+	if t.dialer != nil {
+		transport.DialContext = t.dialer
+	}
+	if t.tlsHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = t.tlsHandshakeTimeout
+	}
+	if t.expectContinueTimeout > 0 {
+		transport.ExpectContinueTimeout = t.expectContinueTimeout
+	}
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	if c.dns != nil {
+		dial = c.dns.dial(dial)
+	}
+	if t.unixSocket != "" {
+		dial = unixDial(dial, t.unixSocket)
+	}
+	transport.DialContext = c.pool.dial(dial)
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	c.httpClient = &httpClient
+}
+
+// unixDial dials the socket at path with dial instead of addr
+func unixDial(dial func(ctx context.Context, network string, addr string) (net.Conn, error), path string) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+		return dial(ctx, "unix", path)
+	}
+}
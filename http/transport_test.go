@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTransportTuning(t *testing.T) {
+	given := &http.Transport{}
+	client := NewClient(
+		WithMaxConnsPerHost(4),
+		WithDisableKeepAlives(),
+		WithForceHTTP2(),
+		WithDialTimeout(time.Second),
+		WithTLSHandshakeTimeout(2*time.Second),
+		WithExpectContinueTimeout(3*time.Second),
+		WithTransport(given),
+	)
+	got := client.httpClient.Transport.(*http.Transport)
+	if got == given {
+		t.Fatalf("NewClient() changed the given transport, want a clone")
+	}
+	if got.MaxConnsPerHost != 4 || !got.DisableKeepAlives || !got.ForceAttemptHTTP2 || got.DialContext == nil ||
+		got.TLSHandshakeTimeout != 2*time.Second || got.ExpectContinueTimeout != 3*time.Second {
+		t.Errorf("NewClient() transport got = %+v", got)
+	}
+	if given.MaxConnsPerHost != 0 || given.DialContext != nil {
+		t.Errorf("given transport got = %+v, want unchanged", given)
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithLogger(NopLogger))
+	for i := 0; i < 2; i++ {
+		if _, err := client.NewRequest(GET, server.URL).Send(); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	want := PoolStats{Open: 1, Active: 0, Idle: 1, Dialed: 1, Reused: 1}
+	if got := client.PoolStats(); got != want {
+		t.Errorf("PoolStats() got = %+v, want %+v", got, want)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = client.NewRequest(GET, server.URL+"/slow").Send()
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for client.PoolStats().Active == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := client.PoolStats(); got.Active != 1 || got.Idle != 0 {
+		t.Errorf("PoolStats() during request got = %+v, want 1 active", got)
+	}
+	close(release)
+	<-done
+
+	client.httpClient.CloseIdleConnections()
+	if got := client.PoolStats(); got.Open != 0 || got.Active != 0 {
+		t.Errorf("PoolStats() after close got = %+v, want no connections", got)
+	}
+}
+
+func TestUnixSocketAndDialer(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "daemon.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host + r.URL.Path))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	client := NewClient(WithUnixSocket(socket), WithLogger(NopLogger))
+	resp, err := client.NewRequest(GET, "http://docker/v1.43/containers/json").Send()
+	if err != nil || resp.String() != "docker/v1.43/containers/json" {
+		t.Errorf("Send() over unix socket got = %v %v", resp, err)
+	}
+
+	var dialed []string
+	dialer := func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		dialed = append(dialed, network+" "+addr)
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+	client = NewClient(WithDialer(dialer), WithUnixSocket(socket), WithLogger(NopLogger))
+	if _, err := client.NewRequest(GET, "http://docker/_ping").Send(); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(dialed) != 1 || dialed[0] != "unix "+socket {
+		t.Errorf("WithDialer() dialed = %v, want the socket", dialed)
+	}
+	if got := client.PoolStats().Dialed; got != 1 {
+		t.Errorf("PoolStats().Dialed got = %v, want 1", got)
+	}
+}
@@ -0,0 +1,239 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+const tusVersion = "1.0.0"
+
+type uploadConfig struct {
+	method      string
+	header      map[string]string
+	contentType string
+	chunkSize   int64
+	offset      func(ctx context.Context) (int64, error)
+	tus         bool
+	progress    func(sent int64, total int64)
+}
+
+type UploadOption = opt.Option[uploadConfig]
+
+var uploadChecks = []opt.Check[uploadConfig]{
+	opt.Exclusive(map[string]func(*uploadConfig) bool{
+		"WithTus":          func(c *uploadConfig) bool { return c.tus },
+		"WithResumeOffset": func(c *uploadConfig) bool { return c.offset != nil },
+	}),
+}
+
+// WithUploadMethod sets the request method, PUT by default
+func WithUploadMethod(method RequestMethodType) UploadOption {
+	return func(c *uploadConfig) {
+		c.method = string(method)
+	}
+}
+
+// WithUploadHeader adds request headers
+func WithUploadHeader(header map[string]string) UploadOption {
+	return func(c *uploadConfig) {
+		c.header = header
+	}
+}
+
+// WithContentType overrides the Content-Type detected from the file
+func WithContentType(contentType string) UploadOption {
+	return func(c *uploadConfig) {
+		c.contentType = contentType
+	}
+}
+
+// WithChunkSize uploads the file in requests of at most size bytes, each
+// carrying a Content-Range header
+func WithChunkSize(size int64) UploadOption {
+	return func(c *uploadConfig) {
+		c.chunkSize = size
+	}
+}
+
+// WithResumeOffset asks fn where to resume, bytes before the offset are skipped
+func WithResumeOffset(fn func(ctx context.Context) (int64, error)) UploadOption {
+	return func(c *uploadConfig) {
+		c.offset = fn
+	}
+}
+
+// WithTus speaks the tus resumable upload protocol against an already created
+// upload url: the offset comes from a HEAD request and the data is sent with PATCH
+func WithTus() UploadOption {
+	return func(c *uploadConfig) {
+		c.tus = true
+	}
+}
+
+// WithProgress is called while the file is sent with the bytes sent so far,
+// the resumed offset included, and the file size
+func WithProgress(fn func(sent int64, total int64)) UploadOption {
+	return func(c *uploadConfig) {
+		c.progress = fn
+	}
+}
+
+// UploadFile streams the file at path to url with the default Client
+func UploadFile(ctx context.Context, url string, path string, opts ...UploadOption) (int, http.Header, any, error) {
+	return defaultClient.UploadFile(ctx, url, path, opts...)
+}
+
+// UploadFile streams the file at path to url, the file is never loaded in memory.
+// Any 2xx response counts as success. The timeout of the http.Client does not
+// apply to the chunks, however long they take, bound them with ctx.
+func (c *Client) UploadFile(ctx context.Context, url string, path string, opts ...UploadOption) (int, http.Header, any, error) {
+	cfg := uploadConfig{method: http.MethodPut}
+	if err := opt.Build(&cfg, opts, uploadChecks...); err != nil {
+		return -1, nil, nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return -1, nil, nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return -1, nil, nil, err
+	}
+	size := info.Size()
+	if cfg.contentType == "" {
+		cfg.contentType, err = detectContentType(file, path)
+		if err != nil {
+			return -1, nil, nil, err
+		}
+	}
+
+	var offset int64
+	switch {
+	case cfg.tus:
+		cfg.method = http.MethodPatch
+		cfg.contentType = "application/offset+octet-stream"
+		if offset, err = c.tusOffset(ctx, url, cfg.header); err != nil {
+			return -1, nil, nil, err
+		}
+	case cfg.offset != nil:
+		if offset, err = cfg.offset(ctx); err != nil {
+			return -1, nil, nil, err
+		}
+	}
+	if offset < 0 || offset > size {
+		return -1, nil, nil, fmt.Errorf("upload: resume offset %d out of range for %d bytes", offset, size)
+	}
+
+	chunkSize := cfg.chunkSize
+	if chunkSize <= 0 {
+		chunkSize = size - offset
+	}
+	for {
+		n := size - offset
+		if n > chunkSize {
+			n = chunkSize
+		}
+		code, header, data, err := c.uploadChunk(ctx, url, &cfg, file, offset, n, size)
+		if err != nil {
+			return code, header, data, err
+		}
+		offset += n
+		if offset >= size {
+			return code, header, data, nil
+		}
+	}
+}
+
+// uploadChunk sends n bytes of file from offset, partial requests carry the range they cover
+func (c *Client) uploadChunk(ctx context.Context, url string, cfg *uploadConfig, file *os.File, offset int64, n int64, size int64) (int, http.Header, any, error) {
+	var body io.Reader = io.NewSectionReader(file, offset, n)
+	if cfg.progress != nil {
+		body = &progressReader{reader: body, sent: offset, total: size, progress: cfg.progress}
+	}
+	if n == 0 {
+		// a non nil body with zero length would be sent chunked
+		body = http.NoBody
+	}
+	ctx = withoutTimeout(ctx)
+	httpRequest, err := http.NewRequestWithContext(ctx, cfg.method, url, body)
+	if err != nil {
+		return -1, nil, nil, err
+	}
+	httpRequest.ContentLength = n
+	httpRequest.Header = mapHeader2netHeader(cfg.header)
+	httpRequest.Header.Set("Content-Type", cfg.contentType)
+	switch {
+	case cfg.tus:
+		httpRequest.Header.Set("Tus-Resumable", tusVersion)
+		httpRequest.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	case offset > 0 || n < size:
+		httpRequest.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+n-1, size))
+	}
+	return acceptUpload(c.do(ctx, httpRequest))
+}
+
+func (c *Client) tusOffset(ctx context.Context, url string, header map[string]string) (int64, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	httpRequest.Header = mapHeader2netHeader(header)
+	httpRequest.Header.Set("Tus-Resumable", tusVersion)
+	_, respHeader, _, err := acceptUpload(c.do(ctx, httpRequest))
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.ParseInt(respHeader.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("upload: invalid Upload-Offset: %w", err)
+	}
+	return offset, nil
+}
+
+// acceptUpload treats every 2xx as success, upload servers answer 201 and 204 as well
+func acceptUpload(code int, header http.Header, data any, err error) (int, http.Header, any, error) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && code >= 200 && code < 300 {
+		return code, header, statusErr.Body, nil
+	}
+	return code, header, data, err
+}
+
+// detectContentType goes by the file extension, then by the content
+func detectContentType(file *os.File, path string) (string, error) {
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		return contentType, nil
+	}
+	head := make([]byte, 512)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+type progressReader struct {
+	reader   io.Reader
+	sent     int64
+	total    int64
+	progress func(sent int64, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		r.progress(r.sent, r.total)
+	}
+	return n, err
+}
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type uploadRequest struct {
+	method        string
+	contentType   string
+	contentLength int64
+	contentRange  string
+	uploadOffset  string
+	body          string
+}
+
+func uploadServer(t *testing.T, status int) (*httptest.Server, func() []uploadRequest) {
+	var (
+		mu       sync.Mutex
+		requests []uploadRequest
+		received int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodHead {
+			w.Header().Set("Upload-Offset", strconv.Itoa(received))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received += len(body)
+		requests = append(requests, uploadRequest{
+			method:        r.Method,
+			contentType:   r.Header.Get("Content-Type"),
+			contentLength: r.ContentLength,
+			contentRange:  r.Header.Get("Content-Range"),
+			uploadOffset:  r.Header.Get("Upload-Offset"),
+			body:          string(body),
+		})
+		w.WriteHeader(status)
+	}))
+	return server, func() []uploadRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]uploadRequest(nil), requests...)
+	}
+}
+
+func TestUploadFile(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "data.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rawPath := filepath.Join(dir, "data")
+	if err := os.WriteFile(rawPath, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		opts   []UploadOption
+		want   []uploadRequest
+	}{
+		{
+			name:   "single put",
+			path:   jsonPath,
+			status: http.StatusCreated,
+			want:   []uploadRequest{{method: "PUT", contentType: "application/json", contentLength: 7, body: `{"a":1}`}},
+		},
+		{
+			name:   "chunked",
+			path:   rawPath,
+			status: http.StatusOK,
+			opts:   []UploadOption{WithChunkSize(4), WithUploadMethod(POST)},
+			want: []uploadRequest{
+				{method: "POST", contentType: "text/plain; charset=utf-8", contentLength: 4, contentRange: "bytes 0-3/10", body: "0123"},
+				{method: "POST", contentType: "text/plain; charset=utf-8", contentLength: 4, contentRange: "bytes 4-7/10", body: "4567"},
+				{method: "POST", contentType: "text/plain; charset=utf-8", contentLength: 2, contentRange: "bytes 8-9/10", body: "89"},
+			},
+		},
+		{
+			name:   "resume offset",
+			path:   rawPath,
+			status: http.StatusNoContent,
+			opts: []UploadOption{WithContentType("application/octet-stream"), WithResumeOffset(func(ctx context.Context) (int64, error) {
+				return 6, nil
+			})},
+			want: []uploadRequest{{method: "PUT", contentType: "application/octet-stream", contentLength: 4, contentRange: "bytes 6-9/10", body: "6789"}},
+		},
+		{
+			name:   "tus",
+			path:   rawPath,
+			status: http.StatusNoContent,
+			opts:   []UploadOption{WithTus(), WithChunkSize(6)},
+			want: []uploadRequest{
+				{method: "PATCH", contentType: "application/offset+octet-stream", contentLength: 6, uploadOffset: "0", body: "012345"},
+				{method: "PATCH", contentType: "application/offset+octet-stream", contentLength: 4, uploadOffset: "6", body: "6789"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := uploadServer(t, tt.status)
+			defer server.Close()
+
+			code, _, _, err := UploadFile(context.Background(), server.URL, tt.path, tt.opts...)
+			if err != nil || code != tt.status {
+				t.Fatalf("UploadFile() got = %v %v, want %v", code, err, tt.status)
+			}
+			got := requests()
+			if len(got) != len(tt.want) {
+				t.Fatalf("UploadFile() requests got = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("UploadFile() request %d got = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUploadFileProgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 100000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server, _ := uploadServer(t, http.StatusOK)
+	defer server.Close()
+
+	var last, total int64
+	calls := 0
+	_, _, _, err := UploadFile(context.Background(), server.URL, path, WithChunkSize(40000), WithProgress(func(sent int64, size int64) {
+		if sent < last {
+			t.Errorf("progress went back from %v to %v", last, sent)
+		}
+		last, total = sent, size
+		calls++
+	}))
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if last != 100000 || total != 100000 || calls < 3 {
+		t.Errorf("progress got = %v/%v in %v calls, want 100000/100000 in at least 3", last, total, calls)
+	}
+}
+
+func TestUploadFileOutlastsTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 100000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server, requests := uploadServer(t, http.StatusOK)
+	defer server.Close()
+	client := NewClient(WithLogger(NopLogger))
+	client.httpClient.Timeout = 50 * time.Millisecond
+
+	// a slow link, every read of the file takes a while
+	_, _, _, err := client.UploadFile(context.Background(), server.URL, path, WithProgress(func(sent int64, total int64) {
+		time.Sleep(30 * time.Millisecond)
+	}))
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if got := requests(); len(got) != 1 || len(got[0].body) != 100000 {
+		t.Errorf("UploadFile() got = %d requests, want the whole file in one", len(got))
+	}
+}
+
+func TestUploadFileError(t *testing.T) {
+	server, _ := uploadServer(t, http.StatusRequestEntityTooLarge)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, _, _, err := UploadFile(context.Background(), server.URL, path)
+	if code != http.StatusRequestEntityTooLarge || err == nil {
+		t.Errorf("UploadFile() got = %v %v, want 413 and an error", code, err)
+	}
+	_, _, _, err = UploadFile(context.Background(), server.URL, path, WithTus(), WithResumeOffset(func(ctx context.Context) (int64, error) {
+		return 0, nil
+	}))
+	if !errors.Is(err, opt.ErrConflict) {
+		t.Errorf("UploadFile() conflicting options error = %v, want %v", err, opt.ErrConflict)
+	}
+	if _, _, _, err := UploadFile(context.Background(), server.URL, filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("UploadFile() missing file error = %v, want not exist", err)
+	}
+}
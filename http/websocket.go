@@ -0,0 +1,540 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	CloseNormalClosure = 1000
+	CloseGoingAway     = 1001
+
+	DefaultWSMaxMessageSize = 16 << 20
+	wsAcceptGUID            = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsCloseWait             = time.Second
+)
+
+var (
+	// ErrWSClosed is returned after Close was called
+	ErrWSClosed = errors.New("websocket: connection closed")
+	// ErrWSMessageTooLarge is returned for messages over the configured size
+	ErrWSMessageTooLarge = errors.New("websocket: message too large")
+)
+
+// WSCloseError is returned by ReadMessage when the peer closed the connection
+type WSCloseError struct {
+	Code int
+	Text string
+}
+
+func (e *WSCloseError) Error() string {
+	return fmt.Sprintf("websocket: closed by peer: %d %s", e.Code, e.Text)
+}
+
+type wsConfig struct {
+	pingInterval   time.Duration
+	maxMessageSize int64
+	reconnect      bool
+	maxAttempts    int
+	backoff        time.Duration
+	onReconnect    func(ctx context.Context, conn *WSConn) error
+}
+
+type WSOption = opt.Option[wsConfig]
+
+var wsChecks = []opt.Check[wsConfig]{
+	opt.Requires("WithOnReconnect", func(c *wsConfig) bool { return c.onReconnect != nil },
+		"WithReconnect", func(c *wsConfig) bool { return c.reconnect }),
+}
+
+// WithPingInterval sends a ping every d and drops the connection when nothing
+// was received for two intervals, zero disables keepalive
+func WithPingInterval(d time.Duration) WSOption {
+	return func(c *wsConfig) {
+		c.pingInterval = d
+	}
+}
+
+// WithMaxMessageSize limits the size of a received message
+func WithMaxMessageSize(n int64) WSOption {
+	return func(c *wsConfig) {
+		c.maxMessageSize = n
+	}
+}
+
+// WithReconnect redials a dropped connection up to maxAttempts times (unlimited
+// when zero), waiting backoff, 2*backoff... capped at 30s between attempts
+func WithReconnect(maxAttempts int, backoff time.Duration) WSOption {
+	return func(c *wsConfig) {
+		c.reconnect = true
+		c.maxAttempts = maxAttempts
+		c.backoff = backoff
+	}
+}
+
+// WithOnReconnect is called after every successful reconnect, e.g. to resubscribe
+func WithOnReconnect(fn func(ctx context.Context, conn *WSConn) error) WSOption {
+	return func(c *wsConfig) {
+		c.onReconnect = fn
+	}
+}
+
+// WSConn is a client websocket connection. ReadMessage must be called from a
+// single goroutine, writes may come from several.
+type WSConn struct {
+	client *Client
+	url    string
+	header map[string]string
+	cfg    wsConfig
+
+	mu       sync.Mutex
+	rwc      io.ReadWriteCloser
+	br       *bufio.Reader
+	lastSeen time.Time
+	closed   bool
+	stopPing chan struct{}
+
+	writeMu sync.Mutex
+}
+
+// Dial opens a websocket with the default Client, url uses the ws or wss scheme
+func Dial(ctx context.Context, url string, header map[string]string, opts ...WSOption) (*WSConn, error) {
+	return defaultClient.Dial(ctx, url, header, opts...)
+}
+
+// Dial opens a websocket, the handshake goes through the hooks and the client's transport
+func (c *Client) Dial(ctx context.Context, url string, header map[string]string, opts ...WSOption) (*WSConn, error) {
+	cfg := wsConfig{
+		maxMessageSize: DefaultWSMaxMessageSize,
+		backoff:        time.Second,
+	}
+	if err := opt.Build(&cfg, opts, wsChecks...); err != nil {
+		return nil, err
+	}
+	w := &WSConn{client: c, url: url, header: header, cfg: cfg}
+	if err := w.connect(ctx); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *WSConn) connect(ctx context.Context) error {
+	httpURL := url2HTTP(w.url)
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, httpURL, nil)
+	if err != nil {
+		return err
+	}
+	if err := w.client.lifecycle.enter(); err != nil {
+		return err
+	}
+	defer w.client.lifecycle.leave()
+	if err := w.client.checkHost(httpRequest.URL); err != nil {
+		return err
+	}
+	if w.header != nil {
+		httpRequest.Header = mapHeader2netHeader(w.header)
+	}
+	httpRequest = w.client.withDefaultHeaders(httpRequest)
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	challenge := base64.StdEncoding.EncodeToString(key)
+	httpRequest.Header.Set("Connection", "Upgrade")
+	httpRequest.Header.Set("Upgrade", "websocket")
+	httpRequest.Header.Set("Sec-WebSocket-Version", "13")
+	httpRequest.Header.Set("Sec-WebSocket-Key", challenge)
+
+	hookList := hooks()
+	for i, hook := range hookList {
+		_ctx, err := hook.Before(ctx, httpRequest)
+		if err != nil {
+			_, _, _, _ = w.client.afterHooks(ctx, hookList[:i], -1, nil, nil, err)
+			return err
+		}
+		ctx = _ctx
+	}
+	// the client timeout would also cut the upgraded connection, ctx bounds the handshake instead
+	httpClient := *w.client.httpClient
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(httpRequest)
+	code := -1
+	var respHeader http.Header
+	if err == nil {
+		code, respHeader = resp.StatusCode, resp.Header
+		err = checkHandshake(resp, challenge)
+	}
+	for _, hook := range hookList {
+		_ctx, hookErr := hook.After(ctx, code, respHeader, nil, err)
+		ctx = _ctx
+		if hookErr != nil && err == nil {
+			err = hookErr
+		}
+	}
+	if err != nil {
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		_ = resp.Body.Close()
+		return errors.New("websocket: transport does not support protocol upgrades")
+	}
+
+	w.mu.Lock()
+	w.rwc = rwc
+	w.br = bufio.NewReader(rwc)
+	w.lastSeen = time.Now()
+	if w.cfg.pingInterval > 0 {
+		w.stopPing = make(chan struct{})
+		go w.keepalive(rwc, w.stopPing)
+	}
+	w.mu.Unlock()
+	w.client.getLogger().Debug("websocket connected", "url", w.url)
+	return nil
+}
+
+func checkHandshake(resp *http.Response, challenge string) error {
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &StatusError{Code: resp.StatusCode, Body: body}
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return errors.New("websocket: bad Upgrade header in handshake response")
+	}
+	h := sha1.New()
+	h.Write([]byte(challenge + wsAcceptGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(h.Sum(nil)) {
+		return errors.New("websocket: bad Sec-WebSocket-Accept in handshake response")
+	}
+	return nil
+}
+
+func url2HTTP(url string) string {
+	switch {
+	case strings.HasPrefix(url, "ws://"):
+		return "http://" + strings.TrimPrefix(url, "ws://")
+	case strings.HasPrefix(url, "wss://"):
+		return "https://" + strings.TrimPrefix(url, "wss://")
+	}
+	return url
+}
+
+// keepalive pings until stop is closed and drops a silent connection
+func (w *WSConn) keepalive(rwc io.ReadWriteCloser, stop chan struct{}) {
+	ticker := time.NewTicker(w.cfg.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			silent := time.Since(w.lastSeen) > 2*w.cfg.pingInterval
+			w.mu.Unlock()
+			if silent {
+				w.client.getLogger().Warn("websocket keepalive timed out", "url", w.url)
+				_ = rwc.Close()
+				return
+			}
+			if err := w.writeFrame(rwc, opPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// WriteMessage sends a text or binary message
+func (w *WSConn) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", typ)
+	}
+	rwc, err := w.current()
+	if err != nil {
+		return err
+	}
+	return w.writeFrame(rwc, byte(typ), data)
+}
+
+// WriteText sends a text message
+func (w *WSConn) WriteText(text string) error {
+	return w.WriteMessage(TextMessage, []byte(text))
+}
+
+func (w *WSConn) current() (io.ReadWriteCloser, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, ErrWSClosed
+	}
+	return w.rwc, nil
+}
+
+// writeFrame writes a single final frame, client frames are always masked
+func (w *WSConn) writeFrame(rwc io.Writer, opcode byte, payload []byte) error {
+	header := make([]byte, 2, 14)
+	header[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = 0x80 | byte(n)
+	case n <= 0xffff:
+		header[1] = 0x80 | 126
+		header = header[:4]
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 0x80 | 127
+		header = header[:10]
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	frame := append(header, mask...)
+	start := len(frame)
+	frame = append(frame, payload...)
+	for i := range frame[start:] {
+		frame[start+i] ^= mask[i%4]
+	}
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	_, err := rwc.Write(frame)
+	return err
+}
+
+// ReadMessage returns the next text or binary message, answering pings on the
+// way. With WithReconnect a dropped connection is redialed transparently.
+func (w *WSConn) ReadMessage() (MessageType, []byte, error) {
+	for {
+		typ, data, err := w.readMessage()
+		if err == nil {
+			return typ, data, nil
+		}
+		w.mu.Lock()
+		closed := w.closed
+		w.mu.Unlock()
+		if closed {
+			return 0, nil, ErrWSClosed
+		}
+		if !w.cfg.reconnect || errors.Is(err, ErrWSMessageTooLarge) {
+			return 0, nil, err
+		}
+		w.client.getLogger().Warn("websocket connection lost", "url", w.url, "err", err)
+		if err := w.reconnect(); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+func (w *WSConn) readMessage() (MessageType, []byte, error) {
+	w.mu.Lock()
+	br := w.br
+	rwc := w.rwc
+	w.mu.Unlock()
+
+	var (
+		typ     MessageType
+		message []byte
+	)
+	for {
+		fin, opcode, payload, err := w.readFrame(br)
+		if err != nil {
+			return 0, nil, err
+		}
+		w.mu.Lock()
+		w.lastSeen = time.Now()
+		w.mu.Unlock()
+		switch opcode {
+		case opPing:
+			if err := w.writeFrame(rwc, opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			closeErr := &WSCloseError{Code: CloseNormalClosure}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Text = string(payload[2:])
+			}
+			_ = w.writeFrame(rwc, opClose, payload[:min2(len(payload), 2)])
+			_ = rwc.Close()
+			return 0, nil, closeErr
+		case opText, opBinary:
+			typ = MessageType(opcode)
+			message = payload
+		case opContinuation:
+			message = append(message, payload...)
+		default:
+			return 0, nil, fmt.Errorf("websocket: unknown opcode %d", opcode)
+		}
+		if int64(len(message)) > w.cfg.maxMessageSize {
+			return 0, nil, ErrWSMessageTooLarge
+		}
+		if fin {
+			return typ, message, nil
+		}
+	}
+}
+
+func (w *WSConn) readFrame(br *bufio.Reader) (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(br, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if length < 0 || length > w.cfg.maxMessageSize {
+		err = ErrWSMessageTooLarge
+		return
+	}
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(br, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(br, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+func (w *WSConn) reconnect() error {
+	w.dropConn()
+	backoff := w.cfg.backoff
+	var err error
+	for attempt := 1; w.cfg.maxAttempts == 0 || attempt <= w.cfg.maxAttempts; attempt++ {
+		time.Sleep(backoff)
+		w.mu.Lock()
+		closed := w.closed
+		w.mu.Unlock()
+		if closed {
+			return ErrWSClosed
+		}
+		if err = w.connect(context.Background()); errors.Is(err, ErrClientClosed) {
+			return err
+		} else if err == nil {
+			if w.cfg.onReconnect != nil {
+				if err = w.cfg.onReconnect(context.Background(), w); err != nil {
+					w.dropConn()
+					continue
+				}
+			}
+			w.client.getLogger().Info("websocket reconnected", "url", w.url, "attempt", attempt)
+			return nil
+		}
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+	return fmt.Errorf("websocket: reconnect failed after %d attempts: %w", w.cfg.maxAttempts, err)
+}
+
+// dropConn closes the current connection and stops its keepalive
+func (w *WSConn) dropConn() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopPing != nil {
+		close(w.stopPing)
+		w.stopPing = nil
+	}
+	if w.rwc != nil {
+		_ = w.rwc.Close()
+	}
+}
+
+// Close sends a normal close frame, waits shortly for the peer to answer and
+// closes the connection. It stops reconnecting.
+func (w *WSConn) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	rwc, br := w.rwc, w.br
+	w.mu.Unlock()
+
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, CloseNormalClosure)
+	err := w.writeFrame(rwc, opClose, payload)
+	if err == nil {
+		// drain until the peer's close frame or the timeout, whatever comes first
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				_, opcode, _, err := w.readFrame(br)
+				if err != nil || opcode == opClose {
+					return
+				}
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(wsCloseWait):
+		}
+	}
+	w.dropConn()
+	return err
+}
+
+func min2(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,238 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// wsEchoServer echoes messages back, "drop" closes the connection without a
+// close frame and "bye" closes it with code 1001
+func wsEchoServer(t *testing.T, connects *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(connects, 1)
+		h := sha1.New()
+		h.Write([]byte(r.Header.Get("Sec-WebSocket-Key") + wsAcceptGUID))
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack() error = %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h.Sum(nil)) + "\r\n\r\n")
+		_ = rw.Flush()
+		server := &WSConn{cfg: wsConfig{maxMessageSize: DefaultWSMaxMessageSize}}
+		for {
+			_, opcode, payload, err := server.readFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			switch {
+			case opcode == opPing:
+				writeServerFrame(conn, opPong, payload)
+			case opcode == opClose:
+				writeServerFrame(conn, opClose, payload)
+				return
+			case string(payload) == "drop":
+				return
+			case string(payload) == "bye":
+				writeServerFrame(conn, opClose, []byte{0x03, 0xe9, 'b', 'y', 'e'})
+				return
+			case string(payload) == "ping me":
+				writeServerFrame(conn, opPing, []byte("p"))
+				writeServerFrame(conn, opText, payload)
+			default:
+				writeServerFrame(conn, opcode, payload)
+			}
+		}
+	}))
+}
+
+func writeServerFrame(conn net.Conn, opcode byte, payload []byte) {
+	frame := []byte{0x80 | opcode}
+	if len(payload) < 126 {
+		frame = append(frame, byte(len(payload)))
+	} else {
+		frame = append(frame, 126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	}
+	_, _ = conn.Write(append(frame, payload...))
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws://" + strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestDial(t *testing.T) {
+	var connects int64
+	server := wsEchoServer(t, &connects)
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), wsURL(server), map[string]string{"X-Trace": "1"})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	tests := []struct {
+		name    string
+		typ     MessageType
+		message string
+	}{
+		{name: "text", typ: TextMessage, message: "hello"},
+		{name: "binary", typ: BinaryMessage, message: "\x00\x01\x02"},
+		{name: "extended length", typ: TextMessage, message: strings.Repeat("x", 300)},
+		{name: "ping from server", typ: TextMessage, message: "ping me"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := conn.WriteMessage(tt.typ, []byte(tt.message)); err != nil {
+				t.Fatalf("WriteMessage() error = %v", err)
+			}
+			typ, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("ReadMessage() error = %v", err)
+			}
+			if typ != tt.typ || string(data) != tt.message {
+				t.Errorf("ReadMessage() got = %v %q, want %v %q", typ, data, tt.typ, tt.message)
+			}
+		})
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := conn.WriteText("late"); !errors.Is(err, ErrWSClosed) {
+		t.Errorf("WriteText() after Close got = %v, want %v", err, ErrWSClosed)
+	}
+}
+
+func TestDialHandshakeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := Dial(context.Background(), wsURL(server), nil, WithOnReconnect(func(ctx context.Context, conn *WSConn) error {
+		return nil
+	}))
+	if !errors.Is(err, opt.ErrConflict) {
+		t.Errorf("Dial() without WithReconnect error = %v, want %v", err, opt.ErrConflict)
+	}
+	_, err = Dial(context.Background(), wsURL(server), nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusForbidden {
+		t.Errorf("Dial() error got = %v, want StatusError 403", err)
+	}
+}
+
+func TestWSConnPeerClose(t *testing.T) {
+	var connects int64
+	server := wsEchoServer(t, &connects)
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), wsURL(server), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	_ = conn.WriteText("bye")
+	_, _, err = conn.ReadMessage()
+	var closeErr *WSCloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseGoingAway || closeErr.Text != "bye" {
+		t.Errorf("ReadMessage() error got = %v, want close 1001 bye", err)
+	}
+}
+
+func TestWSConnReconnect(t *testing.T) {
+	var connects int64
+	server := wsEchoServer(t, &connects)
+	defer server.Close()
+
+	var resubscribed int64
+	conn, err := Dial(context.Background(), wsURL(server), nil,
+		WithReconnect(3, 10*time.Millisecond),
+		WithOnReconnect(func(ctx context.Context, conn *WSConn) error {
+			atomic.AddInt64(&resubscribed, 1)
+			return conn.WriteText("subscribe")
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	_ = conn.WriteText("drop")
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if string(data) != "subscribe" {
+		t.Errorf("ReadMessage() got = %q, want %q", data, "subscribe")
+	}
+	if got := atomic.LoadInt64(&connects); got != 2 {
+		t.Errorf("connects got = %v, want 2", got)
+	}
+	if got := atomic.LoadInt64(&resubscribed); got != 1 {
+		t.Errorf("resubscribed got = %v, want 1", got)
+	}
+}
+
+func TestWSConnKeepalive(t *testing.T) {
+	// a server that never answers, so the keepalive has to drop the connection
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		h := sha1.New()
+		h.Write([]byte(req.Header.Get("Sec-WebSocket-Key") + wsAcceptGUID))
+		_, _ = io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Accept: "+base64.StdEncoding.EncodeToString(h.Sum(nil))+"\r\n\r\n")
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	conn, err := NewClient(WithLogger(NopLogger)).Dial(context.Background(), "ws://"+listener.Addr().String(), nil,
+		WithPingInterval(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := conn.ReadMessage()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("ReadMessage() error got = nil, want keepalive failure")
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("keepalive did not drop the silent connection")
+	}
+}
@@ -0,0 +1,127 @@
+// Package httpapi declares REST clients in the manner of Retrofit: an API is
+// a struct of function fields whose tags name the endpoint, and Implement
+// returns one with every field sending its request through a gohttp.Client.
+// Go can't implement interfaces at run time, so the API is a struct rather
+// than an interface, called the same way:
+//
+//	type UsersAPI struct {
+//		Get    func(ctx context.Context, in GetUser) (*User, error) `method:"GET" path:"/users/{id}"`
+//		Create func(ctx context.Context, in User) (*User, error)    `method:"POST" path:"/users" body:"*"`
+//	}
+//
+//	users, err := httpapi.Implement[UsersAPI](client,
+//		httpapi.WithBaseURL("https://api.example.com/v1"),
+//		httpapi.WithError(func() error { return &APIError{} }))
+//	user, err := users.Get(ctx, GetUser{ID: 7})
+//	var apiErr *APIError
+//	if errors.As(err, &apiErr) { ... }
+//
+// Parameters are bound as by the transcode package: fields of the input
+// fill the path placeholders, the body tag picks the body, and the other
+// fields become the query.
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/Stellar1999/gotool/transcode"
+)
+
+type config struct {
+	baseURL string
+	header  map[string]string
+	newErr  func() error
+}
+
+type Option = opt.Option[config]
+
+// WithBaseURL is the URL the paths are relative to
+func WithBaseURL(url string) Option {
+	return func(c *config) {
+		c.baseURL = url
+	}
+}
+
+// WithHeader adds headers to every request
+func WithHeader(header map[string]string) Option {
+	return func(c *config) {
+		c.header = header
+	}
+}
+
+// WithError decodes the body of responses that are not 2xx into the error
+// newErr returns, a pointer, and returns it in an Error. Bodies that can't
+// be decoded leave the *gohttp.StatusError as it is.
+func WithError(newErr func() error) Option {
+	return func(c *config) {
+		c.newErr = newErr
+	}
+}
+
+// Error is returned for responses that are not 2xx with WithError. It
+// unwraps to the decoded error and matches the *gohttp.StatusError as well,
+// with errors.As.
+type Error struct {
+	Status *gohttp.StatusError
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("httpapi: status %d: %v", e.Status.Code, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return errors.Is(e.Status, target)
+}
+
+func (e *Error) As(target any) bool {
+	return errors.As(e.Status, target)
+}
+
+// Implement returns a T whose function fields with method and path tags, or
+// an http tag like "GET /users/{id}", send their requests with client, the
+// default Client of the http package when nil. T must be a struct, invalid
+// tags and signatures fail with transcode.ErrSpec.
+func Implement[T any](client *gohttp.Client, opts ...Option) (*T, error) {
+	var cfg config
+	if err := opt.Build(&cfg, opts); err != nil {
+		return nil, err
+	}
+	api := new(T)
+	if reflect.TypeOf(api).Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T is not a struct", transcode.ErrSpec, *api)
+	}
+	bindOpts := []transcode.Option{transcode.WithHeader(cfg.header)}
+	if client != nil {
+		bindOpts = append(bindOpts, transcode.WithClient(client))
+	}
+	if cfg.newErr != nil {
+		bindOpts = append(bindOpts, transcode.WithErrorDecoder(cfg.decodeError))
+	}
+	if err := transcode.Bind(api, cfg.baseURL, bindOpts...); err != nil {
+		return nil, err
+	}
+	return api, nil
+}
+
+// decodeError decodes the body of a failed response into the error of
+// WithError
+func (c *config) decodeError(resp *gohttp.Response, err error) error {
+	var statusErr *gohttp.StatusError
+	if !errors.As(err, &statusErr) || len(resp.Body) == 0 {
+		return err
+	}
+	decoded := c.newErr()
+	if resp.Decode(decoded) != nil {
+		return err
+	}
+	return &Error{Status: statusErr, Err: decoded}
+}
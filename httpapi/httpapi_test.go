@@ -0,0 +1,98 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Stellar1999/gotool/errs"
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/transcode"
+)
+
+type User struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type GetUser struct {
+	ID int64 `json:"id"`
+}
+
+type UsersAPI struct {
+	Get    func(ctx context.Context, in GetUser) (*User, error) `method:"GET" path:"/users/{id}"`
+	Create func(ctx context.Context, in User) (*User, error)    `method:"POST" path:"/users" body:"*"`
+}
+
+type apiError struct {
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func TestImplement(t *testing.T) {
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/users/7":
+			_, _ = io.WriteString(w, `{"id":7,"name":"ada"}`)
+		case "/v1/users":
+			body, _ := io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		case "/v1/users/500":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, `not json`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"message":"no such user"}`)
+		}
+	}))
+	defer server.Close()
+	users, err := Implement[UsersAPI](gohttp.NewClient(gohttp.WithLogger(gohttp.NopLogger)),
+		WithBaseURL(server.URL+"/v1"),
+		WithHeader(map[string]string{"Authorization": "Bearer t"}),
+		WithError(func() error { return &apiError{} }))
+	if err != nil {
+		t.Fatalf("Implement() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if user, err := users.Get(ctx, GetUser{ID: 7}); err != nil || user.Name != "ada" || token != "Bearer t" {
+		t.Errorf("Get() got = %+v %v, Authorization %q", user, err, token)
+	}
+	if user, err := users.Create(ctx, User{Name: "grace"}); err != nil || user.Name != "grace" {
+		t.Errorf("Create() got = %+v %v", user, err)
+	}
+
+	_, err = users.Get(ctx, GetUser{ID: 404})
+	var apiErr *apiError
+	var statusErr *gohttp.StatusError
+	if !errors.As(err, &apiErr) || apiErr.Message != "no such user" {
+		t.Errorf("Get() error got = %v, want the decoded apiError", err)
+	}
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusNotFound || !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("Get() error got = %v, want it to match the StatusError", err)
+	}
+	_, err = users.Get(ctx, GetUser{ID: 500})
+	if errors.As(err, &apiErr) || !errors.As(err, &statusErr) || statusErr.Code != http.StatusInternalServerError {
+		t.Errorf("Get() error got = %v, want the StatusError of an undecodable body", err)
+	}
+
+	if _, err := Implement[func()](nil); !errors.Is(err, transcode.ErrSpec) {
+		t.Errorf("Implement() error = %v, want ErrSpec for a non-struct", err)
+	}
+	type badAPI struct {
+		Get func() error `method:"GET" path:"/x"`
+	}
+	if _, err := Implement[badAPI](nil); !errors.Is(err, transcode.ErrSpec) {
+		t.Errorf("Implement() error = %v, want ErrSpec", err)
+	}
+}
@@ -0,0 +1,479 @@
+// Package httpdl downloads large files with a gohttp.Client: the file is
+// split into ranged segments fetched concurrently and written in place,
+// progress is kept in a metadata file next to it so an interrupted download
+// resumes where it stopped, and the result can be checked against a digest.
+//
+//	d := httpdl.New(client)
+//	err := d.Fetch(ctx, url, "backup.tar",
+//		httpdl.WithSegments(8),
+//		httpdl.WithChecksum(crypto.SHA256, want),
+//		httpdl.WithBandwidth(10*env.MiB))
+//
+// While downloading, the data goes to dstPath+".part" and the progress to
+// dstPath+".part.json". Servers that don't answer range requests are
+// downloaded in one request and can't resume.
+package httpdl
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Stellar1999/gotool/cryptox"
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/Stellar1999/gotool/syncx"
+)
+
+var (
+	// ErrChecksum is returned when the downloaded file doesn't match the
+	// WithChecksum digest, the file is removed
+	ErrChecksum = errors.New("httpdl: checksum mismatch")
+	// ErrChanged is returned when the file changed on the server while it was
+	// being downloaded, the next Fetch starts over
+	ErrChanged = errors.New("httpdl: remote file changed")
+	// ErrShortSegment is returned when the server sent less of a segment than
+	// asked for
+	ErrShortSegment = errors.New("httpdl: short segment")
+)
+
+type config struct {
+	segments   int
+	minSegment int64
+	header     map[string]string
+	hash       crypto.Hash
+	sum        string
+	bandwidth  int64
+	progress   func(done int64, total int64)
+	saveEvery  time.Duration
+}
+
+type Option = opt.Option[config]
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.segments < 1 {
+			return fmt.Errorf("httpdl: segments %d must be at least 1", c.segments)
+		}
+		if c.hash != 0 && !c.hash.Available() {
+			return fmt.Errorf("httpdl: hash %v is not linked into the binary", c.hash)
+		}
+		return nil
+	},
+}
+
+// WithSegments fetches the file in up to n concurrent ranged requests, 4 by
+// default
+func WithSegments(n int) Option {
+	return func(c *config) {
+		c.segments = n
+	}
+}
+
+// WithMinSegmentSize keeps segments at least size bytes, so small files are
+// fetched in fewer requests, 1MiB by default
+func WithMinSegmentSize(size int64) Option {
+	return func(c *config) {
+		c.minSegment = size
+	}
+}
+
+// WithHeader adds headers to the download requests
+func WithHeader(header map[string]string) Option {
+	return func(c *config) {
+		c.header = header
+	}
+}
+
+// WithChecksum checks the downloaded file against the hex digest sum with h,
+// e.g. crypto.SHA256
+func WithChecksum(h crypto.Hash, sum string) Option {
+	return func(c *config) {
+		c.hash = h
+		c.sum = strings.ToLower(sum)
+	}
+}
+
+// WithBandwidth limits all segments together to bytesPerSec, 0 doesn't limit
+func WithBandwidth(bytesPerSec int64) Option {
+	return func(c *config) {
+		c.bandwidth = bytesPerSec
+	}
+}
+
+// WithProgress calls fn as data arrives with the bytes downloaded so far,
+// including those of an earlier attempt, and the size of the file, -1 when
+// the server didn't tell. Calls don't overlap.
+func WithProgress(fn func(done int64, total int64)) Option {
+	return func(c *config) {
+		c.progress = fn
+	}
+}
+
+// Downloader fetches files with a client, its hooks, retries and limits
+// apply to every request
+type Downloader struct {
+	client *gohttp.Client
+}
+
+// New returns a Downloader sending requests with client
+func New(client *gohttp.Client) *Downloader {
+	return &Downloader{client: client}
+}
+
+// state is the metadata file of a download in progress
+type state struct {
+	URL          string     `json:"url"`
+	Size         int64      `json:"size"`
+	ETag         string     `json:"etag,omitempty"`
+	LastModified string     `json:"last_modified,omitempty"`
+	Segments     []*segment `json:"segments"`
+}
+
+// segment is the byte range [Start, End) of the file, Done bytes of it are
+// written
+type segment struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  int64 `json:"done"`
+}
+
+// download is one Fetch
+type download struct {
+	d        *Downloader
+	cfg      config
+	url      string
+	partPath string
+	metaPath string
+	throttle *throttle
+
+	mu    sync.Mutex
+	state state
+	done  int64
+	saved time.Time
+}
+
+// Fetch downloads url to dstPath, resuming an earlier Fetch of the same url
+// and file that was interrupted. dstPath only appears once the whole file is
+// downloaded and checked.
+func (d *Downloader) Fetch(ctx context.Context, url string, dstPath string, opts ...Option) error {
+	cfg := config{segments: 4, minSegment: 1 << 20, saveEvery: time.Second}
+	if err := opt.Build(&cfg, opts, checks...); err != nil {
+		return err
+	}
+	dl := &download{
+		d:        d,
+		cfg:      cfg,
+		url:      url,
+		partPath: dstPath + ".part",
+		metaPath: dstPath + ".part.json",
+	}
+	if cfg.bandwidth > 0 {
+		dl.throttle = &throttle{rate: float64(cfg.bandwidth)}
+	}
+	if err := dl.run(ctx); err != nil {
+		return err
+	}
+	if cfg.hash != 0 {
+		sum, err := cryptox.HashFile(cfg.hash, dl.partPath)
+		if err != nil {
+			return err
+		}
+		if sum != cfg.sum {
+			dl.discard()
+			return fmt.Errorf("%w: got %s, want %s", ErrChecksum, sum, cfg.sum)
+		}
+	}
+	if err := os.Rename(dl.partPath, dstPath); err != nil {
+		return err
+	}
+	_ = os.Remove(dl.metaPath)
+	return nil
+}
+
+func (dl *download) request(ctx context.Context) *gohttp.Request {
+	req := dl.d.client.NewRequest(gohttp.GET, dl.url).WithContext(ctx)
+	for k, v := range dl.cfg.header {
+		req.Header(k, v)
+	}
+	return req
+}
+
+// run probes the server with a one byte range and downloads the file into
+// partPath
+func (dl *download) run(ctx context.Context) error {
+	resp, err := dl.request(ctx).Header("Range", "bytes=0-0").Stream()
+	if err != nil {
+		return err
+	}
+	size := int64(-1)
+	if resp.StatusCode == http.StatusPartialContent {
+		_ = resp.Body.Close()
+		if _, total, ok := parseContentRange(resp.Header.Get("Content-Range")); ok {
+			size = total
+		}
+		if size >= 0 {
+			return dl.segmented(ctx, size, resp.Header)
+		}
+		// the size is needed to split the file, fetch it whole instead
+		if resp, err = dl.request(ctx).Stream(); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+	return dl.whole(ctx, resp)
+}
+
+// whole writes the body of resp to partPath, for servers without ranges
+func (dl *download) whole(ctx context.Context, resp *http.Response) error {
+	dl.discard()
+	f, err := os.Create(dl.partPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dl.state.Size = resp.ContentLength
+	seg := &segment{End: resp.ContentLength}
+	if err := dl.copy(ctx, f, resp.Body, seg); err != nil {
+		return err
+	}
+	if resp.ContentLength >= 0 && seg.Done != resp.ContentLength {
+		return fmt.Errorf("%w: got %d of %d bytes", ErrShortSegment, seg.Done, resp.ContentLength)
+	}
+	return f.Close()
+}
+
+// segmented downloads a file of size bytes in segments, resuming them from
+// the metadata file when it matches the file on the server
+func (dl *download) segmented(ctx context.Context, size int64, header http.Header) error {
+	fresh := state{
+		URL:          dl.url,
+		Size:         size,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+	f, resumed := dl.resume(fresh)
+	if !resumed {
+		dl.discard()
+		fresh.Segments = split(size, dl.cfg.segments, dl.cfg.minSegment)
+		dl.state = fresh
+		var err error
+		if f, err = os.Create(dl.partPath); err != nil {
+			return err
+		}
+		if err := f.Truncate(size); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	defer f.Close()
+	for _, seg := range dl.state.Segments {
+		dl.done += seg.Done
+	}
+
+	// a failing segment leaves the others running, what they get is kept for
+	// the next attempt
+	g := syncx.NewWaitGroup(ctx)
+	for _, seg := range dl.state.Segments {
+		seg := seg
+		if seg.Done == seg.End-seg.Start {
+			continue
+		}
+		g.Go(func(ctx context.Context) error {
+			return dl.fetchSegment(ctx, f, seg)
+		})
+	}
+	err := g.Wait()
+	if saveErr := dl.save(); err == nil {
+		err = saveErr
+	}
+	if errors.Is(err, ErrChanged) {
+		dl.discard()
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// resume opens partPath when the metadata file describes the same file as
+// fresh
+func (dl *download) resume(fresh state) (*os.File, bool) {
+	data, err := os.ReadFile(dl.metaPath)
+	if err != nil {
+		return nil, false
+	}
+	var saved state
+	if json.Unmarshal(data, &saved) != nil || saved.URL != fresh.URL || saved.Size != fresh.Size ||
+		saved.ETag != fresh.ETag || saved.LastModified != fresh.LastModified || len(saved.Segments) == 0 {
+		return nil, false
+	}
+	for _, seg := range saved.Segments {
+		if seg.Start < 0 || seg.End > saved.Size || seg.Done < 0 || seg.Done > seg.End-seg.Start {
+			return nil, false
+		}
+	}
+	f, err := os.OpenFile(dl.partPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, false
+	}
+	if info, err := f.Stat(); err != nil || info.Size() != saved.Size {
+		_ = f.Close()
+		return nil, false
+	}
+	dl.state = saved
+	return f, true
+}
+
+// fetchSegment downloads the rest of seg into f
+func (dl *download) fetchSegment(ctx context.Context, f *os.File, seg *segment) error {
+	from := seg.Start + seg.Done
+	req := dl.request(ctx).Header("Range", fmt.Sprintf("bytes=%d-%d", from, seg.End-1))
+	// the server answers 200 and the whole file if it changed since
+	if validator := dl.state.ETag; validator != "" && !strings.HasPrefix(validator, "W/") {
+		req.Header("If-Range", validator)
+	} else if dl.state.LastModified != "" {
+		req.Header("If-Range", dl.state.LastModified)
+	}
+	resp, err := req.Stream()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if start, _, ok := parseContentRange(resp.Header.Get("Content-Range")); resp.StatusCode != http.StatusPartialContent || !ok || start != from {
+		return ErrChanged
+	}
+	if err := dl.copy(ctx, f, resp.Body, seg); err != nil {
+		return err
+	}
+	if seg.Done != seg.End-seg.Start {
+		return fmt.Errorf("%w: bytes %d-%d ended at %d", ErrShortSegment, seg.Start, seg.End-1, seg.Start+seg.Done)
+	}
+	return nil
+}
+
+// copy reads body into w at the end of seg, counting the progress
+func (dl *download) copy(ctx context.Context, w io.WriterAt, body io.Reader, seg *segment) error {
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.WriteAt(buf[:n], seg.Start+seg.Done); werr != nil {
+				return werr
+			}
+			dl.advance(seg, int64(n))
+			if dl.throttle != nil {
+				if werr := dl.throttle.wait(ctx, n); werr != nil {
+					return werr
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// advance records n more bytes of seg written, saving the metadata now and
+// then so a crash loses little
+func (dl *download) advance(seg *segment, n int64) {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	seg.Done += n
+	dl.done += n
+	if dl.cfg.progress != nil {
+		dl.cfg.progress(dl.done, dl.state.Size)
+	}
+	if dl.state.Segments != nil && time.Since(dl.saved) >= dl.cfg.saveEvery {
+		_ = dl.saveLocked()
+	}
+}
+
+func (dl *download) save() error {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	return dl.saveLocked()
+}
+
+func (dl *download) saveLocked() error {
+	dl.saved = time.Now()
+	data, err := json.Marshal(dl.state)
+	if err != nil {
+		return err
+	}
+	// written aside and renamed, a crash never leaves half a metadata file
+	tmp := dl.metaPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dl.metaPath)
+}
+
+// discard removes the data and metadata of an earlier attempt
+func (dl *download) discard() {
+	_ = os.Remove(dl.partPath)
+	_ = os.Remove(dl.metaPath)
+}
+
+// split cuts size bytes in at most n segments of at least min bytes
+func split(size int64, n int, min int64) []*segment {
+	if min > 0 && size/min < int64(n) {
+		n = int(size / min)
+	}
+	if n < 1 {
+		n = 1
+	}
+	segments := make([]*segment, n)
+	for i := range segments {
+		segments[i] = &segment{Start: size * int64(i) / int64(n), End: size * int64(i+1) / int64(n)}
+	}
+	return segments
+}
+
+// parseContentRange returns the first byte and the total size of a
+// "bytes first-last/total" header, total is -1 for "*"
+func parseContentRange(s string) (int64, int64, bool) {
+	rest, ok := cutPrefix(s, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	span, total, ok := strings.Cut(rest, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	first, _, ok := strings.Cut(span, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if total == "*" {
+		return start, -1, true
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+func cutPrefix(s string, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
@@ -0,0 +1,194 @@
+package httpdl
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/cryptox"
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+var content = bytes.Repeat([]byte("0123456789abcdef"), 64<<10)
+
+// server serves content with range support, counting the bytes sent and
+// recording the Range headers
+type server struct {
+	*httptest.Server
+	sent   int64
+	mu     sync.Mutex
+	ranges []string
+	// abortAt cuts responses starting past 0 after that many bytes
+	abortAt int64
+}
+
+func newServer(t *testing.T) *server {
+	s := &server{}
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		s.mu.Unlock()
+		cw := &countingWriter{ResponseWriter: w, s: s}
+		if s.abortAt > 0 && r.Header.Get("Range") != "" && !strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") {
+			cw.limit = s.abortAt
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(cw, r, "file.bin", modified, bytes.NewReader(content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	s     *server
+	limit int64
+	n     int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.limit > 0 && w.n+int64(len(p)) > w.limit {
+		p = p[:w.limit-w.n]
+		_, _ = w.ResponseWriter.Write(p)
+		atomic.AddInt64(&w.s.sent, int64(len(p)))
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	w.n += int64(len(p))
+	atomic.AddInt64(&w.s.sent, int64(len(p)))
+	return w.ResponseWriter.Write(p)
+}
+
+func TestFetch(t *testing.T) {
+	s := newServer(t)
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	var last int64
+	err := New(gohttp.NewClient()).Fetch(context.Background(), s.URL, dst,
+		WithSegments(4),
+		WithMinSegmentSize(1<<10),
+		WithChecksum(crypto.SHA256, cryptox.SHA256(content)),
+		WithProgress(func(done int64, total int64) {
+			if total != int64(len(content)) || done < last {
+				t.Errorf("WithProgress() got = %v/%v after %v", done, total, last)
+			}
+			last = done
+		}))
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Errorf("Fetch() wrote %d bytes differing from the %d served", len(got), len(content))
+	}
+	if last != int64(len(content)) {
+		t.Errorf("WithProgress() ended at %v, want %v", last, len(content))
+	}
+	// the probe and one request per segment
+	if len(s.ranges) != 5 {
+		t.Errorf("Fetch() sent ranges %q, want a probe and 4 segments", s.ranges)
+	}
+	for _, leftover := range []string{dst + ".part", dst + ".part.json"} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("Fetch() left %v behind", leftover)
+		}
+	}
+}
+
+func TestFetchChecksum(t *testing.T) {
+	s := newServer(t)
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	err := New(gohttp.NewClient()).Fetch(context.Background(), s.URL, dst, WithChecksum(crypto.SHA256, cryptox.SHA256([]byte("other"))))
+	if !errors.Is(err, ErrChecksum) {
+		t.Fatalf("Fetch() error got = %v, want ErrChecksum", err)
+	}
+	for _, path := range []string{dst, dst + ".part", dst + ".part.json"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Fetch() left %v after a checksum mismatch", path)
+		}
+	}
+	if err := New(gohttp.NewClient()).Fetch(context.Background(), s.URL, dst, WithSegments(0)); err == nil {
+		t.Errorf("Fetch() WithSegments(0) got no error")
+	}
+}
+
+func TestFetchResume(t *testing.T) {
+	s := newServer(t)
+	s.abortAt = 100 << 10
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	d := New(gohttp.NewClient())
+	opts := []Option{WithSegments(4), WithMinSegmentSize(1 << 10)}
+	if err := d.Fetch(context.Background(), s.URL, dst, opts...); err == nil {
+		t.Fatalf("Fetch() got no error from a failing server")
+	}
+	if _, err := os.Stat(dst + ".part.json"); err != nil {
+		t.Fatalf("Fetch() kept no metadata to resume from: %v", err)
+	}
+
+	s.abortAt = 0
+	atomic.StoreInt64(&s.sent, 0)
+	if err := d.Fetch(context.Background(), s.URL, dst, opts...); err != nil {
+		t.Fatalf("Fetch() resume error = %v", err)
+	}
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Errorf("Fetch() resumed file differs from the content")
+	}
+	// the first segment and part of each other one were already there
+	if sent := atomic.LoadInt64(&s.sent); sent >= int64(len(content))*3/4 {
+		t.Errorf("Fetch() resume downloaded %v bytes of %v again", sent, len(content))
+	}
+}
+
+func TestFetchRestartsChangedFile(t *testing.T) {
+	s := newServer(t)
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	stale := `{"url":"` + s.URL + `","size":` + "1024" + `,"etag":"\"v0\"","segments":[{"start":0,"end":1024,"done":1024}]}`
+	os.WriteFile(dst+".part", make([]byte, 1024), 0o644)
+	os.WriteFile(dst+".part.json", []byte(stale), 0o644)
+	if err := New(gohttp.NewClient()).Fetch(context.Background(), s.URL, dst); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if got, _ := os.ReadFile(dst); !bytes.Equal(got, content) {
+		t.Errorf("Fetch() kept data of a stale download")
+	}
+}
+
+func TestFetchWithoutRanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content[:1000])
+	}))
+	defer server.Close()
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	if err := New(gohttp.NewClient()).Fetch(context.Background(), server.URL, dst); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if got, _ := os.ReadFile(dst); !bytes.Equal(got, content[:1000]) {
+		t.Errorf("Fetch() got %d bytes, want the 1000 served", len(got))
+	}
+}
+
+func TestFetchBandwidth(t *testing.T) {
+	s := newServer(t)
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := New(gohttp.NewClient()).Fetch(ctx, s.URL, dst, WithBandwidth(256<<10))
+	if err == nil {
+		t.Fatalf("Fetch() of 1MiB at 256KiB/s finished in %v", time.Since(start))
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Fetch() error got = %v, want the deadline", err)
+	}
+}
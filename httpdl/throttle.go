@@ -0,0 +1,37 @@
+package httpdl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// throttle spaces the reads of all segments to rate bytes per second. Bytes
+// are paid for after they are read, so a read waits for its own bytes.
+type throttle struct {
+	rate float64
+	mu   sync.Mutex
+	// paid is when the bytes read so far are paid for
+	paid time.Time
+}
+
+func (t *throttle) wait(ctx context.Context, n int) error {
+	t.mu.Lock()
+	now := time.Now()
+	if t.paid.Before(now) {
+		// idle time is not saved up for a burst
+		t.paid = now
+	}
+	t.paid = t.paid.Add(time.Duration(float64(n) / t.rate * float64(time.Second)))
+	d := t.paid.Sub(now)
+	t.mu.Unlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,219 @@
+package httpmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoStub is returned by Transport for requests no stub matches
+var ErrNoStub = errors.New("httpmock: no stub matches the request")
+
+// TestingT is the subset of testing.TB used by this package
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Transport is a http.RoundTripper answering with canned responses, install it
+// with http.WithTransport. Stubs are matched in registration order.
+type Transport struct {
+	recorder Recorder
+
+	mu    sync.Mutex
+	stubs []*Stub
+}
+
+func NewTransport() *Transport {
+	return &Transport{}
+}
+
+// On registers a stub for method and rawURL. Without a query in rawURL the
+// request query is ignored, otherwise it must hold the same values.
+func (t *Transport) On(method string, rawURL string) *Stub {
+	s := &Stub{
+		method: strings.ToUpper(method),
+		status: http.StatusOK,
+		header: make(http.Header),
+		times:  -1,
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		s.err = fmt.Errorf("httpmock: invalid stub url %q: %w", rawURL, err)
+	} else {
+		s.url = u
+	}
+	t.mu.Lock()
+	t.stubs = append(t.stubs, s)
+	t.mu.Unlock()
+	return s
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := t.recorder.record(req)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	var stub *Stub
+	for _, s := range t.stubs {
+		if s.times != 0 && s.matches(req, recorded.Body) {
+			stub = s
+			if s.times > 0 {
+				s.times--
+			}
+			s.calls++
+			break
+		}
+	}
+	t.mu.Unlock()
+	if stub == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoStub, req.Method, req.URL)
+	}
+	return stub.response(req)
+}
+
+// Requests returns the requests received so far, matched or not
+func (t *Transport) Requests() []RecordedRequest {
+	return t.recorder.Requests()
+}
+
+// Verify reports stubs that were never called
+func (t *Transport) Verify(tb TestingT) {
+	tb.Helper()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range t.stubs {
+		if s.calls == 0 {
+			tb.Errorf("httpmock: stub %s %s was never called", s.method, s.url)
+		}
+	}
+}
+
+// Stub is a canned response for matching requests, configure it with the chained methods
+type Stub struct {
+	method   string
+	url      *url.URL
+	body     *[]byte
+	jsonBody any
+	match    func(req *http.Request, body []byte) bool
+
+	status       int
+	header       http.Header
+	responseBody []byte
+	err          error
+	times        int
+	calls        int
+}
+
+// WithBody only matches requests whose body equals body
+func (s *Stub) WithBody(body string) *Stub {
+	b := []byte(body)
+	s.body = &b
+	return s
+}
+
+// WithJSONBody only matches requests whose body is JSON equal to v
+func (s *Stub) WithJSONBody(v any) *Stub {
+	s.jsonBody = v
+	return s
+}
+
+// Match adds a custom condition on the request
+func (s *Stub) Match(fn func(req *http.Request, body []byte) bool) *Stub {
+	s.match = fn
+	return s
+}
+
+// Reply sets the response status and body
+func (s *Stub) Reply(status int, body string) *Stub {
+	s.status = status
+	s.responseBody = []byte(body)
+	return s
+}
+
+// ReplyJSON sets the response status and a JSON encoded body
+func (s *Stub) ReplyJSON(status int, v any) *Stub {
+	body, err := json.Marshal(v)
+	if err != nil {
+		s.err = fmt.Errorf("httpmock: encode reply: %w", err)
+	}
+	s.status = status
+	s.responseBody = body
+	s.header.Set("Content-Type", "application/json")
+	return s
+}
+
+// Header adds a response header
+func (s *Stub) Header(key string, value string) *Stub {
+	s.header.Add(key, value)
+	return s
+}
+
+// Error makes the transport fail with err instead of responding
+func (s *Stub) Error(err error) *Stub {
+	s.err = err
+	return s
+}
+
+// Times limits how many requests the stub answers, unlimited by default
+func (s *Stub) Times(n int) *Stub {
+	s.times = n
+	return s
+}
+
+func (s *Stub) matches(req *http.Request, body []byte) bool {
+	if s.method != req.Method || s.url == nil {
+		return false
+	}
+	if s.url.Scheme != req.URL.Scheme || s.url.Host != req.URL.Host || s.url.Path != req.URL.Path {
+		return false
+	}
+	if s.url.RawQuery != "" && !reflect.DeepEqual(s.url.Query(), req.URL.Query()) {
+		return false
+	}
+	if s.body != nil && !bytes.Equal(*s.body, body) {
+		return false
+	}
+	if s.jsonBody != nil && !jsonEqual(s.jsonBody, body) {
+		return false
+	}
+	return s.match == nil || s.match(req, body)
+}
+
+func (s *Stub) response(req *http.Request) (*http.Response, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(s.status) + " " + http.StatusText(s.status),
+		StatusCode:    s.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        s.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(s.responseBody)),
+		ContentLength: int64(len(s.responseBody)),
+		Request:       req,
+	}, nil
+}
+
+func jsonEqual(want any, body []byte) bool {
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		return false
+	}
+	var w, g any
+	if json.Unmarshal(wantJSON, &w) != nil || json.Unmarshal(body, &g) != nil {
+		return false
+	}
+	return reflect.DeepEqual(w, g)
+}
@@ -0,0 +1,137 @@
+package httpmock
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (*fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestTransport(t *testing.T) {
+	mock := NewTransport()
+	mock.On("GET", "http://api.example.com/users/1").ReplyJSON(http.StatusOK, map[string]any{"id": 1})
+	mock.On("GET", "http://api.example.com/search?q=go").Reply(http.StatusOK, "found")
+	mock.On("POST", "http://api.example.com/users").WithJSONBody(map[string]any{"name": "bob"}).Reply(http.StatusOK, "created").Times(1)
+	mock.On("DELETE", "http://api.example.com/users/1").Error(errors.New("boom"))
+	client := gohttp.NewClient(gohttp.WithTransport(mock))
+
+	tests := []struct {
+		name     string
+		call     func() (int, http.Header, any, error)
+		wantCode int
+		wantBody string
+		wantErr  bool
+	}{
+		{
+			name:     "json reply",
+			call:     func() (int, http.Header, any, error) { return client.Get("http://api.example.com/users/1", nil, nil) },
+			wantCode: http.StatusOK,
+			wantBody: `{"id":1}`,
+		},
+		{
+			name: "query match",
+			call: func() (int, http.Header, any, error) {
+				return client.Get("http://api.example.com/search", nil, map[string]string{"q": "go"})
+			},
+			wantCode: http.StatusOK,
+			wantBody: "found",
+		},
+		{
+			name: "query mismatch",
+			call: func() (int, http.Header, any, error) {
+				return client.Get("http://api.example.com/search", nil, map[string]string{"q": "rust"})
+			},
+			wantCode: -1,
+			wantErr:  true,
+		},
+		{
+			name: "body match",
+			call: func() (int, http.Header, any, error) {
+				return client.Post("http://api.example.com/users", nil, nil, map[string]string{"name": "bob"})
+			},
+			wantCode: http.StatusOK,
+			wantBody: "created",
+		},
+		{
+			name: "times exhausted",
+			call: func() (int, http.Header, any, error) {
+				return client.Post("http://api.example.com/users", nil, nil, map[string]string{"name": "bob"})
+			},
+			wantCode: -1,
+			wantErr:  true,
+		},
+		{
+			name: "stubbed error",
+			call: func() (int, http.Header, any, error) {
+				return client.Delete("http://api.example.com/users/1", nil, nil)
+			},
+			wantCode: -1,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, data, err := tt.call()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("call error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code != tt.wantCode {
+				t.Errorf("call got = %v, want %v", code, tt.wantCode)
+			}
+			if tt.wantBody != "" && string(data.([]byte)) != tt.wantBody {
+				t.Errorf("call body got = %s, want %s", data, tt.wantBody)
+			}
+		})
+	}
+
+	if got := len(mock.Requests()); got != len(tests) {
+		t.Errorf("Requests() got = %d, want %d", got, len(tests))
+	}
+	mock.Verify(t)
+
+	unused := NewTransport()
+	unused.On("GET", "http://api.example.com/never")
+	ft := &fakeT{}
+	unused.Verify(ft)
+	if len(ft.errors) != 1 {
+		t.Errorf("Verify() got errors = %v, want 1", ft.errors)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(nil)
+	client := gohttp.NewClient(gohttp.WithTransport(recorder))
+	code, _, _, err := client.Put(server.URL+"/items/7", map[string]string{"X-Test": "1"}, nil, map[string]int{"qty": 2})
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("Put() got = %v, error = %v", code, err)
+	}
+	req, ok := recorder.LastRequest()
+	if !ok {
+		t.Fatalf("LastRequest() got none")
+	}
+	if req.Method != http.MethodPut || req.URL.Path != "/items/7" || req.Header.Get("X-Test") != "1" || string(req.Body) != `{"qty":2}` {
+		t.Errorf("LastRequest() got = %+v", req)
+	}
+	recorder.Reset()
+	if _, ok := recorder.LastRequest(); ok {
+		t.Errorf("LastRequest() after Reset got a request")
+	}
+}
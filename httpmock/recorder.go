@@ -0,0 +1,92 @@
+package httpmock
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// RecordedRequest is a copy of an outgoing request
+type RecordedRequest struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// Recorder captures outgoing requests and forwards them to Next,
+// http.DefaultTransport when nil.
+type Recorder struct {
+	Next http.RoundTripper
+
+	mu       sync.Mutex
+	requests []RecordedRequest
+}
+
+func NewRecorder(next http.RoundTripper) *Recorder {
+	return &Recorder{Next: next}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, err := r.record(req); err != nil {
+		return nil, err
+	}
+	next := r.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(req)
+}
+
+// Requests returns the recorded requests in the order they were sent
+func (r *Recorder) Requests() []RecordedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	requests := make([]RecordedRequest, len(r.requests))
+	copy(requests, r.requests)
+	return requests
+}
+
+// LastRequest returns the most recent request
+func (r *Recorder) LastRequest() (RecordedRequest, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.requests) == 0 {
+		return RecordedRequest{}, false
+	}
+	return r.requests[len(r.requests)-1], true
+}
+
+// Reset forgets the recorded requests
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.requests = nil
+	r.mu.Unlock()
+}
+
+// record copies req, its body is read and replaced so it can still be sent
+func (r *Recorder) record(req *http.Request) (RecordedRequest, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return RecordedRequest{}, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	u := *req.URL
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    &u,
+		Header: req.Header.Clone(),
+		Body:   body,
+	}
+	r.mu.Lock()
+	r.requests = append(r.requests, recorded)
+	r.mu.Unlock()
+	return recorded, nil
+}
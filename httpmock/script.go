@@ -0,0 +1,164 @@
+package httpmock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Step is one scripted answer of a ScriptServer
+type Step struct {
+	Status int
+	Header http.Header
+	Body   string
+	// Delay is waited before answering
+	Delay time.Duration
+	// Repeat is how many requests the step answers, 1 when zero
+	Repeat int
+	etag   string
+}
+
+// Respond creates a step answering status and body
+func Respond(status int, body string) Step {
+	return Step{Status: status, Body: body, Header: make(http.Header)}
+}
+
+// RetryAfter creates a 429 step asking the client to wait d
+func RetryAfter(d time.Duration) Step {
+	return Respond(http.StatusTooManyRequests, "").WithHeader("Retry-After", strconv.Itoa(int(d/time.Second)))
+}
+
+// WithHeader adds a response header
+func (s Step) WithHeader(key string, value string) Step {
+	header := s.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Add(key, value)
+	s.Header = header
+	return s
+}
+
+// WithETag sets the ETag header, requests whose If-None-Match matches it get a 304
+func (s Step) WithETag(etag string) Step {
+	s.etag = etag
+	return s.WithHeader("ETag", etag)
+}
+
+// WithDelay makes the step wait d before answering
+func (s Step) WithDelay(d time.Duration) Step {
+	s.Delay = d
+	return s
+}
+
+// Times makes the step answer n requests
+func (s Step) Times(n int) Step {
+	s.Repeat = n
+	return s
+}
+
+// ScriptServer answers requests following a timeline of steps, e.g. a 500,
+// then a 429 with Retry-After, then a 200 with an ETag. Once the script is
+// exhausted the last step keeps answering. It records when each attempt
+// arrived so retry, cache and circuit breaker behaviour can be asserted.
+type ScriptServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	steps    []Step
+	step     int
+	used     int
+	attempts []time.Time
+}
+
+// NewScriptServer starts a server playing steps, Close it when done
+func NewScriptServer(steps ...Step) *ScriptServer {
+	s := &ScriptServer{steps: steps}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *ScriptServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.attempts = append(s.attempts, time.Now())
+	if len(s.steps) == 0 {
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	step := s.steps[s.step]
+	s.used++
+	repeat := step.Repeat
+	if repeat <= 0 {
+		repeat = 1
+	}
+	if s.used >= repeat && s.step < len(s.steps)-1 {
+		s.step++
+		s.used = 0
+	}
+	s.mu.Unlock()
+
+	if step.Delay > 0 {
+		select {
+		case <-time.After(step.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	for k, v := range step.Header {
+		w.Header()[k] = v
+	}
+	if step.etag != "" && r.Header.Get("If-None-Match") == step.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	status := step.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(step.Body))
+}
+
+// Attempts returns how many requests arrived
+func (s *ScriptServer) Attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.attempts)
+}
+
+// Intervals returns the time between consecutive attempts
+func (s *ScriptServer) Intervals() []time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var intervals []time.Duration
+	for i := 1; i < len(s.attempts); i++ {
+		intervals = append(intervals, s.attempts[i].Sub(s.attempts[i-1]))
+	}
+	return intervals
+}
+
+// AssertAttempts reports an error unless exactly n requests arrived
+func (s *ScriptServer) AssertAttempts(t TestingT, n int) {
+	t.Helper()
+	if got := s.Attempts(); got != n {
+		t.Errorf("httpmock: attempts got = %d, want %d", got, n)
+	}
+}
+
+// AssertMinIntervals reports an error when attempt i+1 came sooner than min[i] after attempt i
+func (s *ScriptServer) AssertMinIntervals(t TestingT, min ...time.Duration) {
+	t.Helper()
+	intervals := s.Intervals()
+	for i, want := range min {
+		if i >= len(intervals) {
+			t.Errorf("httpmock: interval %d missing, only %d attempts", i, len(intervals)+1)
+			return
+		}
+		if intervals[i] < want {
+			t.Errorf("httpmock: interval %d got = %v, want >= %v", i, intervals[i], want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package httpmock
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+func TestScriptServer(t *testing.T) {
+	server := NewScriptServer(
+		Respond(http.StatusInternalServerError, "boom").Times(2),
+		RetryAfter(time.Second),
+		Respond(http.StatusOK, "fresh").WithETag(`"v1"`),
+	)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		header     map[string]string
+		wait       time.Duration
+		wantCode   int
+		wantHeader [2]string
+	}{
+		{name: "first failure", wantCode: http.StatusInternalServerError},
+		{name: "second failure", wantCode: http.StatusInternalServerError},
+		{name: "rate limited", wantCode: http.StatusTooManyRequests, wantHeader: [2]string{"Retry-After", "1"}},
+		{name: "success", wait: 30 * time.Millisecond, wantCode: http.StatusOK, wantHeader: [2]string{"Etag", `"v1"`}},
+		{name: "not modified", header: map[string]string{"If-None-Match": `"v1"`}, wantCode: http.StatusNotModified},
+		{name: "last step repeats", wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			time.Sleep(tt.wait)
+			code, header, _, _ := gohttp.Get(server.URL, tt.header, nil)
+			if code != tt.wantCode {
+				t.Errorf("Get() got = %v, want %v", code, tt.wantCode)
+			}
+			if tt.wantHeader[0] != "" && header.Get(tt.wantHeader[0]) != tt.wantHeader[1] {
+				t.Errorf("Get() header %s got = %v, want %v", tt.wantHeader[0], header.Get(tt.wantHeader[0]), tt.wantHeader[1])
+			}
+		})
+	}
+	server.AssertAttempts(t, len(tests))
+	server.AssertMinIntervals(t, 0, 0, 30*time.Millisecond)
+
+	ft := &fakeT{}
+	server.AssertAttempts(ft, 1)
+	server.AssertMinIntervals(ft, time.Hour)
+	if len(ft.errors) != 2 {
+		t.Errorf("assertions got errors = %v, want 2", ft.errors)
+	}
+}
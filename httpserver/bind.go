@@ -0,0 +1,114 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Stellar1999/gotool/errorx"
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/Stellar1999/gotool/validate"
+)
+
+type bindConfig struct {
+	maxBytes     int64
+	allowUnknown bool
+	validate     func(v any) error
+}
+
+type BindOption = opt.Option[bindConfig]
+
+// WithMaxBodyBytes limits the body Bind reads, 1MB by default
+func WithMaxBodyBytes(n int64) BindOption {
+	return func(c *bindConfig) {
+		c.maxBytes = n
+	}
+}
+
+// WithUnknownFields accepts fields the struct does not have, they are
+// rejected by default to catch typos of clients
+func WithUnknownFields() BindOption {
+	return func(c *bindConfig) {
+		c.allowUnknown = true
+	}
+}
+
+// WithValidator validates with v instead of the default validate.Validator,
+// e.g. one with custom rules. A nil v skips validation.
+func WithValidator(v *validate.Validator) BindOption {
+	return func(c *bindConfig) {
+		c.validate = nil
+		if v != nil {
+			c.validate = v.Struct
+		}
+	}
+}
+
+// Bind decodes the JSON body of r into v and validates it with its validate
+// tags. Its errors are errorx errors ready for Error: InvalidArgument for a
+// malformed or invalid body, with the failing fields as details, and
+// unsupported media types or too large bodies with their own statuses.
+func Bind(r *http.Request, v any, opts ...BindOption) error {
+	cfg := opt.Apply(&bindConfig{maxBytes: 1 << 20, validate: validate.Struct}, opts...)
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+			return errorx.New(errorx.InvalidArgument, "content type "+ct,
+				errorx.WithStatus(http.StatusUnsupportedMediaType),
+				errorx.WithUserMessage("the body must be JSON"))
+		}
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, cfg.maxBytes+1))
+	if err != nil {
+		return errorx.Wrap(err, errorx.InvalidArgument, "read body")
+	}
+	if int64(len(body)) > cfg.maxBytes {
+		return errorx.New(errorx.InvalidArgument, "body too large",
+			errorx.WithStatus(http.StatusRequestEntityTooLarge),
+			errorx.WithUserMessage(fmt.Sprintf("the body must not exceed %d bytes", cfg.maxBytes)))
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if !cfg.allowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return errorx.Wrap(err, errorx.InvalidArgument, "decode body", errorx.WithUserMessage(decodeMessage(err)))
+	}
+	if dec.More() {
+		return errorx.New(errorx.InvalidArgument, "data after the body", errorx.WithUserMessage("the body must hold a single JSON value"))
+	}
+	if cfg.validate == nil {
+		return nil
+	}
+	verr := cfg.validate(v)
+	var fields validate.Errors
+	if errors.As(verr, &fields) {
+		return errorx.Wrap(verr, errorx.InvalidArgument, "", errorx.WithUserMessage("invalid fields"), errorx.WithDetail("fields", fields.Fields()))
+	}
+	if verr != nil {
+		return errorx.Wrap(verr, errorx.Internal, "validate body")
+	}
+	return nil
+}
+
+// decodeMessage describes a decoding error without echoing the body
+func decodeMessage(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "the body is empty"
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		return fmt.Sprintf("field %s must be %v", typeErr.Field, typeErr.Type)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return "unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+	}
+	return "malformed JSON"
+}
@@ -0,0 +1,130 @@
+package httpserver
+
+import (
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/Stellar1999/gotool/errorx"
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/Stellar1999/gotool/randx"
+)
+
+// ChaosHeader names the fault Chaos injected in a response, to tell
+// injected failures from real ones
+const ChaosHeader = "X-Chaos-Fault"
+
+// Fault is a failure Chaos injects into a share of the requests
+type Fault struct {
+	// Paths are path.Match patterns of the URL path, such as "/orders/*".
+	// None match every path.
+	Paths []string
+	// Percent of the matching requests hit by the fault, 0 to 100
+	Percent float64
+	// Latency delays the request by Latency and up to Jitter more, then the
+	// request is answered by the handler unless Statuses or Drop are set
+	Latency time.Duration
+	Jitter  time.Duration
+	// Statuses answers with one of these statuses picked at random instead of
+	// calling the handler, e.g. 500, 502 and 503 for random server errors
+	Statuses []int
+	// Drop closes the connection without answering
+	Drop bool
+}
+
+func (f *Fault) matches(p string) bool {
+	if len(f.Paths) == 0 {
+		return true
+	}
+	for _, pattern := range f.Paths {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type chaosConfig struct {
+	enabled func(r *http.Request) bool
+	rand    *randx.Rand
+}
+
+type ChaosOption = opt.Option[chaosConfig]
+
+// WithChaosEnabled turns faults on for the requests enabled returns true
+// for, e.g. reading a flag or a header set by a test client. Faults are
+// always on by default.
+func WithChaosEnabled(enabled func(r *http.Request) bool) ChaosOption {
+	return func(c *chaosConfig) {
+		c.enabled = enabled
+	}
+}
+
+// WithChaosRand draws the hit requests, latencies and statuses from r, a
+// randx.NewSeeded one repeats the same faults on every run
+func WithChaosRand(r *randx.Rand) ChaosOption {
+	return func(c *chaosConfig) {
+		c.rand = r
+	}
+}
+
+// Chaos injects faults into requests to test how clients cope with slow and
+// failing services, their retries, hedging and circuit breakers. Meant for
+// staging, not production. The first fault whose paths match and whose
+// percent hits the request is injected, at most one per request.
+//
+//	handler = httpserver.Chain(handler, httpserver.Chaos([]httpserver.Fault{
+//		{Paths: []string{"/orders/*"}, Percent: 5, Statuses: []int{500, 503}},
+//		{Percent: 10, Latency: 200 * time.Millisecond, Jitter: time.Second},
+//	}))
+func Chaos(faults []Fault, opts ...ChaosOption) Middleware {
+	cfg := opt.Apply(&chaosConfig{}, opts...)
+	rng := func() *randx.Rand {
+		if cfg.rand != nil {
+			return cfg.rand
+		}
+		return randx.Default()
+	}
+	pick := func(r *http.Request) *Fault {
+		if cfg.enabled != nil && !cfg.enabled(r) {
+			return nil
+		}
+		for i := range faults {
+			f := &faults[i]
+			if f.matches(r.URL.Path) && rng().Float64()*100 < f.Percent {
+				return f
+			}
+		}
+		return nil
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			f := pick(r)
+			if f == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if delay := f.Latency + time.Duration(rng().Float64()*float64(f.Jitter)); delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					timer.Stop()
+					return
+				}
+			}
+			switch {
+			case f.Drop:
+				// the server closes the connection without a response
+				panic(http.ErrAbortHandler)
+			case len(f.Statuses) > 0:
+				code := randx.Choice(rng(), f.Statuses)
+				w.Header().Set(ChaosHeader, "status")
+				Error(w, errorx.New(errorx.CodeFromStatus(code), "chaos: injected fault", errorx.WithStatus(code)))
+			default:
+				w.Header().Set(ChaosHeader, "latency")
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package httpserver
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/errorx"
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/randx"
+)
+
+type signup struct {
+	Email string `json:"email" validate:"required,email"`
+	Age   int    `json:"age" validate:"gte=18"`
+}
+
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder) errorx.Response {
+	t.Helper()
+	var resp errorx.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return resp
+}
+
+func TestJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	JSON(rec, http.StatusCreated, map[string]string{"q": "a<b"})
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != "application/json" || rec.Body.String() != "{\"q\":\"a<b\"}\n" {
+		t.Errorf("JSON() got = %v %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	JSON(rec, http.StatusOK, func() {})
+	if rec.Code != http.StatusInternalServerError || decodeResponse(t, rec).Code != errorx.Internal {
+		t.Errorf("JSON() unencodable got = %v %q, want an internal error", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	Error(rec, errorx.New(errorx.NotFound, "user 7 in shard 3", errorx.WithUserMessage("no such user")))
+	if resp := decodeResponse(t, rec); rec.Code != http.StatusNotFound || resp.Message != "no such user" {
+		t.Errorf("Error() got = %v %+v", rec.Code, resp)
+	}
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        []BindOption
+		status      int
+		message     string
+	}{
+		{"valid", "application/json", `{"email":"a@b.io","age":30}`, nil, 0, ""},
+		{"charset", "application/json; charset=utf-8", `{"email":"a@b.io","age":30}`, nil, 0, ""},
+		{"no content type", "", `{"email":"a@b.io","age":30}`, nil, 0, ""},
+		{"invalid", "application/json", `{"email":"nope","age":3}`, nil, 400, "invalid fields"},
+		{"no validation", "application/json", `{"email":"nope","age":3}`, []BindOption{WithValidator(nil)}, 0, ""},
+		{"unknown field", "application/json", `{"email":"a@b.io","age":30,"admin":true}`, nil, 400, "unknown field \"admin\""},
+		{"unknown allowed", "application/json", `{"email":"a@b.io","age":30,"admin":true}`, []BindOption{WithUnknownFields()}, 0, ""},
+		{"wrong type", "application/json", `{"email":"a@b.io","age":"30"}`, nil, 400, "field age must be int"},
+		{"malformed", "application/json", `{"email":`, nil, 400, "malformed JSON"},
+		{"empty", "application/json", ``, nil, 400, "the body is empty"},
+		{"trailing", "application/json", `{"email":"a@b.io","age":30} {}`, nil, 400, "the body must hold a single JSON value"},
+		{"form", "application/x-www-form-urlencoded", `email=a`, nil, 415, "the body must be JSON"},
+		{"too large", "application/json", `{"email":"a@b.io","age":30}`, []BindOption{WithMaxBodyBytes(10)}, 413, "the body must not exceed 10 bytes"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		var v signup
+		err := Bind(req, &v, tt.opts...)
+		if tt.status == 0 {
+			if err != nil {
+				t.Errorf("Bind(%v) error = %v", tt.name, err)
+			}
+			continue
+		}
+		if got := errorx.StatusOf(err); got != tt.status {
+			t.Errorf("Bind(%v) status got = %v, want %v", tt.name, got, tt.status)
+		}
+		if got := errorx.UserMessageOf(err); !strings.HasPrefix(got, tt.message) {
+			t.Errorf("Bind(%v) message got = %q, want %q", tt.name, got, tt.message)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(`{"age":3}`))
+	rec := httptest.NewRecorder()
+	Error(rec, Bind(req, &signup{}))
+	fields, _ := decodeResponse(t, rec).Details["fields"].(map[string]any)
+	if len(fields) != 2 || fields["email"] == nil || fields["age"] == nil {
+		t.Errorf("Bind() details got = %v, want both fields", fields)
+	}
+}
+
+type recordingLogger struct {
+	gohttp.Logger
+	lines []string
+}
+
+func (l *recordingLogger) log(level string, msg string, kv ...any) {
+	var b strings.Builder
+	b.WriteString(level + " " + msg)
+	for i := 0; i+1 < len(kv); i += 2 {
+		b.WriteString(" ")
+		b.WriteString(kv[i].(string))
+		b.WriteString("=")
+		b.WriteString(strings.SplitN(strings.TrimSpace(toString(kv[i+1])), "\n", 2)[0])
+	}
+	l.lines = append(l.lines, b.String())
+}
+
+func toString(v any) string {
+	data, _ := json.Marshal(v)
+	return strings.Trim(string(data), `"`)
+}
+
+func (l *recordingLogger) Info(msg string, kv ...any)  { l.log("info", msg, kv...) }
+func (l *recordingLogger) Warn(msg string, kv ...any)  { l.log("warn", msg, kv...) }
+func (l *recordingLogger) Error(msg string, kv ...any) { l.log("error", msg, kv...) }
+
+func TestMiddleware(t *testing.T) {
+	logger := &recordingLogger{}
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+		JSON(w, http.StatusOK, map[string]string{"id": RequestIDFromContext(r.Context())})
+	}), mark("first"), RequestID(), AccessLog(logger), Recover(logger), mark("last"))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	handler.ServeHTTP(rec, req)
+	if strings.Join(order, ",") != "first,last" {
+		t.Errorf("Chain() order got = %v", order)
+	}
+	if rec.Header().Get(RequestIDHeader) != "req-1" || !strings.Contains(rec.Body.String(), `"req-1"`) {
+		t.Errorf("RequestID() got = %v %q", rec.Header(), rec.Body.String())
+	}
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "info request method=GET path=/ok status=200") || !strings.Contains(logger.lines[0], "request_id=req-1") {
+		t.Errorf("AccessLog() got = %v", logger.lines)
+	}
+
+	logger.lines = nil
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "boom") {
+		t.Errorf("Recover() got = %v %q, want a 500 hiding the panic", rec.Code, rec.Body.String())
+	}
+	id := rec.Header().Get(RequestIDHeader)
+	if len(id) != 36 {
+		t.Errorf("RequestID() generated got = %q, want a UUID", id)
+	}
+	if len(logger.lines) != 2 || !strings.Contains(logger.lines[0], "error handler panicked") || !strings.Contains(logger.lines[0], "panic=boom") ||
+		!strings.Contains(logger.lines[1], "error request") || !strings.Contains(logger.lines[1], "status=500") {
+		t.Errorf("Recover() logs got = %v", logger.lines)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	var left time.Duration
+	var budgeted bool
+	handler := Budget("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		left, budgeted = gohttp.BudgetFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(gohttp.BudgetHeader, "250")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if !budgeted || left <= 200*time.Millisecond || left > 250*time.Millisecond {
+		t.Errorf("Budget() got = %v %v, want about 250ms left", left, budgeted)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if budgeted {
+		t.Errorf("Budget() started a budget without the header")
+	}
+}
+
+func TestChaos(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+	serve := func(h http.Handler, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	handler := Chaos([]Fault{{Paths: []string{"/orders/*"}, Percent: 100, Statuses: []int{http.StatusServiceUnavailable}}})(ok)
+	if rec := serve(handler, "/orders/7"); rec.Code != http.StatusServiceUnavailable || rec.Header().Get(ChaosHeader) != "status" {
+		t.Errorf("Chaos() got = %v %v, want an injected 503", rec.Code, rec.Header())
+	}
+	if rec := serve(handler, "/users/7"); rec.Code != http.StatusTeapot {
+		t.Errorf("Chaos() got = %v for another path, want the handler", rec.Code)
+	}
+
+	handler = Chaos([]Fault{{Percent: 30, Statuses: []int{500, 502}}}, WithChaosRand(randx.NewSeeded(1)))(ok)
+	hits := 0
+	for i := 0; i < 1000; i++ {
+		switch serve(handler, "/").Code {
+		case 500, 502:
+			hits++
+		case http.StatusTeapot:
+		default:
+			t.Fatalf("Chaos() answered with an unexpected status")
+		}
+	}
+	if hits < 250 || hits > 350 {
+		t.Errorf("Chaos() hit %v of 1000 requests, want about 30%%", hits)
+	}
+
+	enabled := false
+	handler = Chaos([]Fault{{Percent: 100, Latency: 30 * time.Millisecond}}, WithChaosEnabled(func(*http.Request) bool { return enabled }))(ok)
+	start := time.Now()
+	if rec := serve(handler, "/"); rec.Code != http.StatusTeapot || rec.Header().Get(ChaosHeader) != "" || time.Since(start) > 20*time.Millisecond {
+		t.Errorf("Chaos() disabled got = %v %v", rec.Header(), time.Since(start))
+	}
+	enabled = true
+	start = time.Now()
+	if rec := serve(handler, "/"); rec.Code != http.StatusTeapot || rec.Header().Get(ChaosHeader) != "latency" || time.Since(start) < 30*time.Millisecond {
+		t.Errorf("Chaos() latency got = %v %v, want the handler after 30ms", rec.Header(), time.Since(start))
+	}
+
+	server := httptest.NewServer(Chaos([]Fault{{Percent: 100, Drop: true}})(ok))
+	defer server.Close()
+	if resp, err := http.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Errorf("Chaos() drop got = %v, want the connection closed", resp.Status)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+	tests := []struct {
+		name    string
+		opts    []CORSOption
+		method  string
+		origin  string
+		status  int
+		allow   string
+		methods string
+	}{
+		{"any origin", []CORSOption{WithOrigins("*")}, http.MethodGet, "https://a.io", http.StatusTeapot, "*", ""},
+		{"listed", []CORSOption{WithOrigins("https://a.io")}, http.MethodGet, "https://a.io", http.StatusTeapot, "https://a.io", ""},
+		{"not listed", []CORSOption{WithOrigins("https://a.io")}, http.MethodGet, "https://b.io", http.StatusTeapot, "", ""},
+		{"credentials", []CORSOption{WithOrigins("*"), WithCredentials()}, http.MethodGet, "https://b.io", http.StatusTeapot, "https://b.io", ""},
+		{"preflight", []CORSOption{WithOrigins("*"), WithMethods("GET", "PUT"), WithMaxAge(time.Hour)}, http.MethodOptions, "https://a.io", http.StatusNoContent, "*", "GET, PUT"},
+		{"no origin", []CORSOption{WithOrigins("*")}, http.MethodOptions, "", http.StatusTeapot, "", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		req.Header.Set("Access-Control-Request-Method", "PUT")
+		rec := httptest.NewRecorder()
+		CORS(tt.opts...)(ok).ServeHTTP(rec, req)
+		h := rec.Header()
+		if rec.Code != tt.status || h.Get("Access-Control-Allow-Origin") != tt.allow || h.Get("Access-Control-Allow-Methods") != tt.methods {
+			t.Errorf("CORS(%v) got = %v %v", tt.name, rec.Code, h)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	body := strings.Repeat("compress me ", 100)
+	handler := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Length", "1200")
+		_, _ = io.WriteString(w, body)
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Content-Length") != "" {
+		t.Fatalf("Gzip() header got = %v", rec.Header())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != body {
+		t.Errorf("Gzip() body got = %q", data)
+	}
+
+	for _, accept := range []string{"", "gzip;q=0", "br"} {
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		handler.ServeHTTP(rec, req)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != body {
+			t.Errorf("Gzip(%q) got = %v, want the plain body", accept, rec.Header())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/empty", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+		t.Errorf("Gzip() 204 got = %v %v %q", rec.Code, rec.Header(), rec.Body.Bytes())
+	}
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, srv, WithListener(l), WithShutdownTimeout(5*time.Second)) }()
+
+	got := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String())
+		if err != nil {
+			got <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		got <- string(data)
+	}()
+	<-started
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if body := <-got; body != "done" {
+		t.Errorf("Serve() request in flight got = %q, want it to finish", body)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+
+	l, _ = net.Listen("tcp", "127.0.0.1:0")
+	defer l.Close()
+	srv = &http.Server{Addr: l.Addr().String()}
+	if err := Serve(context.Background(), srv); err == nil || errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Serve() busy address error = %v", err)
+	}
+	if err := Serve(context.Background(), srv, WithShutdownTimeout(0)); err == nil {
+		t.Errorf("Serve() invalid option error = %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package httpserver
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Stellar1999/gotool/errorx"
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/idgen"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Middleware wraps a handler
+type Middleware func(next http.Handler) http.Handler
+
+// Chain wraps h with mws, the first one is the outermost and sees requests
+// first
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// recorder remembers the status and size of a response
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("httpserver: response does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// Recover answers a panicking handler with a 500 error and logs the panic
+// with its stack. http.ErrAbortHandler is raised again, it aborts the
+// response on purpose.
+func Recover(logger gohttp.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &recorder{ResponseWriter: w}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				logger.Error("handler panicked", "method", r.Method, "path", r.URL.Path,
+					"request_id", RequestIDFromContext(r.Context()), "panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+				if rec.status == 0 {
+					Error(w, errorx.New(errorx.Internal, fmt.Sprint("panic: ", p)))
+				}
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// RequestIDHeader carries the request ID
+const RequestIDHeader = gohttp.RequestIDHeader
+
+// RequestID keeps the X-Request-Id of the request, or sets a new UUID, and
+// echoes it in the response. Handlers read it with RequestIDFromContext, and
+// a gohttp.RequestIDHook sends it on with the requests made with the request
+// context.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if id == "" || len(id) > 128 {
+				id = idgen.UUIDv4()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(gohttp.ContextWithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// RequestIDFromContext returns the ID set by RequestID, "" without it
+func RequestIDFromContext(ctx context.Context) string {
+	return gohttp.RequestIDFromContext(ctx)
+}
+
+// Budget starts a gohttp.WithBudget for the request from the header name,
+// gohttp.BudgetHeader when empty, so the requests the handler sends with
+// the request context give up when the caller does
+func Budget(name string) Middleware {
+	if name == "" {
+		name = gohttp.BudgetHeader
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := gohttp.BudgetFromHeader(r.Context(), r.Header, name)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AccessLog logs every request once answered with its method, path,
+// status, response size, duration and request ID. Server errors are logged
+// at the Error level, others at Info.
+func AccessLog(logger gohttp.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &recorder{ResponseWriter: w}
+			defer func() {
+				status := rec.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				log := logger.Info
+				if status >= 500 {
+					log = logger.Error
+				}
+				log("request", "method", r.Method, "path", r.URL.Path, "status", status, "bytes", rec.bytes,
+					"duration", time.Since(start), "remote", r.RemoteAddr, "request_id", RequestIDFromContext(r.Context()))
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+type corsConfig struct {
+	origins     []string
+	methods     []string
+	headers     []string
+	credentials bool
+	maxAge      time.Duration
+}
+
+type CORSOption = opt.Option[corsConfig]
+
+// WithOrigins are the allowed origins, "*" allows any. None are allowed by
+// default.
+func WithOrigins(origins ...string) CORSOption {
+	return func(c *corsConfig) {
+		c.origins = origins
+	}
+}
+
+// WithMethods are the allowed methods, GET, HEAD and POST by default
+func WithMethods(methods ...string) CORSOption {
+	return func(c *corsConfig) {
+		c.methods = methods
+	}
+}
+
+// WithHeaders are the allowed request headers, Content-Type and
+// Authorization by default
+func WithHeaders(headers ...string) CORSOption {
+	return func(c *corsConfig) {
+		c.headers = headers
+	}
+}
+
+// WithCredentials allows cookies and authorization headers, the origin is
+// then echoed instead of "*"
+func WithCredentials() CORSOption {
+	return func(c *corsConfig) {
+		c.credentials = true
+	}
+}
+
+// WithMaxAge lets browsers cache preflight answers for d
+func WithMaxAge(d time.Duration) CORSOption {
+	return func(c *corsConfig) {
+		c.maxAge = d
+	}
+}
+
+// CORS answers preflight requests and adds the CORS headers to responses
+// for allowed origins
+func CORS(opts ...CORSOption) Middleware {
+	cfg := opt.Apply(&corsConfig{
+		methods: []string{http.MethodGet, http.MethodHead, http.MethodPost},
+		headers: []string{"Content-Type", "Authorization"},
+	}, opts...)
+	methods, headers := strings.Join(cfg.methods, ", "), strings.Join(cfg.headers, ", ")
+	allowed := func(origin string) bool {
+		for _, o := range cfg.origins {
+			if o == "*" || strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if origin == "" || !allowed(origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if cfg.credentials || !allowed("*") {
+				h.Set("Access-Control-Allow-Origin", origin)
+			} else {
+				h.Set("Access-Control-Allow-Origin", "*")
+			}
+			if cfg.credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", methods)
+			h.Set("Access-Control-Allow-Headers", headers)
+			if cfg.maxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.maxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+
+// gzipWriter compresses the body unless the handler encoded it already or
+// the response has no body
+type gzipWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	if !g.decided {
+		g.decided = true
+		h := g.ResponseWriter.Header()
+		if h.Get("Content-Encoding") == "" && code != http.StatusNoContent && code != http.StatusNotModified && code >= 200 {
+			h.Set("Content-Encoding", "gzip")
+			h.Del("Content-Length")
+			g.gz = gzipWriters.Get().(*gzip.Writer)
+			g.gz.Reset(g.ResponseWriter)
+		}
+	}
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func (g *gzipWriter) Write(b []byte) (int, error) {
+	if !g.decided {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz == nil {
+		return g.ResponseWriter.Write(b)
+	}
+	return g.gz.Write(b)
+}
+
+func (g *gzipWriter) Flush() {
+	if g.gz != nil {
+		_ = g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *gzipWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+func (g *gzipWriter) close() {
+	if g.gz == nil {
+		return
+	}
+	_ = g.gz.Close()
+	g.gz.Reset(io.Discard)
+	gzipWriters.Put(g.gz)
+}
+
+// Gzip compresses responses for clients accepting gzip
+func Gzip() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipWriter{ResponseWriter: w}
+			defer gw.close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		if name, q, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(q), 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
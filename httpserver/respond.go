@@ -0,0 +1,48 @@
+// Package httpserver has helpers for small services, in the style of the
+// http client: JSON responses and errors, binding of validated request
+// bodies, middleware for recovery, request IDs, access logs, CORS, gzip and
+// fault injection, and a server shutting down gracefully.
+//
+//	handler := httpserver.Chain(mux,
+//		httpserver.Recover(logger),
+//		httpserver.RequestID(),
+//		httpserver.AccessLog(logger),
+//		httpserver.Gzip(),
+//	)
+//	err := httpserver.Run(":8080", handler, httpserver.WithLogger(logger))
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Stellar1999/gotool/errorx"
+)
+
+// JSON answers with code and v encoded as JSON. A v that cannot be encoded
+// is answered with a 500 error instead.
+func JSON(w http.ResponseWriter, code int, v any) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		Error(w, errorx.Wrap(err, errorx.Internal, "encode response"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// Error answers with the errorx.Response of err and its status, internal
+// messages are never shown
+func Error(w http.ResponseWriter, err error) {
+	errorx.WriteJSON(w, err)
+}
+
+// NoContent answers with 204 No Content
+func NoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,112 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type serverConfig struct {
+	shutdownTimeout time.Duration
+	logger          gohttp.Logger
+	listener        net.Listener
+}
+
+type ServerOption = opt.Option[serverConfig]
+
+// WithShutdownTimeout is how long requests in flight may finish once the
+// server stops, 10s by default
+func WithShutdownTimeout(d time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.shutdownTimeout = d
+	}
+}
+
+// WithLogger logs when the server starts and stops
+func WithLogger(logger gohttp.Logger) ServerOption {
+	return func(c *serverConfig) {
+		c.logger = logger
+	}
+}
+
+// WithListener serves on l instead of listening on the server address
+func WithListener(l net.Listener) ServerOption {
+	return func(c *serverConfig) {
+		c.listener = l
+	}
+}
+
+var serverChecks = []opt.Check[serverConfig]{
+	func(c *serverConfig) error {
+		if c.shutdownTimeout <= 0 {
+			return errors.New("httpserver: shutdown timeout must be positive")
+		}
+		return nil
+	},
+}
+
+// Serve runs srv until ctx is done, then shuts it down and waits for the
+// requests in flight up to the shutdown timeout. It returns nil after a
+// clean shutdown, or the error stopping the server.
+func Serve(ctx context.Context, srv *http.Server, opts ...ServerOption) error {
+	cfg := serverConfig{shutdownTimeout: 10 * time.Second, logger: gohttp.NopLogger}
+	if err := opt.Build(&cfg, opts, serverChecks...); err != nil {
+		return err
+	}
+	l := cfg.listener
+	if l == nil {
+		addr := srv.Addr
+		if addr == "" {
+			addr = ":http"
+		}
+		var err error
+		if l, err = net.Listen("tcp", addr); err != nil {
+			return err
+		}
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		cfg.logger.Info("server started", "addr", l.Addr().String())
+		served <- srv.Serve(l)
+	}()
+	select {
+	case err := <-served:
+		// the server failed before ctx was done
+		return err
+	case <-ctx.Done():
+	}
+
+	cfg.logger.Info("server shutting down", "timeout", cfg.shutdownTimeout)
+	shutdown, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdown)
+	if errors.Is(err, context.DeadlineExceeded) {
+		cfg.logger.Warn("server shutdown timed out, closing connections")
+		_ = srv.Close()
+	}
+	if serveErr := <-served; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+	if err == nil {
+		cfg.logger.Info("server stopped")
+	}
+	return err
+}
+
+// Run serves handler on addr until the process gets SIGINT or SIGTERM, then
+// shuts down gracefully like Serve
+func Run(addr string, handler http.Handler, opts ...ServerOption) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	return Serve(ctx, srv, opts...)
+}
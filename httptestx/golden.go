@@ -0,0 +1,177 @@
+package httptestx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Stellar1999/gotool/jsonx"
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/Stellar1999/gotool/redact"
+)
+
+// UpdateEnv names the environment variable that rewrites golden files with
+// what the tests got instead of comparing: UPDATE_GOLDEN=1 go test ./...
+const UpdateEnv = "UPDATE_GOLDEN"
+
+type goldenConfig struct {
+	dir    string
+	policy *redact.Policy
+	update bool
+}
+
+type GoldenOption = opt.Option[goldenConfig]
+
+// WithDir keeps the golden files in dir instead of testdata
+func WithDir(dir string) GoldenOption {
+	return func(c *goldenConfig) {
+		c.dir = dir
+	}
+}
+
+// WithRedaction masks the fields and secrets of policy before comparing, so
+// tokens and other values changing between runs stay out of golden files
+func WithRedaction(policy *redact.Policy) GoldenOption {
+	return func(c *goldenConfig) {
+		c.policy = policy
+	}
+}
+
+// WithUpdate rewrites the golden file when update holds, e.g. from a -update
+// flag of the test package
+func WithUpdate(update bool) GoldenOption {
+	return func(c *goldenConfig) {
+		c.update = c.update || update
+	}
+}
+
+// AssertGolden checks got against the golden file <dir>/<name>.golden. JSON
+// is compared with its keys sorted and indented, so formatting doesn't
+// matter and the differences are listed by path, other content line by
+// line. A missing golden file fails, set UpdateEnv to write it.
+func AssertGolden(t TestingT, name string, got []byte, opts ...GoldenOption) bool {
+	t.Helper()
+	cfg := goldenConfig{dir: "testdata", update: os.Getenv(UpdateEnv) != ""}
+	opt.Apply(&cfg, opts...)
+	if cfg.policy != nil {
+		got = cfg.policy.Body(got)
+	}
+	isJSON := json.Valid(got)
+	if isJSON {
+		got = normalizeJSON(got)
+	}
+
+	path := filepath.Join(cfg.dir, name+".golden")
+	if cfg.update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Errorf("golden %s: %v", path, err)
+			return false
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Errorf("golden %s: %v", path, err)
+			return false
+		}
+		return true
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("golden %s: %v, run with %s=1 to write it", path, err, UpdateEnv)
+		return false
+	}
+	if isJSON && json.Valid(want) {
+		diffs, err := jsonx.Diff(want, got)
+		if err == nil && len(diffs) == 0 {
+			return true
+		}
+		if err == nil {
+			t.Errorf("golden %s differs (want then got):\n%s", path, listDiffs("", diffs))
+			return false
+		}
+	}
+	if bytes.Equal(want, got) {
+		return true
+	}
+	t.Errorf("golden %s differs:\n%s", path, LineDiff(string(want), string(got)))
+	return false
+}
+
+// normalizeJSON sorts the keys of data and indents it, for stable files
+func normalizeJSON(data []byte) []byte {
+	sorted, err := jsonx.SortKeys(data)
+	if err != nil {
+		return data
+	}
+	pretty, err := jsonx.Pretty(sorted)
+	if err != nil {
+		return data
+	}
+	return append(pretty, '\n')
+}
+
+// LineDiff returns the lines of want missing from got prefixed with "-" and
+// the lines added in got with "+", with the unchanged lines around them
+func LineDiff(want string, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+	// longest common subsequence of lines, lcs[i][j] for a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			lines = append(lines, line{'+', b[j]})
+			j++
+		default:
+			lines = append(lines, line{'-', a[i]})
+			i++
+		}
+	}
+
+	// keep 2 unchanged lines around the changes
+	const context = 2
+	var out strings.Builder
+	lastShown := -1
+	for n, l := range lines {
+		near := false
+		for k := n - context; k <= n+context; k++ {
+			if k >= 0 && k < len(lines) && lines[k].op != ' ' {
+				near = true
+				break
+			}
+		}
+		if !near {
+			continue
+		}
+		if lastShown >= 0 && n > lastShown+1 {
+			out.WriteString("  ...\n")
+		}
+		fmt.Fprintf(&out, "%c %s\n", l.op, l.text)
+		lastShown = n
+	}
+	return out.String()
+}
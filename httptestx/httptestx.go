@@ -0,0 +1,180 @@
+// Package httptestx asserts on HTTP responses in tests: the status, headers,
+// values at JSON paths and whole bodies against golden files, reporting
+// differences path by path or line by line.
+//
+//	resp, err := client.NewRequest(gohttp.GET, url).Send()
+//	httptestx.AssertStatus(t, resp, http.StatusOK)
+//	httptestx.AssertHeader(t, resp, "Content-Type", "application/json")
+//	httptestx.AssertJSONPath(t, resp.Body, "$.items[0].id", 42)
+//	httptestx.AssertGolden(t, "list_users", resp.Body, httptestx.WithRedaction(policy))
+//
+// The assertions take a *http.Response, a *httptest.ResponseRecorder or a
+// *gohttp.Response, as returned with the mock transport of httpmock or a
+// test server. They report with Errorf and return whether they passed.
+package httptestx
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/jsonx"
+)
+
+// TestingT is the subset of testing.TB used by this package
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Response is a response the assertions accept
+type Response interface {
+	*http.Response | *httptest.ResponseRecorder | *gohttp.Response
+}
+
+// parts returns the status, header and body of resp. The body of a
+// *http.Response is read and put back, so it can still be read after.
+func parts[R Response](resp R) (int, http.Header, []byte) {
+	switch r := any(resp).(type) {
+	case *http.Response:
+		if r == nil {
+			return 0, nil, nil
+		}
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(r.Body)
+			_ = r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		return r.StatusCode, r.Header, body
+	case *httptest.ResponseRecorder:
+		if r == nil {
+			return 0, nil, nil
+		}
+		return r.Code, r.Result().Header, r.Body.Bytes()
+	case *gohttp.Response:
+		if r == nil {
+			return 0, nil, nil
+		}
+		return r.StatusCode, r.Header, r.Body
+	}
+	return 0, nil, nil
+}
+
+// AssertStatus checks the status code of resp, the body is shown when it
+// differs as it usually tells why
+func AssertStatus[R Response](t TestingT, resp R, want int) bool {
+	t.Helper()
+	got, _, body := parts(resp)
+	if got == want {
+		return true
+	}
+	t.Errorf("status got = %d, want %d, body: %s", got, want, excerpt(body))
+	return false
+}
+
+// AssertHeader checks the header name of resp holds want. An empty want
+// checks the header is absent.
+func AssertHeader[R Response](t TestingT, resp R, name string, want string) bool {
+	t.Helper()
+	_, header, _ := parts(resp)
+	values := header.Values(name)
+	if want == "" {
+		if len(values) == 0 {
+			return true
+		}
+		t.Errorf("header %s got = %q, want none", name, values)
+		return false
+	}
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	t.Errorf("header %s got = %q, want %q", name, values, want)
+	return false
+}
+
+// AssertJSONPath checks the value at path in body equals want once both are
+// JSON, so 42, 42.0 and json.Number("42") are equal. Paths follow
+// jsonx.Get, with an optional "$." in front: "$.items[0].id".
+func AssertJSONPath(t TestingT, body []byte, path string, want any) bool {
+	t.Helper()
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	got, err := jsonx.GetRaw(body, path)
+	if err != nil {
+		t.Errorf("JSON path %s: %v, body: %s", path, err, excerpt(body))
+		return false
+	}
+	return assertJSON(t, "JSON path "+path, path, got, want)
+}
+
+// AssertJSON checks body is the JSON document want, which may be JSON text
+// as a string or []byte, or a value to marshal. The differences are listed
+// by path.
+func AssertJSON(t TestingT, body []byte, want any) bool {
+	t.Helper()
+	if s, ok := want.(string); ok {
+		want = json.RawMessage(s)
+	}
+	return assertJSON(t, "JSON body", "", body, want)
+}
+
+// assertJSON compares the JSON got at path with want
+func assertJSON(t TestingT, what string, path string, got []byte, want any) bool {
+	t.Helper()
+	var wantJSON []byte
+	switch w := want.(type) {
+	case []byte:
+		wantJSON = w
+	case json.RawMessage:
+		wantJSON = w
+	default:
+		var err error
+		if wantJSON, err = json.Marshal(want); err != nil {
+			t.Errorf("%s: can't marshal want: %v", what, err)
+			return false
+		}
+	}
+	diffs, err := jsonx.Diff(wantJSON, got)
+	if err != nil {
+		t.Errorf("%s: %v, got: %s", what, err, excerpt(got))
+		return false
+	}
+	if len(diffs) == 0 {
+		return true
+	}
+	t.Errorf("%s got = %s, want %s, differences (want then got):\n%s", what, excerpt(got), excerpt(wantJSON), listDiffs(path, diffs))
+	return false
+}
+
+// listDiffs formats diffs one per line, their paths below path
+func listDiffs(path string, diffs []jsonx.Difference) string {
+	lines := make([]string, len(diffs))
+	for i, d := range diffs {
+		switch {
+		case path == "":
+		case d.Path == "":
+			d.Path = path
+		case strings.HasPrefix(d.Path, "["):
+			d.Path = path + d.Path
+		default:
+			d.Path = path + "." + d.Path
+		}
+		lines[i] = "  " + d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// excerpt cuts body for messages
+func excerpt(body []byte) string {
+	const max = 512
+	if len(body) > max {
+		return string(body[:max]) + "..."
+	}
+	return string(body)
+}
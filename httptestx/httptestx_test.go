@@ -0,0 +1,144 @@
+package httptestx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/httpmock"
+	"github.com/Stellar1999/gotool/redact"
+)
+
+// fakeT records the failures of the assertions under test
+type fakeT struct {
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+const listBody = `{"items":[{"id":42,"name":"ada"},{"id":7,"name":"grace"}],"total":2}`
+
+func TestAssertions(t *testing.T) {
+	mock := httpmock.NewTransport()
+	mock.On("GET", "https://api.test/users").Reply(http.StatusOK, listBody).Header("Content-Type", "application/json")
+	client := gohttp.NewClient(gohttp.WithTransport(mock))
+	resp, err := client.NewRequest(gohttp.GET, "https://api.test/users").Send()
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	ft := &fakeT{}
+	passing := []bool{
+		AssertStatus(ft, resp, http.StatusOK),
+		AssertHeader(ft, resp, "Content-Type", "application/json"),
+		AssertHeader(ft, resp, "X-Missing", ""),
+		AssertJSONPath(ft, resp.Body, "$.items[0].id", 42),
+		AssertJSONPath(ft, resp.Body, "items[1]", map[string]any{"id": 7.0, "name": "grace"}),
+		AssertJSONPath(ft, resp.Body, "$.total", 2.0),
+		AssertJSON(ft, resp.Body, `{"total":2,"items":[{"id":42,"name":"ada"},{"name":"grace","id":7}]}`),
+	}
+	for i, ok := range passing {
+		if !ok {
+			t.Errorf("assertion %d failed: %v", i, ft.errors)
+		}
+	}
+
+	ft = &fakeT{}
+	failing := []bool{
+		AssertStatus(ft, resp, http.StatusCreated),
+		AssertHeader(ft, resp, "Content-Type", "text/plain"),
+		AssertJSONPath(ft, resp.Body, "$.items[0].name", "linus"),
+		AssertJSONPath(ft, resp.Body, "$.items[5]", nil),
+		AssertJSON(ft, resp.Body, map[string]any{"total": 3}),
+	}
+	for i, ok := range failing {
+		if ok {
+			t.Errorf("assertion %d passed, want a failure", i)
+		}
+	}
+	if len(ft.errors) != len(failing) {
+		t.Fatalf("failures got = %v", ft.errors)
+	}
+	for i, want := range []string{"want 201", `want "text/plain"`, `items[0].name: "linus" != "ada"`, "path not found", "items: added"} {
+		if !strings.Contains(ft.errors[i], want) {
+			t.Errorf("failure %d got = %q, want it to contain %q", i, ft.errors[i], want)
+		}
+	}
+}
+
+func TestResponseKinds(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("ETag", `"v1"`)
+	rec.WriteHeader(http.StatusAccepted)
+	_, _ = io.WriteString(rec, `{"ok":true}`)
+
+	ft := &fakeT{}
+	AssertStatus(ft, rec, http.StatusAccepted)
+	AssertHeader(ft, rec, "ETag", `"v1"`)
+
+	resp := rec.Result()
+	AssertStatus(ft, resp, http.StatusAccepted)
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"ok":true}` {
+		t.Errorf("AssertStatus() consumed the body, left %q", body)
+	}
+	if len(ft.errors) > 0 {
+		t.Errorf("assertions failed: %v", ft.errors)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	dir := t.TempDir()
+	policy := redact.MustNew(redact.WithFields("token"))
+	body := []byte(`{"user":"ada","token":"s3cr3t","roles":["admin"]}`)
+
+	ft := &fakeT{}
+	if AssertGolden(ft, "login", body, WithDir(dir)) || !strings.Contains(ft.errors[0], UpdateEnv) {
+		t.Errorf("AssertGolden() without a file got = %v, want a failure naming %s", ft.errors, UpdateEnv)
+	}
+	if !AssertGolden(ft, "login", body, WithDir(dir), WithRedaction(policy), WithUpdate(true)) {
+		t.Fatalf("AssertGolden() update failed: %v", ft.errors)
+	}
+	written, _ := os.ReadFile(filepath.Join(dir, "login.golden"))
+	if strings.Contains(string(written), "s3cr3t") || !strings.Contains(string(written), "\n  \"roles\"") {
+		t.Errorf("AssertGolden() wrote %s, want sorted, indented and redacted JSON", written)
+	}
+
+	ft = &fakeT{}
+	reordered := []byte(`{"roles":["admin"],"token":"other","user":"ada"}`)
+	if !AssertGolden(ft, "login", reordered, WithDir(dir), WithRedaction(policy)) {
+		t.Errorf("AssertGolden() got = %v, want a pass for equal JSON with another token", ft.errors)
+	}
+	changed := []byte(`{"roles":["admin","dev"],"token":"x","user":"ada"}`)
+	if AssertGolden(ft, "login", changed, WithDir(dir), WithRedaction(policy)) || !strings.Contains(ft.errors[0], "roles[1]: added") {
+		t.Errorf("AssertGolden() got = %v, want the added role reported", ft.errors)
+	}
+
+	ft = &fakeT{}
+	os.WriteFile(filepath.Join(dir, "page.golden"), []byte("<h1>Hi</h1>\n<p>one</p>\n<p>two</p>\n"), 0o644)
+	if AssertGolden(ft, "page", []byte("<h1>Hi</h1>\n<p>uno</p>\n<p>two</p>\n"), WithDir(dir)) {
+		t.Fatalf("AssertGolden() passed for different text")
+	}
+	if !strings.Contains(ft.errors[0], "- <p>one</p>\n+ <p>uno</p>") {
+		t.Errorf("AssertGolden() text diff got = %q", ft.errors[0])
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	want := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj"
+	got := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk"
+	diff := LineDiff(want, got)
+	wantDiff := "  b\n  c\n- d\n+ D\n  e\n  f\n  ...\n  i\n  j\n+ k\n"
+	if diff != wantDiff {
+		t.Errorf("LineDiff() got = %q, want %q", diff, wantDiff)
+	}
+}
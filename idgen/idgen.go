@@ -0,0 +1,162 @@
+// Package idgen generates identifiers: random and time ordered UUIDs, ULIDs,
+// Snowflake IDs and short random IDs for humans. The random bits come from
+// crypto/rand, the generators panic when it fails, as it does not on
+// supported platforms.
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Stellar1999/gotool/stringx"
+)
+
+// ShortAlphabet leaves out characters that are easily confused, like 0, O,
+// 1, l and I
+const ShortAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// crockford is the base32 alphabet of ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ErrInvalidULID is returned by ULIDTime for strings that are not ULIDs
+var ErrInvalidULID = errors.New("idgen: invalid ULID")
+
+// now is replaced in tests
+var now = time.Now
+
+func random(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic("idgen: reading random bytes failed: " + err.Error())
+	}
+}
+
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// UUIDv4 returns a random version 4 UUID
+func UUIDv4() string {
+	var b [16]byte
+	random(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b[:])
+}
+
+// v7State makes UUIDv7 monotonic: a counter in the 12 bits after the
+// timestamp restarts each millisecond and borrows the next one when it runs
+// out
+var v7State struct {
+	sync.Mutex
+	ms  int64
+	seq uint16
+}
+
+// UUIDv7 returns a version 7 UUID, which starts with the time in
+// milliseconds. UUIDs of one process sort in the order they were made.
+func UUIDv7() string {
+	var b [16]byte
+	random(b[:])
+	ms := now().UnixMilli()
+
+	v7State.Lock()
+	switch {
+	case ms > v7State.ms:
+		// a random start leaves room to count within the millisecond
+		v7State.ms, v7State.seq = ms, binary.BigEndian.Uint16(b[6:])&0x7ff
+	case v7State.seq < 0xfff:
+		v7State.seq++
+	default:
+		v7State.ms, v7State.seq = v7State.ms+1, 0
+	}
+	ms, seq := v7State.ms, v7State.seq
+	v7State.Unlock()
+
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	b[6] = 0x70 | byte(seq>>8)
+	b[7] = byte(seq)
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b[:])
+}
+
+var ulidState struct {
+	sync.Mutex
+	ms      int64
+	entropy [10]byte
+}
+
+// ULID returns a ULID: 26 Crockford base32 characters of a 48 bits
+// millisecond timestamp and 80 random bits. ULIDs of one process made in
+// the same millisecond increment the random part, so they sort in the order
+// they were made.
+func ULID() string {
+	ms := now().UnixMilli()
+	ulidState.Lock()
+	if ms > ulidState.ms {
+		ulidState.ms = ms
+		random(ulidState.entropy[:])
+	} else if !increment(ulidState.entropy[:]) {
+		ulidState.ms++
+	}
+	var b [16]byte
+	ms = ulidState.ms
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	copy(b[6:], ulidState.entropy[:])
+	ulidState.Unlock()
+
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// increment adds one to the big endian number b, false when it wraps to zero
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ULIDTime returns the time a ULID was made, with millisecond precision
+func ULIDTime(ulid string) (time.Time, error) {
+	if len(ulid) != 26 || ulid[0] > '7' {
+		return time.Time{}, ErrInvalidULID
+	}
+	var ms int64
+	for _, c := range strings.ToUpper(ulid[:10]) {
+		v := strings.IndexRune(crockford, c)
+		if v < 0 {
+			return time.Time{}, ErrInvalidULID
+		}
+		ms = ms<<5 | int64(v)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// Short returns a random ID of n characters of ShortAlphabet, for codes
+// people read or type. 10 characters give about 50 bits.
+func Short(n int) string {
+	id, err := stringx.RandomString(n, ShortAlphabet)
+	if err != nil {
+		panic("idgen: " + err.Error())
+	}
+	return id
+}
+
+// ShortWithAlphabet returns a random ID of n characters of alphabet
+func ShortWithAlphabet(n int, alphabet string) (string, error) {
+	return stringx.RandomString(n, alphabet)
+}
@@ -0,0 +1,154 @@
+package idgen
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setNow replaces the clock and forgets the last IDs, which may be later
+func setNow(clock func() time.Time) {
+	now = clock
+	v7State.ms, v7State.seq = 0, 0
+	ulidState.ms = 0
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([47])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestUUID(t *testing.T) {
+	seen := make(map[string]bool)
+	var v7s []string
+	for i := 0; i < 5000; i++ {
+		v4, v7 := UUIDv4(), UUIDv7()
+		if m := uuidPattern.FindStringSubmatch(v4); m == nil || m[1] != "4" {
+			t.Fatalf("UUIDv4() got = %v", v4)
+		}
+		if m := uuidPattern.FindStringSubmatch(v7); m == nil || m[1] != "7" {
+			t.Fatalf("UUIDv7() got = %v", v7)
+		}
+		if seen[v4] || seen[v7] {
+			t.Fatalf("UUID repeated: %v %v", v4, v7)
+		}
+		seen[v4], seen[v7] = true, true
+		v7s = append(v7s, v7)
+	}
+	if !sort.StringsAreSorted(v7s) {
+		t.Errorf("UUIDv7() got unsorted UUIDs")
+	}
+
+	defer setNow(time.Now)
+	setNow(func() time.Time { return time.UnixMilli(0x0123456789ab) })
+	if got := UUIDv7(); !strings.HasPrefix(got, "01234567-89ab-7") {
+		t.Errorf("UUIDv7() got = %v, want the timestamp first", got)
+	}
+}
+
+func TestULID(t *testing.T) {
+	defer setNow(time.Now)
+	at := time.UnixMilli(1469918176385)
+	setNow(func() time.Time { return at })
+	var ids []string
+	for i := 0; i < 1000; i++ {
+		ids = append(ids, ULID())
+	}
+	// the timestamp of the example in the ULID spec
+	if !strings.HasPrefix(ids[0], "01ARYZ6S41") || len(ids[0]) != 26 {
+		t.Errorf("ULID() got = %v", ids[0])
+	}
+	if !sort.StringsAreSorted(ids) || ids[0] == ids[1] {
+		t.Errorf("ULID() got unsorted or repeated IDs in one millisecond")
+	}
+	if got, err := ULIDTime(strings.ToLower(ids[0])); err != nil || !got.Equal(at) {
+		t.Errorf("ULIDTime() got = %v %v, want %v", got, err, at)
+	}
+	for _, bad := range []string{"", "81ARYZ6S41TSV4RRFFQ69G5FAV", "01ARYZ6S4ITSV4RRFFQ69G5FAV"} {
+		if _, err := ULIDTime(bad); !errors.Is(err, ErrInvalidULID) {
+			t.Errorf("ULIDTime(%q) error = %v, want %v", bad, err, ErrInvalidULID)
+		}
+	}
+}
+
+func TestShort(t *testing.T) {
+	id := Short(10)
+	if len(id) != 10 || strings.Trim(id, ShortAlphabet) != "" {
+		t.Errorf("Short() got = %v", id)
+	}
+	if id, err := ShortWithAlphabet(6, "01"); err != nil || strings.Trim(id, "01") != "" || len(id) != 6 {
+		t.Errorf("ShortWithAlphabet() got = %v %v", id, err)
+	}
+	if _, err := ShortWithAlphabet(6, ""); err == nil {
+		t.Errorf("ShortWithAlphabet() error = nil for an empty alphabet")
+	}
+}
+
+func TestSnowflake(t *testing.T) {
+	s, err := NewSnowflake(42)
+	if err != nil {
+		t.Fatalf("NewSnowflake() error = %v", err)
+	}
+	var mu sync.Mutex
+	seen := make(map[int64]bool)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := int64(0)
+			for i := 0; i < 5000; i++ {
+				id, err := s.Next()
+				if err != nil || id <= last {
+					t.Errorf("Next() got = %v %v after %v", id, err, last)
+					return
+				}
+				last = id
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 20000 {
+		t.Errorf("Next() got %v distinct IDs, want 20000", len(seen))
+	}
+	id, _ := s.Next()
+	parsed := s.Parse(id)
+	if parsed.Machine != 42 || time.Since(parsed.Time) > time.Second {
+		t.Errorf("Parse() got = %+v", parsed)
+	}
+
+	if _, err := NewSnowflake(MaxMachineID + 1); err == nil {
+		t.Errorf("NewSnowflake() error = nil for machine %v", MaxMachineID+1)
+	}
+}
+
+func TestSnowflakeClockDrift(t *testing.T) {
+	base := time.Now()
+	var offsets []time.Duration
+	clock := func() time.Time {
+		if len(offsets) == 0 {
+			return time.Now()
+		}
+		d := offsets[0]
+		offsets = offsets[1:]
+		return base.Add(d)
+	}
+	s, _ := NewSnowflake(1, WithClock(clock), WithEpoch(base.Add(-time.Hour)), WithMaxDrift(50*time.Millisecond))
+
+	offsets = []time.Duration{time.Second}
+	first, _ := s.Next()
+	// 20ms back is waited out, the clock then catches up
+	offsets = []time.Duration{980 * time.Millisecond, 990 * time.Millisecond, time.Second}
+	second, err := s.Next()
+	if err != nil || second <= first {
+		t.Errorf("Next() got = %v %v, want an ID after %v", second, err, first)
+	}
+	offsets = []time.Duration{500 * time.Millisecond}
+	if _, err := s.Next(); !errors.Is(err, ErrClockBackwards) {
+		t.Errorf("Next() error = %v, want %v", err, ErrClockBackwards)
+	}
+}
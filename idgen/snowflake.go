@@ -0,0 +1,147 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Layout of Snowflake IDs: a sign bit, 41 bits of milliseconds since the
+// epoch, 10 bits of machine ID and 12 bits of sequence
+const (
+	machineBits  = 10
+	sequenceBits = 12
+	MaxMachineID = 1<<machineBits - 1
+	maxSequence  = 1<<sequenceBits - 1
+)
+
+// ErrClockBackwards is returned when the clock went back by more than the
+// tolerated drift
+var ErrClockBackwards = errors.New("idgen: clock moved backwards")
+
+// DefaultEpoch is 2020-01-01 UTC, the IDs last about 69 years from it
+var DefaultEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type snowflakeConfig struct {
+	epoch    time.Time
+	maxDrift time.Duration
+	now      func() time.Time
+}
+
+type SnowflakeOption = opt.Option[snowflakeConfig]
+
+// WithEpoch sets the time IDs count from, DefaultEpoch by default. All
+// generators of a system must share it.
+func WithEpoch(epoch time.Time) SnowflakeOption {
+	return func(c *snowflakeConfig) {
+		c.epoch = epoch
+	}
+}
+
+// WithMaxDrift sets how far the clock may go back, e.g. when NTP corrects
+// it, before Next fails with ErrClockBackwards instead of waiting for the
+// clock to catch up, 10ms by default
+func WithMaxDrift(d time.Duration) SnowflakeOption {
+	return func(c *snowflakeConfig) {
+		c.maxDrift = d
+	}
+}
+
+// WithClock replaces time.Now, for tests
+func WithClock(now func() time.Time) SnowflakeOption {
+	return func(c *snowflakeConfig) {
+		c.now = now
+	}
+}
+
+// Snowflake generates unique 63 bits IDs ordered by time, up to 4096 per
+// millisecond. Every generator of a system needs its own machine ID.
+type Snowflake struct {
+	cfg     snowflakeConfig
+	machine int64
+
+	mu       sync.Mutex
+	last     int64
+	sequence int64
+}
+
+// NewSnowflake returns a generator for machineID, from 0 to MaxMachineID
+func NewSnowflake(machineID int64, opts ...SnowflakeOption) (*Snowflake, error) {
+	cfg := snowflakeConfig{epoch: DefaultEpoch, maxDrift: 10 * time.Millisecond, now: time.Now}
+	err := opt.Build(&cfg, opts, func(c *snowflakeConfig) error {
+		if machineID < 0 || machineID > MaxMachineID {
+			return fmt.Errorf("idgen: machine ID must be between 0 and %d, got %d", MaxMachineID, machineID)
+		}
+		if c.maxDrift < 0 {
+			return errors.New("idgen: max drift must not be negative")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Snowflake{cfg: cfg, machine: machineID}, nil
+}
+
+func (s *Snowflake) millis() int64 {
+	return s.cfg.now().Sub(s.cfg.epoch).Milliseconds()
+}
+
+// Next returns a new ID. When the clock went back by less than the max
+// drift it waits for the clock to catch up, beyond that it fails with
+// ErrClockBackwards.
+func (s *Snowflake) Next() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ms := s.millis()
+	if ms < s.last {
+		behind := time.Duration(s.last-ms) * time.Millisecond
+		if behind > s.cfg.maxDrift {
+			return 0, fmt.Errorf("%w by %v", ErrClockBackwards, behind)
+		}
+		ms = s.waitUntil(s.last)
+	}
+	if ms < 0 || ms >= 1<<41 {
+		return 0, fmt.Errorf("idgen: time out of the range of the epoch %v", s.cfg.epoch)
+	}
+	if ms == s.last {
+		s.sequence = (s.sequence + 1) & maxSequence
+		if s.sequence == 0 {
+			// all IDs of this millisecond are taken
+			ms = s.waitUntil(s.last + 1)
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.last = ms
+	return ms<<(machineBits+sequenceBits) | s.machine<<sequenceBits | s.sequence, nil
+}
+
+// waitUntil waits until the clock reaches ms and returns the time then
+func (s *Snowflake) waitUntil(ms int64) int64 {
+	now := s.millis()
+	for now < ms {
+		time.Sleep(time.Duration(ms-now) * time.Millisecond)
+		now = s.millis()
+	}
+	return now
+}
+
+// SnowflakeID is a decoded Snowflake ID
+type SnowflakeID struct {
+	Time     time.Time
+	Machine  int64
+	Sequence int64
+}
+
+// Parse decodes id made by s, or a generator with the same epoch
+func (s *Snowflake) Parse(id int64) SnowflakeID {
+	return SnowflakeID{
+		Time:     s.cfg.epoch.Add(time.Duration(id>>(machineBits+sequenceBits)) * time.Millisecond),
+		Machine:  id >> sequenceBits & MaxMachineID,
+		Sequence: id & maxSequence,
+	}
+}
@@ -0,0 +1,282 @@
+package ipc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Client calls a Server over one connection. When the connection breaks the
+// pending calls fail with ErrDisconnected and the Client redials in the
+// background, restoring its subscriptions; calls made meanwhile wait for the
+// new connection until their ctx is done.
+type Client struct {
+	cfg  config
+	path string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	ready   chan struct{} // closed while connected
+	closed  bool
+	nextID  uint64
+	pending map[uint64]chan frame
+	subs    map[string][]func(*Message)
+	writeMu sync.Mutex
+	done    chan struct{}
+}
+
+// Dial connects to the Server listening at path
+func Dial(ctx context.Context, path string, opts ...Option) (*Client, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		cfg:     cfg,
+		path:    path,
+		conn:    conn,
+		ready:   make(chan struct{}),
+		pending: make(map[uint64]chan frame),
+		subs:    make(map[string][]func(*Message)),
+		done:    make(chan struct{}),
+	}
+	close(c.ready)
+	go c.run(conn)
+	return c, nil
+}
+
+// connection waits until the Client is connected
+func (c *Client) connection(ctx context.Context) (net.Conn, error) {
+	for {
+		c.mu.Lock()
+		conn, ready, closed := c.conn, c.ready, c.closed
+		c.mu.Unlock()
+		if closed {
+			return nil, ErrClosed
+		}
+		if conn != nil {
+			return conn, nil
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) write(conn net.Conn, f frame) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return writeFrame(conn, f)
+}
+
+// Call calls method with params and decodes the reply into result, a nil
+// result discards it. Handler errors are returned as *RemoteError.
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	payload, err := c.cfg.encode(params)
+	if err != nil {
+		return err
+	}
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return err
+	}
+	reply := make(chan frame, 1)
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = reply
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+	if err := c.write(conn, frame{kind: kindCall, id: id, name: method, payload: payload}); err != nil {
+		return err
+	}
+	select {
+	case f, ok := <-reply:
+		if !ok {
+			return ErrDisconnected
+		}
+		if f.kind == kindError {
+			return &RemoteError{Method: method, Message: string(f.payload)}
+		}
+		if result == nil {
+			return nil
+		}
+		return c.cfg.codec.Unmarshal(f.payload, result)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe calls fn for every message published to topic, on the goroutine
+// reading the connection, so fn should not block
+func (c *Client) Subscribe(ctx context.Context, topic string, fn func(*Message)) error {
+	c.mu.Lock()
+	first := len(c.subs[topic]) == 0
+	c.subs[topic] = append(c.subs[topic], fn)
+	c.mu.Unlock()
+	if !first {
+		return nil
+	}
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return err
+	}
+	// a failed write is repaired by the resubscription after reconnecting
+	_ = c.write(conn, frame{kind: kindSubscribe, name: topic})
+	return nil
+}
+
+// Unsubscribe drops the subscriptions to topic
+func (c *Client) Unsubscribe(ctx context.Context, topic string) error {
+	c.mu.Lock()
+	delete(c.subs, topic)
+	c.mu.Unlock()
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return err
+	}
+	return c.write(conn, frame{kind: kindUnsubscribe, name: topic})
+}
+
+// Publish sends v to the connections subscribed to topic through the server
+func (c *Client) Publish(ctx context.Context, topic string, v any) error {
+	payload, err := c.cfg.encode(v)
+	if err != nil {
+		return err
+	}
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return err
+	}
+	return c.write(conn, frame{kind: kindPublish, name: topic, payload: payload})
+}
+
+// Close closes the connection and stops reconnecting
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	conn := c.conn
+	close(c.done)
+	c.mu.Unlock()
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+// run reads conn and every connection dialed after it broke
+func (c *Client) run(conn net.Conn) {
+	for {
+		c.read(conn)
+		c.mu.Lock()
+		c.conn = nil
+		c.ready = make(chan struct{})
+		for id, reply := range c.pending {
+			close(reply)
+			delete(c.pending, id)
+		}
+		closed := c.closed
+		c.mu.Unlock()
+		if closed {
+			return
+		}
+		c.cfg.logger.Warn("ipc connection lost, reconnecting", "path", c.path)
+		if conn = c.redial(); conn == nil {
+			return
+		}
+	}
+}
+
+func (c *Client) read(conn net.Conn) {
+	for {
+		f, err := readFrame(conn, c.cfg.maxMessageSize)
+		if err != nil {
+			_ = conn.Close()
+			return
+		}
+		switch f.kind {
+		case kindReply, kindError:
+			c.mu.Lock()
+			reply, ok := c.pending[f.id]
+			delete(c.pending, f.id)
+			c.mu.Unlock()
+			if ok {
+				reply <- f
+			}
+		case kindPublish:
+			c.mu.Lock()
+			subs := c.subs[f.name]
+			c.mu.Unlock()
+			for _, fn := range subs {
+				fn(&Message{Topic: f.name, body: f.payload, codec: c.cfg.codec})
+			}
+		}
+	}
+}
+
+// redial connects again with growing pauses, it returns nil once closed
+func (c *Client) redial() net.Conn {
+	pause := c.cfg.minReconnect
+	for {
+		select {
+		case <-c.done:
+			return nil
+		case <-time.After(pause):
+		}
+		conn, err := net.Dial("unix", c.path)
+		if err != nil {
+			c.cfg.logger.Debug("ipc redial failed", "path", c.path, "err", err)
+			if pause *= 2; pause > c.cfg.maxReconnect {
+				pause = c.cfg.maxReconnect
+			}
+			continue
+		}
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			_ = conn.Close()
+			return nil
+		}
+		topics := make([]string, 0, len(c.subs))
+		for topic := range c.subs {
+			topics = append(topics, topic)
+		}
+		c.mu.Unlock()
+		var subscribeErr error
+		for _, topic := range topics {
+			if subscribeErr = c.write(conn, frame{kind: kindSubscribe, name: topic}); subscribeErr != nil {
+				break
+			}
+		}
+		if subscribeErr != nil {
+			_ = conn.Close()
+			continue
+		}
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			_ = conn.Close()
+			return nil
+		}
+		c.conn = conn
+		close(c.ready)
+		c.mu.Unlock()
+		c.cfg.logger.Info("ipc reconnected", "path", c.path)
+		return conn
+	}
+}
@@ -0,0 +1,241 @@
+// Package ipc connects processes on the same machine over Unix domain
+// sockets, for sidecars and agents where HTTP is overkill. A Server answers
+// calls (Handle) and brokers pub/sub messages (Publish, Subscribe), a Client
+// calls it and reconnects when the server restarts. Peers are identified by
+// the credentials of their socket, WithAuth decides who may connect.
+//
+// Payloads are encoded with a gohttp.Codec, JSON by default. There is no
+// msgpack codec in the module yet, any Codec implementation can be plugged
+// in with WithCodec. Windows named pipes are not supported, but Windows 10
+// and later support Unix domain sockets.
+package ipc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+var (
+	// ErrClosed is returned when the Client or Server was closed
+	ErrClosed = errors.New("ipc: closed")
+	// ErrDisconnected fails the calls pending when the connection broke
+	ErrDisconnected = errors.New("ipc: disconnected")
+	// ErrUnauthorized is returned by the auth functions of this package
+	ErrUnauthorized = errors.New("ipc: peer not authorized")
+	// ErrNoHandler is the remote error for methods without a handler
+	ErrNoHandler = errors.New("ipc: no handler")
+)
+
+// RemoteError is returned by Client.Call when the handler failed
+type RemoteError struct {
+	Method  string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("ipc: %s: %s", e.Method, e.Message)
+}
+
+// Peer is the process at the other end of a connection. Known is false where
+// the platform does not report socket credentials.
+type Peer struct {
+	PID   int
+	UID   int
+	GID   int
+	Known bool
+}
+
+// SameUser lets processes of the user running the server connect
+func SameUser() func(Peer) error {
+	return AllowUIDs(currentUID())
+}
+
+// AllowUIDs lets processes of the given users connect
+func AllowUIDs(uids ...int) func(Peer) error {
+	return func(p Peer) error {
+		if !p.Known {
+			return fmt.Errorf("%w: unknown credentials", ErrUnauthorized)
+		}
+		for _, uid := range uids {
+			if p.UID == uid {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: uid %d", ErrUnauthorized, p.UID)
+	}
+}
+
+type config struct {
+	codec          gohttp.Codec
+	logger         gohttp.Logger
+	auth           func(Peer) error
+	maxMessageSize int
+	minReconnect   time.Duration
+	maxReconnect   time.Duration
+}
+
+type Option = opt.Option[config]
+
+// WithCodec encodes payloads with codec instead of JSON, both ends must agree
+func WithCodec(codec gohttp.Codec) Option {
+	return func(c *config) {
+		c.codec = codec
+	}
+}
+
+// WithLogger logs connections, rejected peers and reconnects
+func WithLogger(logger gohttp.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithAuth makes the Server close connections of peers auth rejects, see
+// SameUser and AllowUIDs
+func WithAuth(auth func(Peer) error) Option {
+	return func(c *config) {
+		c.auth = auth
+	}
+}
+
+// WithMaxMessageSize bounds the encoded payloads, 16MB by default
+func WithMaxMessageSize(n int) Option {
+	return func(c *config) {
+		c.maxMessageSize = n
+	}
+}
+
+// WithReconnect is the pause of the Client before redialing a broken
+// connection, doubling from min up to max. 100ms and 5s by default.
+func WithReconnect(min time.Duration, max time.Duration) Option {
+	return func(c *config) {
+		c.minReconnect = min
+		c.maxReconnect = max
+	}
+}
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.maxMessageSize <= 0 || c.minReconnect <= 0 || c.maxReconnect < c.minReconnect {
+			return errors.New("ipc: message size and reconnect pauses must be positive")
+		}
+		return nil
+	},
+}
+
+func newConfig(opts []Option) (config, error) {
+	cfg := config{
+		codec:          gohttp.JSONCodec,
+		logger:         gohttp.NopLogger,
+		maxMessageSize: 16 << 20,
+		minReconnect:   100 * time.Millisecond,
+		maxReconnect:   5 * time.Second,
+	}
+	return cfg, opt.Build(&cfg, opts, checks...)
+}
+
+// kinds of frames
+const (
+	kindCall byte = iota + 1
+	kindReply
+	kindError
+	kindSubscribe
+	kindUnsubscribe
+	kindPublish
+)
+
+// frame is the unit on the wire:
+//
+//	length uint32 | kind uint8 | id uint64 | name length uint16 | name | payload
+//
+// name is the method of calls and the topic of pub/sub frames
+type frame struct {
+	kind    byte
+	id      uint64
+	name    string
+	payload []byte
+}
+
+const frameHeader = 1 + 8 + 2
+
+func writeFrame(w io.Writer, f frame) error {
+	if len(f.name) > 0xffff {
+		return fmt.Errorf("ipc: name of %d bytes is too long", len(f.name))
+	}
+	buf := make([]byte, 4+frameHeader+len(f.name)+len(f.payload))
+	binary.BigEndian.PutUint32(buf, uint32(len(buf)-4))
+	buf[4] = f.kind
+	binary.BigEndian.PutUint64(buf[5:], f.id)
+	binary.BigEndian.PutUint16(buf[13:], uint16(len(f.name)))
+	copy(buf[15:], f.name)
+	copy(buf[15+len(f.name):], f.payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readFrame(r io.Reader, maxSize int) (frame, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return frame{}, err
+	}
+	n := int(binary.BigEndian.Uint32(size[:]))
+	if n < frameHeader || n > maxSize+frameHeader+1<<16 {
+		return frame{}, fmt.Errorf("ipc: invalid frame size %d", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return frame{}, err
+	}
+	nameLen := int(binary.BigEndian.Uint16(buf[9:]))
+	if frameHeader+nameLen > n {
+		return frame{}, fmt.Errorf("ipc: invalid name length %d", nameLen)
+	}
+	return frame{
+		kind:    buf[0],
+		id:      binary.BigEndian.Uint64(buf[1:]),
+		name:    string(buf[frameHeader : frameHeader+nameLen]),
+		payload: buf[frameHeader+nameLen:],
+	}, nil
+}
+
+func (c config) encode(v any) ([]byte, error) {
+	payload, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > c.maxMessageSize {
+		return nil, fmt.Errorf("ipc: message of %d bytes exceeds %d", len(payload), c.maxMessageSize)
+	}
+	return payload, nil
+}
+
+// Request is a call received by a handler
+type Request struct {
+	Method string
+	Peer   Peer
+	body   []byte
+	codec  gohttp.Codec
+}
+
+// Decode decodes the params of the call into v
+func (r *Request) Decode(v any) error {
+	return r.codec.Unmarshal(r.body, v)
+}
+
+// Message is a published message received by a subscriber
+type Message struct {
+	Topic string
+	body  []byte
+	codec gohttp.Codec
+}
+
+// Decode decodes the message into v
+func (m *Message) Decode(v any) error {
+	return m.codec.Unmarshal(m.body, v)
+}
@@ -0,0 +1,171 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, path string, opts ...Option) *Server {
+	t.Helper()
+	server, err := NewServer(opts...)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	server.Handle("add", func(ctx context.Context, req *Request) (any, error) {
+		var nums []int
+		if err := req.Decode(&nums); err != nil {
+			return nil, err
+		}
+		sum := 0
+		for _, n := range nums {
+			sum += n
+		}
+		return sum, nil
+	})
+	server.Handle("whoami", func(ctx context.Context, req *Request) (any, error) {
+		return req.Peer, nil
+	})
+	server.Handle("fail", func(ctx context.Context, req *Request) (any, error) {
+		return nil, errors.New("broken")
+	})
+	go func() { _ = server.Listen(path) }()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return server
+}
+
+func TestCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	server := startServer(t, path, WithAuth(SameUser()))
+	defer server.Close(context.Background())
+
+	ctx := context.Background()
+	client, err := Dial(ctx, path)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+
+	var sum int
+	if err := client.Call(ctx, "add", []int{1, 2, 3}, &sum); err != nil || sum != 6 {
+		t.Errorf("Call() got = %v %v, want 6", sum, err)
+	}
+	var peer Peer
+	if err := client.Call(ctx, "whoami", nil, &peer); err != nil || peer.Known && peer.PID != os.Getpid() {
+		t.Errorf("Call() whoami got = %+v %v", peer, err)
+	}
+	var remoteErr *RemoteError
+	if err := client.Call(ctx, "fail", nil, nil); !errors.As(err, &remoteErr) || remoteErr.Message != "broken" {
+		t.Errorf("Call() error got = %v, want broken", err)
+	}
+	if err := client.Call(ctx, "missing", nil, nil); !errors.As(err, &remoteErr) || remoteErr.Message != ErrNoHandler.Error() {
+		t.Errorf("Call() error got = %v, want %v", err, ErrNoHandler)
+	}
+
+	// a second server on the same path is refused
+	if err := (&Server{}).Listen(path); err == nil {
+		t.Errorf("Listen() on a busy path error = nil")
+	}
+}
+
+func TestAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	server := startServer(t, path, WithAuth(AllowUIDs(-2)))
+	defer server.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	client, err := Dial(ctx, path, WithReconnect(time.Hour, time.Hour))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+	if err := client.Call(ctx, "add", []int{1}, nil); err == nil {
+		t.Errorf("Call() by a rejected peer error = nil")
+	}
+	if err := AllowUIDs(1)(Peer{}); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("AllowUIDs() unknown peer got = %v, want %v", err, ErrUnauthorized)
+	}
+}
+
+func TestPubSubAndReconnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	server := startServer(t, path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := Dial(ctx, path, WithReconnect(10*time.Millisecond, 50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+	other, _ := Dial(ctx, path)
+	defer other.Close()
+
+	var mu sync.Mutex
+	var got []string
+	received := make(chan struct{}, 10)
+	if err := client.Subscribe(ctx, "events", func(m *Message) {
+		var s string
+		_ = m.Decode(&s)
+		mu.Lock()
+		got = append(got, m.Topic+":"+s)
+		mu.Unlock()
+		received <- struct{}{}
+	}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	// the call orders the subscription before the publishing
+	_ = client.Call(ctx, "add", nil, nil)
+	if err := server.Publish("events", "from server"); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := other.Publish(ctx, "events", "from client"); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	_ = server.Publish("other", "ignored")
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-ctx.Done():
+			t.Fatalf("messages got = %v, want 2", got)
+		}
+	}
+
+	// restart the server, the client reconnects and subscribes again
+	if err := server.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	server = startServer(t, path)
+	defer server.Close(context.Background())
+	var sum int
+	if err := client.Call(ctx, "add", []int{2, 2}, &sum); err != nil || sum != 4 {
+		t.Fatalf("Call() after restart got = %v %v", sum, err)
+	}
+	_ = server.Publish("events", "after restart")
+	select {
+	case <-received:
+	case <-ctx.Done():
+		t.Fatalf("no message after restart")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 3 || got[0] != "events:from server" || got[2] != "events:after restart" {
+		t.Errorf("messages got = %v", got)
+	}
+
+	_ = client.Close()
+	if err := client.Call(ctx, "add", nil, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Call() after Close got = %v, want %v", err, ErrClosed)
+	}
+}
@@ -0,0 +1,30 @@
+package ipc
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// peerOf reads the SO_PEERCRED credentials of a Unix socket
+func peerOf(conn net.Conn) Peer {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return Peer{}
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return Peer{}
+	}
+	var cred *syscall.Ucred
+	if err := raw.Control(func(fd uintptr) {
+		cred, err = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil || cred == nil {
+		return Peer{}
+	}
+	return Peer{PID: int(cred.Pid), UID: int(cred.Uid), GID: int(cred.Gid), Known: true}
+}
+
+func currentUID() int {
+	return os.Getuid()
+}
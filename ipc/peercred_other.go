@@ -0,0 +1,17 @@
+//go:build !linux
+
+package ipc
+
+import (
+	"net"
+	"os"
+)
+
+// peerOf reports unknown credentials, only Linux is supported so far
+func peerOf(conn net.Conn) Peer {
+	return Peer{}
+}
+
+func currentUID() int {
+	return os.Getuid()
+}
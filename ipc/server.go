@@ -0,0 +1,244 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+)
+
+// HandlerFunc answers a call, the result is encoded as the reply
+type HandlerFunc func(ctx context.Context, req *Request) (any, error)
+
+// Server answers calls and relays published messages to the subscribed
+// connections, it is safe for concurrent use
+type Server struct {
+	cfg config
+
+	mu        sync.Mutex
+	handlers  map[string]HandlerFunc
+	listeners map[net.Listener]struct{}
+	conns     map[*serverConn]struct{}
+	closed    bool
+	calls     sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+type serverConn struct {
+	conn    net.Conn
+	peer    Peer
+	writeMu sync.Mutex
+	topics  map[string]bool // guarded by Server.mu
+}
+
+func (c *serverConn) write(f frame) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return writeFrame(c.conn, f)
+}
+
+// NewServer creates a Server, add handlers with Handle and start it with
+// Listen or Serve
+func NewServer(opts ...Option) (*Server, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		cfg:       cfg,
+		handlers:  make(map[string]HandlerFunc),
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[*serverConn]struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// Handle answers calls of method with h, replacing an earlier handler
+func (s *Server) Handle(method string, h HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = h
+}
+
+// Listen serves on a Unix socket at path. A stale socket file left by a
+// crashed server is replaced, the new one is only accessible to the user.
+func (s *Server) Listen(path string) error {
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return &net.OpError{Op: "listen", Net: "unix", Addr: &net.UnixAddr{Name: path, Net: "unix"}, Err: errors.New("address in use by a running server")}
+	}
+	_ = os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = l.Close()
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until the Server is closed, it then returns
+// ErrClosed
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = l.Close()
+		return ErrClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mu.Unlock()
+			if closed {
+				return ErrClosed
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	c := &serverConn{conn: conn, peer: peerOf(conn), topics: make(map[string]bool)}
+	if s.cfg.auth != nil {
+		if err := s.cfg.auth(c.peer); err != nil {
+			s.cfg.logger.Warn("ipc peer rejected", "pid", c.peer.PID, "uid", c.peer.UID, "err", err)
+			_ = conn.Close()
+			return
+		}
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = conn.Close()
+		return
+	}
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	s.cfg.logger.Debug("ipc peer connected", "pid", c.peer.PID, "uid", c.peer.UID)
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	for {
+		f, err := readFrame(conn, s.cfg.maxMessageSize)
+		if err != nil {
+			return
+		}
+		switch f.kind {
+		case kindCall:
+			s.mu.Lock()
+			h, ok := s.handlers[f.name]
+			closed := s.closed
+			if ok && !closed {
+				s.calls.Add(1)
+			}
+			s.mu.Unlock()
+			if closed {
+				_ = c.write(frame{kind: kindError, id: f.id, name: f.name, payload: []byte(ErrClosed.Error())})
+				continue
+			}
+			if !ok {
+				_ = c.write(frame{kind: kindError, id: f.id, name: f.name, payload: []byte(ErrNoHandler.Error())})
+				continue
+			}
+			go s.call(c, h, f)
+		case kindSubscribe, kindUnsubscribe:
+			s.mu.Lock()
+			if f.kind == kindSubscribe {
+				c.topics[f.name] = true
+			} else {
+				delete(c.topics, f.name)
+			}
+			s.mu.Unlock()
+		case kindPublish:
+			s.broadcast(f.name, f.payload)
+		}
+	}
+}
+
+func (s *Server) call(c *serverConn, h HandlerFunc, f frame) {
+	defer s.calls.Done()
+	result, err := h(s.ctx, &Request{Method: f.name, Peer: c.peer, body: f.payload, codec: s.cfg.codec})
+	var payload []byte
+	if err == nil {
+		payload, err = s.cfg.encode(result)
+	}
+	if err != nil {
+		_ = c.write(frame{kind: kindError, id: f.id, name: f.name, payload: []byte(err.Error())})
+		return
+	}
+	_ = c.write(frame{kind: kindReply, id: f.id, name: f.name, payload: payload})
+}
+
+// Publish sends v to the connections subscribed to topic
+func (s *Server) Publish(topic string, v any) error {
+	payload, err := s.cfg.encode(v)
+	if err != nil {
+		return err
+	}
+	s.broadcast(topic, payload)
+	return nil
+}
+
+func (s *Server) broadcast(topic string, payload []byte) {
+	s.mu.Lock()
+	var subscribers []*serverConn
+	for c := range s.conns {
+		if c.topics[topic] {
+			subscribers = append(subscribers, c)
+		}
+	}
+	s.mu.Unlock()
+	for _, c := range subscribers {
+		if err := c.write(frame{kind: kindPublish, name: topic, payload: payload}); err != nil {
+			s.cfg.logger.Warn("ipc publish failed", "topic", topic, "pid", c.peer.PID, "err", err)
+		}
+	}
+}
+
+// Close stops accepting connections, waits for the running handlers until
+// ctx is done and closes the connections. The ctx of the handlers is
+// canceled when ctx is done.
+func (s *Server) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		_ = l.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.calls.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	s.cancel()
+	s.mu.Lock()
+	for c := range s.conns {
+		_ = c.conn.Close()
+	}
+	s.mu.Unlock()
+	return err
+}
@@ -0,0 +1,283 @@
+package itest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+const (
+	DefaultReadyTimeout = 60 * time.Second
+	readyInterval       = 200 * time.Millisecond
+)
+
+// ReadyCheck returns nil once the service listening on addr accepts work
+type ReadyCheck func(ctx context.Context, addr string) error
+
+// TCPReady waits until addr accepts tcp connections
+func TCPReady() ReadyCheck {
+	return func(ctx context.Context, addr string) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// HTTPReady waits until GET http://addr/path answers 200
+func HTTPReady(path string) ReadyCheck {
+	return func(ctx context.Context, addr string) error {
+		_, _, _, err := gohttp.GetWithContext(ctx, "http://"+addr+path, nil, nil)
+		return err
+	}
+}
+
+// Service is a compose service the tests talk to
+type Service struct {
+	// Name is the service name in the compose file
+	Name string
+	// Port is the container port, its published host address is looked up with "compose port"
+	Port int
+	// Ready defaults to TCPReady
+	Ready ReadyCheck
+	// EnvVar, when set, receives the "host:port" address while the suite is up
+	EnvVar string
+}
+
+// Suite describes a compose project started for a group of integration tests
+type Suite struct {
+	ComposeFile string
+	// Project isolates containers, networks and volumes, defaults to "itest-<pid>"
+	Project  string
+	Services []Service
+	// Env is passed to the compose command for variable interpolation
+	Env          map[string]string
+	ReadyTimeout time.Duration
+	// Reset restores the services to a clean state between tests
+	Reset func(ctx context.Context, env *Env) error
+	// Command defaults to "docker compose"
+	Command []string
+}
+
+// Env is a running suite
+type Env struct {
+	suite   Suite
+	addrs   map[string]string
+	prevEnv map[string]*string
+}
+
+// Up starts the suite and waits for every service to be ready.
+// On failure everything started so far is torn down again.
+func Up(ctx context.Context, s Suite) (*Env, error) {
+	if s.Project == "" {
+		s.Project = "itest-" + strconv.Itoa(os.Getpid())
+	}
+	if s.ReadyTimeout == 0 {
+		s.ReadyTimeout = DefaultReadyTimeout
+	}
+	if len(s.Command) == 0 {
+		s.Command = []string{"docker", "compose"}
+	}
+	e := &Env{suite: s, addrs: make(map[string]string), prevEnv: make(map[string]*string)}
+	if _, err := e.compose(ctx, "up", "-d", "--wait"); err != nil {
+		// older compose versions do not know --wait, readiness is checked below anyway
+		if _, err := e.compose(ctx, "up", "-d"); err != nil {
+			_ = e.Down(context.Background())
+			return nil, err
+		}
+	}
+	for _, service := range s.Services {
+		addr, err := e.resolve(ctx, service)
+		if err == nil {
+			err = waitReady(ctx, service, addr, s.ReadyTimeout)
+		}
+		if err != nil {
+			_ = e.Down(context.Background())
+			return nil, err
+		}
+		e.addrs[service.Name] = addr
+		if service.EnvVar != "" {
+			e.setenv(service.EnvVar, addr)
+		}
+	}
+	return e, nil
+}
+
+// Addr returns the "host:port" published for a service
+func (e *Env) Addr(service string) string {
+	return e.addrs[service]
+}
+
+// Reset runs the suite's Reset function, if any
+func (e *Env) Reset(ctx context.Context) error {
+	if e.suite.Reset == nil {
+		return nil
+	}
+	return e.suite.Reset(ctx, e)
+}
+
+// Down removes the containers and volumes and restores injected environment variables
+func (e *Env) Down(ctx context.Context) error {
+	for key, prev := range e.prevEnv {
+		if prev == nil {
+			_ = os.Unsetenv(key)
+		} else {
+			_ = os.Setenv(key, *prev)
+		}
+	}
+	e.prevEnv = make(map[string]*string)
+	_, err := e.compose(ctx, "down", "-v", "--remove-orphans")
+	return err
+}
+
+func (e *Env) setenv(key string, value string) {
+	if _, ok := e.prevEnv[key]; !ok {
+		if prev, ok := os.LookupEnv(key); ok {
+			e.prevEnv[key] = &prev
+		} else {
+			e.prevEnv[key] = nil
+		}
+	}
+	_ = os.Setenv(key, value)
+}
+
+func (e *Env) resolve(ctx context.Context, service Service) (string, error) {
+	out, err := e.compose(ctx, "port", service.Name, strconv.Itoa(service.Port))
+	if err != nil {
+		return "", err
+	}
+	addr := strings.TrimSpace(string(out))
+	if i := strings.IndexByte(addr, '\n'); i >= 0 {
+		addr = addr[:i]
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("itest: unexpected port output for %s: %q", service.Name, addr)
+	}
+	if host == "0.0.0.0" || host == "::" || host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func (e *Env) compose(ctx context.Context, args ...string) ([]byte, error) {
+	cmdArgs := append([]string{}, e.suite.Command[1:]...)
+	if e.suite.ComposeFile != "" {
+		cmdArgs = append(cmdArgs, "-f", e.suite.ComposeFile)
+	}
+	cmdArgs = append(cmdArgs, "-p", e.suite.Project)
+	cmdArgs = append(cmdArgs, args...)
+	cmd := exec.CommandContext(ctx, e.suite.Command[0], cmdArgs...)
+	cmd.Env = os.Environ()
+	for k, v := range e.suite.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return out, fmt.Errorf("itest: %s %s: %w: %s", e.suite.Command[0], strings.Join(cmdArgs, " "), err, exitErr.Stderr)
+		}
+		return out, fmt.Errorf("itest: %s %s: %w", e.suite.Command[0], strings.Join(cmdArgs, " "), err)
+	}
+	return out, nil
+}
+
+func waitReady(ctx context.Context, service Service, addr string, timeout time.Duration) error {
+	ready := service.Ready
+	if ready == nil {
+		ready = TCPReady()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		err := ready(ctx, addr)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("itest: service %s at %s not ready after %v: %w", service.Name, addr, timeout, err)
+		case <-time.After(readyInterval):
+		}
+	}
+}
+
+// TestingT is the subset of testing.TB used by this package
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...any)
+}
+
+// Start brings the suite up for a single test and tears it down in t.Cleanup,
+// which also runs when the test panics.
+func Start(t TestingT, s Suite) *Env {
+	t.Helper()
+	e, err := Up(context.Background(), s)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(func() {
+		_ = e.Down(context.Background())
+	})
+	return e
+}
+
+// Run resets the suite before running fn, use it for each test sharing the env
+func (e *Env) Run(t TestingT, fn func()) {
+	t.Helper()
+	if err := e.Reset(context.Background()); err != nil {
+		t.Fatalf("itest: reset: %v", err)
+	}
+	fn()
+}
+
+// M is implemented by *testing.M
+type M interface {
+	Run() int
+}
+
+// Main runs the whole test binary against the suite, for use in TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(itest.Main(m, suite)) }
+//
+// The suite is torn down when the tests finish, panic or the process is interrupted.
+func Main(m M, s Suite) int {
+	e, err := Up(context.Background(), s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	shutdown := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			_ = e.Down(context.Background())
+			os.Exit(1)
+		case <-shutdown:
+		}
+	}()
+	defer func() {
+		signal.Stop(signals)
+		close(shutdown)
+		if err := e.Down(context.Background()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+	return m.Run()
+}
@@ -0,0 +1,105 @@
+package itest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCompose writes a script standing in for "docker compose", it logs its
+// arguments and publishes every service port on addr.
+func fakeCompose(t *testing.T, addr string) (command []string, log string) {
+	dir := t.TempDir()
+	log = filepath.Join(dir, "calls.log")
+	script := filepath.Join(dir, "compose.sh")
+	body := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %q
+case "$*" in
+  *" port "*) echo %q ;;
+esac
+`, log, addr)
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return []string{"/bin/sh", script}, log
+}
+
+func TestStart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+	command, log := fakeCompose(t, addr)
+
+	resets := 0
+	t.Run("suite", func(t *testing.T) {
+		env := Start(t, Suite{
+			ComposeFile: "compose.yaml",
+			Project:     "itest-test",
+			Command:     command,
+			Services: []Service{
+				{Name: "mock", Port: 8080, Ready: HTTPReady("/health"), EnvVar: "ITEST_MOCK_ADDR"},
+				{Name: "redis", Port: 6379},
+			},
+			Reset: func(ctx context.Context, env *Env) error {
+				resets++
+				return nil
+			},
+		})
+		if got := env.Addr("mock"); got != addr {
+			t.Errorf("Addr() got = %v, want %v", got, addr)
+		}
+		if got := os.Getenv("ITEST_MOCK_ADDR"); got != addr {
+			t.Errorf("ITEST_MOCK_ADDR got = %v, want %v", got, addr)
+		}
+		env.Run(t, func() {})
+		env.Run(t, func() {})
+	})
+
+	if resets != 2 {
+		t.Errorf("Reset calls got = %d, want 2", resets)
+	}
+	if _, ok := os.LookupEnv("ITEST_MOCK_ADDR"); ok {
+		t.Errorf("ITEST_MOCK_ADDR still set after teardown")
+	}
+	calls, _ := os.ReadFile(log)
+	want := []string{
+		"-f compose.yaml -p itest-test up -d --wait",
+		"-f compose.yaml -p itest-test port mock 8080",
+		"-f compose.yaml -p itest-test port redis 6379",
+		"-f compose.yaml -p itest-test down -v --remove-orphans",
+	}
+	if got := strings.Split(strings.TrimSpace(string(calls)), "\n"); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("compose calls got = %q, want %q", got, want)
+	}
+}
+
+func TestUpNotReady(t *testing.T) {
+	// a port nobody listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	command, log := fakeCompose(t, addr)
+
+	_, err = Up(context.Background(), Suite{
+		Command:      command,
+		Services:     []Service{{Name: "db", Port: 5432}},
+		ReadyTimeout: 300 * time.Millisecond,
+	})
+	if err == nil || !strings.Contains(err.Error(), "service db") {
+		t.Errorf("Up() error = %v, want not ready error", err)
+	}
+	calls, _ := os.ReadFile(log)
+	if !strings.Contains(string(calls), "down -v") {
+		t.Errorf("Up() failure did not tear down, calls:\n%s", calls)
+	}
+}
@@ -0,0 +1,233 @@
+// Package jsonrpc calls JSON-RPC 2.0 services over HTTP with the http
+// package, so the calls go through the hooks, signing and settings of a
+// gohttp.Client.
+//
+//	var balance string
+//	err := jsonrpc.Call(ctx, endpoint, "eth_getBalance", []any{address, "latest"}, &balance)
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Error codes defined by the specification, servers use -32000 to -32099
+// for their own errors
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// RPCError is the error member of a response
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("jsonrpc: code %d: %s", e.Code, e.Message)
+}
+
+var (
+	// ErrNoResponse is set on batch calls the server did not answer
+	ErrNoResponse = errors.New("jsonrpc: no response for call")
+	// ErrResponse is returned for responses that are not JSON-RPC 2.0
+	ErrResponse = errors.New("jsonrpc: invalid response")
+)
+
+type config struct {
+	client *gohttp.Client
+}
+
+type Option = opt.Option[config]
+
+// WithClient sends the calls with client instead of the default Client of
+// the http package
+func WithClient(client *gohttp.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// Client calls one endpoint
+type Client struct {
+	endpoint string
+	cfg      config
+}
+
+// New creates a Client for endpoint
+func New(endpoint string, opts ...Option) (*Client, error) {
+	var cfg config
+	if err := opt.Build(&cfg, opts); err != nil {
+		return nil, err
+	}
+	return &Client{endpoint: endpoint, cfg: cfg}, nil
+}
+
+// lastID numbers the calls of all clients
+var lastID uint64
+
+func nextID() uint64 {
+	return atomic.AddUint64(&lastID, 1)
+}
+
+// Call calls method at endpoint with the default Client of the http package
+func Call(ctx context.Context, endpoint string, method string, params any, result any) error {
+	return (&Client{endpoint: endpoint}).Call(ctx, method, params, result)
+}
+
+// Notify sends a notification to endpoint with the default Client of the
+// http package
+func Notify(ctx context.Context, endpoint string, method string, params any) error {
+	return (&Client{endpoint: endpoint}).Notify(ctx, method, params)
+}
+
+type request struct {
+	JSONRPC string  `json:"jsonrpc"`
+	Method  string  `json:"method"`
+	Params  any     `json:"params,omitempty"`
+	ID      *uint64 `json:"id,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *RPCError       `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Call calls method and decodes its result into result, which may be nil.
+// Params is an array or an object, nil sends none. A failed call returns an
+// *RPCError.
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	id := nextID()
+	body, err := c.send(ctx, request{JSONRPC: "2.0", Method: method, Params: params, ID: &id})
+	if err != nil {
+		return err
+	}
+	var resp response
+	if err := json.Unmarshal(body, &resp); err != nil || resp.JSONRPC != "2.0" {
+		return fmt.Errorf("%w: %s", ErrResponse, truncate(body))
+	}
+	return resp.decode(result)
+}
+
+// Notify calls method without waiting for a result, the server sends none
+func (c *Client) Notify(ctx context.Context, method string, params any) error {
+	_, err := c.send(ctx, request{JSONRPC: "2.0", Method: method, Params: params})
+	return err
+}
+
+// BatchCall is one call of a Batch
+type BatchCall struct {
+	Method string
+	Params any
+	// Result receives the result, it may be nil
+	Result any
+	// Notification calls get no response
+	Notification bool
+	// Err is set by Batch, an *RPCError for a failed call
+	Err error
+}
+
+// Batch sends calls in one request. The error is for the request as a whole,
+// the outcome of every call is in its Err.
+func (c *Client) Batch(ctx context.Context, calls ...*BatchCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+	requests := make([]request, len(calls))
+	byID := make(map[string]*BatchCall, len(calls))
+	for i, call := range calls {
+		requests[i] = request{JSONRPC: "2.0", Method: call.Method, Params: call.Params}
+		call.Err = nil
+		if !call.Notification {
+			id := nextID()
+			requests[i].ID = &id
+			byID[fmt.Sprint(id)] = call
+			call.Err = ErrNoResponse
+		}
+	}
+	body, err := c.send(ctx, requests)
+	if err != nil || len(byID) == 0 {
+		return err
+	}
+	var responses []response
+	if err := json.Unmarshal(body, &responses); err != nil {
+		// a batch the server can't read is answered with a single error
+		var resp response
+		if json.Unmarshal(body, &resp) == nil && resp.Error != nil {
+			return resp.Error
+		}
+		return fmt.Errorf("%w: %s", ErrResponse, truncate(body))
+	}
+	for _, resp := range responses {
+		// ids are numbers, some servers echo them as strings
+		call, ok := byID[strings.Trim(string(resp.ID), `"`)]
+		if !ok {
+			continue
+		}
+		call.Err = resp.decode(call.Result)
+	}
+	return nil
+}
+
+func (r *response) decode(result any) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if result == nil || len(r.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
+}
+
+// send posts payload and returns the response body, empty for notifications
+func (c *Client) send(ctx context.Context, payload any) ([]byte, error) {
+	var req *gohttp.Request
+	if c.cfg.client != nil {
+		req = c.cfg.client.NewRequest(gohttp.POST, c.endpoint)
+	} else {
+		req = gohttp.NewRequest(gohttp.POST, c.endpoint)
+	}
+	resp, err := req.WithContext(ctx).Header("Accept", "application/json").Body(payload).Send()
+	var statusErr *gohttp.StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.Code == http.StatusNoContent || statusErr.Code == http.StatusAccepted:
+			// the answer to notifications
+			return nil, nil
+		case json.Valid(statusErr.Body) && len(statusErr.Body) > 0:
+			// some servers send errors with a 4xx or 5xx status
+			var probe struct {
+				JSONRPC string `json:"jsonrpc"`
+			}
+			if json.Unmarshal(statusErr.Body, &probe) == nil && probe.JSONRPC == "2.0" {
+				return statusErr.Body, nil
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func truncate(body []byte) string {
+	if len(body) > 200 {
+		return string(body[:200]) + "..."
+	}
+	return string(body)
+}
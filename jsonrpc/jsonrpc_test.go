@@ -0,0 +1,159 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+type serverRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  []int           `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// server adds its params, fails "boom" and doesn't know other methods
+type server struct {
+	mu       sync.Mutex
+	notified []string
+}
+
+func (s *server) handle(req serverRequest) map[string]any {
+	if req.ID == nil {
+		s.mu.Lock()
+		s.notified = append(s.notified, req.Method)
+		s.mu.Unlock()
+		return nil
+	}
+	resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	switch req.Method {
+	case "add":
+		sum := 0
+		for _, p := range req.Params {
+			sum += p
+		}
+		resp["result"] = sum
+	case "boom":
+		resp["error"] = map[string]any{"code": -32000, "message": "exploded", "data": map[string]string{"why": "test"}}
+	default:
+		resp["error"] = map[string]any{"code": CodeMethodNotFound, "message": "method not found"}
+	}
+	return resp
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	_ = json.NewDecoder(r.Body).Decode(&raw)
+	var batch []serverRequest
+	if json.Unmarshal(raw, &batch) == nil {
+		var out []map[string]any
+		for _, req := range batch {
+			if resp := s.handle(req); resp != nil {
+				out = append(out, resp)
+			}
+		}
+		if len(out) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(out)
+		return
+	}
+	var req serverRequest
+	_ = json.Unmarshal(raw, &req)
+	resp := s.handle(req)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if req.Method == "boom" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func TestCall(t *testing.T) {
+	fake := &server{}
+	endpoint := httptest.NewServer(fake)
+	defer endpoint.Close()
+	ctx := context.Background()
+
+	var sum int
+	if err := Call(ctx, endpoint.URL, "add", []int{1, 2, 3}, &sum); err != nil || sum != 6 {
+		t.Errorf("Call() got = %v %v, want 6", sum, err)
+	}
+
+	tests := []struct {
+		method string
+		code   int
+	}{
+		{"boom", -32000},
+		{"nope", CodeMethodNotFound},
+	}
+	for _, tt := range tests {
+		var rpcErr *RPCError
+		if err := Call(ctx, endpoint.URL, tt.method, nil, nil); !errors.As(err, &rpcErr) || rpcErr.Code != tt.code {
+			t.Errorf("Call(%v) error got = %v, want code %v", tt.method, err, tt.code)
+		}
+	}
+	var rpcErr *RPCError
+	_ = Call(ctx, endpoint.URL, "boom", nil, nil)
+	if err := Call(ctx, endpoint.URL, "boom", nil, nil); !errors.As(err, &rpcErr) || string(rpcErr.Data) != `{"why":"test"}` {
+		t.Errorf("Call() error data got = %v", err)
+	}
+
+	if err := Notify(ctx, endpoint.URL, "log", []int{1}); err != nil || len(fake.notified) != 1 {
+		t.Errorf("Notify() got = %v %v", fake.notified, err)
+	}
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"result": 1}`))
+	}))
+	defer plain.Close()
+	if err := Call(ctx, plain.URL, "add", nil, &sum); !errors.Is(err, ErrResponse) {
+		t.Errorf("Call() error got = %v, want %v", err, ErrResponse)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	fake := &server{}
+	endpoint := httptest.NewServer(fake)
+	defer endpoint.Close()
+	client, _ := New(endpoint.URL, WithClient(gohttp.NewClient()))
+
+	var a, b int
+	calls := []*BatchCall{
+		{Method: "add", Params: []int{1, 2}, Result: &a},
+		{Method: "log", Notification: true},
+		{Method: "boom"},
+		{Method: "add", Params: []int{10, 20}, Result: &b},
+	}
+	if err := client.Batch(context.Background(), calls...); err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	var rpcErr *RPCError
+	if a != 3 || b != 30 || calls[0].Err != nil || calls[1].Err != nil || !errors.As(calls[2].Err, &rpcErr) {
+		t.Errorf("Batch() got = %v %v, errors %v %v %v", a, b, calls[0].Err, calls[1].Err, calls[2].Err)
+	}
+
+	notifications := []*BatchCall{{Method: "x", Notification: true}, {Method: "y", Notification: true}}
+	if err := client.Batch(context.Background(), notifications...); err != nil || len(fake.notified) != 3 {
+		t.Errorf("Batch() notifications got = %v %v", fake.notified, err)
+	}
+
+	silent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer silent.Close()
+	lost := []*BatchCall{{Method: "add"}}
+	if err := (&Client{endpoint: silent.URL}).Batch(context.Background(), lost...); err != nil || !errors.Is(lost[0].Err, ErrNoResponse) {
+		t.Errorf("Batch() unanswered got = %v %v, want %v", err, lost[0].Err, ErrNoResponse)
+	}
+}
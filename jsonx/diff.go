@@ -0,0 +1,123 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// DiffKind tells how a value differs between two documents
+type DiffKind string
+
+const (
+	Added   DiffKind = "added"
+	Removed DiffKind = "removed"
+	Changed DiffKind = "changed"
+)
+
+// Difference is a value that differs between two documents, Old is nil for
+// Added and New for Removed
+type Difference struct {
+	Path string
+	Kind DiffKind
+	Old  any
+	New  any
+}
+
+func (d Difference) String() string {
+	path := d.Path
+	if path == "" {
+		path = "(root)"
+	}
+	oldJSON, _ := json.Marshal(d.Old)
+	newJSON, _ := json.Marshal(d.New)
+	switch d.Kind {
+	case Added:
+		return fmt.Sprintf("%s: added %s", path, newJSON)
+	case Removed:
+		return fmt.Sprintf("%s: removed %s", path, oldJSON)
+	}
+	return fmt.Sprintf("%s: %s != %s", path, oldJSON, newJSON)
+}
+
+// Diff compares the documents a and b and returns their differences sorted
+// by path, in the path syntax of Get. Key order does not matter, numbers
+// are compared by value so 1 and 1.0 are equal, arrays by index.
+func Diff(a []byte, b []byte) ([]Difference, error) {
+	va, err := decodeAny(a)
+	if err != nil {
+		return nil, err
+	}
+	vb, err := decodeAny(b)
+	if err != nil {
+		return nil, err
+	}
+	var out []Difference
+	diff(nil, va, vb, &out)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, nil
+}
+
+// Equal reports whether the documents a and b are equal as Diff compares them
+func Equal(a []byte, b []byte) (bool, error) {
+	diffs, err := Diff(a, b)
+	return len(diffs) == 0, err
+}
+
+func diff(path []segment, a any, b any, out *[]Difference) {
+	at := func(seg segment) []segment {
+		return append(path[:len(path):len(path)], seg)
+	}
+	switch x := a.(type) {
+	case map[string]any:
+		if y, ok := b.(map[string]any); ok {
+			for k, av := range x {
+				if bv, ok := y[k]; ok {
+					diff(at(segment{key: k}), av, bv, out)
+				} else {
+					*out = append(*out, Difference{Path: formatPath(at(segment{key: k})), Kind: Removed, Old: av})
+				}
+			}
+			for k, bv := range y {
+				if _, ok := x[k]; !ok {
+					*out = append(*out, Difference{Path: formatPath(at(segment{key: k})), Kind: Added, New: bv})
+				}
+			}
+			return
+		}
+	case []any:
+		if y, ok := b.([]any); ok {
+			for i := 0; i < len(x) || i < len(y); i++ {
+				seg := segment{index: i, isIndex: true}
+				switch {
+				case i >= len(y):
+					*out = append(*out, Difference{Path: formatPath(at(seg)), Kind: Removed, Old: x[i]})
+				case i >= len(x):
+					*out = append(*out, Difference{Path: formatPath(at(seg)), Kind: Added, New: y[i]})
+				default:
+					diff(at(seg), x[i], y[i], out)
+				}
+			}
+			return
+		}
+	case json.Number:
+		if y, ok := b.(json.Number); ok && numberEqual(x, y) {
+			return
+		}
+	default:
+		if a == b {
+			return
+		}
+	}
+	*out = append(*out, Difference{Path: formatPath(path), Kind: Changed, Old: a, New: b})
+}
+
+func numberEqual(a json.Number, b json.Number) bool {
+	if a == b {
+		return true
+	}
+	x, okA := new(big.Float).SetString(string(a))
+	y, okB := new(big.Float).SetString(string(b))
+	return okA && okB && x.Cmp(y) == 0
+}
@@ -0,0 +1,41 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Pretty indents data with two spaces, keeping the order of keys
+func Pretty(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Compact removes the insignificant white space of data
+func Compact(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SortKeys returns data compacted with the keys of all objects sorted, so
+// equal documents are equal bytes. Numbers are kept as written.
+func SortKeys(data []byte) ([]byte, error) {
+	v, err := decodeAny(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	// encoding/json writes map keys sorted
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,154 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const doc = `{
+	"data": {
+		"items": [{"name": "a", "price": 1.5}, {"name": "b", "tags": ["x", "y"]}],
+		"a.b": {"c]": true},
+		"total": 12345678901234567890
+	}
+}`
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    any
+		wantErr error
+	}{
+		{"data.items[0].name", "a", nil},
+		{"data.items[1].tags[1]", "y", nil},
+		{"data.items[0].price", json.Number("1.5"), nil},
+		{`data["a.b"]["c]"]`, true, nil},
+		{"data.total", json.Number("12345678901234567890"), nil},
+		{"data.items[2]", nil, ErrNotFound},
+		{"data.missing.x", nil, ErrNotFound},
+		{"data.items.name", nil, ErrNotFound},
+		{"data..items", nil, ErrInvalidPath},
+		{"data.items[x]", nil, ErrInvalidPath},
+		{"data.items[0", nil, ErrInvalidPath},
+		{".data", nil, ErrInvalidPath},
+	}
+	for _, tt := range tests {
+		got, err := Get([]byte(doc), tt.path)
+		if !errors.Is(err, tt.wantErr) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Get(%q) got = %#v %v, want %#v %v", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	raw, err := GetRaw([]byte(doc), "")
+	if err != nil || raw[0] != '{' {
+		t.Errorf("GetRaw() whole document got = %s %v", raw, err)
+	}
+	if _, err := GetRaw([]byte(`{"a":`), "a"); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("GetRaw() error = %v, want %v", err, ErrInvalidJSON)
+	}
+	tags, err := GetAs[[]string]([]byte(doc), "data.items[1].tags")
+	if err != nil || !reflect.DeepEqual(tags, []string{"x", "y"}) {
+		t.Errorf("GetAs() got = %v %v", tags, err)
+	}
+}
+
+type Base struct {
+	ID int64 `json:"id"`
+}
+
+type product struct {
+	Base
+	Name      string            `json:"name"`
+	Price     float64           `json:"price"`
+	Stock     *int              `json:"stock"`
+	Active    bool              `json:"active"`
+	Sizes     []int             `json:"sizes"`
+	Extra     map[string]uint16 `json:"extra"`
+	Created   time.Time         `json:"created"`
+	Code      string
+	Ignored   int `json:"-"`
+	unexposed int
+}
+
+func TestUnmarshal(t *testing.T) {
+	data := `{"id": "7", "name": 42, "price": " 9.5 ", "stock": "3", "active": "true",
+		"sizes": ["1", 2, null], "extra": {"a": "5"}, "created": "2024-01-02T03:04:05Z", "CODE": true, "Ignored": "x"}`
+	var got product
+	if err := Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	stock := 3
+	want := product{
+		Base: Base{ID: 7}, Name: "42", Price: 9.5, Stock: &stock, Active: true,
+		Sizes: []int{1, 2, 0}, Extra: map[string]uint16{"a": 5},
+		Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Code: "true",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() got = %+v, want %+v", got, want)
+	}
+
+	got = product{Name: "old", Price: 1, Active: true}
+	if err := Unmarshal([]byte(`{"name": null, "price": "", "active": 0, "stock": null}`), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Name != "" || got.Price != 0 || got.Active || got.Stock != nil {
+		t.Errorf("Unmarshal() nulls got = %+v, want zero values", got)
+	}
+
+	if err := Unmarshal([]byte(`{"price": "cheap"}`), &got); err == nil {
+		t.Errorf("Unmarshal() error = nil for a non numeric string")
+	}
+	if err := Unmarshal([]byte(`{}`), got); err == nil {
+		t.Errorf("Unmarshal() error = nil for a non pointer")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	in := []byte(`{"b": 1, "a": {"d": [1, 2.50], "c": "<x>"}}`)
+	if got, err := Compact(in); err != nil || string(got) != `{"b":1,"a":{"d":[1,2.50],"c":"<x>"}}` {
+		t.Errorf("Compact() got = %s %v", got, err)
+	}
+	if got, err := SortKeys(in); err != nil || string(got) != `{"a":{"c":"<x>","d":[1,2.50]},"b":1}` {
+		t.Errorf("SortKeys() got = %s %v", got, err)
+	}
+	want := "{\n  \"b\": 1,\n  \"a\": {\n    \"d\": [\n      1,\n      2.50\n    ],\n    \"c\": \"<x>\"\n  }\n}"
+	if got, err := Pretty(in); err != nil || string(got) != want {
+		t.Errorf("Pretty() got = %s %v", got, err)
+	}
+	if _, err := Pretty([]byte(`{`)); err == nil {
+		t.Errorf("Pretty() error = nil for invalid JSON")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := `{"name": "a", "n": 1, "tags": ["x", "y"], "meta": {"k.v": 1, "gone": null}}`
+	b := `{"n": 1.0, "name": "b", "tags": ["x"], "meta": {"k.v": 2, "new": {"x": true}}}`
+	diffs, err := Diff([]byte(a), []byte(b))
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	var got []string
+	for _, d := range diffs {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`meta.gone: removed null`,
+		`meta.new: added {"x":true}`,
+		`meta["k.v"]: 1 != 2`,
+		`name: "a" != "b"`,
+		`tags[1]: removed "y"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() got = %q, want %q", got, want)
+	}
+
+	if eq, err := Equal([]byte(`{"a":[1,{"b":null}]}`), []byte(` {"a": [1.00, {"b": null}]}`)); !eq || err != nil {
+		t.Errorf("Equal() got = %v %v, want true", eq, err)
+	}
+	if diffs, _ := Diff([]byte(`[1]`), []byte(`{"a":1}`)); len(diffs) != 1 || diffs[0].Path != "" || diffs[0].Kind != Changed {
+		t.Errorf("Diff() root got = %v", diffs)
+	}
+}
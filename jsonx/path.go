@@ -0,0 +1,179 @@
+// Package jsonx has helpers for raw JSON documents: path queries, tolerant
+// decoding, formatting and diffs, e.g. to assert on response bodies:
+//
+//	name, err := jsonx.GetAs[string](resp.Body, "data.items[0].name")
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned for a path missing from the document
+	ErrNotFound = errors.New("jsonx: path not found")
+	// ErrInvalidPath is returned for a path that does not parse
+	ErrInvalidPath = errors.New("jsonx: invalid path")
+	// ErrInvalidJSON is returned for documents that are not valid JSON
+	ErrInvalidJSON = errors.New("jsonx: invalid JSON")
+)
+
+// segment is an object key or, when key is empty and isIndex holds, an index
+type segment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsePath splits a path like `items[0].name` or `["a.b"].c` into segments
+func parsePath(path string) ([]segment, error) {
+	var segs []segment
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidPath, path, reason)
+	}
+	for i := 0; i < len(path); {
+		switch c := path[i]; {
+		case c == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, invalid("missing ]")
+			}
+			inner := path[i+1 : i+end]
+			if strings.HasPrefix(inner, `"`) {
+				// quoted keys may contain ], find the closing quote instead
+				key, rest, err := unquotePrefix(path[i+1:])
+				if err != nil || !strings.HasPrefix(rest, "]") {
+					return nil, invalid("bad quoted key")
+				}
+				segs = append(segs, segment{key: key})
+				i = len(path) - len(rest) + 1
+				continue
+			}
+			n, err := strconv.Atoi(inner)
+			if err != nil || n < 0 {
+				return nil, invalid("bad index " + inner)
+			}
+			segs = append(segs, segment{index: n, isIndex: true})
+			i += end + 1
+		case c == '.':
+			// a dot separates two segments
+			if i == 0 || i+1 == len(path) || path[i+1] == '.' || path[i+1] == '[' {
+				return nil, invalid("empty key")
+			}
+			i++
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			segs = append(segs, segment{key: path[i : i+end]})
+			i += end
+		}
+	}
+	return segs, nil
+}
+
+// unquotePrefix decodes the JSON string s starts with
+func unquotePrefix(s string) (string, string, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	var key string
+	if err := dec.Decode(&key); err != nil {
+		return "", "", err
+	}
+	return key, s[dec.InputOffset():], nil
+}
+
+// formatPath is the inverse of parsePath
+func formatPath(segs []segment) string {
+	var b strings.Builder
+	for _, seg := range segs {
+		switch {
+		case seg.isIndex:
+			b.WriteString("[" + strconv.Itoa(seg.index) + "]")
+		case seg.key == "" || strings.ContainsAny(seg.key, `.[]"`):
+			quoted, _ := json.Marshal(seg.key)
+			b.WriteString("[" + string(quoted) + "]")
+		default:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(seg.key)
+		}
+	}
+	return b.String()
+}
+
+// GetRaw returns the JSON at path in data. Paths are dot separated keys and
+// bracketed indexes, keys with dots or brackets are quoted in brackets:
+// `items[0].name`, `["a.b"].c`. The empty path is the whole document.
+func GetRaw(data []byte, path string) (json.RawMessage, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, ErrInvalidJSON
+	}
+	current := json.RawMessage(bytes.TrimSpace(data))
+	for i, seg := range segs {
+		if current, err = child(current, seg); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, formatPath(segs[:i+1]))
+		}
+	}
+	return current, nil
+}
+
+func child(data json.RawMessage, seg segment) (json.RawMessage, error) {
+	if seg.isIndex {
+		var arr []json.RawMessage
+		if err := json.Unmarshal(data, &arr); err != nil || arr == nil || seg.index >= len(arr) {
+			return nil, ErrNotFound
+		}
+		return arr[seg.index], nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, ErrNotFound
+	}
+	v, ok := obj[seg.key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return v, nil
+}
+
+// Get returns the value at path in data, see GetRaw for paths. Objects are
+// map[string]any, arrays []any and numbers json.Number.
+func Get(data []byte, path string) (any, error) {
+	raw, err := GetRaw(data, path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAny(raw)
+}
+
+// GetAs decodes the value at path in data into a T with Unmarshal, so
+// "42" is read as an int
+func GetAs[T any](data []byte, path string) (T, error) {
+	var v T
+	raw, err := GetRaw(data, path)
+	if err != nil {
+		return v, err
+	}
+	err = Unmarshal(raw, &v)
+	return v, err
+}
+
+func decodeAny(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
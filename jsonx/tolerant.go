@@ -0,0 +1,180 @@
+package jsonx
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Unmarshal is a tolerant json.Unmarshal for documents of loosely typed
+// producers. Where v expects them:
+//
+//   - numeric strings like "42" decode into numbers, "" into 0
+//   - numbers and booleans decode into strings
+//   - "true", "false", "1", "0", 1 and 0 decode into booleans
+//   - null sets numbers, strings and booleans to their zero value
+//
+// Types implementing json.Unmarshaler or encoding.TextUnmarshaler get their
+// JSON as is.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	tree, err := decodeAny(data)
+	if err != nil {
+		return err
+	}
+	fixed, err := json.Marshal(coerce(tree, rv.Type().Elem()))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(fixed, v)
+}
+
+// coerce converts v decoded with decodeAny to the JSON t decodes from
+func coerce(v any, t reflect.Type) any {
+	if reflect.PtrTo(t).Implements(jsonUnmarshaler) || reflect.PtrTo(t).Implements(textUnmarshaler) {
+		return v
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if v == nil {
+			return nil
+		}
+		return coerce(v, t.Elem())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		switch x := v.(type) {
+		case nil:
+			return json.Number("0")
+		case string:
+			s := strings.TrimSpace(x)
+			if s == "" {
+				return json.Number("0")
+			}
+			if _, err := strconv.ParseFloat(s, 64); err == nil {
+				return json.Number(s)
+			}
+		case bool:
+			if x {
+				return json.Number("1")
+			}
+			return json.Number("0")
+		}
+	case reflect.String:
+		switch x := v.(type) {
+		case nil:
+			return ""
+		case json.Number:
+			return string(x)
+		case bool:
+			return strconv.FormatBool(x)
+		}
+	case reflect.Bool:
+		switch x := v.(type) {
+		case nil:
+			return false
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(x)); err == nil {
+				return b
+			}
+		case json.Number:
+			if x == "0" || x == "1" {
+				return x == "1"
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if arr, ok := v.([]any); ok {
+			for i := range arr {
+				arr[i] = coerce(arr[i], t.Elem())
+			}
+		}
+	case reflect.Map:
+		if obj, ok := v.(map[string]any); ok {
+			for k, e := range obj {
+				obj[k] = coerce(e, t.Elem())
+			}
+		}
+	case reflect.Struct:
+		if obj, ok := v.(map[string]any); ok {
+			fields := structFields(t)
+			for k, e := range obj {
+				if ft, ok := fields.lookup(k); ok {
+					obj[k] = coerce(e, ft)
+				}
+			}
+		}
+	}
+	return v
+}
+
+// fieldTypes maps the JSON names of struct fields to their types
+type fieldTypes map[string]reflect.Type
+
+// lookup matches name like encoding/json, exactly or else case-insensitively
+func (f fieldTypes) lookup(name string) (reflect.Type, bool) {
+	if t, ok := f[name]; ok {
+		return t, true
+	}
+	for field, t := range f {
+		if strings.EqualFold(field, name) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+var fieldCache sync.Map
+
+func structFields(t reflect.Type) fieldTypes {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(fieldTypes)
+	}
+	fields := make(fieldTypes)
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	// promoted fields lose to the fields of t
+	for _, et := range embedded {
+		for name, ft := range structFields(et) {
+			if _, ok := fields[name]; !ok {
+				fields[name] = ft
+			}
+		}
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
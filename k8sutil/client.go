@@ -0,0 +1,143 @@
+package k8sutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for objects that do not exist
+	ErrNotFound = errors.New("k8sutil: not found")
+	// ErrConflict is returned when an object changed since it was read
+	ErrConflict = errors.New("k8sutil: conflict")
+)
+
+// StatusError is a failed API request with the Status the server returned
+type StatusError struct {
+	Code    int
+	Reason  string
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("k8sutil: %d %s: %s", e.Code, e.Reason, e.Message)
+}
+
+// Is matches ErrNotFound and ErrConflict
+func (e *StatusError) Is(target error) bool {
+	return (target == ErrNotFound && e.Code == http.StatusNotFound) ||
+		(target == ErrConflict && e.Code == http.StatusConflict)
+}
+
+// tokenRefresh is how often a BearerTokenFile is read again
+const tokenRefresh = time.Minute
+
+// Client sends requests to the API server, it is safe for concurrent use
+type Client struct {
+	config     Config
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	tokenRead time.Time
+}
+
+func NewClient(cfg *Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg.TLS
+	return &Client{config: *cfg, httpClient: &http.Client{Transport: transport}, now: time.Now}
+}
+
+// Namespace returns the namespace of the config
+func (c *Client) Namespace() string {
+	return c.config.Namespace
+}
+
+func (c *Client) bearerToken() (string, error) {
+	if c.config.BearerTokenFile == "" {
+		return c.config.BearerToken, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && c.now().Sub(c.tokenRead) < tokenRefresh {
+		return c.token, nil
+	}
+	data, err := os.ReadFile(c.config.BearerTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("k8sutil: reading the token: %w", err)
+	}
+	c.token, c.tokenRead = strings.TrimSpace(string(data)), c.now()
+	return c.token, nil
+}
+
+// stream sends a request and returns the response body of a 2xx response
+func (c *Client) stream(ctx context.Context, method string, path string, in any) (io.ReadCloser, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.config.Host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token, err := c.bearerToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, decodeStatus(resp.StatusCode, resp.Body)
+	}
+	return resp.Body, nil
+}
+
+// do sends a request and decodes the response into out unless it is nil
+func (c *Client) do(ctx context.Context, method string, path string, in any, out any) error {
+	body, err := c.stream(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return fmt.Errorf("k8sutil: decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+func decodeStatus(code int, body io.Reader) error {
+	status := StatusError{Code: code}
+	data, _ := io.ReadAll(io.LimitReader(body, 64<<10))
+	if json.Unmarshal(data, &status) != nil || status.Message == "" {
+		status.Message = strings.TrimSpace(string(data))
+	}
+	status.Code = code
+	return &status
+}
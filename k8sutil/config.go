@@ -0,0 +1,227 @@
+// Package k8sutil talks to the Kubernetes API without client-go: it loads the
+// in-cluster or kubeconfig credentials, reads and watches ConfigMaps and
+// Secrets, elects a leader with a Lease and tells the pod identity.
+package k8sutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serviceAccountDir holds the token, CA and namespace mounted into pods
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// ErrNotInCluster is returned by InClusterConfig outside of a pod
+var ErrNotInCluster = errors.New("k8sutil: not running in a cluster")
+
+// Config tells how to reach the API server
+type Config struct {
+	// Host is the API server url, e.g. https://10.96.0.1:443
+	Host string
+	// BearerToken authenticates requests, BearerTokenFile is read again while
+	// the client runs as projected service account tokens are rotated
+	BearerToken     string
+	BearerTokenFile string
+	TLS             *tls.Config
+	// Namespace is the namespace of the pod or the kubeconfig context
+	Namespace string
+}
+
+// InClusterConfig uses the service account of the pod
+func InClusterConfig() (*Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+	tokenFile := filepath.Join(serviceAccountDir, "token")
+	if _, err := os.Stat(tokenFile); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotInCluster, err)
+	}
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("k8sutil: reading the cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("k8sutil: no certificate in the cluster CA")
+	}
+	return &Config{
+		Host:            "https://" + net.JoinHostPort(host, port),
+		BearerTokenFile: tokenFile,
+		TLS:             &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		Namespace:       Namespace(),
+	}, nil
+}
+
+// LoadConfig uses the in-cluster config in a pod, otherwise the kubeconfig
+// of $KUBECONFIG or ~/.kube/config with its current context
+func LoadConfig() (*Config, error) {
+	cfg, err := InClusterConfig()
+	if !errors.Is(err, ErrNotInCluster) {
+		return cfg, err
+	}
+	path := os.Getenv("KUBECONFIG")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, ".kube", "config")
+	} else {
+		// the first of a list is enough for the common case
+		path = filepath.SplitList(path)[0]
+	}
+	return LoadKubeconfig(path, "")
+}
+
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string    `yaml:"token"`
+			TokenFile             string    `yaml:"tokenFile"`
+			ClientCertificate     string    `yaml:"client-certificate"`
+			ClientCertificateData string    `yaml:"client-certificate-data"`
+			ClientKey             string    `yaml:"client-key"`
+			ClientKeyData         string    `yaml:"client-key-data"`
+			Exec                  yaml.Node `yaml:"exec"`
+			AuthProvider          yaml.Node `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// LoadKubeconfig reads the kubeconfig at path for context, the current
+// context when it is empty. Tokens and client certificates are supported,
+// exec and auth-provider plugins are not.
+func LoadKubeconfig(path string, context string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, fmt.Errorf("k8sutil: parsing %s: %w", path, err)
+	}
+	if context == "" {
+		context = kc.CurrentContext
+	}
+	// relative paths in a kubeconfig are relative to its directory
+	dir := filepath.Dir(path)
+	file := func(name string) string {
+		if name == "" || filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(dir, name)
+	}
+
+	cfg := &Config{Namespace: "default"}
+	var clusterName, userName string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == context {
+			found = true
+			clusterName, userName = c.Context.Cluster, c.Context.User
+			if c.Context.Namespace != "" {
+				cfg.Namespace = c.Context.Namespace
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("k8sutil: context %q not found in %s", context, path)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	found = false
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		cfg.Host = strings.TrimRight(c.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		tlsConfig.ServerName = c.Cluster.TLSServerName
+		ca, err := dataOrFile(c.Cluster.CertificateAuthorityData, file(c.Cluster.CertificateAuthority))
+		if err != nil {
+			return nil, err
+		}
+		if ca != nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("k8sutil: no certificate in the CA of cluster %q", clusterName)
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("k8sutil: cluster %q not found in %s", clusterName, path)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		if !u.User.Exec.IsZero() || !u.User.AuthProvider.IsZero() {
+			return nil, fmt.Errorf("k8sutil: user %q needs a credential plugin, which is not supported", userName)
+		}
+		cfg.BearerToken, cfg.BearerTokenFile = u.User.Token, file(u.User.TokenFile)
+		cert, err := dataOrFile(u.User.ClientCertificateData, file(u.User.ClientCertificate))
+		if err != nil {
+			return nil, err
+		}
+		key, err := dataOrFile(u.User.ClientKeyData, file(u.User.ClientKey))
+		if err != nil {
+			return nil, err
+		}
+		if cert != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("k8sutil: client certificate of user %q: %w", userName, err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+	}
+	cfg.TLS = tlsConfig
+	return cfg, nil
+}
+
+// dataOrFile decodes the base64 data of a kubeconfig field or reads its file
+func dataOrFile(data string, file string) ([]byte, error) {
+	if data != "" {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("k8sutil: decoding kubeconfig data: %w", err)
+		}
+		return decoded, nil
+	}
+	if file == "" {
+		return nil, nil
+	}
+	return os.ReadFile(file)
+}
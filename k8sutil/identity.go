@@ -0,0 +1,40 @@
+package k8sutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// InCluster tells whether the process runs in a pod with a service account
+func InCluster() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(serviceAccountDir, "token"))
+	return err == nil
+}
+
+// PodName returns $POD_NAME, set it with the downward API, or the host name
+// which is the pod name unless the pod sets its own
+func PodName() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// Namespace returns $POD_NAMESPACE, the namespace of the service account or
+// "default"
+func Namespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if data, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+		if ns := strings.TrimSpace(string(data)); ns != "" {
+			return ns
+		}
+	}
+	return "default"
+}
@@ -0,0 +1,258 @@
+package k8sutil
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// apiServer keeps objects by path and bumps a resource version on every write.
+// Watches are served from events, one stream per watch request.
+type apiServer struct {
+	mu      sync.Mutex
+	version int
+	objects map[string]map[string]any
+	events  chan string
+}
+
+func newAPIServer(t *testing.T) (*apiServer, *Client) {
+	api := &apiServer{objects: map[string]map[string]any{}, events: make(chan string, 10)}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return api, NewClient(&Config{Host: server.URL, BearerToken: "secret", Namespace: "default"})
+}
+
+func (a *apiServer) put(path string, obj map[string]any) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.version++
+	version := strconv.Itoa(a.version)
+	obj["metadata"].(map[string]any)["resourceVersion"] = version
+	a.objects[path] = obj
+	return version
+}
+
+func (a *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Query().Get("watch") == "1" {
+		for event := range a.events {
+			if event == "" {
+				return
+			}
+			fmt.Fprintln(w, event)
+			w.(http.Flusher).Flush()
+		}
+		return
+	}
+	var obj map[string]any
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&obj)
+	}
+	a.mu.Lock()
+	current, ok := a.objects[r.URL.Path]
+	a.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"kind":"Status","code":404,"reason":"NotFound","message":"not found"}`)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(current)
+	case http.MethodPost:
+		a.put(r.URL.Path+"/"+obj["metadata"].(map[string]any)["name"].(string), obj)
+	case http.MethodPut:
+		if current["metadata"].(map[string]any)["resourceVersion"] != obj["metadata"].(map[string]any)["resourceVersion"] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		a.put(r.URL.Path, obj)
+	}
+}
+
+func TestLoadKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "token"), []byte("file-token\n"), 0o600)
+	kubeconfig := `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com:6443/
+    insecure-skip-tls-verify: true
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com
+users:
+- name: dev-user
+  user:
+    tokenFile: token
+- name: prod-user
+  user:
+    exec:
+      command: aws
+contexts:
+- name: dev
+  context: {cluster: dev-cluster, user: dev-user, namespace: team-a}
+- name: prod
+  context: {cluster: prod-cluster, user: prod-user}
+`
+	path := filepath.Join(dir, "config")
+	_ = os.WriteFile(path, []byte(kubeconfig), 0o600)
+
+	cfg, err := LoadKubeconfig(path, "")
+	if err != nil {
+		t.Fatalf("LoadKubeconfig() error = %v", err)
+	}
+	if cfg.Host != "https://dev.example.com:6443" || cfg.Namespace != "team-a" || !cfg.TLS.InsecureSkipVerify || cfg.BearerTokenFile != filepath.Join(dir, "token") {
+		t.Errorf("LoadKubeconfig() got = %+v", cfg)
+	}
+	if token, err := NewClient(cfg).bearerToken(); token != "file-token" || err != nil {
+		t.Errorf("bearerToken() got = %q %v, want file-token", token, err)
+	}
+	if _, err := LoadKubeconfig(path, "prod"); err == nil || !strings.Contains(err.Error(), "credential plugin") {
+		t.Errorf("LoadKubeconfig() prod error got = %v, want unsupported plugin", err)
+	}
+	if _, err := LoadKubeconfig(path, "staging"); err == nil {
+		t.Errorf("LoadKubeconfig() unknown context error = nil")
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	dir := t.TempDir()
+	old := serviceAccountDir
+	serviceAccountDir = dir
+	defer func() { serviceAccountDir = old }()
+	_ = os.WriteFile(filepath.Join(dir, "namespace"), []byte("payments\n"), 0o600)
+
+	t.Setenv("POD_NAME", "api-7d9f")
+	t.Setenv("POD_NAMESPACE", "")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	if got := PodName(); got != "api-7d9f" {
+		t.Errorf("PodName() got = %v, want api-7d9f", got)
+	}
+	if got := Namespace(); got != "payments" {
+		t.Errorf("Namespace() got = %v, want payments", got)
+	}
+	if InCluster() {
+		t.Errorf("InCluster() got = true without KUBERNETES_SERVICE_HOST")
+	}
+	if _, err := InClusterConfig(); !errors.Is(err, ErrNotInCluster) {
+		t.Errorf("InClusterConfig() error got = %v, want %v", err, ErrNotInCluster)
+	}
+}
+
+func TestConfigMaps(t *testing.T) {
+	api, client := newAPIServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := "/api/v1/namespaces/default/configmaps/app"
+	if _, err := client.GetConfigMap(ctx, "default", "app"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetConfigMap() error got = %v, want %v", err, ErrNotFound)
+	}
+	api.put(path, map[string]any{"metadata": map[string]any{"name": "app"}, "data": map[string]any{"level": "info"}})
+	api.put("/api/v1/namespaces/default/secrets/db", map[string]any{"metadata": map[string]any{"name": "db"},
+		"data": map[string]any{"password": base64.StdEncoding.EncodeToString([]byte("hunter2"))}})
+	if secret, err := client.GetSecret(ctx, "default", "db"); err != nil || string(secret.Data["password"]) != "hunter2" {
+		t.Errorf("GetSecret() got = %+v %v", secret, err)
+	}
+
+	updates := make(chan *ConfigMap, 10)
+	go func() {
+		_ = client.WatchConfigMap(ctx, "default", "app", func(cm *ConfigMap) { updates <- cm })
+	}()
+	next := func() *ConfigMap {
+		select {
+		case cm := <-updates:
+			return cm
+		case <-time.After(2 * time.Second):
+			t.Fatalf("WatchConfigMap() no update")
+			return nil
+		}
+	}
+	if cm := next(); cm.Data["level"] != "info" {
+		t.Errorf("WatchConfigMap() first got = %+v, want level info", cm)
+	}
+	api.events <- `{"type":"MODIFIED","object":{"metadata":{"name":"app","resourceVersion":"9"},"data":{"level":"debug"}}}`
+	if cm := next(); cm.Data["level"] != "debug" {
+		t.Errorf("WatchConfigMap() modified got = %+v, want level debug", cm)
+	}
+	api.events <- `{"type":"DELETED","object":{"metadata":{"name":"app","resourceVersion":"10"}}}`
+	if cm := next(); cm != nil {
+		t.Errorf("WatchConfigMap() deleted got = %+v, want nil", cm)
+	}
+	cancel()
+	close(api.events)
+}
+
+func TestElect(t *testing.T) {
+	_, client := newAPIServer(t)
+	opts := []ElectionOption{WithRetryPeriod(20 * time.Millisecond), WithRenewDeadline(100 * time.Millisecond), WithLeaseDuration(time.Second)}
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	ledA := make(chan struct{})
+	doneA := make(chan error)
+	go func() {
+		doneA <- client.Elect(ctxA, "default", "lock", "a", func(ctx context.Context) {
+			close(ledA)
+			<-ctx.Done()
+		}, opts...)
+	}()
+	<-ledA
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	ledB := make(chan struct{})
+	var seen []string
+	var mu sync.Mutex
+	go func() {
+		_ = client.Elect(ctxB, "default", "lock", "b", func(ctx context.Context) {
+			close(ledB)
+			<-ctx.Done()
+		}, append(opts, WithOnNewLeader(func(identity string) {
+			mu.Lock()
+			seen = append(seen, identity)
+			mu.Unlock()
+		}))...)
+	}()
+	select {
+	case <-ledB:
+		t.Fatalf("Elect() b leads while a holds the lease")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancelA()
+	if err := <-doneA; !errors.Is(err, context.Canceled) {
+		t.Errorf("Elect() a error got = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case <-ledB:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("Elect() b did not take over the released lease")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 1 || seen[0] != "a" {
+		t.Errorf("WithOnNewLeader() got = %v, want [a]", seen)
+	}
+
+	if err := client.Elect(context.Background(), "default", "lock", "c", nil, WithRenewDeadline(time.Minute)); err == nil {
+		t.Errorf("Elect() with a renew deadline above the lease duration error = nil")
+	}
+}
@@ -0,0 +1,235 @@
+package k8sutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	gourl "net/url"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// microTime is the time format of Lease fields
+const microTime = "2006-01-02T15:04:05.000000Z07:00"
+
+type lease struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   ObjectMeta `json:"metadata"`
+	Spec       leaseSpec  `json:"spec"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions,omitempty"`
+}
+
+type electionConfig struct {
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+	onNewLeader   func(identity string)
+}
+
+type ElectionOption = opt.Option[electionConfig]
+
+// WithLeaseDuration is how long others wait after the last renewal before
+// they take over, 15s by default
+func WithLeaseDuration(d time.Duration) ElectionOption {
+	return func(c *electionConfig) {
+		c.leaseDuration = d
+	}
+}
+
+// WithRenewDeadline is how long the leader keeps trying to renew before it
+// steps down, 10s by default. It must be shorter than the lease duration.
+func WithRenewDeadline(d time.Duration) ElectionOption {
+	return func(c *electionConfig) {
+		c.renewDeadline = d
+	}
+}
+
+// WithRetryPeriod is the pause between attempts to acquire or renew, 2s by default
+func WithRetryPeriod(d time.Duration) ElectionOption {
+	return func(c *electionConfig) {
+		c.retryPeriod = d
+	}
+}
+
+// WithOnNewLeader is called when another identity is seen holding the lease
+func WithOnNewLeader(fn func(identity string)) ElectionOption {
+	return func(c *electionConfig) {
+		c.onNewLeader = fn
+	}
+}
+
+var electionChecks = []opt.Check[electionConfig]{
+	func(c *electionConfig) error {
+		if c.retryPeriod <= 0 || c.renewDeadline <= c.retryPeriod || c.leaseDuration <= c.renewDeadline {
+			return errors.New("k8sutil: want retry period < renew deadline < lease duration")
+		}
+		return nil
+	},
+}
+
+// Elect campaigns for the coordination.k8s.io Lease namespace/name as
+// identity, typically PodName(). While it holds the lease lead runs with a
+// context that is canceled when the lease is lost, then Elect campaigns
+// again. When ctx is done the lease is released so another candidate takes
+// over at once, and Elect returns ctx.Err().
+func (c *Client) Elect(ctx context.Context, namespace string, name string, identity string, lead func(ctx context.Context), opts ...ElectionOption) error {
+	cfg := electionConfig{leaseDuration: 15 * time.Second, renewDeadline: 10 * time.Second, retryPeriod: 2 * time.Second}
+	if err := opt.Build(&cfg, opts, electionChecks...); err != nil {
+		return err
+	}
+	e := &election{client: c, namespace: namespace, name: name, identity: identity, config: cfg}
+	for {
+		if err := e.acquire(ctx); err != nil {
+			return err
+		}
+		leadCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			lead(leadCtx)
+		}()
+		e.renew(leadCtx)
+		cancel()
+		<-done
+		if ctx.Err() != nil {
+			e.release()
+			return ctx.Err()
+		}
+	}
+}
+
+type election struct {
+	client    *Client
+	namespace string
+	name      string
+	identity  string
+	config    electionConfig
+	leader    string
+}
+
+// acquire blocks until the lease is held or ctx is done
+func (e *election) acquire(ctx context.Context) error {
+	for {
+		if ok, _ := e.tryAcquireOrRenew(ctx); ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.config.retryPeriod):
+		}
+	}
+}
+
+// renew keeps the lease until a renewal did not succeed within the deadline
+func (e *election) renew(ctx context.Context) {
+	renewed := e.client.now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.config.retryPeriod):
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, e.config.renewDeadline)
+		ok, _ := e.tryAcquireOrRenew(attemptCtx)
+		cancel()
+		if ok {
+			renewed = e.client.now()
+		} else if e.client.now().Sub(renewed) >= e.config.renewDeadline || e.leader != e.identity {
+			return
+		}
+	}
+}
+
+func (e *election) path() string {
+	return fmt.Sprintf("/apis/coordination.k8s.io/v1/namespaces/%s/leases/%s", gourl.PathEscape(e.namespace), gourl.PathEscape(e.name))
+}
+
+func (e *election) tryAcquireOrRenew(ctx context.Context) (bool, error) {
+	now := e.client.now()
+	stamp := now.UTC().Format(microTime)
+	var l lease
+	err := e.client.do(ctx, http.MethodGet, e.path(), nil, &l)
+	if errors.Is(err, ErrNotFound) {
+		l = lease{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   ObjectMeta{Name: e.name, Namespace: e.namespace},
+			Spec: leaseSpec{
+				HolderIdentity:       e.identity,
+				LeaseDurationSeconds: e.leaseSeconds(),
+				AcquireTime:          stamp,
+				RenewTime:            stamp,
+			},
+		}
+		path := fmt.Sprintf("/apis/coordination.k8s.io/v1/namespaces/%s/leases", gourl.PathEscape(e.namespace))
+		if err := e.client.do(ctx, http.MethodPost, path, &l, nil); err != nil {
+			return false, err
+		}
+		e.observe(e.identity)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	holder := l.Spec.HolderIdentity
+	if holder != "" && holder != e.identity {
+		renewed, _ := time.Parse(microTime, l.Spec.RenewTime)
+		if now.Before(renewed.Add(time.Duration(l.Spec.LeaseDurationSeconds) * time.Second)) {
+			e.observe(holder)
+			return false, nil
+		}
+	}
+	if holder != e.identity {
+		l.Spec.AcquireTime = stamp
+		l.Spec.LeaseTransitions++
+	}
+	l.Spec.HolderIdentity = e.identity
+	l.Spec.LeaseDurationSeconds = e.leaseSeconds()
+	l.Spec.RenewTime = stamp
+	// the resource version makes a concurrent update fail with a conflict
+	if err := e.client.do(ctx, http.MethodPut, e.path(), &l, nil); err != nil {
+		return false, err
+	}
+	e.observe(e.identity)
+	return true, nil
+}
+
+// leaseSeconds rounds the lease duration up to whole seconds, the unit of the Lease
+func (e *election) leaseSeconds() int {
+	return int((e.config.leaseDuration + time.Second - 1) / time.Second)
+}
+
+func (e *election) observe(holder string) {
+	if holder == e.leader {
+		return
+	}
+	e.leader = holder
+	if holder != e.identity && e.config.onNewLeader != nil {
+		e.config.onNewLeader(holder)
+	}
+}
+
+// release gives up the lease if it is still held
+func (e *election) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.renewDeadline)
+	defer cancel()
+	var l lease
+	if err := e.client.do(ctx, http.MethodGet, e.path(), nil, &l); err != nil || l.Spec.HolderIdentity != e.identity {
+		return
+	}
+	l.Spec.HolderIdentity = ""
+	l.Spec.LeaseDurationSeconds = 1
+	l.Spec.RenewTime = e.client.now().UTC().Format(microTime)
+	_ = e.client.do(ctx, http.MethodPut, e.path(), &l, nil)
+}
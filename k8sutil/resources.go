@@ -0,0 +1,183 @@
+package k8sutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	gourl "net/url"
+	"time"
+)
+
+// ObjectMeta holds the metadata fields the helpers use
+type ObjectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+type ConfigMap struct {
+	Metadata   ObjectMeta        `json:"metadata"`
+	Data       map[string]string `json:"data,omitempty"`
+	BinaryData map[string][]byte `json:"binaryData,omitempty"`
+}
+
+// Secret has its values decoded
+type Secret struct {
+	Metadata ObjectMeta        `json:"metadata"`
+	Type     string            `json:"type,omitempty"`
+	Data     map[string][]byte `json:"data,omitempty"`
+}
+
+// GetConfigMap reads a ConfigMap, a missing one is ErrNotFound
+func (c *Client) GetConfigMap(ctx context.Context, namespace string, name string) (*ConfigMap, error) {
+	var cm ConfigMap
+	if err := c.do(ctx, http.MethodGet, objectPath(namespace, "configmaps", name), nil, &cm); err != nil {
+		return nil, err
+	}
+	return &cm, nil
+}
+
+// GetSecret reads a Secret, a missing one is ErrNotFound
+func (c *Client) GetSecret(ctx context.Context, namespace string, name string) (*Secret, error) {
+	var secret Secret
+	if err := c.do(ctx, http.MethodGet, objectPath(namespace, "secrets", name), nil, &secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// WatchConfigMap calls handler with the ConfigMap and then on every change,
+// with nil while it does not exist. It reconnects until ctx is done and
+// returns ctx.Err(). Feed a config loader with cm.Data from handler.
+func (c *Client) WatchConfigMap(ctx context.Context, namespace string, name string, handler func(cm *ConfigMap)) error {
+	return watch(ctx, c, namespace, "configmaps", name, func(cm *ConfigMap) *ObjectMeta { return &cm.Metadata }, handler)
+}
+
+// WatchSecret is WatchConfigMap for a Secret
+func (c *Client) WatchSecret(ctx context.Context, namespace string, name string, handler func(secret *Secret)) error {
+	return watch(ctx, c, namespace, "secrets", name, func(s *Secret) *ObjectMeta { return &s.Metadata }, handler)
+}
+
+func objectPath(namespace string, resource string, name string) string {
+	return fmt.Sprintf("/api/v1/namespaces/%s/%s/%s", gourl.PathEscape(namespace), resource, gourl.PathEscape(name))
+}
+
+// errExpired is a watch whose resource version is too old, the object is read again
+var errExpired = errors.New("k8sutil: resource version expired")
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+func watch[T any](ctx context.Context, c *Client, namespace string, resource string, name string, meta func(*T) *ObjectMeta, handler func(*T)) error {
+	var version string
+	delivered := false
+	deliver := func(obj *T) {
+		v := ""
+		if obj != nil {
+			v = meta(obj).ResourceVersion
+		}
+		if !delivered || v != version {
+			handler(obj)
+		}
+		delivered, version = true, v
+	}
+	backoff := time.Second
+	relist := true
+	for {
+		if relist {
+			var obj T
+			err := c.do(ctx, http.MethodGet, objectPath(namespace, resource, name), nil, &obj)
+			switch {
+			case errors.Is(err, ErrNotFound):
+				deliver(nil)
+			case err == nil:
+				deliver(&obj)
+			}
+			if err == nil || errors.Is(err, ErrNotFound) {
+				relist, backoff = false, time.Second
+			}
+		}
+		if !relist {
+			listVersion := version
+			if listVersion == "" {
+				// a missing object is watched from now
+				listVersion = "0"
+			}
+			err := watchOnce(ctx, c, namespace, resource, name, listVersion, func(event string, obj *T) {
+				switch event {
+				case "ADDED", "MODIFIED":
+					deliver(obj)
+				case "DELETED":
+					deliver(nil)
+				case "BOOKMARK":
+					version = meta(obj).ResourceVersion
+				}
+				backoff = time.Second
+			})
+			if errors.Is(err, errExpired) {
+				relist = true
+				continue
+			}
+			if err == io.EOF && ctx.Err() == nil {
+				// the server ended the watch after timeoutSeconds
+				continue
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+}
+
+// watchOnce streams the events of one watch request until the server ends it
+func watchOnce[T any](ctx context.Context, c *Client, namespace string, resource string, name string, version string, onEvent func(event string, obj *T)) error {
+	query := gourl.Values{
+		"watch":               {"1"},
+		"fieldSelector":       {"metadata.name=" + name},
+		"resourceVersion":     {version},
+		"allowWatchBookmarks": {"true"},
+		"timeoutSeconds":      {"300"},
+	}
+	path := fmt.Sprintf("/api/v1/namespaces/%s/%s?%s", gourl.PathEscape(namespace), resource, query.Encode())
+	body, err := c.stream(ctx, http.MethodGet, path, nil)
+	var status *StatusError
+	if errors.As(err, &status) && status.Code == http.StatusGone {
+		return errExpired
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	decoder := json.NewDecoder(body)
+	for {
+		var event watchEvent
+		if err := decoder.Decode(&event); err != nil {
+			return err
+		}
+		if event.Type == "ERROR" {
+			status := StatusError{}
+			_ = json.Unmarshal(event.Object, &status)
+			if status.Code == http.StatusGone {
+				return errExpired
+			}
+			return &status
+		}
+		var obj T
+		if err := json.Unmarshal(event.Object, &obj); err != nil {
+			return fmt.Errorf("k8sutil: decoding watch event: %w", err)
+		}
+		onEvent(event.Type, &obj)
+	}
+}
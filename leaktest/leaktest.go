@@ -0,0 +1,224 @@
+package leaktest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout  = 2 * time.Second
+	pollingInterval = 10 * time.Millisecond
+)
+
+// TestingT is the subset of testing.TB used by this package
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...any)
+}
+
+type options struct {
+	timeout          time.Duration
+	ignoreGoroutines []string
+	ignoreFDs        []string
+	skipFDs          bool
+}
+
+type Option func(*options)
+
+// Timeout sets how long Check waits for goroutines and fds to go away
+func Timeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// IgnoreGoroutine allows leaked goroutines whose stack contains substr
+func IgnoreGoroutine(substr string) Option {
+	return func(o *options) {
+		o.ignoreGoroutines = append(o.ignoreGoroutines, substr)
+	}
+}
+
+// IgnoreFD allows leaked file descriptors whose target contains substr
+func IgnoreFD(substr string) Option {
+	return func(o *options) {
+		o.ignoreFDs = append(o.ignoreFDs, substr)
+	}
+}
+
+// SkipFDs disables the file descriptor check
+func SkipFDs() Option {
+	return func(o *options) {
+		o.skipFDs = true
+	}
+}
+
+// Check snapshots the running goroutines and open file descriptors and
+// registers a cleanup that reports any extra ones left when t ends.
+// Leftovers get the configured timeout to wind down before failing.
+func Check(t TestingT, opts ...Option) {
+	t.Helper()
+	o := options{timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	goroutines := goroutineIDs()
+	var fds map[int]string
+	if !o.skipFDs {
+		fds = openFDs()
+	}
+	t.Cleanup(func() {
+		t.Helper()
+		var leakedGoroutines []string
+		var leakedFDs []string
+		deadline := time.Now().Add(o.timeout)
+		for {
+			leakedGoroutines = leakedGoroutineStacks(goroutines, o.ignoreGoroutines)
+			leakedFDs = nil
+			if fds != nil {
+				leakedFDs = leakedFDTargets(fds, o.ignoreFDs)
+			}
+			if (len(leakedGoroutines) == 0 && len(leakedFDs) == 0) || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(pollingInterval)
+		}
+		for _, stack := range leakedGoroutines {
+			t.Errorf("leaktest: leaked goroutine:\n%s", stack)
+		}
+		for _, target := range leakedFDs {
+			t.Errorf("leaktest: leaked file descriptor: %s", target)
+		}
+	})
+}
+
+// goroutineIDs returns the ids of all running goroutines
+func goroutineIDs() map[int]bool {
+	ids := make(map[int]bool)
+	for _, stack := range goroutineStacks() {
+		ids[goroutineID(stack)] = true
+	}
+	return ids
+}
+
+func leakedGoroutineStacks(before map[int]bool, ignore []string) []string {
+	var leaked []string
+	for _, stack := range goroutineStacks() {
+		if before[goroutineID(stack)] || containsAny(stack, ignore) {
+			continue
+		}
+		leaked = append(leaked, stack)
+	}
+	return leaked
+}
+
+func goroutineStacks() []string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	var stacks []string
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if len(stack) > 0 {
+			stacks = append(stacks, string(stack))
+		}
+	}
+	return stacks
+}
+
+// goroutineID parses the id from a "goroutine 12 [running]:" header
+func goroutineID(stack string) int {
+	fields := strings.Fields(stack)
+	if len(fields) < 2 {
+		return -1
+	}
+	id, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
+// openFDs maps the open file descriptors to their targets, nil if the
+// platform offers no fd listing
+func openFDs() map[int]string {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		fds := make(map[int]string, len(entries))
+		for _, entry := range entries {
+			fd, err := strconv.Atoi(entry.Name())
+			if err != nil {
+				continue
+			}
+			// the descriptor used to read dir is already closed and fails here
+			target, err := os.Readlink(dir + "/" + entry.Name())
+			if err != nil {
+				continue
+			}
+			fds[fd] = target
+		}
+		return fds
+	}
+	return nil
+}
+
+func leakedFDTargets(before map[int]string, ignore []string) []string {
+	var leaked []string
+	for fd, target := range openFDs() {
+		if prev, ok := before[fd]; ok && prev == target {
+			continue
+		}
+		if containsAny(target, ignore) {
+			continue
+		}
+		leaked = append(leaked, fmt.Sprintf("fd %d -> %s", fd, target))
+	}
+	sort.Strings(leaked)
+	return leaked
+}
+
+// HeapGrowth runs fn iterations times and reports an error when the live
+// heap grew by more than maxBytes, after garbage collection on both ends.
+func HeapGrowth(t TestingT, maxBytes uint64, iterations int, fn func()) {
+	t.Helper()
+	before := liveHeap()
+	for i := 0; i < iterations; i++ {
+		fn()
+	}
+	after := liveHeap()
+	if after > before && after-before > maxBytes {
+		t.Errorf("leaktest: heap grew by %d bytes over %d iterations, limit %d", after-before, iterations, maxBytes)
+	}
+}
+
+func liveHeap() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
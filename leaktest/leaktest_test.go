@@ -0,0 +1,126 @@
+package leaktest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeT collects errors and cleanups so failures can be asserted on
+type fakeT struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (*fakeT) Helper() {}
+
+func (f *fakeT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestCheck(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	tmp := t.TempDir()
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		leak       func()
+		wantErrors int
+	}{
+		{
+			name:       "no leak",
+			leak:       func() {},
+			wantErrors: 0,
+		},
+		{
+			name:       "goroutine finishes within timeout",
+			leak:       func() { go time.Sleep(50 * time.Millisecond) },
+			wantErrors: 0,
+		},
+		{
+			name:       "leaked goroutine",
+			opts:       []Option{Timeout(50 * time.Millisecond)},
+			leak:       func() { go func() { <-stop }() },
+			wantErrors: 1,
+		},
+		{
+			name:       "ignored goroutine",
+			opts:       []Option{Timeout(50 * time.Millisecond), IgnoreGoroutine("leaktest.TestCheck")},
+			leak:       func() { go func() { <-stop }() },
+			wantErrors: 0,
+		},
+		{
+			name: "leaked file",
+			opts: []Option{Timeout(50 * time.Millisecond)},
+			leak: func() {
+				f, _ := os.Create(filepath.Join(tmp, "leaked"))
+				t.Cleanup(func() { _ = f.Close() })
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "ignored file",
+			opts: []Option{Timeout(50 * time.Millisecond), IgnoreFD("ignored")},
+			leak: func() {
+				f, _ := os.Create(filepath.Join(tmp, "ignored"))
+				t.Cleanup(func() { _ = f.Close() })
+			},
+			wantErrors: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeT{}
+			Check(ft, tt.opts...)
+			tt.leak()
+			ft.finish()
+			if len(ft.errors) != tt.wantErrors {
+				t.Errorf("Check() got errors = %v, want %d", ft.errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestHeapGrowth(t *testing.T) {
+	var retained [][]byte
+	tests := []struct {
+		name       string
+		fn         func()
+		wantErrors int
+	}{
+		{
+			name:       "garbage only",
+			fn:         func() { _ = make([]byte, 1<<10) },
+			wantErrors: 0,
+		},
+		{
+			name:       "retained memory",
+			fn:         func() { retained = append(retained, make([]byte, 1<<10)) },
+			wantErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeT{}
+			HeapGrowth(ft, 256<<10, 1000, tt.fn)
+			if len(ft.errors) != tt.wantErrors {
+				t.Errorf("HeapGrowth() got errors = %v, want %d", ft.errors, tt.wantErrors)
+			}
+		})
+	}
+	_ = retained
+}
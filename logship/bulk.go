@@ -0,0 +1,31 @@
+package logship
+
+import (
+	"context"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+// HTTPBulk posts each batch as a JSON array of records to URL
+type HTTPBulk struct {
+	URL    string
+	Header map[string]string
+	// Client defaults to a new gohttp.Client
+	Client *gohttp.Client
+}
+
+func (b *HTTPBulk) Ship(ctx context.Context, records []Record) error {
+	client := b.Client
+	if client == nil {
+		client = gohttp.NewClient()
+	}
+	req := client.NewRequest(gohttp.POST, b.URL).WithContext(ctx).Body(records)
+	for k, v := range b.Header {
+		req.Header(k, v)
+	}
+	resp, err := req.Send()
+	if resp != nil && resp.StatusCode/100 == 2 {
+		return nil
+	}
+	return err
+}
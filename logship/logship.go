@@ -0,0 +1,422 @@
+package logship
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// ErrClosed is returned by Flush after Close
+var ErrClosed = errors.New("logship: shipper closed")
+
+// Record is one log entry
+type Record struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"msg"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// Sink delivers a batch of records, a failed batch is spooled and retried as a whole
+type Sink interface {
+	Ship(ctx context.Context, records []Record) error
+}
+
+type config struct {
+	batchSize     int
+	flushInterval time.Duration
+	bufferSize    int
+	block         bool
+	spoolDir      string
+	timeout       time.Duration
+	onError       func(err error)
+}
+
+type Option = opt.Option[config]
+
+// WithBatchSize ships once n records are buffered, 100 by default
+func WithBatchSize(n int) Option {
+	return func(c *config) {
+		c.batchSize = n
+	}
+}
+
+// WithFlushInterval ships buffered records at least every d, 1s by default
+func WithFlushInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.flushInterval = d
+	}
+}
+
+// WithBufferSize sets how many records may wait for shipping, 10000 by default
+func WithBufferSize(n int) Option {
+	return func(c *config) {
+		c.bufferSize = n
+	}
+}
+
+// WithBlockOnFull makes logging block while the buffer is full instead of
+// dropping records
+func WithBlockOnFull() Option {
+	return func(c *config) {
+		c.block = true
+	}
+}
+
+// WithSpoolDir writes batches that failed to ship to dir and ships them again,
+// oldest first, before the next batch
+func WithSpoolDir(dir string) Option {
+	return func(c *config) {
+		c.spoolDir = dir
+	}
+}
+
+// WithShipTimeout bounds a single Ship call, 10s by default
+func WithShipTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithErrorHandler is called with shipping and spooling errors
+func WithErrorHandler(fn func(err error)) Option {
+	return func(c *config) {
+		c.onError = fn
+	}
+}
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.batchSize <= 0 || c.bufferSize <= 0 || c.flushInterval <= 0 {
+			return errors.New("logship: batch size, buffer size and flush interval must be positive")
+		}
+		return nil
+	},
+}
+
+// Shipper buffers records and ships them in batches from a background
+// goroutine. It implements the Debug/Info/Warn/Error logger interface of the
+// http package, so it can be installed with http.SetLogger.
+type Shipper struct {
+	sink    Sink
+	cfg     config
+	records chan Record
+	flushes chan chan error
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	shipped int64
+	dropped int64
+	failed  int64
+}
+
+// New starts a Shipper delivering to sink, stop it with Close
+func New(sink Sink, opts ...Option) (*Shipper, error) {
+	cfg := config{
+		batchSize:     100,
+		flushInterval: time.Second,
+		bufferSize:    10000,
+		timeout:       10 * time.Second,
+	}
+	if err := opt.Build(&cfg, opts, checks...); err != nil {
+		return nil, err
+	}
+	if cfg.spoolDir != "" {
+		if err := os.MkdirAll(cfg.spoolDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	s := &Shipper{
+		sink:    sink,
+		cfg:     cfg,
+		records: make(chan Record, cfg.bufferSize),
+		flushes: make(chan chan error),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.loop()
+	return s, nil
+}
+
+// Log queues r, it is dropped when the buffer is full unless WithBlockOnFull is set
+func (s *Shipper) Log(r Record) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	select {
+	case <-s.done:
+		atomic.AddInt64(&s.dropped, 1)
+		return
+	default:
+	}
+	if s.cfg.block {
+		select {
+		case s.records <- r:
+		case <-s.done:
+			atomic.AddInt64(&s.dropped, 1)
+		}
+		return
+	}
+	select {
+	case s.records <- r:
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+func (s *Shipper) Debug(msg string, keysAndValues ...any) {
+	s.Log(newRecord("debug", msg, keysAndValues))
+}
+
+func (s *Shipper) Info(msg string, keysAndValues ...any) {
+	s.Log(newRecord("info", msg, keysAndValues))
+}
+
+func (s *Shipper) Warn(msg string, keysAndValues ...any) {
+	s.Log(newRecord("warn", msg, keysAndValues))
+}
+
+func (s *Shipper) Error(msg string, keysAndValues ...any) {
+	s.Log(newRecord("error", msg, keysAndValues))
+}
+
+func newRecord(level string, msg string, keysAndValues []any) Record {
+	r := Record{Time: time.Now(), Level: level, Message: msg}
+	if len(keysAndValues) > 0 {
+		r.Fields = make(map[string]any, len(keysAndValues)/2)
+		for i := 0; i < len(keysAndValues); i += 2 {
+			key := fmt.Sprint(keysAndValues[i])
+			if i+1 == len(keysAndValues) {
+				r.Fields[key] = "<missing>"
+				break
+			}
+			value := keysAndValues[i+1]
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			r.Fields[key] = value
+		}
+	}
+	return r
+}
+
+// Flush ships everything queued so far
+func (s *Shipper) Flush(ctx context.Context) error {
+	result := make(chan error, 1)
+	select {
+	case s.flushes <- result:
+	case <-s.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close ships the queued records and stops the background goroutine
+func (s *Shipper) Close(ctx context.Context) error {
+	s.once.Do(func() { close(s.done) })
+	select {
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats implements stats.Stats
+func (s *Shipper) Stats() map[string]float64 {
+	return map[string]float64{
+		"shipped_total":        float64(atomic.LoadInt64(&s.shipped)),
+		"dropped_total":        float64(atomic.LoadInt64(&s.dropped)),
+		"failed_batches_total": float64(atomic.LoadInt64(&s.failed)),
+		"buffered":             float64(len(s.records)),
+	}
+}
+
+func (s *Shipper) loop() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.cfg.flushInterval)
+	defer ticker.Stop()
+	batch := make([]Record, 0, s.cfg.batchSize)
+	ship := func() error {
+		err := s.ship(batch)
+		batch = make([]Record, 0, s.cfg.batchSize)
+		return err
+	}
+	drain := func() {
+		for {
+			select {
+			case r := <-s.records:
+				batch = append(batch, r)
+				if len(batch) == s.cfg.batchSize {
+					_ = ship()
+				}
+			default:
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case r := <-s.records:
+			batch = append(batch, r)
+			if len(batch) == s.cfg.batchSize {
+				_ = ship()
+			}
+		case <-ticker.C:
+			_ = ship()
+		case result := <-s.flushes:
+			drain()
+			result <- ship()
+		case <-s.done:
+			drain()
+			_ = ship()
+			return
+		}
+	}
+}
+
+// ship replays the spool, then sends batch, spooling it on failure
+func (s *Shipper) ship(batch []Record) error {
+	if err := s.replaySpool(); err != nil {
+		if len(batch) > 0 {
+			s.spool(batch, err)
+		}
+		return err
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := s.send(batch); err != nil {
+		s.spool(batch, err)
+		return err
+	}
+	return nil
+}
+
+func (s *Shipper) send(batch []Record) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.timeout)
+	defer cancel()
+	if err := s.sink.Ship(ctx, batch); err != nil {
+		atomic.AddInt64(&s.failed, 1)
+		s.report(fmt.Errorf("logship: ship %d records: %w", len(batch), err))
+		return err
+	}
+	atomic.AddInt64(&s.shipped, int64(len(batch)))
+	return nil
+}
+
+func (s *Shipper) report(err error) {
+	if s.cfg.onError != nil {
+		s.cfg.onError(err)
+	}
+}
+
+// spool writes batch as JSON lines to a new file, without a spool dir the batch is dropped
+func (s *Shipper) spool(batch []Record, cause error) {
+	if s.cfg.spoolDir == "" {
+		atomic.AddInt64(&s.dropped, int64(len(batch)))
+		return
+	}
+	name := filepath.Join(s.cfg.spoolDir, fmt.Sprintf("spool-%020d.ndjson", time.Now().UnixNano()))
+	file, err := os.Create(name)
+	if err == nil {
+		encoder := json.NewEncoder(file)
+		for _, r := range batch {
+			if err = encoder.Encode(r); err != nil {
+				break
+			}
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		atomic.AddInt64(&s.dropped, int64(len(batch)))
+		s.report(fmt.Errorf("logship: spool after %v: %w", cause, err))
+	}
+}
+
+// replaySpool ships spooled batches oldest first and stops at the first failure
+func (s *Shipper) replaySpool() error {
+	if s.cfg.spoolDir == "" {
+		return nil
+	}
+	names, err := filepath.Glob(filepath.Join(s.cfg.spoolDir, "spool-*.ndjson"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		batch, err := readSpool(name)
+		if err != nil {
+			s.report(fmt.Errorf("logship: corrupt spool file %s dropped: %w", name, err))
+			_ = os.Remove(name)
+			continue
+		}
+		if err := s.send(batch); err != nil {
+			return err
+		}
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSpool(name string) ([]Record, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var batch []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, err
+		}
+		batch = append(batch, r)
+	}
+	return batch, scanner.Err()
+}
+
+// formatFields renders fields as sorted key=value pairs, values with spaces are quoted
+func formatFields(fields map[string]any, skip map[string]bool) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if !skip[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := fmt.Sprint(fields[k])
+		if strings.ContainsAny(v, " \"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, " ")
+}
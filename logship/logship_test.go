@@ -0,0 +1,282 @@
+package logship
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+// memorySink records batches and fails while failing is set
+type memorySink struct {
+	mu      sync.Mutex
+	batches [][]Record
+	failing bool
+	block   chan struct{}
+}
+
+func (m *memorySink) Ship(ctx context.Context, records []Record) error {
+	if m.block != nil {
+		<-m.block
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failing {
+		return errors.New("sink down")
+	}
+	m.batches = append(m.batches, append([]Record(nil), records...))
+	return nil
+}
+
+func (m *memorySink) messages() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []string
+	for _, batch := range m.batches {
+		for _, r := range batch {
+			out = append(out, r.Message)
+		}
+	}
+	return out
+}
+
+func TestShipper(t *testing.T) {
+	sink := &memorySink{}
+	s, err := New(sink, WithBatchSize(2), WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	s.Info("a", "user", "u1", "err", errors.New("boom"))
+	s.Warn("b")
+	s.Error("c", "dangling")
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := strings.Join(sink.messages(), ","); got != "a,b,c" {
+		t.Errorf("shipped got = %v, want a,b,c", got)
+	}
+	if len(sink.batches) != 2 || len(sink.batches[0]) != 2 {
+		t.Errorf("batches got = %v, want a full batch and a flushed one", sink.batches)
+	}
+	first := sink.batches[0][0]
+	if first.Level != "info" || first.Fields["user"] != "u1" || first.Fields["err"] != "boom" {
+		t.Errorf("record got = %+v", first)
+	}
+	if sink.batches[1][0].Fields["dangling"] != "<missing>" {
+		t.Errorf("dangling key got = %+v", sink.batches[1][0])
+	}
+	if err := s.Close(context.Background()); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := s.Flush(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Flush() after Close error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestShipperSpool(t *testing.T) {
+	dir := t.TempDir()
+	sink := &memorySink{failing: true}
+	var reported []error
+	s, err := New(sink, WithSpoolDir(dir), WithFlushInterval(time.Hour), WithErrorHandler(func(err error) {
+		reported = append(reported, err)
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Close(context.Background())
+
+	s.Info("first")
+	if err := s.Flush(context.Background()); err == nil {
+		t.Fatalf("Flush() error = nil, want sink error")
+	}
+	s.Info("second")
+	_ = s.Flush(context.Background())
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.ndjson")); len(files) != 2 {
+		t.Fatalf("spool files got = %v, want 2", files)
+	}
+
+	sink.mu.Lock()
+	sink.failing = false
+	sink.mu.Unlock()
+	s.Info("third")
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := strings.Join(sink.messages(), ","); got != "first,second,third" {
+		t.Errorf("shipped got = %v, want spooled batches first", got)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.ndjson")); len(files) != 0 {
+		t.Errorf("spool files got = %v, want none", files)
+	}
+	if len(reported) == 0 {
+		t.Errorf("error handler was not called")
+	}
+}
+
+func TestShipperBackpressure(t *testing.T) {
+	sink := &memorySink{block: make(chan struct{})}
+	s, err := New(sink, WithBatchSize(1), WithBufferSize(1), WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	// one record is stuck in the sink, one fills the buffer, the rest is dropped
+	for i := 0; i < 5; i++ {
+		s.Info("x")
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := s.Stats()["dropped_total"]; got < 2 {
+		t.Errorf("dropped_total got = %v, want at least 2", got)
+	}
+	close(sink.block)
+	_ = s.Close(context.Background())
+
+	sink = &memorySink{block: make(chan struct{})}
+	s, _ = New(sink, WithBatchSize(1), WithBufferSize(1), WithFlushInterval(time.Hour), WithBlockOnFull())
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 4; i++ {
+			s.Info("y")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Errorf("Log() with WithBlockOnFull did not block")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(sink.block)
+	<-done
+	_ = s.Close(context.Background())
+	if got := len(sink.messages()); got != 4 {
+		t.Errorf("shipped got = %v, want 4", got)
+	}
+}
+
+func TestLoki(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	loki := &Loki{URL: server.URL, Labels: map[string]string{"app": "api"}, LabelKeys: []string{"tenant"}}
+	ts := time.Unix(0, 1700000000000000000)
+	err := loki.Ship(context.Background(), []Record{
+		{Time: ts, Level: "info", Message: "hello", Fields: map[string]any{"tenant": "t1", "user": "bob smith"}},
+		{Time: ts, Level: "info", Message: "again", Fields: map[string]any{"tenant": "t1"}},
+		{Time: ts, Level: "error", Message: "failed"},
+	})
+	if err != nil {
+		t.Fatalf("Ship() error = %v", err)
+	}
+	var payload struct {
+		Streams []struct {
+			Stream map[string]string
+			Values [][2]string
+		}
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("payload %s: %v", body, err)
+	}
+	if len(payload.Streams) != 2 {
+		t.Fatalf("streams got = %+v, want 2", payload.Streams)
+	}
+	first := payload.Streams[0]
+	if first.Stream["tenant"] != "t1" || first.Stream["app"] != "api" || first.Stream["level"] != "info" || len(first.Values) != 2 {
+		t.Errorf("stream got = %+v", first)
+	}
+	if want := [2]string{"1700000000000000000", `hello user="bob smith"`}; first.Values[0] != want {
+		t.Errorf("value got = %v, want %v", first.Values[0], want)
+	}
+}
+
+func TestHTTPBulk(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var records []Record
+		if err := json.NewDecoder(r.Body).Decode(&records); err != nil || len(records) != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	bulk := &HTTPBulk{URL: server.URL, Client: gohttp.NewClient(gohttp.WithLogger(gohttp.NopLogger))}
+	if err := bulk.Ship(context.Background(), []Record{{Message: "a"}, {Message: "b"}}); err != nil {
+		t.Errorf("Ship() error = %v", err)
+	}
+	if err := bulk.Ship(context.Background(), []Record{{Message: "a"}}); err == nil {
+		t.Errorf("Ship() error = nil, want the 400")
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := make(chan string, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			count, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(count))
+			if err != nil {
+				return
+			}
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(reader, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	sink := &Syslog{Network: "tcp", Addr: listener.Addr().String(), AppName: "api", Facility: FacilityLocal0}
+	defer sink.Close()
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+	err = sink.Ship(context.Background(), []Record{
+		{Time: ts, Level: "error", Message: "disk full", Fields: map[string]any{"path": `/var/"x"]`, "free": 0}},
+		{Time: ts, Level: "info", Message: "ok"},
+	})
+	if err != nil {
+		t.Fatalf("Ship() error = %v", err)
+	}
+	hostname, _ := os.Hostname()
+	tests := []*regexp.Regexp{
+		regexp.MustCompile(`^<131>1 2024-01-02T03:04:05\.000006Z ` + regexp.QuoteMeta(hostname) + ` api \d+ - \[fields@32473 free="0" path="/var/\\"x\\"\\]"\] disk full$`),
+		regexp.MustCompile(`^<134>1 2024-01-02T03:04:05\.000006Z \S+ api \d+ - - ok$`),
+	}
+	for _, want := range tests {
+		select {
+		case got := <-received:
+			if !want.MatchString(got) {
+				t.Errorf("syslog message got = %q, want %v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("syslog message not received")
+		}
+	}
+}
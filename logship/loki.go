@@ -0,0 +1,94 @@
+package logship
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+// Loki ships to the Loki push API (/loki/api/v1/push). Every stream carries
+// the level label, the static labels and the record fields named in
+// LabelKeys; the other fields are appended to the line as key=value pairs.
+type Loki struct {
+	URL       string
+	Labels    map[string]string
+	LabelKeys []string
+	Header    map[string]string
+	// Client defaults to a new gohttp.Client
+	Client *gohttp.Client
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (l *Loki) Ship(ctx context.Context, records []Record) error {
+	labelKeys := make(map[string]bool, len(l.LabelKeys))
+	for _, k := range l.LabelKeys {
+		labelKeys[k] = true
+	}
+	streams := make(map[string]*lokiStream)
+	var order []string
+	for _, r := range records {
+		labels := map[string]string{"level": r.Level}
+		for k, v := range l.Labels {
+			labels[k] = v
+		}
+		for k := range labelKeys {
+			if v, ok := r.Fields[k]; ok {
+				labels[k] = fmt.Sprint(v)
+			}
+		}
+		key := labelSetKey(labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			order = append(order, key)
+		}
+		line := r.Message
+		if fields := formatFields(r.Fields, labelKeys); fields != "" {
+			line += " " + fields
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(r.Time.UnixNano(), 10), line})
+	}
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range order {
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+
+	client := l.Client
+	if client == nil {
+		client = gohttp.NewClient()
+	}
+	req := client.NewRequest(gohttp.POST, l.URL).WithContext(ctx).Body(payload)
+	for k, v := range l.Header {
+		req.Header(k, v)
+	}
+	resp, err := req.Send()
+	// loki answers 204 No Content
+	if resp != nil && resp.StatusCode/100 == 2 {
+		return nil
+	}
+	return err
+}
+
+func labelSetKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + strconv.Quote(labels[k]) + ",")
+	}
+	return b.String()
+}
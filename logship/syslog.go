@@ -0,0 +1,166 @@
+package logship
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog facilities, the facility is combined with the record severity
+const (
+	FacilityUser   = 1
+	FacilityDaemon = 3
+	FacilityLocal0 = 16
+)
+
+const rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+
+// Syslog ships RFC 5424 messages over "udp", "tcp" or "tls". TCP and TLS use
+// octet-counting framing (RFC 6587), UDP sends one message per datagram.
+// Record fields become structured data under the SD-ID fields@32473.
+type Syslog struct {
+	Network  string
+	Addr     string
+	AppName  string
+	Facility int
+	// TLSConfig is used for the "tls" network
+	TLSConfig *tls.Config
+	// DialTimeout defaults to 5s
+	DialTimeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (s *Syslog) Ship(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	}
+	for _, r := range records {
+		msg := s.format(r)
+		if s.Network != "udp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			// redial on the next batch
+			_ = s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *Syslog) dial(ctx context.Context) (net.Conn, error) {
+	timeout := s.DialTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	switch s.Network {
+	case "tls":
+		return (&tls.Dialer{NetDialer: dialer, Config: s.TLSConfig}).DialContext(ctx, "tcp", s.Addr)
+	case "udp", "tcp":
+		return dialer.DialContext(ctx, s.Network, s.Addr)
+	}
+	return nil, fmt.Errorf("logship: unsupported syslog network %q", s.Network)
+}
+
+// format renders <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func (s *Syslog) format(r Record) string {
+	facility := s.Facility
+	if facility == 0 {
+		facility = FacilityUser
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	appName := s.AppName
+	if appName == "" {
+		appName = "-"
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %d - %s %s",
+		facility*8+severity(r.Level),
+		r.Time.Format(rfc5424Time),
+		hostname,
+		appName,
+		os.Getpid(),
+		structuredData(r.Fields),
+		r.Message,
+	)
+}
+
+func severity(level string) int {
+	switch strings.ToLower(level) {
+	case "debug":
+		return 7
+	case "warn", "warning":
+		return 4
+	case "error":
+		return 3
+	}
+	return 6
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func structuredData(fields map[string]any) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("[fields@32473")
+	for _, k := range keys {
+		b.WriteString(" " + sdName(k) + `="` + sdEscaper.Replace(fmt.Sprint(fields[k])) + `"`)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// sdName drops the characters not allowed in a PARAM-NAME
+func sdName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r >= 127 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
@@ -0,0 +1,390 @@
+package mailrecv
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IMAP is a connection to an IMAP4rev1 server, it is not safe for concurrent use.
+// Messages are addressed by uid within the selected mailbox.
+type IMAP struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	tag     int
+	caps    map[string]bool
+}
+
+// response is an untagged server line with the literals it carried
+type response struct {
+	text     string
+	literals [][]byte
+}
+
+var (
+	literalPattern  = regexp.MustCompile(`\{(\d+)\}$`)
+	fetchUIDPattern = regexp.MustCompile(`\bUID (\d+)`)
+)
+
+// DialIMAP connects to addr and reads the greeting and capabilities
+func DialIMAP(ctx context.Context, addr string, opts ...Option) (*IMAP, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := cfg.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &IMAP{conn: conn, r: bufio.NewReader(conn), timeout: cfg.timeout}
+	_ = conn.SetDeadline(time.Now().Add(c.timeout))
+	greeting, err := c.readResponse()
+	if err == nil && !strings.HasPrefix(greeting.text, "* OK") && !strings.HasPrefix(greeting.text, "* PREAUTH") {
+		err = fmt.Errorf("%w: %s", ErrRejected, greeting.text)
+	}
+	if err == nil && cfg.startTLS {
+		if _, err = c.cmd("STARTTLS"); err == nil {
+			tlsConn := tls.Client(conn, cfg.tlsConfigFor(addr))
+			c.conn, c.r = tlsConn, bufio.NewReader(tlsConn)
+		}
+	}
+	if err == nil {
+		err = c.refreshCapabilities()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Capability reports whether the server announced name, e.g. "IDLE" or "MOVE"
+func (c *IMAP) Capability(name string) bool {
+	return c.caps[strings.ToUpper(name)]
+}
+
+// Login authenticates with LOGIN
+func (c *IMAP) Login(user string, password string) error {
+	if _, err := c.cmd("LOGIN %s %s", quote(user), quote(password)); err != nil {
+		return err
+	}
+	// servers may announce more after login
+	return c.refreshCapabilities()
+}
+
+// Select opens mailbox, e.g. "INBOX", and returns its message count
+func (c *IMAP) Select(mailbox string) (int, error) {
+	responses, err := c.cmd("SELECT %s", quote(mailbox))
+	if err != nil {
+		return 0, err
+	}
+	for _, resp := range responses {
+		if fields := strings.Fields(resp.text); len(fields) == 3 && fields[2] == "EXISTS" {
+			return strconv.Atoi(fields[1])
+		}
+	}
+	return 0, nil
+}
+
+// Search returns the uids matching the IMAP search criteria, e.g. "UNSEEN" or
+// `FROM "alice@example.com" SINCE 1-Feb-2024`
+func (c *IMAP) Search(criteria string) ([]uint32, error) {
+	responses, err := c.cmd("UID SEARCH %s", criteria)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, resp := range responses {
+		rest, ok := cutPrefix(resp.text, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(rest) {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("mailrecv: bad SEARCH answer %q", resp.text)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	return uids, nil
+}
+
+// Fetch downloads and parses the messages without marking them seen
+func (c *IMAP) Fetch(uids ...uint32) ([]*Message, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	responses, err := c.cmd("UID FETCH %s (UID BODY.PEEK[])", uidSet(uids))
+	if err != nil {
+		return nil, err
+	}
+	var msgs []*Message
+	for _, resp := range responses {
+		if !strings.Contains(resp.text, " FETCH ") || len(resp.literals) == 0 {
+			continue
+		}
+		msg, err := Parse(resp.literals[0])
+		if err != nil {
+			return nil, err
+		}
+		if match := fetchUIDPattern.FindStringSubmatch(resp.text); match != nil {
+			uid, _ := strconv.ParseUint(match[1], 10, 32)
+			msg.UID = uint32(uid)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// AddFlags sets flags like `\Seen` or `\Flagged` on a message
+func (c *IMAP) AddFlags(uid uint32, flags ...string) error {
+	_, err := c.cmd("UID STORE %d +FLAGS.SILENT (%s)", uid, strings.Join(flags, " "))
+	return err
+}
+
+// RemoveFlags clears flags of a message
+func (c *IMAP) RemoveFlags(uid uint32, flags ...string) error {
+	_, err := c.cmd("UID STORE %d -FLAGS.SILENT (%s)", uid, strings.Join(flags, " "))
+	return err
+}
+
+// MarkSeen sets the \Seen flag
+func (c *IMAP) MarkSeen(uid uint32) error {
+	return c.AddFlags(uid, `\Seen`)
+}
+
+// Move moves a message to mailbox, with MOVE when the server supports it and
+// COPY and Delete otherwise
+func (c *IMAP) Move(uid uint32, mailbox string) error {
+	if c.Capability("MOVE") {
+		_, err := c.cmd("UID MOVE %d %s", uid, quote(mailbox))
+		return err
+	}
+	if _, err := c.cmd("UID COPY %d %s", uid, quote(mailbox)); err != nil {
+		return err
+	}
+	return c.Delete(uid)
+}
+
+// Delete flags a message \Deleted and expunges it. Without UIDPLUS all
+// messages flagged \Deleted in the mailbox are expunged.
+func (c *IMAP) Delete(uid uint32) error {
+	if err := c.AddFlags(uid, `\Deleted`); err != nil {
+		return err
+	}
+	if c.Capability("UIDPLUS") {
+		_, err := c.cmd("UID EXPUNGE %d", uid)
+		return err
+	}
+	_, err := c.cmd("EXPUNGE")
+	return err
+}
+
+// Idle waits with IDLE until the selected mailbox gets new messages, for at
+// most maxWait. It reports whether new messages arrived.
+func (c *IMAP) Idle(ctx context.Context, maxWait time.Duration) (bool, error) {
+	if !c.Capability("IDLE") {
+		return false, errors.New("mailrecv: server does not support IDLE")
+	}
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := fmt.Fprintf(c.conn, "%s IDLE\r\n", tag); err != nil {
+		return false, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return false, err
+	}
+	if !strings.HasPrefix(resp.text, "+") {
+		return false, fmt.Errorf("%w: IDLE: %s", ErrRejected, resp.text)
+	}
+
+	// a cancelled ctx interrupts the read through the deadline
+	_ = c.conn.SetDeadline(time.Now().Add(maxWait))
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			_ = c.conn.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	arrived := false
+	for !arrived {
+		resp, err = c.readResponse()
+		if err != nil {
+			break
+		}
+		fields := strings.Fields(resp.text)
+		arrived = len(fields) == 3 && (fields[2] == "EXISTS" || fields[2] == "RECENT")
+	}
+	close(stop)
+	<-stopped
+	var netErr net.Error
+	if err != nil && !(errors.As(err, &netErr) && netErr.Timeout()) {
+		return false, err
+	}
+
+	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := io.WriteString(c.conn, "DONE\r\n"); err != nil {
+		return false, err
+	}
+	if _, err := c.readTagged(tag, "IDLE"); err != nil {
+		return false, err
+	}
+	return arrived, ctx.Err()
+}
+
+// Watch delivers the unseen messages of mailbox to handler and marks them
+// seen once handler returns nil, a failed message is delivered again on the
+// next round. New mail is awaited with IDLE when the server supports it,
+// otherwise the mailbox is polled every interval. Watch returns when ctx is done.
+func (c *IMAP) Watch(ctx context.Context, mailbox string, interval time.Duration, handler func(msg *Message) error) error {
+	if _, err := c.Select(mailbox); err != nil {
+		return err
+	}
+	for {
+		uids, err := c.Search("UNSEEN")
+		if err != nil {
+			return err
+		}
+		for _, uid := range uids {
+			msgs, err := c.Fetch(uid)
+			if err != nil {
+				return err
+			}
+			for _, msg := range msgs {
+				if handler(msg) == nil {
+					if err := c.MarkSeen(msg.UID); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if c.Capability("IDLE") {
+			if _, err := c.Idle(ctx, interval); err != nil {
+				return err
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		// NOOP lets the server report new messages
+		if _, err := c.cmd("NOOP"); err != nil {
+			return err
+		}
+	}
+}
+
+// Logout ends the session and closes the connection
+func (c *IMAP) Logout() error {
+	_, err := c.cmd("LOGOUT")
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (c *IMAP) refreshCapabilities() error {
+	responses, err := c.cmd("CAPABILITY")
+	if err != nil {
+		return err
+	}
+	c.caps = make(map[string]bool)
+	for _, resp := range responses {
+		if rest, ok := cutPrefix(resp.text, "* CAPABILITY"); ok {
+			for _, name := range strings.Fields(rest) {
+				c.caps[strings.ToUpper(name)] = true
+			}
+		}
+	}
+	return nil
+}
+
+// cmd sends a tagged command and returns the untagged responses before its completion
+func (c *IMAP) cmd(format string, args ...any) ([]response, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	command := fmt.Sprintf(format, args...)
+	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := io.WriteString(c.conn, tag+" "+command+"\r\n"); err != nil {
+		return nil, err
+	}
+	verb, _, _ := strings.Cut(command, " ")
+	if verb == "UID" {
+		verb = strings.Join(strings.Fields(command)[:2], " ")
+	}
+	return c.readTagged(tag, verb)
+}
+
+func (c *IMAP) readTagged(tag string, verb string) ([]response, error) {
+	var untagged []response
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		rest, ok := cutPrefix(resp.text, tag+" ")
+		if !ok {
+			untagged = append(untagged, resp)
+			continue
+		}
+		if strings.HasPrefix(rest, "OK") {
+			return untagged, nil
+		}
+		return nil, fmt.Errorf("%w: %s: %s", ErrRejected, verb, rest)
+	}
+}
+
+// readResponse reads a line, following {n} literals into the next lines
+func (c *IMAP) readResponse() (response, error) {
+	var resp response
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		resp.text += line
+		match := literalPattern.FindStringSubmatch(line)
+		if match == nil {
+			return resp, nil
+		}
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n > 256<<20 {
+			return resp, fmt.Errorf("mailrecv: bad literal size %q", match[1])
+		}
+		literal := make([]byte, n)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, literal)
+	}
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func uidSet(uids []uint32) string {
+	parts := make([]string, len(uids))
+	for i, uid := range uids {
+		parts[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+	return strings.Join(parts, ",")
+}
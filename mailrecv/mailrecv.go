@@ -0,0 +1,76 @@
+// Package mailrecv reads mail from IMAP and POP3 servers and parses it into
+// text, HTML and attachments.
+package mailrecv
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// ErrRejected is returned when the server answers a command with NO, BAD or -ERR
+var ErrRejected = errors.New("mailrecv: command rejected")
+
+type config struct {
+	tlsConfig *tls.Config
+	startTLS  bool
+	timeout   time.Duration
+}
+
+type Option = opt.Option[config]
+
+// WithTLS connects with TLS from the start, like on ports 993 and 995
+func WithTLS(cfg *tls.Config) Option {
+	return func(c *config) {
+		c.tlsConfig = cfg
+		c.startTLS = false
+	}
+}
+
+// WithStartTLS upgrades a plain connection to TLS after the greeting
+func WithStartTLS(cfg *tls.Config) Option {
+	return func(c *config) {
+		c.tlsConfig = cfg
+		c.startTLS = true
+	}
+}
+
+// WithTimeout bounds every command, 30s by default
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+func newConfig(opts []Option) (config, error) {
+	c := config{timeout: 30 * time.Second}
+	err := opt.Build(&c, opts, func(c *config) error {
+		if c.timeout <= 0 {
+			return errors.New("mailrecv: timeout must be positive")
+		}
+		return nil
+	})
+	return c, err
+}
+
+func (c *config) dial(ctx context.Context, addr string) (net.Conn, error) {
+	if c.tlsConfig != nil && !c.startTLS {
+		dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: c.timeout}, Config: c.tlsConfigFor(addr)}
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	dialer := &net.Dialer{Timeout: c.timeout}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// tlsConfigFor fills in the server name from addr
+func (c *config) tlsConfigFor(addr string) *tls.Config {
+	cfg := c.tlsConfig.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	return cfg
+}
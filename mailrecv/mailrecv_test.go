@@ -0,0 +1,245 @@
+package mailrecv
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const multipartMail = "From: =?UTF-8?Q?J=C3=B6rg?= <jorg@example.com>\r\n" +
+	"To: alice@example.com, bob@example.com\r\n" +
+	"Subject: =?UTF-8?B?UmVwb3J0IMOc?=\r\n" +
+	"Date: Mon, 02 Jan 2006 15:04:05 +0000\r\n" +
+	"Message-ID: <1@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Gr=FC=DFe\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Hi</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"report.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"report.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0x\r\n" +
+	"LjQ=\r\n" +
+	"--outer--\r\n"
+
+const plainMail = "From: carol@example.com\r\nSubject: ping\r\n\r\nhello\r\n"
+
+func TestParse(t *testing.T) {
+	msg, err := Parse([]byte(multipartMail))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if msg.Subject != "Report Ü" || msg.From[0].Name != "Jörg" || len(msg.To) != 2 || msg.MessageID != "<1@example.com>" {
+		t.Errorf("Parse() header got = %q %v %v %v", msg.Subject, msg.From, msg.To, msg.MessageID)
+	}
+	if msg.Date.Year() != 2006 {
+		t.Errorf("Parse() date got = %v", msg.Date)
+	}
+	if msg.Text != "Grüße" || msg.HTML != "<p>Hi</p>" {
+		t.Errorf("Parse() bodies got = %q %q", msg.Text, msg.HTML)
+	}
+	want := []Attachment{{Filename: "report.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")}}
+	if !reflect.DeepEqual(msg.Attachments, want) {
+		t.Errorf("Parse() attachments got = %+v, want %+v", msg.Attachments, want)
+	}
+
+	msg, err = Parse([]byte(plainMail))
+	if err != nil || msg.Text != "hello\r\n" || msg.Subject != "ping" || msg.To != nil {
+		t.Errorf("Parse() plain got = %+v %v", msg, err)
+	}
+}
+
+// serve runs script against the first connection to a local listener
+func serve(t *testing.T, script func(r *bufio.Reader, w net.Conn)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		script(bufio.NewReader(conn), conn)
+	}()
+	return listener.Addr().String()
+}
+
+// imapServer answers commands by their text after the tag, {tag} in a reply is replaced
+func imapServer(t *testing.T, replies map[string]string) string {
+	return serve(t, func(r *bufio.Reader, w net.Conn) {
+		fmt.Fprint(w, "* OK ready\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			tag, command, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+			reply, ok := replies[command]
+			if !ok {
+				fmt.Fprintf(w, "%s BAD unknown command\r\n", tag)
+				continue
+			}
+			fmt.Fprint(w, strings.ReplaceAll(reply, "{tag}", tag))
+			if command == "IDLE" {
+				// wait for DONE
+				_, _ = r.ReadString('\n')
+				fmt.Fprintf(w, "%s OK IDLE done\r\n", tag)
+			}
+		}
+	})
+}
+
+func TestIMAP(t *testing.T) {
+	body := plainMail
+	addr := imapServer(t, map[string]string{
+		"CAPABILITY":                           "* CAPABILITY IMAP4rev1 IDLE UIDPLUS\r\n{tag} OK done\r\n",
+		`LOGIN "me" "se\"cret"`:                "{tag} OK logged in\r\n",
+		`SELECT "INBOX"`:                       "* 3 EXISTS\r\n* FLAGS (\\Seen)\r\n{tag} OK [READ-WRITE] selected\r\n",
+		"UID SEARCH UNSEEN":                    "* SEARCH 7 9\r\n{tag} OK done\r\n",
+		"UID FETCH 7 (UID BODY.PEEK[])":        fmt.Sprintf("* 2 FETCH (UID 7 BODY[] {%d}\r\n%s)\r\n{tag} OK done\r\n", len(body), body),
+		`UID STORE 7 +FLAGS.SILENT (\Seen)`:    "{tag} OK done\r\n",
+		`UID STORE 9 +FLAGS.SILENT (\Deleted)`: "{tag} OK done\r\n",
+		`UID COPY 9 "Archive"`:                 "{tag} OK done\r\n",
+		"UID EXPUNGE 9":                        "{tag} OK done\r\n",
+		"IDLE":                                 "+ idling\r\n* 4 EXISTS\r\n",
+		"LOGOUT":                               "* BYE\r\n{tag} OK bye\r\n",
+	})
+	c, err := DialIMAP(context.Background(), addr, WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("DialIMAP() error = %v", err)
+	}
+	if err := c.Login("me", `se"cret`); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if n, err := c.Select("INBOX"); n != 3 || err != nil {
+		t.Errorf("Select() got = %v %v, want 3", n, err)
+	}
+	uids, err := c.Search("UNSEEN")
+	if !reflect.DeepEqual(uids, []uint32{7, 9}) || err != nil {
+		t.Errorf("Search() got = %v %v, want [7 9]", uids, err)
+	}
+	msgs, err := c.Fetch(7)
+	if err != nil || len(msgs) != 1 || msgs[0].UID != 7 || msgs[0].Subject != "ping" {
+		t.Fatalf("Fetch() got = %+v %v", msgs, err)
+	}
+	if err := c.MarkSeen(7); err != nil {
+		t.Errorf("MarkSeen() error = %v", err)
+	}
+	if err := c.Move(9, "Archive"); err != nil {
+		t.Errorf("Move() error = %v", err)
+	}
+	if arrived, err := c.Idle(context.Background(), time.Second); !arrived || err != nil {
+		t.Errorf("Idle() got = %v %v, want new mail", arrived, err)
+	}
+	if _, err := c.Search("FROM x"); !errors.Is(err, ErrRejected) {
+		t.Errorf("Search() error got = %v, want %v", err, ErrRejected)
+	}
+	if err := c.Logout(); err != nil {
+		t.Errorf("Logout() error = %v", err)
+	}
+}
+
+func TestIMAPWatch(t *testing.T) {
+	body := plainMail
+	addr := imapServer(t, map[string]string{
+		"CAPABILITY":                        "* CAPABILITY IMAP4rev1\r\n{tag} OK done\r\n",
+		`SELECT "INBOX"`:                    "* 1 EXISTS\r\n{tag} OK selected\r\n",
+		"UID SEARCH UNSEEN":                 "* SEARCH 3\r\n{tag} OK done\r\n",
+		"UID FETCH 3 (UID BODY.PEEK[])":     fmt.Sprintf("* 1 FETCH (UID 3 BODY[] {%d}\r\n%s)\r\n{tag} OK done\r\n", len(body), body),
+		`UID STORE 3 +FLAGS.SILENT (\Seen)`: "{tag} OK done\r\n",
+		"NOOP":                              "{tag} OK done\r\n",
+	})
+	c, err := DialIMAP(context.Background(), addr)
+	if err != nil {
+		t.Fatalf("DialIMAP() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []string
+	err = c.Watch(ctx, "INBOX", 10*time.Millisecond, func(msg *Message) error {
+		got = append(got, msg.Subject)
+		if len(got) == 2 {
+			cancel()
+			return nil
+		}
+		return errors.New("not yet")
+	})
+	if !errors.Is(err, context.Canceled) || !reflect.DeepEqual(got, []string{"ping", "ping"}) {
+		t.Errorf("Watch() got = %v %v, want the failed message again", got, err)
+	}
+}
+
+func TestPOP3(t *testing.T) {
+	addr := serve(t, func(r *bufio.Reader, w net.Conn) {
+		fmt.Fprint(w, "+OK POP3 ready\r\n")
+		replies := map[string]string{
+			"USER me":   "+OK\r\n",
+			"PASS pass": "+OK logged in\r\n",
+			"STAT":      "+OK 2 320\r\n",
+			"LIST":      "+OK\r\n1 120\r\n2 200\r\n.\r\n",
+			"UIDL":      "+OK\r\n1 abc\r\n2 def\r\n.\r\n",
+			"RETR 1":    "+OK\r\nSubject: dots\r\n\r\n..leading dot\r\n.\r\n",
+			"DELE 1":    "+OK\r\n",
+			"DELE 5":    "-ERR no such message\r\n",
+			"QUIT":      "+OK bye\r\n",
+		}
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fmt.Fprint(w, replies[strings.TrimRight(line, "\r\n")])
+		}
+	})
+	c, err := DialPOP3(context.Background(), addr)
+	if err != nil {
+		t.Fatalf("DialPOP3() error = %v", err)
+	}
+	if err := c.Login("me", "pass"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if count, size, err := c.Stat(); count != 2 || size != 320 || err != nil {
+		t.Errorf("Stat() got = %v %v %v, want 2 320", count, size, err)
+	}
+	infos, err := c.List()
+	want := []MessageInfo{{Number: 1, Size: 120, UID: "abc"}, {Number: 2, Size: 200, UID: "def"}}
+	if !reflect.DeepEqual(infos, want) || err != nil {
+		t.Errorf("List() got = %v %v, want %v", infos, err, want)
+	}
+	msg, err := c.Retrieve(1)
+	if err != nil || msg.Subject != "dots" || msg.Text != ".leading dot\n" {
+		t.Errorf("Retrieve() got = %+v %v", msg, err)
+	}
+	if err := c.Delete(1); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := c.Delete(5); !errors.Is(err, ErrRejected) {
+		t.Errorf("Delete() error got = %v, want %v", err, ErrRejected)
+	}
+	if err := c.Quit(); err != nil {
+		t.Errorf("Quit() error = %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package mailrecv
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Message is a parsed mail. UID is the IMAP uid, or the POP3 message number.
+type Message struct {
+	UID         uint32
+	Header      mail.Header
+	From        []*mail.Address
+	To          []*mail.Address
+	Cc          []*mail.Address
+	Subject     string
+	Date        time.Time
+	MessageID   string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+	// Raw is the message as received
+	Raw []byte
+}
+
+// Attachment is a part with a file name, an attachment disposition or a non
+// text content type, like inline images
+type Attachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Inline      bool
+	Data        []byte
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// Parse reads a RFC 5322 message and walks its MIME parts: the first
+// text/plain and text/html parts become Text and HTML, parts with a file
+// name or an attachment disposition become Attachments.
+func Parse(raw []byte) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	msg := &Message{Header: m.Header, Raw: raw, MessageID: m.Header.Get("Message-Id")}
+	msg.Subject = decodeHeader(m.Header.Get("Subject"))
+	msg.Date, _ = m.Header.Date()
+	msg.From = addressList(m.Header, "From")
+	msg.To = addressList(m.Header, "To")
+	msg.Cc = addressList(m.Header, "Cc")
+	if err := msg.walk(m.Header, m.Body, 0); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// partHeader is satisfied by the headers of messages and multipart parts
+type partHeader interface {
+	Get(key string) string
+}
+
+func (m *Message) walk(header partHeader, body io.Reader, depth int) error {
+	if depth > 20 {
+		return fmt.Errorf("mailrecv: mime parts nested too deep")
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", nil
+	}
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	disposition, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	contentID := strings.Trim(header.Get("Content-Id"), "<>")
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	if disposition == "attachment" || filename != "" || !isText {
+		m.Attachments = append(m.Attachments, Attachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			ContentID:   contentID,
+			Inline:      disposition == "inline",
+			Data:        data,
+		})
+		return nil
+	}
+	text := decodeCharset(params["charset"], data)
+	switch {
+	case mediaType == "text/html" && m.HTML == "":
+		m.HTML = text
+	case mediaType != "text/html" && m.Text == "":
+		m.Text = text
+	}
+	return nil
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &lineSkipper{r: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// lineSkipper drops the line breaks of base64 bodies
+type lineSkipper struct {
+	r io.Reader
+}
+
+func (l *lineSkipper) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	out := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+			p[out] = b
+			out++
+		}
+	}
+	if out == 0 && n > 0 && err == nil {
+		return l.Read(p)
+	}
+	return out, err
+}
+
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+func addressList(header mail.Header, key string) []*mail.Address {
+	if header.Get(key) == "" {
+		return nil
+	}
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	list, err := parser.ParseList(header.Get(key))
+	if err != nil {
+		return nil
+	}
+	return list
+}
+
+// charsetReader supports UTF-8, US-ASCII and ISO-8859-1, other charsets are
+// passed through unchanged
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decodeCharset(charset, data)), nil
+}
+
+func decodeCharset(charset string, data []byte) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return string(data)
+}
@@ -0,0 +1,178 @@
+package mailrecv
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// POP3 is a connection to a POP3 server, it is not safe for concurrent use
+type POP3 struct {
+	conn    net.Conn
+	text    *textproto.Conn
+	timeout time.Duration
+}
+
+// MessageInfo is an entry of the POP3 maildrop listing
+type MessageInfo struct {
+	Number int
+	Size   int
+	// UID is the unique id of UIDL, empty when the server does not support it
+	UID string
+}
+
+// DialPOP3 connects to addr and reads the greeting
+func DialPOP3(ctx context.Context, addr string, opts ...Option) (*POP3, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := cfg.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &POP3{conn: conn, text: textproto.NewConn(conn), timeout: cfg.timeout}
+	if _, err := c.readStatus(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if cfg.startTLS {
+		if _, err := c.cmd("STLS"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, cfg.tlsConfigFor(addr))
+		c.conn, c.text = tlsConn, textproto.NewConn(tlsConn)
+	}
+	return c, nil
+}
+
+// Login authenticates with USER and PASS
+func (c *POP3) Login(user string, password string) error {
+	if _, err := c.cmd("USER %s", user); err != nil {
+		return err
+	}
+	_, err := c.cmd("PASS %s", password)
+	return err
+}
+
+// Stat returns the number of messages and their total size
+func (c *POP3) Stat() (int, int, error) {
+	line, err := c.cmd("STAT")
+	if err != nil {
+		return 0, 0, err
+	}
+	var count, size int
+	if _, err := fmt.Sscanf(line, "%d %d", &count, &size); err != nil {
+		return 0, 0, fmt.Errorf("mailrecv: bad STAT answer %q", line)
+	}
+	return count, size, nil
+}
+
+// List returns the messages of the maildrop with their sizes and unique ids
+func (c *POP3) List() ([]MessageInfo, error) {
+	lines, err := c.multiline("LIST")
+	if err != nil {
+		return nil, err
+	}
+	var infos []MessageInfo
+	index := make(map[int]int)
+	for _, line := range lines {
+		var info MessageInfo
+		if _, err := fmt.Sscanf(line, "%d %d", &info.Number, &info.Size); err != nil {
+			return nil, fmt.Errorf("mailrecv: bad LIST line %q", line)
+		}
+		index[info.Number] = len(infos)
+		infos = append(infos, info)
+	}
+	lines, err = c.multiline("UIDL")
+	if err != nil {
+		// UIDL is optional
+		return infos, nil
+	}
+	for _, line := range lines {
+		number, uid, ok := strings.Cut(line, " ")
+		n, err := strconv.Atoi(number)
+		if i, found := index[n]; ok && err == nil && found {
+			infos[i].UID = uid
+		}
+	}
+	return infos, nil
+}
+
+// Retrieve downloads and parses message n
+func (c *POP3) Retrieve(n int) (*Message, error) {
+	if _, err := c.cmd("RETR %d", n); err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(c.text.DotReader())
+	if err != nil {
+		return nil, err
+	}
+	msg, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	msg.UID = uint32(n)
+	return msg, nil
+}
+
+// Delete marks message n for deletion, it is removed on Quit
+func (c *POP3) Delete(n int) error {
+	_, err := c.cmd("DELE %d", n)
+	return err
+}
+
+// Quit removes the deleted messages and closes the connection
+func (c *POP3) Quit() error {
+	_, err := c.cmd("QUIT")
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// cmd sends a command and returns the text after +OK
+func (c *POP3) cmd(format string, args ...any) (string, error) {
+	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if err := c.text.PrintfLine(format, args...); err != nil {
+		return "", err
+	}
+	line, err := c.readStatus()
+	if err != nil {
+		verb, _, _ := strings.Cut(format, " ")
+		return "", fmt.Errorf("%s: %w", verb, err)
+	}
+	return line, nil
+}
+
+func (c *POP3) readStatus() (string, error) {
+	line, err := c.text.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	if rest, ok := cutPrefix(line, "+OK"); ok {
+		return strings.TrimSpace(rest), nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrRejected, line)
+}
+
+func (c *POP3) multiline(command string) ([]string, error) {
+	if _, err := c.cmd(command); err != nil {
+		return nil, err
+	}
+	return c.text.ReadDotLines()
+}
+
+func cutPrefix(s string, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
@@ -0,0 +1,58 @@
+// Package mapx has generic helpers for maps and OrderedMap, a map that keeps
+// the insertion order of its keys.
+package mapx
+
+// Keys returns the keys of m in no particular order
+func Keys[K comparable, V any](m map[K]V) []K {
+	out := make([]K, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+// Values returns the values of m in no particular order
+func Values[K comparable, V any](m map[K]V) []V {
+	out := make([]V, 0, len(m))
+	for _, v := range m {
+		out = append(out, v)
+	}
+	return out
+}
+
+// Merge returns a new map with the entries of maps, a key in several maps
+// takes the value of the last
+func Merge[K comparable, V any](maps ...map[K]V) map[K]V {
+	n := 0
+	for _, m := range maps {
+		n += len(m)
+	}
+	out := make(map[K]V, n)
+	for _, m := range maps {
+		for k, v := range m {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Invert swaps the keys and values of m. When several keys share a value,
+// which of them is kept is undefined.
+func Invert[K comparable, V comparable](m map[K]V) map[V]K {
+	out := make(map[V]K, len(m))
+	for k, v := range m {
+		out[v] = k
+	}
+	return out
+}
+
+// FilterKeys returns the entries of m whose key keep returns true for
+func FilterKeys[K comparable, V any](m map[K]V, keep func(K) bool) map[K]V {
+	out := make(map[K]V)
+	for k, v := range m {
+		if keep(k) {
+			out[k] = v
+		}
+	}
+	return out
+}
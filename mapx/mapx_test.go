@@ -0,0 +1,113 @@
+package mapx
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHelpers(t *testing.T) {
+	m := map[string]int{"a": 1, "b": 2, "c": 3}
+	keys := Keys(m)
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"a", "b", "c"}) {
+		t.Errorf("Keys() got = %v", keys)
+	}
+	values := Values(m)
+	sort.Ints(values)
+	if !reflect.DeepEqual(values, []int{1, 2, 3}) {
+		t.Errorf("Values() got = %v", values)
+	}
+
+	merged := Merge(m, nil, map[string]int{"c": 30, "d": 4})
+	if want := map[string]int{"a": 1, "b": 2, "c": 30, "d": 4}; !reflect.DeepEqual(merged, want) {
+		t.Errorf("Merge() got = %v, want %v", merged, want)
+	}
+	if m["c"] != 3 {
+		t.Errorf("Merge() changed its input %v", m)
+	}
+	if got, want := Invert(m), map[int]string{1: "a", 2: "b", 3: "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Invert() got = %v, want %v", got, want)
+	}
+	got := FilterKeys(m, func(k string) bool { return k != "b" })
+	if want := map[string]int{"a": 1, "c": 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterKeys() got = %v, want %v", got, want)
+	}
+}
+
+func TestOrderedMap(t *testing.T) {
+	var m OrderedMap[string, int]
+	for _, k := range []string{"z", "a", "m"} {
+		m.Set(k, len(k))
+	}
+	m.Set("a", 10)
+	m.Delete("z")
+	m.Delete("missing")
+	m.Set("z", 26)
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"a", "m", "z"}) {
+		t.Errorf("Keys() got = %v", got)
+	}
+	if got := m.Values(); !reflect.DeepEqual(got, []int{10, 1, 26}) {
+		t.Errorf("Values() got = %v", got)
+	}
+	if v, ok := m.Get("a"); !ok || v != 10 || m.Has("b") || m.Len() != 3 {
+		t.Errorf("Get() got = %v %v", v, ok)
+	}
+	var seen []string
+	m.Range(func(k string, v int) bool {
+		seen = append(seen, k)
+		return k != "m"
+	})
+	if !reflect.DeepEqual(seen, []string{"a", "m"}) {
+		t.Errorf("Range() got = %v, want it to stop at m", seen)
+	}
+}
+
+func TestOrderedMapJSON(t *testing.T) {
+	m := NewOrderedMap[string, any]()
+	m.Set("zeta", 1)
+	m.Set("alpha", []string{"x"})
+	m.Set("mid", nil)
+	payload := struct {
+		Data  *OrderedMap[string, any] `json:"data"`
+		Empty OrderedMap[int, string]  `json:"empty"`
+	}{Data: m}
+	data, err := json.Marshal(payload)
+	want := `{"data":{"zeta":1,"alpha":["x"],"mid":null},"empty":{}}`
+	if err != nil || string(data) != want {
+		t.Errorf("Marshal() got = %s %v, want %s", data, err, want)
+	}
+
+	var decoded OrderedMap[string, json.RawMessage]
+	if err := json.Unmarshal([]byte(`{"b": 1, "a": {"x": [1]}, "b": 2}`), &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got := decoded.Keys(); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("Unmarshal() keys got = %v", got)
+	}
+	if v, _ := decoded.Get("b"); string(v) != "2" {
+		t.Errorf("Unmarshal() b got = %s, want the last value", v)
+	}
+
+	var ints OrderedMap[int, bool]
+	if err := json.Unmarshal([]byte(`{"3": true, "-1": false}`), &ints); err != nil || !reflect.DeepEqual(ints.Keys(), []int{3, -1}) {
+		t.Errorf("Unmarshal() int keys got = %v %v", ints.Keys(), err)
+	}
+	if data, _ := json.Marshal(ints); string(data) != `{"3":true,"-1":false}` {
+		t.Errorf("Marshal() int keys got = %s", data)
+	}
+
+	errs := []string{`[1]`, `{"x": 1}`, `{"1": "s"}`}
+	for _, doc := range errs {
+		if err := json.Unmarshal([]byte(doc), &ints); err == nil {
+			t.Errorf("Unmarshal(%s) error = nil", doc)
+		}
+	}
+	var bools OrderedMap[bool, int]
+	bools.Set(true, 1)
+	if _, err := json.Marshal(bools); err == nil || !strings.Contains(err.Error(), "unsupported key") {
+		t.Errorf("Marshal() bool keys error = %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package mapx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// OrderedMap is a map that iterates and encodes to JSON in the order its keys
+// were first set, which makes payloads built from it deterministic. The zero
+// value is an empty map ready to use. It is not safe for concurrent writes.
+type OrderedMap[K comparable, V any] struct {
+	keys   []K
+	values map[K]V
+}
+
+// NewOrderedMap returns an empty OrderedMap
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{}
+}
+
+// Set sets the value of k, a new key is added at the end
+func (m *OrderedMap[K, V]) Set(k K, v V) {
+	if m.values == nil {
+		m.values = make(map[K]V)
+	}
+	if _, ok := m.values[k]; !ok {
+		m.keys = append(m.keys, k)
+	}
+	m.values[k] = v
+}
+
+// Get returns the value of k and whether it is set
+func (m *OrderedMap[K, V]) Get(k K) (V, bool) {
+	v, ok := m.values[k]
+	return v, ok
+}
+
+// Has reports whether k is set
+func (m *OrderedMap[K, V]) Has(k K) bool {
+	_, ok := m.values[k]
+	return ok
+}
+
+// Delete removes k, setting it again adds it at the end
+func (m *OrderedMap[K, V]) Delete(k K) {
+	if _, ok := m.values[k]; !ok {
+		return
+	}
+	delete(m.values, k)
+	for i, key := range m.keys {
+		if key == k {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Len returns the number of keys
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the keys in order
+func (m *OrderedMap[K, V]) Keys() []K {
+	return append([]K(nil), m.keys...)
+}
+
+// Values returns the values in the order of their keys
+func (m *OrderedMap[K, V]) Values() []V {
+	out := make([]V, len(m.keys))
+	for i, k := range m.keys {
+		out[i] = m.values[k]
+	}
+	return out
+}
+
+// Range calls fn for each entry in order until it returns false
+func (m *OrderedMap[K, V]) Range(fn func(k K, v V) bool) {
+	for _, k := range m.keys {
+		if !fn(k, m.values[k]) {
+			return
+		}
+	}
+}
+
+// MarshalJSON encodes m as a JSON object with the keys in order. Keys are
+// encoded like encoding/json encodes map keys: strings, numbers and
+// encoding.TextMarshaler.
+func (m OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalKey(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func marshalKey(k any) ([]byte, error) {
+	data, err := json.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	switch data[0] {
+	case '"':
+		return data, nil
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return json.Marshal(string(data))
+	}
+	return nil, fmt.Errorf("mapx: unsupported key type %T", k)
+}
+
+// UnmarshalJSON decodes a JSON object into m, appending its keys in the
+// order of the document. A key repeated in the document keeps its first
+// position and its last value.
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		// null leaves m unchanged, like encoding/json does for maps
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("mapx: cannot unmarshal %v into an OrderedMap", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var k K
+		if err := unmarshalKey(tok.(string), &k); err != nil {
+			return err
+		}
+		var v V
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		m.Set(k, v)
+	}
+	_, err = dec.Token()
+	return err
+}
+
+func unmarshalKey(s string, k any) error {
+	quoted, _ := json.Marshal(s)
+	if err := json.Unmarshal(quoted, k); err == nil {
+		return nil
+	}
+	// numeric keys are quoted in JSON but decode from the bare number
+	if err := json.Unmarshal([]byte(s), k); err != nil {
+		return fmt.Errorf("mapx: cannot unmarshal key %q: %w", s, err)
+	}
+	return nil
+}
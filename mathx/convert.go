@@ -0,0 +1,84 @@
+package mathx
+
+import (
+	"fmt"
+	"math"
+)
+
+func signed[T Integer]() bool {
+	var zero T
+	return zero-1 < zero
+}
+
+// Convert converts v to another integer type, failing with ErrOverflow when
+// v does not fit it instead of wrapping around
+func Convert[To Integer, From Integer](v From) (To, error) {
+	to := To(v)
+	if From(to) != v || (v < 0) != (to < 0) {
+		return 0, fmt.Errorf("%w: %v does not fit %T", ErrOverflow, v, to)
+	}
+	return to, nil
+}
+
+// FromFloat converts f to an integer type, dropping its fraction. It fails
+// with ErrOverflow for NaN, infinities and values out of the range of To.
+func FromFloat[To Integer, From Float](f From) (To, error) {
+	t := math.Trunc(float64(f))
+	var to To
+	// the bounds of To as floats, 2^bits-1 rounds up so the upper one is exclusive
+	bits := bitSize[To]()
+	lo, hi := 0.0, math.Ldexp(1, bits)
+	if signed[To]() {
+		lo, hi = -math.Ldexp(1, bits-1), math.Ldexp(1, bits-1)
+	}
+	if math.IsNaN(t) || t < lo || t >= hi {
+		return 0, fmt.Errorf("%w: %v does not fit %T", ErrOverflow, f, to)
+	}
+	return To(t), nil
+}
+
+// bitSize returns the number of bits of an integer type
+func bitSize[T Integer]() int {
+	bits := 0
+	for v := T(1); v != 0; v <<= 1 {
+		bits++
+	}
+	return bits
+}
+
+// Add returns a+b, or ErrOverflow when the sum does not fit T
+func Add[T Integer](a T, b T) (T, error) {
+	c := a + b
+	if signed[T]() && (b > 0 && c < a || b < 0 && c > a) || !signed[T]() && c < a {
+		return 0, fmt.Errorf("%w: %v + %v", ErrOverflow, a, b)
+	}
+	return c, nil
+}
+
+// Sub returns a-b, or ErrOverflow when the difference does not fit T
+func Sub[T Integer](a T, b T) (T, error) {
+	c := a - b
+	if signed[T]() && (b > 0 && c > a || b < 0 && c < a) || !signed[T]() && b > a {
+		return 0, fmt.Errorf("%w: %v - %v", ErrOverflow, a, b)
+	}
+	return c, nil
+}
+
+// Mul returns a*b, or ErrOverflow when the product does not fit T
+func Mul[T Integer](a T, b T) (T, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	c := a * b
+	overflow := c/b != a
+	if signed[T]() {
+		// the smallest value times -1 is itself, which the division misses
+		var minusOne T
+		minusOne--
+		overflow = overflow || b == minusOne && c == a || a == minusOne && c == b
+	}
+	if overflow {
+		return 0, fmt.Errorf("%w: %v * %v", ErrOverflow, a, b)
+	}
+	return c, nil
+}
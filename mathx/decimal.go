@@ -0,0 +1,288 @@
+package mathx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MaxScale is the most decimal places a Decimal holds
+const MaxScale = 18
+
+// ErrInvalidDecimal is returned for text that is not a decimal number
+var ErrInvalidDecimal = errors.New("mathx: invalid decimal")
+
+// Decimal is a fixed-point number of up to 18 decimal places, exact where
+// floats are not, e.g. for prices: 0.1 + 0.2 is 0.3. Its value is Units
+// divided by 10^Scale and must fit an int64 at that scale, operations
+// overflowing it fail with ErrOverflow. The zero value is 0.
+//
+// Decimals encode to JSON as strings, "19.99", so clients parsing JSON
+// numbers as floats don't lose precision, and decode from strings and
+// numbers.
+type Decimal struct {
+	units int64
+	scale int
+}
+
+// NewDecimal returns units divided by 10^scale, NewDecimal(1999, 2) is 19.99.
+// It panics for a scale out of the range 0 to MaxScale.
+func NewDecimal(units int64, scale int) Decimal {
+	if scale < 0 || scale > MaxScale {
+		panic(fmt.Sprintf("mathx: decimal scale %d out of range 0 to %d", scale, MaxScale))
+	}
+	return Decimal{units: units, scale: scale}
+}
+
+// DecimalFromInt returns n as a Decimal
+func DecimalFromInt(n int64) Decimal {
+	return Decimal{units: n}
+}
+
+// ParseDecimal parses a decimal number such as "-12.50", "3" or "1.5e3". The
+// scale is the number of decimal places written.
+func ParseDecimal(s string) (Decimal, error) {
+	mantissa, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+		}
+		mantissa, exp = s[:i], e
+	}
+	intPart, frac, _ := strings.Cut(mantissa, ".")
+	digits := strings.TrimLeft(intPart, "+-") + frac
+	if digits == "" || strings.Trim(digits, "0123456789") != "" || len(intPart)-len(strings.TrimLeft(intPart, "+-")) > 1 {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+	// the exponent is bounded before any big.Int work, 1e50000000 would take
+	// seconds to compute only to overflow
+	if strings.Trim(digits, "0") == "" {
+		switch {
+		case exp > len(frac):
+			return Decimal{}, nil
+		case exp < len(frac)-MaxScale:
+			return Decimal{scale: MaxScale}, nil
+		default:
+			return Decimal{scale: len(frac) - exp}, nil
+		}
+	}
+	if exp > len(frac)+MaxScale+19 || exp < len(frac)-MaxScale-len(digits) {
+		return Decimal{}, fmt.Errorf("%w: decimal %q", ErrOverflow, s)
+	}
+	n, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+	if strings.HasPrefix(intPart, "-") {
+		n.Neg(n)
+	}
+	scale := len(frac) - exp
+	if scale < 0 {
+		n.Mul(n, pow10(-scale))
+		scale = 0
+	}
+	// trailing zeros past the largest scale are dropped, other digits are lost
+	for scale > MaxScale && new(big.Int).Rem(n, big.NewInt(10)).Sign() == 0 {
+		n.Quo(n, big.NewInt(10))
+		scale--
+	}
+	if scale > MaxScale || !n.IsInt64() {
+		return Decimal{}, fmt.Errorf("%w: decimal %q", ErrOverflow, s)
+	}
+	return Decimal{units: n.Int64(), scale: scale}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics on errors, for constants
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Units returns the value of d times 10^Scale
+func (d Decimal) Units() int64 {
+	return d.units
+}
+
+// Scale returns the number of decimal places of d
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Add returns d+o at the larger scale of both
+func (d Decimal) Add(o Decimal) (Decimal, error) {
+	a, b, scale := align(d, o)
+	return fromBig(a.Add(a, b), scale)
+}
+
+// Sub returns d-o at the larger scale of both
+func (d Decimal) Sub(o Decimal) (Decimal, error) {
+	a, b, scale := align(d, o)
+	return fromBig(a.Sub(a, b), scale)
+}
+
+// Mul returns d*o exactly, at the sum of both scales. A product past
+// MaxScale is rounded half even to MaxScale places.
+func (d Decimal) Mul(o Decimal) (Decimal, error) {
+	n := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(o.units))
+	scale := d.scale + o.scale
+	if scale > MaxScale {
+		n = roundQuo(n, pow10(scale-MaxScale), HalfEven)
+		scale = MaxScale
+	}
+	return fromBig(n, scale)
+}
+
+// Div returns d/o rounded with mode to scale places, e.g. a price split into
+// shares. Dividing by zero fails.
+func (d Decimal) Div(o Decimal, scale int, mode RoundingMode) (Decimal, error) {
+	if o.units == 0 {
+		return Decimal{}, errors.New("mathx: decimal division by zero")
+	}
+	if scale < 0 || scale > MaxScale {
+		return Decimal{}, fmt.Errorf("mathx: decimal scale %d out of range 0 to %d", scale, MaxScale)
+	}
+	num, den := big.NewInt(d.units), big.NewInt(o.units)
+	// d/o = num/10^ds / (den/10^os), scaled by 10^scale
+	if exp := scale + o.scale - d.scale; exp >= 0 {
+		num.Mul(num, pow10(exp))
+	} else {
+		den.Mul(den, pow10(-exp))
+	}
+	return fromBig(roundQuo(num, den, mode), scale)
+}
+
+// Round returns d rounded with mode to scale places, d itself when it has
+// no more places than that
+func (d Decimal) Round(scale int, mode RoundingMode) Decimal {
+	if scale < 0 {
+		scale = 0
+	}
+	if scale >= d.scale {
+		return d
+	}
+	// fewer places can't overflow
+	n := roundQuo(big.NewInt(d.units), pow10(d.scale-scale), mode)
+	return Decimal{units: n.Int64(), scale: scale}
+}
+
+// Cmp returns -1, 0 or 1 when d is less than, equal to or greater than o,
+// whatever their scales
+func (d Decimal) Cmp(o Decimal) int {
+	a, b, _ := align(d, o)
+	return a.Cmp(b)
+}
+
+// Equal reports whether d and o have the same value, 1.50 equals 1.5
+func (d Decimal) Equal(o Decimal) bool {
+	return d.Cmp(o) == 0
+}
+
+// Sign returns -1, 0 or 1 for negative, zero and positive values
+func (d Decimal) Sign() int {
+	switch {
+	case d.units < 0:
+		return -1
+	case d.units > 0:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether d is 0
+func (d Decimal) IsZero() bool {
+	return d.units == 0
+}
+
+// Float64 returns the float64 nearest to d
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String formats d with all its decimal places, "19.90"
+func (d Decimal) String() string {
+	s := strconv.FormatInt(d.units, 10)
+	if d.scale == 0 {
+		return s
+	}
+	sign := ""
+	if d.units < 0 {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= d.scale {
+		s = strings.Repeat("0", d.scale-len(s)+1) + s
+	}
+	return sign + s[:len(s)-d.scale] + "." + s[len(s)-d.scale:]
+}
+
+// MarshalText encodes d as String does
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses text with ParseDecimal
+func (d *Decimal) UnmarshalText(text []byte) error {
+	v, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// UnmarshalJSON decodes a JSON string or number, null leaves d unchanged
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+	return d.UnmarshalText(data)
+}
+
+// align returns the units of d and o at the larger scale of both
+func align(d Decimal, o Decimal) (*big.Int, *big.Int, int) {
+	a, b := big.NewInt(d.units), big.NewInt(o.units)
+	scale := Max(d.scale, o.scale)
+	a.Mul(a, pow10(scale-d.scale))
+	b.Mul(b, pow10(scale-o.scale))
+	return a, b, scale
+}
+
+func fromBig(n *big.Int, scale int) (Decimal, error) {
+	if !n.IsInt64() {
+		return Decimal{}, fmt.Errorf("%w: decimal %s with %d places", ErrOverflow, n, scale)
+	}
+	return Decimal{units: n.Int64(), scale: scale}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundQuo returns num/den rounded with mode
+func roundQuo(num *big.Int, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 || mode == Down {
+		return q
+	}
+	// compare twice the remainder with the divisor to find halves
+	twice := new(big.Int).Abs(r)
+	cmp := twice.Lsh(twice, 1).Cmp(new(big.Int).Abs(den))
+	if cmp > 0 || cmp == 0 && (mode == HalfUp || q.Bit(0) == 1) {
+		if num.Sign()*den.Sign() < 0 {
+			return q.Sub(q, big.NewInt(1))
+		}
+		return q.Add(q, big.NewInt(1))
+	}
+	return q
+}
@@ -0,0 +1,132 @@
+// Package mathx has generic numeric helpers, integer conversions and
+// arithmetic that report overflows instead of wrapping around, rounding
+// helpers and Decimal, a fixed-point number for amounts of money.
+package mathx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+var (
+	// ErrOverflow is returned when a result does not fit its type
+	ErrOverflow = errors.New("mathx: overflow")
+	// ErrEmpty is returned by functions needing at least one value
+	ErrEmpty = errors.New("mathx: no values")
+)
+
+// Signed is the set of signed integer types
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Unsigned is the set of unsigned integer types
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Integer is the set of integer types
+type Integer interface {
+	Signed | Unsigned
+}
+
+// Float is the set of floating-point types
+type Float interface {
+	~float32 | ~float64
+}
+
+// Number is the set of integer and floating-point types
+type Number interface {
+	Integer | Float
+}
+
+// Ordered is the set of types supporting < and >
+type Ordered interface {
+	Number | ~string
+}
+
+// Min returns the smallest of its arguments
+func Min[T Ordered](first T, rest ...T) T {
+	for _, v := range rest {
+		if v < first {
+			first = v
+		}
+	}
+	return first
+}
+
+// Max returns the largest of its arguments
+func Max[T Ordered](first T, rest ...T) T {
+	for _, v := range rest {
+		if v > first {
+			first = v
+		}
+	}
+	return first
+}
+
+// Clamp returns v limited to the range lo to hi
+func Clamp[T Ordered](v T, lo T, hi T) T {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// Abs returns the absolute value of v. The smallest value of a signed type has
+// no positive counterpart and is returned unchanged.
+func Abs[T Signed | Float](v T) T {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Sum returns the sum of s, integers wrap around on overflow like + does
+func Sum[T Number](s []T) T {
+	var sum T
+	for _, v := range s {
+		sum += v
+	}
+	return sum
+}
+
+// Mean returns the arithmetic mean of s, 0 for an empty s
+func Mean[T Number](s []T) float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range s {
+		sum += float64(v)
+	}
+	return sum / float64(len(s))
+}
+
+// Percentile returns the p-th percentile of s, p from 0 to 100, interpolating
+// linearly between the closest ranks like spreadsheets and numpy do. s is not
+// changed.
+func Percentile[T Number](s []T, p float64) (float64, error) {
+	if len(s) == 0 {
+		return 0, ErrEmpty
+	}
+	if p < 0 || p > 100 || math.IsNaN(p) {
+		return 0, fmt.Errorf("mathx: percentile %v out of range 0 to 100", p)
+	}
+	sorted := make([]float64, len(s))
+	for i, v := range s {
+		sorted[i] = float64(v)
+	}
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(rank)
+	if lo == len(sorted)-1 {
+		return sorted[lo], nil
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[lo+1]-sorted[lo]), nil
+}
@@ -0,0 +1,260 @@
+package mathx
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestGeneric(t *testing.T) {
+	if got := Min(3, 1, 2); got != 1 {
+		t.Errorf("Min() got = %v", got)
+	}
+	if got := Max("b", "c", "a"); got != "c" {
+		t.Errorf("Max() got = %v", got)
+	}
+	if got := Max(1.5); got != 1.5 {
+		t.Errorf("Max() single got = %v", got)
+	}
+	clamps := []struct{ v, want int }{{-5, 0}, {5, 5}, {15, 10}}
+	for _, tt := range clamps {
+		if got := Clamp(tt.v, 0, 10); got != tt.want {
+			t.Errorf("Clamp(%v) got = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+	if Abs(-3) != 3 || Abs(2.5) != 2.5 || Abs(int8(math.MinInt8)) != math.MinInt8 {
+		t.Errorf("Abs() got wrong result")
+	}
+	if got := Sum([]int{1, 2, 3}); got != 6 {
+		t.Errorf("Sum() got = %v", got)
+	}
+	if got := Mean([]int{1, 2, 4}); got != 7.0/3 {
+		t.Errorf("Mean() got = %v", got)
+	}
+	if got := Mean([]float64(nil)); got != 0 {
+		t.Errorf("Mean() empty got = %v", got)
+	}
+
+	s := []int{15, 20, 35, 40, 50}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 15},
+		{25, 20},
+		{50, 35},
+		{90, 46},
+		{100, 50},
+	}
+	for _, tt := range tests {
+		if got, err := Percentile(s, tt.p); err != nil || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Percentile(%v) got = %v %v, want %v", tt.p, got, err, tt.want)
+		}
+	}
+	if s[0] != 15 {
+		t.Errorf("Percentile() changed its input")
+	}
+	if _, err := Percentile([]int{}, 50); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Percentile() empty error got = %v, want %v", err, ErrEmpty)
+	}
+	if _, err := Percentile(s, 101); err == nil {
+		t.Errorf("Percentile(101) error = nil")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	if v, err := Convert[int8](127); v != 127 || err != nil {
+		t.Errorf("Convert[int8](127) got = %v %v", v, err)
+	}
+	if _, err := Convert[int8](128); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Convert[int8](128) error got = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := Convert[uint](-1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Convert[uint](-1) error got = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := Convert[int64](uint64(math.MaxUint64)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Convert[int64](MaxUint64) error got = %v, want %v", err, ErrOverflow)
+	}
+	if v, err := Convert[uint32](int64(4e9)); v != 4e9 || err != nil {
+		t.Errorf("Convert[uint32](4e9) got = %v %v", v, err)
+	}
+
+	floats := []struct {
+		f  float64
+		ok bool
+	}{
+		{-128.9, true},
+		{127.9, true},
+		{128, false},
+		{-129, false},
+		{math.NaN(), false},
+		{math.Inf(1), false},
+	}
+	for _, tt := range floats {
+		v, err := FromFloat[int8](tt.f)
+		if (err == nil) != tt.ok || tt.ok && float64(v) != math.Trunc(tt.f) {
+			t.Errorf("FromFloat[int8](%v) got = %v %v", tt.f, v, err)
+		}
+	}
+	if _, err := FromFloat[int64](float64(math.MaxInt64)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("FromFloat[int64](2^63) error got = %v, want %v", err, ErrOverflow)
+	}
+	if v, err := FromFloat[uint8](255.5); v != 255 || err != nil {
+		t.Errorf("FromFloat[uint8](255.5) got = %v %v", v, err)
+	}
+}
+
+func TestChecked(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(int8, int8) (int8, error)
+		a, b int8
+		want int8
+		ok   bool
+	}{
+		{"Add", Add[int8], 100, 27, 127, true},
+		{"Add", Add[int8], 100, 28, 0, false},
+		{"Add", Add[int8], -100, -29, 0, false},
+		{"Sub", Sub[int8], -100, 28, -128, true},
+		{"Sub", Sub[int8], 0, -128, 0, false},
+		{"Mul", Mul[int8], -16, 8, -128, true},
+		{"Mul", Mul[int8], 16, 8, 0, false},
+		{"Mul", Mul[int8], -128, -1, 0, false},
+		{"Mul", Mul[int8], -1, -128, 0, false},
+		{"Mul", Mul[int8], 0, -128, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.fn(tt.a, tt.b)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("%v(%v, %v) got = %v %v, want %v", tt.name, tt.a, tt.b, got, err, tt.want)
+		}
+	}
+	if _, err := Sub[uint](1, 2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Sub[uint](1, 2) error got = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := Add[uint8](200, 56); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Add[uint8](200, 56) error got = %v, want %v", err, ErrOverflow)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		x      float64
+		places int
+		up     float64
+		even   float64
+	}{
+		{2.5, 0, 3, 2},
+		{3.5, 0, 4, 4},
+		{-2.5, 0, -3, -2},
+		{2.675, 2, 2.68, 2.68},
+		{1.005, 2, 1.01, 1.0},
+		{0.125, 2, 0.13, 0.12},
+		{1234.5, -2, 1200, 1200},
+		{1250, -2, 1300, 1200},
+		{1e300, 2, 1e300, 1e300},
+	}
+	for _, tt := range tests {
+		if got := RoundHalfUp(tt.x, tt.places); got != tt.up {
+			t.Errorf("RoundHalfUp(%v, %v) got = %v, want %v", tt.x, tt.places, got, tt.up)
+		}
+		if got := RoundHalfEven(tt.x, tt.places); got != tt.even {
+			t.Errorf("RoundHalfEven(%v, %v) got = %v, want %v", tt.x, tt.places, got, tt.even)
+		}
+	}
+	if got := Round(-1.99, 1, Down); got != -1.9 {
+		t.Errorf("Round(Down) got = %v", got)
+	}
+	if got := Round(math.NaN(), 2, HalfUp); !math.IsNaN(got) {
+		t.Errorf("Round(NaN) got = %v", got)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	parse := []struct {
+		s    string
+		want string
+		ok   bool
+	}{
+		{"19.99", "19.99", true},
+		{"-0.5", "-0.5", true},
+		{"+3", "3", true},
+		{".25", "0.25", true},
+		{"1.50", "1.50", true},
+		{"1.5e3", "1500", true},
+		{"125e-4", "0.0125", true},
+		{"0.1000000000000000000000", "0.100000000000000000", true},
+		{"0.0000000000000000001", "", false},
+		{"99999999999999999999", "", false},
+		{"1e50000000", "", false},
+		{"1e-50000000", "", false},
+		{"-0.00e50000000", "0", true},
+		{"0e-100000000", "0.000000000000000000", true},
+		{"1e-9223372036854775808", "", false},
+		{"0e-9223372036854775808", "0.000000000000000000", true},
+		{"", "", false},
+		{"-", "", false},
+		{"--1", "", false},
+		{"1.2.3", "", false},
+		{"1,5", "", false},
+	}
+	for _, tt := range parse {
+		d, err := ParseDecimal(tt.s)
+		if (err == nil) != tt.ok || tt.ok && d.String() != tt.want {
+			t.Errorf("ParseDecimal(%q) got = %v %v, want %v", tt.s, d, err, tt.want)
+		}
+	}
+
+	a, b := MustParseDecimal("0.1"), MustParseDecimal("0.2")
+	if sum, err := a.Add(b); err != nil || !sum.Equal(MustParseDecimal("0.3")) {
+		t.Errorf("Add() got = %v %v", sum, err)
+	}
+	if diff, _ := NewDecimal(1999, 2).Sub(DecimalFromInt(20)); diff.String() != "-0.01" || diff.Sign() != -1 {
+		t.Errorf("Sub() got = %v", diff)
+	}
+	price, qty := NewDecimal(1999, 2), MustParseDecimal("3.5")
+	if total, _ := price.Mul(qty); total.String() != "69.965" || total.Round(2, HalfUp).String() != "69.97" || total.Round(2, HalfEven).String() != "69.96" {
+		t.Errorf("Mul() got = %v", total)
+	}
+	if share, err := DecimalFromInt(100).Div(DecimalFromInt(3), 2, HalfEven); err != nil || share.String() != "33.33" {
+		t.Errorf("Div() got = %v %v", share, err)
+	}
+	if share, _ := DecimalFromInt(-2).Div(DecimalFromInt(3), 2, HalfUp); share.String() != "-0.67" {
+		t.Errorf("Div() negative got = %v", share)
+	}
+	if _, err := a.Div(Decimal{}, 2, HalfUp); err == nil {
+		t.Errorf("Div() by zero error = nil")
+	}
+	big := NewDecimal(math.MaxInt64, 0)
+	if _, err := big.Add(DecimalFromInt(1)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Add() overflow error got = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := big.Mul(DecimalFromInt(2)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Mul() overflow error got = %v, want %v", err, ErrOverflow)
+	}
+	if MustParseDecimal("1.50").Cmp(MustParseDecimal("1.5")) != 0 || a.Cmp(b) != -1 || !(Decimal{}).IsZero() {
+		t.Errorf("Cmp() got wrong result")
+	}
+	if got := MustParseDecimal("-0.05").Float64(); got != -0.05 {
+		t.Errorf("Float64() got = %v", got)
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	type payment struct {
+		Amount Decimal  `json:"amount"`
+		Fee    *Decimal `json:"fee,omitempty"`
+	}
+	data, err := json.Marshal(payment{Amount: NewDecimal(1050, 2)})
+	if err != nil || string(data) != `{"amount":"10.50"}` {
+		t.Errorf("Marshal() got = %s %v", data, err)
+	}
+	var p payment
+	if err := json.Unmarshal([]byte(`{"amount":12.345,"fee":"0.30"}`), &p); err != nil || p.Amount.String() != "12.345" || p.Fee.String() != "0.30" {
+		t.Errorf("Unmarshal() got = %+v %v", p, err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"ten"}`), &p); !errors.Is(err, ErrInvalidDecimal) {
+		t.Errorf("Unmarshal() error got = %v, want %v", err, ErrInvalidDecimal)
+	}
+}
@@ -0,0 +1,61 @@
+package mathx
+
+import (
+	"math"
+	"strconv"
+)
+
+// RoundingMode chooses how values halfway between two results are rounded
+type RoundingMode int
+
+const (
+	// HalfUp rounds halves away from zero, 2.5 to 3 and -2.5 to -3, as
+	// commercial rounding does
+	HalfUp RoundingMode = iota
+	// HalfEven rounds halves to the even neighbour, 2.5 to 2 and 3.5 to 4,
+	// as banker's rounding does to avoid a bias in sums
+	HalfEven
+	// Down truncates towards zero
+	Down
+)
+
+// RoundHalfUp rounds x to places decimal places, halves away from zero. x is
+// rounded as written in decimal, so 2.675 gives 2.68 although its float64 is
+// slightly below. Negative places round to tens, hundreds and so on.
+func RoundHalfUp(x float64, places int) float64 {
+	return Round(x, places, HalfUp)
+}
+
+// RoundHalfEven rounds x to places decimal places, halves to the even
+// neighbour, like RoundHalfUp otherwise
+func RoundHalfEven(x float64, places int) float64 {
+	return Round(x, places, HalfEven)
+}
+
+// Round rounds x to places decimal places with mode
+func Round(x float64, places int, mode RoundingMode) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return x
+	}
+	if places >= 0 {
+		// the shortest decimal form of x is what people expect to be rounded
+		if d, err := ParseDecimal(strconv.FormatFloat(x, 'f', -1, 64)); err == nil {
+			return d.Round(places, mode).Float64()
+		}
+	}
+	scale := math.Pow10(places)
+	v := x * scale
+	if math.IsInf(v, 0) {
+		// x is too large to have decimal places
+		return x
+	}
+	switch mode {
+	case HalfEven:
+		v = math.RoundToEven(v)
+	case Down:
+		v = math.Trunc(v)
+	default:
+		v = math.Round(v)
+	}
+	return v / scale
+}
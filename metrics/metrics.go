@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const DefaultNamespace = "gotool"
+
+// Hook implements the http package Hook interface and records request
+// totals, latency and in-flight requests on prometheus collectors. For
+// clients with WithHedging it also counts the hedges sent and won.
+type Hook struct {
+	requests  *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	inFlight  prometheus.Gauge
+	hedges    *prometheus.CounterVec
+	hedgeWins *prometheus.CounterVec
+}
+
+type Option func(*config)
+
+type config struct {
+	namespace string
+	buckets   []float64
+}
+
+// WithNamespace sets the metric namespace, "gotool" by default
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithBuckets sets the latency histogram buckets in seconds
+func WithBuckets(buckets []float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+// NewHook creates the collectors and registers them on reg,
+// add the result with http.AddHook.
+func NewHook(reg prometheus.Registerer, opts ...Option) (*Hook, error) {
+	c := config{
+		namespace: DefaultNamespace,
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	h := &Hook{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Subsystem: "http_client",
+			Name:      "requests_total",
+			Help:      "Total number of outgoing HTTP requests.",
+		}, []string{"method", "host", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.namespace,
+			Subsystem: "http_client",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of outgoing HTTP requests.",
+			Buckets:   c.buckets,
+		}, []string{"method", "host"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: c.namespace,
+			Subsystem: "http_client",
+			Name:      "requests_in_flight",
+			Help:      "Number of outgoing HTTP requests in flight.",
+		}),
+		hedges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Subsystem: "http_client",
+			Name:      "hedged_requests_total",
+			Help:      "Total number of extra requests sent by hedging.",
+		}, []string{"method", "host"}),
+		hedgeWins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Subsystem: "http_client",
+			Name:      "hedge_wins_total",
+			Help:      "Total number of requests answered first by a hedge.",
+		}, []string{"method", "host"}),
+	}
+	for _, collector := range []prometheus.Collector{h.requests, h.duration, h.inFlight, h.hedges, h.hedgeWins} {
+		if err := reg.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+type requestInfoKey struct{}
+
+type requestInfo struct {
+	method string
+	host   string
+	start  time.Time
+}
+
+func (h *Hook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	h.inFlight.Inc()
+	return context.WithValue(ctx, requestInfoKey{}, requestInfo{
+		method: req.Method,
+		host:   req.URL.Host,
+		start:  time.Now(),
+	}), nil
+}
+
+func (h *Hook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	info, ok := ctx.Value(requestInfoKey{}).(requestInfo)
+	if !ok {
+		return ctx, nil
+	}
+	h.inFlight.Dec()
+	status := "error"
+	if respCode > 0 {
+		status = strconv.Itoa(respCode)
+	}
+	h.requests.WithLabelValues(info.method, info.host, status).Inc()
+	h.duration.WithLabelValues(info.method, info.host).Observe(time.Since(info.start).Seconds())
+	if hedge, ok := gohttp.HedgeInfoFromContext(ctx); ok && hedge.Extra > 0 {
+		h.hedges.WithLabelValues(info.method, info.host).Add(float64(hedge.Extra))
+		if hedge.Won {
+			h.hedgeWins.WithLabelValues(info.method, info.host).Inc()
+		}
+	}
+	return ctx, nil
+}
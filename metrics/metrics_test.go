@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	reg := prometheus.NewRegistry()
+	hook, err := NewHook(reg)
+	if err != nil {
+		t.Fatalf("NewHook() error = %v", err)
+	}
+	gohttp.AddHook(hook)
+
+	_, _, _, _ = gohttp.Get(server.URL+"/ok", nil, nil)
+	_, _, _, _ = gohttp.Get(server.URL+"/ok", nil, nil)
+	_, _, _, _ = gohttp.Get(server.URL+"/missing", nil, nil)
+	_, _, _, _ = gohttp.Get("http://127.0.0.1:1/refused", nil, nil)
+
+	tests := []struct {
+		name   string
+		labels []string
+		want   float64
+	}{
+		{name: "ok", labels: []string{"GET", host, "200"}, want: 2},
+		{name: "not found", labels: []string{"GET", host, "404"}, want: 1},
+		{name: "transport error", labels: []string{"GET", "127.0.0.1:1", "error"}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testutil.ToFloat64(hook.requests.WithLabelValues(tt.labels...)); got != tt.want {
+				t.Errorf("requests_total%v got = %v, want %v", tt.labels, got, tt.want)
+			}
+		})
+	}
+	if got := testutil.ToFloat64(hook.inFlight); got != 0 {
+		t.Errorf("requests_in_flight got = %v, want 0", got)
+	}
+	if got := testutil.CollectAndCount(hook.duration); got != 2 {
+		t.Errorf("request_duration_seconds series got = %v, want 2", got)
+	}
+	if _, err := NewHook(reg); err == nil {
+		t.Errorf("NewHook() on the same registry error = nil, want AlreadyRegisteredError")
+	}
+}
+
+func TestHookHedging(t *testing.T) {
+	first := make(chan struct{}, 1)
+	first <- struct{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-first:
+			<-r.Context().Done()
+		default:
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	hook, err := NewHook(prometheus.NewRegistry(), WithNamespace("hedging"))
+	if err != nil {
+		t.Fatalf("NewHook() error = %v", err)
+	}
+	gohttp.AddHook(hook)
+
+	client := gohttp.NewClient(gohttp.WithHedging(10*time.Millisecond, 1), gohttp.WithLogger(gohttp.NopLogger))
+	for i := 0; i < 2; i++ {
+		if _, err := client.NewRequest(gohttp.GET, server.URL).Send(); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if got := testutil.ToFloat64(hook.hedges.WithLabelValues("GET", host)); got != 1 {
+		t.Errorf("hedged_requests_total got = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(hook.hedgeWins.WithLabelValues("GET", host)); got != 1 {
+		t.Errorf("hedge_wins_total got = %v, want 1", got)
+	}
+}
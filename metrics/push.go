@@ -0,0 +1,378 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	gourl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type pushConfig struct {
+	gatherer       prometheus.Gatherer
+	client         *gohttp.Client
+	header         map[string]string
+	batchSize      int
+	retries        int
+	backoff        time.Duration
+	externalLabels map[string]string
+}
+
+type PushOption = opt.Option[pushConfig]
+
+// WithGatherer sets where metrics are gathered from, prometheus.DefaultGatherer by default
+func WithGatherer(g prometheus.Gatherer) PushOption {
+	return func(c *pushConfig) {
+		c.gatherer = g
+	}
+}
+
+// WithPushClient sends with client instead of a new gohttp.Client
+func WithPushClient(client *gohttp.Client) PushOption {
+	return func(c *pushConfig) {
+		c.client = client
+	}
+}
+
+// WithPushHeader adds headers to every push, e.g. for authentication
+func WithPushHeader(header map[string]string) PushOption {
+	return func(c *pushConfig) {
+		c.header = header
+	}
+}
+
+// WithBatchSize limits the series per remote-write request, 500 by default
+func WithBatchSize(n int) PushOption {
+	return func(c *pushConfig) {
+		c.batchSize = n
+	}
+}
+
+// WithRetries retries failed pushes n times, waiting backoff, 2*backoff...
+// Only transport errors, 429 and 5xx responses are retried.
+func WithRetries(n int, backoff time.Duration) PushOption {
+	return func(c *pushConfig) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+// WithExternalLabels adds labels to every remote-write series
+func WithExternalLabels(labels map[string]string) PushOption {
+	return func(c *pushConfig) {
+		c.externalLabels = labels
+	}
+}
+
+func newPushConfig(opts []PushOption) (pushConfig, error) {
+	c := pushConfig{
+		gatherer:  prometheus.DefaultGatherer,
+		batchSize: 500,
+		retries:   2,
+		backoff:   500 * time.Millisecond,
+	}
+	err := opt.Build(&c, opts, func(c *pushConfig) error {
+		if c.batchSize <= 0 || c.retries < 0 {
+			return errors.New("metrics: batch size must be positive and retries not negative")
+		}
+		return nil
+	})
+	if c.client == nil {
+		c.client = gohttp.NewClient()
+	}
+	return c, err
+}
+
+// send sends the request built by build, retrying transient failures
+func (c *pushConfig) send(ctx context.Context, build func() *gohttp.Request) error {
+	backoff := c.backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var resp *gohttp.Response
+		resp, err = build().WithContext(ctx).Send()
+		// pushgateway answers 202 on older versions
+		if resp != nil && resp.StatusCode/100 == 2 {
+			return nil
+		}
+		retryable := resp == nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retryable || attempt >= c.retries || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Pushgateway pushes gathered metrics to a Prometheus Pushgateway for a job
+type Pushgateway struct {
+	url      string
+	job      string
+	grouping [][2]string
+	config   pushConfig
+}
+
+// NewPushgateway creates a pusher for job, url is the gateway base url
+func NewPushgateway(url string, job string, opts ...PushOption) (*Pushgateway, error) {
+	if job == "" {
+		return nil, errors.New("metrics: pushgateway job must not be empty")
+	}
+	c, err := newPushConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Pushgateway{url: strings.TrimSuffix(url, "/"), job: job, config: c}, nil
+}
+
+// Grouping adds a grouping label to the push url
+func (p *Pushgateway) Grouping(name string, value string) *Pushgateway {
+	p.grouping = append(p.grouping, [2]string{name, value})
+	return p
+}
+
+// Push replaces all metrics of the group (PUT)
+func (p *Pushgateway) Push(ctx context.Context) error {
+	return p.push(ctx, gohttp.PUT)
+}
+
+// Add replaces only the metrics with the same names (POST)
+func (p *Pushgateway) Add(ctx context.Context) error {
+	return p.push(ctx, gohttp.POST)
+}
+
+// Delete removes the metrics of the group
+func (p *Pushgateway) Delete(ctx context.Context) error {
+	return p.config.send(ctx, func() *gohttp.Request {
+		return p.config.client.NewRequest(gohttp.DELETE, p.groupURL())
+	})
+}
+
+func (p *Pushgateway) push(ctx context.Context, method gohttp.RequestMethodType) error {
+	families, err := p.config.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, expfmt.FmtText)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return err
+		}
+	}
+	return p.config.send(ctx, func() *gohttp.Request {
+		req := p.config.client.NewRequest(method, p.groupURL()).RawBody(buf.Bytes())
+		for k, v := range p.config.header {
+			req.Header(k, v)
+		}
+		return req.Header("Content-Type", string(expfmt.FmtText))
+	})
+}
+
+// groupURL renders /metrics/job/<job>/<label>/<value>..., values that cannot
+// be a path segment use the @base64 form of the gateway
+func (p *Pushgateway) groupURL() string {
+	url := p.url + "/metrics/" + encodeGroupingPair("job", p.job)
+	for _, pair := range p.grouping {
+		url += "/" + encodeGroupingPair(pair[0], pair[1])
+	}
+	return url
+}
+
+func encodeGroupingPair(name string, value string) string {
+	switch {
+	case value == "":
+		return name + "@base64/="
+	case strings.Contains(value, "/"):
+		return name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return name + "/" + gourl.PathEscape(value)
+}
+
+// RemoteWriter sends gathered metrics with the Prometheus remote-write
+// protocol (snappy compressed protobuf), split in batches
+type RemoteWriter struct {
+	url    string
+	config pushConfig
+	now    func() time.Time
+}
+
+func NewRemoteWriter(url string, opts ...PushOption) (*RemoteWriter, error) {
+	c, err := newPushConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteWriter{url: url, config: c, now: time.Now}, nil
+}
+
+// Write gathers once and sends every series
+func (w *RemoteWriter) Write(ctx context.Context) error {
+	families, err := w.config.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	series := toTimeSeries(families, w.config.externalLabels, w.now().UnixMilli())
+	for start := 0; start < len(series); start += w.config.batchSize {
+		end := start + w.config.batchSize
+		if end > len(series) {
+			end = len(series)
+		}
+		body := snappy.Encode(nil, encodeWriteRequest(series[start:end]))
+		err := w.config.send(ctx, func() *gohttp.Request {
+			req := w.config.client.NewRequest(gohttp.POST, w.url).RawBody(body)
+			for k, v := range w.config.header {
+				req.Header(k, v)
+			}
+			return req.Header("Content-Type", "application/x-protobuf").
+				Header("Content-Encoding", "snappy").
+				Header("X-Prometheus-Remote-Write-Version", "0.1.0")
+		})
+		if err != nil {
+			return fmt.Errorf("metrics: remote write of series %d-%d: %w", start, end-1, err)
+		}
+	}
+	return nil
+}
+
+// Run writes every interval until ctx is done, failed writes are returned on errs if not nil
+func (w *RemoteWriter) Run(ctx context.Context, interval time.Duration, errs chan<- error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Write(ctx); err != nil && errs != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+		}
+	}
+}
+
+type label struct {
+	name, value string
+}
+
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+// toTimeSeries flattens families the way the exposition format does: histograms
+// become _bucket, _sum and _count series and summaries quantile, _sum and _count series
+func toTimeSeries(families []*dto.MetricFamily, external map[string]string, now int64) []timeSeries {
+	var out []timeSeries
+	for _, family := range families {
+		name := family.GetName()
+		for _, m := range family.GetMetric() {
+			ts := now
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+			add := func(name string, value float64, extra ...label) {
+				labels := []label{{"__name__", name}}
+				for _, lp := range m.GetLabel() {
+					labels = append(labels, label{lp.GetName(), lp.GetValue()})
+				}
+				for k, v := range external {
+					labels = append(labels, label{k, v})
+				}
+				labels = append(labels, extra...)
+				sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+				out = append(out, timeSeries{labels: labels, samples: []sample{{value, ts}}})
+			}
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					add(name+"_bucket", float64(b.GetCumulativeCount()), label{"le", formatFloat(b.GetUpperBound())})
+				}
+				add(name+"_bucket", float64(h.GetSampleCount()), label{"le", "+Inf"})
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, q.GetValue(), label{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add(name+"_sum", s.GetSampleSum())
+				add(name+"_count", float64(s.GetSampleCount()))
+			}
+		}
+	}
+	return out
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// encodeWriteRequest encodes prometheus.WriteRequest:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []timeSeries) []byte {
+	var out []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, lb)
+		}
+		for _, smp := range s.samples {
+			var sb []byte
+			sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+			sb = protowire.AppendFixed64(sb, math.Float64bits(smp.value))
+			sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+			sb = protowire.AppendVarint(sb, uint64(smp.timestamp))
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, sb)
+		}
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		out = protowire.AppendBytes(out, ts)
+	}
+	return out
+}
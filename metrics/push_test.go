@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+func testRegistry(t *testing.T) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	jobs := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "jobs_total", Help: "Jobs."}, []string{"kind"})
+	jobs.WithLabelValues("batch").Add(3)
+	latency := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Help: "Latency.", Buckets: []float64{0.1, 1}})
+	latency.Observe(0.5)
+	reg.MustRegister(jobs, latency)
+	return reg
+}
+
+func TestPushgateway(t *testing.T) {
+	var mu sync.Mutex
+	var gotMethod, gotPath, gotBody string
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotBody = r.Method, r.URL.EscapedPath(), string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	pusher, err := NewPushgateway(server.URL+"/", "nightly",
+		WithGatherer(testRegistry(t)),
+		WithRetries(1, time.Millisecond),
+		WithPushClient(gohttp.NewClient(gohttp.WithLogger(gohttp.NopLogger))),
+	)
+	if err != nil {
+		t.Fatalf("NewPushgateway() error = %v", err)
+	}
+	if err := pusher.Grouping("instance", "a/b").Grouping("zone", "").Push(context.Background()); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if gotMethod != "PUT" || gotPath != "/metrics/job/nightly/instance@base64/YS9i/zone@base64/=" {
+		t.Errorf("Push() got = %v %v", gotMethod, gotPath)
+	}
+	if !strings.Contains(gotBody, `jobs_total{kind="batch"} 3`) {
+		t.Errorf("Push() body got = %v, want text exposition", gotBody)
+	}
+	if attempts != 2 {
+		t.Errorf("Push() attempts got = %v, want 2", attempts)
+	}
+}
+
+// decodeSeries reads a WriteRequest back into "labels value" lines
+func decodeSeries(t *testing.T, data []byte) []string {
+	var out []string
+	each := func(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, u uint64)) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			b = b[n:]
+			switch typ {
+			case protowire.BytesType:
+				v, n := protowire.ConsumeBytes(b)
+				fn(num, typ, v, 0)
+				b = b[n:]
+			case protowire.Fixed64Type:
+				u, n := protowire.ConsumeFixed64(b)
+				fn(num, typ, nil, u)
+				b = b[n:]
+			case protowire.VarintType:
+				u, n := protowire.ConsumeVarint(b)
+				fn(num, typ, nil, u)
+				b = b[n:]
+			default:
+				t.Fatalf("unexpected wire type %v", typ)
+			}
+		}
+	}
+	each(data, func(_ protowire.Number, _ protowire.Type, series []byte, _ uint64) {
+		var labels []string
+		var value float64
+		each(series, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+			if num == 1 {
+				var name, val string
+				each(v, func(num protowire.Number, _ protowire.Type, s []byte, _ uint64) {
+					if num == 1 {
+						name = string(s)
+					} else {
+						val = string(s)
+					}
+				})
+				labels = append(labels, name+"="+val)
+				return
+			}
+			each(v, func(num protowire.Number, _ protowire.Type, _ []byte, u uint64) {
+				if num == 1 {
+					value = math.Float64frombits(u)
+				}
+			})
+		})
+		out = append(out, strings.Join(labels, ",")+" "+formatFloat(value))
+	})
+	return out
+}
+
+func TestRemoteWriter(t *testing.T) {
+	var mu sync.Mutex
+	var series []string
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		data, err := snappy.Decode(nil, body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		requests++
+		series = append(series, decodeSeries(t, data)...)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	writer, err := NewRemoteWriter(server.URL, WithGatherer(testRegistry(t)), WithBatchSize(2), WithExternalLabels(map[string]string{"env": "test"}))
+	if err != nil {
+		t.Fatalf("NewRemoteWriter() error = %v", err)
+	}
+	if err := writer.Write(context.Background()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	sort.Strings(series)
+	want := []string{
+		"__name__=jobs_total,env=test,kind=batch 3",
+		"__name__=latency_seconds_bucket,env=test,le=+Inf 1",
+		"__name__=latency_seconds_bucket,env=test,le=0.1 0",
+		"__name__=latency_seconds_bucket,env=test,le=1 1",
+		"__name__=latency_seconds_count,env=test 1",
+		"__name__=latency_seconds_sum,env=test 0.5",
+	}
+	if strings.Join(series, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Write() series got = %v, want %v", series, want)
+	}
+	if requests != 3 {
+		t.Errorf("Write() requests got = %v, want 3 batches", requests)
+	}
+}
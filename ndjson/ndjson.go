@@ -0,0 +1,72 @@
+// Package ndjson streams rows to a client as newline delimited JSON, one
+// JSON value per line, flushed as the rows are produced.
+package ndjson
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ContentType of newline delimited JSON
+const ContentType = "application/x-ndjson"
+
+const maxBatch = 256
+
+// ErrNotFlusher is returned for a ResponseWriter that can't flush
+var ErrNotFlusher = errors.New("ndjson: response writer can't flush")
+
+// Write writes the rows received from rows to w until rows is closed, ctx is
+// done or writing fails, e.g. because the client went away. Rows arriving
+// together are flushed together, a row is never held back waiting for the
+// next one. The status is 200 unless the handler wrote one before.
+//
+// An error after the first row can't be reported with the status anymore,
+// producers can send a final row describing it instead.
+func Write[T any](ctx context.Context, w http.ResponseWriter, rows <-chan T) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return ErrNotFlusher
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", ContentType)
+	}
+	w.Header().Set("X-Accel-Buffering", "no")
+	// json.Encoder ends every value with a newline
+	encoder := json.NewEncoder(w)
+	flusher.Flush()
+	for {
+		var row T
+		var open bool
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case row, open = <-rows:
+		}
+		if !open {
+			return nil
+		}
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	batch:
+		// write what is ready before flushing, a busy producer is flushed
+		// every maxBatch rows
+		for i := 1; i < maxBatch; i++ {
+			select {
+			case row, open = <-rows:
+				if !open {
+					flusher.Flush()
+					return nil
+				}
+				if err := encoder.Encode(row); err != nil {
+					return err
+				}
+			default:
+				break batch
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,80 @@
+package ndjson
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type row struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestWrite(t *testing.T) {
+	next := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rows := make(chan row)
+		go func() {
+			defer close(rows)
+			for i := 1; i <= 3; i++ {
+				rows <- row{ID: i, Name: "n"}
+				// the client must see a row before the next one is produced
+				<-next
+			}
+		}()
+		if err := Write(r.Context(), w, rows); err != nil {
+			t.Errorf("Write() error = %v", err)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type got = %v, want %v", got, ContentType)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for i := 1; i <= 3; i++ {
+		if !scanner.Scan() {
+			t.Fatalf("Scan() row %v error = %v", i, scanner.Err())
+		}
+		var got row
+		if err := json.Unmarshal(scanner.Bytes(), &got); err != nil || got.ID != i {
+			t.Errorf("row got = %v %v, want id %v", got, err, i)
+		}
+		next <- struct{}{}
+	}
+	if scanner.Scan() {
+		t.Errorf("Scan() got = %q, want end of stream", scanner.Text())
+	}
+}
+
+func TestWriteCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := make(chan int)
+	done := make(chan error)
+	w := httptest.NewRecorder()
+	go func() { done <- Write(ctx, w, rows) }()
+	rows <- 1
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Write() error got = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Write() did not return after cancel")
+	}
+	if got := w.Body.String(); got != "1\n" {
+		t.Errorf("body got = %q, want %q", got, "1\n")
+	}
+}
@@ -0,0 +1,246 @@
+package openapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Stellar1999/gotool/convert"
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Params are the parameters of a call by name, wherever the operation takes
+// them: path, query, header or cookie
+type Params map[string]any
+
+type config struct {
+	client *gohttp.Client
+	server string
+	header map[string]string
+}
+
+type Option = opt.Option[config]
+
+// WithClient sends the calls with client instead of the default Client of
+// the http package
+func WithClient(client *gohttp.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithServer sends the calls to baseURL instead of the first server of the
+// document
+func WithServer(baseURL string) Option {
+	return func(c *config) {
+		c.server = baseURL
+	}
+}
+
+// WithHeader adds headers to every call, e.g. for authentication
+func WithHeader(header map[string]string) Option {
+	return func(c *config) {
+		c.header = header
+	}
+}
+
+// Client calls the operations of a document
+type Client struct {
+	spec *Spec
+	cfg  config
+}
+
+// New returns a Client for spec
+func New(spec *Spec, opts ...Option) (*Client, error) {
+	var cfg config
+	if err := opt.Build(&cfg, opts); err != nil {
+		return nil, err
+	}
+	if cfg.server == "" {
+		if len(spec.Servers) == 0 {
+			return nil, fmt.Errorf("%w: no servers, use WithServer", ErrSpec)
+		}
+		cfg.server = spec.Servers[0].URL
+	}
+	cfg.server = strings.TrimSuffix(cfg.server, "/")
+	return &Client{spec: spec, cfg: cfg}, nil
+}
+
+// Call sends the operation operationID with params and body, which may be
+// nil, and decodes a 2xx response into out, which may be nil too. Invalid
+// params or body fail with ValidationErrors before anything is sent, other
+// responses fail with a *gohttp.StatusError.
+func (c *Client) Call(ctx context.Context, operationID string, params Params, body any, out any) error {
+	op, ok := c.spec.operations[operationID]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownOperation, operationID)
+	}
+	v := &validator{spec: c.spec}
+	values, err := c.params(v, op, params)
+	if err != nil {
+		return err
+	}
+	var payload any
+	if body != nil {
+		if payload, err = generic(body); err != nil {
+			return fmt.Errorf("openapi: %s: encoding body: %w", operationID, err)
+		}
+	}
+	c.checkBody(v, op, body != nil, payload)
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+
+	var req *gohttp.Request
+	if c.cfg.client != nil {
+		req = c.cfg.client.NewRequest(gohttp.RequestMethodType(op.method), c.cfg.server+op.path)
+	} else {
+		req = gohttp.NewRequest(gohttp.RequestMethodType(op.method), c.cfg.server+op.path)
+	}
+	req.WithContext(ctx)
+	for k, v := range c.cfg.header {
+		req.Header(k, v)
+	}
+	var cookies []string
+	for _, p := range op.parameters {
+		list, ok := values[p.Name]
+		if !ok {
+			continue
+		}
+		switch p.In {
+		case "path":
+			req.PathParam(p.Name, strings.Join(list, ","))
+		case "query":
+			if explode(p) {
+				for _, s := range list {
+					req.Query(p.Name, s)
+				}
+			} else {
+				req.Query(p.Name, strings.Join(list, ","))
+			}
+		case "header":
+			req.Header(p.Name, strings.Join(list, ","))
+		case "cookie":
+			cookies = append(cookies, p.Name+"="+strings.Join(list, ","))
+		}
+	}
+	if len(cookies) > 0 {
+		req.Header("Cookie", strings.Join(cookies, "; "))
+	}
+	if body != nil {
+		req.Body(body)
+	}
+
+	resp, err := req.Send()
+	var statusErr *gohttp.StatusError
+	if errors.As(err, &statusErr) && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	if out == nil || len(resp.Body) == 0 || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return resp.Decode(out)
+}
+
+// params validates params against the parameters of op and formats their
+// values
+func (c *Client) params(v *validator, op *operationRef, params Params) (map[string][]string, error) {
+	known := map[string]bool{}
+	values := map[string][]string{}
+	for _, p := range op.parameters {
+		known[p.Name] = true
+		field := p.In + "." + p.Name
+		raw, ok := params[p.Name]
+		if !ok || raw == nil {
+			if p.Required || p.In == "path" {
+				v.fail(field, "is required")
+			}
+			continue
+		}
+		value, err := generic(raw)
+		if err != nil {
+			return nil, fmt.Errorf("openapi: parameter %s: %w", p.Name, err)
+		}
+		before := len(v.errs)
+		v.check(p.Schema, value, field)
+		if len(v.errs) > before {
+			continue
+		}
+		list, err := format(value)
+		if err != nil {
+			v.fail(field, "can't be sent: %v", err)
+			continue
+		}
+		values[p.Name] = list
+	}
+	var unknown []string
+	for name := range params {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		v.fail(name, "is not a parameter of the operation")
+	}
+	return values, nil
+}
+
+// checkBody validates the body against the JSON schema of the operation
+func (c *Client) checkBody(v *validator, op *operationRef, present bool, payload any) {
+	if op.body == nil {
+		if present {
+			v.fail("body", "is not taken by the operation")
+		}
+		return
+	}
+	if !present {
+		if op.body.Required {
+			v.fail("body", "is required")
+		}
+		return
+	}
+	for contentType, media := range op.body.Content {
+		if contentType == "application/json" || strings.HasSuffix(contentType, "+json") {
+			v.check(media.Schema, payload, "body")
+			return
+		}
+	}
+}
+
+// explode tells whether array query parameters repeat their name, the
+// default of the form style
+func explode(p *Parameter) bool {
+	if p.Explode != nil {
+		return *p.Explode
+	}
+	return p.Style == "" || p.Style == "form"
+}
+
+// format turns a generic JSON parameter value into its strings, one per item
+// of an array
+func format(value any) ([]string, error) {
+	items, ok := value.([]any)
+	if !ok {
+		items = []any{value}
+	}
+	out := make([]string, len(items))
+	for i, item := range items {
+		if _, nested := item.(map[string]any); nested {
+			return nil, errors.New("object values are not supported")
+		}
+		s, err := convert.ToString(item)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = s
+	}
+	return out, nil
+}
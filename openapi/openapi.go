@@ -0,0 +1,228 @@
+// Package openapi calls the operations of an OpenAPI 3 document loaded at
+// runtime, without generating code. Parameters and request bodies are
+// checked against the schemas of the document before anything is sent, and
+// the requests go through a gohttp.Client, its hooks and settings.
+//
+//	spec, err := openapi.Load("partner.yaml")
+//	c, err := openapi.New(spec, openapi.WithClient(client))
+//	var order Order
+//	err = c.Call(ctx, "getOrder", openapi.Params{"orderId": 42, "expand": []string{"items"}}, nil, &order)
+//
+// Schemas are checked for type, enum, format, bounds, lengths, pattern,
+// items, properties, required and additionalProperties, following $ref into
+// the components of the document, and allOf, anyOf and oneOf.
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrSpec is returned for documents that can't be used
+	ErrSpec = errors.New("openapi: invalid spec")
+	// ErrUnknownOperation is returned by Call for operation IDs missing from
+	// the document
+	ErrUnknownOperation = errors.New("openapi: unknown operation")
+)
+
+// Spec is the part of an OpenAPI 3 document needed to call its operations
+type Spec struct {
+	OpenAPI    string               `yaml:"openapi"`
+	Servers    []Server             `yaml:"servers"`
+	Paths      map[string]*PathItem `yaml:"paths"`
+	Components Components           `yaml:"components"`
+
+	operations map[string]*operationRef
+}
+
+// Server is a base URL of the API
+type Server struct {
+	URL string `yaml:"url"`
+}
+
+// PathItem holds the operations of a path
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Put        *Operation   `yaml:"put"`
+	Post       *Operation   `yaml:"post"`
+	Delete     *Operation   `yaml:"delete"`
+	Patch      *Operation   `yaml:"patch"`
+	Head       *Operation   `yaml:"head"`
+	Options    *Operation   `yaml:"options"`
+}
+
+// Operation is one method of a path
+type Operation struct {
+	OperationID string       `yaml:"operationId"`
+	Parameters  []*Parameter `yaml:"parameters"`
+	RequestBody *RequestBody `yaml:"requestBody"`
+}
+
+// Parameter is a path, query, header or cookie parameter
+type Parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+	Style    string  `yaml:"style"`
+	Explode  *bool   `yaml:"explode"`
+}
+
+// RequestBody is the body an operation takes
+type RequestBody struct {
+	Ref      string               `yaml:"$ref"`
+	Required bool                 `yaml:"required"`
+	Content  map[string]MediaType `yaml:"content"`
+}
+
+// MediaType is the schema of a body of one content type
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+// Components holds the definitions $ref points to
+type Components struct {
+	Schemas       map[string]*Schema      `yaml:"schemas"`
+	Parameters    map[string]*Parameter   `yaml:"parameters"`
+	RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+}
+
+// operationRef is an operation with its method, path and the parameters of
+// the path and the operation merged and resolved
+type operationRef struct {
+	method     string
+	path       string
+	parameters []*Parameter
+	body       *RequestBody
+}
+
+// Load reads a document from a YAML or JSON file
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse reads a YAML or JSON document
+func Parse(data []byte) (*Spec, error) {
+	var s Spec
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSpec, err)
+	}
+	if !strings.HasPrefix(s.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%w: version %q is not OpenAPI 3", ErrSpec, s.OpenAPI)
+	}
+	if err := s.index(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Operations returns the IDs of the operations of the document
+func (s *Spec) Operations() []string {
+	ids := make([]string, 0, len(s.operations))
+	for id := range s.operations {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// index maps the operation IDs to their operations, resolving references
+func (s *Spec) index() error {
+	s.operations = map[string]*operationRef{}
+	for path, item := range s.Paths {
+		if item == nil {
+			continue
+		}
+		for _, m := range []struct {
+			method string
+			op     *Operation
+		}{{"GET", item.Get}, {"PUT", item.Put}, {"POST", item.Post}, {"DELETE", item.Delete}, {"PATCH", item.Patch}, {"HEAD", item.Head}, {"OPTIONS", item.Options}} {
+			if m.op == nil || m.op.OperationID == "" {
+				continue
+			}
+			if _, dup := s.operations[m.op.OperationID]; dup {
+				return fmt.Errorf("%w: operationId %q is used twice", ErrSpec, m.op.OperationID)
+			}
+			ref := &operationRef{method: m.method, path: path}
+			// parameters of the operation override those of the path
+			byKey := map[string]int{}
+			for _, p := range append(append([]*Parameter{}, item.Parameters...), m.op.Parameters...) {
+				p, err := s.parameter(p)
+				if err != nil {
+					return fmt.Errorf("%w: %s: %v", ErrSpec, m.op.OperationID, err)
+				}
+				key := p.In + "/" + p.Name
+				if i, ok := byKey[key]; ok {
+					ref.parameters[i] = p
+					continue
+				}
+				byKey[key] = len(ref.parameters)
+				ref.parameters = append(ref.parameters, p)
+			}
+			if m.op.RequestBody != nil {
+				body, err := s.requestBody(m.op.RequestBody)
+				if err != nil {
+					return fmt.Errorf("%w: %s: %v", ErrSpec, m.op.OperationID, err)
+				}
+				ref.body = body
+			}
+			s.operations[m.op.OperationID] = ref
+		}
+	}
+	return nil
+}
+
+func (s *Spec) parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		if p.Name == "" || p.In == "" {
+			return nil, errors.New("parameter without name or in")
+		}
+		return p, nil
+	}
+	name, ok := cutRef(p.Ref, "#/components/parameters/")
+	if found := s.Components.Parameters[name]; ok && found != nil && found.Ref == "" {
+		return s.parameter(found)
+	}
+	return nil, fmt.Errorf("unresolved $ref %q", p.Ref)
+}
+
+func (s *Spec) requestBody(b *RequestBody) (*RequestBody, error) {
+	if b.Ref == "" {
+		return b, nil
+	}
+	name, ok := cutRef(b.Ref, "#/components/requestBodies/")
+	if found := s.Components.RequestBodies[name]; ok && found != nil && found.Ref == "" {
+		return found, nil
+	}
+	return nil, fmt.Errorf("unresolved $ref %q", b.Ref)
+}
+
+// schema follows the $ref of sch into the components
+func (s *Spec) schema(sch *Schema) (*Schema, error) {
+	for depth := 0; sch != nil && sch.Ref != ""; depth++ {
+		name, ok := cutRef(sch.Ref, "#/components/schemas/")
+		next := s.Components.Schemas[name]
+		if !ok || next == nil || depth > 32 {
+			return nil, fmt.Errorf("%w: unresolved $ref %q", ErrSpec, sch.Ref)
+		}
+		sch = next
+	}
+	return sch, nil
+}
+
+func cutRef(ref string, prefix string) (string, bool) {
+	if !strings.HasPrefix(ref, prefix) {
+		return "", false
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(ref[len(prefix):], "~1", "/"), "~0", "~"), true
+}
@@ -0,0 +1,232 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+type recorded struct {
+	method string
+	url    string
+	header http.Header
+	body   string
+}
+
+func newClient(t *testing.T) (*Client, *recorded) {
+	rec := &recorded{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*rec = recorded{method: r.Method, url: r.URL.RequestURI(), header: r.Header, body: string(body)}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/orders/404":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"id":3}`)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			_, _ = io.WriteString(w, `{"id":42,"status":"open"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	spec, err := Load("testdata/orders.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	c, err := New(spec, WithClient(gohttp.NewClient()), WithServer(server.URL+"/v1/"), WithHeader(map[string]string{"Authorization": "Bearer t"}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return c, rec
+}
+
+func TestLoad(t *testing.T) {
+	spec, err := Load("testdata/orders.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	ops := spec.Operations()
+	sort.Strings(ops)
+	if want := []string{"createOrder", "deleteOrder", "getOrder", "listOrders"}; !reflect.DeepEqual(ops, want) {
+		t.Errorf("Operations() got = %v, want %v", ops, want)
+	}
+	if c, err := New(spec); err != nil || c.cfg.server != "https://orders.example.com/v1" {
+		t.Errorf("New() got server %v %v, want the first server of the spec", c, err)
+	}
+
+	bad := []string{
+		`swagger: "2.0"`,
+		`{"openapi": "3.0.0", "paths": {"/a": {"get": {"operationId": "x", "parameters": [{"$ref": "#/components/parameters/Missing"}]}}}}`,
+		`{"openapi": "3.0.0", "paths": {"/a": {"get": {"operationId": "x"}}, "/b": {"get": {"operationId": "x"}}}}`,
+	}
+	for _, doc := range bad {
+		if _, err := Parse([]byte(doc)); !errors.Is(err, ErrSpec) {
+			t.Errorf("Parse(%s) got = %v, want ErrSpec", doc, err)
+		}
+	}
+	if _, err := New(&Spec{}); !errors.Is(err, ErrSpec) {
+		t.Errorf("New() without servers got = %v, want ErrSpec", err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	c, rec := newClient(t)
+	ctx := context.Background()
+
+	var order struct {
+		ID     int    `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := c.Call(ctx, "getOrder", Params{"orderId": 42, "expand": []string{"items", "customer"}}, nil, &order); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if order.ID != 42 || rec.method != http.MethodGet || rec.url != "/v1/orders/42?expand=items%2Ccustomer" {
+		t.Errorf("Call() got = %+v, sent %v %v", order, rec.method, rec.url)
+	}
+	if rec.header.Get("Authorization") != "Bearer t" {
+		t.Errorf("Call() header got = %v", rec.header)
+	}
+
+	if err := c.Call(ctx, "listOrders", Params{"limit": 10, "status": []string{"open", "shipped"}, "X-Tenant": "acme"}, nil, nil); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if rec.url != "/v1/orders?limit=10&status=open&status=shipped" || rec.header.Get("X-Tenant") != "acme" {
+		t.Errorf("Call() sent %v %v", rec.url, rec.header)
+	}
+
+	body := map[string]any{"customer": "ada@example.com", "items": []map[string]any{{"sku": "ABC-1", "quantity": 2}}}
+	var created map[string]any
+	if err := c.Call(ctx, "createOrder", nil, body, &created); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	var sent map[string]any
+	if json.Unmarshal([]byte(rec.body), &sent) != nil || sent["customer"] != "ada@example.com" || created["id"] != 3.0 {
+		t.Errorf("Call() sent %q, got %v", rec.body, created)
+	}
+
+	if err := c.Call(ctx, "deleteOrder", Params{"orderId": 5}, nil, &created); err != nil || rec.method != http.MethodDelete {
+		t.Errorf("Call() delete got = %v %v", err, rec.method)
+	}
+
+	var statusErr *gohttp.StatusError
+	if err := c.Call(ctx, "getOrder", Params{"orderId": 404}, nil, nil); !errors.As(err, &statusErr) || statusErr.Code != http.StatusNotFound {
+		t.Errorf("Call() got = %v, want a *StatusError", err)
+	}
+	if err := c.Call(ctx, "missing", nil, nil, nil); !errors.Is(err, ErrUnknownOperation) {
+		t.Errorf("Call() got = %v, want ErrUnknownOperation", err)
+	}
+}
+
+func TestCallValidation(t *testing.T) {
+	c, rec := newClient(t)
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		op     string
+		params Params
+		body   any
+		want   map[string][]string
+	}{
+		{"path missing", "getOrder", nil, nil, map[string][]string{"path.orderId": {"is required"}}},
+		{"path bound", "getOrder", Params{"orderId": 0}, nil, map[string][]string{"path.orderId": {"must be at least 1"}}},
+		{"path type", "getOrder", Params{"orderId": "abc"}, nil, map[string][]string{"path.orderId": {"must be of type integer"}}},
+		{"unknown and header", "listOrders", Params{"limit": 500, "page": 2}, nil, map[string][]string{
+			"query.limit":     {"must be at most 100"},
+			"header.X-Tenant": {"is required"},
+			"page":            {"is not a parameter of the operation"},
+		}},
+		{"enum item", "listOrders", Params{"X-Tenant": "a", "status": []string{"lost"}}, nil, map[string][]string{"query.status[0]": {`must be one of "open", "shipped"`}}},
+		{"body required", "createOrder", nil, nil, map[string][]string{"body": {"is required"}}},
+		{"body not taken", "deleteOrder", Params{"orderId": 1}, map[string]any{}, map[string][]string{"body": {"is not taken by the operation"}}},
+		{"body schema", "createOrder", nil, map[string]any{
+			"customer": "not an email",
+			"note":     "far too long a note",
+			"items":    []map[string]any{{"sku": "abc", "quantity": 0}, {"quantity": 1.5}},
+			"extra":    true,
+		}, map[string][]string{
+			"body.customer":          {"must be a valid email"},
+			"body.note":              {"must be at most 10 characters"},
+			"body.items[0].sku":      {"must match ^[A-Z]{3}-[0-9]+$"},
+			"body.items[0].quantity": {"must be greater than 0"},
+			"body.items[1].sku":      {"is required"},
+			"body.items[1].quantity": {"must be of type integer"},
+			"body.extra":             {"is not allowed"},
+		}},
+	}
+	for _, tt := range tests {
+		rec.method = ""
+		err := c.Call(ctx, tt.op, tt.params, tt.body, nil)
+		var errs ValidationErrors
+		if !errors.As(err, &errs) {
+			t.Errorf("Call() %s got = %v, want ValidationErrors", tt.name, err)
+			continue
+		}
+		if got := errs.Fields(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Call() %s got = %v, want %v", tt.name, got, tt.want)
+		}
+		if rec.method != "" {
+			t.Errorf("Call() %s sent a request despite invalid input", tt.name)
+		}
+	}
+
+	nullNote := map[string]any{"customer": "a@b.co", "note": nil, "items": []map[string]any{{"sku": "ABC-1", "quantity": 1}}}
+	if err := c.Call(ctx, "createOrder", nil, nullNote, nil); err != nil {
+		t.Errorf("Call() with a nullable null got = %v", err)
+	}
+}
+
+func TestSchemaCombinators(t *testing.T) {
+	spec, err := Parse([]byte(`
+openapi: 3.1.0
+paths: {}
+components:
+  schemas:
+    Id:
+      oneOf:
+        - type: integer
+        - type: string
+          format: uuid
+    Any:
+      anyOf:
+        - type: string
+          minLength: 3
+        - type: [number, "null"]
+          exclusiveMaximum: 10
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		schema string
+		value  any
+		ok     bool
+	}{
+		{"Id", 7.0, true},
+		{"Id", "0b8e4a4c-1c58-4f0e-9a46-9d1f1f4b7c2e", true},
+		{"Id", "nope", false},
+		{"Id", 1.5, false},
+		{"Any", "abc", true},
+		{"Any", nil, true},
+		{"Any", 9.0, true},
+		{"Any", 10.0, false},
+		{"Any", "ab", false},
+	}
+	for _, tt := range tests {
+		v := &validator{spec: spec}
+		v.check(&Schema{Ref: "#/components/schemas/" + tt.schema}, tt.value, "v")
+		if ok := len(v.errs) == 0; ok != tt.ok {
+			t.Errorf("check(%s, %v) got = %v, want ok %v", tt.schema, tt.value, v.errs, tt.ok)
+		}
+	}
+}
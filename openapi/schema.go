@@ -0,0 +1,424 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the subset of JSON Schema OpenAPI uses for values
+type Schema struct {
+	Ref              string             `yaml:"$ref"`
+	Type             Types              `yaml:"type"`
+	Format           string             `yaml:"format"`
+	Enum             []any              `yaml:"enum"`
+	Nullable         bool               `yaml:"nullable"`
+	Minimum          *float64           `yaml:"minimum"`
+	Maximum          *float64           `yaml:"maximum"`
+	ExclusiveMinimum Bound              `yaml:"exclusiveMinimum"`
+	ExclusiveMaximum Bound              `yaml:"exclusiveMaximum"`
+	MultipleOf       float64            `yaml:"multipleOf"`
+	MinLength        *int               `yaml:"minLength"`
+	MaxLength        *int               `yaml:"maxLength"`
+	Pattern          string             `yaml:"pattern"`
+	MinItems         *int               `yaml:"minItems"`
+	MaxItems         *int               `yaml:"maxItems"`
+	UniqueItems      bool               `yaml:"uniqueItems"`
+	Items            *Schema            `yaml:"items"`
+	Properties       map[string]*Schema `yaml:"properties"`
+	Required         []string           `yaml:"required"`
+	// AdditionalProperties is nil when any are allowed, a schema with
+	// Forbidden set for additionalProperties: false
+	AdditionalProperties *Schema   `yaml:"additionalProperties"`
+	AllOf                []*Schema `yaml:"allOf"`
+	AnyOf                []*Schema `yaml:"anyOf"`
+	OneOf                []*Schema `yaml:"oneOf"`
+
+	// Forbidden is set by additionalProperties: false
+	Forbidden bool `yaml:"-"`
+}
+
+// UnmarshalYAML reads true and false as schemas
+func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!bool" {
+		var allowed bool
+		if err := node.Decode(&allowed); err != nil {
+			return err
+		}
+		*s = Schema{Forbidden: !allowed}
+		return nil
+	}
+	type plain Schema
+	return node.Decode((*plain)(s))
+}
+
+// Types is the type of a schema, a list of types in OpenAPI 3.1
+type Types []string
+
+// UnmarshalYAML reads a single type or a list
+func (t *Types) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = Types{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// Bound is exclusiveMinimum or exclusiveMaximum: a flag on minimum or
+// maximum in OpenAPI 3.0, a number of its own in 3.1
+type Bound struct {
+	Flag  bool
+	Value *float64
+}
+
+// UnmarshalYAML reads a flag or a number
+func (b *Bound) UnmarshalYAML(node *yaml.Node) error {
+	if node.Tag == "!!bool" {
+		return node.Decode(&b.Flag)
+	}
+	var v float64
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	b.Value = &v
+	return nil
+}
+
+// ValidationError is a value breaking its schema
+type ValidationError struct {
+	// Field is the path of the value, e.g. "body.items[2].name" or "query.limit"
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationErrors are the problems of the parameters and body of a call
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "openapi: " + strings.Join(msgs, "; ")
+}
+
+// Fields maps each failing field to its messages
+func (e ValidationErrors) Fields() map[string][]string {
+	out := make(map[string][]string, len(e))
+	for _, err := range e {
+		out[err.Field] = append(out[err.Field], err.Message)
+	}
+	return out
+}
+
+// validator checks generic JSON values, as decoded into any, against the
+// schemas of a spec
+type validator struct {
+	spec *Spec
+	errs ValidationErrors
+}
+
+func (v *validator) fail(field string, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// check validates value at field against sch
+func (v *validator) check(sch *Schema, value any, field string) {
+	sch, err := v.spec.schema(sch)
+	if err != nil {
+		v.fail(field, "has a schema that %v", err)
+		return
+	}
+	if sch == nil {
+		return
+	}
+	if sch.Forbidden {
+		v.fail(field, "is not allowed")
+		return
+	}
+	for _, sub := range sch.AllOf {
+		v.check(sub, value, field)
+	}
+	if len(sch.AnyOf) > 0 && v.matching(sch.AnyOf, value, field) == 0 {
+		v.fail(field, "matches none of anyOf")
+	}
+	if len(sch.OneOf) > 0 {
+		if n := v.matching(sch.OneOf, value, field); n != 1 {
+			v.fail(field, "matches %d of oneOf, want 1", n)
+		}
+	}
+
+	if value == nil {
+		if len(sch.Type) > 0 && !sch.Nullable && !sch.Type.has("null") {
+			v.fail(field, "must not be null")
+		}
+		return
+	}
+	if len(sch.Type) > 0 && !sch.Type.accepts(value) {
+		v.fail(field, "must be of type %s", strings.Join(sch.Type, " or "))
+		return
+	}
+	if len(sch.Enum) > 0 && !inEnum(sch.Enum, value) {
+		v.fail(field, "must be one of %s", enumString(sch.Enum))
+	}
+
+	switch val := value.(type) {
+	case float64:
+		v.checkNumber(sch, val, field)
+	case string:
+		v.checkString(sch, val, field)
+	case []any:
+		v.checkArray(sch, val, field)
+	case map[string]any:
+		v.checkObject(sch, val, field)
+	}
+}
+
+// matching counts the schemas value satisfies
+func (v *validator) matching(schemas []*Schema, value any, field string) int {
+	n := 0
+	for _, sub := range schemas {
+		trial := &validator{spec: v.spec}
+		trial.check(sub, value, field)
+		if len(trial.errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (v *validator) checkNumber(sch *Schema, n float64, field string) {
+	if sch.Minimum != nil {
+		if sch.ExclusiveMinimum.Flag && n <= *sch.Minimum {
+			v.fail(field, "must be greater than %v", *sch.Minimum)
+		} else if n < *sch.Minimum {
+			v.fail(field, "must be at least %v", *sch.Minimum)
+		}
+	}
+	if sch.ExclusiveMinimum.Value != nil && n <= *sch.ExclusiveMinimum.Value {
+		v.fail(field, "must be greater than %v", *sch.ExclusiveMinimum.Value)
+	}
+	if sch.Maximum != nil {
+		if sch.ExclusiveMaximum.Flag && n >= *sch.Maximum {
+			v.fail(field, "must be less than %v", *sch.Maximum)
+		} else if n > *sch.Maximum {
+			v.fail(field, "must be at most %v", *sch.Maximum)
+		}
+	}
+	if sch.ExclusiveMaximum.Value != nil && n >= *sch.ExclusiveMaximum.Value {
+		v.fail(field, "must be less than %v", *sch.ExclusiveMaximum.Value)
+	}
+	if sch.MultipleOf > 0 {
+		if q := n / sch.MultipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			v.fail(field, "must be a multiple of %v", sch.MultipleOf)
+		}
+	}
+	if (sch.Format == "int32" || sch.Format == "int64") && n != math.Trunc(n) {
+		v.fail(field, "must be an integer")
+	}
+	if sch.Format == "int32" && (n < math.MinInt32 || n > math.MaxInt32) {
+		v.fail(field, "must fit in int32")
+	}
+}
+
+var patterns sync.Map // string to *regexp.Regexp
+
+func (v *validator) checkString(sch *Schema, s string, field string) {
+	length := len([]rune(s))
+	if sch.MinLength != nil && length < *sch.MinLength {
+		v.fail(field, "must be at least %d characters", *sch.MinLength)
+	}
+	if sch.MaxLength != nil && length > *sch.MaxLength {
+		v.fail(field, "must be at most %d characters", *sch.MaxLength)
+	}
+	if sch.Pattern != "" {
+		re, ok := patterns.Load(sch.Pattern)
+		if !ok {
+			compiled, err := regexp.Compile(sch.Pattern)
+			if err != nil {
+				v.fail(field, "has an invalid pattern: %v", err)
+				return
+			}
+			re, _ = patterns.LoadOrStore(sch.Pattern, compiled)
+		}
+		if !re.(*regexp.Regexp).MatchString(s) {
+			v.fail(field, "must match %s", sch.Pattern)
+		}
+	}
+	if check, ok := formats[sch.Format]; ok && !check(s) {
+		v.fail(field, "must be a valid %s", sch.Format)
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// formats are the string formats checked, others are accepted as is
+var formats = map[string]func(string) bool{
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	},
+	"date": func(s string) bool {
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	},
+	"email": func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	},
+	"uuid": uuidPattern.MatchString,
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	},
+}
+
+func (v *validator) checkArray(sch *Schema, items []any, field string) {
+	if sch.MinItems != nil && len(items) < *sch.MinItems {
+		v.fail(field, "must have at least %d items", *sch.MinItems)
+	}
+	if sch.MaxItems != nil && len(items) > *sch.MaxItems {
+		v.fail(field, "must have at most %d items", *sch.MaxItems)
+	}
+	if sch.UniqueItems {
+		seen := map[string]bool{}
+		for _, item := range items {
+			key := canonical(item)
+			if seen[key] {
+				v.fail(field, "must have unique items")
+				break
+			}
+			seen[key] = true
+		}
+	}
+	if sch.Items != nil {
+		for i, item := range items {
+			v.check(sch.Items, item, field+"["+strconv.Itoa(i)+"]")
+		}
+	}
+}
+
+func (v *validator) checkObject(sch *Schema, obj map[string]any, field string) {
+	for _, name := range sch.Required {
+		if _, ok := obj[name]; !ok {
+			v.fail(join(field, name), "is required")
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if prop, ok := sch.Properties[k]; ok {
+			v.check(prop, obj[k], join(field, k))
+		} else if sch.AdditionalProperties != nil {
+			v.check(sch.AdditionalProperties, obj[k], join(field, k))
+		}
+	}
+}
+
+func join(field string, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func (t Types) has(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// accepts reports whether the generic JSON value has one of the types
+func (t Types) accepts(value any) bool {
+	for _, typ := range t {
+		switch value := value.(type) {
+		case string:
+			if typ == "string" {
+				return true
+			}
+		case bool:
+			if typ == "boolean" {
+				return true
+			}
+		case float64:
+			if typ == "number" || typ == "integer" && value == math.Trunc(value) {
+				return true
+			}
+		case []any:
+			if typ == "array" {
+				return true
+			}
+		case map[string]any:
+			if typ == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inEnum(enum []any, value any) bool {
+	key := canonical(value)
+	for _, e := range enum {
+		if canonical(e) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func enumString(enum []any) string {
+	parts := make([]string, len(enum))
+	for i, e := range enum {
+		parts[i] = canonical(e)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// canonical is the JSON of v, so values decoded from YAML and JSON compare
+// equal
+func canonical(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// generic turns a Go value into its generic JSON form
+func generic(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
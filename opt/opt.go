@@ -0,0 +1,111 @@
+package opt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Option configures a T, packages alias it for their config type:
+//
+//	type Option = opt.Option[Client]
+type Option[T any] func(*T)
+
+// Check validates a configuration once every option is applied, typically
+// rejecting options that do not combine
+type Check[T any] func(*T) error
+
+// Apply applies opts in order to target, nil options are skipped
+func Apply[T any](target *T, opts ...Option[T]) *T {
+	for _, o := range opts {
+		if o != nil {
+			o(target)
+		}
+	}
+	return target
+}
+
+// Build applies opts to target and runs the checks, all failing checks are reported
+func Build[T any](target *T, opts []Option[T], checks ...Check[T]) error {
+	Apply(target, opts...)
+	var msgs []string
+	var first error
+	for _, check := range checks {
+		if err := check(target); err != nil {
+			if first == nil {
+				first = err
+			}
+			msgs = append(msgs, err.Error())
+		}
+	}
+	switch len(msgs) {
+	case 0:
+		return nil
+	case 1:
+		return first
+	}
+	return &Error{Errs: msgs, first: first}
+}
+
+// Error holds every failing check of Build, it unwraps to the first one
+type Error struct {
+	Errs  []string
+	first error
+}
+
+func (e *Error) Error() string {
+	msg := "invalid options:"
+	for _, err := range e.Errs {
+		msg += " " + err + ";"
+	}
+	return msg[:len(msg)-1]
+}
+
+func (e *Error) Unwrap() error {
+	return e.first
+}
+
+// Combine bundles several options into one, e.g. for presets
+func Combine[T any](opts ...Option[T]) Option[T] {
+	return func(t *T) {
+		Apply(t, opts...)
+	}
+}
+
+// When applies o only if cond holds
+func When[T any](cond bool, o Option[T]) Option[T] {
+	if !cond {
+		return nil
+	}
+	return o
+}
+
+// ErrConflict is wrapped by the checks of Exclusive
+var ErrConflict = errors.New("conflicting options")
+
+// Exclusive returns a check failing when more than one of the named settings is on
+func Exclusive[T any](settings map[string]func(*T) bool) Check[T] {
+	return func(t *T) error {
+		var on []string
+		for name, isSet := range settings {
+			if isSet(t) {
+				on = append(on, name)
+			}
+		}
+		if len(on) > 1 {
+			sort.Strings(on)
+			return fmt.Errorf("%w: %v", ErrConflict, on)
+		}
+		return nil
+	}
+}
+
+// Requires returns a check failing when a setting is on without the one it depends on
+func Requires[T any](name string, isSet func(*T) bool, dependency string, dependencySet func(*T) bool) Check[T] {
+	return func(t *T) error {
+		if isSet(t) && !dependencySet(t) {
+			return fmt.Errorf("%w: %s requires %s", ErrConflict, name, dependency)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package opt
+
+import (
+	"errors"
+	"testing"
+)
+
+type config struct {
+	name    string
+	retries int
+	tls     bool
+	socket  string
+	verify  bool
+}
+
+func withName(name string) Option[config] {
+	return func(c *config) { c.name = name }
+}
+
+func withRetries(n int) Option[config] {
+	return func(c *config) { c.retries = n }
+}
+
+func withTLS() Option[config] {
+	return func(c *config) { c.tls = true }
+}
+
+func withSocket(path string) Option[config] {
+	return func(c *config) { c.socket = path }
+}
+
+func withVerify() Option[config] {
+	return func(c *config) { c.verify = true }
+}
+
+func TestApply(t *testing.T) {
+	preset := Combine(withName("preset"), withRetries(3))
+	tests := []struct {
+		name string
+		opts []Option[config]
+		want config
+	}{
+		{name: "defaults", want: config{name: "default", retries: 1}},
+		{name: "in order", opts: []Option[config]{withRetries(2), withRetries(5)}, want: config{name: "default", retries: 5}},
+		{name: "combine", opts: []Option[config]{preset, withTLS()}, want: config{name: "preset", retries: 3, tls: true}},
+		{name: "when", opts: []Option[config]{When(false, withTLS()), When(true, withName("x"))}, want: config{name: "x", retries: 1}},
+		{name: "nil skipped", opts: []Option[config]{nil}, want: config{name: "default", retries: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Apply(&config{name: "default", retries: 1}, tt.opts...)
+			if *got != tt.want {
+				t.Errorf("Apply() got = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	checks := []Check[config]{
+		Exclusive(map[string]func(*config) bool{
+			"tls":    func(c *config) bool { return c.tls },
+			"socket": func(c *config) bool { return c.socket != "" },
+		}),
+		Requires("verify", func(c *config) bool { return c.verify }, "tls", func(c *config) bool { return c.tls }),
+		func(c *config) error {
+			if c.retries < 0 {
+				return errors.New("retries must not be negative")
+			}
+			return nil
+		},
+	}
+	tests := []struct {
+		name    string
+		opts    []Option[config]
+		wantErr string
+	}{
+		{name: "valid", opts: []Option[config]{withTLS(), withVerify()}},
+		{name: "exclusive", opts: []Option[config]{withTLS(), withSocket("/run/a.sock")}, wantErr: "conflicting options: [socket tls]"},
+		{name: "requires", opts: []Option[config]{withVerify()}, wantErr: "conflicting options: verify requires tls"},
+		{
+			name:    "several",
+			opts:    []Option[config]{withVerify(), withRetries(-1)},
+			wantErr: "invalid options: conflicting options: verify requires tls; retries must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Build(&config{}, tt.opts, checks...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Build() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Build() error got = %v, want %v", err, tt.wantErr)
+			}
+			if !errors.Is(err, ErrConflict) {
+				t.Errorf("Build() error got = %v, want to wrap ErrConflict", err)
+			}
+		})
+	}
+}
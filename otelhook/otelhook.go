@@ -0,0 +1,105 @@
+package otelhook
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/Stellar1999/gotool/otelhook"
+
+// Hook implements the http package Hook interface, it starts a client span
+// in Before, injects the trace context into the request headers and ends
+// the span in After.
+type Hook struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+type Option func(*config)
+
+type config struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracerProvider sets the provider spans are created from, the global one by default
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithPropagator sets the propagator used to inject headers, W3C trace context by default
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// New creates a Hook, register it with http.AddHook
+func New(opts ...Option) *Hook {
+	c := config{
+		provider:   otel.GetTracerProvider(),
+		propagator: propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &Hook{
+		tracer:     c.provider.Tracer(instrumentationName),
+		propagator: c.propagator,
+	}
+}
+
+func (h *Hook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPMethod(req.Method),
+		semconv.HTTPURL(redactedURL(req)),
+		semconv.NetPeerName(req.URL.Hostname()),
+	}
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		attrs = append(attrs, semconv.NetPeerPort(port))
+	}
+	ctx, _ = h.tracer.Start(ctx, "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	h.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return ctx, nil
+}
+
+func (h *Hook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	span := trace.SpanFromContext(ctx)
+	if respCode > 0 {
+		span.SetAttributes(semconv.HTTPStatusCode(respCode))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if respCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(respCode))
+	}
+	span.End()
+	return ctx, nil
+}
+
+// redactedURL drops user credentials from the url recorded on the span
+func redactedURL(req *http.Request) string {
+	if req.URL.User == nil {
+		return req.URL.String()
+	}
+	u := *req.URL
+	u.User = nil
+	return u.String()
+}
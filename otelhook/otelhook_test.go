@@ -0,0 +1,105 @@
+package otelhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHook(t *testing.T) {
+	var gotTraceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTraceparent = r.Header.Get("traceparent")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	gohttp.AddHook(New(WithTracerProvider(provider)))
+
+	tests := []struct {
+		name           string
+		url            string
+		wantStatusCode int64
+		wantStatus     codes.Code
+	}{
+		{name: "ok", url: server.URL + "/ok", wantStatusCode: 200, wantStatus: codes.Unset},
+		{name: "not found", url: server.URL + "/missing", wantStatusCode: 404, wantStatus: codes.Error},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, _ = gohttp.Get(tt.url, nil, nil)
+			spans := recorder.Ended()
+			if len(spans) != i+1 {
+				t.Fatalf("ended spans got = %d, want %d", len(spans), i+1)
+			}
+			span := spans[i]
+			attrs := make(map[attribute.Key]attribute.Value)
+			for _, kv := range span.Attributes() {
+				attrs[kv.Key] = kv.Value
+			}
+			if got := attrs["http.method"].AsString(); got != "GET" {
+				t.Errorf("http.method got = %v, want GET", got)
+			}
+			if got := attrs["http.url"].AsString(); got != tt.url {
+				t.Errorf("http.url got = %v, want %v", got, tt.url)
+			}
+			if got := attrs["http.status_code"].AsInt64(); got != tt.wantStatusCode {
+				t.Errorf("http.status_code got = %v, want %v", got, tt.wantStatusCode)
+			}
+			if got := span.Status().Code; got != tt.wantStatus {
+				t.Errorf("status got = %v, want %v", got, tt.wantStatus)
+			}
+			if !strings.Contains(gotTraceparent, span.SpanContext().TraceID().String()) {
+				t.Errorf("traceparent got = %v, want trace id %v", gotTraceparent, span.SpanContext().TraceID())
+			}
+		})
+	}
+}
+
+// failingHook fails every request in Before
+type failingHook struct{}
+
+func (failingHook) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	return ctx, errors.New("rejected")
+}
+
+func (failingHook) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	return ctx, nil
+}
+
+func TestHookLaterHookFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel := gohttp.AddHook(New(WithTracerProvider(provider)))
+	defer otel.Remove()
+	failing := gohttp.AddHook(failingHook{})
+	defer failing.Remove()
+
+	if _, _, _, err := gohttp.Get(server.URL, nil, nil); err == nil {
+		t.Fatal("Get() error = nil, want the hook error")
+	}
+	if started, ended := len(recorder.Started()), len(recorder.Ended()); started != 1 || ended != 1 {
+		t.Errorf("spans got = %d started, %d ended, want 1 and 1", started, ended)
+	}
+	if spans := recorder.Ended(); len(spans) == 1 && spans[0].Status().Code != codes.Error {
+		t.Errorf("status got = %v, want %v", spans[0].Status().Code, codes.Error)
+	}
+}
@@ -0,0 +1,99 @@
+// Package paysign builds and verifies the signatures of WeChat Pay API v3 and
+// Alipay (RSA2) requests, responses and callback notifications.
+package paysign
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSignature is returned when a signature does not verify
+var ErrSignature = errors.New("paysign: signature mismatch")
+
+// ParsePrivateKey reads a PEM encoded PKCS#1 or PKCS#8 RSA private key. A bare
+// base64 key, as shown in the Alipay console, is accepted as well.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	der, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("paysign: parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("paysign: %T is not an RSA private key", parsed)
+	}
+	return key, nil
+}
+
+// ParsePublicKey reads a PEM or bare base64 PKIX RSA public key
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	der, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("paysign: parse public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("paysign: %T is not an RSA public key", parsed)
+	}
+	return key, nil
+}
+
+// ParseCertificate reads a PEM encoded certificate
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	der, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func decodePEM(data []byte) ([]byte, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		return block.Bytes, nil
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.New("paysign: key is neither PEM nor base64")
+	}
+	return der, nil
+}
+
+// signSHA256 returns the base64 RSA PKCS#1 v1.5 SHA-256 signature of message
+func signSHA256(key *rsa.PrivateKey, message []byte) (string, error) {
+	sum := sha256.Sum256(message)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+func verifySHA256(key *rsa.PublicKey, message []byte, signature string) error {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature is not base64", ErrSignature)
+	}
+	sum := sha256.Sum256(message)
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], raw); err != nil {
+		return ErrSignature
+	}
+	return nil
+}
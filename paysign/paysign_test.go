@@ -0,0 +1,236 @@
+package paysign
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	gourl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const apiV3Key = "0123456789abcdef0123456789abcdef"
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return key
+}
+
+func newCertificate(t *testing.T, key *rsa.PrivateKey, serial int64) (*x509.Certificate, []byte) {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "Tenpay.com Root CA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encrypt(t *testing.T, plaintext []byte) Resource {
+	t.Helper()
+	block, _ := aes.NewCipher([]byte(apiV3Key))
+	gcm, _ := cipher.NewGCMWithNonceSize(block, 12)
+	nonce, data := "abcdefghijkl", "transaction"
+	return Resource{
+		Algorithm:      "AEAD_AES_256_GCM",
+		Ciphertext:     base64.StdEncoding.EncodeToString(gcm.Seal(nil, []byte(nonce), plaintext, []byte(data))),
+		AssociatedData: data,
+		Nonce:          nonce,
+	}
+}
+
+// platformHeader signs body like the WeChat Pay platform
+func platformHeader(t *testing.T, key *rsa.PrivateKey, serial string, at time.Time, body []byte) http.Header {
+	t.Helper()
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	signature, err := signSHA256(key, []byte(timestamp+"\nNONCE\n"+string(body)+"\n"))
+	if err != nil {
+		t.Fatalf("signSHA256() error = %v", err)
+	}
+	header := make(http.Header)
+	header.Set("Wechatpay-Timestamp", timestamp)
+	header.Set("Wechatpay-Nonce", "NONCE")
+	header.Set("Wechatpay-Signature", signature)
+	header.Set("Wechatpay-Serial", serial)
+	return header
+}
+
+func TestParseKeys(t *testing.T) {
+	key := newKey(t)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+	pub, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "pkcs1 pem", data: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})},
+		{name: "pkcs8 pem", data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})},
+		{name: "pkcs8 base64", data: []byte(base64.StdEncoding.EncodeToString(pkcs8))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePrivateKey(tt.data)
+			if err != nil || !got.Equal(key) {
+				t.Errorf("ParsePrivateKey() got = %v, want the key", err)
+			}
+		})
+	}
+	if got, err := ParsePublicKey([]byte(base64.StdEncoding.EncodeToString(pub))); err != nil || !got.Equal(&key.PublicKey) {
+		t.Errorf("ParsePublicKey() got = %v, want the key", err)
+	}
+}
+
+func TestAlipay(t *testing.T) {
+	appKey, platformKey := newKey(t), newKey(t)
+	app := &Alipay{PrivateKey: appKey, PublicKey: &platformKey.PublicKey}
+	platform := &Alipay{PrivateKey: platformKey, PublicKey: &appKey.PublicKey}
+
+	params := gourl.Values{"app_id": {"2021"}, "method": {"alipay.trade.query"}, "biz_content": {`{"out_trade_no":"1"}`}, "empty": {""}}
+	if err := app.Sign(params); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	want := `app_id=2021&biz_content={"out_trade_no":"1"}&method=alipay.trade.query&sign_type=RSA2`
+	if got := app.Content(params, true); got != want {
+		t.Errorf("Content() got = %v, want %v", got, want)
+	}
+	if err := verifySHA256(&appKey.PublicKey, []byte(want), params.Get("sign")); err != nil {
+		t.Errorf("Sign() signature does not verify: %v", err)
+	}
+
+	notification := gourl.Values{"trade_no": {"42"}, "trade_status": {"TRADE_SUCCESS"}, "sign_type": {"RSA2"}}
+	sign, _ := signSHA256(platformKey, []byte(platform.Content(notification, false)))
+	notification.Set("sign", sign)
+	if err := app.VerifyNotification(notification); err != nil {
+		t.Errorf("VerifyNotification() error = %v", err)
+	}
+	notification.Set("trade_status", "TRADE_CLOSED")
+	if err := app.VerifyNotification(notification); !errors.Is(err, ErrSignature) {
+		t.Errorf("VerifyNotification() tampered error got = %v, want %v", err, ErrSignature)
+	}
+
+	body, err := platform.SignResponse("alipay.trade.query", []byte(`{"code":"10000", "msg":"Success"}`))
+	if err != nil {
+		t.Fatalf("SignResponse() error = %v", err)
+	}
+	content, err := app.VerifyResponse(body, "alipay.trade.query")
+	if err != nil || string(content) != `{"code":"10000", "msg":"Success"}` {
+		t.Errorf("VerifyResponse() got = %s %v", content, err)
+	}
+	tampered := []byte(strings.Replace(string(body), "10000", "20000", 1))
+	if _, err := app.VerifyResponse(tampered, "alipay.trade.query"); !errors.Is(err, ErrSignature) {
+		t.Errorf("VerifyResponse() tampered error got = %v, want %v", err, ErrSignature)
+	}
+}
+
+func TestWeChatPayAuthorization(t *testing.T) {
+	key := newKey(t)
+	w, err := NewWeChatPay("1900000001", "5157F09EFDC096DE15EBE81A47057A72", key, apiV3Key)
+	if err != nil {
+		t.Fatalf("NewWeChatPay() error = %v", err)
+	}
+	w.now = func() time.Time { return time.Unix(1554208460, 0) }
+	req, _ := http.NewRequest(http.MethodPost, "https://api.mch.weixin.qq.com/v3/pay/transactions/jsapi?x=1", nil)
+	body := []byte(`{"amount":{"total":1}}`)
+	if err := w.Sign(req, body); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	pattern := regexp.MustCompile(`^WECHATPAY2-SHA256-RSA2048 mchid="1900000001",nonce_str="([0-9A-F]{32})",signature="([^"]+)",timestamp="1554208460",serial_no="5157F09EFDC096DE15EBE81A47057A72"$`)
+	match := pattern.FindStringSubmatch(req.Header.Get("Authorization"))
+	if match == nil {
+		t.Fatalf("Authorization got = %v", req.Header.Get("Authorization"))
+	}
+	message := "POST\n/v3/pay/transactions/jsapi?x=1\n1554208460\n" + match[1] + "\n" + string(body) + "\n"
+	if err := verifySHA256(&key.PublicKey, []byte(message), match[2]); err != nil {
+		t.Errorf("Authorization signature does not verify: %v", err)
+	}
+}
+
+func TestWeChatPayNotification(t *testing.T) {
+	platformKey := newKey(t)
+	cert, certPEM := newCertificate(t, platformKey, 0x1A2B)
+	now := time.Now()
+
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "WECHATPAY2-SHA256-RSA2048 ") || r.URL.Path != "/v3/certificates" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := json.Marshal(map[string]any{"data": []any{map[string]any{
+			"serial_no":           "1A2B",
+			"encrypt_certificate": encrypt(t, certPEM),
+		}}})
+		for k, v := range platformHeader(t, platformKey, "1A2B", now, body) {
+			w.Header()[k] = v
+		}
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	w, err := NewWeChatPay("1900000001", "SERIAL", newKey(t), apiV3Key, WithWeChatBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewWeChatPay() error = %v", err)
+	}
+	notification, _ := json.Marshal(map[string]any{
+		"id":         "EV-2018022511223320873",
+		"event_type": "TRANSACTION.SUCCESS",
+		"resource":   encrypt(t, []byte(`{"transaction_id":"42"}`)),
+	})
+	header := platformHeader(t, platformKey, "1A2B", now, notification)
+
+	n, err := w.ParseNotification(context.Background(), header, notification)
+	if err != nil || n.EventType != "TRANSACTION.SUCCESS" || string(n.Plaintext) != `{"transaction_id":"42"}` {
+		t.Fatalf("ParseNotification() got = %+v %v", n, err)
+	}
+	if downloads != 1 || w.certs["1A2B"] == nil || !w.certs["1A2B"].Equal(cert) {
+		t.Errorf("certificates got = %v downloads, %v", downloads, w.certs)
+	}
+
+	// the certificate is cached and checks the download from now on
+	if err := w.RefreshCertificates(context.Background()); err != nil || downloads != 2 {
+		t.Errorf("RefreshCertificates() got = %v %v", downloads, err)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{name: "tampered", header: header, body: []byte(strings.Replace(string(notification), "SUCCESS", "REFUND", 1)), want: ErrSignature},
+		{name: "replayed", header: platformHeader(t, platformKey, "1A2B", now.Add(-time.Hour), notification), body: notification, want: ErrSignature},
+		{name: "unknown serial", header: platformHeader(t, platformKey, "FFFF", now, notification), body: notification, want: ErrUnknownCertificate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := w.ParseNotification(context.Background(), tt.header, tt.body); !errors.Is(err, tt.want) {
+				t.Errorf("ParseNotification() error got = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
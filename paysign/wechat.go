@@ -0,0 +1,353 @@
+package paysign
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// ErrUnknownCertificate is returned when a WeChat Pay message names a platform
+// certificate serial that is not loaded, even after refreshing
+var ErrUnknownCertificate = errors.New("paysign: unknown platform certificate")
+
+const wechatBaseURL = "https://api.mch.weixin.qq.com"
+
+// WeChatPay signs API v3 requests of a merchant and verifies the platform
+// signatures of responses and callback notifications
+type WeChatPay struct {
+	mchID     string
+	serialNo  string
+	key       *rsa.PrivateKey
+	apiV3Key  []byte
+	client    *gohttp.Client
+	baseURL   string
+	maxSkew   time.Duration
+	now       func() time.Time
+	certMu    sync.RWMutex
+	certs     map[string]*x509.Certificate
+	refreshMu sync.Mutex
+}
+
+type WeChatOption = opt.Option[WeChatPay]
+
+// WithWeChatClient downloads certificates with client instead of a new gohttp.Client
+func WithWeChatClient(client *gohttp.Client) WeChatOption {
+	return func(w *WeChatPay) {
+		w.client = client
+	}
+}
+
+// WithWeChatBaseURL changes the API host, e.g. for a sandbox or a proxy
+func WithWeChatBaseURL(url string) WeChatOption {
+	return func(w *WeChatPay) {
+		w.baseURL = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithPlatformCertificates adds platform certificates known up front
+func WithPlatformCertificates(certs ...*x509.Certificate) WeChatOption {
+	return func(w *WeChatPay) {
+		for _, cert := range certs {
+			w.certs[certSerial(cert)] = cert
+		}
+	}
+}
+
+// WithMaxClockSkew rejects messages with a timestamp further off than d, 5
+// minutes by default, to stop replays
+func WithMaxClockSkew(d time.Duration) WeChatOption {
+	return func(w *WeChatPay) {
+		w.maxSkew = d
+	}
+}
+
+// NewWeChatPay creates a signer for merchant mchID, serialNo is the serial of
+// the merchant API certificate and apiV3Key the key for decrypting resources
+func NewWeChatPay(mchID string, serialNo string, key *rsa.PrivateKey, apiV3Key string, opts ...WeChatOption) (*WeChatPay, error) {
+	w := &WeChatPay{
+		mchID:    mchID,
+		serialNo: serialNo,
+		key:      key,
+		apiV3Key: []byte(apiV3Key),
+		baseURL:  wechatBaseURL,
+		maxSkew:  5 * time.Minute,
+		now:      time.Now,
+		certs:    make(map[string]*x509.Certificate),
+	}
+	err := opt.Build(w, opts, func(w *WeChatPay) error {
+		if w.mchID == "" || w.serialNo == "" || w.key == nil {
+			return errors.New("paysign: merchant id, serial number and key are required")
+		}
+		if len(w.apiV3Key) != 32 {
+			return errors.New("paysign: the API v3 key must be 32 bytes")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if w.client == nil {
+		w.client = gohttp.NewClient()
+	}
+	return w, nil
+}
+
+// Authorization returns the Authorization header for a request, url is the
+// path with the query, e.g. /v3/pay/transactions/jsapi
+func (w *WeChatPay) Authorization(method string, url string, body []byte) (string, error) {
+	nonce := nonceStr()
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+	signature, err := signSHA256(w.key, []byte(method+"\n"+url+"\n"+timestamp+"\n"+nonce+"\n"+string(body)+"\n"))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`WECHATPAY2-SHA256-RSA2048 mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		w.mchID, nonce, signature, timestamp, w.serialNo), nil
+}
+
+// Sign implements gohttp.Signer, so requests can be signed with gohttp.NewSigningHook
+func (w *WeChatPay) Sign(req *http.Request, body []byte) error {
+	auth, err := w.Authorization(req.Method, req.URL.RequestURI(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	return nil
+}
+
+// Verify checks the Wechatpay-* signature headers of a response or callback.
+// An unknown certificate serial triggers one certificate refresh.
+func (w *WeChatPay) Verify(ctx context.Context, header http.Header, body []byte) error {
+	serial := header.Get("Wechatpay-Serial")
+	if serial == "" {
+		return fmt.Errorf("%w: missing Wechatpay signature headers", ErrSignature)
+	}
+	cert, err := w.certificate(ctx, serial)
+	if err != nil {
+		return err
+	}
+	return w.verifySignature(header, body, cert)
+}
+
+func (w *WeChatPay) verifySignature(header http.Header, body []byte, cert *x509.Certificate) error {
+	timestamp := header.Get("Wechatpay-Timestamp")
+	nonce := header.Get("Wechatpay-Nonce")
+	signature := header.Get("Wechatpay-Signature")
+	if timestamp == "" || nonce == "" || signature == "" {
+		return fmt.Errorf("%w: missing Wechatpay signature headers", ErrSignature)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp %q", ErrSignature, timestamp)
+	}
+	if skew := w.now().Sub(time.Unix(seconds, 0)); skew > w.maxSkew || skew < -w.maxSkew {
+		return fmt.Errorf("%w: timestamp is %v off", ErrSignature, skew.Round(time.Second))
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("paysign: certificate %s has no RSA key", certSerial(cert))
+	}
+	return verifySHA256(key, []byte(timestamp+"\n"+nonce+"\n"+string(body)+"\n"), signature)
+}
+
+// Resource is the encrypted part of notifications and certificate downloads
+type Resource struct {
+	Algorithm      string `json:"algorithm"`
+	Ciphertext     string `json:"ciphertext"`
+	AssociatedData string `json:"associated_data"`
+	Nonce          string `json:"nonce"`
+	OriginalType   string `json:"original_type,omitempty"`
+}
+
+// Decrypt opens an AEAD_AES_256_GCM resource with the API v3 key
+func (w *WeChatPay) Decrypt(r Resource) ([]byte, error) {
+	if r.Algorithm != "AEAD_AES_256_GCM" {
+		return nil, fmt.Errorf("paysign: resource algorithm %s is not supported", r.Algorithm)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(r.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(w.apiV3Key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(r.Nonce))
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, []byte(r.Nonce), ciphertext, []byte(r.AssociatedData))
+}
+
+// Notification is a verified callback with its decrypted resource
+type Notification struct {
+	ID           string    `json:"id"`
+	CreateTime   time.Time `json:"create_time"`
+	EventType    string    `json:"event_type"`
+	ResourceType string    `json:"resource_type"`
+	Summary      string    `json:"summary"`
+	Resource     Resource  `json:"resource"`
+	// Plaintext is the decrypted resource, e.g. the transaction JSON
+	Plaintext []byte `json:"-"`
+}
+
+// ParseNotification verifies a callback request and decrypts its resource.
+// Answer with status 200 or 204 afterwards, failures with 4xx or 5xx and
+// a {"code":"FAIL","message":...} body.
+func (w *WeChatPay) ParseNotification(ctx context.Context, header http.Header, body []byte) (*Notification, error) {
+	if err := w.Verify(ctx, header, body); err != nil {
+		return nil, err
+	}
+	var n Notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, err
+	}
+	plaintext, err := w.Decrypt(n.Resource)
+	if err != nil {
+		return nil, err
+	}
+	n.Plaintext = plaintext
+	return &n, nil
+}
+
+// RefreshCertificates downloads the platform certificates, verifying the
+// download with the certificates already known. The first download cannot be
+// verified unless certificates were given WithPlatformCertificates.
+func (w *WeChatPay) RefreshCertificates(ctx context.Context) error {
+	w.refreshMu.Lock()
+	defer w.refreshMu.Unlock()
+	const path = "/v3/certificates"
+	auth, err := w.Authorization(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.NewRequest(gohttp.GET, w.baseURL+path).
+		WithContext(ctx).
+		Header("Authorization", auth).
+		Header("Accept", "application/json").
+		Send()
+	if err != nil {
+		return fmt.Errorf("paysign: download certificates: %w", err)
+	}
+	var list struct {
+		Data []struct {
+			SerialNo           string   `json:"serial_no"`
+			ExpireTime         string   `json:"expire_time"`
+			EncryptCertificate Resource `json:"encrypt_certificate"`
+		} `json:"data"`
+	}
+	if err := resp.JSON(&list); err != nil {
+		return err
+	}
+	certs := make(map[string]*x509.Certificate, len(list.Data))
+	for _, item := range list.Data {
+		plaintext, err := w.Decrypt(item.EncryptCertificate)
+		if err != nil {
+			return fmt.Errorf("paysign: decrypt certificate %s: %w", item.SerialNo, err)
+		}
+		cert, err := ParseCertificate(plaintext)
+		if err != nil {
+			return err
+		}
+		certs[certSerial(cert)] = cert
+	}
+	w.certMu.Lock()
+	verified := len(w.certs) > 0
+	w.certMu.Unlock()
+	if verified {
+		if err := w.verifyWith(resp.Header, resp.Body, certs); err != nil {
+			return err
+		}
+	}
+	w.certMu.Lock()
+	for serial, cert := range certs {
+		w.certs[serial] = cert
+	}
+	// drop expired certificates, they are rotated about yearly
+	for serial, cert := range w.certs {
+		if w.now().After(cert.NotAfter) {
+			delete(w.certs, serial)
+		}
+	}
+	w.certMu.Unlock()
+	return nil
+}
+
+// RefreshCertificatesEvery refreshes the certificates every interval until
+// ctx is done, failures are passed to onError when it is not nil
+func (w *WeChatPay) RefreshCertificatesEvery(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.RefreshCertificates(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// verifyWith checks a certificate download against the known and the downloaded certificates
+func (w *WeChatPay) verifyWith(header http.Header, body []byte, downloaded map[string]*x509.Certificate) error {
+	serial := header.Get("Wechatpay-Serial")
+	w.certMu.RLock()
+	cert, ok := w.certs[serial]
+	w.certMu.RUnlock()
+	if !ok {
+		// a new certificate signs once the old one is retired
+		cert, ok = downloaded[serial]
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCertificate, serial)
+	}
+	return w.verifySignature(header, body, cert)
+}
+
+func (w *WeChatPay) certificate(ctx context.Context, serial string) (*x509.Certificate, error) {
+	w.certMu.RLock()
+	cert, ok := w.certs[serial]
+	w.certMu.RUnlock()
+	if ok {
+		return cert, nil
+	}
+	if err := w.RefreshCertificates(ctx); err != nil {
+		return nil, err
+	}
+	w.certMu.RLock()
+	cert, ok = w.certs[serial]
+	w.certMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCertificate, serial)
+	}
+	return cert, nil
+}
+
+// certSerial formats the serial like WeChat Pay does, upper case hex
+func certSerial(cert *x509.Certificate) string {
+	return strings.ToUpper(cert.SerialNumber.Text(16))
+}
+
+func nonceStr() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return strings.ToUpper(hex.EncodeToString(b))
+}
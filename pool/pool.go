@@ -0,0 +1,251 @@
+// Package pool runs tasks on a bounded set of goroutines. Tasks wait in a
+// queue of limited length for a free worker, a panicking task fails with a
+// *PanicError instead of crashing the process:
+//
+//	p, _ := pool.New(pool.WithSize(8))
+//	defer p.Close()
+//	f, err := pool.SubmitValue(ctx, p, func(ctx context.Context) (int, error) {
+//		return count(ctx)
+//	})
+//	n, err := f.Wait(ctx)
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// ErrClosed is returned when submitting to a closed Pool
+var ErrClosed = errors.New("pool: closed")
+
+// PanicError is the error of a task that panicked
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("pool: task panicked: %v", e.Value)
+}
+
+type config struct {
+	size    int
+	queue   int
+	onPanic func(err *PanicError)
+}
+
+type Option = opt.Option[config]
+
+// WithSize sets the number of workers, runtime.NumCPU() by default
+func WithSize(n int) Option {
+	return func(c *config) {
+		c.size = n
+	}
+}
+
+// WithQueueSize sets how many tasks wait for a worker before Submit blocks,
+// the number of workers by default
+func WithQueueSize(n int) Option {
+	return func(c *config) {
+		c.queue = n
+	}
+}
+
+// WithPanicHandler calls fn with every recovered panic, e.g. to log it
+func WithPanicHandler(fn func(err *PanicError)) Option {
+	return func(c *config) {
+		c.onPanic = fn
+	}
+}
+
+type task struct {
+	ctx context.Context
+	run func(ctx context.Context) error
+	// finish completes the Future with the error of run, a panic or ctx
+	finish func(err error)
+}
+
+// Pool is a bounded worker pool, it is safe for concurrent use
+type Pool struct {
+	cfg   config
+	tasks chan task
+	wg    sync.WaitGroup
+
+	// mu guards closed against sends on tasks while it is closed
+	mu     sync.RWMutex
+	closed bool
+
+	running   int64
+	queued    int64
+	completed int64
+	failed    int64
+	panics    int64
+}
+
+// New starts a Pool with its workers
+func New(opts ...Option) (*Pool, error) {
+	cfg := config{size: runtime.NumCPU(), queue: -1}
+	err := opt.Build(&cfg, opts, func(c *config) error {
+		if c.size <= 0 {
+			return errors.New("pool: size must be positive")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.queue < 0 {
+		cfg.queue = cfg.size
+	}
+	p := &Pool{cfg: cfg, tasks: make(chan task, cfg.queue)}
+	p.wg.Add(cfg.size)
+	for i := 0; i < cfg.size; i++ {
+		go p.work()
+	}
+	return p, nil
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for t := range p.tasks {
+		atomic.AddInt64(&p.queued, -1)
+		atomic.AddInt64(&p.running, 1)
+		err := p.run(t)
+		atomic.AddInt64(&p.running, -1)
+		atomic.AddInt64(&p.completed, 1)
+		if err != nil {
+			atomic.AddInt64(&p.failed, 1)
+		}
+	}
+}
+
+func (p *Pool) run(t task) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			atomic.AddInt64(&p.panics, 1)
+			panicErr := &PanicError{Value: v, Stack: debug.Stack()}
+			if p.cfg.onPanic != nil {
+				p.cfg.onPanic(panicErr)
+			}
+			err = panicErr
+		}
+		t.finish(err)
+	}()
+	// a task whose caller gave up while it was queued is not run
+	if err := t.ctx.Err(); err != nil {
+		return err
+	}
+	return t.run(t.ctx)
+}
+
+// Submit queues fn, waiting for room in the queue until ctx is done. fn runs
+// with ctx, the returned Future completes with its error.
+func (p *Pool) Submit(ctx context.Context, fn func(ctx context.Context) error) (*Future[struct{}], error) {
+	return SubmitValue(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+}
+
+// SubmitValue queues fn on p like Pool.Submit, the Future completes with its
+// result
+func SubmitValue[T any](ctx context.Context, p *Pool, fn func(ctx context.Context) (T, error)) (*Future[T], error) {
+	f := &Future[T]{done: make(chan struct{})}
+	t := task{
+		ctx: ctx,
+		run: func(ctx context.Context) error {
+			value, err := fn(ctx)
+			f.value = value
+			return err
+		},
+		finish: func(err error) {
+			f.err = err
+			close(f.done)
+		},
+	}
+	if err := p.enqueue(ctx, t); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *Pool) enqueue(ctx context.Context, t task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+	// counted before the send so a worker never sees a negative count
+	atomic.AddInt64(&p.queued, 1)
+	select {
+	case p.tasks <- t:
+		return nil
+	case <-ctx.Done():
+		atomic.AddInt64(&p.queued, -1)
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting tasks and waits until the queued and running tasks
+// are done. Calling it again does nothing.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// Running returns the number of tasks being run
+func (p *Pool) Running() int {
+	return int(atomic.LoadInt64(&p.running))
+}
+
+// Queued returns the number of tasks waiting for a worker
+func (p *Pool) Queued() int {
+	return int(atomic.LoadInt64(&p.queued))
+}
+
+// Stats returns the counters of the pool, register it with
+// stats.Register("pool", p).
+func (p *Pool) Stats() map[string]float64 {
+	return map[string]float64{
+		"workers":         float64(p.cfg.size),
+		"running":         float64(atomic.LoadInt64(&p.running)),
+		"queued":          float64(atomic.LoadInt64(&p.queued)),
+		"completed_total": float64(atomic.LoadInt64(&p.completed)),
+		"failed_total":    float64(atomic.LoadInt64(&p.failed)),
+		"panics_total":    float64(atomic.LoadInt64(&p.panics)),
+	}
+}
+
+// Future is the pending result of a submitted task
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Done is closed when the task is complete
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the result of the task until ctx is done
+func (f *Future[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,135 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmit(t *testing.T) {
+	p, err := New(WithSize(3))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer p.Close()
+	ctx := context.Background()
+
+	var inFlight, maxInFlight int64
+	futures := make([]*Future[int], 20)
+	for i := range futures {
+		i := i
+		futures[i], err = SubmitValue(ctx, p, func(ctx context.Context) (int, error) {
+			n := atomic.AddInt64(&inFlight, 1)
+			for {
+				max := atomic.LoadInt64(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&inFlight, -1)
+			return i * i, nil
+		})
+		if err != nil {
+			t.Fatalf("SubmitValue() error = %v", err)
+		}
+	}
+	for i, f := range futures {
+		if got, err := f.Wait(ctx); got != i*i || err != nil {
+			t.Errorf("Wait() got = %v %v, want %v", got, err, i*i)
+		}
+	}
+	if maxInFlight > 3 {
+		t.Errorf("tasks in flight got = %v, want at most 3", maxInFlight)
+	}
+
+	errTask := errors.New("task failed")
+	f, _ := p.Submit(ctx, func(ctx context.Context) error { return errTask })
+	if _, err := f.Wait(ctx); err != errTask {
+		t.Errorf("Wait() error = %v, want %v", err, errTask)
+	}
+	stats := p.Stats()
+	if stats["completed_total"] != 21 || stats["failed_total"] != 1 || stats["workers"] != 3 {
+		t.Errorf("Stats() got = %v", stats)
+	}
+
+	if _, err := New(WithSize(0)); err == nil {
+		t.Errorf("New() error = nil, want invalid size")
+	}
+}
+
+func TestQueue(t *testing.T) {
+	var panics int64
+	p, _ := New(WithSize(1), WithQueueSize(1), WithPanicHandler(func(err *PanicError) {
+		atomic.AddInt64(&panics, 1)
+	}))
+	release := make(chan struct{})
+	ctx := context.Background()
+	blocked, _ := p.Submit(ctx, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	for p.Running() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	panicked, _ := p.Submit(ctx, func(ctx context.Context) error { panic("boom") })
+	if p.Queued() != 1 {
+		t.Errorf("Queued() got = %v, want 1", p.Queued())
+	}
+
+	// the queue is full, Submit waits until its ctx is done
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Submit(timeout, func(ctx context.Context) error { return nil }); err != context.DeadlineExceeded {
+		t.Errorf("Submit() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := blocked.Wait(timeout); err != context.DeadlineExceeded {
+		t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	var panicErr *PanicError
+	if _, err := panicked.Wait(ctx); !errors.As(err, &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Errorf("Wait() error = %v, want a *PanicError", err)
+	}
+	if atomic.LoadInt64(&panics) != 1 || p.Stats()["panics_total"] != 1 {
+		t.Errorf("panics got = %v, want 1", panics)
+	}
+	p.Close()
+}
+
+func TestClose(t *testing.T) {
+	p, _ := New(WithSize(1), WithQueueSize(10))
+	var ran int64
+	ctx := context.Background()
+	canceled, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var skipped *Future[struct{}]
+	for i := 0; i < 5; i++ {
+		_, _ = p.Submit(ctx, func(ctx context.Context) error {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&ran, 1)
+			return nil
+		})
+		if i == 0 {
+			skipped, _ = p.Submit(canceled, func(ctx context.Context) error {
+				t.Error("task ran after its ctx was canceled")
+				return nil
+			})
+			cancel()
+		}
+	}
+	p.Close()
+	if ran != 5 || p.Running() != 0 || p.Queued() != 0 {
+		t.Errorf("Close() ran %v tasks, want the queued 5 drained", ran)
+	}
+	if _, err := skipped.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := p.Submit(ctx, func(ctx context.Context) error { return nil }); err != ErrClosed {
+		t.Errorf("Submit() error = %v, want %v", err, ErrClosed)
+	}
+	p.Close()
+}
@@ -0,0 +1,169 @@
+package queue
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// DelayQueue holds each item until its time comes, Pop returns the item due
+// first once it is due, e.g. for deliveries retried later
+type DelayQueue[T any] struct {
+	capacity int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	items   itemHeap[delayed[T]]
+	seq     uint64
+	closed  bool
+	changed signal
+}
+
+type delayed[T any] struct {
+	v  T
+	at time.Time
+}
+
+// NewDelay returns a DelayQueue holding up to capacity items, due or not,
+// without limit for 0
+func NewDelay[T any](capacity int) *DelayQueue[T] {
+	return &DelayQueue[T]{
+		capacity: capacity,
+		now:      time.Now,
+		items: itemHeap[delayed[T]]{less: func(a, b delayed[T]) bool {
+			return a.at.Before(b.at)
+		}},
+	}
+}
+
+// Push adds v due at at, waiting for room until ctx is done
+func (q *DelayQueue[T]) Push(ctx context.Context, v T, at time.Time) error {
+	q.mu.Lock()
+	for !q.closed && q.capacity > 0 && q.items.Len() >= q.capacity {
+		wait := q.changed.wait()
+		q.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		q.mu.Lock()
+	}
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	q.seq++
+	heap.Push(&q.items, item[delayed[T]]{v: delayed[T]{v: v, at: at}, seq: q.seq})
+	q.changed.broadcast()
+	return nil
+}
+
+// PushAfter adds v due after d
+func (q *DelayQueue[T]) PushAfter(ctx context.Context, v T, d time.Duration) error {
+	return q.Push(ctx, v, q.now().Add(d))
+}
+
+// Pop removes and returns the item due first, waiting until it is due or
+// ctx is done. A closed queue still returns its items as they come due, then
+// ErrClosed.
+func (q *DelayQueue[T]) Pop(ctx context.Context) (T, error) {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		q.mu.Lock()
+		if v, ok := q.popDue(); ok {
+			q.mu.Unlock()
+			return v, nil
+		}
+		if q.closed && q.items.Len() == 0 {
+			q.mu.Unlock()
+			var zero T
+			return zero, ErrClosed
+		}
+		wait := q.changed.wait()
+		var due <-chan time.Time
+		if q.items.Len() > 0 {
+			delay := q.items.items[0].v.at.Sub(q.now())
+			if timer == nil {
+				timer = time.NewTimer(delay)
+			} else {
+				timer.Reset(delay)
+			}
+			due = timer.C
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-due:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+		if timer != nil && !timer.Stop() {
+			// drain a fired timer so Reset starts clean
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// TryPop removes and returns the item due first when it is due, it fails
+// with ErrEmpty otherwise
+func (q *DelayQueue[T]) TryPop() (T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if v, ok := q.popDue(); ok {
+		return v, nil
+	}
+	var zero T
+	if q.closed && q.items.Len() == 0 {
+		return zero, ErrClosed
+	}
+	return zero, ErrEmpty
+}
+
+// popDue pops the first item when it is due, q.mu must be held
+func (q *DelayQueue[T]) popDue() (T, bool) {
+	if q.items.Len() == 0 || q.items.items[0].v.at.After(q.now()) {
+		var zero T
+		return zero, false
+	}
+	v := heap.Pop(&q.items).(item[delayed[T]]).v.v
+	q.changed.broadcast()
+	return v, true
+}
+
+// Len returns the number of items, due or not
+func (q *DelayQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.Len()
+}
+
+// Stats returns the length, due or not, and capacity of the queue, 0
+// without limit. Push waits for room, so nothing is dropped.
+func (q *DelayQueue[T]) Stats() map[string]float64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return map[string]float64{
+		"length":   float64(q.items.Len()),
+		"capacity": float64(q.capacity),
+	}
+}
+
+// Close stops Push, Pop returns the remaining items as they come due
+func (q *DelayQueue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.changed.broadcast()
+}
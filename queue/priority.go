@@ -0,0 +1,68 @@
+package queue
+
+import "container/heap"
+
+// PriorityQueue pops its items by priority, the item less than all others
+// first. Items of equal priority come out in the order they were pushed.
+type PriorityQueue[T any] struct {
+	blocking[T]
+}
+
+// NewPriority returns a PriorityQueue holding up to capacity items, without
+// limit for 0. less(a, b) reports whether a comes out before b.
+func NewPriority[T any](less func(a T, b T) bool, capacity int) *PriorityQueue[T] {
+	return &PriorityQueue[T]{blocking[T]{capacity: capacity, items: &priorityStore[T]{h: itemHeap[T]{less: less}}}}
+}
+
+type item[T any] struct {
+	v   T
+	seq uint64
+}
+
+// itemHeap is a container/heap ordered by less, then by push order
+type itemHeap[T any] struct {
+	items []item[T]
+	less  func(a T, b T) bool
+}
+
+func (h *itemHeap[T]) Len() int { return len(h.items) }
+
+func (h *itemHeap[T]) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.less(a.v, b.v) {
+		return true
+	}
+	if h.less(b.v, a.v) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (h *itemHeap[T]) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *itemHeap[T]) Push(x any) { h.items = append(h.items, x.(item[T])) }
+
+func (h *itemHeap[T]) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items[len(h.items)-1] = item[T]{}
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+type priorityStore[T any] struct {
+	h   itemHeap[T]
+	seq uint64
+}
+
+func (s *priorityStore[T]) push(v T) {
+	s.seq++
+	heap.Push(&s.h, item[T]{v: v, seq: s.seq})
+}
+
+func (s *priorityStore[T]) pop() T {
+	return heap.Pop(&s.h).(item[T]).v
+}
+
+func (s *priorityStore[T]) len() int {
+	return s.h.Len()
+}
@@ -0,0 +1,230 @@
+// Package queue has generic in-memory queues safe for concurrent use: Queue
+// is a bounded FIFO, PriorityQueue orders its items, DelayQueue holds items
+// until their time comes and Ring keeps the latest items of a stream. Their
+// Push and Pop wait for room and items until their ctx is done.
+//
+//	q := queue.New[Job](100)
+//	go func() {
+//		for {
+//			job, err := q.Pop(ctx)
+//			if err != nil {
+//				return // ctx done, or queue closed and drained
+//			}
+//			handle(job)
+//		}
+//	}()
+//	err := q.Push(ctx, job)
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Stellar1999/gotool/errs"
+)
+
+var (
+	// ErrClosed is returned by Push after Close, and by Pop once a closed
+	// queue is empty. It matches errs.ErrUnavailable.
+	ErrClosed = errs.Mark(errors.New("queue: closed"), errs.ErrUnavailable)
+	// ErrFull is returned by TryPush when the queue has no room. It matches
+	// errs.ErrUnavailable.
+	ErrFull = errs.Mark(errors.New("queue: full"), errs.ErrUnavailable)
+	// ErrEmpty is returned by TryPop when the queue has no item
+	ErrEmpty = errors.New("queue: empty")
+)
+
+// signal wakes all waiters of a state change, its methods need the lock of
+// the queue
+type signal struct {
+	ch chan struct{}
+}
+
+// wait returns a channel closed at the next broadcast
+func (s *signal) wait() <-chan struct{} {
+	if s.ch == nil {
+		s.ch = make(chan struct{})
+	}
+	return s.ch
+}
+
+func (s *signal) broadcast() {
+	if s.ch != nil {
+		close(s.ch)
+		s.ch = nil
+	}
+}
+
+// store holds the items of a blocking queue
+type store[T any] interface {
+	push(v T)
+	pop() T
+	len() int
+}
+
+// blocking is a queue of bounded capacity over a store
+type blocking[T any] struct {
+	capacity int
+	mu       sync.Mutex
+	items    store[T]
+	closed   bool
+	// changed is broadcast on every push, pop and close
+	changed signal
+	// dropped counts the items TryPush turned away for lack of room
+	dropped int
+}
+
+func (q *blocking[T]) full() bool {
+	return q.capacity > 0 && q.items.len() >= q.capacity
+}
+
+// Push adds v, waiting for room until ctx is done
+func (q *blocking[T]) Push(ctx context.Context, v T) error {
+	q.mu.Lock()
+	for !q.closed && q.full() {
+		wait := q.changed.wait()
+		q.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		q.mu.Lock()
+	}
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	q.items.push(v)
+	q.changed.broadcast()
+	return nil
+}
+
+// TryPush adds v when there is room now, it fails with ErrFull otherwise
+func (q *blocking[T]) TryPush(v T) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if q.full() {
+		q.dropped++
+		return ErrFull
+	}
+	q.items.push(v)
+	q.changed.broadcast()
+	return nil
+}
+
+// Pop removes and returns the next item, waiting for one until ctx is done.
+// A closed queue returns its remaining items, then ErrClosed.
+func (q *blocking[T]) Pop(ctx context.Context) (T, error) {
+	q.mu.Lock()
+	for !q.closed && q.items.len() == 0 {
+		wait := q.changed.wait()
+		q.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+		q.mu.Lock()
+	}
+	defer q.mu.Unlock()
+	if q.items.len() == 0 {
+		var zero T
+		return zero, ErrClosed
+	}
+	v := q.items.pop()
+	q.changed.broadcast()
+	return v, nil
+}
+
+// TryPop removes and returns the next item when there is one, it fails with
+// ErrEmpty otherwise
+func (q *blocking[T]) TryPop() (T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.items.len() == 0 {
+		var zero T
+		if q.closed {
+			return zero, ErrClosed
+		}
+		return zero, ErrEmpty
+	}
+	v := q.items.pop()
+	q.changed.broadcast()
+	return v, nil
+}
+
+// Len returns the number of items
+func (q *blocking[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.len()
+}
+
+// Stats returns the length and capacity of the queue, 0 without limit, and
+// the items TryPush turned away as dropped_total, register it with
+// stats.Register("jobs_queue", q).
+func (q *blocking[T]) Stats() map[string]float64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return map[string]float64{
+		"length":        float64(q.items.len()),
+		"capacity":      float64(q.capacity),
+		"dropped_total": float64(q.dropped),
+	}
+}
+
+// Close stops Push, Pop returns the remaining items before failing
+func (q *blocking[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.changed.broadcast()
+}
+
+// Queue is a first in, first out queue
+type Queue[T any] struct {
+	blocking[T]
+}
+
+// New returns a Queue holding up to capacity items, without limit for 0
+func New[T any](capacity int) *Queue[T] {
+	return &Queue[T]{blocking[T]{capacity: capacity, items: &fifo[T]{}}}
+}
+
+// fifo is a growing ring of items
+type fifo[T any] struct {
+	buf   []T
+	head  int
+	count int
+}
+
+func (f *fifo[T]) push(v T) {
+	if f.count == len(f.buf) {
+		grown := make([]T, 2*len(f.buf)+1)
+		n := copy(grown, f.buf[f.head:])
+		copy(grown[n:], f.buf[:f.head])
+		f.buf, f.head = grown, 0
+	}
+	f.buf[(f.head+f.count)%len(f.buf)] = v
+	f.count++
+}
+
+func (f *fifo[T]) pop() T {
+	var zero T
+	v := f.buf[f.head]
+	// drop the reference for the garbage collector
+	f.buf[f.head] = zero
+	f.head = (f.head + 1) % len(f.buf)
+	f.count--
+	return v
+}
+
+func (f *fifo[T]) len() int {
+	return f.count
+}
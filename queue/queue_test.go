@@ -0,0 +1,219 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/errs"
+	"github.com/Stellar1999/gotool/stats"
+)
+
+var (
+	_ stats.Stats = (*Queue[int])(nil)
+	_ stats.Stats = (*PriorityQueue[int])(nil)
+	_ stats.Stats = (*DelayQueue[int])(nil)
+	_ stats.Stats = (*Ring[int])(nil)
+)
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+	q := New[int](2)
+	for i := 1; i <= 2; i++ {
+		if err := q.Push(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.TryPush(3); !errors.Is(err, ErrFull) || !errors.Is(err, errs.ErrUnavailable) {
+		t.Errorf("TryPush() full error got = %v, want %v", err, ErrFull)
+	}
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := q.Push(short, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Push() full error got = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	pushed := make(chan error)
+	go func() { pushed <- q.Push(ctx, 3) }()
+	if v, err := q.Pop(ctx); v != 1 || err != nil {
+		t.Errorf("Pop() got = %v %v, want 1", v, err)
+	}
+	if err := <-pushed; err != nil {
+		t.Errorf("Push() after room error = %v", err)
+	}
+	q.Close()
+	if err := q.Push(ctx, 4); !errors.Is(err, ErrClosed) || !errors.Is(err, errs.ErrUnavailable) {
+		t.Errorf("Push() closed error got = %v, want %v", err, ErrClosed)
+	}
+	var got []int
+	for {
+		v, err := q.Pop(ctx)
+		if errors.Is(err, ErrClosed) {
+			break
+		}
+		got = append(got, v)
+	}
+	if !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("Pop() after Close got = %v, want the remaining items", got)
+	}
+
+	empty := New[string](0)
+	if _, err := empty.TryPop(); !errors.Is(err, ErrEmpty) {
+		t.Errorf("TryPop() error got = %v, want %v", err, ErrEmpty)
+	}
+	for i := 0; i < 100; i++ {
+		_ = empty.TryPush("x")
+	}
+	if empty.Len() != 100 {
+		t.Errorf("Len() unbounded got = %v", empty.Len())
+	}
+}
+
+func TestQueueConcurrent(t *testing.T) {
+	ctx := context.Background()
+	q := New[int](4)
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				_ = q.Push(ctx, p*1000+i)
+			}
+		}(p)
+	}
+	results := make(chan map[int]bool)
+	for c := 0; c < 3; c++ {
+		go func() {
+			seen := make(map[int]bool)
+			for {
+				v, err := q.Pop(ctx)
+				if err != nil {
+					results <- seen
+					return
+				}
+				seen[v] = true
+			}
+		}()
+	}
+	wg.Wait()
+	q.Close()
+	total := 0
+	for c := 0; c < 3; c++ {
+		total += len(<-results)
+	}
+	if total != 1000 {
+		t.Errorf("popped %v items, want 1000", total)
+	}
+}
+
+func TestPriorityQueue(t *testing.T) {
+	type job struct {
+		name     string
+		priority int
+	}
+	q := NewPriority(func(a, b job) bool { return a.priority > b.priority }, 0)
+	for _, j := range []job{{"low", 1}, {"high", 9}, {"mid", 5}, {"high2", 9}} {
+		_ = q.TryPush(j)
+	}
+	var got []string
+	for q.Len() > 0 {
+		j, _ := q.Pop(context.Background())
+		got = append(got, j.name)
+	}
+	if want := []string{"high", "high2", "mid", "low"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pop() order got = %v, want %v", got, want)
+	}
+}
+
+func TestDelayQueue(t *testing.T) {
+	ctx := context.Background()
+	q := NewDelay[string](0)
+	start := time.Now()
+	_ = q.PushAfter(ctx, "late", 40*time.Millisecond)
+	_ = q.PushAfter(ctx, "soon", 10*time.Millisecond)
+	_ = q.Push(ctx, "now", start)
+	if v, err := q.TryPop(); v != "now" || err != nil {
+		t.Errorf("TryPop() got = %v %v, want now", v, err)
+	}
+	if _, err := q.TryPop(); !errors.Is(err, ErrEmpty) {
+		t.Errorf("TryPop() not due error got = %v, want %v", err, ErrEmpty)
+	}
+	if v, _ := q.Pop(ctx); v != "soon" || time.Since(start) < 10*time.Millisecond {
+		t.Errorf("Pop() got = %v after %v", v, time.Since(start))
+	}
+
+	// an item due earlier wakes a waiting Pop
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		_ = q.PushAfter(ctx, "earlier", 0)
+	}()
+	if v, _ := q.Pop(ctx); v != "earlier" || time.Since(start) >= 40*time.Millisecond {
+		t.Errorf("Pop() got = %v after %v, want the earlier item", v, time.Since(start))
+	}
+	short, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if _, err := q.Pop(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Pop() error got = %v, want %v", err, context.DeadlineExceeded)
+	}
+	q.Close()
+	if v, err := q.Pop(ctx); v != "late" || err != nil || time.Since(start) < 40*time.Millisecond {
+		t.Errorf("Pop() after Close got = %v %v", v, err)
+	}
+	if _, err := q.Pop(ctx); !errors.Is(err, ErrClosed) {
+		t.Errorf("Pop() drained error got = %v, want %v", err, ErrClosed)
+	}
+
+	bounded := NewDelay[int](1)
+	_ = bounded.PushAfter(ctx, 1, time.Hour)
+	short, cancel = context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if err := bounded.PushAfter(short, 2, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Push() full error got = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRing(t *testing.T) {
+	ctx := context.Background()
+	r := NewRing[int](3)
+	for i := 1; i <= 5; i++ {
+		if err := r.Push(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := r.Items(); !reflect.DeepEqual(got, []int{3, 4, 5}) || r.Dropped() != 2 {
+		t.Errorf("Items() got = %v, dropped %v", got, r.Dropped())
+	}
+	if v, _ := r.Pop(ctx); v != 3 {
+		t.Errorf("Pop() got = %v, want the oldest", v)
+	}
+	_ = r.Push(ctx, 6)
+	if got := r.Items(); !reflect.DeepEqual(got, []int{4, 5, 6}) {
+		t.Errorf("Items() got = %v", got)
+	}
+}
+
+func TestStats(t *testing.T) {
+	q := New[int](2)
+	_ = q.TryPush(1)
+	_ = q.TryPush(2)
+	_ = q.TryPush(3)
+	if got, want := q.Stats(), map[string]float64{"length": 2, "capacity": 2, "dropped_total": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() got = %v, want %v", got, want)
+	}
+	r := NewRing[int](2)
+	for i := 0; i < 5; i++ {
+		_ = r.Push(context.Background(), i)
+	}
+	if got, want := r.Stats(), map[string]float64{"length": 2, "capacity": 2, "dropped_total": 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Ring.Stats() got = %v, want %v", got, want)
+	}
+	d := NewDelay[int](0)
+	_ = d.PushAfter(context.Background(), 1, time.Hour)
+	if got, want := d.Stats(), map[string]float64{"length": 1, "capacity": 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DelayQueue.Stats() got = %v, want %v", got, want)
+	}
+}
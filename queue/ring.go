@@ -0,0 +1,79 @@
+package queue
+
+// Ring keeps the latest items pushed: once it holds size items a Push drops
+// the oldest instead of waiting, e.g. for the recent events of a stream
+type Ring[T any] struct {
+	blocking[T]
+	ring *ringStore[T]
+}
+
+// NewRing returns a Ring of size items, it panics for a size below 1
+func NewRing[T any](size int) *Ring[T] {
+	if size < 1 {
+		panic("queue: ring size below 1")
+	}
+	r := &ringStore[T]{buf: make([]T, size)}
+	return &Ring[T]{blocking: blocking[T]{items: r}, ring: r}
+}
+
+// Items returns the items from the oldest to the latest, without removing
+// them
+func (r *Ring[T]) Items() []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]T, r.ring.count)
+	for i := range out {
+		out[i] = r.ring.buf[(r.ring.head+i)%len(r.ring.buf)]
+	}
+	return out
+}
+
+// Dropped returns how many items were overwritten before being popped
+func (r *Ring[T]) Dropped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ring.dropped
+}
+
+// Stats returns the length and size of the ring and the items overwritten
+// before being popped as dropped_total
+func (r *Ring[T]) Stats() map[string]float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return map[string]float64{
+		"length":        float64(r.ring.count),
+		"capacity":      float64(len(r.ring.buf)),
+		"dropped_total": float64(r.ring.dropped),
+	}
+}
+
+type ringStore[T any] struct {
+	buf     []T
+	head    int
+	count   int
+	dropped int
+}
+
+func (s *ringStore[T]) push(v T) {
+	if s.count == len(s.buf) {
+		s.buf[s.head] = v
+		s.head = (s.head + 1) % len(s.buf)
+		s.dropped++
+		return
+	}
+	s.buf[(s.head+s.count)%len(s.buf)] = v
+	s.count++
+}
+
+func (s *ringStore[T]) pop() T {
+	var zero T
+	v := s.buf[s.head]
+	s.buf[s.head] = zero
+	s.head = (s.head + 1) % len(s.buf)
+	s.count--
+	return v
+}
+
+func (s *ringStore[T]) len() int {
+	return s.count
+}
@@ -0,0 +1,215 @@
+package quick2
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Int generates ints in [min, max], shrinking toward the bound closest to zero
+func Int(min int, max int) Gen[int] {
+	target := min
+	if min <= 0 && max >= 0 {
+		target = 0
+	} else if max < 0 {
+		target = max
+	}
+	return Gen[int]{
+		Generate: func(r *rand.Rand, size int) int {
+			return min + int(r.Int63n(int64(max)-int64(min)+1))
+		},
+		Shrink: func(v int) []int {
+			var candidates []int
+			for d := (v - target) / 2; d != 0; d /= 2 {
+				candidates = append(candidates, v-d)
+			}
+			if v != target {
+				candidates = append([]int{target}, candidates...)
+			}
+			return candidates
+		},
+	}
+}
+
+// Bool generates booleans, shrinking to false
+func Bool() Gen[bool] {
+	return Gen[bool]{
+		Generate: func(r *rand.Rand, size int) bool {
+			return r.Intn(2) == 1
+		},
+		Shrink: func(v bool) []bool {
+			if v {
+				return []bool{false}
+			}
+			return nil
+		},
+	}
+}
+
+// OneOf picks one of values, shrinking toward the first
+func OneOf[T any](values ...T) Gen[T] {
+	return Gen[T]{
+		Generate: func(r *rand.Rand, size int) T {
+			return values[r.Intn(len(values))]
+		},
+		Shrink: func(v T) []T {
+			return values[:1]
+		},
+	}
+}
+
+// SliceOf generates slices up to size elements, shrinking by dropping and shrinking elements
+func SliceOf[T any](gen Gen[T]) Gen[[]T] {
+	return Gen[[]T]{
+		Generate: func(r *rand.Rand, size int) []T {
+			n := r.Intn(size + 1)
+			s := make([]T, n)
+			for i := range s {
+				s[i] = gen.Generate(r, size)
+			}
+			return s
+		},
+		Shrink: func(v []T) [][]T {
+			var candidates [][]T
+			if len(v) == 0 {
+				return nil
+			}
+			candidates = append(candidates, v[:0:0], v[:len(v)/2:len(v)/2])
+			for i := range v {
+				candidates = append(candidates, removeAt(v, i))
+			}
+			if gen.Shrink != nil {
+				for i := range v {
+					for _, element := range gen.Shrink(v[i]) {
+						c := append([]T(nil), v...)
+						c[i] = element
+						candidates = append(candidates, c)
+					}
+				}
+			}
+			return candidates
+		},
+	}
+}
+
+func removeAt[T any](v []T, i int) []T {
+	c := make([]T, 0, len(v)-1)
+	c = append(c, v[:i]...)
+	return append(c, v[i+1:]...)
+}
+
+// runes mixes ASCII with multi-byte and astral characters
+var runeRanges = [][2]rune{
+	{0x20, 0x7e},       // printable ASCII
+	{0xa0, 0x24f},      // latin supplements
+	{0x391, 0x3c9},     // greek
+	{0x4e00, 0x9fff},   // CJK
+	{0x1f600, 0x1f64f}, // emoji
+	{0x0, 0x1f},        // control characters
+}
+
+// String generates valid UTF-8 strings of up to size runes, shrinking by dropping runes
+func String() Gen[string] {
+	return Gen[string]{
+		Generate: func(r *rand.Rand, size int) string {
+			n := r.Intn(size + 1)
+			var b strings.Builder
+			for i := 0; i < n; i++ {
+				rr := runeRanges[r.Intn(len(runeRanges))]
+				b.WriteRune(rr[0] + rune(r.Intn(int(rr[1]-rr[0]+1))))
+			}
+			return b.String()
+		},
+		Shrink: shrinkString,
+	}
+}
+
+// StringFrom generates strings of up to size runes taken from alphabet
+func StringFrom(alphabet string) Gen[string] {
+	runes := []rune(alphabet)
+	return Gen[string]{
+		Generate: func(r *rand.Rand, size int) string {
+			return randomRunes(r, runes, r.Intn(size+1))
+		},
+		Shrink: shrinkString,
+	}
+}
+
+func randomRunes(r *rand.Rand, alphabet []rune, n int) string {
+	s := make([]rune, n)
+	for i := range s {
+		s[i] = alphabet[r.Intn(len(alphabet))]
+	}
+	return string(s)
+}
+
+func shrinkString(v string) []string {
+	runes := []rune(v)
+	if len(runes) == 0 {
+		return nil
+	}
+	candidates := []string{"", string(runes[:len(runes)/2])}
+	for i := range runes {
+		candidates = append(candidates, string(removeAt(runes, i)))
+	}
+	return candidates
+}
+
+const (
+	lower  = "abcdefghijklmnopqrstuvwxyz"
+	alnum  = lower + "0123456789"
+	local  = alnum + "._+-"
+	labels = alnum + "-"
+)
+
+// Email generates syntactically valid addresses like "a.b+c@host-1.example.org"
+func Email() Gen[string] {
+	return Gen[string]{
+		Generate: func(r *rand.Rand, size int) string {
+			user := randomRunes(r, []rune(alnum), 1) + randomRunes(r, []rune(local), r.Intn(size/4+1))
+			user = strings.TrimRight(user, ".")
+			for strings.Contains(user, "..") {
+				user = strings.ReplaceAll(user, "..", ".")
+			}
+			return user + "@" + hostname(r, size)
+		},
+	}
+}
+
+// URL generates absolute http(s) urls with optional port, path and query
+func URL() Gen[string] {
+	return Gen[string]{
+		Generate: func(r *rand.Rand, size int) string {
+			var b strings.Builder
+			b.WriteString([]string{"http", "https"}[r.Intn(2)])
+			b.WriteString("://")
+			b.WriteString(hostname(r, size))
+			if r.Intn(4) == 0 {
+				b.WriteString(":" + strconv.Itoa(1+r.Intn(65535)))
+			}
+			for i := r.Intn(size/10 + 2); i > 0; i-- {
+				b.WriteString("/" + randomRunes(r, []rune(alnum+"-_~"), 1+r.Intn(8)))
+			}
+			if r.Intn(2) == 0 {
+				sep := "?"
+				for i := 1 + r.Intn(3); i > 0; i-- {
+					b.WriteString(sep + randomRunes(r, []rune(lower), 1+r.Intn(5)) + "=" + randomRunes(r, []rune(alnum), r.Intn(6)))
+					sep = "&"
+				}
+			}
+			return b.String()
+		},
+	}
+}
+
+// hostname builds dns labels that neither start nor end with a dash
+func hostname(r *rand.Rand, size int) string {
+	n := 1 + r.Intn(size/20+2)
+	parts := make([]string, 0, n+1)
+	for i := 0; i < n; i++ {
+		label := randomRunes(r, []rune(alnum), 1) + randomRunes(r, []rune(labels), r.Intn(10))
+		parts = append(parts, strings.TrimRight(label, "-"))
+	}
+	parts = append(parts, []string{"com", "org", "net", "io", "dev"}[r.Intn(5)])
+	return strings.Join(parts, ".")
+}
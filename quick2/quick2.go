@@ -0,0 +1,145 @@
+package quick2
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	DefaultRuns       = 100
+	DefaultMaxSize    = 100
+	DefaultShrinkStep = 1000
+)
+
+// TestingT is the subset of testing.TB used by this package
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Gen generates values of T. Size is a hint that grows with the run number,
+// bigger sizes mean longer strings and slices. Shrink returns simpler
+// candidates for a failing value and may be nil.
+type Gen[T any] struct {
+	Generate func(r *rand.Rand, size int) T
+	Shrink   func(v T) []T
+}
+
+type options struct {
+	runs    int
+	maxSize int
+	seed    int64
+}
+
+type Option func(*options)
+
+// Runs sets how many values are tried
+func Runs(n int) Option {
+	return func(o *options) {
+		o.runs = n
+	}
+}
+
+// MaxSize sets the size hint of the last run
+func MaxSize(n int) Option {
+	return func(o *options) {
+		o.maxSize = n
+	}
+}
+
+// Seed makes the generated values reproducible
+func Seed(seed int64) Option {
+	return func(o *options) {
+		o.seed = seed
+	}
+}
+
+// ForAll checks prop against generated values, a failing value is shrunk
+// to the simplest one still failing and reported with the seed.
+func ForAll[T any](t TestingT, gen Gen[T], prop func(v T) bool, opts ...Option) {
+	t.Helper()
+	o := options{runs: DefaultRuns, maxSize: DefaultMaxSize, seed: time.Now().UnixNano()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	r := rand.New(rand.NewSource(o.seed))
+	for i := 0; i < o.runs; i++ {
+		size := 1 + i*o.maxSize/o.runs
+		v := gen.Generate(r, size)
+		if check(prop, v) {
+			continue
+		}
+		shrunk, steps := shrink(gen, prop, v)
+		t.Errorf("quick2: property failed on run %d (seed %d)\noriginal: %#v\nshrunk (%d steps): %#v", i, o.seed, v, steps, shrunk)
+		return
+	}
+}
+
+// check reports whether prop holds, a panic counts as a failure
+func check[T any](prop func(v T) bool, v T) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return prop(v)
+}
+
+// shrink greedily moves to the first simpler candidate that still fails
+func shrink[T any](gen Gen[T], prop func(v T) bool, v T) (T, int) {
+	if gen.Shrink == nil {
+		return v, 0
+	}
+	steps := 0
+	for steps < DefaultShrinkStep {
+		moved := false
+		for _, candidate := range gen.Shrink(v) {
+			if !check(prop, candidate) {
+				v = candidate
+				steps++
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return v, steps
+}
+
+// Map derives a generator by transforming the values of gen, the result does not shrink
+func Map[T any, U any](gen Gen[T], fn func(T) U) Gen[U] {
+	return Gen[U]{
+		Generate: func(r *rand.Rand, size int) U {
+			return fn(gen.Generate(r, size))
+		},
+	}
+}
+
+// Filter retries generation until keep accepts the value, giving up after 100 tries
+func Filter[T any](gen Gen[T], keep func(T) bool) Gen[T] {
+	var shrinkFn func(T) []T
+	if gen.Shrink != nil {
+		shrinkFn = func(v T) []T {
+			var kept []T
+			for _, candidate := range gen.Shrink(v) {
+				if keep(candidate) {
+					kept = append(kept, candidate)
+				}
+			}
+			return kept
+		}
+	}
+	return Gen[T]{
+		Generate: func(r *rand.Rand, size int) T {
+			for i := 0; i < 100; i++ {
+				if v := gen.Generate(r, size); keep(v) {
+					return v
+				}
+			}
+			panic("quick2: Filter rejected 100 values in a row")
+		},
+		Shrink: shrinkFn,
+	}
+}
@@ -0,0 +1,105 @@
+package quick2
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (*fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestGenerators(t *testing.T) {
+	ForAll(t, String(), utf8.ValidString, Seed(1))
+	ForAll(t, Email(), func(v string) bool {
+		_, err := mail.ParseAddress(v)
+		return err == nil
+	}, Seed(2))
+	ForAll(t, URL(), func(v string) bool {
+		u, err := url.Parse(v)
+		return err == nil && u.IsAbs() && u.Host != ""
+	}, Seed(3))
+	ForAll(t, Int(-5, 5), func(v int) bool { return v >= -5 && v <= 5 }, Seed(4))
+	ForAll(t, SliceOf(Int(0, 9)), func(v []int) bool { return len(v) <= DefaultMaxSize }, Seed(5))
+}
+
+type user struct {
+	Name  string
+	Age   int
+	Tags  []string
+	Admin *bool
+	note  string
+}
+
+func TestShrink(t *testing.T) {
+	tests := []struct {
+		name       string
+		run        func(ft *fakeT)
+		wantShrunk string
+	}{
+		{
+			name: "int",
+			run: func(ft *fakeT) {
+				ForAll(ft, Int(0, 1000), func(v int) bool { return v < 100 }, Seed(1))
+			},
+			wantShrunk: "100",
+		},
+		{
+			name: "slice",
+			run: func(ft *fakeT) {
+				ForAll(ft, SliceOf(Int(0, 100)), func(v []int) bool { return len(v) < 3 }, Seed(1))
+			},
+			wantShrunk: "[]int{0, 0, 0}",
+		},
+		{
+			name: "string",
+			run: func(ft *fakeT) {
+				ForAll(ft, StringFrom("ab"), func(v string) bool { return !strings.Contains(v, "b") }, Seed(1))
+			},
+			wantShrunk: `"b"`,
+		},
+		{
+			name: "struct",
+			run: func(ft *fakeT) {
+				ForAll(ft, Any[user](), func(v user) bool { return v.Age == 0 || v.Name == "" }, Seed(1))
+			},
+			wantShrunk: `quick2.user{Name:"`,
+		},
+		{
+			name: "panic is a failure",
+			run: func(ft *fakeT) {
+				ForAll(ft, Int(1, 10), func(v int) bool { return 10/(v-v) == 0 }, Seed(1))
+			},
+			wantShrunk: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeT{}
+			tt.run(ft)
+			if len(ft.errors) != 1 {
+				t.Fatalf("ForAll() got errors = %v, want 1", ft.errors)
+			}
+			_, shrunk, _ := strings.Cut(ft.errors[0], "steps): ")
+			if !strings.HasPrefix(shrunk, tt.wantShrunk) {
+				t.Errorf("ForAll() shrunk got = %v, want %v", shrunk, tt.wantShrunk)
+			}
+		})
+	}
+}
+
+func TestAny(t *testing.T) {
+	ForAll(t, Any[user](), func(v user) bool {
+		return v.note == "" && utf8.ValidString(v.Name)
+	}, Seed(9))
+}
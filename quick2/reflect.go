@@ -0,0 +1,150 @@
+package quick2
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"time"
+)
+
+// maxDepth bounds recursion into nested slices, maps and pointers
+const maxDepth = 4
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Any generates arbitrary values of T by reflection, filling exported struct
+// fields, slices, maps and pointers. Interfaces, channels and funcs stay nil.
+// It shrinks by zeroing one field or element at a time.
+func Any[T any]() Gen[T] {
+	return Gen[T]{
+		Generate: func(r *rand.Rand, size int) T {
+			var v T
+			fill(reflect.ValueOf(&v).Elem(), r, size, 0)
+			return v
+		},
+		Shrink: func(v T) []T {
+			var candidates []T
+			for _, c := range shrinkValue(reflect.ValueOf(&v).Elem()) {
+				candidates = append(candidates, c.Interface().(T))
+			}
+			return candidates
+		},
+	}
+}
+
+func fill(v reflect.Value, r *rand.Rand, size int, depth int) {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(r.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if r.Intn(2) == 0 {
+			v.SetInt(int64(r.Intn(2*size+1) - size))
+		} else {
+			v.SetInt(int64(r.Uint64()))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if r.Intn(2) == 0 {
+			v.SetUint(uint64(r.Intn(size + 1)))
+		} else {
+			v.SetUint(r.Uint64())
+		}
+	case reflect.Float32, reflect.Float64:
+		f := (r.Float64()*2 - 1) * float64(size)
+		if v.Kind() == reflect.Float32 {
+			f = math.Max(-math.MaxFloat32, math.Min(math.MaxFloat32, f))
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		v.SetString(String().Generate(r, size))
+	case reflect.Slice:
+		n := 0
+		if depth < maxDepth {
+			n = r.Intn(size/(depth+1) + 1)
+		}
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			fill(s.Index(i), r, size, depth+1)
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fill(v.Index(i), r, size, depth+1)
+		}
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		if depth < maxDepth {
+			for i := r.Intn(size/(depth+1) + 1); i > 0; i-- {
+				key := reflect.New(v.Type().Key()).Elem()
+				elem := reflect.New(v.Type().Elem()).Elem()
+				fill(key, r, size, depth+1)
+				fill(elem, r, size, depth+1)
+				m.SetMapIndex(key, elem)
+			}
+		}
+		v.Set(m)
+	case reflect.Ptr:
+		if depth >= maxDepth || r.Intn(4) == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		p := reflect.New(v.Type().Elem())
+		fill(p.Elem(), r, size, depth+1)
+		v.Set(p)
+	case reflect.Struct:
+		if v.Type() == timeType {
+			v.Set(reflect.ValueOf(time.Unix(r.Int63n(1<<33), 0).UTC()))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				fill(v.Field(i), r, size, depth)
+			}
+		}
+	}
+}
+
+// shrinkValue returns copies of v with one part replaced by its zero value
+func shrinkValue(v reflect.Value) []reflect.Value {
+	if v.IsZero() {
+		return nil
+	}
+	zero := reflect.New(v.Type()).Elem()
+	candidates := []reflect.Value{zero}
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return candidates
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Field(i).CanSet() {
+				continue
+			}
+			for _, field := range shrinkValue(v.Field(i)) {
+				c := reflect.New(v.Type()).Elem()
+				c.Set(v)
+				c.Field(i).Set(field)
+				candidates = append(candidates, c)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			c := reflect.AppendSlice(reflect.MakeSlice(v.Type(), 0, v.Len()-1), v.Slice(0, i))
+			candidates = append(candidates, reflect.AppendSlice(c, v.Slice(i+1, v.Len())))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c := reflect.New(v.Type()).Elem()
+		c.SetInt(v.Int() / 2)
+		candidates = append(candidates, c)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		c := reflect.New(v.Type()).Elem()
+		c.SetUint(v.Uint() / 2)
+		candidates = append(candidates, c)
+	case reflect.String:
+		for _, s := range shrinkString(v.String()) {
+			c := reflect.New(v.Type()).Elem()
+			c.SetString(s)
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
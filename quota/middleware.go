@@ -0,0 +1,103 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type middleware struct {
+	degraded http.Handler
+	cost     func(r *http.Request) int64
+}
+
+type MiddlewareOption = opt.Option[middleware]
+
+// WithDegraded serves requests of exhausted keys with h instead of a 429
+func WithDegraded(h http.Handler) MiddlewareOption {
+	return func(m *middleware) {
+		m.degraded = h
+	}
+}
+
+// WithCost sets what a request consumes, 1 by default
+func WithCost(cost func(r *http.Request) int64) MiddlewareOption {
+	return func(m *middleware) {
+		m.cost = cost
+	}
+}
+
+type usageKey struct{}
+
+// UsageFromContext returns the usage recorded for the request by Middleware,
+// handlers can use it to degrade past a soft limit
+func UsageFromContext(ctx context.Context) ([]Usage, bool) {
+	usages, ok := ctx.Value(usageKey{}).([]Usage)
+	return usages, ok
+}
+
+// OverSoftLimit reports whether any usage is at or past its soft limit
+func OverSoftLimit(usages []Usage) bool {
+	for _, u := range usages {
+		if u.Limit.Soft > 0 && u.Used >= u.Limit.Soft {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware consumes the quota of the key returned by key for every request.
+// Requests of exhausted keys get 429 with Retry-After, or the WithDegraded
+// handler. Requests with an empty key and store failures pass unchecked.
+// X-Quota-Limit, X-Quota-Remaining and X-Quota-Reset describe the tightest hard limit.
+func Middleware(t *Tracker, key func(r *http.Request) string, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := opt.Apply(&middleware{cost: func(*http.Request) int64 { return 1 }}, opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			usages, err := t.Consume(r.Context(), k, m.cost(r))
+			if err != nil && !errors.Is(err, ErrExhausted) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			setHeaders(w.Header(), usages)
+			r = r.WithContext(context.WithValue(r.Context(), usageKey{}, usages))
+			if err == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if m.degraded != nil {
+				m.degraded.ServeHTTP(w, r)
+				return
+			}
+			exhausted := usages[len(usages)-1]
+			retryAfter := math.Ceil(exhausted.ResetAt.Sub(t.now()).Seconds())
+			w.Header().Set("Retry-After", strconv.FormatFloat(math.Max(retryAfter, 1), 'f', 0, 64))
+			http.Error(w, "quota exhausted", http.StatusTooManyRequests)
+		})
+	}
+}
+
+func setHeaders(header http.Header, usages []Usage) {
+	var tightest *Usage
+	for i := range usages {
+		u := &usages[i]
+		if u.Limit.Hard > 0 && (tightest == nil || u.Remaining < tightest.Remaining) {
+			tightest = u
+		}
+	}
+	if tightest == nil {
+		return
+	}
+	header.Set("X-Quota-Limit", strconv.FormatInt(tightest.Limit.Hard, 10))
+	header.Set("X-Quota-Remaining", strconv.FormatInt(tightest.Remaining, 10))
+	header.Set("X-Quota-Reset", strconv.FormatInt(tightest.ResetAt.Unix(), 10))
+}
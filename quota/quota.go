@@ -0,0 +1,183 @@
+// Package quota tracks consumption per key against daily and monthly quotas
+// that roll over at calendar boundaries of a time zone.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// ErrExhausted is returned when consuming would exceed a hard limit
+var ErrExhausted = errors.New("quota: exhausted")
+
+// Period is the calendar window a limit applies to
+type Period int
+
+const (
+	Daily Period = iota
+	Monthly
+)
+
+func (p Period) String() string {
+	if p == Monthly {
+		return "monthly"
+	}
+	return "daily"
+}
+
+// Limit is a quota for one period. Crossing Soft only notifies, Hard rejects,
+// zero disables either.
+type Limit struct {
+	Period Period
+	Soft   int64
+	Hard   int64
+}
+
+// Usage is the state of one limit for a key after a call
+type Usage struct {
+	Key    string
+	Limit  Limit
+	Window string
+	Used   int64
+	// Remaining is left before the hard limit, -1 without one
+	Remaining int64
+	ResetAt   time.Time
+}
+
+// Event is passed to the soft and hard limit callbacks
+type Event struct {
+	Usage Usage
+	// Hard is set for the hard limit callback
+	Hard bool
+}
+
+type Option = opt.Option[Tracker]
+
+// WithLocation rolls the windows over at midnight in loc, UTC by default
+func WithLocation(loc *time.Location) Option {
+	return func(t *Tracker) {
+		t.loc = loc
+	}
+}
+
+// WithOnSoftLimit calls fn when a key crosses a soft limit
+func WithOnSoftLimit(fn func(ctx context.Context, e Event)) Option {
+	return func(t *Tracker) {
+		t.onSoft = fn
+	}
+}
+
+// WithOnHardLimit calls fn when a key reaches a hard limit
+func WithOnHardLimit(fn func(ctx context.Context, e Event)) Option {
+	return func(t *Tracker) {
+		t.onHard = fn
+	}
+}
+
+// Tracker counts consumption in a Store
+type Tracker struct {
+	store  Store
+	limits []Limit
+	loc    *time.Location
+	onSoft func(ctx context.Context, e Event)
+	onHard func(ctx context.Context, e Event)
+	now    func() time.Time
+}
+
+// New creates a Tracker applying limits to every key
+func New(store Store, limits []Limit, opts ...Option) (*Tracker, error) {
+	t := &Tracker{store: store, limits: limits, loc: time.UTC, now: time.Now}
+	err := opt.Build(t, opts, func(t *Tracker) error {
+		for _, l := range t.limits {
+			if l.Soft < 0 || l.Hard < 0 || (l.Hard > 0 && l.Soft > l.Hard) {
+				return fmt.Errorf("quota: invalid %v limit soft %d hard %d", l.Period, l.Soft, l.Hard)
+			}
+		}
+		if t.loc == nil {
+			return errors.New("quota: location must not be nil")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Consume adds n to every limit of key. When a hard limit would be exceeded
+// nothing is added and the error is ErrExhausted, the usage is returned either way.
+func (t *Tracker) Consume(ctx context.Context, key string, n int64) ([]Usage, error) {
+	now := t.now().In(t.loc)
+	usages := make([]Usage, len(t.limits))
+	for i, l := range t.limits {
+		window, reset := l.window(now)
+		used, ok, err := t.store.Incr(ctx, storeKey(key, window), n, l.Hard, reset)
+		if err != nil || !ok {
+			// roll back the windows already counted
+			for j := 0; j < i; j++ {
+				_, _, _ = t.store.Incr(ctx, storeKey(key, usages[j].Window), -n, 0, usages[j].ResetAt)
+				usages[j].Used -= n
+				usages[j].Remaining = remaining(usages[j].Limit, usages[j].Used)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		usages[i] = Usage{Key: key, Limit: l, Window: window, Used: used, Remaining: remaining(l, used), ResetAt: reset}
+		if !ok {
+			return usages[:i+1], fmt.Errorf("%w: %s %v limit of %d", ErrExhausted, key, l.Period, l.Hard)
+		}
+	}
+	for _, u := range usages {
+		before := u.Used - n
+		if t.onSoft != nil && u.Limit.Soft > 0 && before < u.Limit.Soft && u.Used >= u.Limit.Soft {
+			t.onSoft(ctx, Event{Usage: u})
+		}
+		if t.onHard != nil && u.Limit.Hard > 0 && before < u.Limit.Hard && u.Used >= u.Limit.Hard {
+			t.onHard(ctx, Event{Usage: u, Hard: true})
+		}
+	}
+	return usages, nil
+}
+
+// Usage returns the current usage of key without consuming
+func (t *Tracker) Usage(ctx context.Context, key string) ([]Usage, error) {
+	now := t.now().In(t.loc)
+	usages := make([]Usage, len(t.limits))
+	for i, l := range t.limits {
+		window, reset := l.window(now)
+		used, _, err := t.store.Incr(ctx, storeKey(key, window), 0, 0, reset)
+		if err != nil {
+			return nil, err
+		}
+		usages[i] = Usage{Key: key, Limit: l, Window: window, Used: used, Remaining: remaining(l, used), ResetAt: reset}
+	}
+	return usages, nil
+}
+
+// window names the calendar window of now and returns when it ends
+func (l Limit) window(now time.Time) (string, time.Time) {
+	year, month, day := now.Date()
+	if l.Period == Monthly {
+		return now.Format("m:2006-01"), time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location())
+	}
+	return now.Format("d:2006-01-02"), time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+}
+
+func remaining(l Limit, used int64) int64 {
+	if l.Hard == 0 {
+		return -1
+	}
+	if used >= l.Hard {
+		return 0
+	}
+	return l.Hard - used
+}
+
+func storeKey(key string, window string) string {
+	return key + "|" + window
+}
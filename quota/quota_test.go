@@ -0,0 +1,150 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestTracker(t *testing.T, store Store, now *time.Time, opts ...Option) *Tracker {
+	t.Helper()
+	tracker, err := New(store, []Limit{{Period: Daily, Soft: 2, Hard: 3}, {Period: Monthly, Hard: 5}}, opts...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tracker.now = func() time.Time { return *now }
+	return tracker
+}
+
+func TestConsume(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	// 23:30 UTC is already the next day in Berlin
+	now := time.Date(2024, 1, 29, 23, 30, 0, 0, time.UTC)
+	var soft, hard []string
+	tracker := newTestTracker(t, NewMemoryStore(), &now,
+		WithLocation(berlin),
+		WithOnSoftLimit(func(ctx context.Context, e Event) { soft = append(soft, e.Usage.Window) }),
+		WithOnHardLimit(func(ctx context.Context, e Event) { hard = append(hard, e.Usage.Window) }),
+	)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		n       int64
+		used    []int64
+		wantErr bool
+	}{
+		{name: "first", n: 1, used: []int64{1, 1}},
+		{name: "soft", n: 1, used: []int64{2, 2}},
+		{name: "over daily hard", n: 2, used: []int64{2}, wantErr: true},
+		{name: "daily hard", n: 1, used: []int64{3, 3}},
+		{name: "exhausted", n: 1, used: []int64{3}, wantErr: true},
+		{name: "next day", advance: 24 * time.Hour, n: 2, used: []int64{2, 5}},
+		{name: "monthly exhausted, daily rolled back", n: 1, used: []int64{2, 5}, wantErr: true},
+		{name: "next month in berlin", advance: 24 * time.Hour, n: 1, used: []int64{1, 1}},
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		usages, err := tracker.Consume(ctx, "alice", tt.n)
+		if errors.Is(err, ErrExhausted) != tt.wantErr {
+			t.Errorf("%s: Consume() error got = %v, want exhausted %v", tt.name, err, tt.wantErr)
+		}
+		var used []int64
+		for _, u := range usages {
+			used = append(used, u.Used)
+		}
+		if len(used) != len(tt.used) || used[0] != tt.used[0] || (len(used) > 1 && used[1] != tt.used[1]) {
+			t.Errorf("%s: Consume() used got = %v, want %v", tt.name, used, tt.used)
+		}
+	}
+	usages, _ := tracker.Usage(ctx, "alice")
+	if usages[0].Window != "d:2024-02-01" || usages[1].Window != "m:2024-02" || usages[0].Used != 1 {
+		t.Errorf("Usage() got = %+v", usages)
+	}
+	if want := time.Date(2024, 2, 2, 0, 0, 0, 0, berlin); !usages[0].ResetAt.Equal(want) {
+		t.Errorf("Usage() reset got = %v, want %v", usages[0].ResetAt, want)
+	}
+	if len(soft) != 2 || len(hard) != 2 {
+		t.Errorf("callbacks got = soft %v hard %v, want 2 each", soft, hard)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	store.now = func() time.Time { return now }
+	tracker := newTestTracker(t, store, &now)
+	_, _ = tracker.Consume(context.Background(), "bob", 2)
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore() reload error = %v", err)
+	}
+	store.now = func() time.Time { return now }
+	tracker = newTestTracker(t, store, &now)
+	usages, err := tracker.Usage(context.Background(), "bob")
+	if err != nil || usages[0].Used != 2 || usages[1].Used != 2 {
+		t.Errorf("Usage() after reload got = %+v %v, want 2", usages, err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC)
+	tracker := newTestTracker(t, NewMemoryStore(), &now)
+	key := func(r *http.Request) string { return r.Header.Get("X-Api-Key") }
+	var degradedSeen bool
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		usages, _ := UsageFromContext(r.Context())
+		degradedSeen = OverSoftLimit(usages)
+	})
+	handler := Middleware(tracker, key)(ok)
+
+	tests := []struct {
+		apiKey     string
+		code       int
+		remaining  string
+		retryAfter string
+		soft       bool
+	}{
+		{apiKey: "k", code: 200, remaining: "2"},
+		{apiKey: "k", code: 200, remaining: "1", soft: true},
+		{apiKey: "k", code: 200, remaining: "0", soft: true},
+		{apiKey: "k", code: 429, remaining: "0", retryAfter: "60"},
+		{apiKey: "", code: 200},
+	}
+	for i, tt := range tests {
+		degradedSeen = false
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", tt.apiKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.code || rec.Header().Get("X-Quota-Remaining") != tt.remaining ||
+			rec.Header().Get("Retry-After") != tt.retryAfter || degradedSeen != tt.soft {
+			t.Errorf("request %d got = %v %v %v %v, want %+v", i, rec.Code, rec.Header().Get("X-Quota-Remaining"),
+				rec.Header().Get("Retry-After"), degradedSeen, tt)
+		}
+	}
+
+	degraded := Middleware(tracker, key, WithDegraded(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNonAuthoritativeInfo)
+	})))(ok)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Api-Key", "k")
+	rec := httptest.NewRecorder()
+	degraded.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNonAuthoritativeInfo {
+		t.Errorf("degraded got = %v, want %v", rec.Code, http.StatusNonAuthoritativeInfo)
+	}
+}
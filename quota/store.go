@@ -0,0 +1,132 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store keeps the counters of a Tracker
+type Store interface {
+	// Incr adds n to the counter of key unless the result would exceed max,
+	// max 0 means no limit. It returns the counter after the call and whether
+	// n was added. The counter may be dropped after expires.
+	Incr(ctx context.Context, key string, n int64, max int64, expires time.Time) (int64, bool, error)
+}
+
+type counter struct {
+	Count   int64     `json:"count"`
+	Expires time.Time `json:"expires"`
+}
+
+// counters are the counters of a store. Keys name their window, so expired
+// counters are never read again and are swept once a minute.
+type counters struct {
+	m         map[string]*counter
+	nextSweep time.Time
+}
+
+func (cs *counters) incr(now time.Time, key string, n int64, max int64, expires time.Time) (int64, bool) {
+	if cs.m == nil {
+		cs.m = make(map[string]*counter)
+	}
+	if now.After(cs.nextSweep) {
+		for k, c := range cs.m {
+			if now.After(c.Expires) {
+				delete(cs.m, k)
+			}
+		}
+		cs.nextSweep = now.Add(time.Minute)
+	}
+	c, found := cs.m[key]
+	if !found {
+		c = &counter{Expires: expires}
+	}
+	if max > 0 && c.Count+n > max {
+		return c.Count, false
+	}
+	c.Count += n
+	if n != 0 && !found {
+		cs.m[key] = c
+	}
+	return c.Count, true
+}
+
+// MemoryStore keeps the counters in memory
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters counters
+	now      func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now}
+}
+
+func (s *MemoryStore) Incr(ctx context.Context, key string, n int64, max int64, expires time.Time) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count, ok := s.counters.incr(s.now(), key, n, max, expires)
+	return count, ok, nil
+}
+
+// FileStore keeps the counters in a JSON file so they survive restarts. The
+// file is rewritten on every change, it suits a single process with moderate traffic.
+type FileStore struct {
+	mu       sync.Mutex
+	path     string
+	counters counters
+	now      func() time.Time
+}
+
+// NewFileStore loads the counters from path, a missing file starts empty
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, now: time.Now}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.counters.m); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Incr(ctx context.Context, key string, n int64, max int64, expires time.Time) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count, ok := s.counters.incr(s.now(), key, n, max, expires)
+	if !ok || n == 0 {
+		return count, ok, nil
+	}
+	return count, ok, s.save()
+}
+
+// save writes a temporary file and renames it, so a crash leaves the old or the new state
+func (s *FileStore) save() error {
+	data, err := json.Marshal(s.counters.m)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
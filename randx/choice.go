@@ -0,0 +1,74 @@
+package randx
+
+import "errors"
+
+// Choice returns a random item of items drawn from r, the Default Rand when
+// nil. It panics for empty items.
+func Choice[T any](r *Rand, items []T) T {
+	if len(items) == 0 {
+		panic("randx: Choice from no items")
+	}
+	return items[or(r).IntN(len(items))]
+}
+
+// WeightedChoice returns an item of items with a probability proportional to
+// its weight, drawn from r or the Default Rand when nil. Items with a weight
+// of 0 or below are never picked.
+func WeightedChoice[T any](r *Rand, items []T, weight func(T) float64) (T, error) {
+	var total float64
+	weights := make([]float64, len(items))
+	for i, item := range items {
+		if w := weight(item); w > 0 {
+			weights[i] = w
+			total += w
+		}
+	}
+	var zero T
+	if total == 0 {
+		return zero, ErrNoWeight
+	}
+	x := or(r).Float64() * total
+	last := -1
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		if x < w {
+			return items[i], nil
+		}
+		x -= w
+		last = i
+	}
+	// rounding left x at the very end
+	return items[last], nil
+}
+
+// Sample returns k distinct items of items in random order, drawn from r or
+// the Default Rand when nil, without replacement: an item is returned at most
+// once. All items are returned shuffled when k exceeds them. items is not
+// changed.
+func Sample[T any](r *Rand, items []T, k int) ([]T, error) {
+	if k < 0 {
+		return nil, errors.New("randx: negative sample size")
+	}
+	r = or(r)
+	pool := append([]T(nil), items...)
+	if k > len(pool) {
+		k = len(pool)
+	}
+	// a partial Fisher-Yates shuffle, the first k are the sample
+	for i := 0; i < k; i++ {
+		j := i + r.IntN(len(pool)-i)
+		pool[i], pool[j] = pool[j], pool[i]
+	}
+	return pool[:k:k], nil
+}
+
+// Shuffle shuffles s in place with r, the Default Rand when nil
+func Shuffle[T any](r *Rand, s []T) {
+	r = or(r)
+	for i := len(s) - 1; i > 0; i-- {
+		j := r.IntN(i + 1)
+		s[i], s[j] = s[j], s[i]
+	}
+}
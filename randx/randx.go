@@ -0,0 +1,184 @@
+// Package randx draws random values from crypto/rand, safe for tokens and
+// keys: bytes and their hex and base64 forms, integers in a range, floats,
+// weighted choices and samples.
+//
+// Tests needing the same values on every run use NewSeeded, or Seed to make
+// the package functions deterministic. Seeded values are predictable and not
+// for secrets.
+//
+//	token := randx.Base64(32)
+//	server, err := randx.WeightedChoice(nil, servers, func(s Server) float64 { return s.Weight })
+package randx
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	mrand "math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNoWeight is returned by WeightedChoice when no item has a positive weight
+var ErrNoWeight = errors.New("randx: no item with a positive weight")
+
+// Rand is a source of random values, safe for concurrent use
+type Rand struct {
+	mu     sync.Mutex
+	seeded *mrand.Rand
+}
+
+// New returns a Rand reading crypto/rand
+func New() *Rand {
+	return &Rand{}
+}
+
+// NewSeeded returns a Rand giving the same values for the same seed, for
+// tests. Its values are predictable and not for secrets.
+func NewSeeded(seed int64) *Rand {
+	return &Rand{seeded: mrand.New(mrand.NewSource(seed))}
+}
+
+var defaultRand atomic.Value
+
+func init() {
+	defaultRand.Store(New())
+}
+
+// Default returns the Rand of the package functions, reading crypto/rand
+// unless Seed replaced it
+func Default() *Rand {
+	return defaultRand.Load().(*Rand)
+}
+
+// Seed makes the package functions deterministic until restore is called,
+// for tests: defer randx.Seed(1)()
+func Seed(seed int64) (restore func()) {
+	prev := Default()
+	defaultRand.Store(NewSeeded(seed))
+	return func() {
+		defaultRand.Store(prev)
+	}
+}
+
+// or returns r, or the Default Rand for nil
+func or(r *Rand) *Rand {
+	if r == nil {
+		return Default()
+	}
+	return r
+}
+
+// Read fills p with random bytes, it implements io.Reader and never fails.
+// A failing crypto/rand leaves nothing safe to do and panics, like the idgen
+// package.
+func (r *Rand) Read(p []byte) (int, error) {
+	if r.seeded != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.seeded.Read(p)
+	}
+	if _, err := rand.Read(p); err != nil {
+		panic("randx: reading random bytes failed: " + err.Error())
+	}
+	return len(p), nil
+}
+
+// Bytes returns n random bytes
+func (r *Rand) Bytes(n int) []byte {
+	b := make([]byte, n)
+	_, _ = r.Read(b)
+	return b
+}
+
+// Hex returns n random bytes in hex, 2n characters
+func (r *Rand) Hex(n int) string {
+	return hex.EncodeToString(r.Bytes(n))
+}
+
+// Base64 returns n random bytes in unpadded URL-safe base64, usable in URLs
+// and file names as is
+func (r *Rand) Base64(n int) string {
+	return base64.RawURLEncoding.EncodeToString(r.Bytes(n))
+}
+
+// Uint64 returns a random uint64
+func (r *Rand) Uint64() uint64 {
+	var b [8]byte
+	_, _ = r.Read(b[:])
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// IntN returns a random int from 0 to n-1, it panics for n below 1
+func (r *Rand) IntN(n int) int {
+	if n < 1 {
+		panic("randx: IntN with n below 1")
+	}
+	return int(r.uint64n(uint64(n)))
+}
+
+// uint64n returns a uniform value below n, dropping the values that would
+// favor the low results
+func (r *Rand) uint64n(n uint64) uint64 {
+	if n&(n-1) == 0 {
+		return r.Uint64() & (n - 1)
+	}
+	limit := math.MaxUint64 - math.MaxUint64%n
+	for {
+		if v := r.Uint64(); v < limit {
+			return v % n
+		}
+	}
+}
+
+// IntRange returns a random int from min to max included, it panics when max
+// is below min
+func (r *Rand) IntRange(min int, max int) int {
+	if max < min {
+		panic("randx: IntRange with max below min")
+	}
+	span := uint64(max) - uint64(min) + 1
+	if span == 0 {
+		// the range covers every uint64
+		return int(r.Uint64())
+	}
+	return min + int(r.uint64n(span))
+}
+
+// Float64 returns a random float64 from 0 up to 1 excluded
+func (r *Rand) Float64() float64 {
+	return float64(r.Uint64()>>11) / (1 << 53)
+}
+
+// Bytes returns n random bytes from the Default Rand
+func Bytes(n int) []byte {
+	return Default().Bytes(n)
+}
+
+// Hex returns n random bytes in hex from the Default Rand
+func Hex(n int) string {
+	return Default().Hex(n)
+}
+
+// Base64 returns n random bytes in URL-safe base64 from the Default Rand
+func Base64(n int) string {
+	return Default().Base64(n)
+}
+
+// IntN returns a random int from 0 to n-1 from the Default Rand
+func IntN(n int) int {
+	return Default().IntN(n)
+}
+
+// IntRange returns a random int from min to max included from the Default Rand
+func IntRange(min int, max int) int {
+	return Default().IntRange(min, max)
+}
+
+// Float64 returns a random float64 in [0, 1) from the Default Rand
+func Float64() float64 {
+	return Default().Float64()
+}
@@ -0,0 +1,130 @@
+package randx
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestBytes(t *testing.T) {
+	if b := Bytes(16); len(b) != 16 || reflect.DeepEqual(b, Bytes(16)) {
+		t.Errorf("Bytes() got = %x, want 16 bytes differing between calls", b)
+	}
+	h := Hex(8)
+	if _, err := hex.DecodeString(h); err != nil || len(h) != 16 {
+		t.Errorf("Hex() got = %q %v", h, err)
+	}
+	s := Base64(32)
+	if b, err := base64.RawURLEncoding.DecodeString(s); err != nil || len(b) != 32 {
+		t.Errorf("Base64() got = %q %v", s, err)
+	}
+}
+
+func TestInts(t *testing.T) {
+	counts := make(map[int]int)
+	for i := 0; i < 30000; i++ {
+		v := IntRange(-1, 1)
+		if v < -1 || v > 1 {
+			t.Fatalf("IntRange(-1, 1) got = %v", v)
+		}
+		counts[v]++
+	}
+	for v, n := range counts {
+		if n < 9000 || n > 11000 {
+			t.Errorf("IntRange() %v drawn %v times of 30000, want about 10000", v, n)
+		}
+	}
+	if v := IntRange(5, 5); v != 5 {
+		t.Errorf("IntRange(5, 5) got = %v", v)
+	}
+	_ = IntRange(math.MinInt, math.MaxInt)
+	for i := 0; i < 100; i++ {
+		if v := IntN(8); v < 0 || v >= 8 {
+			t.Fatalf("IntN(8) got = %v", v)
+		}
+		if f := Float64(); f < 0 || f >= 1 {
+			t.Fatalf("Float64() got = %v", f)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("IntN(0) did not panic")
+		}
+	}()
+	IntN(0)
+}
+
+func TestSeeded(t *testing.T) {
+	a, b := NewSeeded(1), NewSeeded(1)
+	if a.Hex(8) != b.Hex(8) || a.IntN(1000) != b.IntN(1000) || a.Float64() != b.Float64() {
+		t.Errorf("NewSeeded() got different values for the same seed")
+	}
+	restore := Seed(3)
+	first := Hex(8)
+	restore()
+	if Hex(8) == first {
+		t.Errorf("Seed() restore kept the seeded source")
+	}
+	defer Seed(3)()
+	if got := Hex(8); got != first {
+		t.Errorf("Seed() got = %v, want %v", got, first)
+	}
+}
+
+func TestChoice(t *testing.T) {
+	r := NewSeeded(1)
+	type server struct {
+		name   string
+		weight float64
+	}
+	servers := []server{{"a", 1}, {"b", 3}, {"off", 0}, {"down", -1}}
+	counts := make(map[string]int)
+	for i := 0; i < 8000; i++ {
+		s, err := WeightedChoice(r, servers, func(s server) float64 { return s.weight })
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[s.name]++
+	}
+	if counts["off"] != 0 || counts["down"] != 0 || counts["a"] < 1700 || counts["a"] > 2300 || counts["b"] < 5700 {
+		t.Errorf("WeightedChoice() got = %v, want a 1:3 split", counts)
+	}
+	if _, err := WeightedChoice(r, servers[2:], func(s server) float64 { return s.weight }); !errors.Is(err, ErrNoWeight) {
+		t.Errorf("WeightedChoice() error got = %v, want %v", err, ErrNoWeight)
+	}
+	if got := Choice(r, []string{"only"}); got != "only" {
+		t.Errorf("Choice() got = %v", got)
+	}
+
+	items := []int{1, 2, 3, 4, 5, 6}
+	sample, err := Sample(r, items, 4)
+	if err != nil || len(sample) != 4 {
+		t.Fatalf("Sample() got = %v %v", sample, err)
+	}
+	seen := make(map[int]bool)
+	for _, v := range sample {
+		if seen[v] {
+			t.Errorf("Sample() got = %v, want distinct items", sample)
+		}
+		seen[v] = true
+	}
+	all, _ := Sample(nil, items, 10)
+	sort.Ints(all)
+	if !reflect.DeepEqual(all, items) || items[0] != 1 {
+		t.Errorf("Sample() all got = %v from %v", all, items)
+	}
+	if _, err := Sample(r, items, -1); err == nil {
+		t.Errorf("Sample(-1) error = nil")
+	}
+	s := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	Shuffle(r, s)
+	sorted := append([]int(nil), s...)
+	sort.Ints(sorted)
+	if reflect.DeepEqual(s, sorted) || sorted[7] != 8 {
+		t.Errorf("Shuffle() got = %v", s)
+	}
+}
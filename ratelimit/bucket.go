@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TokenBucket holds up to burst tokens refilled at a rate, an event takes a
+// token. It allows bursts after idle periods while keeping the average rate.
+type TokenBucket struct {
+	counters
+	cfg    config
+	rate   Rate
+	burst  int
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a full bucket of burst tokens refilled at r
+func NewTokenBucket(r Rate, burst int, opts ...Option) (*TokenBucket, error) {
+	cfg, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRate(r); err != nil {
+		return nil, err
+	}
+	if burst < 1 {
+		return nil, fmt.Errorf("ratelimit: burst %d must be at least 1", burst)
+	}
+	return &TokenBucket{cfg: cfg, rate: r, burst: burst, tokens: float64(burst), last: cfg.now()}, nil
+}
+
+// refill adds the tokens earned since the last call, under the lock
+func (b *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(b.rate.interval())
+		if b.tokens > float64(b.burst) {
+			b.tokens = float64(b.burst)
+		}
+		b.last = now
+	}
+}
+
+func (b *TokenBucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.cfg.now())
+	if b.tokens < 1 {
+		return b.count(false)
+	}
+	b.tokens--
+	return b.count(true)
+}
+
+// Reserve takes a token, borrowing it from the future when the bucket is
+// empty
+func (b *TokenBucket) Reserve() *Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.cfg.now()
+	b.refill(now)
+	b.count(true)
+	b.tokens--
+	at := now
+	if b.tokens < 0 {
+		at = now.Add(time.Duration(-b.tokens * float64(b.rate.interval())))
+	}
+	return &Reservation{ok: true, at: at, now: b.cfg.now, cancel: func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.refill(b.cfg.now())
+		b.tokens++
+		if b.tokens > float64(b.burst) {
+			b.tokens = float64(b.burst)
+		}
+	}}
+}
+
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	return wait(ctx, b)
+}
+
+// Tokens returns the tokens available now
+func (b *TokenBucket) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.cfg.now())
+	return b.tokens
+}
+
+// LeakyBucket lets events through evenly spaced at a rate, queuing at most
+// capacity of them, so there are no bursts
+type LeakyBucket struct {
+	counters
+	cfg      config
+	interval time.Duration
+	capacity int
+	mu       sync.Mutex
+	next     time.Time
+}
+
+// NewLeakyBucket returns a bucket letting events through at r, Reserve and
+// Wait queue up to capacity events
+func NewLeakyBucket(r Rate, capacity int, opts ...Option) (*LeakyBucket, error) {
+	cfg, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRate(r); err != nil {
+		return nil, err
+	}
+	if capacity < 0 {
+		return nil, fmt.Errorf("ratelimit: capacity %d must not be negative", capacity)
+	}
+	return &LeakyBucket{cfg: cfg, interval: r.interval(), capacity: capacity}, nil
+}
+
+// slot returns the time of the next event, under the lock
+func (b *LeakyBucket) slot(now time.Time) time.Time {
+	if b.next.Before(now) {
+		return now
+	}
+	return b.next
+}
+
+func (b *LeakyBucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.cfg.now()
+	at := b.slot(now)
+	if at.After(now) {
+		return b.count(false)
+	}
+	b.next = at.Add(b.interval)
+	return b.count(true)
+}
+
+// Reserve books the next slot, it is not OK when capacity events wait already
+func (b *LeakyBucket) Reserve() *Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.cfg.now()
+	at := b.slot(now)
+	if at.Sub(now) > time.Duration(b.capacity)*b.interval {
+		b.count(false)
+		return &Reservation{now: b.cfg.now}
+	}
+	b.count(true)
+	b.next = at.Add(b.interval)
+	return &Reservation{ok: true, at: at, now: b.cfg.now, cancel: func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		// only the last slot can be given back without moving others
+		if b.next.Equal(at.Add(b.interval)) {
+			b.next = at
+		}
+	}}
+}
+
+func (b *LeakyBucket) Wait(ctx context.Context) error {
+	return wait(ctx, b)
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PerKeyLimiter keeps a limiter per key, e.g. per client IP or per host,
+// created on first use. Limiters of keys unused for the idle timeout are
+// dropped.
+type PerKeyLimiter[K comparable] struct {
+	counters
+	cfg       config
+	create    func(key K) Limiter
+	mu        sync.Mutex
+	limiters  map[K]*keyed
+	lastSweep time.Time
+}
+
+type keyed struct {
+	limiter Limiter
+	used    time.Time
+}
+
+// NewPerKeyLimiter returns a PerKeyLimiter creating the limiter of a key with
+// create
+func NewPerKeyLimiter[K comparable](create func(key K) Limiter, opts ...Option) (*PerKeyLimiter[K], error) {
+	cfg, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PerKeyLimiter[K]{cfg: cfg, create: create, limiters: make(map[K]*keyed), lastSweep: cfg.now()}, nil
+}
+
+// Limiter returns the limiter of key
+func (p *PerKeyLimiter[K]) Limiter(key K) Limiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.cfg.now()
+	if now.Sub(p.lastSweep) >= p.cfg.idle {
+		for k, e := range p.limiters {
+			if now.Sub(e.used) >= p.cfg.idle {
+				delete(p.limiters, k)
+			}
+		}
+		p.lastSweep = now
+	}
+	e, ok := p.limiters[key]
+	if !ok {
+		e = &keyed{limiter: p.create(key)}
+		p.limiters[key] = e
+	}
+	e.used = now
+	return e.limiter
+}
+
+// Allow calls Allow of the limiter of key
+func (p *PerKeyLimiter[K]) Allow(key K) bool {
+	return p.count(p.Limiter(key).Allow())
+}
+
+// Wait calls Wait of the limiter of key
+func (p *PerKeyLimiter[K]) Wait(ctx context.Context, key K) error {
+	err := p.Limiter(key).Wait(ctx)
+	p.count(err == nil)
+	return err
+}
+
+// Reserve calls Reserve of the limiter of key
+func (p *PerKeyLimiter[K]) Reserve(key K) *Reservation {
+	r := p.Limiter(key).Reserve()
+	p.count(r.OK())
+	return r
+}
+
+// Len returns the number of keys with a limiter
+func (p *PerKeyLimiter[K]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.limiters)
+}
+
+// Stats returns the counters of the calls through p and the number of keys
+// with a limiter as active_keys, register it with
+// stats.Register("client_limiter", p)
+func (p *PerKeyLimiter[K]) Stats() map[string]float64 {
+	stats := p.counters.Stats()
+	stats["active_keys"] = float64(p.Len())
+	return stats
+}
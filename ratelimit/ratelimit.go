@@ -0,0 +1,200 @@
+// Package ratelimit has limiters for client and server code: a token bucket
+// allowing bursts, a leaky bucket spacing requests evenly, a sliding window
+// log counting requests exactly, and PerKeyLimiter keeping a limiter per
+// client, host or user. All limiters are safe for concurrent use.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Stellar1999/gotool/errs"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// ErrLimitExceeded is returned by Wait when the request can never be allowed,
+// or not without queuing more than the limiter accepts. It matches
+// errs.ErrRateLimited, a deadline Wait can't meet matches errs.ErrTimeout.
+var ErrLimitExceeded = errs.Mark(errors.New("ratelimit: limit exceeded"), errs.ErrRateLimited)
+
+// Rate is N events per Per
+type Rate struct {
+	N   int
+	Per time.Duration
+}
+
+// PerSecond is n events per second
+func PerSecond(n int) Rate {
+	return Rate{N: n, Per: time.Second}
+}
+
+// PerMinute is n events per minute
+func PerMinute(n int) Rate {
+	return Rate{N: n, Per: time.Minute}
+}
+
+// interval is the time between two events at the rate
+func (r Rate) interval() time.Duration {
+	if r.N <= 0 {
+		return 0
+	}
+	return r.Per / time.Duration(r.N)
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("%d/%v", r.N, r.Per)
+}
+
+// Limiter decides when events may happen
+type Limiter interface {
+	// Allow reports whether an event may happen now, consuming it if so
+	Allow() bool
+	// Wait blocks until an event may happen, consuming it, or ctx is done
+	Wait(ctx context.Context) error
+	// Reserve books an event and tells how long to wait for it
+	Reserve() *Reservation
+}
+
+// Reservation is an event booked with Reserve
+type Reservation struct {
+	ok     bool
+	at     time.Time
+	now    func() time.Time
+	cancel func()
+}
+
+// OK reports whether the event was booked, a limiter refusing to queue it
+// returns a Reservation that is not OK
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay is how long to wait before the event may happen, 0 when it may
+// happen now
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return 0
+	}
+	if d := r.at.Sub(r.now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel gives the event back, when it is not yet due, so later events wait
+// less
+func (r *Reservation) Cancel() {
+	if r.ok && r.cancel != nil && r.at.After(r.now()) {
+		r.cancel()
+	}
+	r.cancel = nil
+}
+
+// counters count the decisions of a limiter for its Stats
+type counters struct {
+	allowed int64
+	denied  int64
+}
+
+// count counts ok as allowed or denied and returns it
+func (c *counters) count(ok bool) bool {
+	if ok {
+		atomic.AddInt64(&c.allowed, 1)
+	} else {
+		atomic.AddInt64(&c.denied, 1)
+	}
+	return ok
+}
+
+// Stats returns the counters of the limiter, register it with
+// stats.Register("api_limiter", l). allowed_total counts the events allowed
+// or booked with Reserve, denied_total the ones refused.
+func (c *counters) Stats() map[string]float64 {
+	return map[string]float64{
+		"allowed_total": float64(atomic.LoadInt64(&c.allowed)),
+		"denied_total":  float64(atomic.LoadInt64(&c.denied)),
+	}
+}
+
+type config struct {
+	now  func() time.Time
+	idle time.Duration
+}
+
+type Option = opt.Option[config]
+
+// WithClock replaces time.Now, for tests
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// WithIdleTimeout makes PerKeyLimiter drop the limiters of keys unused for d,
+// 10 minutes by default. A dropped key starts again with a fresh limiter.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.idle = d
+	}
+}
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.idle <= 0 {
+			return errors.New("ratelimit: idle timeout must be positive")
+		}
+		return nil
+	},
+}
+
+func build(opts []Option) (config, error) {
+	cfg := config{now: time.Now, idle: 10 * time.Minute}
+	err := opt.Build(&cfg, opts, checks...)
+	return cfg, err
+}
+
+func checkRate(r Rate) error {
+	if r.N <= 0 || r.Per <= 0 {
+		return fmt.Errorf("ratelimit: rate %v must be positive", r)
+	}
+	return nil
+}
+
+// wait waits for a reservation of l, giving it back when ctx ends first
+func wait(ctx context.Context, l Limiter) error {
+	if err := ctx.Err(); err != nil {
+		return ctxError(err)
+	}
+	r := l.Reserve()
+	if !r.OK() {
+		return ErrLimitExceeded
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		r.Cancel()
+		return errs.Mark(fmt.Errorf("%w: waiting %v would pass the deadline", context.DeadlineExceeded, delay), errs.ErrTimeout)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctxError(ctx.Err())
+	}
+}
+
+// ctxError marks a deadline as errs.ErrTimeout
+func ctxError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errs.Mark(err, errs.ErrTimeout)
+	}
+	return err
+}
@@ -0,0 +1,226 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/errs"
+	"github.com/Stellar1999/gotool/stats"
+)
+
+var (
+	_ stats.Stats = (*TokenBucket)(nil)
+	_ stats.Stats = (*LeakyBucket)(nil)
+	_ stats.Stats = (*SlidingWindow)(nil)
+	_ stats.Stats = (*PerKeyLimiter[string])(nil)
+)
+
+// clock is a fake time source moved by hand
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newClock() *clock {
+	return &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func allowed(l Limiter, n int) int {
+	got := 0
+	for i := 0; i < n; i++ {
+		if l.Allow() {
+			got++
+		}
+	}
+	return got
+}
+
+func TestTokenBucket(t *testing.T) {
+	c := newClock()
+	b, err := NewTokenBucket(PerSecond(10), 5, WithClock(c.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := allowed(b, 10); got != 5 {
+		t.Errorf("Allow() burst got = %v, want 5", got)
+	}
+	c.Add(250 * time.Millisecond)
+	if got := allowed(b, 10); got != 2 {
+		t.Errorf("Allow() after 250ms got = %v, want 2", got)
+	}
+	c.Add(time.Hour)
+	if got := b.Tokens(); got != 5 {
+		t.Errorf("Tokens() got = %v, want the burst", got)
+	}
+
+	allowed(b, 5)
+	r1, r2 := b.Reserve(), b.Reserve()
+	if r1.Delay() != 100*time.Millisecond || r2.Delay() != 200*time.Millisecond {
+		t.Errorf("Reserve() delays got = %v %v, want 100ms 200ms", r1.Delay(), r2.Delay())
+	}
+	r2.Cancel()
+	if r3 := b.Reserve(); r3.Delay() != 200*time.Millisecond {
+		t.Errorf("Reserve() after Cancel got = %v, want 200ms", r3.Delay())
+	}
+
+	for _, tt := range []struct {
+		r     Rate
+		burst int
+	}{{PerSecond(0), 1}, {Rate{N: 1}, 1}, {PerSecond(1), 0}} {
+		if _, err := NewTokenBucket(tt.r, tt.burst); err == nil {
+			t.Errorf("NewTokenBucket(%v, %v) got = nil, want an error", tt.r, tt.burst)
+		}
+	}
+}
+
+func TestLeakyBucket(t *testing.T) {
+	c := newClock()
+	b, _ := NewLeakyBucket(PerSecond(10), 2, WithClock(c.Now))
+	if got := allowed(b, 3); got != 1 {
+		t.Errorf("Allow() got = %v, want 1 without burst", got)
+	}
+	r1, r2, r3 := b.Reserve(), b.Reserve(), b.Reserve()
+	if !r1.OK() || !r2.OK() || r3.OK() {
+		t.Errorf("Reserve() got = %v %v %v, want 2 queued and the third refused", r1.OK(), r2.OK(), r3.OK())
+	}
+	if r1.Delay() != 100*time.Millisecond || r2.Delay() != 200*time.Millisecond || r3.Delay() != 0 {
+		t.Errorf("Reserve() delays got = %v %v %v", r1.Delay(), r2.Delay(), r3.Delay())
+	}
+	if err := b.Wait(context.Background()); !errors.Is(err, ErrLimitExceeded) || !errors.Is(err, errs.ErrRateLimited) {
+		t.Errorf("Wait() full queue got = %v, want %v", err, ErrLimitExceeded)
+	}
+	r2.Cancel()
+	if r := b.Reserve(); r.Delay() != 200*time.Millisecond {
+		t.Errorf("Reserve() after Cancel got = %v", r.Delay())
+	}
+	c.Add(time.Second)
+	if !b.Allow() || b.Allow() {
+		t.Errorf("Allow() after the queue drained got wrong results")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	c := newClock()
+	w, _ := NewSlidingWindow(3, time.Minute, WithClock(c.Now))
+	allowed(w, 2)
+	c.Add(30 * time.Second)
+	if got := allowed(w, 5); got != 1 || w.Remaining() != 0 {
+		t.Errorf("Allow() got = %v, want 1", got)
+	}
+	// the first 2 events leave the window at 60s
+	c.Add(31 * time.Second)
+	if w.Remaining() != 2 {
+		t.Errorf("Remaining() got = %v, want 2", w.Remaining())
+	}
+	allowed(w, 2)
+	r := w.Reserve()
+	if r.Delay() != 29*time.Second {
+		t.Errorf("Reserve() got = %v, want 29s until the third event leaves", r.Delay())
+	}
+	if w.Allow() {
+		t.Errorf("Allow() got = true behind a reservation")
+	}
+	r.Cancel()
+	c.Add(29 * time.Second)
+	if !w.Allow() {
+		t.Errorf("Allow() got = false once the window moved")
+	}
+}
+
+func TestWait(t *testing.T) {
+	b, _ := NewTokenBucket(PerSecond(50), 1)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := b.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() got = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Wait() 3 events took %v, want about 40ms", elapsed)
+	}
+
+	slow, _ := NewTokenBucket(PerMinute(1), 1)
+	slow.Allow()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := slow.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errs.ErrTimeout) {
+		t.Errorf("Wait() past the deadline got = %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := slow.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() canceled got = %v", err)
+	}
+	if tokens := slow.Tokens(); tokens < -0.1 {
+		t.Errorf("Tokens() got = %v, want the canceled waits given back", tokens)
+	}
+}
+
+func TestPerKeyLimiter(t *testing.T) {
+	c := newClock()
+	p, err := NewPerKeyLimiter(func(string) Limiter {
+		b, _ := NewTokenBucket(PerMinute(1), 1, WithClock(c.Now))
+		return b
+	}, WithClock(c.Now), WithIdleTimeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Allow("a") || p.Allow("a") || !p.Allow("b") {
+		t.Errorf("Allow() got wrong results, want a bucket per key")
+	}
+	if r := p.Reserve("b"); r.Delay() != time.Minute {
+		t.Errorf("Reserve() got = %v", r.Delay())
+	}
+	c.Add(30 * time.Second)
+	p.Allow("c")
+	c.Add(40 * time.Second)
+	p.Limiter("c")
+	if p.Len() != 1 {
+		t.Errorf("Len() got = %v, want the idle keys dropped", p.Len())
+	}
+	if _, err := NewPerKeyLimiter(func(int) Limiter { return nil }, WithIdleTimeout(0)); err == nil {
+		t.Errorf("NewPerKeyLimiter() got = nil, want an error for a zero idle timeout")
+	}
+}
+
+func TestStats(t *testing.T) {
+	c := newClock()
+	b, _ := NewLeakyBucket(PerSecond(1), 1, WithClock(c.Now))
+	b.Allow()
+	b.Allow()
+	b.Reserve()
+	b.Reserve()
+	if got, want := b.Stats(), map[string]float64{"allowed_total": 2, "denied_total": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() got = %v, want %v", got, want)
+	}
+
+	p, _ := NewPerKeyLimiter(func(string) Limiter {
+		b, _ := NewTokenBucket(PerMinute(1), 1, WithClock(c.Now))
+		return b
+	}, WithClock(c.Now))
+	p.Allow("a")
+	p.Allow("a")
+	p.Allow("b")
+	if got, want := p.Stats(), map[string]float64{"allowed_total": 2, "denied_total": 1, "active_keys": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PerKeyLimiter.Stats() got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SlidingWindow allows limit events in any window of time, it keeps the time
+// of every event so the count is exact at the cost of memory per event
+type SlidingWindow struct {
+	counters
+	cfg    config
+	limit  int
+	window time.Duration
+	mu     sync.Mutex
+	// log holds the times of the events in order, some may be in the future
+	// for reservations
+	log []time.Time
+}
+
+// NewSlidingWindow returns a limiter of limit events per window
+func NewSlidingWindow(limit int, window time.Duration, opts ...Option) (*SlidingWindow, error) {
+	cfg, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 || window <= 0 {
+		return nil, fmt.Errorf("ratelimit: limit %d and window %v must be positive", limit, window)
+	}
+	return &SlidingWindow{cfg: cfg, limit: limit, window: window}, nil
+}
+
+// prune drops the events out of the window, under the lock
+func (w *SlidingWindow) prune(now time.Time) {
+	cut := 0
+	for cut < len(w.log) && !w.log[cut].After(now.Add(-w.window)) {
+		cut++
+	}
+	if cut > 0 {
+		w.log = append(w.log[:0], w.log[cut:]...)
+	}
+}
+
+// slot returns the first time an event fits, under the lock
+func (w *SlidingWindow) slot(now time.Time) time.Time {
+	w.prune(now)
+	if len(w.log) < w.limit {
+		return now
+	}
+	return w.log[len(w.log)-w.limit].Add(w.window)
+}
+
+// insert adds t to the log keeping it ordered, reservations may be later
+// than an event allowed now
+func (w *SlidingWindow) insert(t time.Time) {
+	i := sort.Search(len(w.log), func(i int) bool { return w.log[i].After(t) })
+	w.log = append(w.log, time.Time{})
+	copy(w.log[i+1:], w.log[i:])
+	w.log[i] = t
+}
+
+func (w *SlidingWindow) Allow() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.cfg.now()
+	if w.slot(now).After(now) {
+		return w.count(false)
+	}
+	w.insert(now)
+	return w.count(true)
+}
+
+func (w *SlidingWindow) Reserve() *Reservation {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.cfg.now()
+	at := w.slot(now)
+	w.insert(at)
+	w.count(true)
+	return &Reservation{ok: true, at: at, now: w.cfg.now, cancel: func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for i := len(w.log) - 1; i >= 0; i-- {
+			if w.log[i].Equal(at) {
+				w.log = append(w.log[:i], w.log[i+1:]...)
+				return
+			}
+		}
+	}}
+}
+
+func (w *SlidingWindow) Wait(ctx context.Context) error {
+	return wait(ctx, w)
+}
+
+// Remaining returns how many events are allowed now
+func (w *SlidingWindow) Remaining() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.cfg.now()
+	w.prune(now)
+	n := 0
+	for _, t := range w.log {
+		if !t.After(now) {
+			n++
+		}
+	}
+	if len(w.log) > n {
+		// reservations hold the next slots
+		return 0
+	}
+	return w.limit - n
+}
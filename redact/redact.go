@@ -0,0 +1,356 @@
+// Package redact hides secrets before data leaves the process. A Policy holds
+// the rules in one place: field names, JSON paths and patterns for secrets in
+// free text. The same Policy is used by the logger (Logger), the debug dumps
+// of the http package (http.WithRedactor) and the crash reporter
+// (crashreport.WithRedactor). Verify and Leaks help testing a Policy.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Mask replaces redacted values unless WithMask sets another one
+const Mask = "[REDACTED]"
+
+// DefaultFields are the field names redacted by default, see WithFields
+var DefaultFields = []string{
+	"*password*", "*passwd*", "*secret*", "*token*", "*apikey*", "*privatekey*",
+	"authorization", "proxyauthorization", "cookie", "setcookie",
+	"ssn", "creditcard", "cardnumber", "cvv", "cvc",
+}
+
+// DefaultSecrets are the patterns of secrets redacted by default in free
+// text, see WithSecrets
+var DefaultSecrets = []string{
+	// bearer and basic credentials
+	`(?i)\bbearer\s+([a-z0-9\-._~+/]{8,}=*)|\bbasic\s+([a-z0-9+/]{8,}=*)`,
+	// JWTs
+	`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+	// AWS access key ids
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	// GitHub and Slack tokens
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+	// PEM private keys
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+	// passwords in urls and credentials in query strings
+	`://[^/\s:@]+:([^/\s@]+)@`,
+	`(?i)[?&](?:access_token|api_key|apikey|token|password|secret|signature|sig)=([^&\s#"]+)`,
+}
+
+type config struct {
+	fields     []string
+	paths      []string
+	secrets    []string
+	noDefaults bool
+	mask       string
+}
+
+type Option = opt.Option[config]
+
+// WithFields redacts the values of fields, headers and log keys whose name
+// matches one of the patterns. Matching ignores case, '-' and '_', so
+// "apikey" matches "X-Api-Key" and "api_key"; '*' matches any characters.
+func WithFields(patterns ...string) Option {
+	return func(c *config) {
+		c.fields = append(c.fields, patterns...)
+	}
+}
+
+// WithPaths redacts the values at JSON paths like "user.ssn" or
+// "cards.*.number", '*' matches any key or list element
+func WithPaths(paths ...string) Option {
+	return func(c *config) {
+		c.paths = append(c.paths, paths...)
+	}
+}
+
+// WithSecrets redacts the matches of regular expressions in free text. When
+// a pattern has groups only the first group taking part in the match is
+// replaced, so the context of the secret stays readable.
+func WithSecrets(patterns ...string) Option {
+	return func(c *config) {
+		c.secrets = append(c.secrets, patterns...)
+	}
+}
+
+// WithoutDefaults drops DefaultFields and DefaultSecrets
+func WithoutDefaults() Option {
+	return func(c *config) {
+		c.noDefaults = true
+	}
+}
+
+// WithMask replaces redacted values with mask instead of Mask
+func WithMask(mask string) Option {
+	return func(c *config) {
+		c.mask = mask
+	}
+}
+
+// Policy is a set of redaction rules, it is safe for concurrent use
+type Policy struct {
+	fields  []string
+	paths   [][]string
+	secrets []*regexp.Regexp
+	mask    string
+}
+
+// Default is the Policy with the default rules
+var Default = MustNew()
+
+// New compiles a Policy, it starts with the default rules
+func New(opts ...Option) (*Policy, error) {
+	cfg := config{mask: Mask}
+	if err := opt.Build(&cfg, opts); err != nil {
+		return nil, err
+	}
+	fields, secrets := cfg.fields, cfg.secrets
+	if !cfg.noDefaults {
+		fields = append(append([]string{}, DefaultFields...), fields...)
+		secrets = append(append([]string{}, DefaultSecrets...), secrets...)
+	}
+	p := &Policy{mask: cfg.mask}
+	for _, pattern := range fields {
+		pattern = normalize(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("redact: field pattern %q: %w", pattern, err)
+		}
+		p.fields = append(p.fields, pattern)
+	}
+	for _, jsonPath := range cfg.paths {
+		jsonPath = strings.TrimPrefix(strings.TrimPrefix(jsonPath, "$"), ".")
+		if jsonPath == "" {
+			return nil, fmt.Errorf("redact: empty json path")
+		}
+		p.paths = append(p.paths, strings.Split(jsonPath, "."))
+	}
+	for _, pattern := range secrets {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact: secret pattern: %w", err)
+		}
+		p.secrets = append(p.secrets, re)
+	}
+	return p, nil
+}
+
+// MustNew is New panicking on invalid rules, for package level policies
+func MustNew(opts ...Option) *Policy {
+	p, err := New(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func normalize(name string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+}
+
+// Field reports whether values of the field name are redacted
+func (p *Policy) Field(name string) bool {
+	name = normalize(name)
+	for _, pattern := range p.fields {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Text replaces the secrets in s
+func (p *Policy) Text(s string) string {
+	for _, re := range p.secrets {
+		matches := re.FindAllStringSubmatchIndex(s, -1)
+		if matches == nil {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			// the first group that took part in the match
+			for g := 2; g < len(m); g += 2 {
+				if m[g] >= 0 {
+					start, end = m[g], m[g+1]
+					break
+				}
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(p.mask)
+			last = end
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
+
+// Value redacts v as the value of the field name: everything for a redacted
+// name, otherwise the secrets in strings and the fields of maps and lists
+func (p *Policy) Value(name string, v any) any {
+	if name != "" && p.Field(name) {
+		return p.mask
+	}
+	switch v := v.(type) {
+	case string:
+		return p.Text(v)
+	case []byte:
+		return p.Body(v)
+	case error:
+		// a changed error loses its chain, the causes hold the secret too
+		if msg := v.Error(); p.Text(msg) != msg {
+			return redactedError(p.Text(msg))
+		}
+	case map[string]any:
+		return p.Map(v)
+	case map[string]string:
+		out := make(map[string]string, len(v))
+		for k, s := range v {
+			out[k] = p.Value(k, s).(string)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = p.Value("", item)
+		}
+		return out
+	case http.Header:
+		return p.Header(v)
+	case fmt.Stringer:
+		if text := v.String(); p.Text(text) != text {
+			return p.Text(text)
+		}
+	}
+	return v
+}
+
+type redactedError string
+
+func (e redactedError) Error() string {
+	return string(e)
+}
+
+// Map returns a redacted copy of m
+func (p *Policy) Map(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = p.Value(k, v)
+	}
+	return out
+}
+
+// KeyValues returns a redacted copy of alternating key-value pairs, the
+// arguments of the gohttp.Logger methods
+func (p *Policy) KeyValues(keysAndValues []any) []any {
+	out := make([]any, len(keysAndValues))
+	for i := 0; i < len(keysAndValues); i++ {
+		if i%2 == 0 {
+			out[i] = keysAndValues[i]
+			continue
+		}
+		key, _ := keysAndValues[i-1].(string)
+		out[i] = p.Value(key, keysAndValues[i])
+	}
+	return out
+}
+
+// Header returns a redacted copy of header
+func (p *Policy) Header(header http.Header) http.Header {
+	out := make(http.Header, len(header))
+	for name, values := range header {
+		if p.Field(name) {
+			out[name] = []string{p.mask}
+			continue
+		}
+		redacted := make([]string, len(values))
+		for i, v := range values {
+			redacted[i] = p.Text(v)
+		}
+		out[name] = redacted
+	}
+	return out
+}
+
+// Body redacts a JSON document by field names, paths and secrets, other
+// bodies like form posts and text are redacted as text
+func (p *Policy) Body(body []byte) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return []byte(p.Text(string(body)))
+	}
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return []byte(p.Text(string(body)))
+	}
+	for _, jsonPath := range p.paths {
+		doc = p.redactPath(doc, jsonPath)
+	}
+	data, err := json.Marshal(p.Value("", doc))
+	if err != nil {
+		return []byte(p.Text(string(body)))
+	}
+	return data
+}
+
+// redactPath masks the values at jsonPath in doc
+func (p *Policy) redactPath(doc any, jsonPath []string) any {
+	if len(jsonPath) == 0 {
+		return p.mask
+	}
+	key, rest := jsonPath[0], jsonPath[1:]
+	switch v := doc.(type) {
+	case map[string]any:
+		for k, item := range v {
+			if key == "*" || key == k {
+				v[k] = p.redactPath(item, rest)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			if key == "*" || key == fmt.Sprint(i) {
+				v[i] = p.redactPath(item, rest)
+			}
+		}
+	}
+	return doc
+}
+
+// Logger redacts the message and the values of the calls before next logs them
+func Logger(next gohttp.Logger, p *Policy) gohttp.Logger {
+	return &logger{next: next, policy: p}
+}
+
+type logger struct {
+	next   gohttp.Logger
+	policy *Policy
+}
+
+func (l *logger) Debug(msg string, keysAndValues ...any) {
+	l.next.Debug(l.policy.Text(msg), l.policy.KeyValues(keysAndValues)...)
+}
+
+func (l *logger) Info(msg string, keysAndValues ...any) {
+	l.next.Info(l.policy.Text(msg), l.policy.KeyValues(keysAndValues)...)
+}
+
+func (l *logger) Warn(msg string, keysAndValues ...any) {
+	l.next.Warn(l.policy.Text(msg), l.policy.KeyValues(keysAndValues)...)
+}
+
+func (l *logger) Error(msg string, keysAndValues ...any) {
+	l.next.Error(l.policy.Text(msg), l.policy.KeyValues(keysAndValues)...)
+}
@@ -0,0 +1,39 @@
+package redact
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Verify checks the text rules of p against samples, meant for the tests of
+// a service's Policy: every deny sample must be changed by Text and every
+// allow sample kept. The error lists all samples that failed.
+func (p *Policy) Verify(deny []string, allow []string) error {
+	var failed []string
+	for _, sample := range deny {
+		if p.Text(sample) == sample {
+			failed = append(failed, fmt.Sprintf("not redacted: %q", sample))
+		}
+	}
+	for _, sample := range allow {
+		if got := p.Text(sample); got != sample {
+			failed = append(failed, fmt.Sprintf("redacted: %q became %q", sample, got))
+		}
+	}
+	if failed != nil {
+		return fmt.Errorf("redact: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Leaks returns the secrets that appear in output, e.g. in a log captured
+// while a test sent requests with known credentials
+func Leaks(output string, secrets ...string) []string {
+	var leaked []string
+	for _, secret := range secrets {
+		if secret != "" && strings.Contains(output, secret) {
+			leaked = append(leaked, secret)
+		}
+	}
+	return leaked
+}
@@ -0,0 +1,179 @@
+// Package retry runs an operation again after failures, waiting between the
+// attempts:
+//
+//	err := retry.Do(ctx, func() error {
+//		return db.PingContext(ctx)
+//	}, retry.Attempts(5), retry.Backoff(retry.Exponential(100*time.Millisecond, 2, 0.2)))
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/Stellar1999/gotool/randx"
+)
+
+// Strategy returns the delay after the given failed attempt, counting from 1
+type Strategy func(attempt int) time.Duration
+
+type config struct {
+	attempts int
+	backoff  Strategy
+	retryIf  func(error) bool
+	onRetry  func(attempt int, err error, delay time.Duration)
+}
+
+type Option = opt.Option[config]
+
+// Attempts sets how often fn is called at most, counting the first call, 3
+// by default. Zero retries until ctx is done.
+func Attempts(n int) Option {
+	return func(c *config) {
+		c.attempts = n
+	}
+}
+
+// MaxAttempts returns how often Do calls fn at most with opts, 0 when it
+// retries until ctx is done
+func MaxAttempts(opts ...Option) int {
+	return opt.Apply(&config{attempts: 3}, opts...).attempts
+}
+
+// Backoff sets the delays between attempts, Exponential(100ms, 2, 0.2) by
+// default
+func Backoff(s Strategy) Option {
+	return func(c *config) {
+		c.backoff = s
+	}
+}
+
+// RetryIf sets which errors are retried, by default all but those of a
+// canceled or expired context
+func RetryIf(fn func(err error) bool) Option {
+	return func(c *config) {
+		c.retryIf = fn
+	}
+}
+
+// OnRetry calls fn before waiting for the next attempt, e.g. to log the
+// failure
+func OnRetry(fn func(attempt int, err error, delay time.Duration)) Option {
+	return func(c *config) {
+		c.onRetry = fn
+	}
+}
+
+// Constant waits d between all attempts
+func Constant(d time.Duration) Strategy {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// Exponential waits base, base*factor, base*factor²... Each delay is moved
+// by up to ±jitter of itself at random, e.g. 0.2 for ±20%, so clients failing
+// together do not retry together. The jitter is drawn from randx, randx.Seed
+// makes it repeatable in tests.
+func Exponential(base time.Duration, factor float64, jitter float64) Strategy {
+	return func(attempt int) time.Duration {
+		d := float64(base) * math.Pow(factor, float64(attempt-1))
+		if jitter > 0 {
+			d += d * jitter * (2*randx.Float64() - 1)
+		}
+		if d >= math.MaxInt64 {
+			return math.MaxInt64
+		}
+		return time.Duration(d)
+	}
+}
+
+// Capped limits the delays of s to max
+func Capped(s Strategy, max time.Duration) Strategy {
+	return func(attempt int) time.Duration {
+		if d := s(attempt); d < max {
+			return d
+		}
+		return max
+	}
+}
+
+type unrecoverable struct {
+	err error
+}
+
+func (e *unrecoverable) Error() string {
+	return e.err.Error()
+}
+
+func (e *unrecoverable) Unwrap() error {
+	return e.err
+}
+
+// Unrecoverable marks err so Do returns it at once, without retrying. Do
+// returns err itself, not the marked error.
+func Unrecoverable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &unrecoverable{err: err}
+}
+
+func defaultRetryIf(err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// Do calls fn until it succeeds, the attempts are used up or its error is
+// not retried, and returns the last error of fn. When ctx is done before an
+// attempt or while waiting for it, Do returns ctx.Err().
+func Do(ctx context.Context, fn func() error, opts ...Option) error {
+	c := config{
+		attempts: 3,
+		backoff:  Exponential(100*time.Millisecond, 2, 0.2),
+		retryIf:  defaultRetryIf,
+	}
+	err := opt.Build(&c, opts, func(c *config) error {
+		if c.attempts < 0 || c.backoff == nil || c.retryIf == nil {
+			return errors.New("retry: attempts must not be negative, backoff and retry condition not nil")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var timer *time.Timer
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		var stop *unrecoverable
+		if errors.As(err, &stop) {
+			return stop.err
+		}
+		if !c.retryIf(err) || attempt == c.attempts {
+			return err
+		}
+		delay := c.backoff(attempt)
+		if c.onRetry != nil {
+			c.onRetry(attempt, err, delay)
+		}
+		if timer == nil {
+			timer = time.NewTimer(delay)
+			defer timer.Stop()
+		} else {
+			timer.Reset(delay)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
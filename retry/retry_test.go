@@ -0,0 +1,123 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/randx"
+)
+
+var errTemporary = errors.New("temporary")
+
+func TestDo(t *testing.T) {
+	errFatal := errors.New("fatal")
+	tests := []struct {
+		name     string
+		results  []error
+		opts     []Option
+		want     error
+		attempts int
+	}{
+		{"success", []error{nil}, nil, nil, 1},
+		{"recovers", []error{errTemporary, errTemporary, nil}, nil, nil, 3},
+		{"gives up", []error{errTemporary, errTemporary, errTemporary, nil}, nil, errTemporary, 3},
+		{"more attempts", []error{errTemporary, errTemporary, errTemporary, nil}, []Option{Attempts(5)}, nil, 4},
+		{"unlimited", []error{errTemporary, errTemporary, errTemporary, errTemporary, nil}, []Option{Attempts(0)}, nil, 5},
+		{"not retried", []error{errFatal, nil}, []Option{RetryIf(func(err error) bool { return err == errTemporary })}, errFatal, 1},
+		{"unrecoverable", []error{errTemporary, Unrecoverable(errFatal), nil}, nil, errFatal, 2},
+		{"canceled", []error{context.Canceled, nil}, nil, context.Canceled, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			opts := append([]Option{Backoff(Constant(time.Millisecond))}, tt.opts...)
+			err := Do(context.Background(), func() error {
+				calls++
+				return tt.results[calls-1]
+			}, opts...)
+			if err != tt.want || calls != tt.attempts {
+				t.Errorf("Do() got = %v after %v calls, want %v after %v", err, calls, tt.want, tt.attempts)
+			}
+		})
+	}
+
+	if err := Do(context.Background(), func() error { return nil }, Attempts(-1)); err == nil {
+		t.Errorf("Do() error = nil, want invalid options")
+	}
+	if n := MaxAttempts(); n != 3 {
+		t.Errorf("MaxAttempts() got = %v, want 3", n)
+	}
+	if n := MaxAttempts(Attempts(5), Backoff(Constant(0))); n != 5 {
+		t.Errorf("MaxAttempts() got = %v, want 5", n)
+	}
+}
+
+func TestDoContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	start := time.Now()
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err := Do(ctx, func() error {
+		calls++
+		return errTemporary
+	}, Attempts(0), Backoff(Constant(time.Hour)))
+	if err != context.Canceled || calls != 1 || time.Since(start) > time.Second {
+		t.Errorf("Do() got = %v after %v calls, want to stop waiting when canceled", err, calls)
+	}
+	if err := Do(ctx, func() error { t.Error("called with a done ctx"); return nil }); err != context.Canceled {
+		t.Errorf("Do() got = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestOnRetry(t *testing.T) {
+	var delays []time.Duration
+	var attempts []int
+	_ = Do(context.Background(), func() error { return errTemporary },
+		Attempts(4),
+		Backoff(Capped(Exponential(time.Millisecond, 2, 0), 3*time.Millisecond)),
+		OnRetry(func(attempt int, err error, delay time.Duration) {
+			if err != errTemporary {
+				t.Errorf("OnRetry() err got = %v", err)
+			}
+			attempts = append(attempts, attempt)
+			delays = append(delays, delay)
+		}))
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(attempts, want) {
+		t.Errorf("OnRetry() attempts got = %v, want %v", attempts, want)
+	}
+	if want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}; !reflect.DeepEqual(delays, want) {
+		t.Errorf("OnRetry() delays got = %v, want %v", delays, want)
+	}
+}
+
+func TestExponentialJitter(t *testing.T) {
+	s := Exponential(100*time.Millisecond, 2, 0.5)
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		d := s(3)
+		if d < 200*time.Millisecond || d > 600*time.Millisecond {
+			t.Fatalf("Exponential() got = %v, want 400ms ±50%%", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 50 {
+		t.Errorf("Exponential() got %v distinct delays of 100, want them spread", len(seen))
+	}
+	if d := Exponential(time.Second, 10, 0)(100); d <= 0 {
+		t.Errorf("Exponential() got = %v, want it not to overflow", d)
+	}
+}
+
+func TestExponentialSeeded(t *testing.T) {
+	s := Exponential(100*time.Millisecond, 2, 0.5)
+	restore := randx.Seed(7)
+	first := []time.Duration{s(1), s(2), s(3)}
+	restore()
+	defer randx.Seed(7)()
+	if again := []time.Duration{s(1), s(2), s(3)}; !reflect.DeepEqual(again, first) {
+		t.Errorf("Exponential() seeded got = %v, want %v", again, first)
+	}
+}
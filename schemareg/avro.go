@@ -0,0 +1,540 @@
+package schemareg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ErrAvro is wrapped by the errors of Avro schemas and values
+var ErrAvro = errors.New("schemareg: avro")
+
+// Avro is a parsed Avro schema. Values are nil, bool, int64 (int and long),
+// float64 (float and double), string, []byte (bytes and fixed),
+// map[string]any (records and maps), string (enums) and []any (arrays).
+// Encode also takes the other Go integer and float types and json.Number.
+type Avro struct {
+	root *avroType
+}
+
+type avroField struct {
+	name       string
+	typ        *avroType
+	def        any
+	hasDefault bool
+}
+
+type avroType struct {
+	kind     string // a primitive name, record, enum, array, map, fixed or union
+	name     string
+	fields   []avroField
+	symbols  []string
+	items    *avroType // of arrays and maps
+	branches []*avroType
+	size     int
+}
+
+// ParseAvro parses an Avro schema in its JSON form
+func ParseAvro(schema string) (*Avro, error) {
+	var raw any
+	if err := json.Unmarshal([]byte(schema), &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAvro, err)
+	}
+	p := &avroParser{named: make(map[string]*avroType)}
+	root, err := p.parse(raw, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Avro{root: root}, nil
+}
+
+type avroParser struct {
+	named map[string]*avroType
+}
+
+var avroPrimitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+func (p *avroParser) parse(raw any, namespace string) (*avroType, error) {
+	switch v := raw.(type) {
+	case string:
+		if avroPrimitives[v] {
+			return &avroType{kind: v}, nil
+		}
+		if t, ok := p.named[fullName(v, namespace)]; ok {
+			return t, nil
+		}
+		if t, ok := p.named[v]; ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("%w: unknown type %q", ErrAvro, v)
+	case []any:
+		t := &avroType{kind: "union"}
+		for _, branch := range v {
+			bt, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			t.branches = append(t.branches, bt)
+		}
+		return t, nil
+	case map[string]any:
+		return p.parseComplex(v, namespace)
+	}
+	return nil, fmt.Errorf("%w: invalid schema %v", ErrAvro, raw)
+}
+
+func fullName(name string, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func (p *avroParser) parseComplex(v map[string]any, namespace string) (*avroType, error) {
+	kind, _ := v["type"].(string)
+	if ns, ok := v["namespace"].(string); ok {
+		namespace = ns
+	}
+	name, _ := v["name"].(string)
+	switch kind {
+	case "record", "error", "enum", "fixed":
+		if name == "" {
+			return nil, fmt.Errorf("%w: %s without name", ErrAvro, kind)
+		}
+		name = fullName(name, namespace)
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			namespace = name[:i]
+		}
+	}
+	switch kind {
+	case "record", "error":
+		t := &avroType{kind: "record", name: name}
+		// registered first so fields can refer to the record itself
+		p.named[name] = t
+		fields, _ := v["fields"].([]any)
+		for _, f := range fields {
+			fm, _ := f.(map[string]any)
+			fieldName, _ := fm["name"].(string)
+			ft, err := p.parse(fm["type"], namespace)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", name, fieldName, err)
+			}
+			def, hasDefault := fm["default"]
+			t.fields = append(t.fields, avroField{name: fieldName, typ: ft, def: def, hasDefault: hasDefault})
+		}
+		return t, nil
+	case "enum":
+		t := &avroType{kind: "enum", name: name}
+		symbols, _ := v["symbols"].([]any)
+		for _, s := range symbols {
+			symbol, _ := s.(string)
+			t.symbols = append(t.symbols, symbol)
+		}
+		p.named[name] = t
+		return t, nil
+	case "fixed":
+		size, _ := v["size"].(float64)
+		t := &avroType{kind: "fixed", name: name, size: int(size)}
+		p.named[name] = t
+		return t, nil
+	case "array", "map":
+		key := "items"
+		if kind == "map" {
+			key = "values"
+		}
+		items, err := p.parse(v[key], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &avroType{kind: kind, items: items}, nil
+	}
+	// primitives with attributes like logicalType
+	return p.parse(v["type"], namespace)
+}
+
+// Encode encodes v in the Avro binary encoding
+func (a *Avro) Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeAvro(&buf, a.root, v, ""); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeLong(buf *bytes.Buffer, n int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutVarint(tmp[:], n)])
+}
+
+func avroError(path string, t *avroType, v any) error {
+	if path == "" {
+		path = "value"
+	}
+	return fmt.Errorf("%w: %s: %T is not a valid %s", ErrAvro, path, v, t.kind)
+}
+
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	case float64:
+		return int64(n), n == math.Trunc(n)
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func toFloat64(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	i, ok := toInt64(v)
+	return float64(i), ok
+}
+
+func encodeAvro(buf *bytes.Buffer, t *avroType, v any, path string) error {
+	switch t.kind {
+	case "null":
+		if v != nil {
+			return avroError(path, t, v)
+		}
+	case "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return avroError(path, t, v)
+		}
+		if b {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case "int", "long":
+		n, ok := toInt64(v)
+		if !ok || t.kind == "int" && (n < math.MinInt32 || n > math.MaxInt32) {
+			return avroError(path, t, v)
+		}
+		writeLong(buf, n)
+	case "float":
+		f, ok := toFloat64(v)
+		if !ok {
+			return avroError(path, t, v)
+		}
+		_ = binary.Write(buf, binary.LittleEndian, float32(f))
+	case "double":
+		f, ok := toFloat64(v)
+		if !ok {
+			return avroError(path, t, v)
+		}
+		_ = binary.Write(buf, binary.LittleEndian, f)
+	case "bytes", "string":
+		var data []byte
+		switch s := v.(type) {
+		case string:
+			data = []byte(s)
+		case []byte:
+			data = s
+		default:
+			return avroError(path, t, v)
+		}
+		writeLong(buf, int64(len(data)))
+		buf.Write(data)
+	case "fixed":
+		data, ok := v.([]byte)
+		if s, isString := v.(string); isString {
+			data, ok = []byte(s), true
+		}
+		if !ok || len(data) != t.size {
+			return avroError(path, t, v)
+		}
+		buf.Write(data)
+	case "enum":
+		s, _ := v.(string)
+		for i, symbol := range t.symbols {
+			if symbol == s {
+				writeLong(buf, int64(i))
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s: %q is not a symbol of %s", ErrAvro, path, s, t.name)
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return avroError(path, t, v)
+		}
+		if len(items) > 0 {
+			writeLong(buf, int64(len(items)))
+			for i, item := range items {
+				if err := encodeAvro(buf, t.items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+		writeLong(buf, 0)
+	case "map":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return avroError(path, t, v)
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if len(keys) > 0 {
+			writeLong(buf, int64(len(keys)))
+			for _, k := range keys {
+				writeLong(buf, int64(len(k)))
+				buf.WriteString(k)
+				if err := encodeAvro(buf, t.items, m[k], path+"."+k); err != nil {
+					return err
+				}
+			}
+		}
+		writeLong(buf, 0)
+	case "record":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return avroError(path, t, v)
+		}
+		for _, f := range t.fields {
+			value, ok := m[f.name]
+			if !ok && f.hasDefault {
+				value = f.def
+			}
+			if err := encodeAvro(buf, f.typ, value, strings.TrimPrefix(path+"."+f.name, ".")); err != nil {
+				return err
+			}
+		}
+	case "union":
+		// the first branch taking the value wins
+		for i, branch := range t.branches {
+			var tmp bytes.Buffer
+			if encodeAvro(&tmp, branch, v, path) == nil {
+				writeLong(buf, int64(i))
+				buf.Write(tmp.Bytes())
+				return nil
+			}
+		}
+		return avroError(path, t, v)
+	}
+	return nil
+}
+
+// Decode decodes a value in the Avro binary encoding
+func (a *Avro) Decode(data []byte) (any, error) {
+	r := bytes.NewReader(data)
+	v, err := decodeAvro(r, a.root)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAvro, err)
+	}
+	if r.Len() > 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrAvro, r.Len())
+	}
+	return v, nil
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > int64(r.Len()) {
+		return nil, fmt.Errorf("invalid length %d", n)
+	}
+	data := make([]byte, n)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+// readBlocks calls item for every item of an array or map
+func readBlocks(r *bytes.Reader, item func() error) error {
+	for {
+		n, err := binary.ReadVarint(r)
+		if err != nil || n == 0 {
+			return err
+		}
+		if n < 0 {
+			// a negative count is followed by the size of the block
+			n = -n
+			if _, err := binary.ReadVarint(r); err != nil {
+				return err
+			}
+		}
+		if n > int64(r.Len()) {
+			return fmt.Errorf("invalid block count %d", n)
+		}
+		for i := int64(0); i < n; i++ {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func decodeAvro(r *bytes.Reader, t *avroType) (any, error) {
+	switch t.kind {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := r.ReadByte()
+		return b == 1, err
+	case "int", "long":
+		return binary.ReadVarint(r)
+	case "float":
+		var f float32
+		err := binary.Read(r, binary.LittleEndian, &f)
+		return float64(f), err
+	case "double":
+		var f float64
+		err := binary.Read(r, binary.LittleEndian, &f)
+		return f, err
+	case "bytes":
+		return readBytes(r)
+	case "string":
+		data, err := readBytes(r)
+		return string(data), err
+	case "fixed":
+		data := make([]byte, t.size)
+		_, err := io.ReadFull(r, data)
+		return data, err
+	case "enum":
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(t.symbols)) {
+			return nil, fmt.Errorf("invalid symbol %d of %s", i, t.name)
+		}
+		return t.symbols[i], nil
+	case "array":
+		items := []any{}
+		err := readBlocks(r, func() error {
+			item, err := decodeAvro(r, t.items)
+			items = append(items, item)
+			return err
+		})
+		return items, err
+	case "map":
+		m := map[string]any{}
+		err := readBlocks(r, func() error {
+			key, err := readBytes(r)
+			if err != nil {
+				return err
+			}
+			m[string(key)], err = decodeAvro(r, t.items)
+			return err
+		})
+		return m, err
+	case "record":
+		m := make(map[string]any, len(t.fields))
+		for _, f := range t.fields {
+			v, err := decodeAvro(r, f.typ)
+			if err != nil {
+				return nil, err
+			}
+			m[f.name] = v
+		}
+		return m, nil
+	case "union":
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(t.branches)) {
+			return nil, fmt.Errorf("invalid union branch %d", i)
+		}
+		return decodeAvro(r, t.branches[i])
+	}
+	return nil, fmt.Errorf("unknown type %s", t.kind)
+}
+
+// Resolve adapts a value decoded with the writer schema a to the reader
+// schema: record fields missing in the value get their default and fields
+// unknown to the reader are dropped.
+func (a *Avro) Resolve(v any) any {
+	return resolveAvro(a.root, v)
+}
+
+func resolveAvro(t *avroType, v any) any {
+	switch t.kind {
+	case "record":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		out := make(map[string]any, len(t.fields))
+		for _, f := range t.fields {
+			if value, ok := m[f.name]; ok {
+				out[f.name] = resolveAvro(f.typ, value)
+			} else if f.hasDefault {
+				out[f.name] = f.def
+			}
+		}
+		return out
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return v
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			out[i] = resolveAvro(t.items, item)
+		}
+		return out
+	case "map":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		out := make(map[string]any, len(m))
+		for k, item := range m {
+			out[k] = resolveAvro(t.items, item)
+		}
+		return out
+	case "union":
+		// the first record branch resolves records
+		if _, ok := v.(map[string]any); ok {
+			for _, branch := range t.branches {
+				if branch.kind == "record" {
+					return resolveAvro(branch, v)
+				}
+			}
+		}
+	}
+	return v
+}
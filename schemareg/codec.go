@@ -0,0 +1,271 @@
+package schemareg
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+	"google.golang.org/protobuf/proto"
+)
+
+type codecConfig struct {
+	timeout        time.Duration
+	reader         *Avro
+	messageIndexes []int
+}
+
+type CodecOption = opt.Option[codecConfig]
+
+// WithTimeout bounds the registry lookups of Marshal and Unmarshal, which
+// take no context, 10s by default
+func WithTimeout(d time.Duration) CodecOption {
+	return func(c *codecConfig) {
+		c.timeout = d
+	}
+}
+
+// WithReaderSchema resolves decoded Avro values to the schema the consumer
+// was written for, see Avro.Resolve
+func WithReaderSchema(reader *Avro) CodecOption {
+	return func(c *codecConfig) {
+		c.reader = reader
+	}
+}
+
+// WithMessageIndexes selects the message of a Protobuf schema with several
+// ones by its index path, the first top level message by default
+func WithMessageIndexes(indexes ...int) CodecOption {
+	return func(c *codecConfig) {
+		c.messageIndexes = indexes
+	}
+}
+
+func newCodecConfig(opts []CodecOption) (codecConfig, error) {
+	cfg := codecConfig{timeout: 10 * time.Second}
+	return cfg, opt.Build(&cfg, opts)
+}
+
+// AvroCodec encodes values with a version of a subject and decodes messages
+// of any Avro schema of the registry
+type AvroCodec struct {
+	client  *Client
+	subject string
+	version int
+	cfg     codecConfig
+
+	mu     sync.Mutex
+	parsed map[int]*Avro
+}
+
+var _ gohttp.Codec = (*AvroCodec)(nil)
+
+// AvroCodec returns a codec writing with version of subject, Latest follows
+// new versions as they are registered
+func (c *Client) AvroCodec(subject string, version int, opts ...CodecOption) (*AvroCodec, error) {
+	cfg, err := newCodecConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &AvroCodec{client: c, subject: subject, version: version, cfg: cfg, parsed: make(map[int]*Avro)}, nil
+}
+
+func (a *AvroCodec) ContentType() string {
+	return "avro/binary"
+}
+
+func (a *AvroCodec) avro(schema *Schema) (*Avro, error) {
+	if schema.schemaType() != TypeAvro {
+		return nil, fmt.Errorf("%w: schema %d is %s", ErrSchemaType, schema.ID, schema.schemaType())
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if parsed, ok := a.parsed[schema.ID]; ok {
+		return parsed, nil
+	}
+	parsed, err := ParseAvro(schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+	a.parsed[schema.ID] = parsed
+	return parsed, nil
+}
+
+// Marshal encodes v, a generic Avro value or a value encoding to one as JSON
+// like a struct with json tags
+func (a *AvroCodec) Marshal(v any) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.timeout)
+	defer cancel()
+	schema, err := a.client.Schema(ctx, a.subject, a.version)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := a.avro(schema)
+	if err != nil {
+		return nil, err
+	}
+	generic, err := toGeneric(v)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := parsed.Encode(generic)
+	if err != nil {
+		return nil, err
+	}
+	return Frame(schema.ID, payload), nil
+}
+
+// Unmarshal decodes a message into v, a *any, a *map[string]any or a value
+// decoding the value as JSON
+func (a *AvroCodec) Unmarshal(data []byte, v any) error {
+	id, payload, err := Unframe(data)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.timeout)
+	defer cancel()
+	schema, err := a.client.SchemaByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	parsed, err := a.avro(schema)
+	if err != nil {
+		return err
+	}
+	value, err := parsed.Decode(payload)
+	if err != nil {
+		return err
+	}
+	if a.cfg.reader != nil {
+		value = a.cfg.reader.Resolve(value)
+	}
+	return fromGeneric(value, v)
+}
+
+func toGeneric(v any) (any, error) {
+	switch v.(type) {
+	case nil, bool, string, []byte, map[string]any, []any, int, int32, int64, float32, float64:
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic any
+	return generic, decoder.Decode(&generic)
+}
+
+func fromGeneric(value any, v any) error {
+	switch target := v.(type) {
+	case *any:
+		*target = value
+		return nil
+	case *map[string]any:
+		if m, ok := value.(map[string]any); ok {
+			*target = m
+			return nil
+		}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ProtobufCodec encodes proto.Message values with a version of a subject
+type ProtobufCodec struct {
+	client  *Client
+	subject string
+	version int
+	cfg     codecConfig
+}
+
+var _ gohttp.Codec = (*ProtobufCodec)(nil)
+
+// ProtobufCodec returns a codec writing with version of subject, Latest
+// follows new versions as they are registered. The schema is not checked
+// against the messages, the registry checks the compatibility of versions.
+func (c *Client) ProtobufCodec(subject string, version int, opts ...CodecOption) (*ProtobufCodec, error) {
+	cfg, err := newCodecConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ProtobufCodec{client: c, subject: subject, version: version, cfg: cfg}, nil
+}
+
+func (p *ProtobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+// Marshal encodes v, which must be a proto.Message
+func (p *ProtobufCodec) Marshal(v any) ([]byte, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T is not a proto.Message", gohttp.ErrCodec, v)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.timeout)
+	defer cancel()
+	schema, err := p.client.Schema(ctx, p.subject, p.version)
+	if err != nil {
+		return nil, err
+	}
+	if schema.schemaType() != TypeProtobuf {
+		return nil, fmt.Errorf("%w: schema %d is %s", ErrSchemaType, schema.ID, schema.schemaType())
+	}
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	var indexes bytes.Buffer
+	var tmp [binary.MaxVarintLen64]byte
+	if len(p.cfg.messageIndexes) == 0 || len(p.cfg.messageIndexes) == 1 && p.cfg.messageIndexes[0] == 0 {
+		// [0] is written as a single zero
+		indexes.WriteByte(0)
+	} else {
+		indexes.Write(tmp[:binary.PutVarint(tmp[:], int64(len(p.cfg.messageIndexes)))])
+		for _, i := range p.cfg.messageIndexes {
+			indexes.Write(tmp[:binary.PutVarint(tmp[:], int64(i))])
+		}
+	}
+	return Frame(schema.ID, append(indexes.Bytes(), payload...)), nil
+}
+
+// Unmarshal decodes a message into v, which must be a proto.Message
+func (p *ProtobufCodec) Unmarshal(data []byte, v any) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T is not a proto.Message", gohttp.ErrCodec, v)
+	}
+	id, payload, err := Unframe(data)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.timeout)
+	defer cancel()
+	schema, err := p.client.SchemaByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if schema.schemaType() != TypeProtobuf {
+		return fmt.Errorf("%w: schema %d is %s", ErrSchemaType, id, schema.schemaType())
+	}
+	r := bytes.NewReader(payload)
+	count, err := binary.ReadVarint(r)
+	if err != nil || count < 0 || count > int64(r.Len()) {
+		return ErrFormat
+	}
+	for i := int64(0); i < count; i++ {
+		if _, err := binary.ReadVarint(r); err != nil {
+			return ErrFormat
+		}
+	}
+	return proto.Unmarshal(payload[len(payload)-r.Len():], message)
+}
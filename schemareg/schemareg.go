@@ -0,0 +1,251 @@
+// Package schemareg talks to a Confluent compatible schema registry and
+// encodes messages in its wire format: a zero byte, the 4 byte id of the
+// schema and the payload. AvroCodec and ProtobufCodec implement the Codec of
+// the http package, so they plug into anything taking one.
+package schemareg
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	gourl "net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Schema types of the registry
+const (
+	TypeAvro     = "AVRO"
+	TypeProtobuf = "PROTOBUF"
+	TypeJSON     = "JSON"
+)
+
+// Latest selects the latest version of a subject
+const Latest = -1
+
+var (
+	// ErrFormat is returned for messages not in the wire format
+	ErrFormat = errors.New("schemareg: not in the schema registry wire format")
+	// ErrSchemaType is returned for schemas of another type than the codec
+	ErrSchemaType = errors.New("schemareg: unexpected schema type")
+)
+
+// Reference is a schema imported by another one
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Schema is a schema stored in the registry
+type Schema struct {
+	ID         int         `json:"id"`
+	Subject    string      `json:"subject,omitempty"`
+	Version    int         `json:"version,omitempty"`
+	Type       string      `json:"schemaType,omitempty"`
+	Schema     string      `json:"schema"`
+	References []Reference `json:"references,omitempty"`
+}
+
+func (s *Schema) schemaType() string {
+	// the registry leaves the type out for Avro
+	if s.Type == "" {
+		return TypeAvro
+	}
+	return s.Type
+}
+
+type config struct {
+	client    *gohttp.Client
+	username  string
+	password  string
+	latestTTL time.Duration
+}
+
+type Option = opt.Option[config]
+
+// WithClient sends the requests with client
+func WithClient(client *gohttp.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithBasicAuth authenticates to the registry, e.g. with an API key and secret
+func WithBasicAuth(username string, password string) Option {
+	return func(c *config) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithLatestTTL is how long the latest version of a subject is cached, 5m by
+// default. Schemas by id and by version never change and are cached forever.
+func WithLatestTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.latestTTL = d
+	}
+}
+
+// Client reads and registers schemas, it is safe for concurrent use
+type Client struct {
+	url string
+	cfg config
+
+	mu        sync.Mutex
+	byID      map[int]*Schema
+	byVersion map[string]*Schema
+	latest    map[string]latestEntry
+}
+
+type latestEntry struct {
+	schema  *Schema
+	expires time.Time
+}
+
+// New returns a Client of the registry at url
+func New(url string, opts ...Option) (*Client, error) {
+	cfg := config{latestTTL: 5 * time.Minute}
+	if err := opt.Build(&cfg, opts); err != nil {
+		return nil, err
+	}
+	if cfg.client == nil {
+		cfg.client = gohttp.NewClient()
+	}
+	return &Client{
+		url:       url,
+		cfg:       cfg,
+		byID:      make(map[int]*Schema),
+		byVersion: make(map[string]*Schema),
+		latest:    make(map[string]latestEntry),
+	}, nil
+}
+
+func (c *Client) request(ctx context.Context, method gohttp.RequestMethodType, path string, body any, v any) error {
+	req := c.cfg.client.NewRequest(method, c.url+path).WithContext(ctx).
+		Header("Accept", "application/vnd.schemaregistry.v1+json")
+	if c.cfg.username != "" {
+		req.Headers(gohttp.NewHeaders().BasicAuth(c.cfg.username, c.cfg.password))
+	}
+	if body != nil {
+		req.Body(body).Header("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
+	resp, err := req.Send()
+	if err != nil {
+		return fmt.Errorf("schemareg: %s %s: %w", method, path, err)
+	}
+	if err := resp.JSON(v); err != nil {
+		return fmt.Errorf("schemareg: decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+// SchemaByID returns the schema with id
+func (c *Client) SchemaByID(ctx context.Context, id int) (*Schema, error) {
+	c.mu.Lock()
+	schema, ok := c.byID[id]
+	c.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+	schema = &Schema{}
+	if err := c.request(ctx, gohttp.GET, "/schemas/ids/"+strconv.Itoa(id), nil, schema); err != nil {
+		return nil, err
+	}
+	schema.ID = id
+	c.mu.Lock()
+	c.byID[id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+// Schema returns a version of subject, Latest for the latest one
+func (c *Client) Schema(ctx context.Context, subject string, version int) (*Schema, error) {
+	key := subject + "/" + strconv.Itoa(version)
+	now := time.Now()
+	c.mu.Lock()
+	schema, ok := c.byVersion[key]
+	if entry, cached := c.latest[subject]; version == Latest && cached && now.Before(entry.expires) {
+		schema, ok = entry.schema, true
+	}
+	c.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+	path := "latest"
+	if version != Latest {
+		path = strconv.Itoa(version)
+	}
+	schema = &Schema{}
+	if err := c.request(ctx, gohttp.GET, "/subjects/"+gourl.PathEscape(subject)+"/versions/"+path, nil, schema); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.byID[schema.ID] = schema
+	c.byVersion[subject+"/"+strconv.Itoa(schema.Version)] = schema
+	if version == Latest {
+		c.latest[subject] = latestEntry{schema: schema, expires: now.Add(c.cfg.latestTTL)}
+	}
+	c.mu.Unlock()
+	return schema, nil
+}
+
+// Register registers schema under subject and returns its id, registering
+// an existing schema again returns the id it has
+func (c *Client) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+	body := Schema{Type: schema.Type, Schema: schema.Schema, References: schema.References}
+	if body.Type == TypeAvro {
+		body.Type = ""
+	}
+	if err := c.request(ctx, gohttp.POST, "/subjects/"+gourl.PathEscape(subject)+"/versions", body, &resp); err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	delete(c.latest, subject)
+	c.mu.Unlock()
+	return resp.ID, nil
+}
+
+// Compatible reports whether schema is compatible with a version of subject
+// under the compatibility level of the subject
+func (c *Client) Compatible(ctx context.Context, subject string, version int, schema Schema) (bool, error) {
+	path := "latest"
+	if version != Latest {
+		path = strconv.Itoa(version)
+	}
+	var resp struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	body := Schema{Type: schema.Type, Schema: schema.Schema, References: schema.References}
+	if body.Type == TypeAvro {
+		body.Type = ""
+	}
+	if err := c.request(ctx, gohttp.POST, "/compatibility/subjects/"+gourl.PathEscape(subject)+"/versions/"+path, body, &resp); err != nil {
+		return false, err
+	}
+	return resp.IsCompatible, nil
+}
+
+// Frame prefixes payload with the magic byte and the schema id
+func Frame(id int, payload []byte) []byte {
+	out := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(out[1:], uint32(id))
+	copy(out[5:], payload)
+	return out
+}
+
+// Unframe splits a message into its schema id and payload
+func Unframe(data []byte) (int, []byte, error) {
+	if len(data) < 5 || data[0] != 0 {
+		return 0, nil, ErrFormat
+	}
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
@@ -0,0 +1,229 @@
+package schemareg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const userV1 = `{"type":"record","name":"User","namespace":"acme","fields":[
+	{"name":"name","type":"string"},
+	{"name":"age","type":["null","int"],"default":null},
+	{"name":"tags","type":{"type":"array","items":"string"}},
+	{"name":"role","type":{"type":"enum","name":"Role","symbols":["ADMIN","USER"]}},
+	{"name":"attrs","type":{"type":"map","values":"double"}},
+	{"name":"manager","type":["null","User"],"default":null}
+]}`
+
+const userV2 = `{"type":"record","name":"User","namespace":"acme","fields":[
+	{"name":"name","type":"string"},
+	{"name":"email","type":"string","default":"unknown"}
+]}`
+
+// registry is a fake schema registry
+type registry struct {
+	mu       sync.Mutex
+	schemas  []Schema
+	requests int
+}
+
+func (reg *registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.requests++
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodPost && parts[0] == "subjects":
+		var s Schema
+		_ = json.NewDecoder(r.Body).Decode(&s)
+		for _, existing := range reg.schemas {
+			if existing.Subject == parts[1] && existing.Schema == s.Schema {
+				_ = json.NewEncoder(w).Encode(map[string]int{"id": existing.ID})
+				return
+			}
+		}
+		s.Subject, s.ID = parts[1], len(reg.schemas)+1
+		for _, existing := range reg.schemas {
+			if existing.Subject == s.Subject {
+				s.Version++
+			}
+		}
+		s.Version++
+		reg.schemas = append(reg.schemas, s)
+		_ = json.NewEncoder(w).Encode(map[string]int{"id": s.ID})
+	case r.Method == http.MethodPost && parts[0] == "compatibility":
+		_ = json.NewEncoder(w).Encode(map[string]bool{"is_compatible": true})
+	case parts[0] == "schemas":
+		id, _ := strconv.Atoi(parts[2])
+		if id < 1 || id > len(reg.schemas) {
+			http.Error(w, `{"error_code":40403}`, http.StatusNotFound)
+			return
+		}
+		s := reg.schemas[id-1]
+		_ = json.NewEncoder(w).Encode(Schema{Type: s.Type, Schema: s.Schema})
+	case parts[0] == "subjects":
+		var found *Schema
+		for i := range reg.schemas {
+			s := &reg.schemas[i]
+			if s.Subject == parts[1] && (parts[3] == "latest" || strconv.Itoa(s.Version) == parts[3]) {
+				found = s
+			}
+		}
+		if found == nil {
+			http.Error(w, `{"error_code":40401}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(found)
+	}
+}
+
+func TestAvro(t *testing.T) {
+	schema, err := ParseAvro(userV1)
+	if err != nil {
+		t.Fatalf("ParseAvro() error = %v", err)
+	}
+	value := map[string]any{
+		"name":  "ann",
+		"age":   int64(41),
+		"tags":  []any{"a", "b"},
+		"role":  "ADMIN",
+		"attrs": map[string]any{"score": 1.5},
+		"manager": map[string]any{
+			"name": "bob", "age": nil, "tags": []any{}, "role": "USER", "attrs": map[string]any{}, "manager": nil,
+		},
+	}
+	data, err := schema.Encode(value)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := schema.Decode(data)
+	if err != nil || !reflect.DeepEqual(got, value) {
+		t.Errorf("Decode() got = %v %v, want %v", got, err, value)
+	}
+
+	tests := []struct {
+		name  string
+		value map[string]any
+	}{
+		{"missing field", map[string]any{"name": "ann"}},
+		{"unknown symbol", map[string]any{"name": "ann", "tags": []any{}, "role": "GUEST", "attrs": map[string]any{}}},
+		{"wrong type", map[string]any{"name": 1, "tags": []any{}, "role": "USER", "attrs": map[string]any{}}},
+	}
+	for _, tt := range tests {
+		if _, err := schema.Encode(tt.value); !errors.Is(err, ErrAvro) {
+			t.Errorf("Encode() %v error got = %v, want %v", tt.name, err, ErrAvro)
+		}
+	}
+	if _, err := ParseAvro(`{"type":"record","name":"X","fields":[{"name":"a","type":"Missing"}]}`); !errors.Is(err, ErrAvro) {
+		t.Errorf("ParseAvro() unknown type error got = %v", err)
+	}
+}
+
+type user struct {
+	Name  string             `json:"name"`
+	Age   *int               `json:"age"`
+	Tags  []string           `json:"tags"`
+	Role  string             `json:"role"`
+	Attrs map[string]float64 `json:"attrs"`
+}
+
+func TestAvroCodec(t *testing.T) {
+	reg := &registry{}
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	client, _ := New(server.URL)
+	ctx := context.Background()
+
+	id, err := client.Register(ctx, "users-value", Schema{Type: TypeAvro, Schema: userV1})
+	if err != nil || id != 1 {
+		t.Fatalf("Register() got = %v %v, want 1", id, err)
+	}
+	if again, _ := client.Register(ctx, "users-value", Schema{Schema: userV1}); again != id {
+		t.Errorf("Register() again got = %v, want %v", again, id)
+	}
+	if ok, err := client.Compatible(ctx, "users-value", Latest, Schema{Schema: userV2}); !ok || err != nil {
+		t.Errorf("Compatible() got = %v %v", ok, err)
+	}
+
+	codec, err := client.AvroCodec("users-value", Latest)
+	if err != nil {
+		t.Fatalf("AvroCodec() error = %v", err)
+	}
+	age := 30
+	data, err := codec.Marshal(user{Name: "ann", Age: &age, Tags: []string{"x"}, Role: "USER", Attrs: map[string]float64{}})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if gotID, _, err := Unframe(data); err != nil || gotID != 1 {
+		t.Errorf("Unframe() got = %v %v, want schema 1", gotID, err)
+	}
+	var decoded user
+	if err := codec.Unmarshal(data, &decoded); err != nil || decoded.Name != "ann" || *decoded.Age != 30 || decoded.Tags[0] != "x" {
+		t.Errorf("Unmarshal() got = %+v %v", decoded, err)
+	}
+
+	// a consumer on the new version reads messages of the old one
+	v2, _ := ParseAvro(userV2)
+	reader, _ := client.AvroCodec("users-value", Latest, WithReaderSchema(v2))
+	var generic map[string]any
+	if err := reader.Unmarshal(data, &generic); err != nil || !reflect.DeepEqual(generic, map[string]any{"name": "ann", "email": "unknown"}) {
+		t.Errorf("Unmarshal() with reader schema got = %v %v", generic, err)
+	}
+
+	requests := reg.requests
+	for i := 0; i < 3; i++ {
+		_, _ = codec.Marshal(map[string]any{"name": "x", "tags": []any{}, "role": "USER", "attrs": map[string]any{}})
+	}
+	if reg.requests != requests {
+		t.Errorf("registry requests got = %v, want schemas cached", reg.requests-requests)
+	}
+	if err := codec.Unmarshal([]byte{1, 2}, &generic); !errors.Is(err, ErrFormat) {
+		t.Errorf("Unmarshal() error got = %v, want %v", err, ErrFormat)
+	}
+}
+
+func TestProtobufCodec(t *testing.T) {
+	reg := &registry{}
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	client, _ := New(server.URL, WithBasicAuth("key", "secret"))
+	ctx := context.Background()
+	_, _ = client.Register(ctx, "names-value", Schema{Type: TypeProtobuf, Schema: `syntax = "proto3"; message StringValue { string value = 1; }`})
+	_, _ = client.Register(ctx, "users-value", Schema{Type: TypeAvro, Schema: userV2})
+
+	codec, _ := client.ProtobufCodec("names-value", 1)
+	data, err := codec.Marshal(wrapperspb.String("ann"))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if data[5] != 0 {
+		t.Errorf("Marshal() message indexes got = %v, want a single zero", data[5])
+	}
+	got := &wrapperspb.StringValue{}
+	if err := codec.Unmarshal(data, got); err != nil || got.Value != "ann" {
+		t.Errorf("Unmarshal() got = %v %v", got, err)
+	}
+
+	indexed, _ := client.ProtobufCodec("names-value", 1, WithMessageIndexes(1, 0))
+	data, _ = indexed.Marshal(wrapperspb.String("bob"))
+	if err := codec.Unmarshal(data, got); err != nil || got.Value != "bob" {
+		t.Errorf("Unmarshal() with indexes got = %v %v", got, err)
+	}
+
+	if _, err := codec.Marshal("not a message"); err == nil {
+		t.Errorf("Marshal() non proto error = nil")
+	}
+	avro, _ := client.ProtobufCodec("users-value", Latest)
+	if _, err := avro.Marshal(wrapperspb.String("x")); !errors.Is(err, ErrSchemaType) {
+		t.Errorf("Marshal() avro subject error got = %v, want %v", err, ErrSchemaType)
+	}
+}
@@ -0,0 +1,269 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	gourl "net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Point is where the scripts of an Engine run
+type Point string
+
+const (
+	// Rewrite scripts change the outgoing request through req, a map with
+	// method, url and header
+	Rewrite Point = "rewrite"
+	// Transform scripts change the response through resp, a map with status,
+	// header, body and json, the decoded body of JSON responses. A changed
+	// body wins over a changed json.
+	Transform Point = "transform"
+	// Validate scripts check the response in resp. A result that is false,
+	// nil, 0 or "" or a call of fail(message) fails the request with a
+	// *ValidationError.
+	Validate Point = "validate"
+)
+
+// ValidationError is returned for responses rejected by a Validate script
+type ValidationError struct {
+	Script  string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("script %s: %s", e.Script, e.Message)
+}
+
+type namedProgram struct {
+	name    string
+	program *Program
+}
+
+// Engine runs registered scripts at the hook points of the http package.
+// Install it with http.AddHook(engine) for Rewrite and Validate scripts and
+// wrap the transport with engine.Transport for Transform scripts. Scripts can
+// be registered and removed while requests are sent.
+type Engine struct {
+	cfg     config
+	mu      sync.RWMutex
+	scripts map[Point][]namedProgram
+}
+
+// NewEngine creates an Engine, the options apply to every run
+func NewEngine(opts ...Option) (*Engine, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{cfg: cfg, scripts: map[Point][]namedProgram{}}, nil
+}
+
+// Register compiles source and runs it at point after the scripts registered
+// before, replacing a script with the same name
+func (e *Engine) Register(point Point, name string, source string) error {
+	program, err := Compile(source)
+	if err != nil {
+		return fmt.Errorf("script %s: %w", name, err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	scripts := e.scripts[point]
+	for i := range scripts {
+		if scripts[i].name == name {
+			// copy on write, runs in progress keep their slice
+			scripts = append([]namedProgram{}, scripts...)
+			scripts[i].program = program
+			e.scripts[point] = scripts
+			return nil
+		}
+	}
+	e.scripts[point] = append(scripts[:len(scripts):len(scripts)], namedProgram{name: name, program: program})
+	return nil
+}
+
+// Remove unregisters the script name at point
+func (e *Engine) Remove(point Point, name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	scripts := e.scripts[point]
+	for i := range scripts {
+		if scripts[i].name == name {
+			e.scripts[point] = append(append([]namedProgram{}, scripts[:i]...), scripts[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) programs(point Point) []namedProgram {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.scripts[point]
+}
+
+// Before runs the Rewrite scripts
+func (e *Engine) Before(ctx context.Context, req *http.Request) (context.Context, error) {
+	scripts := e.programs(Rewrite)
+	if len(scripts) == 0 {
+		return ctx, nil
+	}
+	r := map[string]any{"method": req.Method, "url": req.URL.String(), "header": headerMap(req.Header)}
+	for _, s := range scripts {
+		vars := map[string]any{"req": r}
+		if _, err := s.program.run(ctx, vars, e.cfg); err != nil {
+			return ctx, fmt.Errorf("script %s: %w", s.name, err)
+		}
+		var ok bool
+		if r, ok = vars["req"].(map[string]any); !ok {
+			return ctx, fmt.Errorf("script %s: req must stay a map", s.name)
+		}
+	}
+	if method, ok := r["method"].(string); ok && method != "" {
+		req.Method = strings.ToUpper(method)
+	}
+	if url, ok := r["url"].(string); ok && url != req.URL.String() {
+		u, err := gourl.Parse(url)
+		if err != nil {
+			return ctx, fmt.Errorf("script: rewritten url: %w", err)
+		}
+		req.URL, req.Host = u, u.Host
+	}
+	if header, ok := r["header"].(map[string]any); ok {
+		req.Header = netHeader(header)
+	}
+	return ctx, nil
+}
+
+// After runs the Validate scripts on successful responses
+func (e *Engine) After(ctx context.Context, respCode int, respHeader http.Header, respData any, err error) (context.Context, error) {
+	scripts := e.programs(Validate)
+	if err != nil || len(scripts) == 0 {
+		return ctx, nil
+	}
+	body, _ := respData.([]byte)
+	resp := responseMap(respCode, respHeader, body)
+	for _, s := range scripts {
+		result, err := s.program.run(ctx, map[string]any{"resp": resp}, e.cfg)
+		var failure *Failure
+		switch {
+		case errors.As(err, &failure):
+			return ctx, &ValidationError{Script: s.name, Message: failure.Message}
+		case err != nil:
+			return ctx, fmt.Errorf("script %s: %w", s.name, err)
+		case !truthy(result):
+			return ctx, &ValidationError{Script: s.name, Message: "rule not met"}
+		}
+	}
+	return ctx, nil
+}
+
+// Transport runs the Transform scripts on the responses of next
+func (e *Engine) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transformTransport{engine: e, next: next}
+}
+
+type transformTransport struct {
+	engine *Engine
+	next   http.RoundTripper
+}
+
+func (t *transformTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	scripts := t.engine.programs(Transform)
+	if err != nil || len(scripts) == 0 {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r := responseMap(resp.StatusCode, resp.Header, body)
+	for _, s := range scripts {
+		vars := map[string]any{"resp": r}
+		if _, err := s.program.run(req.Context(), vars, t.engine.cfg); err != nil {
+			return nil, fmt.Errorf("script %s: %w", s.name, err)
+		}
+		var ok bool
+		if r, ok = vars["resp"].(map[string]any); !ok {
+			return nil, fmt.Errorf("script %s: resp must stay a map", s.name)
+		}
+	}
+	if status, ok := r["status"].(float64); ok && status >= 100 && status <= 999 {
+		resp.StatusCode = int(status)
+		resp.Status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+	}
+	if header, ok := r["header"].(map[string]any); ok {
+		resp.Header = netHeader(header)
+	}
+	if newBody, _ := r["body"].(string); newBody != string(body) {
+		body = []byte(newBody)
+	} else if isJSON(resp.Header) {
+		// decoded again as the scripts changed the map in place
+		var original any
+		_ = json.Unmarshal(body, &original)
+		if !reflect.DeepEqual(original, r["json"]) {
+			if body, err = json.Marshal(r["json"]); err != nil {
+				return nil, fmt.Errorf("script: encoding json: %w", err)
+			}
+		}
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+func responseMap(code int, header http.Header, body []byte) map[string]any {
+	resp := map[string]any{"status": float64(code), "header": headerMap(header), "body": string(body), "json": nil}
+	if isJSON(header) {
+		var v any
+		if json.Unmarshal(body, &v) == nil {
+			resp["json"] = v
+		}
+	}
+	return resp
+}
+
+func isJSON(header http.Header) bool {
+	return strings.Contains(header.Get("Content-Type"), "json")
+}
+
+// headerMap maps every header to its first value
+func headerMap(header http.Header) map[string]any {
+	m := make(map[string]any, len(header))
+	for name, values := range header {
+		if len(values) > 0 {
+			m[name] = values[0]
+		}
+	}
+	return m
+}
+
+// netHeader converts back, values may be strings or lists of them
+func netHeader(m map[string]any) http.Header {
+	header := make(http.Header, len(m))
+	for name, v := range m {
+		switch v := v.(type) {
+		case nil:
+		case []any:
+			for _, item := range v {
+				header.Add(name, toString(item))
+			}
+		default:
+			header.Set(name, toString(v))
+		}
+	}
+	return header
+}
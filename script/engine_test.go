@@ -0,0 +1,108 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+func TestEngineRewrite(t *testing.T) {
+	engine, err := NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	if err := engine.Register(Rewrite, "https", `req.url = replace(req.url, "http://", "https://")`); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := engine.Register(Rewrite, "tenant", `
+let tenant = query(req.url, "tenant")
+if tenant != "" {
+	req.header["X-Tenant"] = tenant
+	req.url = set_query(req.url, "tenant", "")
+}
+req.method = req.header["X-Method"] || req.method
+`); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := engine.Register(Rewrite, "broken", "let = 1"); err == nil {
+		t.Errorf("Register() invalid script error = nil")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://api.test/v1?tenant=acme", nil)
+	req.Header.Set("X-Method", "post")
+	if _, err := engine.Before(context.Background(), req); err != nil {
+		t.Fatalf("Before() error = %v", err)
+	}
+	if req.URL.String() != "https://api.test/v1?tenant=" || req.Header.Get("X-Tenant") != "acme" || req.Method != "POST" {
+		t.Errorf("Before() got = %v %v %v", req.Method, req.URL, req.Header)
+	}
+
+	if !engine.Remove(Rewrite, "https") || engine.Remove(Rewrite, "https") {
+		t.Errorf("Remove() got = false for a registered script or true twice")
+	}
+	_ = engine.Register(Rewrite, "tenant", `fail("blocked")`)
+	if _, err := engine.Before(context.Background(), req); err == nil || !strings.Contains(err.Error(), "script tenant") {
+		t.Errorf("Before() replaced script error got = %v", err)
+	}
+}
+
+func TestEngineValidate(t *testing.T) {
+	engine, _ := NewEngine()
+	_ = engine.Register(Validate, "status", `resp.status == 200`)
+	_ = engine.Register(Validate, "items", `
+if len(resp.json.items) == 0 {
+	fail("no items for " + resp.header["X-Query"])
+}
+true
+`)
+	header := http.Header{"Content-Type": {"application/json"}, "X-Query": {"shoes"}}
+	if _, err := engine.After(context.Background(), 200, header, []byte(`{"items":[1]}`), nil); err != nil {
+		t.Errorf("After() error = %v", err)
+	}
+	var validationErr *ValidationError
+	_, err := engine.After(context.Background(), 200, header, []byte(`{"items":[]}`), nil)
+	if !errors.As(err, &validationErr) || validationErr.Script != "items" || validationErr.Message != "no items for shoes" {
+		t.Errorf("After() error got = %v, want items validation error", err)
+	}
+	if _, err := engine.After(context.Background(), 201, header, []byte(`{}`), nil); !errors.As(err, &validationErr) || validationErr.Message != "rule not met" {
+		t.Errorf("After() error got = %v, want rule not met", err)
+	}
+	sendErr := errors.New("refused")
+	if _, err := engine.After(context.Background(), -1, nil, nil, sendErr); err != nil {
+		t.Errorf("After() with a send error got = %v, want nil", err)
+	}
+}
+
+func TestEngineTransform(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Internal", "1")
+		_, _ = w.Write([]byte(`{"user":{"name":"ann","password":"secret"}}`))
+	}))
+	defer server.Close()
+
+	engine, _ := NewEngine()
+	_ = engine.Register(Transform, "redact", `
+resp.json.user.password = nil
+resp.header["X-Internal"] = nil
+`)
+	client := gohttp.NewClient(gohttp.WithTransport(engine.Transport(nil)))
+	resp, err := client.NewRequest(gohttp.GET, server.URL).Send()
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if resp.String() != `{"user":{"name":"ann","password":null}}` || resp.Header.Get("X-Internal") != "" {
+		t.Errorf("Send() got = %s %v", resp.Body, resp.Header)
+	}
+
+	_ = engine.Register(Transform, "redact", `resp.status = 503; resp.body = "down"`)
+	resp, err = client.NewRequest(gohttp.GET, server.URL).Send()
+	if resp == nil || resp.StatusCode != 503 || resp.String() != "down" || err == nil {
+		t.Errorf("Send() got = %v %v, want 503 down", resp, err)
+	}
+}
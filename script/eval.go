@@ -0,0 +1,556 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+type node interface {
+	eval(s *state) (any, error)
+}
+
+type state struct {
+	ctx      context.Context
+	vars     map[string]any
+	scopes   []map[string]any
+	funcs    map[string]Func
+	steps    int
+	maxSteps int
+	result   any
+}
+
+// step counts the work done and checks ctx every 64 steps
+func (s *state) step(at int) error {
+	s.steps++
+	if s.steps > s.maxSteps {
+		return &Error{Line: at, Msg: fmt.Sprintf("more than %d steps", s.maxSteps), Err: ErrStepLimit}
+	}
+	if s.steps%64 == 0 {
+		if err := s.ctx.Err(); err != nil {
+			return &Error{Line: at, Msg: "stopped", Err: err}
+		}
+	}
+	return nil
+}
+
+func (s *state) run(stmts []node) error {
+	s.scopes = append(s.scopes, map[string]any{})
+	defer func() { s.scopes = s.scopes[:len(s.scopes)-1] }()
+	for _, stmt := range stmts {
+		if _, err := stmt.eval(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *state) lookup(name string) (any, bool) {
+	for i := len(s.scopes) - 1; i >= 0; i-- {
+		if v, ok := s.scopes[i][name]; ok {
+			return v, true
+		}
+	}
+	if v, ok := s.vars[name]; ok {
+		return v, true
+	}
+	if fn, ok := s.funcs[name]; ok {
+		return fn, true
+	}
+	return nil, false
+}
+
+func errorf(at int, format string, args ...any) error {
+	return &Error{Line: at, Msg: fmt.Sprintf(format, args...)}
+}
+
+type literal struct {
+	at int
+	v  any
+}
+
+func (n *literal) eval(s *state) (any, error) {
+	return n.v, s.step(n.at)
+}
+
+type ident struct {
+	at   int
+	name string
+}
+
+func (n *ident) eval(s *state) (any, error) {
+	if err := s.step(n.at); err != nil {
+		return nil, err
+	}
+	v, ok := s.lookup(n.name)
+	if !ok {
+		return nil, errorf(n.at, "undefined: %s", n.name)
+	}
+	return v, nil
+}
+
+type listLit struct {
+	at    int
+	items []node
+}
+
+func (n *listLit) eval(s *state) (any, error) {
+	list := make([]any, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, s.step(n.at)
+}
+
+type mapLit struct {
+	at     int
+	keys   []string
+	values []node
+}
+
+func (n *mapLit) eval(s *state) (any, error) {
+	m := make(map[string]any, len(n.keys))
+	for i, key := range n.keys {
+		v, err := n.values[i].eval(s)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, s.step(n.at)
+}
+
+type member struct {
+	at   int
+	x    node
+	name string
+}
+
+func (n *member) eval(s *state) (any, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := x.(map[string]any)
+	if !ok {
+		return nil, errorf(n.at, "%s has no field %s", typeName(x), n.name)
+	}
+	return m[n.name], s.step(n.at)
+}
+
+type index struct {
+	at int
+	x  node
+	i  node
+}
+
+func (n *index) eval(s *state) (any, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	i, err := n.i.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.step(n.at); err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case map[string]any:
+		key, ok := i.(string)
+		if !ok {
+			return nil, errorf(n.at, "map key must be a string, got %s", typeName(i))
+		}
+		return x[key], nil
+	case []any:
+		pos, err := listIndex(n.at, x, i)
+		if err != nil {
+			return nil, err
+		}
+		return x[pos], nil
+	}
+	return nil, errorf(n.at, "cannot index %s", typeName(x))
+}
+
+func listIndex(at int, list []any, i any) (int, error) {
+	f, ok := i.(float64)
+	if !ok || f != math.Trunc(f) {
+		return 0, errorf(at, "list index must be an integer, got %v", i)
+	}
+	pos := int(f)
+	if pos < 0 {
+		// negative indexes count from the end
+		pos += len(list)
+	}
+	if pos < 0 || pos >= len(list) {
+		return 0, errorf(at, "index %v out of range for list of %d", i, len(list))
+	}
+	return pos, nil
+}
+
+type call struct {
+	at   int
+	fn   node
+	args []node
+}
+
+func (n *call) eval(s *state) (any, error) {
+	fn, err := n.fn.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := fn.(Func)
+	if !ok {
+		return nil, errorf(n.at, "cannot call %s", typeName(fn))
+	}
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		if args[i], err = arg.eval(s); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.step(n.at); err != nil {
+		return nil, err
+	}
+	v, err := f(args...)
+	if err != nil {
+		if e, ok := err.(*Error); ok {
+			return nil, e
+		}
+		return nil, &Error{Line: n.at, Msg: err.Error(), Err: err}
+	}
+	return normalize(v), nil
+}
+
+type unary struct {
+	at int
+	op string
+	x  node
+}
+
+func (n *unary) eval(s *state) (any, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !truthy(x), s.step(n.at)
+	}
+	f, ok := x.(float64)
+	if !ok {
+		return nil, errorf(n.at, "cannot negate %s", typeName(x))
+	}
+	return -f, s.step(n.at)
+}
+
+type binary struct {
+	at int
+	op string
+	l  node
+	r  node
+}
+
+func (n *binary) eval(s *state) (any, error) {
+	l, err := n.l.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	// like in Lua the operands are returned, a || "default" picks a value
+	case "&&":
+		if !truthy(l) {
+			return l, nil
+		}
+		return n.r.eval(s)
+	case "||":
+		if truthy(l) {
+			return l, nil
+		}
+		return n.r.eval(s)
+	}
+	r, err := n.r.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.step(n.at); err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "in":
+		return contains(n.at, r, l)
+	}
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			return stringOp(n.at, n.op, ls, rs)
+		}
+	}
+	if ll, ok := l.([]any); ok && n.op == "+" {
+		if rl, ok := r.([]any); ok {
+			if len(ll)+len(rl) > MaxListLen {
+				return nil, errorf(n.at, "list longer than %d items", MaxListLen)
+			}
+			return append(append(make([]any, 0, len(ll)+len(rl)), ll...), rl...), nil
+		}
+	}
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, errorf(n.at, "invalid operation %s %s %s", typeName(l), n.op, typeName(r))
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, errorf(n.at, "division by zero")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, errorf(n.at, "division by zero")
+		}
+		return math.Mod(lf, rf), nil
+	case "<":
+		return lf < rf, nil
+	case "<=":
+		return lf <= rf, nil
+	case ">":
+		return lf > rf, nil
+	}
+	return lf >= rf, nil
+}
+
+func stringOp(at int, op string, l string, r string) (any, error) {
+	switch op {
+	case "+":
+		if len(l)+len(r) > MaxStringLen {
+			return nil, errorf(at, "string longer than %d bytes", MaxStringLen)
+		}
+		return l + r, nil
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return nil, errorf(at, "invalid operation string %s string", op)
+}
+
+// contains implements x in container
+func contains(at int, container any, x any) (any, error) {
+	switch c := container.(type) {
+	case []any:
+		for _, item := range c {
+			if equal(item, x) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		key, ok := x.(string)
+		_, found := c[key]
+		return ok && found, nil
+	case string:
+		sub, ok := x.(string)
+		if !ok {
+			return nil, errorf(at, "invalid operation %s in string", typeName(x))
+		}
+		return strings.Contains(c, sub), nil
+	}
+	return nil, errorf(at, "invalid operation in %s", typeName(container))
+}
+
+type condExpr struct {
+	at   int
+	cond node
+	a    node
+	b    node
+}
+
+func (n *condExpr) eval(s *state) (any, error) {
+	cond, err := n.cond.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(cond) {
+		return n.a.eval(s)
+	}
+	return n.b.eval(s)
+}
+
+type letStmt struct {
+	at   int
+	name string
+	x    node
+}
+
+func (n *letStmt) eval(s *state) (any, error) {
+	v, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	s.scopes[len(s.scopes)-1][n.name] = v
+	return nil, nil
+}
+
+type assignStmt struct {
+	at     int
+	target node
+	x      node
+}
+
+func (n *assignStmt) eval(s *state) (any, error) {
+	v, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch t := n.target.(type) {
+	case *ident:
+		for i := len(s.scopes) - 1; i >= 0; i-- {
+			if _, ok := s.scopes[i][t.name]; ok {
+				s.scopes[i][t.name] = v
+				return nil, nil
+			}
+		}
+		if _, ok := s.vars[t.name]; ok {
+			s.vars[t.name] = v
+			return nil, nil
+		}
+		return nil, errorf(n.at, "undefined: %s, declare it with let", t.name)
+	case *member:
+		x, err := t.x.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := x.(map[string]any)
+		if !ok {
+			return nil, errorf(n.at, "cannot set field %s of %s", t.name, typeName(x))
+		}
+		m[t.name] = v
+		return nil, nil
+	}
+	t := n.target.(*index)
+	x, err := t.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	i, err := t.i.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case map[string]any:
+		key, ok := i.(string)
+		if !ok {
+			return nil, errorf(n.at, "map key must be a string, got %s", typeName(i))
+		}
+		x[key] = v
+		return nil, nil
+	case []any:
+		pos, err := listIndex(n.at, x, i)
+		if err != nil {
+			return nil, err
+		}
+		x[pos] = v
+		return nil, nil
+	}
+	return nil, errorf(n.at, "cannot index %s", typeName(x))
+}
+
+type ifStmt struct {
+	at   int
+	cond node
+	then []node
+	els  []node
+}
+
+func (n *ifStmt) eval(s *state) (any, error) {
+	cond, err := n.cond.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(cond) {
+		return nil, s.run(n.then)
+	}
+	return nil, s.run(n.els)
+}
+
+type exprStmt struct {
+	at int
+	x  node
+}
+
+func (n *exprStmt) eval(s *state) (any, error) {
+	v, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	s.result = v
+	return v, nil
+}
+
+// truthy is false for nil, false, 0 and "", true otherwise
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+func equal(a any, b any) bool {
+	switch a.(type) {
+	case []any, map[string]any:
+		return reflect.DeepEqual(a, b)
+	case Func:
+		return false
+	}
+	if _, ok := b.(Func); ok {
+		return false
+	}
+	return a == b
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "map"
+	case Func:
+		return "function"
+	}
+	return fmt.Sprintf("%T", v)
+}
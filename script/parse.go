@@ -0,0 +1,482 @@
+package script
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tNewline
+	tIdent
+	tNumber
+	tString
+	tOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// value is the number or the unquoted string
+	value any
+	line  int
+}
+
+// twoCharOps are matched before the single character ones
+var twoCharOps = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+const oneCharOps = "+-*/%<>!=.,:;()[]{}?"
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			tokens = append(tokens, token{kind: tNewline, line: line})
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case isLetter(c):
+			start := i
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tIdent, text: src[start:i], line: line})
+		case isDigit(c):
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, &Error{Line: line, Msg: "invalid number " + src[start:i]}
+			}
+			tokens = append(tokens, token{kind: tNumber, text: src[start:i], value: n, line: line})
+		case c == '"' || c == '`':
+			end := i + 1
+			for end < len(src) && src[end] != c && !(c == '"' && src[end] == '\n') {
+				if c == '"' && src[end] == '\\' && end+1 < len(src) {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) || src[end] != c {
+				return nil, &Error{Line: line, Msg: "unterminated string"}
+			}
+			s, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, &Error{Line: line, Msg: "invalid string " + src[i:end+1]}
+			}
+			tokens = append(tokens, token{kind: tString, text: src[i : end+1], value: s, line: line})
+			line += strings.Count(src[i:end+1], "\n")
+			i = end + 1
+		default:
+			op := ""
+			for _, two := range twoCharOps {
+				if strings.HasPrefix(src[i:], two) {
+					op = two
+				}
+			}
+			if op == "" && strings.IndexByte(oneCharOps, c) >= 0 {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, &Error{Line: line, Msg: "unexpected character " + strconv.QuoteRune(rune(c))}
+			}
+			tokens = append(tokens, token{kind: tOp, text: op, line: line})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tEOF, line: line}), nil
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+var keywords = map[string]bool{"let": true, "if": true, "else": true, "true": true, "false": true, "nil": true, "in": true}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(src string) ([]node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	stmts, err := p.statements(tEOF)
+	if err != nil {
+		return nil, err
+	}
+	return stmts, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is reports whether the next token is the operator or keyword text
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == tOp || t.kind == tIdent) && t.text == text
+}
+
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected("want " + text)
+	}
+	return nil
+}
+
+func (p *parser) unexpected(want string) error {
+	t := p.peek()
+	got := t.text
+	switch t.kind {
+	case tEOF:
+		got = "end of script"
+	case tNewline:
+		got = "end of line"
+	}
+	return &Error{Line: t.line, Msg: "unexpected " + got + ", " + want}
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().kind == tNewline {
+		p.next()
+	}
+}
+
+// statements parses up to end, tEOF or the closing brace of a block
+func (p *parser) statements(end tokenKind) ([]node, error) {
+	var stmts []node
+	for {
+		for p.peek().kind == tNewline || p.is(";") {
+			p.next()
+		}
+		if p.peek().kind == tEOF || (end == tOp && p.is("}")) {
+			if end == tOp && !p.is("}") {
+				return nil, p.unexpected("want }")
+			}
+			return stmts, nil
+		}
+		stmt, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+		if t := p.peek(); t.kind != tNewline && t.kind != tEOF && !p.is(";") && !p.is("}") {
+			return nil, p.unexpected("want end of statement")
+		}
+	}
+}
+
+func (p *parser) block() ([]node, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	stmts, err := p.statements(tOp)
+	if err != nil {
+		return nil, err
+	}
+	return stmts, p.expect("}")
+}
+
+func (p *parser) statement() (node, error) {
+	line := p.peek().line
+	switch {
+	case p.accept("let"):
+		name := p.next()
+		if name.kind != tIdent || keywords[name.text] {
+			return nil, &Error{Line: line, Msg: "let wants a name"}
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return &letStmt{at: line, name: name.text, x: x}, nil
+	case p.accept("if"):
+		return p.ifStatement(line)
+	}
+	x, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("=") {
+		return &exprStmt{at: line, x: x}, nil
+	}
+	switch x.(type) {
+	case *ident, *member, *index:
+	default:
+		return nil, &Error{Line: line, Msg: "cannot assign to this expression"}
+	}
+	value, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &assignStmt{at: line, target: x, x: value}, nil
+}
+
+func (p *parser) ifStatement(line int) (node, error) {
+	cond, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	then, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &ifStmt{at: line, cond: cond, then: then}
+	if !p.accept("else") {
+		return stmt, nil
+	}
+	if p.accept("if") {
+		elseIf, err := p.ifStatement(p.peek().line)
+		if err != nil {
+			return nil, err
+		}
+		stmt.els = []node{elseIf}
+		return stmt, nil
+	}
+	stmt.els, err = p.block()
+	return stmt, err
+}
+
+func (p *parser) expr() (node, error) {
+	cond, err := p.binary(0)
+	if err != nil || !p.is("?") {
+		return cond, err
+	}
+	line := p.next().line
+	p.skipNewlines()
+	a, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipNewlines()
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	p.skipNewlines()
+	b, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &condExpr{at: line, cond: cond, a: a, b: b}, nil
+}
+
+// precedence lists the binary operators from the loosest binding
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) binary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range precedence[level] {
+			if p.is(candidate) {
+				op = candidate
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		line := p.next().line
+		p.skipNewlines()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{at: line, op: op, l: left, r: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if p.is("!") || p.is("-") {
+		t := p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{at: t.line, op: t.text, x: x}, nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (node, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		line := p.peek().line
+		switch {
+		case p.accept("."):
+			name := p.next()
+			if name.kind != tIdent {
+				return nil, &Error{Line: line, Msg: "want a field name after ."}
+			}
+			x = &member{at: line, x: x, name: name.text}
+		case p.accept("["):
+			p.skipNewlines()
+			i, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			p.skipNewlines()
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &index{at: line, x: x, i: i}
+		case p.accept("("):
+			args, err := p.list(")")
+			if err != nil {
+				return nil, err
+			}
+			x = &call{at: line, fn: x, args: args}
+		default:
+			return x, nil
+		}
+	}
+}
+
+// list parses comma separated expressions up to end, a trailing comma is allowed
+func (p *parser) list(end string) ([]node, error) {
+	var items []node
+	for {
+		p.skipNewlines()
+		if p.accept(end) {
+			return items, nil
+		}
+		item, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.skipNewlines()
+		if !p.accept(",") {
+			p.skipNewlines()
+			return items, p.expect(end)
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tNumber || t.kind == tString:
+		p.next()
+		return &literal{at: t.line, v: t.value}, nil
+	case t.kind == tIdent:
+		p.next()
+		switch t.text {
+		case "true":
+			return &literal{at: t.line, v: true}, nil
+		case "false":
+			return &literal{at: t.line, v: false}, nil
+		case "nil":
+			return &literal{at: t.line, v: nil}, nil
+		}
+		if keywords[t.text] {
+			return nil, &Error{Line: t.line, Msg: "unexpected " + t.text}
+		}
+		return &ident{at: t.line, name: t.text}, nil
+	case p.accept("("):
+		p.skipNewlines()
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipNewlines()
+		return x, p.expect(")")
+	case p.accept("["):
+		items, err := p.list("]")
+		if err != nil {
+			return nil, err
+		}
+		return &listLit{at: t.line, items: items}, nil
+	case p.accept("{"):
+		return p.mapLiteral(t.line)
+	}
+	return nil, p.unexpected("want an expression")
+}
+
+// mapLiteral parses {key: value, ...}, keys are names or strings
+func (p *parser) mapLiteral(line int) (node, error) {
+	m := &mapLit{at: line}
+	for {
+		p.skipNewlines()
+		if p.accept("}") {
+			return m, nil
+		}
+		key := p.next()
+		switch key.kind {
+		case tIdent:
+			m.keys = append(m.keys, key.text)
+		case tString:
+			m.keys = append(m.keys, key.value.(string))
+		default:
+			return nil, &Error{Line: key.line, Msg: "want a name or string as map key"}
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		p.skipNewlines()
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		m.values = append(m.values, value)
+		p.skipNewlines()
+		if !p.accept(",") {
+			p.skipNewlines()
+			return m, p.expect("}")
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package script runs small user supplied scripts, so behaviour like request
+// rewrites, response transforms and validation rules can change at runtime
+// without recompiling.
+//
+// Scripts are expressions and statements over nil, bools, numbers, strings,
+// lists and maps:
+//
+//	# a comment
+//	let host = lower(req.header["X-Forwarded-Host"] || "")
+//	if host in ["a.example.com", "b.example.com"] {
+//		req.header["X-Tenant"] = split(host, ".")[0]
+//	} else if !has_prefix(req.url, "https://") {
+//		fail("plain http is not allowed")
+//	}
+//	resp.status == 200 && len(resp.json.items) > 0
+//
+// The value of the last expression statement is the result of the script,
+// && and || return one of their operands. Header maps use canonical names.
+// There are no loops and no access to files, the network or the environment,
+// only to the variables and the functions given to Run. Runs are bounded by a
+// step limit and a timeout.
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+const (
+	// MaxStringLen bounds the strings built by scripts
+	MaxStringLen = 1 << 20
+	// MaxListLen bounds the lists built by scripts
+	MaxListLen = 1 << 16
+)
+
+// ErrStepLimit is returned when a run takes more steps than WithMaxSteps
+var ErrStepLimit = errors.New("script: step limit exceeded")
+
+// Func is a function scripts can call. Arguments and results are nil, bool,
+// float64, string, []any or map[string]any; other numbers, []string and
+// map[string]string results are converted.
+type Func func(args ...any) (any, error)
+
+// Error is a compile or run error of a script
+type Error struct {
+	Line int
+	Msg  string
+	// Err is the cause, like ErrStepLimit, context.DeadlineExceeded or the
+	// *Failure raised by fail()
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("script: line %d: %s", e.Line, e.Msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Failure is raised by the fail(message) function of scripts
+type Failure struct {
+	Message string
+}
+
+func (f *Failure) Error() string {
+	return f.Message
+}
+
+// Program is a compiled script, it is safe for concurrent runs
+type Program struct {
+	stmts []node
+}
+
+// Compile parses source
+func Compile(source string) (*Program, error) {
+	stmts, err := parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{stmts: stmts}, nil
+}
+
+type config struct {
+	timeout  time.Duration
+	maxSteps int
+	funcs    map[string]Func
+}
+
+type Option = opt.Option[config]
+
+// WithTimeout bounds a run, 100ms by default
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithMaxSteps bounds the expressions evaluated by a run, 100000 by default
+func WithMaxSteps(n int) Option {
+	return func(c *config) {
+		c.maxSteps = n
+	}
+}
+
+// WithFuncs adds functions to the standard ones, replacing those with the
+// same name
+func WithFuncs(funcs map[string]Func) Option {
+	return func(c *config) {
+		for name, fn := range funcs {
+			c.funcs[name] = fn
+		}
+	}
+}
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.timeout <= 0 || c.maxSteps <= 0 {
+			return errors.New("script: timeout and max steps must be positive")
+		}
+		return nil
+	},
+}
+
+func newConfig(opts []Option) (config, error) {
+	cfg := config{timeout: 100 * time.Millisecond, maxSteps: 100000, funcs: map[string]Func{}}
+	for name, fn := range stdlib {
+		cfg.funcs[name] = fn
+	}
+	return cfg, opt.Build(&cfg, opts, checks...)
+}
+
+// Run runs the program with vars as its variables and returns the value of
+// the last expression statement. Values in vars are converted like Func
+// results and stored back, so changes made by the script are visible in vars
+// afterwards.
+func (p *Program) Run(ctx context.Context, vars map[string]any, opts ...Option) (any, error) {
+	cfg, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return p.run(ctx, vars, cfg)
+}
+
+func (p *Program) run(ctx context.Context, vars map[string]any, cfg config) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	defer cancel()
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	for name, v := range vars {
+		vars[name] = normalize(v)
+	}
+	s := &state{ctx: ctx, vars: vars, funcs: cfg.funcs, maxSteps: cfg.maxSteps}
+	if err := s.run(p.stmts); err != nil {
+		return nil, err
+	}
+	return s.result, nil
+}
+
+// normalize converts Go values to the types scripts work with
+func normalize(v any) any {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		list := make([]any, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list
+	case map[string]string:
+		m := make(map[string]any, len(v))
+		for k, s := range v {
+			m[k] = s
+		}
+		return m
+	case []any:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+	case map[string]any:
+		for k, item := range v {
+			v[k] = normalize(item)
+		}
+	case func(args ...any) (any, error):
+		return Func(v)
+	}
+	return v
+}
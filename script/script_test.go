@@ -0,0 +1,145 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   any
+	}{
+		{"arithmetic", "1 + 2 * 3 - 4 / 2 % 3", 5.0},
+		{"precedence", "(1 + 2) * 3 == 9 && !false", true},
+		{"strings", `upper("a" + "b") + str(1.5)`, "AB1.5"},
+		{"raw string", "matches(`^v\\d+$`, \"v12\")", true},
+		{"default", `nil || "fallback"`, "fallback"},
+		{"and value", `1 && "second"`, "second"},
+		{"ternary", `len([1, 2]) > 1 ? "many" : "one"`, "many"},
+		{"in", `"b" in ["a", "b"] && "k" in {k: 1} && "ell" in "hello"`, true},
+		{"index", "let l = [1, 2, 3]\nl[-1] + {\"a b\": 4}[\"a b\"]", 7.0},
+		{"member", "let m = {a: {b: [10, 20]}}\nm.a.b[1]", 20.0},
+		{"assign", "let m = {}\nm.x = 1; m[\"y\"] = 2\nlet n = 0\nn = m.x + m.y\nn", 3.0},
+		{"if", "let x = 5\nif x > 10 {\n\"big\"\n} else if x > 3 {\n\"medium\"\n} else {\n\"small\"\n}", "medium"},
+		{"scope", "let x = 1\nif true {\nlet x = 2\n}\nx", 1.0},
+		{"multiline", "let l = [\n  1,\n  2,\n]\nlen(l) +\n  1", 3.0},
+		{"comments", "# first\nlet x = 1 // second\nx", 1.0},
+		{"stdlib", `join(split(replace("a-b-c", "-", ","), ","), "+") + "/" + str(len(sha256("")))`, "a+b+c/64"},
+		{"json", `json_decode(json_encode({a: [1, "x"]})).a[1]`, "x"},
+		{"query", `query(set_query("https://x.test/p?a=1", "b", 2), "b")`, "2"},
+		{"keys", `keys({b: 1, a: 2})`, []any{"a", "b"}},
+		{"base64", `base64_decode(base64_encode("hi"))`, "hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := program.Run(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunVars(t *testing.T) {
+	program, err := Compile(`
+req.header["X-Count"] = str(count + 1)
+req.tags = req.tags + [double(2)]
+total = count * 2
+`)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	vars := map[string]any{
+		"req":   map[string]any{"header": map[string]string{}, "tags": []string{"a"}},
+		"count": 2,
+		"total": nil,
+	}
+	double := WithFuncs(map[string]Func{"double": func(args ...any) (any, error) { return args[0].(float64) * 2, nil }})
+	if _, err := program.Run(context.Background(), vars, double); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	req := vars["req"].(map[string]any)
+	if req["header"].(map[string]any)["X-Count"] != "3" || !reflect.DeepEqual(req["tags"], []any{"a", 4.0}) || vars["total"] != 4.0 {
+		t.Errorf("Run() vars got = %v", vars)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"syntax", "let x = (1 +", "line 1: unexpected end of script"},
+		{"unterminated", "\"abc", "line 1: unterminated string"},
+		{"keyword", "let if = 1", "let wants a name"},
+		{"assign", "1 = 2", "cannot assign"},
+		{"block", "if true {\n1", "want }"},
+		{"trailing", "1 2", "want end of statement"},
+		{"undefined", "\n\nmissing + 1", "line 3: undefined: missing"},
+		{"undeclared", "x = 1", "declare it with let"},
+		{"types", `1 + "a"`, "invalid operation number + string"},
+		{"field", `let n = 1; n.x`, "number has no field x"},
+		{"range", `[1][3]`, "out of range"},
+		{"call", `1(2)`, "cannot call number"},
+		{"args", `lower(1)`, "lower wants a string"},
+		{"zero", `1 / 0`, "division by zero"},
+		{"fail", `fail("custom " + str(42))`, "custom 42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Compile(tt.source)
+			if err == nil {
+				_, err = program.Run(context.Background(), nil)
+			}
+			var scriptErr *Error
+			if !errors.As(err, &scriptErr) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error got = %v, want *Error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	program, _ := Compile(`fail("nope")`)
+	_, err := program.Run(context.Background(), nil)
+	var failure *Failure
+	if !errors.As(err, &failure) || failure.Message != "nope" {
+		t.Errorf("Run() fail error got = %v, want *Failure nope", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	program, _ := Compile(strings.Repeat("let x = 1 + 1\n", 100))
+	if _, err := program.Run(context.Background(), nil, WithMaxSteps(50)); !errors.Is(err, ErrStepLimit) {
+		t.Errorf("Run() error got = %v, want %v", err, ErrStepLimit)
+	}
+
+	slow, _ := Compile(strings.Repeat("wait()\n", 200))
+	wait := WithFuncs(map[string]Func{"wait": func(args ...any) (any, error) {
+		time.Sleep(time.Millisecond)
+		return nil, nil
+	}})
+	if _, err := slow.Run(context.Background(), nil, wait, WithTimeout(10*time.Millisecond)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error got = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	grow, _ := Compile("let s = \"" + strings.Repeat("x", 1024) + "\"\n" + strings.Repeat("s = s + s\n", 11))
+	if _, err := grow.Run(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "string longer than") {
+		t.Errorf("Run() error got = %v, want a string length error", err)
+	}
+	if _, err := grow.Run(context.Background(), nil, WithTimeout(0)); err == nil {
+		t.Errorf("Run() with zero timeout error = nil")
+	}
+}
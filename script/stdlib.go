@@ -0,0 +1,356 @@
+package script
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	gourl "net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stdlib are the functions every script can call
+var stdlib = map[string]Func{
+	"len":           builtinLen,
+	"lower":         stringFunc("lower", strings.ToLower),
+	"upper":         stringFunc("upper", strings.ToUpper),
+	"trim":          stringFunc("trim", strings.TrimSpace),
+	"contains":      stringPredicate("contains", strings.Contains),
+	"has_prefix":    stringPredicate("has_prefix", strings.HasPrefix),
+	"has_suffix":    stringPredicate("has_suffix", strings.HasSuffix),
+	"replace":       builtinReplace,
+	"split":         builtinSplit,
+	"join":          builtinJoin,
+	"matches":       builtinMatches,
+	"str":           builtinStr,
+	"num":           builtinNum,
+	"keys":          builtinKeys,
+	"json_encode":   builtinJSONEncode,
+	"json_decode":   builtinJSONDecode,
+	"base64_encode": builtinBase64Encode,
+	"base64_decode": builtinBase64Decode,
+	"sha256":        builtinSHA256,
+	"hmac_sha256":   builtinHMACSHA256,
+	"query":         builtinQuery,
+	"set_query":     builtinSetQuery,
+	"now":           builtinNow,
+	"fail":          builtinFail,
+}
+
+func wantArgs(name string, args []any, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("%s wants %d arguments, got %d", name, n, len(args))
+	}
+	return nil
+}
+
+func stringArg(name string, args []any, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", fmt.Errorf("%s wants a string as argument %d, got %s", name, i+1, typeName(args[i]))
+	}
+	return s, nil
+}
+
+// stringArgs checks for n string arguments
+func stringArgs(name string, args []any, n int) ([]string, error) {
+	if err := wantArgs(name, args, n); err != nil {
+		return nil, err
+	}
+	strs := make([]string, n)
+	for i := range args {
+		s, err := stringArg(name, args, i)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+func checkLen(s string) (any, error) {
+	if len(s) > MaxStringLen {
+		return nil, fmt.Errorf("string longer than %d bytes", MaxStringLen)
+	}
+	return s, nil
+}
+
+func stringFunc(name string, fn func(string) string) Func {
+	return func(args ...any) (any, error) {
+		strs, err := stringArgs(name, args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return fn(strs[0]), nil
+	}
+}
+
+func stringPredicate(name string, fn func(string, string) bool) Func {
+	return func(args ...any) (any, error) {
+		strs, err := stringArgs(name, args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return fn(strs[0], strs[1]), nil
+	}
+}
+
+func builtinLen(args ...any) (any, error) {
+	if err := wantArgs("len", args, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case string:
+		return len(v), nil
+	case []any:
+		return len(v), nil
+	case map[string]any:
+		return len(v), nil
+	case nil:
+		return 0, nil
+	}
+	return nil, fmt.Errorf("len of %s", typeName(args[0]))
+}
+
+func builtinReplace(args ...any) (any, error) {
+	strs, err := stringArgs("replace", args, 3)
+	if err != nil {
+		return nil, err
+	}
+	if n := strings.Count(strs[0], strs[1]); n > 0 && len(strs[0])+n*(len(strs[2])-len(strs[1])) > MaxStringLen {
+		return nil, fmt.Errorf("string longer than %d bytes", MaxStringLen)
+	}
+	return strings.ReplaceAll(strs[0], strs[1], strs[2]), nil
+}
+
+func builtinSplit(args ...any) (any, error) {
+	strs, err := stringArgs("split", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(strs[0], strs[1], MaxListLen)
+	return parts, nil
+}
+
+func builtinJoin(args ...any) (any, error) {
+	if err := wantArgs("join", args, 2); err != nil {
+		return nil, err
+	}
+	list, ok := args[0].([]any)
+	if !ok {
+		return nil, fmt.Errorf("join wants a list, got %s", typeName(args[0]))
+	}
+	sep, err := stringArg("join", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, len(list))
+	for i, item := range list {
+		strs[i] = toString(item)
+	}
+	return checkLen(strings.Join(strs, sep))
+}
+
+// regexps caches the compiled patterns, it is reset when full
+var regexps = struct {
+	sync.Mutex
+	m map[string]*regexp.Regexp
+}{m: map[string]*regexp.Regexp{}}
+
+func builtinMatches(args ...any) (any, error) {
+	strs, err := stringArgs("matches", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Lock()
+	re, ok := regexps.m[strs[0]]
+	regexps.Unlock()
+	if !ok {
+		// RE2 runs in linear time, patterns cannot be used to stall a run
+		if re, err = regexp.Compile(strs[0]); err != nil {
+			return nil, err
+		}
+		regexps.Lock()
+		if len(regexps.m) >= 256 {
+			regexps.m = map[string]*regexp.Regexp{}
+		}
+		regexps.m[strs[0]] = re
+		regexps.Unlock()
+	}
+	return re.MatchString(strs[1]), nil
+}
+
+func toString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any, map[string]any:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+func builtinStr(args ...any) (any, error) {
+	if err := wantArgs("str", args, 1); err != nil {
+		return nil, err
+	}
+	return checkLen(toString(args[0]))
+}
+
+func builtinNum(args ...any) (any, error) {
+	if err := wantArgs("num", args, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return nil, fmt.Errorf("num of %s", typeName(args[0]))
+}
+
+func builtinKeys(args ...any) (any, error) {
+	if err := wantArgs("keys", args, 1); err != nil {
+		return nil, err
+	}
+	m, ok := args[0].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("keys wants a map, got %s", typeName(args[0]))
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func builtinJSONEncode(args ...any) (any, error) {
+	if err := wantArgs("json_encode", args, 1); err != nil {
+		return nil, err
+	}
+	if _, ok := args[0].(Func); ok {
+		return nil, fmt.Errorf("json_encode of a function")
+	}
+	data, err := json.Marshal(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return checkLen(string(data))
+}
+
+func builtinJSONDecode(args ...any) (any, error) {
+	strs, err := stringArgs("json_decode", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal([]byte(strs[0]), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func builtinBase64Encode(args ...any) (any, error) {
+	strs, err := stringArgs("base64_encode", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return checkLen(base64.StdEncoding.EncodeToString([]byte(strs[0])))
+}
+
+func builtinBase64Decode(args ...any) (any, error) {
+	strs, err := stringArgs("base64_decode", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(strs[0])
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func builtinSHA256(args ...any) (any, error) {
+	strs, err := stringArgs("sha256", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(strs[0]))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func builtinHMACSHA256(args ...any) (any, error) {
+	strs, err := stringArgs("hmac_sha256", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, []byte(strs[0]))
+	mac.Write([]byte(strs[1]))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// builtinQuery returns a query parameter of a url
+func builtinQuery(args ...any) (any, error) {
+	strs, err := stringArgs("query", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	u, err := gourl.Parse(strs[0])
+	if err != nil {
+		return nil, err
+	}
+	return u.Query().Get(strs[1]), nil
+}
+
+// builtinSetQuery returns the url with a query parameter set
+func builtinSetQuery(args ...any) (any, error) {
+	if err := wantArgs("set_query", args, 3); err != nil {
+		return nil, err
+	}
+	strs, err := stringArgs("set_query", args[:2], 2)
+	if err != nil {
+		return nil, err
+	}
+	u, err := gourl.Parse(strs[0])
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set(strs[1], toString(args[2]))
+	u.RawQuery = query.Encode()
+	return checkLen(u.String())
+}
+
+// builtinNow returns the unix time in seconds
+func builtinNow(args ...any) (any, error) {
+	if err := wantArgs("now", args, 0); err != nil {
+		return nil, err
+	}
+	return float64(time.Now().UnixNano()) / 1e9, nil
+}
+
+func builtinFail(args ...any) (any, error) {
+	if err := wantArgs("fail", args, 1); err != nil {
+		return nil, err
+	}
+	return nil, &Failure{Message: toString(args[0])}
+}
@@ -0,0 +1,304 @@
+// Package selfupdate replaces the running binary with a newer release. A
+// release endpoint is asked for the latest version, the asset of the platform
+// is downloaded, verified against its checksum and optionally an ed25519
+// signature, and swapped in atomically. The previous binary is kept until the
+// new one confirmed it starts, so a failed restart can be rolled back.
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+var (
+	// ErrNoAsset is returned when the release has no asset for the platform
+	ErrNoAsset = errors.New("selfupdate: no asset for the platform")
+	// ErrChecksum is returned when the asset has no or a wrong sha256
+	ErrChecksum = errors.New("selfupdate: checksum mismatch")
+	// ErrSignature is returned when the asset has no or an invalid signature
+	ErrSignature = errors.New("selfupdate: invalid signature")
+	// ErrRolledBack is returned by Verify when the new binary was not healthy
+	ErrRolledBack = errors.New("selfupdate: update rolled back")
+)
+
+// Release is the answer of the release endpoint
+type Release struct {
+	Version string  `json:"version"`
+	Notes   string  `json:"notes,omitempty"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a binary of a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// SHA256 is the hex encoded checksum of the binary
+	SHA256 string `json:"sha256"`
+	// Signature is the base64 encoded ed25519 signature of the binary
+	Signature string `json:"signature,omitempty"`
+}
+
+type config struct {
+	client     *gohttp.Client
+	publicKey  ed25519.PublicKey
+	executable string
+	match      func(name string) bool
+	prerelease bool
+	logger     gohttp.Logger
+}
+
+type Option = opt.Option[config]
+
+// WithClient sends the requests with client
+func WithClient(client *gohttp.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithPublicKey requires assets to be signed by the private key of key
+func WithPublicKey(key ed25519.PublicKey) Option {
+	return func(c *config) {
+		c.publicKey = key
+	}
+}
+
+// WithExecutable replaces the binary at path instead of the running one
+func WithExecutable(path string) Option {
+	return func(c *config) {
+		c.executable = path
+	}
+}
+
+// WithAssetMatcher picks the asset of the platform. By default it is the
+// first asset whose name contains GOOS and GOARCH, e.g. app_linux_amd64.
+func WithAssetMatcher(match func(name string) bool) Option {
+	return func(c *config) {
+		c.match = match
+	}
+}
+
+// WithPrerelease also updates to prereleases
+func WithPrerelease() Option {
+	return func(c *config) {
+		c.prerelease = true
+	}
+}
+
+// WithLogger logs the updates and rollbacks
+func WithLogger(logger gohttp.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// Updater updates the binary from a release endpoint
+type Updater struct {
+	endpoint string
+	current  Version
+	config
+}
+
+// New returns an Updater for the binary of version current that asks endpoint
+// for the latest Release as JSON
+func New(endpoint string, current string, opts ...Option) (*Updater, error) {
+	version, err := ParseVersion(current)
+	if err != nil {
+		return nil, err
+	}
+	u := &Updater{endpoint: endpoint, current: version, config: config{logger: gohttp.NopLogger, match: platformMatcher(runtime.GOOS, runtime.GOARCH)}}
+	if err := opt.Build(&u.config, opts); err != nil {
+		return nil, err
+	}
+	if u.client == nil {
+		u.client = gohttp.NewClient(gohttp.WithLogger(u.logger))
+	}
+	if u.executable == "" {
+		if u.executable, err = os.Executable(); err != nil {
+			return nil, err
+		}
+	}
+	// replace the file rather than a symlink to it
+	if resolved, err := filepath.EvalSymlinks(u.executable); err == nil {
+		u.executable = resolved
+	}
+	return u, nil
+}
+
+func platformMatcher(goos string, goarch string) func(name string) bool {
+	return func(name string) bool {
+		name = strings.ToLower(name)
+		return strings.Contains(name, goos) && strings.Contains(name, goarch)
+	}
+}
+
+// Check returns the latest release when it is newer than the running
+// version, nil otherwise
+func (u *Updater) Check(ctx context.Context) (*Release, error) {
+	resp, err := u.client.NewRequest(gohttp.GET, u.endpoint).WithContext(ctx).Send()
+	if err != nil {
+		return nil, err
+	}
+	var release Release
+	if err := resp.JSON(&release); err != nil {
+		return nil, fmt.Errorf("selfupdate: decoding release: %w", err)
+	}
+	version, err := ParseVersion(release.Version)
+	if err != nil {
+		return nil, err
+	}
+	if version.Compare(u.current) <= 0 || (len(version.Prerelease) > 0 && !u.prerelease) {
+		return nil, nil
+	}
+	return &release, nil
+}
+
+// Update checks for a newer release and applies it. It returns the applied
+// release, nil when the binary is up to date. The new binary runs after the
+// process restarts.
+func (u *Updater) Update(ctx context.Context) (*Release, error) {
+	release, err := u.Check(ctx)
+	if err != nil || release == nil {
+		return nil, err
+	}
+	return release, u.Apply(ctx, release)
+}
+
+// Apply downloads and verifies the asset of the platform and replaces the
+// binary with it. The previous binary is kept until Confirm.
+func (u *Updater) Apply(ctx context.Context, release *Release) error {
+	var asset *Asset
+	for i := range release.Assets {
+		if u.match(release.Assets[i].Name) {
+			asset = &release.Assets[i]
+			break
+		}
+	}
+	if asset == nil {
+		return ErrNoAsset
+	}
+	resp, err := u.client.NewRequest(gohttp.GET, asset.URL).WithContext(ctx).Send()
+	if err != nil {
+		return err
+	}
+	if err := u.verify(asset, resp.Body); err != nil {
+		return err
+	}
+	if err := u.replace(resp.Body); err != nil {
+		return err
+	}
+	u.logger.Info("binary updated", "from", u.current.String(), "to", release.Version, "asset", asset.Name)
+	return nil
+}
+
+func (u *Updater) verify(asset *Asset, data []byte) error {
+	want, err := hex.DecodeString(asset.SHA256)
+	sum := sha256.Sum256(data)
+	if err != nil || !bytes.Equal(want, sum[:]) {
+		return fmt.Errorf("%w: %s", ErrChecksum, asset.Name)
+	}
+	if u.publicKey == nil {
+		return nil
+	}
+	signature, err := base64.StdEncoding.DecodeString(asset.Signature)
+	if err != nil || !ed25519.Verify(u.publicKey, data, signature) {
+		return fmt.Errorf("%w: %s", ErrSignature, asset.Name)
+	}
+	return nil
+}
+
+func (u *Updater) backup() string {
+	return u.executable + ".old"
+}
+
+// replace writes data next to the binary and renames it over the binary, so
+// the binary is either the old or the new one
+func (u *Updater) replace(data []byte) error {
+	info, err := os.Stat(u.executable)
+	if err != nil {
+		return err
+	}
+	dir, name := filepath.Split(u.executable)
+	tmp, err := os.CreateTemp(dir, "."+name+".new")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err != nil {
+		return err
+	}
+	// a running binary can be renamed but not always overwritten
+	_ = os.Remove(u.backup())
+	if err := os.Rename(u.executable, u.backup()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), u.executable); err != nil {
+		_ = os.Rename(u.backup(), u.executable)
+		return err
+	}
+	return nil
+}
+
+// Pending reports whether the binary was updated and not confirmed yet
+func (u *Updater) Pending() bool {
+	_, err := os.Stat(u.backup())
+	return err == nil
+}
+
+// Confirm drops the previous binary once the new one works
+func (u *Updater) Confirm() error {
+	if err := os.Remove(u.backup()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Rollback restores the previous binary, it runs after the next restart
+func (u *Updater) Rollback() error {
+	if err := os.Rename(u.backup(), u.executable); err != nil {
+		return fmt.Errorf("selfupdate: rollback: %w", err)
+	}
+	u.logger.Warn("binary rolled back", "executable", u.executable)
+	return nil
+}
+
+// Verify is meant to run early after a restart. With a pending update it
+// confirms the binary when healthy returns nil, otherwise it rolls back and
+// returns ErrRolledBack; the caller should then exit so a supervisor starts
+// the previous binary. Without a pending update it does nothing.
+func (u *Updater) Verify(ctx context.Context, healthy func(ctx context.Context) error) error {
+	if !u.Pending() {
+		return nil
+	}
+	err := healthy(ctx)
+	if err == nil {
+		return u.Confirm()
+	}
+	if rollbackErr := u.Rollback(); rollbackErr != nil {
+		return rollbackErr
+	}
+	return fmt.Errorf("%w: %v", ErrRolledBack, err)
+}
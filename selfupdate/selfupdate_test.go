@@ -0,0 +1,128 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3+build.5", 0},
+		{"1.10.0", "1.9.9", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.beta", "1.0.0-alpha.1", 1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+	}
+	for _, tt := range tests {
+		a, errA := ParseVersion(tt.a)
+		b, errB := ParseVersion(tt.b)
+		if errA != nil || errB != nil {
+			t.Fatalf("ParseVersion() error = %v %v", errA, errB)
+		}
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("Compare(%v, %v) got = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+	for _, s := range []string{"1.2", "1.02.3", "1.2.3-", "x.y.z"} {
+		if _, err := ParseVersion(s); err == nil {
+			t.Errorf("ParseVersion(%q) error = nil", s)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	public, private, _ := ed25519.GenerateKey(nil)
+	release := Release{Version: "1.3.0", Assets: []Asset{
+		{Name: "app_windows_amd64.exe", URL: "/windows"},
+		{Name: "app_linux_amd64", URL: "/linux", SHA256: hex.EncodeToString(sum[:]), Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(private, binary))},
+	}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(release)
+	})
+	mux.HandleFunc("/linux", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(binary)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	release.Assets[1].URL = server.URL + "/linux"
+
+	exe := filepath.Join(t.TempDir(), "app")
+	_ = os.WriteFile(exe, []byte("old binary"), 0o755)
+	newUpdater := func(current string, opts ...Option) *Updater {
+		u, err := New(server.URL+"/latest", current, append([]Option{WithExecutable(exe), WithAssetMatcher(platformMatcher("linux", "amd64"))}, opts...)...)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return u
+	}
+	ctx := context.Background()
+
+	if got, err := newUpdater("1.3.0").Update(ctx); got != nil || err != nil {
+		t.Errorf("Update() up to date got = %v %v, want nil", got, err)
+	}
+	_, other, _ := ed25519.GenerateKey(nil)
+	if _, err := newUpdater("1.2.0", WithPublicKey(other.Public().(ed25519.PublicKey))).Update(ctx); !errors.Is(err, ErrSignature) {
+		t.Errorf("Update() wrong key error got = %v, want %v", err, ErrSignature)
+	}
+
+	u := newUpdater("1.2.0", WithPublicKey(public))
+	if got, err := u.Update(ctx); err != nil || got.Version != "1.3.0" {
+		t.Fatalf("Update() got = %v %v", got, err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new binary" {
+		t.Errorf("binary got = %q, want new binary", data)
+	}
+	if info, _ := os.Stat(exe); info.Mode().Perm() != 0o755 {
+		t.Errorf("binary mode got = %v, want 0755", info.Mode())
+	}
+	if !u.Pending() {
+		t.Errorf("Pending() got = false after Update")
+	}
+
+	// the restarted binary is not healthy
+	if err := u.Verify(ctx, func(ctx context.Context) error { return errors.New("no config") }); !errors.Is(err, ErrRolledBack) {
+		t.Errorf("Verify() error got = %v, want %v", err, ErrRolledBack)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old binary" || u.Pending() {
+		t.Errorf("binary after rollback got = %q, pending %v", data, u.Pending())
+	}
+
+	if _, err := u.Update(ctx); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := u.Verify(ctx, func(ctx context.Context) error { return nil }); err != nil || u.Pending() {
+		t.Errorf("Verify() healthy got = %v, pending %v", err, u.Pending())
+	}
+
+	release.Assets[1].SHA256 = hex.EncodeToString(make([]byte, 32))
+	if _, err := newUpdater("1.2.0").Update(ctx); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Update() bad checksum error got = %v, want %v", err, ErrChecksum)
+	}
+	release.Version = "1.4.0-rc.1"
+	if got, err := newUpdater("1.3.0").Check(ctx); got != nil || err != nil {
+		t.Errorf("Check() prerelease got = %v %v, want nil", got, err)
+	}
+	if _, err := newUpdater("1.3.0", WithAssetMatcher(platformMatcher("darwin", "arm64")), WithPrerelease()).Update(ctx); !errors.Is(err, ErrNoAsset) {
+		t.Errorf("Update() other platform error got = %v, want %v", err, ErrNoAsset)
+	}
+}
@@ -0,0 +1,158 @@
+// Package slicex has generic helpers for slices. None of them changes its
+// input, results are new slices unless stated otherwise.
+package slicex
+
+import "math/rand"
+
+// Map returns fn applied to every element of s
+func Map[T any, U any](s []T, fn func(T) U) []U {
+	if s == nil {
+		return nil
+	}
+	out := make([]U, len(s))
+	for i, v := range s {
+		out[i] = fn(v)
+	}
+	return out
+}
+
+// Filter returns the elements of s that keep returns true for
+func Filter[T any](s []T, keep func(T) bool) []T {
+	var out []T
+	for _, v := range s {
+		if keep(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Reduce folds s into a value, starting with init
+func Reduce[T any, A any](s []T, init A, fn func(acc A, v T) A) A {
+	acc := init
+	for _, v := range s {
+		acc = fn(acc, v)
+	}
+	return acc
+}
+
+// GroupBy groups the elements of s by key, keeping their order in each group
+func GroupBy[T any, K comparable](s []T, key func(T) K) map[K][]T {
+	out := make(map[K][]T)
+	for _, v := range s {
+		k := key(v)
+		out[k] = append(out[k], v)
+	}
+	return out
+}
+
+// IndexBy maps the elements of s by key, the last element wins for a key
+// shared by several
+func IndexBy[T any, K comparable](s []T, key func(T) K) map[K]T {
+	out := make(map[K]T, len(s))
+	for _, v := range s {
+		out[key(v)] = v
+	}
+	return out
+}
+
+// Chunk splits s into slices of size elements, the last may be shorter. The
+// chunks share the array of s. A size below 1 returns nil.
+func Chunk[T any](s []T, size int) [][]T {
+	if size < 1 || len(s) == 0 {
+		return nil
+	}
+	out := make([][]T, 0, (len(s)+size-1)/size)
+	for size < len(s) {
+		out = append(out, s[:size:size])
+		s = s[size:]
+	}
+	return append(out, s)
+}
+
+// Unique returns s without repeated elements, keeping the first of each
+func Unique[T comparable](s []T) []T {
+	seen := make(map[T]struct{}, len(s))
+	var out []T
+	for _, v := range s {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Difference returns the elements of a that are not in b, in the order of a
+func Difference[T comparable](a []T, b []T) []T {
+	exclude := set(b)
+	var out []T
+	for _, v := range a {
+		if _, ok := exclude[v]; !ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Intersect returns the elements both in a and b once, in the order of a
+func Intersect[T comparable](a []T, b []T) []T {
+	include := set(b)
+	var out []T
+	for _, v := range a {
+		if _, ok := include[v]; ok {
+			out = append(out, v)
+			// repeated elements of a are only taken once
+			delete(include, v)
+		}
+	}
+	return out
+}
+
+func set[T comparable](s []T) map[T]struct{} {
+	m := make(map[T]struct{}, len(s))
+	for _, v := range s {
+		m[v] = struct{}{}
+	}
+	return m
+}
+
+// Contains reports whether v is in s
+func Contains[T comparable](s []T, v T) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Shuffle returns the elements of s in random order, drawn from r or from
+// the math/rand source when r is nil
+func Shuffle[T any](s []T, r *rand.Rand) []T {
+	out := append([]T(nil), s...)
+	swap := func(i, j int) { out[i], out[j] = out[j], out[i] }
+	if r != nil {
+		r.Shuffle(len(out), swap)
+	} else {
+		rand.Shuffle(len(out), swap)
+	}
+	return out
+}
+
+// Paginate returns page number page, counting from 1, of perPage elements.
+// Pages past the end are empty.
+func Paginate[T any](s []T, page int, perPage int) []T {
+	if page < 1 || perPage < 1 {
+		return nil
+	}
+	start := (page - 1) * perPage
+	if start >= len(s) || start < 0 {
+		return nil
+	}
+	end := start + perPage
+	if end > len(s) || end < 0 {
+		end = len(s)
+	}
+	return s[start:end:end]
+}
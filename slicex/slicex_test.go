@@ -0,0 +1,120 @@
+package slicex
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+type user struct {
+	ID   int
+	Team string
+}
+
+func TestTransform(t *testing.T) {
+	if got := Map([]int{1, 2, 3}, strconv.Itoa); !reflect.DeepEqual(got, []string{"1", "2", "3"}) {
+		t.Errorf("Map() got = %v", got)
+	}
+	if got := Map[int, int](nil, func(v int) int { return v }); got != nil {
+		t.Errorf("Map() nil got = %v, want nil", got)
+	}
+	if got := Filter([]int{1, 2, 3, 4}, func(v int) bool { return v%2 == 0 }); !reflect.DeepEqual(got, []int{2, 4}) {
+		t.Errorf("Filter() got = %v", got)
+	}
+	if got := Reduce([]int{1, 2, 3}, "", func(acc string, v int) string { return acc + strconv.Itoa(v) }); got != "123" {
+		t.Errorf("Reduce() got = %v", got)
+	}
+
+	users := []user{{1, "a"}, {2, "b"}, {3, "a"}}
+	want := map[string][]user{"a": {{1, "a"}, {3, "a"}}, "b": {{2, "b"}}}
+	if got := GroupBy(users, func(u user) string { return u.Team }); !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupBy() got = %v, want %v", got, want)
+	}
+	byTeam := IndexBy(users, func(u user) string { return u.Team })
+	if byTeam["a"].ID != 3 || len(byTeam) != 2 {
+		t.Errorf("IndexBy() got = %v, want the last user per team", byTeam)
+	}
+}
+
+func TestChunkAndPaginate(t *testing.T) {
+	s := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		size int
+		want [][]int
+	}{
+		{2, [][]int{{1, 2}, {3, 4}, {5}}},
+		{5, [][]int{{1, 2, 3, 4, 5}}},
+		{9, [][]int{{1, 2, 3, 4, 5}}},
+		{0, nil},
+	}
+	for _, tt := range tests {
+		if got := Chunk(s, tt.size); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Chunk(%v) got = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+	chunks := Chunk(s, 2)
+	chunks[0] = append(chunks[0], 99)
+	if s[2] != 3 {
+		t.Errorf("Chunk() append got = %v, want chunks not to overwrite each other", s)
+	}
+
+	pages := []struct {
+		page, perPage int
+		want          []int
+	}{
+		{1, 2, []int{1, 2}},
+		{3, 2, []int{5}},
+		{4, 2, nil},
+		{0, 2, nil},
+		{1, 0, nil},
+	}
+	for _, tt := range pages {
+		if got := Paginate(s, tt.page, tt.perPage); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Paginate(%v, %v) got = %v, want %v", tt.page, tt.perPage, got, tt.want)
+		}
+	}
+}
+
+func TestSets(t *testing.T) {
+	a := []string{"x", "y", "x", "z"}
+	b := []string{"z", "x", "w"}
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"Unique", Unique(a), []string{"x", "y", "z"}},
+		{"Difference", Difference(a, b), []string{"y"}},
+		{"Intersect", Intersect(a, b), []string{"x", "z"}},
+		{"Intersect empty", Intersect(a, nil), nil},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%v() got = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if !Contains(a, "z") || Contains(a, "w") {
+		t.Errorf("Contains() got wrong result")
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	s := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	got := Shuffle(s, rand.New(rand.NewSource(1)))
+	if reflect.DeepEqual(got, s) {
+		t.Errorf("Shuffle() got = %v, want another order", got)
+	}
+	if again := Shuffle(s, rand.New(rand.NewSource(1))); !reflect.DeepEqual(again, got) {
+		t.Errorf("Shuffle() same seed got = %v, want %v", again, got)
+	}
+	sorted := append([]int(nil), got...)
+	sort.Ints(sorted)
+	if !reflect.DeepEqual(sorted, s) || s[0] != 1 || s[7] != 8 {
+		t.Errorf("Shuffle() got = %v from %v, want a permutation and the input unchanged", got, s)
+	}
+	if got := Shuffle(s, nil); len(got) != len(s) {
+		t.Errorf("Shuffle() nil source got = %v", got)
+	}
+}
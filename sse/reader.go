@@ -0,0 +1,104 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reader reads the events of a stream, e.g. the body of a response from a
+// Stream. Comments such as heartbeats are skipped.
+type Reader struct {
+	scanner *bufio.Scanner
+	lastID  string
+}
+
+// NewReader reads events from r, lines may be up to 1MB long
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	scanner.Split(scanLines)
+	return &Reader{scanner: scanner}
+}
+
+// Next returns the next event, with Data as a string of its data lines
+// joined with newlines. It returns io.EOF at the end of the stream, an event
+// cut off by the end is dropped as browsers do.
+func (r *Reader) Next() (Event, error) {
+	var e Event
+	var data []string
+	var hasData bool
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if !hasData && e.Event == "" && e.Retry == 0 {
+				// an empty block or one holding only comments or an id
+				e = Event{}
+				continue
+			}
+			e.ID = r.lastID
+			if hasData {
+				e.Data = strings.Join(data, "\n")
+			}
+			return e, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch name {
+		case "id":
+			// ids with NUL are ignored by the spec
+			if !strings.ContainsRune(value, 0) {
+				r.lastID = value
+			}
+		case "event":
+			e.Event = value
+		case "retry":
+			if ms, err := strconv.ParseInt(value, 10, 64); err == nil && ms >= 0 {
+				e.Retry = time.Duration(ms) * time.Millisecond
+			}
+		case "data":
+			data = append(data, value)
+			hasData = true
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+// LastEventID is the last id the stream sent, to send back as the
+// Last-Event-ID header when reconnecting
+func (r *Reader) LastEventID() string {
+	return r.lastID
+}
+
+// scanLines splits on \n, \r\n and a lone \r as the event stream format does
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// a \r at the end of data may be followed by \n in the next read
+		if i+1 == len(data) && !atEOF {
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,304 @@
+// Package sse streams server-sent events to a client. A Stream buffers the
+// events of each client and writes them from its own goroutine, so a slow
+// client doesn't hold up the code broadcasting to all of them.
+//
+//	s, err := sse.NewStream(w, sse.WithRetry(5*time.Second), sse.WithContext(r.Context()))
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusInternalServerError)
+//		return
+//	}
+//	defer s.Close()
+//	for {
+//		select {
+//		case update := <-updates:
+//			if err := s.Send(sse.Event{Event: "price", Data: update}); err != nil {
+//				return
+//			}
+//		case <-s.Done():
+//			// the client went away
+//			return
+//		}
+//	}
+//
+// A Reader reads the events of a stream on the client side.
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+var (
+	// ErrNotFlusher is returned for a ResponseWriter that can't flush
+	ErrNotFlusher = errors.New("sse: response writer can't flush")
+	// ErrClosed is returned by Send after Close or once writing failed
+	ErrClosed = errors.New("sse: stream closed")
+	// ErrBufferFull is returned by Send while the buffer of a slow client is full
+	ErrBufferFull = errors.New("sse: buffer full")
+	// ErrClientGone ends a stream whose WithContext context is done, the
+	// client disconnected
+	ErrClientGone = errors.New("sse: client gone")
+)
+
+// Event is one server-sent event
+type Event struct {
+	// ID is sent back by browsers as Last-Event-ID when they reconnect
+	ID string
+	// Event names the event type, browsers dispatch it to listeners of that name
+	Event string
+	// Data is sent as is for string and []byte, other values as JSON
+	Data any
+	// Retry tells the client how long to wait before reconnecting
+	Retry time.Duration
+}
+
+type config struct {
+	heartbeat time.Duration
+	retry     time.Duration
+	buffer    int
+	ctx       context.Context
+}
+
+type Option = opt.Option[config]
+
+// WithHeartbeat sends a comment line every d while no event is sent, keeping
+// proxies from closing an idle connection, 15s by default. 0 disables it.
+func WithHeartbeat(d time.Duration) Option {
+	return func(c *config) {
+		c.heartbeat = d
+	}
+}
+
+// WithRetry sends the reconnection delay for the client when the stream starts
+func WithRetry(d time.Duration) Option {
+	return func(c *config) {
+		c.retry = d
+	}
+}
+
+// WithBuffer is how many events wait for a slow client before Send fails with
+// ErrBufferFull, 64 by default
+func WithBuffer(n int) Option {
+	return func(c *config) {
+		c.buffer = n
+	}
+}
+
+// WithContext ends the stream with ErrClientGone once ctx is done. Pass the
+// request context to notice clients disconnecting without waiting for a
+// write to fail.
+func WithContext(ctx context.Context) Option {
+	return func(c *config) {
+		c.ctx = ctx
+	}
+}
+
+// LastEventID returns the ID of the last event a reconnecting client got,
+// "" on its first connection
+func LastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	// EventSource polyfills can't set headers on every browser
+	return r.URL.Query().Get("lastEventId")
+}
+
+// Stream writes events to one client
+type Stream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	cfg     config
+	events  chan []byte
+
+	mu     sync.Mutex
+	closed bool
+	err    error
+	done   chan struct{}
+	exited chan struct{}
+}
+
+// NewStream writes the event stream headers and starts writing events to w.
+// Close the Stream before the handler returns.
+func NewStream(w http.ResponseWriter, opts ...Option) (*Stream, error) {
+	cfg := config{heartbeat: 15 * time.Second, buffer: 64, ctx: context.Background()}
+	if err := opt.Build(&cfg, opts); err != nil {
+		return nil, err
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrNotFlusher
+	}
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	// tells nginx not to buffer the stream
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	s := &Stream{
+		w:       w,
+		flusher: flusher,
+		cfg:     cfg,
+		events:  make(chan []byte, cfg.buffer),
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	if cfg.retry > 0 {
+		data, _ := encode(Event{Retry: cfg.retry})
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	flusher.Flush()
+	go s.run()
+	return s, nil
+}
+
+// Send queues e for the client. It fails with ErrBufferFull while the client
+// is behind and with ErrClosed, or the write error, once the client is gone.
+func (s *Stream) Send(e Event) error {
+	data, err := encode(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return s.closedErr()
+	}
+	select {
+	case s.events <- data:
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+// Done is closed when the stream is closed, writing to the client failed or
+// the client disconnected
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error that ended the stream, ErrClientGone for a client
+// that disconnected, nil while it runs or after Close
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close writes the queued events and stops the stream
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+	<-s.exited
+	return s.Err()
+}
+
+func (s *Stream) closedErr() error {
+	if s.err != nil {
+		return s.err
+	}
+	return ErrClosed
+}
+
+func (s *Stream) run() {
+	defer close(s.exited)
+	var heartbeat <-chan time.Time
+	if s.cfg.heartbeat > 0 {
+		ticker := time.NewTicker(s.cfg.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		var err error
+		select {
+		case data, ok := <-s.events:
+			if !ok {
+				close(s.done)
+				return
+			}
+			_, err = s.w.Write(data)
+		case <-heartbeat:
+			_, err = io.WriteString(s.w, ":\n\n")
+		case <-s.cfg.ctx.Done():
+			err = ErrClientGone
+		}
+		if err == nil {
+			s.flusher.Flush()
+			continue
+		}
+		s.fail(err)
+		return
+	}
+}
+
+// fail ends the stream after a write error or a disconnect, queued events
+// are dropped
+func (s *Stream) fail(err error) {
+	s.mu.Lock()
+	s.err = err
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+	close(s.done)
+}
+
+// encode formats e in the event stream format
+func encode(e Event) ([]byte, error) {
+	var b strings.Builder
+	if e.ID != "" {
+		writeField(&b, "id", e.ID)
+	}
+	if e.Event != "" {
+		writeField(&b, "event", e.Event)
+	}
+	if e.Retry > 0 {
+		writeField(&b, "retry", strconv.FormatInt(e.Retry.Milliseconds(), 10))
+	}
+	if e.Data != nil {
+		var data string
+		switch v := e.Data.(type) {
+		case string:
+			data = v
+		case []byte:
+			data = string(v)
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			data = string(encoded)
+		}
+		// every line of the data gets a field, the client joins them with newlines
+		data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+		for _, line := range strings.Split(data, "\n") {
+			writeField(&b, "data", line)
+		}
+	}
+	b.WriteByte('\n')
+	return []byte(b.String()), nil
+}
+
+func writeField(b *strings.Builder, name string, value string) {
+	b.WriteString(name)
+	b.WriteString(": ")
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
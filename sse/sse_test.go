@@ -0,0 +1,199 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{"data", Event{Data: "hello"}, "data: hello\n\n"},
+		{"all fields", Event{ID: "7", Event: "price", Data: "1.5", Retry: 3 * time.Second}, "id: 7\nevent: price\nretry: 3000\ndata: 1.5\n\n"},
+		{"multi line", Event{Data: "a\r\nb\nc"}, "data: a\ndata: b\ndata: c\n\n"},
+		{"json", Event{Data: map[string]int{"n": 1}}, "data: {\"n\":1}\n\n"},
+		{"bytes", Event{Data: []byte("raw")}, "data: raw\n\n"},
+		{"empty", Event{Data: ""}, "data: \n\n"},
+	}
+	for _, tt := range tests {
+		got, err := encode(tt.event)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("encode() %v got = %q %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := encode(Event{Data: func() {}}); err == nil {
+		t.Errorf("encode() unmarshalable data error = nil")
+	}
+}
+
+func TestStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := NewStream(w, WithRetry(2*time.Second), WithHeartbeat(10*time.Millisecond))
+		if err != nil {
+			t.Errorf("NewStream() error = %v", err)
+			return
+		}
+		defer s.Close()
+		_ = s.Send(Event{Event: "a", Data: "1"})
+		time.Sleep(30 * time.Millisecond)
+		_ = s.Send(Event{Event: "b", Data: "2"})
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type got = %v", got)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	body := strings.Join(lines, "\n")
+	if !strings.HasPrefix(body, "retry: 2000\n\nevent: a\ndata: 1\n") || !strings.HasSuffix(body, "event: b\ndata: 2\n") {
+		t.Errorf("body got = %q", body)
+	}
+	if !strings.Contains(body, "\n:\n") {
+		t.Errorf("body got = %q, want heartbeats", body)
+	}
+}
+
+// blockingWriter stalls writes until release is closed
+type blockingWriter struct {
+	*httptest.ResponseRecorder
+	release chan struct{}
+	fail    bool
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	if w.fail {
+		return 0, errors.New("broken pipe")
+	}
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestSlowClient(t *testing.T) {
+	w := &blockingWriter{ResponseRecorder: httptest.NewRecorder(), release: make(chan struct{})}
+	s, err := NewStream(w, WithBuffer(2), WithHeartbeat(0))
+	if err != nil {
+		t.Fatalf("NewStream() error = %v", err)
+	}
+	// one event is being written, two wait in the buffer
+	var sent int
+	for i := 0; i < 10; i++ {
+		if err := s.Send(Event{Data: "x"}); err == nil {
+			sent++
+		} else if !errors.Is(err, ErrBufferFull) {
+			t.Fatalf("Send() error got = %v, want %v", err, ErrBufferFull)
+		}
+	}
+	if sent > 3 {
+		t.Errorf("Send() accepted %v events, want at most 3", sent)
+	}
+	close(w.release)
+	if err := s.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if got := strings.Count(w.Body.String(), "data: x"); got != sent {
+		t.Errorf("events written got = %v, want %v", got, sent)
+	}
+	if err := s.Send(Event{Data: "x"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Send() after Close error got = %v, want %v", err, ErrClosed)
+	}
+
+	broken := &blockingWriter{ResponseRecorder: httptest.NewRecorder(), release: make(chan struct{}), fail: true}
+	close(broken.release)
+	s, _ = NewStream(broken, WithHeartbeat(0))
+	_ = s.Send(Event{Data: "x"})
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done() not closed after a failed write")
+	}
+	if err := s.Send(Event{Data: "x"}); err == nil || errors.Is(err, ErrBufferFull) {
+		t.Errorf("Send() after failed write error got = %v, want the write error", err)
+	}
+	if _, err := NewStream(struct{ http.ResponseWriter }{httptest.NewRecorder()}); !errors.Is(err, ErrNotFlusher) {
+		t.Errorf("NewStream() error got = %v, want %v", err, ErrNotFlusher)
+	}
+}
+
+func TestClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := NewStream(httptest.NewRecorder(), WithContext(ctx), WithHeartbeat(0))
+	if err != nil {
+		t.Fatalf("NewStream() error = %v", err)
+	}
+	cancel()
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done() not closed after the client left")
+	}
+	if err := s.Send(Event{Data: "x"}); !errors.Is(err, ErrClientGone) {
+		t.Errorf("Send() error got = %v, want %v", err, ErrClientGone)
+	}
+	if err := s.Close(); !errors.Is(err, ErrClientGone) {
+		t.Errorf("Close() error got = %v, want %v", err, ErrClientGone)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/events?lastEventId=4", nil)
+	if got := LastEventID(req); got != "4" {
+		t.Errorf("LastEventID() query got = %q", got)
+	}
+	req.Header.Set("Last-Event-ID", "5")
+	if got := LastEventID(req); got != "5" {
+		t.Errorf("LastEventID() header got = %q", got)
+	}
+}
+
+func TestReader(t *testing.T) {
+	var stream strings.Builder
+	for _, e := range []Event{
+		{Retry: 2 * time.Second},
+		{ID: "1", Event: "price", Data: "1.5"},
+		{Data: "line 1\nline 2"},
+		{ID: "3", Data: map[string]int{"n": 3}},
+	} {
+		data, _ := encode(e)
+		stream.Write(data)
+	}
+	// heartbeats, a lone \r line ending and an event cut off by the end
+	stream.WriteString(":\n\nevent: raw\rdata:no space\r\n\r\ndata: lost")
+
+	r := NewReader(strings.NewReader(stream.String()))
+	want := []Event{
+		{Retry: 2 * time.Second},
+		{ID: "1", Event: "price", Data: "1.5"},
+		{ID: "1", Data: "line 1\nline 2"},
+		{ID: "3", Data: `{"n":3}`},
+		{ID: "3", Event: "raw", Data: "no space"},
+	}
+	for i, w := range want {
+		got, err := r.Next()
+		if err != nil || got != w {
+			t.Errorf("Next() %v got = %+v %v, want %+v", i, got, err, w)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next() at the end error got = %v, want EOF", err)
+	}
+	if got := r.LastEventID(); got != "3" {
+		t.Errorf("LastEventID() got = %q", got)
+	}
+}
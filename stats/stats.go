@@ -0,0 +1,130 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Stats is implemented by components exposing internal counters and gauges.
+// Keys are snake_case metric names such as "requests_total".
+type Stats interface {
+	Stats() map[string]float64
+}
+
+// Func adapts a function to Stats
+type Func func() map[string]float64
+
+func (f Func) Stats() map[string]float64 {
+	return f()
+}
+
+// Collector snapshots all registered components at once
+type Collector struct {
+	mu      sync.RWMutex
+	sources map[string]Stats
+}
+
+func NewCollector() *Collector {
+	return &Collector{sources: make(map[string]Stats)}
+}
+
+// Default is the collector used by the package level functions
+var Default = NewCollector()
+
+// Register adds a component to the Default collector
+func Register(name string, s Stats) error {
+	return Default.Register(name, s)
+}
+
+// Unregister removes a component from the Default collector
+func Unregister(name string) {
+	Default.Unregister(name)
+}
+
+// Register adds a component under name, names must be unique
+func (c *Collector) Register(name string, s Stats) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.sources[name]; ok {
+		return fmt.Errorf("stats: %q already registered", name)
+	}
+	c.sources[name] = s
+	return nil
+}
+
+func (c *Collector) Unregister(name string) {
+	c.mu.Lock()
+	delete(c.sources, name)
+	c.mu.Unlock()
+}
+
+// Snapshot returns the current values of every component keyed by component name
+func (c *Collector) Snapshot() map[string]map[string]float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	snapshot := make(map[string]map[string]float64, len(c.sources))
+	for name, s := range c.sources {
+		snapshot[name] = s.Stats()
+	}
+	return snapshot
+}
+
+// WriteJSON writes the snapshot as one JSON document
+func (c *Collector) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c.Snapshot())
+}
+
+// Handler serves the snapshot as JSON
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = c.WriteJSON(w)
+	})
+}
+
+// Prometheus exposes the snapshot as gauges named <namespace>_<component>_<key>,
+// register the result on a prometheus.Registerer.
+func (c *Collector) Prometheus(namespace string) prometheus.Collector {
+	return &promCollector{collector: c, namespace: namespace}
+}
+
+type promCollector struct {
+	collector *Collector
+	namespace string
+}
+
+// Describe sends nothing, the metric set depends on what is registered, which makes this an unchecked collector
+func (p *promCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (p *promCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := p.collector.Snapshot()
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for key, value := range snapshot[name] {
+			fqName := prometheus.BuildFQName(sanitize(p.namespace), sanitize(name), sanitize(key))
+			desc := prometheus.NewDesc(fqName, "gotool internal stat "+name+"."+key, nil, nil)
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+		}
+	}
+}
+
+// sanitize replaces characters not allowed in metric names
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,54 @@
+package stats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	if err := c.Register("http", Func(func() map[string]float64 {
+		return map[string]float64{"requests_total": 3, "in_flight": 1}
+	})); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := c.Register("http", Func(nil)); err == nil {
+		t.Errorf("Register() duplicate error = nil, want error")
+	}
+	if err := c.Register("cache.users", Func(func() map[string]float64 {
+		return map[string]float64{"hits": 10}
+	})); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if got, want := strings.TrimSpace(buf.String()), `{"cache.users":{"hits":10},"http":{"in_flight":1,"requests_total":3}}`; got != want {
+		t.Errorf("WriteJSON() got = %v, want %v", got, want)
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c.Prometheus("gotool"))
+	expected := `
+# HELP gotool_cache_users_hits gotool internal stat cache.users.hits
+# TYPE gotool_cache_users_hits gauge
+gotool_cache_users_hits 10
+# HELP gotool_http_requests_total gotool internal stat http.requests_total
+# TYPE gotool_http_requests_total gauge
+gotool_http_requests_total 3
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "gotool_cache_users_hits", "gotool_http_requests_total"); err != nil {
+		t.Errorf("Prometheus() %v", err)
+	}
+
+	c.Unregister("http")
+	if _, ok := c.Snapshot()["http"]; ok {
+		t.Errorf("Snapshot() still has http after Unregister")
+	}
+}
@@ -0,0 +1,73 @@
+// Package status has the helpers around HTTP status codes missing from
+// net/http: their class, whether a request failing with them may be sent
+// again, and a text for any code.
+package status
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Class is the first digit of a status code
+type Class int
+
+const (
+	Unknown Class = iota
+	Informational
+	Success
+	Redirection
+	ClientError
+	ServerError
+)
+
+var classNames = [...]string{"Unknown", "Informational", "Success", "Redirection", "Client Error", "Server Error"}
+
+func (c Class) String() string {
+	if c < 0 || int(c) >= len(classNames) {
+		return "Class(" + strconv.Itoa(int(c)) + ")"
+	}
+	return classNames[c]
+}
+
+// ClassOf returns the class of code, Unknown outside 100-599
+func ClassOf(code int) Class {
+	if code < 100 || code > 599 {
+		return Unknown
+	}
+	return Class(code / 100)
+}
+
+// IsSuccess reports whether code is a 2xx
+func IsSuccess(code int) bool {
+	return ClassOf(code) == Success
+}
+
+// IsRedirect reports whether code is a 3xx
+func IsRedirect(code int) bool {
+	return ClassOf(code) == Redirection
+}
+
+// IsError reports whether code is a 4xx or a 5xx
+func IsError(code int) bool {
+	class := ClassOf(code)
+	return class == ClientError || class == ServerError
+}
+
+// IsRetryable reports whether a request failing with code may succeed when
+// sent again: 429 and the 5xx but 501, which won't change without a new
+// server
+func IsRetryable(code int) bool {
+	return code == http.StatusTooManyRequests || ClassOf(code) == ServerError && code != http.StatusNotImplemented
+}
+
+// Text returns the text of code like http.StatusText, or of its class for
+// unregistered codes, e.g. "Client Error" for 499, never an empty string
+func Text(code int) string {
+	if text := http.StatusText(code); text != "" {
+		return text
+	}
+	if class := ClassOf(code); class != Unknown {
+		return class.String()
+	}
+	return "Status " + strconv.Itoa(code)
+}
@@ -0,0 +1,76 @@
+package status
+
+import "testing"
+
+func TestClassOf(t *testing.T) {
+	tests := []struct {
+		code int
+		want Class
+	}{
+		{99, Unknown},
+		{100, Informational},
+		{204, Success},
+		{308, Redirection},
+		{404, ClientError},
+		{599, ServerError},
+		{600, Unknown},
+	}
+	for _, tt := range tests {
+		if got := ClassOf(tt.code); got != tt.want {
+			t.Errorf("ClassOf(%d) got = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+	if got := Class(9).String(); got != "Class(9)" {
+		t.Errorf("String() got = %v, want %v", got, "Class(9)")
+	}
+}
+
+func TestIs(t *testing.T) {
+	if !IsSuccess(201) || IsSuccess(301) {
+		t.Error("IsSuccess() got wrong result")
+	}
+	if !IsRedirect(302) || IsRedirect(200) {
+		t.Error("IsRedirect() got wrong result")
+	}
+	if !IsError(400) || !IsError(503) || IsError(302) {
+		t.Error("IsError() got wrong result")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		code int
+		want bool
+	}{
+		{200, false},
+		{400, false},
+		{404, false},
+		{429, true},
+		{500, true},
+		{501, false},
+		{503, true},
+		{599, true},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.code); got != tt.want {
+			t.Errorf("IsRetryable(%d) got = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{404, "Not Found"},
+		{499, "Client Error"},
+		{599, "Server Error"},
+		{42, "Status 42"},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.code); got != tt.want {
+			t.Errorf("Text(%d) got = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Package stringx has the string helpers missing from strings. Lengths and
+// positions count runes, not bytes, so multi-byte text is never cut inside a
+// character.
+package stringx
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Character sets for RandomString
+const (
+	Digits       = "0123456789"
+	LowerLetters = "abcdefghijklmnopqrstuvwxyz"
+	UpperLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Letters      = LowerLetters + UpperLetters
+	AlphaNumeric = Letters + Digits
+	HexDigits    = Digits + "abcdef"
+)
+
+// ErrEmptyCharset is returned by RandomString for an empty charset
+var ErrEmptyCharset = errors.New("stringx: empty charset")
+
+// IsBlank reports whether s is empty or white space only
+func IsBlank(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsNotBlank reports whether s has a character other than white space
+func IsNotBlank(s string) bool {
+	return !IsBlank(s)
+}
+
+// Truncate cuts s to at most n runes
+func Truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos]
+		}
+		i++
+	}
+	return s
+}
+
+// Ellipsis cuts s to at most n runes, ending a cut string with "…"
+func Ellipsis(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return Truncate(s, n-1) + "…"
+}
+
+// words splits s into words at separators and at case changes, e.g.
+// "parseHTTPResponse_v2" into parse, HTTP, Response, v2
+func words(s string) []string {
+	var out []string
+	runes := []rune(s)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				out = append(out, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		prev := runes[i-1]
+		// lowerUpper or ACRONYMWord
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+			unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			out = append(out, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start >= 0 {
+		out = append(out, string(runes[start:]))
+	}
+	return out
+}
+
+func joinLower(s string, sep string) string {
+	parts := words(s)
+	for i, w := range parts {
+		parts[i] = strings.ToLower(w)
+	}
+	return strings.Join(parts, sep)
+}
+
+// CamelToSnake converts "userID" or "HTTPServer" to "user_id" and
+// "http_server"
+func CamelToSnake(s string) string {
+	return joinLower(s, "_")
+}
+
+// ToKebab converts camel case, snake case or words to kebab case, e.g.
+// "userID", "user_id" and "User ID" to "user-id"
+func ToKebab(s string) string {
+	return joinLower(s, "-")
+}
+
+// SnakeToCamel converts "user_id" to "userId", the case of the letters after
+// the first of each part is kept
+func SnakeToCamel(s string) string {
+	var b strings.Builder
+	first := true
+	for _, part := range strings.Split(s, "_") {
+		if part == "" {
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(part)
+		if first {
+			b.WriteRune(unicode.ToLower(r))
+			first = false
+		} else {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		b.WriteString(part[size:])
+	}
+	return b.String()
+}
+
+// PadLeft prepends pad to s until it is width runes long
+func PadLeft(s string, width int, pad rune) string {
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	return strings.Repeat(string(pad), n) + s
+}
+
+// PadRight appends pad to s until it is width runes long
+func PadRight(s string, width int, pad rune) string {
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	return s + strings.Repeat(string(pad), n)
+}
+
+// Reverse reverses s by character, combining marks stay on their letter so
+// "café" written with a combining accent reverses to "éfac"
+func Reverse(s string) string {
+	runes := []rune(s)
+	out := make([]rune, 0, len(runes))
+	for end := len(runes); end > 0; {
+		start := end - 1
+		for start > 0 && unicode.Is(unicode.M, runes[start]) {
+			start--
+		}
+		out = append(out, runes[start:end]...)
+		end = start
+	}
+	return string(out)
+}
+
+// ContainsAny reports whether s contains any of substrs, unlike
+// strings.ContainsAny which looks for single characters
+func ContainsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// SubstringBetween returns the text between the first open and the close
+// following it, false when either is missing
+func SubstringBetween(s string, open string, close string) (string, bool) {
+	start := strings.Index(s, open)
+	if start < 0 {
+		return "", false
+	}
+	start += len(open)
+	end := strings.Index(s[start:], close)
+	if end < 0 {
+		return "", false
+	}
+	return s[start : start+end], true
+}
+
+// RandomString returns n runes picked uniformly from charset with
+// crypto/rand, suitable for tokens
+func RandomString(n int, charset string) (string, error) {
+	runes := []rune(charset)
+	if len(runes) == 0 {
+		return "", ErrEmptyCharset
+	}
+	// values at or above limit are dropped, they would favor the first runes
+	m := uint32(len(runes))
+	limit := math.MaxUint32 - math.MaxUint32%m
+	if n <= 0 {
+		return "", nil
+	}
+	out := make([]rune, 0, n)
+	buf := make([]byte, 4*n)
+	for len(out) < n {
+		chunk := buf[:4*(n-len(out))]
+		if _, err := rand.Read(chunk); err != nil {
+			return "", err
+		}
+		for i := 0; i < len(chunk); i += 4 {
+			v := binary.LittleEndian.Uint32(chunk[i:])
+			if v < limit {
+				out = append(out, runes[v%m])
+			}
+		}
+	}
+	return string(out), nil
+}
@@ -0,0 +1,196 @@
+package stringx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBlank(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"", true},
+		{" \t\n", true},
+		{" 　", true},
+		{" a ", false},
+	}
+	for _, tt := range tests {
+		if got := IsBlank(tt.s); got != tt.want {
+			t.Errorf("IsBlank(%q) got = %v, want %v", tt.s, got, tt.want)
+		}
+		if got := IsNotBlank(tt.s); got == tt.want {
+			t.Errorf("IsNotBlank(%q) got = %v, want %v", tt.s, got, !tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s        string
+		n        int
+		want     string
+		ellipsis string
+	}{
+		{"hello", 10, "hello", "hello"},
+		{"hello", 5, "hello", "hello"},
+		{"hello", 3, "hel", "he…"},
+		{"héllo wörld", 4, "héll", "hél…"},
+		{"日本語テキスト", 2, "日本", "日…"},
+		{"abc", 0, "", ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("Truncate(%q, %v) got = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+		if got := Ellipsis(tt.s, tt.n); got != tt.ellipsis {
+			t.Errorf("Ellipsis(%q, %v) got = %q, want %q", tt.s, tt.n, got, tt.ellipsis)
+		}
+	}
+}
+
+func TestCase(t *testing.T) {
+	tests := []struct {
+		s     string
+		snake string
+		kebab string
+	}{
+		{"userID", "user_id", "user-id"},
+		{"HTTPServer", "http_server", "http-server"},
+		{"parseHTTPResponse", "parse_http_response", "parse-http-response"},
+		{"v2Api", "v2_api", "v2-api"},
+		{"already_snake", "already_snake", "already-snake"},
+		{"User ID", "user_id", "user-id"},
+		{"ÄrgerÜber", "ärger_über", "ärger-über"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := CamelToSnake(tt.s); got != tt.snake {
+			t.Errorf("CamelToSnake(%q) got = %q, want %q", tt.s, got, tt.snake)
+		}
+		if got := ToKebab(tt.s); got != tt.kebab {
+			t.Errorf("ToKebab(%q) got = %q, want %q", tt.s, got, tt.kebab)
+		}
+	}
+
+	camel := []struct {
+		s    string
+		want string
+	}{
+		{"user_id", "userId"},
+		{"_leading__double_", "leadingDouble"},
+		{"Created_AT", "createdAT"},
+		{"über_groß", "überGroß"},
+	}
+	for _, tt := range camel {
+		if got := SnakeToCamel(tt.s); got != tt.want {
+			t.Errorf("SnakeToCamel(%q) got = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestPadAndReverse(t *testing.T) {
+	if got := PadLeft("7", 3, '0'); got != "007" {
+		t.Errorf("PadLeft() got = %q", got)
+	}
+	if got := PadRight("日本", 4, '・'); got != "日本・・" {
+		t.Errorf("PadRight() got = %q", got)
+	}
+	if got := PadLeft("long", 2, ' '); got != "long" {
+		t.Errorf("PadLeft() got = %q, want it unchanged", got)
+	}
+
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"abc", "cba"},
+		{"héllo", "olléh"},
+		{"café", "éfac"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Reverse(tt.s); got != tt.want {
+			t.Errorf("Reverse(%q) got = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	if !ContainsAny("error: disk full", "warn", "error") || ContainsAny("ok", "warn", "error") || ContainsAny("ok") {
+		t.Errorf("ContainsAny() got wrong result")
+	}
+	tests := []struct {
+		s, open, close string
+		want           string
+		ok             bool
+	}{
+		{"name=[ann] age=[41]", "[", "]", "ann", true},
+		{"<b>bold</b>", "<b>", "</b>", "bold", true},
+		{"no brackets", "[", "]", "", false},
+		{"open [only", "[", "]", "", false},
+		{"«日本»", "«", "»", "日本", true},
+	}
+	for _, tt := range tests {
+		if got, ok := SubstringBetween(tt.s, tt.open, tt.close); got != tt.want || ok != tt.ok {
+			t.Errorf("SubstringBetween(%q) got = %q %v, want %q %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRandomString(t *testing.T) {
+	s, err := RandomString(64, HexDigits)
+	if err != nil || len(s) != 64 || strings.Trim(s, HexDigits) != "" {
+		t.Errorf("RandomString() got = %q %v", s, err)
+	}
+	other, _ := RandomString(64, HexDigits)
+	if s == other {
+		t.Errorf("RandomString() got the same string twice")
+	}
+	s, _ = RandomString(10, "αβγ")
+	if utf8.RuneCountInString(s) != 10 || strings.Trim(s, "αβγ") != "" {
+		t.Errorf("RandomString() unicode charset got = %q", s)
+	}
+	if _, err := RandomString(5, ""); !errors.Is(err, ErrEmptyCharset) {
+		t.Errorf("RandomString() error got = %v, want %v", err, ErrEmptyCharset)
+	}
+
+	counts := make(map[rune]int)
+	s, _ = RandomString(30000, "abc")
+	for _, r := range s {
+		counts[r]++
+	}
+	for r, n := range counts {
+		if n < 9000 || n > 11000 {
+			t.Errorf("RandomString() %q picked %v times of 30000, want about 10000", r, n)
+		}
+	}
+}
+
+func BenchmarkCamelToSnake(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		CamelToSnake("parseHTTPResponseBodyV2")
+	}
+}
+
+func BenchmarkTruncate(b *testing.B) {
+	s := strings.Repeat("héllo wörld ", 100)
+	for i := 0; i < b.N; i++ {
+		Truncate(s, 500)
+	}
+}
+
+func BenchmarkReverse(b *testing.B) {
+	s := strings.Repeat("café ", 100)
+	for i := 0; i < b.N; i++ {
+		Reverse(s)
+	}
+}
+
+func BenchmarkRandomString(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = RandomString(32, AlphaNumeric)
+	}
+}
@@ -0,0 +1,72 @@
+package syncx
+
+import (
+	"sync"
+	"time"
+)
+
+// Debounce returns a function calling fn once calls stopped for wait, e.g. to
+// save a file after a burst of edits, and a cancel dropping a pending call
+func Debounce(wait time.Duration, fn func()) (debounced func(), cancel func()) {
+	var mu sync.Mutex
+	var timer *time.Timer
+	debounced = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(wait, fn)
+	}
+	cancel = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	return debounced, cancel
+}
+
+// Throttle returns a function calling fn at most once per interval: the
+// first call runs at once, calls during the interval are folded into one run
+// at its end. cancel drops that pending run.
+func Throttle(interval time.Duration, fn func()) (throttled func(), cancel func()) {
+	var mu sync.Mutex
+	var timer *time.Timer
+	pending := false
+	var tick func()
+	tick = func() {
+		mu.Lock()
+		if !pending {
+			timer = nil
+			mu.Unlock()
+			return
+		}
+		pending = false
+		timer = time.AfterFunc(interval, tick)
+		mu.Unlock()
+		fn()
+	}
+	throttled = func() {
+		mu.Lock()
+		if timer != nil {
+			pending = true
+			mu.Unlock()
+			return
+		}
+		timer = time.AfterFunc(interval, tick)
+		mu.Unlock()
+		fn()
+	}
+	cancel = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		pending = false
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+		}
+	}
+	return throttled, cancel
+}
@@ -0,0 +1,98 @@
+package syncx
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Stellar1999/gotool/errorx"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type groupConfig struct {
+	limit         int
+	cancelOnError bool
+}
+
+type GroupOption = opt.Option[groupConfig]
+
+// WithLimit runs at most n functions at once, Go blocks while n run. 0, the
+// default, does not limit them.
+func WithLimit(n int) GroupOption {
+	return func(c *groupConfig) {
+		c.limit = n
+	}
+}
+
+// WithCancelOnError cancels the context of the group at the first error, so
+// the other functions can stop early
+func WithCancelOnError() GroupOption {
+	return func(c *groupConfig) {
+		c.cancelOnError = true
+	}
+}
+
+// WaitGroup runs functions in goroutines and collects their errors
+type WaitGroup struct {
+	cfg    groupConfig
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	slots  chan struct{}
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewWaitGroup returns a WaitGroup whose functions get a context derived
+// from ctx, canceled when Wait returns
+func NewWaitGroup(ctx context.Context, opts ...GroupOption) *WaitGroup {
+	g := &WaitGroup{cfg: *opt.Apply(&groupConfig{}, opts...)}
+	g.ctx, g.cancel = context.WithCancel(ctx)
+	if g.cfg.limit > 0 {
+		g.slots = make(chan struct{}, g.cfg.limit)
+	}
+	return g
+}
+
+// Go runs fn in a goroutine. With WithLimit it waits for a free slot, fn is
+// not run when the context of the group is done first and the context error
+// is collected instead.
+func (g *WaitGroup) Go(fn func(ctx context.Context) error) {
+	if g.slots != nil {
+		select {
+		case g.slots <- struct{}{}:
+		case <-g.ctx.Done():
+			g.fail(g.ctx.Err())
+			return
+		}
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.slots != nil {
+			defer func() { <-g.slots }()
+		}
+		if err := fn(g.ctx); err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+func (g *WaitGroup) fail(err error) {
+	g.mu.Lock()
+	g.errs = append(g.errs, err)
+	g.mu.Unlock()
+	if g.cfg.cancelOnError {
+		g.cancel()
+	}
+}
+
+// Wait waits for the functions and returns their errors joined with
+// errorx.Join in the order they failed, nil when all succeeded
+func (g *WaitGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return errorx.Join(g.errs...)
+}
@@ -0,0 +1,54 @@
+// Package syncx has the concurrency primitives missing from sync: typed lazy
+// values, a WaitGroup collecting errors, a weighted Semaphore, a sharded
+// concurrent map, debounced and throttled functions and a typed
+// Singleflight.
+package syncx
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Once computes a value on first use. Unlike sync.Once a failed init is
+// tried again by the next Get, a successful one is kept for good.
+type Once[T any] struct {
+	fn    func() (T, error)
+	done  uint32
+	mu    sync.Mutex
+	value T
+}
+
+// NewOnce returns a Once computing its value with fn
+func NewOnce[T any](fn func() (T, error)) *Once[T] {
+	return &Once[T]{fn: fn}
+}
+
+// Get returns the value, computing it when no Get succeeded yet. Concurrent
+// callers wait for the same computation.
+func (o *Once[T]) Get() (T, error) {
+	if atomic.LoadUint32(&o.done) == 1 {
+		return o.value, nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.done == 1 {
+		return o.value, nil
+	}
+	v, err := o.fn()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	o.value = v
+	atomic.StoreUint32(&o.done, 1)
+	return v, nil
+}
+
+// MustGet is Get panicking on error, for values that cannot fail
+func (o *Once[T]) MustGet() T {
+	v, err := o.Get()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -0,0 +1,160 @@
+package syncx
+
+import (
+	"fmt"
+	"hash/maphash"
+	"runtime"
+	"sync"
+)
+
+// RWMap is a concurrent map split into shards with their own lock, so
+// writers of different keys seldom wait for each other. The zero value is
+// not usable, see NewRWMap.
+type RWMap[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []shard[K, V]
+}
+
+type shard[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+}
+
+// NewRWMap returns a map of n shards, a multiple of GOMAXPROCS when n is 0
+func NewRWMap[K comparable, V any](n int) *RWMap[K, V] {
+	if n <= 0 {
+		n = 4 * runtime.GOMAXPROCS(0)
+	}
+	m := &RWMap[K, V]{seed: maphash.MakeSeed(), shards: make([]shard[K, V], n)}
+	for i := range m.shards {
+		m.shards[i].m = make(map[K]V)
+	}
+	return m
+}
+
+// shard picks the shard of key. Strings and integers are hashed directly,
+// other keys through their fmt representation, which is slower.
+func (m *RWMap[K, V]) shard(key K) *shard[K, V] {
+	var h maphash.Hash
+	h.SetSeed(m.seed)
+	switch k := any(key).(type) {
+	case string:
+		_, _ = h.WriteString(k)
+	case int:
+		writeUint(&h, uint64(k))
+	case int64:
+		writeUint(&h, uint64(k))
+	case int32:
+		writeUint(&h, uint64(k))
+	case uint:
+		writeUint(&h, uint64(k))
+	case uint64:
+		writeUint(&h, k)
+	case uint32:
+		writeUint(&h, uint64(k))
+	default:
+		_, _ = h.WriteString(fmt.Sprintf("%#v", k))
+	}
+	return &m.shards[h.Sum64()%uint64(len(m.shards))]
+}
+
+func writeUint(h *maphash.Hash, v uint64) {
+	var b [8]byte
+	for i := range b {
+		b[i] = byte(v >> (8 * i))
+	}
+	_, _ = h.Write(b[:])
+}
+
+// Load returns the value of key
+func (m *RWMap[K, V]) Load(key K) (V, bool) {
+	s := m.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+// Store sets the value of key
+func (m *RWMap[K, V]) Store(key K, value V) {
+	s := m.shard(key)
+	s.mu.Lock()
+	s.m[key] = value
+	s.mu.Unlock()
+}
+
+// LoadOrStore returns the value of key if present, otherwise stores value.
+// loaded tells which happened.
+func (m *RWMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.m[key]; ok {
+		return v, true
+	}
+	s.m[key] = value
+	return value, false
+}
+
+// LoadAndDelete removes key and returns its value
+func (m *RWMap[K, V]) LoadAndDelete(key K) (V, bool) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.m[key]
+	delete(s.m, key)
+	return v, ok
+}
+
+// Delete removes key
+func (m *RWMap[K, V]) Delete(key K) {
+	s := m.shard(key)
+	s.mu.Lock()
+	delete(s.m, key)
+	s.mu.Unlock()
+}
+
+// Update replaces the value of key with the result of fn atomically, fn gets
+// the current value and whether there is one, and removes the key when keep
+// is false. It must not use the map.
+func (m *RWMap[K, V]) Update(key K, fn func(value V, ok bool) (newValue V, keep bool)) V {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.m[key]
+	v, keep := fn(v, ok)
+	if keep {
+		s.m[key] = v
+	} else {
+		delete(s.m, key)
+	}
+	return v
+}
+
+// Len returns the number of keys, not a snapshot under concurrent writes
+func (m *RWMap[K, V]) Len() int {
+	n := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		n += len(s.m)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Range calls fn for the keys shard by shard until it returns false. A shard
+// is locked for reading during its calls, fn must not write to the map.
+func (m *RWMap[K, V]) Range(fn func(key K, value V) bool) {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		for k, v := range s.m {
+			if !fn(k, v) {
+				s.mu.RUnlock()
+				return
+			}
+		}
+		s.mu.RUnlock()
+	}
+}
@@ -0,0 +1,80 @@
+package syncx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Singleflight runs one call per key at a time, concurrent callers of a key
+// share the result of the running call. The zero value is ready to use.
+type Singleflight[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flight[T]
+}
+
+type flight[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Do runs fn for key unless a call of key runs already, in which case it
+// waits for its result and shared is true. A waiter whose ctx is done
+// returns ctx.Err() without affecting the call. fn runs in the goroutine of
+// the first caller, a panic of fn is raised there and the waiters get an
+// error.
+func (g *Singleflight[T]) Do(ctx context.Context, key string, fn func() (T, error)) (value T, shared bool, err error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.value, true, call.err
+		case <-ctx.Done():
+			var zero T
+			return zero, true, ctx.Err()
+		}
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*flight[T])
+	}
+	call := &flight[T]{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		if p := recover(); p != nil {
+			call.err = fmt.Errorf("syncx: singleflight call panicked: %v", p)
+			g.finish(key, call)
+			panic(p)
+		}
+		g.finish(key, call)
+	}()
+	call.value, call.err = fn()
+	return call.value, false, call.err
+}
+
+func (g *Singleflight[T]) finish(key string, call *flight[T]) {
+	g.mu.Lock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	close(call.done)
+}
+
+// Forget makes the next Do of key start a new call even if one runs, its
+// waiters still get its result
+func (g *Singleflight[T]) Forget(key string) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+}
+
+// InFlight returns the number of running calls
+func (g *Singleflight[T]) InFlight() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.calls)
+}
@@ -0,0 +1,268 @@
+package syncx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Stellar1999/gotool/errorx"
+)
+
+func TestOnce(t *testing.T) {
+	var calls int32
+	fail := true
+	o := NewOnce(func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		if fail {
+			return "", errors.New("not yet")
+		}
+		return "ready", nil
+	})
+	if _, err := o.Get(); err == nil {
+		t.Errorf("Get() got = nil, want the init error")
+	}
+	fail = false
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v := o.MustGet(); v != "ready" {
+				t.Errorf("MustGet() got = %v", v)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 2 {
+		t.Errorf("Once called fn %v times, want 2", calls)
+	}
+}
+
+func TestWaitGroup(t *testing.T) {
+	g := NewWaitGroup(context.Background())
+	for i := 0; i < 5; i++ {
+		i := i
+		g.Go(func(ctx context.Context) error {
+			if i%2 == 1 {
+				return errors.New("job " + strconv.Itoa(i))
+			}
+			return nil
+		})
+	}
+	err := g.Wait()
+	var multi *errorx.MultiError
+	if !errors.As(err, &multi) || len(multi.Errs) != 2 {
+		t.Errorf("Wait() got = %v, want 2 errors", err)
+	}
+	if err := NewWaitGroup(context.Background()).Wait(); err != nil {
+		t.Errorf("Wait() empty got = %v", err)
+	}
+
+	g = NewWaitGroup(context.Background(), WithLimit(2), WithCancelOnError())
+	var running, peak int32
+	failed := errors.New("failed")
+	for i := 0; i < 6; i++ {
+		i := i
+		g.Go(func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			if i == 1 {
+				return failed
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Second):
+				return errors.New("not canceled")
+			}
+		})
+	}
+	err = g.Wait()
+	if !errors.Is(err, failed) || strings.Contains(err.Error(), "not canceled") || peak > 2 {
+		t.Errorf("Wait() limited got = %v with %v at once", err, peak)
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	s := NewSemaphore(3)
+	ctx := context.Background()
+	if err := s.Acquire(ctx, 2); err != nil || !s.TryAcquire(1) || s.TryAcquire(1) {
+		t.Fatalf("Acquire() got = %v", err)
+	}
+
+	// a heavy waiter first in line holds back lighter ones behind it
+	got := make(chan int64, 2)
+	go func() {
+		_ = s.Acquire(ctx, 3)
+		got <- 3
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		_ = s.Acquire(ctx, 1)
+		got <- 1
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Release(1)
+	select {
+	case n := <-got:
+		t.Fatalf("Acquire(%v) got the semaphore too early", n)
+	case <-time.After(10 * time.Millisecond):
+	}
+	s.Release(2)
+	if n := <-got; n != 3 {
+		t.Errorf("Acquire() order got %v first, want 3", n)
+	}
+	s.Release(3)
+	if n := <-got; n != 1 {
+		t.Errorf("Acquire() got %v, want 1", n)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := s.Acquire(short, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() timeout got = %v", err)
+	}
+	s.Release(1)
+	if !s.TryAcquire(3) {
+		t.Errorf("TryAcquire() got = false after a canceled waiter left")
+	}
+}
+
+func TestRWMap(t *testing.T) {
+	m := NewRWMap[string, int](0)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Update("counter", func(v int, ok bool) (int, bool) { return v + 1, true })
+				m.Store(strconv.Itoa(j), j)
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := m.Load("counter"); v != 800 || m.Len() != 101 {
+		t.Errorf("Update() got = %v with %v keys, want 800 and 101", v, m.Len())
+	}
+	if v, loaded := m.LoadOrStore("7", 70); v != 7 || !loaded {
+		t.Errorf("LoadOrStore() got = %v %v", v, loaded)
+	}
+	if v, ok := m.LoadAndDelete("7"); v != 7 || !ok {
+		t.Errorf("LoadAndDelete() got = %v %v", v, ok)
+	}
+	m.Update("counter", func(int, bool) (int, bool) { return 0, false })
+	m.Delete("8")
+	seen := 0
+	m.Range(func(string, int) bool {
+		seen++
+		return true
+	})
+	if seen != 98 {
+		t.Errorf("Range() got = %v keys, want 98", seen)
+	}
+
+	type point struct{ X, Y int }
+	pm := NewRWMap[point, string](4)
+	pm.Store(point{1, 2}, "a")
+	if v, ok := pm.Load(point{1, 2}); v != "a" || !ok {
+		t.Errorf("Load() struct key got = %v %v", v, ok)
+	}
+}
+
+func TestDebounceAndThrottle(t *testing.T) {
+	var calls int32
+	debounced, cancel := Debounce(20*time.Millisecond, func() { atomic.AddInt32(&calls, 1) })
+	for i := 0; i < 5; i++ {
+		debounced()
+		time.Sleep(2 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Debounce() got %v calls, want 1", n)
+	}
+	debounced()
+	cancel()
+	time.Sleep(30 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Debounce() canceled got %v calls, want 1", n)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	throttled, stop := Throttle(30*time.Millisecond, func() { atomic.AddInt32(&calls, 1) })
+	defer stop()
+	for i := 0; i < 5; i++ {
+		throttled()
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Throttle() got %v calls at once, want the leading one", n)
+	}
+	time.Sleep(45 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Throttle() got %v calls, want the trailing one too", n)
+	}
+}
+
+func TestSingleflight(t *testing.T) {
+	var g Singleflight[int]
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 42, nil
+	}
+	results := make(chan bool, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			v, shared, err := g.Do(context.Background(), "k", fn)
+			if v != 42 || err != nil {
+				t.Errorf("Do() got = %v %v", v, err)
+			}
+			results <- shared
+		}()
+	}
+	for g.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, shared, err := g.Do(ctx, "k", fn); !shared || !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() canceled waiter got = %v %v", shared, err)
+	}
+	close(release)
+	sharedCount := 0
+	for i := 0; i < 5; i++ {
+		if <-results {
+			sharedCount++
+		}
+	}
+	if calls != 1 || sharedCount != 4 || g.InFlight() != 0 {
+		t.Errorf("Do() got %v calls and %v shared results, want 1 and 4", calls, sharedCount)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Do() got no panic, want it raised in the caller")
+			}
+		}()
+		_, _, _ = g.Do(context.Background(), "p", func() (int, error) { panic("boom") })
+	}()
+	if g.InFlight() != 0 {
+		t.Errorf("InFlight() after a panic got = %v, want 0", g.InFlight())
+	}
+}
@@ -0,0 +1,112 @@
+package synthetic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Assertion checks a Result, returning why it fails
+type Assertion func(r *Result) error
+
+// Status asserts the response status is one of codes
+func Status(codes ...int) Assertion {
+	return func(r *Result) error {
+		for _, code := range codes {
+			if r.StatusCode == code {
+				return nil
+			}
+		}
+		return fmt.Errorf("status %d, want %v", r.StatusCode, codes)
+	}
+}
+
+// MaxLatency asserts the request took at most d
+func MaxLatency(d time.Duration) Assertion {
+	return func(r *Result) error {
+		if r.Latency > d {
+			return fmt.Errorf("latency %v, want at most %v", r.Latency, d)
+		}
+		return nil
+	}
+}
+
+// BodyContains asserts the body contains s
+func BodyContains(s string) Assertion {
+	return func(r *Result) error {
+		if !bytes.Contains(r.Body, []byte(s)) {
+			return fmt.Errorf("body does not contain %q", s)
+		}
+		return nil
+	}
+}
+
+// JSONPath asserts the value at a dot separated path of the JSON body equals
+// want, array elements are addressed by index, e.g. "data.items.0.id"
+func JSONPath(path string, want any) Assertion {
+	return func(r *Result) error {
+		got, err := lookupJSON(r.Body, path)
+		if err != nil {
+			return err
+		}
+		wantJSON, err := normalizeJSON(want)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(got, wantJSON) {
+			return fmt.Errorf("%s is %v, want %v", path, got, want)
+		}
+		return nil
+	}
+}
+
+// JSONPathExists asserts the path is present in the JSON body
+func JSONPathExists(path string) Assertion {
+	return func(r *Result) error {
+		_, err := lookupJSON(r.Body, path)
+		return err
+	}
+}
+
+func lookupJSON(body []byte, path string) (any, error) {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, fmt.Errorf("body is not JSON: %w", err)
+	}
+	if path == "" {
+		return value, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("%s: no key %q", path, key)
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("%s: no index %q", path, key)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("%s: %q is not an object or array", path, key)
+		}
+	}
+	return value, nil
+}
+
+// normalizeJSON converts v to the types json.Unmarshal produces
+func normalizeJSON(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	return out, json.Unmarshal(data, &out)
+}
@@ -0,0 +1,317 @@
+package synthetic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Stellar1999/gotool/cron"
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Check is a request run on a schedule and the assertions its result must pass.
+// Without assertions the response must be 200 OK.
+type Check struct {
+	Name       string
+	Method     gohttp.RequestMethodType
+	URL        string
+	Header     map[string]string
+	Body       any
+	Interval   time.Duration
+	Timeout    time.Duration
+	Assertions []Assertion
+}
+
+// Result is the outcome of one run, Err is set when no response was received
+type Result struct {
+	Check      string
+	Time       time.Time
+	StatusCode int
+	Body       []byte
+	Latency    time.Duration
+	Err        error
+	Failures   []error
+}
+
+// OK reports whether the request succeeded and every assertion passed
+func (r *Result) OK() bool {
+	return r.Err == nil && len(r.Failures) == 0
+}
+
+// Alert is sent when a check fails Streak times in a row and once more with
+// Recovered set when it passes again
+type Alert struct {
+	Check     string
+	Streak    int
+	Recovered bool
+	Result    Result
+}
+
+// Notifier receives alerts, implementations should not block for long
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// NotifierFunc adapts a function to Notifier
+type NotifierFunc func(ctx context.Context, alert Alert) error
+
+func (f NotifierFunc) Notify(ctx context.Context, alert Alert) error {
+	return f(ctx, alert)
+}
+
+// ChanNotifier delivers alerts on ch, dropping them when nobody is receiving
+func ChanNotifier(ch chan<- Alert) Notifier {
+	return NotifierFunc(func(ctx context.Context, alert Alert) error {
+		select {
+		case ch <- alert:
+			return nil
+		default:
+			return errors.New("synthetic: alert channel is full")
+		}
+	})
+}
+
+type Option = opt.Option[Monitor]
+
+// WithClient sends the checks with client instead of a new gohttp.Client
+func WithClient(client *gohttp.Client) Option {
+	return func(m *Monitor) {
+		m.client = client
+	}
+}
+
+// WithNotifier adds a notifier for failure streaks
+func WithNotifier(n Notifier) Option {
+	return func(m *Monitor) {
+		m.notifiers = append(m.notifiers, n)
+	}
+}
+
+// WithFailureThreshold sets how many failures in a row raise an alert, 3 by default
+func WithFailureThreshold(n int) Option {
+	return func(m *Monitor) {
+		m.threshold = n
+	}
+}
+
+// WithRegisterer records results on prometheus collectors registered on reg
+func WithRegisterer(reg prometheus.Registerer) Option {
+	return func(m *Monitor) {
+		m.registerer = reg
+	}
+}
+
+// Monitor runs checks and tracks their failure streaks
+type Monitor struct {
+	client     *gohttp.Client
+	notifiers  []Notifier
+	threshold  int
+	registerer prometheus.Registerer
+
+	up      *prometheus.GaugeVec
+	latency *prometheus.GaugeVec
+	runs    *prometheus.CounterVec
+
+	mu       sync.Mutex
+	checks   []Check
+	streaks  map[string]int
+	last     map[string]Result
+	total    float64
+	failures float64
+}
+
+// New creates a Monitor, the collectors are registered when WithRegisterer is given
+func New(opts ...Option) (*Monitor, error) {
+	m := &Monitor{
+		threshold: 3,
+		streaks:   make(map[string]int),
+		last:      make(map[string]Result),
+	}
+	err := opt.Build(m, opts, func(m *Monitor) error {
+		if m.threshold <= 0 {
+			return errors.New("synthetic: failure threshold must be positive")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if m.client == nil {
+		m.client = gohttp.NewClient()
+	}
+	if m.registerer != nil {
+		m.up = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gotool",
+			Subsystem: "synthetic",
+			Name:      "check_up",
+			Help:      "Whether the last run of the check passed.",
+		}, []string{"check"})
+		m.latency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gotool",
+			Subsystem: "synthetic",
+			Name:      "check_latency_seconds",
+			Help:      "Latency of the last run of the check.",
+		}, []string{"check"})
+		m.runs = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gotool",
+			Subsystem: "synthetic",
+			Name:      "check_runs_total",
+			Help:      "Runs of the check by result.",
+		}, []string{"check", "result"})
+		for _, collector := range []prometheus.Collector{m.up, m.latency, m.runs} {
+			if err := m.registerer.Register(collector); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// Add schedules c for Run, Name, URL and a positive Interval are required
+func (m *Monitor) Add(c Check) error {
+	if c.Name == "" || c.URL == "" || c.Interval <= 0 {
+		return fmt.Errorf("synthetic: check %q needs a name, url and interval", c.Name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks = append(m.checks, c)
+	return nil
+}
+
+// Run runs every added check at its interval, starting immediately, until ctx is done
+func (m *Monitor) Run(ctx context.Context) error {
+	m.mu.Lock()
+	checks := append([]Check(nil), m.checks...)
+	m.mu.Unlock()
+	scheduler, err := cron.New()
+	if err != nil {
+		return err
+	}
+	for i, c := range checks {
+		c := c
+		// names need not be unique among checks
+		err := scheduler.Add(fmt.Sprintf("%d_%s", i, c.Name), cron.Every(c.Interval), func(context.Context) error {
+			m.RunOnce(ctx, c)
+			return nil
+		}, cron.WithRunOnStart())
+		if err != nil {
+			return err
+		}
+	}
+	scheduler.Start()
+	<-ctx.Done()
+	scheduler.Stop()
+	return ctx.Err()
+}
+
+// RunOnce runs c, records the result and notifies on streak changes
+func (m *Monitor) RunOnce(ctx context.Context, c Check) Result {
+	parent := ctx
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	method := c.Method
+	if method == "" {
+		method = gohttp.GET
+	}
+	req := m.client.NewRequest(method, c.URL).WithContext(ctx)
+	for k, v := range c.Header {
+		req.Header(k, v)
+	}
+	if c.Body != nil {
+		req.Body(c.Body)
+	}
+
+	result := Result{Check: c.Name, Time: time.Now()}
+	resp, err := req.Send()
+	result.Latency = time.Since(result.Time)
+	if resp == nil {
+		result.Err = err
+		if parent.Err() != nil {
+			// the monitor is stopping, this is not a failure of the target
+			return result
+		}
+	} else {
+		result.StatusCode, result.Body = resp.StatusCode, resp.Body
+		assertions := c.Assertions
+		if len(assertions) == 0 {
+			assertions = []Assertion{Status(200)}
+		}
+		for _, assert := range assertions {
+			if err := assert(&result); err != nil {
+				result.Failures = append(result.Failures, err)
+			}
+		}
+	}
+	m.record(ctx, result)
+	return result
+}
+
+func (m *Monitor) record(ctx context.Context, result Result) {
+	if m.up != nil {
+		up, label := 0.0, "failure"
+		if result.OK() {
+			up, label = 1, "success"
+		}
+		m.up.WithLabelValues(result.Check).Set(up)
+		m.latency.WithLabelValues(result.Check).Set(result.Latency.Seconds())
+		m.runs.WithLabelValues(result.Check, label).Inc()
+	}
+
+	m.mu.Lock()
+	m.total++
+	streak := m.streaks[result.Check]
+	var alert *Alert
+	if result.OK() {
+		if streak >= m.threshold {
+			alert = &Alert{Check: result.Check, Streak: streak, Recovered: true, Result: result}
+		}
+		m.streaks[result.Check] = 0
+	} else {
+		m.failures++
+		streak++
+		m.streaks[result.Check] = streak
+		if streak == m.threshold {
+			alert = &Alert{Check: result.Check, Streak: streak, Result: result}
+		}
+	}
+	m.last[result.Check] = result
+	m.mu.Unlock()
+
+	if alert != nil {
+		for _, n := range m.notifiers {
+			_ = n.Notify(ctx, *alert)
+		}
+	}
+}
+
+// Last returns the latest result of the named check
+func (m *Monitor) Last(name string) (Result, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result, ok := m.last[name]
+	return result, ok
+}
+
+// Stats implements stats.Stats
+func (m *Monitor) Stats() map[string]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	failing := 0.0
+	for _, streak := range m.streaks {
+		if streak > 0 {
+			failing++
+		}
+	}
+	return map[string]float64{
+		"runs_total":     m.total,
+		"failures_total": m.failures,
+		"checks_failing": failing,
+	}
+}
@@ -0,0 +1,138 @@
+package synthetic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAssertions(t *testing.T) {
+	result := &Result{
+		StatusCode: 200,
+		Latency:    50 * time.Millisecond,
+		Body:       []byte(`{"status":"ok","data":{"items":[{"id":7,"tags":["a"]}]}}`),
+	}
+	tests := []struct {
+		name    string
+		assert  Assertion
+		wantErr bool
+	}{
+		{name: "status", assert: Status(200, 204)},
+		{name: "status mismatch", assert: Status(204), wantErr: true},
+		{name: "latency", assert: MaxLatency(100 * time.Millisecond)},
+		{name: "latency exceeded", assert: MaxLatency(10 * time.Millisecond), wantErr: true},
+		{name: "contains", assert: BodyContains(`"ok"`)},
+		{name: "json string", assert: JSONPath("status", "ok")},
+		{name: "json number in array", assert: JSONPath("data.items.0.id", 7)},
+		{name: "json slice", assert: JSONPath("data.items.0.tags", []string{"a"})},
+		{name: "json mismatch", assert: JSONPath("data.items.0.id", 8), wantErr: true},
+		{name: "json missing index", assert: JSONPathExists("data.items.1"), wantErr: true},
+		{name: "json exists", assert: JSONPathExists("data.items")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.assert(result); (err != nil) != tt.wantErr {
+				t.Errorf("Assertion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	var healthy int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	alerts := make(chan Alert, 10)
+	reg := prometheus.NewRegistry()
+	m, err := New(
+		WithClient(gohttp.NewClient(gohttp.WithLogger(gohttp.NopLogger))),
+		WithNotifier(ChanNotifier(alerts)),
+		WithFailureThreshold(2),
+		WithRegisterer(reg),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	check := Check{Name: "health", URL: server.URL, Interval: time.Second, Assertions: []Assertion{Status(200), JSONPath("status", "ok")}}
+
+	steps := []struct {
+		healthy   int32
+		wantOK    bool
+		wantAlert *Alert
+	}{
+		{healthy: 1, wantOK: true},
+		{healthy: 0},
+		{healthy: 0, wantAlert: &Alert{Check: "health", Streak: 2}},
+		{healthy: 0},
+		{healthy: 1, wantOK: true, wantAlert: &Alert{Check: "health", Streak: 3, Recovered: true}},
+	}
+	for i, step := range steps {
+		atomic.StoreInt32(&healthy, step.healthy)
+		result := m.RunOnce(context.Background(), check)
+		if result.OK() != step.wantOK {
+			t.Errorf("step %d RunOnce() ok got = %v, want %v (%v)", i, result.OK(), step.wantOK, result.Failures)
+		}
+		select {
+		case alert := <-alerts:
+			if step.wantAlert == nil || alert.Check != step.wantAlert.Check || alert.Streak != step.wantAlert.Streak || alert.Recovered != step.wantAlert.Recovered {
+				t.Errorf("step %d alert got = %+v, want %+v", i, alert, step.wantAlert)
+			}
+		default:
+			if step.wantAlert != nil {
+				t.Errorf("step %d alert got none, want %+v", i, step.wantAlert)
+			}
+		}
+	}
+
+	if got := testutil.ToFloat64(m.runs.WithLabelValues("health", "failure")); got != 3 {
+		t.Errorf("failure runs got = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(m.up.WithLabelValues("health")); got != 1 {
+		t.Errorf("check_up got = %v, want 1", got)
+	}
+	if got := m.Stats(); got["runs_total"] != 5 || got["failures_total"] != 3 || got["checks_failing"] != 0 {
+		t.Errorf("Stats() got = %v", got)
+	}
+}
+
+func TestMonitorRun(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	m, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := m.Add(Check{Name: "bad"}); err == nil {
+		t.Errorf("Add() without url error = nil, want error")
+	}
+	if err := m.Add(Check{Name: "ping", URL: server.URL, Interval: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	_ = m.Run(ctx)
+	if got := atomic.LoadInt32(&hits); got < 3 {
+		t.Errorf("Run() hits got = %v, want at least 3", got)
+	}
+	if last, ok := m.Last("ping"); !ok || !last.OK() {
+		t.Errorf("Last() got = %+v %v, want a passing result", last, ok)
+	}
+}
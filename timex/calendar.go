@@ -0,0 +1,115 @@
+package timex
+
+import (
+	"time"
+
+	"github.com/Stellar1999/gotool/opt"
+)
+
+type date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dateOf(t time.Time) date {
+	y, m, d := t.Date()
+	return date{y, m, d}
+}
+
+type calendarConfig struct {
+	weekend  []time.Weekday
+	holidays []time.Time
+}
+
+type CalendarOption = opt.Option[calendarConfig]
+
+// WithWeekend sets the days without business, Saturday and Sunday by default
+func WithWeekend(days ...time.Weekday) CalendarOption {
+	return func(c *calendarConfig) {
+		c.weekend = days
+	}
+}
+
+// WithHolidays adds days without business, only their date counts
+func WithHolidays(days ...time.Time) CalendarOption {
+	return func(c *calendarConfig) {
+		c.holidays = append(c.holidays, days...)
+	}
+}
+
+// Calendar tells business days from weekends and holidays. Dates are
+// compared in the location of the times passed to its methods.
+type Calendar struct {
+	weekend  [7]bool
+	holidays map[date]bool
+}
+
+// NewCalendar returns a Calendar with the weekend and holidays of opts
+func NewCalendar(opts ...CalendarOption) *Calendar {
+	cfg := opt.Apply(&calendarConfig{weekend: []time.Weekday{time.Saturday, time.Sunday}}, opts...)
+	c := &Calendar{holidays: make(map[date]bool, len(cfg.holidays))}
+	for _, day := range cfg.weekend {
+		c.weekend[day] = true
+	}
+	for _, day := range cfg.holidays {
+		c.holidays[dateOf(day)] = true
+	}
+	return c
+}
+
+// IsBusinessDay reports whether the day of t is neither weekend nor holiday
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	return !c.weekend[t.Weekday()] && !c.holidays[dateOf(t)]
+}
+
+// AddBusinessDays moves t by n business days, back for a negative n, keeping
+// the time of day. From a day off the first step lands on the next business
+// day. It returns t when the calendar has no business day.
+func (c *Calendar) AddBusinessDays(t time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	if c.weekend == [7]bool{true, true, true, true, true, true, true} {
+		return t
+	}
+	y, m, d := t.Date()
+	hour, min, sec := t.Clock()
+	for n > 0 {
+		d += step
+		day := time.Date(y, m, d, hour, min, sec, t.Nanosecond(), t.Location())
+		if c.IsBusinessDay(day) {
+			n--
+		}
+	}
+	return time.Date(y, m, d, hour, min, sec, t.Nanosecond(), t.Location())
+}
+
+// BusinessDaysBetween counts the business days from the day of a up to, not
+// including, the day of b, negative when b is before a
+func (c *Calendar) BusinessDaysBetween(a time.Time, b time.Time) int {
+	sign := 1
+	if b.Before(a) {
+		a, b, sign = b, a, -1
+	}
+	count := 0
+	y, m, d := a.Date()
+	end := dateOf(b)
+	for day := time.Date(y, m, d, 12, 0, 0, 0, a.Location()); dateOf(day) != end; {
+		if c.IsBusinessDay(day) {
+			count++
+		}
+		d++
+		day = time.Date(y, m, d, 12, 0, 0, 0, a.Location())
+	}
+	return sign * count
+}
+
+var defaultCalendar = NewCalendar()
+
+// AddBusinessDays moves t by n weekdays, see Calendar.AddBusinessDays for
+// holidays
+func AddBusinessDays(t time.Time, n int) time.Time {
+	return defaultCalendar.AddBusinessDays(t, n)
+}
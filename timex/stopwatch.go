@@ -0,0 +1,83 @@
+package timex
+
+import (
+	"sync"
+	"time"
+)
+
+// Stopwatch measures elapsed time with the monotonic clock, so wall clock
+// changes do not affect it. It is safe for concurrent use.
+type Stopwatch struct {
+	mu      sync.Mutex
+	started time.Time
+	lap     time.Time
+	elapsed time.Duration
+	running bool
+}
+
+// StartStopwatch returns a running Stopwatch
+func StartStopwatch() *Stopwatch {
+	s := &Stopwatch{}
+	s.Start()
+	return s
+}
+
+// Start starts or resumes the stopwatch
+func (s *Stopwatch) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		s.started = time.Now()
+		s.lap = s.started
+		s.running = true
+	}
+}
+
+// Stop pauses the stopwatch and returns the elapsed time
+func (s *Stopwatch) Stop() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		s.elapsed += time.Since(s.started)
+		s.running = false
+	}
+	return s.elapsed
+}
+
+// Reset stops the stopwatch and sets it to zero
+func (s *Stopwatch) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.elapsed, s.running = 0, false
+}
+
+// Elapsed returns the time the stopwatch ran
+func (s *Stopwatch) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return s.elapsed + time.Since(s.started)
+	}
+	return s.elapsed
+}
+
+// Lap returns the time since the previous Lap or Start of a running
+// stopwatch, 0 when it is stopped
+func (s *Stopwatch) Lap() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return 0
+	}
+	now := time.Now()
+	lap := now.Sub(s.lap)
+	s.lap = now
+	return lap
+}
+
+// Running reports whether the stopwatch runs
+func (s *Stopwatch) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
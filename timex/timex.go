@@ -0,0 +1,173 @@
+// Package timex has time helpers missing from time: parsing of common
+// layouts, human readable durations, truncation to calendar boundaries,
+// business day math and a stopwatch.
+package timex
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnknownFormat is returned by Parse for strings of no known layout
+var ErrUnknownFormat = errors.New("timex: unknown time format")
+
+// Layouts are tried in order by Parse, those without a zone are read in the
+// location passed to ParseIn
+var Layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"20060102T150405Z0700",
+	"20060102T150405Z",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"20060102",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.RFC822Z,
+	time.RFC822,
+	time.ANSIC,
+	time.UnixDate,
+	"02 Jan 2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+}
+
+// Parse reads s in one of Layouts or as a unix timestamp, times without a
+// zone are UTC. See ParseIn.
+func Parse(s string) (time.Time, error) {
+	return ParseIn(s, time.UTC)
+}
+
+// ParseIn reads s in one of Layouts, times without a zone in loc. Numbers
+// are unix timestamps in seconds, with a fraction or not, or in
+// milliseconds, microseconds or nanoseconds told apart by their length.
+func ParseIn(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, ok := parseUnix(s); ok {
+		return t.In(loc), nil
+	}
+	for _, layout := range Layouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+}
+
+func parseUnix(s string) (time.Time, bool) {
+	digits := strings.TrimPrefix(s, "-")
+	whole, frac, hasFrac := strings.Cut(digits, ".")
+	if whole == "" || strings.Trim(whole, "0123456789") != "" || hasFrac && strings.Trim(frac, "0123456789") != "" {
+		return time.Time{}, false
+	}
+	if hasFrac {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		sec, fraction := math.Modf(f)
+		return time.Unix(int64(sec), int64(fraction*1e9)).UTC(), true
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	// 8 digits are a date like 20240102 rather than a 1970s timestamp
+	switch l := len(whole); {
+	case l <= 8:
+		return time.Time{}, false
+	case l <= 11:
+		return time.Unix(n, 0).UTC(), true
+	case l <= 14:
+		return time.UnixMilli(n).UTC(), true
+	case l <= 17:
+		return time.UnixMicro(n).UTC(), true
+	}
+	return time.Unix(0, n).UTC(), true
+}
+
+var units = []struct {
+	d    time.Duration
+	name string
+}{
+	{365 * 24 * time.Hour, "year"},
+	{30 * 24 * time.Hour, "month"},
+	{7 * 24 * time.Hour, "week"},
+	{24 * time.Hour, "day"},
+	{time.Hour, "hour"},
+	{time.Minute, "minute"},
+	{time.Second, "second"},
+}
+
+// Humanize describes how long ago d was, e.g. "3 minutes ago" for
+// Humanize(time.Since(t)), or "in 2 days" for a negative d. The largest
+// unit is used, rounded down, below a second it is "just now".
+func Humanize(d time.Duration) string {
+	future := d < 0
+	if future {
+		d = -d
+	}
+	for _, u := range units {
+		if d < u.d {
+			continue
+		}
+		n := int64(d / u.d)
+		text := strconv.FormatInt(n, 10) + " " + u.name
+		if n != 1 {
+			text += "s"
+		}
+		if future {
+			return "in " + text
+		}
+		return text + " ago"
+	}
+	return "just now"
+}
+
+// HumanizeTime is Humanize(time.Since(t))
+func HumanizeTime(t time.Time) string {
+	return Humanize(time.Since(t))
+}
+
+// StartOfDay returns midnight of the day of t in loc, or in the location of
+// t when loc is nil
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = in(t, loc)
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// StartOfWeek returns midnight of the first day of the week of t in loc,
+// weeks starting on first, e.g. time.Monday
+func StartOfWeek(t time.Time, loc *time.Location, first time.Weekday) time.Time {
+	t = in(t, loc)
+	back := (int(t.Weekday()) - int(first) + 7) % 7
+	y, m, d := t.Date()
+	return time.Date(y, m, d-back, 0, 0, 0, 0, t.Location())
+}
+
+// StartOfMonth returns midnight of the first day of the month of t in loc
+func StartOfMonth(t time.Time, loc *time.Location) time.Time {
+	t = in(t, loc)
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+func in(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
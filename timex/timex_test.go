@@ -0,0 +1,157 @@
+package timex
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	want := time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC)
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"2024-03-05T14:07:09Z", want},
+		{"2024-03-05T16:07:09+02:00", want},
+		{"2024-03-05T14:07:09.250Z", want.Add(250 * time.Millisecond)},
+		{"20240305T140709Z", want},
+		{"2024-03-05T14:07:09", want},
+		{"2024-03-05 14:07:09", want},
+		{" 2024-03-05 14:07:09 ", want},
+		{"2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"20240305", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"Tue, 05 Mar 2024 14:07:09 GMT", want},
+		{"1709647629", want},
+		{"1709647629250", want.Add(250 * time.Millisecond)},
+		{"1709647629.5", want.Add(500 * time.Millisecond)},
+		{"1709647629000000000", want},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.s)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) got = %v %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "yesterday", "2024-13-01", "12.3.4"} {
+		if _, err := Parse(bad); !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("Parse(%q) error = %v, want %v", bad, err, ErrUnknownFormat)
+		}
+	}
+
+	tokyo := time.FixedZone("JST", 9*3600)
+	got, _ := ParseIn("2024-03-05 23:07:09", tokyo)
+	if !got.Equal(want) || got.Location() != tokyo {
+		t.Errorf("ParseIn() got = %v, want %v in JST", got, want)
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "just now"},
+		{500 * time.Millisecond, "just now"},
+		{time.Second, "1 second ago"},
+		{3*time.Minute + 59*time.Second, "3 minutes ago"},
+		{-2 * time.Hour, "in 2 hours"},
+		{36 * time.Hour, "1 day ago"},
+		{15 * 24 * time.Hour, "2 weeks ago"},
+		{400 * 24 * time.Hour, "1 year ago"},
+	}
+	for _, tt := range tests {
+		if got := Humanize(tt.d); got != tt.want {
+			t.Errorf("Humanize(%v) got = %v, want %v", tt.d, got, tt.want)
+		}
+	}
+	if got := HumanizeTime(time.Now().Add(-90 * time.Second)); got != "1 minute ago" {
+		t.Errorf("HumanizeTime() got = %v", got)
+	}
+}
+
+func TestStartOf(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	// 02:30 UTC on Thursday 2024-03-14 is still Wednesday in New York
+	at := time.Date(2024, 3, 14, 2, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{"StartOfDay", StartOfDay(at, ny), time.Date(2024, 3, 13, 0, 0, 0, 0, ny)},
+		{"StartOfDay nil", StartOfDay(at, nil), time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{"StartOfWeek", StartOfWeek(at, ny, time.Monday), time.Date(2024, 3, 11, 0, 0, 0, 0, ny)},
+		{"StartOfWeek Sunday", StartOfWeek(at, ny, time.Sunday), time.Date(2024, 3, 10, 0, 0, 0, 0, ny)},
+		{"StartOfMonth", StartOfMonth(at, ny), time.Date(2024, 3, 1, 0, 0, 0, 0, ny)},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.want) || tt.got.Location() != tt.want.Location() {
+			t.Errorf("%v() got = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 12, d, 9, 30, 0, 0, time.UTC) }
+	cal := NewCalendar(WithHolidays(day(25), time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC)))
+	tests := []struct {
+		from time.Time
+		n    int
+		want time.Time
+	}{
+		// Friday the 20th
+		{day(20), 1, day(23)},
+		{day(20), 3, day(27)},
+		{day(23), -1, day(20)},
+		{day(21), 1, day(23)},
+		{day(21), 0, day(21)},
+	}
+	for _, tt := range tests {
+		if got := cal.AddBusinessDays(tt.from, tt.n); !got.Equal(tt.want) {
+			t.Errorf("AddBusinessDays(%v, %v) got = %v, want %v", tt.from, tt.n, got, tt.want)
+		}
+	}
+	if got := AddBusinessDays(day(24), 1); !got.Equal(day(25)) {
+		t.Errorf("AddBusinessDays() got = %v, want the 25th without holidays", got)
+	}
+	if cal.IsBusinessDay(day(25)) || cal.IsBusinessDay(day(22)) || !cal.IsBusinessDay(day(24)) {
+		t.Errorf("IsBusinessDay() got wrong result")
+	}
+	if got := cal.BusinessDaysBetween(day(20), day(30)); got != 4 {
+		t.Errorf("BusinessDaysBetween() got = %v, want 4", got)
+	}
+	if got := cal.BusinessDaysBetween(day(30), day(20)); got != -4 {
+		t.Errorf("BusinessDaysBetween() got = %v, want -4", got)
+	}
+	friOnly := NewCalendar(WithWeekend(time.Saturday, time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday))
+	if got := friOnly.AddBusinessDays(day(20), 1); !got.Equal(day(27)) {
+		t.Errorf("AddBusinessDays() got = %v, want the next Friday", got)
+	}
+}
+
+func TestStopwatch(t *testing.T) {
+	s := StartStopwatch()
+	time.Sleep(10 * time.Millisecond)
+	lap := s.Lap()
+	stopped := s.Stop()
+	if lap < 10*time.Millisecond || stopped < lap || s.Running() {
+		t.Errorf("Stopwatch got lap %v, stopped at %v", lap, stopped)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if s.Elapsed() != stopped || s.Lap() != 0 {
+		t.Errorf("Elapsed() got = %v while stopped, want %v", s.Elapsed(), stopped)
+	}
+	s.Start()
+	time.Sleep(5 * time.Millisecond)
+	if got := s.Elapsed(); got < stopped+5*time.Millisecond {
+		t.Errorf("Elapsed() got = %v after resuming, want above %v", got, stopped+5*time.Millisecond)
+	}
+	s.Reset()
+	if s.Elapsed() != 0 || s.Running() {
+		t.Errorf("Reset() got = %v, want a stopped zero stopwatch", s.Elapsed())
+	}
+}
@@ -0,0 +1,331 @@
+// Package transcode turns a struct of function fields into a typed REST
+// client. Each field declares its endpoint in a tag, in the manner of the
+// HTTP rules of gRPC transcoding, and Bind fills it with a function sending
+// the request through a gohttp.Client, its codec and hooks:
+//
+//	type UsersAPI struct {
+//		Get    func(ctx context.Context, in GetUser) (*User, error)    `http:"GET /users/{id}"`
+//		List   func(ctx context.Context, in ListUsers) ([]User, error) `http:"GET /users"`
+//		Create func(ctx context.Context, in CreateUser) (*User, error) `http:"POST /users" body:"*"`
+//		Rename func(ctx context.Context, in RenameUser) error          `http:"PATCH /users/{id}" body:"Name"`
+//	}
+//
+//	var users UsersAPI
+//	err := transcode.Bind(&users, "https://api.example.com/v1", transcode.WithClient(client))
+//	user, err := users.Get(ctx, GetUser{ID: 7})
+//
+// The fields of the input struct fill the {name} placeholders of the path,
+// matched by their path tag or else their json name. With body:"*" the whole
+// input is the request body, with body:"Field" that field is, and the other
+// fields become query parameters; zero values are left out of the query.
+// The functions take a context and optionally the input, and return an
+// error, optionally after the decoded response. Any 2xx response succeeds.
+// The endpoint may also be given as separate tags, method:"GET"
+// path:"/users/{id}".
+package transcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/Stellar1999/gotool/convert"
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// ErrSpec is returned by Bind for fields whose tags or types don't describe
+// an endpoint
+var ErrSpec = errors.New("transcode: invalid spec")
+
+var (
+	contextType  = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	placeholders = regexp.MustCompile(`\{([^{}]+)\}`)
+)
+
+type config struct {
+	client      *gohttp.Client
+	header      map[string]string
+	decodeError func(resp *gohttp.Response, err error) error
+}
+
+type Option = opt.Option[config]
+
+// WithClient sends the requests with client instead of the default Client
+// of the http package
+func WithClient(client *gohttp.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithHeader adds headers to every request
+func WithHeader(header map[string]string) Option {
+	return func(c *config) {
+		c.header = header
+	}
+}
+
+// WithErrorDecoder returns fn(resp, err) instead of err for responses that
+// are not 2xx, e.g. the error the body describes
+func WithErrorDecoder(fn func(resp *gohttp.Response, err error) error) Option {
+	return func(c *config) {
+		c.decodeError = fn
+	}
+}
+
+// endpoint is the binding of one function field
+type endpoint struct {
+	name   string
+	method string
+	path   string
+	fn     reflect.Type
+	in     reflect.Type // the input struct, nil without input
+	// pathFields maps placeholders to field indexes of the input
+	pathFields map[string][]int
+	// bodyAll sends the whole input, bodyField one field of it
+	bodyAll   bool
+	bodyField []int
+	query     []queryField
+}
+
+type queryField struct {
+	name  string
+	index []int
+}
+
+// Bind fills the function fields of the struct api points to that have an
+// http tag, or method and path tags. Paths are relative to baseURL.
+func Bind(api any, baseURL string, opts ...Option) error {
+	var cfg config
+	if err := opt.Build(&cfg, opts); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(api)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T is not a pointer to a struct", ErrSpec, api)
+	}
+	v = v.Elem()
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag, ok := endpointTag(field)
+		if !ok {
+			continue
+		}
+		if !field.IsExported() {
+			return fmt.Errorf("%w: %s is not exported", ErrSpec, field.Name)
+		}
+		e, err := parseEndpoint(field, tag)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrSpec, field.Name, err)
+		}
+		e.path = baseURL + e.path
+		v.Field(i).Set(reflect.MakeFunc(field.Type, func(args []reflect.Value) []reflect.Value {
+			return e.call(&cfg, args)
+		}))
+	}
+	return nil
+}
+
+// endpointTag returns the http tag of field, or its method and path tags
+// joined like one
+func endpointTag(field reflect.StructField) (string, bool) {
+	if tag, ok := field.Tag.Lookup("http"); ok {
+		return tag, true
+	}
+	method, ok := field.Tag.Lookup("method")
+	if !ok || field.Type.Kind() != reflect.Func {
+		return "", false
+	}
+	return method + " " + field.Tag.Get("path"), true
+}
+
+func parseEndpoint(field reflect.StructField, tag string) (*endpoint, error) {
+	method, path, ok := strings.Cut(strings.TrimSpace(tag), " ")
+	path = strings.TrimSpace(path)
+	if !ok || method == "" || !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf(`tag %q is not like "GET /path/{param}"`, tag)
+	}
+	e := &endpoint{name: field.Name, method: strings.ToUpper(method), path: path, fn: field.Type, pathFields: map[string][]int{}}
+
+	fn := field.Type
+	if fn.Kind() != reflect.Func || fn.IsVariadic() || fn.NumIn() < 1 || fn.NumIn() > 2 || fn.In(0) != contextType ||
+		fn.NumOut() < 1 || fn.NumOut() > 2 || fn.Out(fn.NumOut()-1) != errorType {
+		return nil, fmt.Errorf("%v is not func(ctx[, in]) ([out, ]error)", fn)
+	}
+	if fn.NumIn() == 2 {
+		e.in = fn.In(1)
+		if e.in.Kind() == reflect.Ptr {
+			e.in = e.in.Elem()
+		}
+		if e.in.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("input %v is not a struct", fn.In(1))
+		}
+	}
+
+	body := field.Tag.Get("body")
+	e.bodyAll = body == "*"
+	if body != "" && !e.bodyAll && e.in == nil {
+		return nil, fmt.Errorf("body %q without input", body)
+	}
+	if e.in != nil {
+		for _, f := range reflect.VisibleFields(e.in) {
+			if !f.IsExported() || f.Anonymous && f.Type.Kind() == reflect.Struct {
+				continue
+			}
+			name := fieldName(f)
+			if param := f.Tag.Get("path"); param != "" {
+				e.pathFields[param] = f.Index
+				continue
+			}
+			if name == "" {
+				continue
+			}
+			if strings.Contains(path, "{"+name+"}") {
+				e.pathFields[name] = f.Index
+				continue
+			}
+			switch {
+			case e.bodyAll:
+			case body != "" && (body == f.Name || body == name):
+				e.bodyField = f.Index
+			default:
+				e.query = append(e.query, queryField{name: name, index: f.Index})
+			}
+		}
+		if body != "" && !e.bodyAll && e.bodyField == nil {
+			return nil, fmt.Errorf("body field %q is not in %v", body, e.in)
+		}
+	}
+	for _, m := range placeholders.FindAllStringSubmatch(path, -1) {
+		if _, ok := e.pathFields[m[1]]; !ok {
+			return nil, fmt.Errorf("no input field for {%s}", m[1])
+		}
+	}
+	return e, nil
+}
+
+// fieldName is the json name of f, "" for fields left out of JSON
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+// call sends the request of one call of the function
+func (e *endpoint) call(cfg *config, args []reflect.Value) []reflect.Value {
+	ctx, _ := args[0].Interface().(context.Context)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var req *gohttp.Request
+	if cfg.client != nil {
+		req = cfg.client.NewRequest(gohttp.RequestMethodType(e.method), e.path)
+	} else {
+		req = gohttp.NewRequest(gohttp.RequestMethodType(e.method), e.path)
+	}
+	req.WithContext(ctx)
+	for k, v := range cfg.header {
+		req.Header(k, v)
+	}
+
+	if len(args) == 2 {
+		in := args[1]
+		if in.Kind() == reflect.Ptr {
+			if in.IsNil() {
+				return e.result(reflect.Value{}, fmt.Errorf("transcode: %s: nil input", e.name))
+			}
+			in = in.Elem()
+		}
+		if err := e.bind(req, in); err != nil {
+			return e.result(reflect.Value{}, fmt.Errorf("transcode: %s: %w", e.name, err))
+		}
+	}
+
+	resp, err := req.Send()
+	var statusErr *gohttp.StatusError
+	if errors.As(err, &statusErr) && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		err = nil
+	}
+	if err != nil && resp != nil && cfg.decodeError != nil {
+		err = cfg.decodeError(resp, err)
+	}
+	if err != nil || e.fn.NumOut() == 1 {
+		return e.result(reflect.Value{}, err)
+	}
+	out := reflect.New(e.fn.Out(0))
+	if len(resp.Body) > 0 && resp.StatusCode != http.StatusNoContent {
+		if err := resp.Decode(out.Interface()); err != nil {
+			return e.result(reflect.Value{}, fmt.Errorf("transcode: %s: decoding response: %w", e.name, err))
+		}
+	}
+	return e.result(out.Elem(), nil)
+}
+
+// bind sets the path parameters, query and body of req from the input in
+func (e *endpoint) bind(req *gohttp.Request, in reflect.Value) error {
+	for name, index := range e.pathFields {
+		value, err := convert.ToString(in.FieldByIndex(index).Interface())
+		if err != nil {
+			return fmt.Errorf("path parameter %s: %w", name, err)
+		}
+		req.PathParam(name, value)
+	}
+	for _, q := range e.query {
+		f := in.FieldByIndex(q.index)
+		if f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Ptr {
+			f = f.Elem()
+		}
+		values := []reflect.Value{f}
+		if f.Kind() == reflect.Slice && f.Type().Elem().Kind() != reflect.Uint8 {
+			values = values[:0]
+			for i := 0; i < f.Len(); i++ {
+				values = append(values, f.Index(i))
+			}
+		}
+		for _, v := range values {
+			s, err := convert.ToString(v.Interface())
+			if err != nil {
+				return fmt.Errorf("query parameter %s: %w", q.name, err)
+			}
+			req.Query(q.name, s)
+		}
+	}
+	switch {
+	case e.bodyAll:
+		req.Body(in.Interface())
+	case e.bodyField != nil:
+		req.Body(in.FieldByIndex(e.bodyField).Interface())
+	}
+	return nil
+}
+
+// result returns the values of the function, out may be invalid for the
+// zero value
+func (e *endpoint) result(out reflect.Value, err error) []reflect.Value {
+	errValue := reflect.New(errorType).Elem()
+	if err != nil {
+		errValue.Set(reflect.ValueOf(err))
+	}
+	if e.fn.NumOut() == 1 {
+		return []reflect.Value{errValue}
+	}
+	if !out.IsValid() {
+		out = reflect.Zero(e.fn.Out(0))
+	}
+	return []reflect.Value{out, errValue}
+}
@@ -0,0 +1,189 @@
+package transcode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+type User struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type GetUser struct {
+	ID int64 `json:"id"`
+}
+
+type ListUsers struct {
+	Limit int      `json:"limit"`
+	Tags  []string `json:"tag"`
+	Name  *string  `json:"name"`
+}
+
+type RenameUser struct {
+	UserID int64  `path:"id" json:"-"`
+	Name   string `json:"name"`
+	DryRun bool   `json:"dry_run"`
+}
+
+type UsersAPI struct {
+	Get    func(ctx context.Context, in GetUser) (*User, error)     `http:"GET /users/{id}"`
+	List   func(ctx context.Context, in *ListUsers) ([]User, error) `http:"GET /users"`
+	Create func(ctx context.Context, in User) (User, error)         `http:"POST /users" body:"*"`
+	Rename func(ctx context.Context, in RenameUser) error           `http:"PATCH /users/{id}" body:"Name"`
+	Ping   func(ctx context.Context) error                          `http:"GET /ping"`
+	Plain  func()
+}
+
+type recorded struct {
+	method string
+	url    string
+	body   string
+	header http.Header
+}
+
+func newServer(t *testing.T) (*httptest.Server, *recorded) {
+	rec := &recorded{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*rec = recorded{method: r.Method, url: r.URL.RequestURI(), body: string(body), header: r.Header}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/users/404":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":"no such user"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/users/7":
+			_, _ = io.WriteString(w, `{"id":7,"name":"ada"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/users":
+			_, _ = io.WriteString(w, `[{"id":1,"name":"a"},{"id":2,"name":"b"}]`)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, rec
+}
+
+func TestBind(t *testing.T) {
+	server, rec := newServer(t)
+	var api UsersAPI
+	if err := Bind(&api, server.URL+"/v1/", WithClient(gohttp.NewClient()), WithHeader(map[string]string{"X-Team": "core"})); err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	if api.Plain != nil {
+		t.Errorf("Bind() filled a field without http tag")
+	}
+	ctx := context.Background()
+
+	user, err := api.Get(ctx, GetUser{ID: 7})
+	if err != nil || !reflect.DeepEqual(user, &User{ID: 7, Name: "ada"}) {
+		t.Errorf("Get() got = %+v %v", user, err)
+	}
+	if rec.url != "/v1/users/7" || rec.header.Get("X-Team") != "core" {
+		t.Errorf("Get() sent %v %v", rec.url, rec.header)
+	}
+
+	name := "b"
+	users, err := api.List(ctx, &ListUsers{Tags: []string{"x", "y"}, Name: &name})
+	if err != nil || len(users) != 2 {
+		t.Errorf("List() got = %+v %v", users, err)
+	}
+	if rec.url != "/v1/users?name=b&tag=x&tag=y" {
+		t.Errorf("List() url got = %v, want the zero limit left out", rec.url)
+	}
+
+	created, err := api.Create(ctx, User{Name: "grace"})
+	if err != nil || created.Name != "grace" {
+		t.Errorf("Create() got = %+v %v, want the 201 body", created, err)
+	}
+	var sent map[string]any
+	if json.Unmarshal([]byte(rec.body), &sent) != nil || sent["name"] != "grace" || rec.method != http.MethodPost {
+		t.Errorf("Create() sent %v %q", rec.method, rec.body)
+	}
+
+	if err := api.Rename(ctx, RenameUser{UserID: 9, Name: "linus", DryRun: true}); err != nil {
+		t.Errorf("Rename() error = %v", err)
+	}
+	if rec.method != http.MethodPatch || rec.url != "/v1/users/9?dry_run=true" || rec.body != `"linus"` {
+		t.Errorf("Rename() sent %v %v %q", rec.method, rec.url, rec.body)
+	}
+
+	if err := api.Ping(ctx); err != nil || rec.url != "/v1/ping" {
+		t.Errorf("Ping() got = %v %v", err, rec.url)
+	}
+
+	_, err = api.Get(ctx, GetUser{ID: 404})
+	var statusErr *gohttp.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusNotFound {
+		t.Errorf("Get() error got = %v, want a *StatusError", err)
+	}
+	if _, err := api.List(ctx, nil); err == nil {
+		t.Errorf("List() got no error for a nil input")
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		api  any
+	}{
+		{"not a pointer", UsersAPI{}},
+		{"bad tag", &struct {
+			F func(context.Context) error `http:"users"`
+		}{}},
+		{"no context", &struct {
+			F func(GetUser) error `http:"GET /users/{id}"`
+		}{}},
+		{"no error", &struct {
+			F func(context.Context) *User `http:"GET /users"`
+		}{}},
+		{"missing placeholder", &struct {
+			F func(context.Context, GetUser) error `http:"GET /users/{userID}"`
+		}{}},
+		{"unknown body", &struct {
+			F func(context.Context, GetUser) error `http:"POST /users" body:"Payload"`
+		}{}},
+		{"input not a struct", &struct {
+			F func(context.Context, int) error `http:"GET /users"`
+		}{}},
+	}
+	for _, tt := range tests {
+		if err := Bind(tt.api, "http://localhost"); !errors.Is(err, ErrSpec) {
+			t.Errorf("Bind() %s got = %v, want ErrSpec", tt.name, err)
+		}
+	}
+}
+
+func TestBindMethodPathTags(t *testing.T) {
+	server, rec := newServer(t)
+	var api struct {
+		Get func(ctx context.Context, in GetUser) (*User, error) `method:"GET" path:"/users/{id}"`
+	}
+	errNotFound := errors.New("no such user")
+	decode := WithErrorDecoder(func(resp *gohttp.Response, err error) error {
+		if resp.StatusCode == http.StatusNotFound {
+			return errNotFound
+		}
+		return err
+	})
+	if err := Bind(&api, server.URL+"/v1", decode); err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	if user, err := api.Get(context.Background(), GetUser{ID: 7}); err != nil || user.Name != "ada" || rec.url != "/v1/users/7" {
+		t.Errorf("Get() got = %+v %v, sent %v", user, err, rec.url)
+	}
+	if _, err := api.Get(context.Background(), GetUser{ID: 404}); err != errNotFound {
+		t.Errorf("Get() error got = %v, want the decoded error", err)
+	}
+}
@@ -0,0 +1,207 @@
+package validate
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+)
+
+// ruleError is raised by built-in rules misused in a tag, e.g. min on a bool
+type ruleError string
+
+func (e ruleError) Error() string {
+	return string(e)
+}
+
+var builtin = map[string]Rule{
+	"required": func(v reflect.Value, _ string) bool { return !isEmpty(v) },
+	"min":      compare(func(a, b float64) bool { return a >= b }),
+	"max":      compare(func(a, b float64) bool { return a <= b }),
+	"len":      compare(func(a, b float64) bool { return a == b }),
+	"gt":       compare(func(a, b float64) bool { return a > b }),
+	"gte":      compare(func(a, b float64) bool { return a >= b }),
+	"lt":       compare(func(a, b float64) bool { return a < b }),
+	"lte":      compare(func(a, b float64) bool { return a <= b }),
+	"eq":       equal(true),
+	"ne":       equal(false),
+	"oneof": func(v reflect.Value, param string) bool {
+		s := text(v)
+		for _, option := range strings.Fields(param) {
+			if s == option {
+				return true
+			}
+		}
+		return false
+	},
+	"email": stringRule(func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s && addr.Name == ""
+	}),
+	"url": stringRule(func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	}),
+	"uuid":     stringRule(uuidPattern.MatchString),
+	"hostname": stringRule(func(s string) bool { return len(s) <= 253 && hostnamePattern.MatchString(s) }),
+	"ip":       stringRule(func(s string) bool { return net.ParseIP(s) != nil }),
+	"ipv4": stringRule(func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	}),
+	"ipv6": stringRule(func(s string) bool { return net.ParseIP(s) != nil && strings.Contains(s, ":") }),
+	"alpha": stringRule(func(s string) bool {
+		return s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+	}),
+	"alphanum": stringRule(func(s string) bool {
+		return s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") == ""
+	}),
+	"numeric": stringRule(func(s string) bool {
+		_, err := strconv.ParseFloat(s, 64)
+		return err == nil
+	}),
+}
+
+func message(rule string, param string) string {
+	switch rule {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return "must be at least " + param
+	case "max", "lte":
+		return "must be at most " + param
+	case "len":
+		return "must have length " + param
+	case "gt":
+		return "must be greater than " + param
+	case "lt":
+		return "must be less than " + param
+	case "eq":
+		return "must equal " + param
+	case "ne":
+		return "must not equal " + param
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "email":
+		return "must be an email address"
+	case "url":
+		return "must be an absolute URL"
+	case "uuid":
+		return "must be a UUID"
+	case "hostname":
+		return "must be a hostname"
+	case "ip":
+		return "must be an IP address"
+	case "ipv4":
+		return "must be an IPv4 address"
+	case "ipv6":
+		return "must be an IPv6 address"
+	case "alpha":
+		return "must contain letters only"
+	case "alphanum":
+		return "must contain letters and digits only"
+	case "numeric":
+		return "must be a number"
+	}
+	if param != "" {
+		return fmt.Sprintf("must satisfy %s=%s", rule, param)
+	}
+	return "must satisfy " + rule
+}
+
+// size is the length of strings in runes, of slices, arrays and maps, or
+// the value of numbers, and param parsed to compare with it
+func size(v reflect.Value, param string) (float64, float64) {
+	var n float64
+	var err error
+	parse := func(s string) float64 {
+		f, e := strconv.ParseFloat(s, 64)
+		err = e
+		return f
+	}
+	var p float64
+	switch v.Kind() {
+	case reflect.String:
+		n, p = float64(utf8.RuneCountInString(v.String())), parse(param)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, p = float64(v.Len()), parse(param)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+		if v.Type() == durationType {
+			d, e := time.ParseDuration(param)
+			p, err = float64(d), e
+		} else {
+			p = parse(param)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, p = float64(v.Uint()), parse(param)
+	case reflect.Float32, reflect.Float64:
+		n, p = v.Float(), parse(param)
+	default:
+		panic(ruleError(fmt.Sprintf("cannot measure %v", v.Type())))
+	}
+	if err != nil {
+		panic(ruleError(fmt.Sprintf("bad parameter %q", param)))
+	}
+	return n, p
+}
+
+func compare(ok func(n float64, param float64) bool) Rule {
+	return func(v reflect.Value, param string) bool {
+		return ok(size(v, param))
+	}
+}
+
+// equal compares strings by value and other kinds by size
+func equal(want bool) Rule {
+	return func(v reflect.Value, param string) bool {
+		if v.Kind() == reflect.String {
+			return (v.String() == param) == want
+		}
+		n, p := size(v, param)
+		return (n == p) == want
+	}
+}
+
+func stringRule(ok func(s string) bool) Rule {
+	return func(v reflect.Value, _ string) bool {
+		if v.Kind() != reflect.String {
+			panic(ruleError(fmt.Sprintf("needs a string, not %v", v.Type())))
+		}
+		return ok(v.String())
+	}
+}
+
+// text formats strings and numbers for oneof
+func text(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+	panic(ruleError(fmt.Sprintf("oneof needs a string or an integer, not %v", v.Type())))
+}
+
+func sortValues(values []reflect.Value) {
+	sort.Slice(values, func(i, j int) bool {
+		return fmt.Sprint(values[i].Interface()) < fmt.Sprint(values[j].Interface())
+	})
+}
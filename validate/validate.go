@@ -0,0 +1,380 @@
+// Package validate checks structs against the rules of their validate tags:
+//
+//	type Signup struct {
+//		Email string   `json:"email" validate:"required,email"`
+//		Name  string   `json:"name" validate:"required,min=1,max=64"`
+//		Plan  string   `json:"plan" validate:"omitempty,oneof=free pro"`
+//		Tags  []string `json:"tags" validate:"max=5,dive,alphanum"`
+//	}
+//
+// Rules are separated by commas, a parameter follows "=". omitempty skips the
+// other rules of an empty field and dive applies the rules after it to the
+// elements of a slice, array or map. Nested structs, and the structs in
+// slices and maps, are validated too unless tagged validate:"-". Fields are
+// named by their json tag, or their Go name, in the errors.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Rule reports whether v passes the rule with param, the text after "=" in
+// the tag. Pointers are dereferenced before rules run.
+type Rule func(v reflect.Value, param string) bool
+
+// FieldError is a field breaking a rule
+type FieldError struct {
+	// Field is the path of the field, e.g. "items[2].name"
+	Field string
+	Rule  string
+	Param string
+	Value any
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + message(e.Rule, e.Param)
+}
+
+// Errors are all the rules a value breaks, in field order
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "validate: " + strings.Join(msgs, "; ")
+}
+
+// Fields maps each failing field to its messages, e.g. for an API response
+func (e Errors) Fields() map[string][]string {
+	out := make(map[string][]string, len(e))
+	for _, err := range e {
+		out[err.Field] = append(out[err.Field], message(err.Rule, err.Param))
+	}
+	return out
+}
+
+// Validator holds the rules, the package functions use a default one with
+// the built-in rules. It is safe for concurrent use.
+type Validator struct {
+	mu     sync.RWMutex
+	rules  map[string]Rule
+	fields sync.Map // reflect.Type to []field
+}
+
+// New returns a Validator with the built-in rules
+func New() *Validator {
+	v := &Validator{rules: make(map[string]Rule, len(builtin))}
+	for name, rule := range builtin {
+		v.rules[name] = rule
+	}
+	return v
+}
+
+var std = New()
+
+// Register adds or replaces the rule name
+func (v *Validator) Register(name string, rule Rule) {
+	v.mu.Lock()
+	v.rules[name] = rule
+	v.mu.Unlock()
+	// parsed tags may refer to the previous rule
+	v.fields.Range(func(k, _ any) bool {
+		v.fields.Delete(k)
+		return true
+	})
+}
+
+// Register adds or replaces a rule of the default Validator
+func Register(name string, rule Rule) {
+	std.Register(name, rule)
+}
+
+// Struct validates s, a struct or a pointer to one, with the default
+// Validator. See Validator.Struct.
+func Struct(s any) error {
+	return std.Struct(s)
+}
+
+// Var validates a single value against tag with the default Validator
+func Var(value any, tag string) error {
+	return std.Var(value, tag)
+}
+
+// Struct returns Errors listing every rule s breaks, nil when it is valid. A
+// misused rule, e.g. an unknown one or a bad parameter, fails with another
+// error.
+func (v *Validator) Struct(s any) error {
+	rv := reflect.ValueOf(s)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: %T is not a struct", s)
+	}
+	var errs Errors
+	if err := v.validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Var validates value against tag, the errors name it "value"
+func (v *Validator) Var(value any, tag string) error {
+	rules, err := v.parse(tag)
+	if err != nil {
+		return err
+	}
+	var errs Errors
+	if err := v.check(reflect.ValueOf(value), rules, "value", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// rule is a parsed rule of a tag, dive separates the rules of the field from
+// those of its elements
+type rule struct {
+	name  string
+	param string
+	fn    Rule
+}
+
+type field struct {
+	index int
+	name  string
+	rules []rule
+	skip  bool
+}
+
+func (v *Validator) parse(tag string) ([]rule, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	var rules []rule
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		r := rule{name: name, param: param}
+		switch name {
+		case "omitempty", "dive":
+		default:
+			fn, ok := v.rules[name]
+			if !ok {
+				return nil, fmt.Errorf("validate: unknown rule %q", name)
+			}
+			r.fn = fn
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (v *Validator) structFields(t reflect.Type) ([]field, error) {
+	if cached, ok := v.fields.Load(t); ok {
+		return cached.([]field), nil
+	}
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("validate")
+		fd := field{index: i, name: fieldName(f), skip: tag == "-"}
+		if !fd.skip {
+			rules, err := v.parse(tag)
+			if err != nil {
+				return nil, fmt.Errorf("%w on %v.%s", err, t, f.Name)
+			}
+			fd.rules = rules
+		}
+		fields = append(fields, fd)
+	}
+	v.fields.Store(t, fields)
+	return fields, nil
+}
+
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+func (v *Validator) validateStruct(rv reflect.Value, path string, errs *Errors) error {
+	fields, err := v.structFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.skip {
+			continue
+		}
+		p := f.name
+		if path != "" {
+			p = path + "." + f.name
+		}
+		if err := v.check(rv.Field(f.index), f.rules, p, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check runs rules on fv and descends into the structs it holds
+func (v *Validator) check(fv reflect.Value, rules []rule, path string, errs *Errors) error {
+	for i, r := range rules {
+		switch r.name {
+		case "omitempty":
+			if isEmpty(fv) {
+				return nil
+			}
+			continue
+		case "dive":
+			return v.dive(indirect(fv), rules[i+1:], path, errs)
+		case "required":
+			if isEmpty(fv) {
+				*errs = append(*errs, &FieldError{Field: path, Rule: r.name, Param: r.param, Value: value(fv)})
+				// the other rules would only repeat the problem
+				return nil
+			}
+			continue
+		}
+		target := indirect(fv)
+		if !target.IsValid() {
+			// a nil pointer is only checked by required
+			return nil
+		}
+		ok, err := run(r, target)
+		if err != nil {
+			return fmt.Errorf("validate: %s: %w", path, err)
+		}
+		if !ok {
+			*errs = append(*errs, &FieldError{Field: path, Rule: r.name, Param: r.param, Value: value(target)})
+		}
+	}
+	return v.descend(indirect(fv), path, errs)
+}
+
+// run calls the rule, turning the panics of misused built-in rules into
+// errors
+func run(r rule, v reflect.Value) (ok bool, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			if e, isRuleErr := p.(ruleError); isRuleErr {
+				err = e
+				return
+			}
+			panic(p)
+		}
+	}()
+	return r.fn(v, r.param), nil
+}
+
+func (v *Validator) dive(fv reflect.Value, rules []rule, path string, errs *Errors) error {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := v.check(fv.Index(i), rules, fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range sortedKeys(fv) {
+			if err := v.check(fv.MapIndex(k), rules, fmt.Sprintf("%s[%v]", path, k), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Invalid:
+	default:
+		return fmt.Errorf("validate: %s: dive needs a slice, array or map, not %v", path, fv.Type())
+	}
+	return nil
+}
+
+// descend validates the structs in fv, directly or as elements
+func (v *Validator) descend(fv reflect.Value, path string, errs *Errors) error {
+	switch fv.Kind() {
+	case reflect.Struct:
+		if fv.Type() == timeType {
+			return nil
+		}
+		return v.validateStruct(fv, path, errs)
+	case reflect.Slice, reflect.Array:
+		if !holdsStructs(fv.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < fv.Len(); i++ {
+			if err := v.descend(indirect(fv.Index(i)), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if !holdsStructs(fv.Type().Elem()) {
+			return nil
+		}
+		for _, k := range sortedKeys(fv) {
+			if err := v.descend(indirect(fv.MapIndex(k)), fmt.Sprintf("%s[%v]", path, k), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func holdsStructs(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		if t.Kind() == reflect.Interface {
+			return true
+		}
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isEmpty(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func value(v reflect.Value) any {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sortValues(keys)
+	return keys
+}
@@ -0,0 +1,170 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"omitempty,len=5,numeric"`
+}
+
+type signup struct {
+	Email    string             `json:"email" validate:"required,email"`
+	Name     string             `json:"name" validate:"required,min=2,max=8"`
+	Plan     string             `json:"plan" validate:"omitempty,oneof=free pro"`
+	Age      int                `validate:"gte=18,lt=130"`
+	Tags     []string           `json:"tags" validate:"max=2,dive,alphanum"`
+	Home     address            `json:"home"`
+	Others   []*address         `json:"others"`
+	ByName   map[string]address `json:"by_name"`
+	Timeout  time.Duration      `validate:"omitempty,min=1s"`
+	Nickname *string            `validate:"min=3"`
+	Ignored  address            `validate:"-"`
+	Created  time.Time
+}
+
+func valid() signup {
+	return signup{Email: "ann@example.com", Name: "ann", Age: 30, Home: address{City: "Oslo"}}
+}
+
+func TestStruct(t *testing.T) {
+	s := valid()
+	if err := Struct(&s); err != nil {
+		t.Errorf("Struct() got = %v, want nil", err)
+	}
+
+	short := "x"
+	tests := []struct {
+		name   string
+		modify func(s *signup)
+		field  string
+		rule   string
+	}{
+		{"required", func(s *signup) { s.Email = "" }, "email", "required"},
+		{"email", func(s *signup) { s.Email = "Ann <ann@example.com>" }, "email", "email"},
+		{"min runes", func(s *signup) { s.Name = "é" }, "name", "min"},
+		{"max", func(s *signup) { s.Name = "abcdefghi" }, "name", "max"},
+		{"oneof", func(s *signup) { s.Plan = "gold" }, "plan", "oneof"},
+		{"gte", func(s *signup) { s.Age = 17 }, "Age", "gte"},
+		{"slice length", func(s *signup) { s.Tags = []string{"a", "b", "c"} }, "tags", "max"},
+		{"dive", func(s *signup) { s.Tags = []string{"ok", "not ok"} }, "tags[1]", "alphanum"},
+		{"nested", func(s *signup) { s.Home.City = "" }, "home.city", "required"},
+		{"nested omitempty", func(s *signup) { s.Home.Zip = "12a45" }, "home.zip", "numeric"},
+		{"slice of structs", func(s *signup) { s.Others = []*address{{City: "a"}, nil, {}} }, "others[2].city", "required"},
+		{"map of structs", func(s *signup) { s.ByName = map[string]address{"x": {}} }, "by_name[x].city", "required"},
+		{"duration", func(s *signup) { s.Timeout = time.Millisecond }, "Timeout", "min"},
+		{"pointer", func(s *signup) { s.Nickname = &short }, "Nickname", "min"},
+	}
+	for _, tt := range tests {
+		s := valid()
+		tt.modify(&s)
+		var errs Errors
+		if err := Struct(s); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != tt.field || errs[0].Rule != tt.rule {
+			t.Errorf("Struct(%v) got = %v, want %v failing %v", tt.name, err, tt.field, tt.rule)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := signup{Name: "a", Age: 10}
+	err := Struct(s)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 4 {
+		t.Fatalf("Struct() got = %v, want 4 errors", err)
+	}
+	want := "validate: email is required; name must be at least 2; Age must be at least 18; home.city is required"
+	if err.Error() != want {
+		t.Errorf("Error() got = %q, want %q", err.Error(), want)
+	}
+	if fields := errs.Fields(); !reflect.DeepEqual(fields["name"], []string{"must be at least 2"}) || len(fields) != 4 {
+		t.Errorf("Fields() got = %v", fields)
+	}
+	if errs[2].Value != 10 || errs[2].Param != "18" {
+		t.Errorf("FieldError got = %+v", errs[2])
+	}
+}
+
+func TestMisuse(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"unknown rule", struct {
+			A string `validate:"nope"`
+		}{}, "unknown rule"},
+		{"bad param", struct {
+			A string `validate:"min=x"`
+		}{"a"}, "bad parameter"},
+		{"wrong kind", struct {
+			A bool `validate:"email"`
+		}{}, "needs a string"},
+		{"not a struct", 3, "not a struct"},
+	}
+	for _, tt := range tests {
+		err := Struct(tt.v)
+		var errs Errors
+		if err == nil || errors.As(err, &errs) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Struct(%v) got = %v, want an error with %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestVarAndRules(t *testing.T) {
+	tests := []struct {
+		v    any
+		tag  string
+		want bool
+	}{
+		{"https://example.com/x", "url", true},
+		{"/relative", "url", false},
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "uuid", true},
+		{"6ba7b810", "uuid", false},
+		{"10.0.0.1", "ipv4", true},
+		{"::1", "ipv4", false},
+		{"::1", "ipv6", true},
+		{"api.example.com", "hostname", true},
+		{"-bad-.com", "hostname", false},
+		{"abc", "alpha", true},
+		{"ab1", "alpha", false},
+		{3, "oneof=1 2 3", true},
+		{"x", "ne=x", false},
+		{[]int{1, 2}, "len=2", true},
+		{1.5, "gt=1,lt=2", true},
+		{"", "omitempty,email", true},
+		{[]string{"a", ""}, "dive,required", false},
+	}
+	for _, tt := range tests {
+		if err := Var(tt.v, tt.tag); (err == nil) != tt.want {
+			t.Errorf("Var(%v, %q) got = %v, want valid %v", tt.v, tt.tag, err, tt.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	v := New()
+	type order struct {
+		SKU string `validate:"sku"`
+	}
+	if err := v.Struct(order{"AB-1"}); err == nil {
+		t.Errorf("Struct() unregistered rule got = nil, want an error")
+	}
+	v.Register("sku", func(fv reflect.Value, _ string) bool {
+		return strings.HasPrefix(fv.String(), "AB-")
+	})
+	if err := v.Struct(order{"AB-1"}); err != nil {
+		t.Errorf("Struct() got = %v", err)
+	}
+	err := v.Struct(order{"X"})
+	if err == nil || err.Error() != "validate: SKU must satisfy sku" {
+		t.Errorf("Struct() custom rule got = %v", err)
+	}
+	if err := Var("AB-1", "sku"); err == nil {
+		t.Errorf("Var() rule of another Validator got = nil, want an error")
+	}
+}
@@ -0,0 +1,248 @@
+package vcr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// ErrNoInteraction is returned in replay mode for requests the cassette does not hold
+var ErrNoInteraction = errors.New("vcr: no recorded interaction matches the request")
+
+const filtered = "[FILTERED]"
+
+type Mode int
+
+const (
+	// ModeAuto replays when the cassette file exists and records otherwise
+	ModeAuto Mode = iota
+	// ModeRecord always sends real requests and overwrites the cassette on Stop
+	ModeRecord
+	// ModeReplay never touches the network
+	ModeReplay
+)
+
+// Matcher decides whether a recorded request answers req
+type Matcher func(req *http.Request, body []byte, recorded Request) bool
+
+// DefaultMatcher compares method and url
+func DefaultMatcher(req *http.Request, body []byte, recorded Request) bool {
+	return req.Method == recorded.Method && req.URL.String() == recorded.URL
+}
+
+// StrictMatcher compares method, url and body
+func StrictMatcher(req *http.Request, body []byte, recorded Request) bool {
+	return DefaultMatcher(req, body, recorded) && bytes.Equal(body, recorded.Body.bytes())
+}
+
+type Option func(*Recorder)
+
+func WithMode(mode Mode) Option {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithTransport sets the transport real requests go through, http.DefaultTransport by default
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// WithFilterHeaders replaces the values of the named request and response headers in the cassette
+func WithFilterHeaders(names ...string) Option {
+	return func(r *Recorder) {
+		for _, name := range names {
+			r.filterHeaders[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// WithMatcher replaces DefaultMatcher
+func WithMatcher(matcher Matcher) Option {
+	return func(r *Recorder) {
+		r.matcher = matcher
+	}
+}
+
+// Sequential requires requests to be replayed in the recorded order, each interaction once
+func Sequential() Option {
+	return func(r *Recorder) {
+		r.sequential = true
+	}
+}
+
+// Recorder is a http.RoundTripper that records to or replays from a cassette file,
+// install it with http.WithTransport and call Stop when done.
+type Recorder struct {
+	path          string
+	mode          Mode
+	transport     http.RoundTripper
+	filterHeaders map[string]bool
+	matcher       Matcher
+	sequential    bool
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+	next     int
+}
+
+// New opens the cassette at path, in ModeAuto the file decides between replay and record
+func New(path string, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:          path,
+		transport:     http.DefaultTransport,
+		filterHeaders: map[string]bool{"Authorization": true, "Proxy-Authorization": true, "Cookie": true, "Set-Cookie": true},
+		matcher:       DefaultMatcher,
+		cassette:      &Cassette{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+	if r.mode == ModeReplay {
+		cassette, err := loadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = cassette
+		r.used = make([]bool, len(cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Mode returns the effective mode
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == ModeReplay {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	interactions := r.cassette.Interactions
+	index := -1
+	if r.sequential {
+		if r.next < len(interactions) && r.matcher(req, body, interactions[r.next].Request) {
+			index = r.next
+			r.next++
+		}
+	} else {
+		for i, interaction := range interactions {
+			if r.matcher(req, body, interaction.Request) {
+				if !r.used[i] {
+					index = i
+					break
+				}
+				// repeated requests fall back to the last matching interaction
+				index = i
+			}
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL)
+	}
+	r.used[index] = true
+	recorded := interactions[index].Response
+	responseBody := recorded.Body.bytes()
+	return &http.Response{
+		Status:        strconv.Itoa(recorded.Status) + " " + http.StatusText(recorded.Status),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(recorded.Header).Clone(),
+		Body:          io.NopCloser(bytes.NewReader(responseBody)),
+		ContentLength: int64(len(responseBody)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	interaction := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: r.filter(req.Header),
+			Body:   newBody(body),
+		},
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: r.filter(resp.Header),
+			Body:   newBody(responseBody),
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Stop writes the cassette when recording
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.save(r.path)
+}
+
+func (r *Recorder) filter(header http.Header) map[string][]string {
+	out := make(map[string][]string, len(header))
+	for k, v := range header {
+		if r.filterHeaders[http.CanonicalHeaderKey(k)] {
+			out[k] = []string{filtered}
+			continue
+		}
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+// readBody reads the request body and puts a fresh reader back
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,104 @@
+package vcr
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path))
+	}))
+	url := server.URL
+
+	for _, name := range []string{"cassette.json", "cassette.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			recorder, err := New(path)
+			if err != nil || recorder.Mode() != ModeRecord {
+				t.Fatalf("New() mode = %v, error = %v, want ModeRecord", recorder.Mode(), err)
+			}
+			client := gohttp.NewClient(gohttp.WithTransport(recorder))
+			header := map[string]string{"Authorization": "Bearer secret"}
+			if _, _, _, err := client.Get(url+"/a", header, nil); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if _, _, _, err := client.Post(url+"/b", header, nil, map[string]int{"n": 1}); err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+			if err := recorder.Stop(); err != nil {
+				t.Fatalf("Stop() error = %v", err)
+			}
+			data, _ := os.ReadFile(path)
+			if strings.Contains(string(data), "secret") || !strings.Contains(string(data), filtered) {
+				t.Errorf("cassette leaks secrets:\n%s", data)
+			}
+
+			replayer, err := New(path, Sequential())
+			if err != nil || replayer.Mode() != ModeReplay {
+				t.Fatalf("New() mode = %v, error = %v, want ModeReplay", replayer.Mode(), err)
+			}
+			before := calls
+			client = gohttp.NewClient(gohttp.WithTransport(replayer))
+			_, _, data1, err := client.Get(url+"/a", nil, nil)
+			if err != nil || string(data1.([]byte)) != "GET /a" {
+				t.Errorf("replayed Get() got = %v, error = %v", data1, err)
+			}
+			_, _, data2, err := client.Post(url+"/b", nil, nil, map[string]int{"n": 1})
+			if err != nil || string(data2.([]byte)) != "POST /b" {
+				t.Errorf("replayed Post() got = %v, error = %v", data2, err)
+			}
+			if _, _, _, err := client.Get(url+"/a", nil, nil); !errors.Is(err, ErrNoInteraction) {
+				t.Errorf("sequential replay past the end error = %v, want ErrNoInteraction", err)
+			}
+			if calls != before {
+				t.Errorf("replay reached the server %d times", calls-before)
+			}
+		})
+	}
+	server.Close()
+}
+
+func TestReplayMatching(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := &Cassette{Interactions: []Interaction{
+		{Request: Request{Method: "POST", URL: "http://example.com/x", Body: Body{Text: `{"n":1}`}}, Response: Response{Status: 200, Body: Body{Text: "one"}}},
+		{Request: Request{Method: "POST", URL: "http://example.com/x", Body: Body{Text: `{"n":2}`}}, Response: Response{Status: 200, Body: Body{Text: "two"}}},
+	}}
+	if err := cassette.save(path); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		body int
+		want string
+	}{
+		{name: "default matcher takes the first unused", body: 2, want: "one"},
+		{name: "strict matcher compares bodies", opts: []Option{WithMatcher(StrictMatcher)}, body: 2, want: "two"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder, err := New(path, append(tt.opts, WithMode(ModeReplay))...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			client := gohttp.NewClient(gohttp.WithTransport(recorder))
+			_, _, data, err := client.Post("http://example.com/x", nil, nil, map[string]int{"n": tt.body})
+			if err != nil || string(data.([]byte)) != tt.want {
+				t.Errorf("Post() got = %s, error = %v, want %s", data, err, tt.want)
+			}
+		})
+	}
+}
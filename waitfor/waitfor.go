@@ -0,0 +1,199 @@
+// Package waitfor blocks startup until the services a program depends on are
+// ready, like wait-for-it scripts do for containers.
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// Dependency is checked until Check succeeds or Timeout passes
+type Dependency struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Timeout overrides the timeout of Wait for this dependency
+	Timeout time.Duration
+}
+
+// WithTimeout returns d with its own timeout
+func (d Dependency) WithTimeout(timeout time.Duration) Dependency {
+	d.Timeout = timeout
+	return d
+}
+
+// TCP is ready when addr accepts connections
+func TCP(addr string) Dependency {
+	return Dependency{Name: "tcp " + addr, Check: func(ctx context.Context) error {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}}
+}
+
+// HTTP is ready when a GET of url returns 200. A nil client uses one that
+// does not log the failed attempts.
+func HTTP(url string, client *gohttp.Client) Dependency {
+	if client == nil {
+		client = gohttp.NewClient(gohttp.WithLogger(gohttp.NopLogger))
+	}
+	return Dependency{Name: "http " + url, Check: func(ctx context.Context) error {
+		_, err := client.NewRequest(gohttp.GET, url).WithContext(ctx).Send()
+		return err
+	}}
+}
+
+// Pinger is implemented by *sql.DB and most database clients
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Ping is ready when p answers a ping
+func Ping(name string, p Pinger) Dependency {
+	return Dependency{Name: name, Check: p.PingContext}
+}
+
+// DNS is ready when host resolves, e.g. a service registered late
+func DNS(host string) Dependency {
+	return Dependency{Name: "dns " + host, Check: func(ctx context.Context) error {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err == nil && len(addrs) == 0 {
+			err = fmt.Errorf("%s has no addresses", host)
+		}
+		return err
+	}}
+}
+
+// Func is ready when check returns nil
+func Func(name string, check func(ctx context.Context) error) Dependency {
+	return Dependency{Name: name, Check: check}
+}
+
+// Failure is a dependency that was not ready in time with its last error
+type Failure struct {
+	Name string
+	Err  error
+}
+
+// Error lists the dependencies that were not ready
+type Error struct {
+	Failures []Failure
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		parts[i] = f.Name + ": " + f.Err.Error()
+	}
+	return fmt.Sprintf("waitfor: %d of the dependencies not ready: %s", len(e.Failures), strings.Join(parts, "; "))
+}
+
+type config struct {
+	timeout     time.Duration
+	interval    time.Duration
+	maxInterval time.Duration
+	logger      gohttp.Logger
+}
+
+type Option = opt.Option[config]
+
+// WithTimeout is how long each dependency is waited for, 60s by default
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithInterval is the pause after the first failed check, it doubles up to
+// maxInterval. 500ms and 5s by default.
+func WithInterval(interval time.Duration, maxInterval time.Duration) Option {
+	return func(c *config) {
+		c.interval = interval
+		c.maxInterval = maxInterval
+	}
+}
+
+// WithLogger logs every failed check and every dependency that became ready
+func WithLogger(logger gohttp.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.timeout <= 0 || c.interval <= 0 || c.maxInterval < c.interval {
+			return errors.New("waitfor: timeout and interval must be positive, the interval at most maxInterval")
+		}
+		return nil
+	},
+}
+
+// Wait checks the dependencies in parallel until all are ready. It returns an
+// *Error naming those that were not ready within their timeout, or ctx.Err()
+// when ctx is done first.
+func Wait(ctx context.Context, deps []Dependency, opts ...Option) error {
+	cfg := config{timeout: time.Minute, interval: 500 * time.Millisecond, maxInterval: 5 * time.Second, logger: gohttp.NopLogger}
+	if err := opt.Build(&cfg, opts, checks...); err != nil {
+		return err
+	}
+	errs := make([]error, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func(i int, dep Dependency) {
+			defer wg.Done()
+			errs[i] = wait(ctx, dep, cfg)
+		}(i, dep)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var failures []Failure
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, Failure{Name: deps[i].Name, Err: err})
+		}
+	}
+	if failures != nil {
+		return &Error{Failures: failures}
+	}
+	return nil
+}
+
+func wait(ctx context.Context, dep Dependency, cfg config) error {
+	timeout := cfg.timeout
+	if dep.Timeout > 0 {
+		timeout = dep.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	interval := cfg.interval
+	for attempt := 1; ; attempt++ {
+		err := dep.Check(ctx)
+		if err == nil {
+			cfg.logger.Info("dependency ready", "dependency", dep.Name, "attempts", attempt, "waited", time.Since(start))
+			return nil
+		}
+		cfg.logger.Debug("dependency not ready", "dependency", dep.Name, "attempt", attempt, "err", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not ready after %v: %w", timeout, err)
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > cfg.maxInterval {
+			interval = cfg.maxInterval
+		}
+	}
+}
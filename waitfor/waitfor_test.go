@@ -0,0 +1,87 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type pinger struct {
+	calls int32
+	ready int32
+}
+
+func (p *pinger) PingContext(ctx context.Context) error {
+	if atomic.AddInt32(&p.calls, 1) < p.ready {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestWait(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	var healthy int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	time.AfterFunc(50*time.Millisecond, func() { atomic.StoreInt32(&healthy, 1) })
+
+	db := &pinger{ready: 3}
+	deps := []Dependency{TCP(listener.Addr().String()), HTTP(server.URL, nil), Ping("db", db), DNS("localhost")}
+	if err := Wait(context.Background(), deps, WithTimeout(2*time.Second), WithInterval(10*time.Millisecond, 20*time.Millisecond)); err != nil {
+		t.Errorf("Wait() error = %v", err)
+	}
+	if db.calls != 3 {
+		t.Errorf("Wait() pings got = %v, want 3", db.calls)
+	}
+}
+
+func TestWaitFailures(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr().String()
+	listener.Close()
+
+	deps := []Dependency{
+		TCP(addr).WithTimeout(50 * time.Millisecond),
+		Func("ok", func(ctx context.Context) error { return nil }),
+		Func("queue", func(ctx context.Context) error { return errors.New("not leader") }),
+	}
+	start := time.Now()
+	err := Wait(context.Background(), deps, WithTimeout(100*time.Millisecond), WithInterval(10*time.Millisecond, 10*time.Millisecond))
+	var waitErr *Error
+	if !errors.As(err, &waitErr) {
+		t.Fatalf("Wait() error got = %v, want *Error", err)
+	}
+	if len(waitErr.Failures) != 2 || waitErr.Failures[0].Name != "tcp "+addr || waitErr.Failures[1].Name != "queue" {
+		t.Errorf("Wait() failures got = %+v", waitErr.Failures)
+	}
+	if !strings.Contains(err.Error(), "queue: not ready after 100ms: not leader") {
+		t.Errorf("Wait() error got = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait() took %v, want the dependencies checked in parallel", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Wait(ctx, deps[2:]); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() canceled error got = %v, want %v", err, context.Canceled)
+	}
+	if err := Wait(context.Background(), deps, WithTimeout(0)); err == nil {
+		t.Errorf("Wait() with zero timeout error = nil")
+	}
+}
@@ -0,0 +1,272 @@
+// Package watchdog keeps long running agents honest: Run bounds a function by
+// wall-clock time whether or not it honours its context, and a Watchdog
+// notices goroutines that stopped sending heartbeats, reports them with their
+// stack and can restart them (Supervise).
+package watchdog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	gohttp "github.com/Stellar1999/gotool/http"
+	"github.com/Stellar1999/gotool/opt"
+)
+
+// ErrTimeout is matched by the *TimeoutError of Run
+var ErrTimeout = errors.New("watchdog: time limit exceeded")
+
+// TimeoutError is returned by Run when fn is still running at the limit
+type TimeoutError struct {
+	Limit time.Duration
+	// Stack is the stack of the goroutine running fn at the limit
+	Stack string
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("watchdog: time limit of %v exceeded", e.Limit)
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// Run runs fn and returns its error, or a *TimeoutError once limit passes.
+// The ctx of fn is canceled at the limit but Run does not wait for fn to
+// notice: a fn that ignores its ctx keeps running in the background, so only
+// use Run for work that is safe to abandon.
+func Run(ctx context.Context, limit time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timer := time.NewTimer(limit)
+	defer timer.Stop()
+	done := make(chan error, 1)
+	started := make(chan int64, 1)
+	go func() {
+		started <- goroutineID()
+		done <- fn(ctx)
+	}()
+	id := <-started
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return &TimeoutError{Limit: limit, Stack: goroutineStack(id)}
+	}
+}
+
+// Stall reports a heartbeat that is overdue
+type Stall struct {
+	Name     string
+	LastBeat time.Time
+	Timeout  time.Duration
+	// Stack is the stack of the goroutine that registered the heartbeat,
+	// empty when it has exited
+	Stack string
+}
+
+type config struct {
+	interval time.Duration
+	alert    func(Stall)
+	logger   gohttp.Logger
+}
+
+type Option = opt.Option[config]
+
+// WithInterval is how often Watch checks the heartbeats, 1s by default
+func WithInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.interval = d
+	}
+}
+
+// WithAlert calls alert once for every stall, e.g. to page someone or to
+// exit the process so a supervisor restarts it
+func WithAlert(alert func(Stall)) Option {
+	return func(c *config) {
+		c.alert = alert
+	}
+}
+
+// WithLogger logs stalls with their stack and the restarts of Supervise
+func WithLogger(logger gohttp.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+var checks = []opt.Check[config]{
+	func(c *config) error {
+		if c.interval <= 0 {
+			return errors.New("watchdog: interval must be positive")
+		}
+		return nil
+	},
+}
+
+// Watchdog tracks heartbeats, it is safe for concurrent use
+type Watchdog struct {
+	cfg   config
+	mu    sync.Mutex
+	beats map[*Heartbeat]struct{}
+}
+
+// New creates a Watchdog, call Watch to start checking
+func New(opts ...Option) (*Watchdog, error) {
+	cfg := config{interval: time.Second, alert: func(Stall) {}, logger: gohttp.NopLogger}
+	if err := opt.Build(&cfg, opts, checks...); err != nil {
+		return nil, err
+	}
+	return &Watchdog{cfg: cfg, beats: make(map[*Heartbeat]struct{})}, nil
+}
+
+// Heartbeat is registered by a goroutine that promises to Beat at least
+// every timeout
+type Heartbeat struct {
+	name      string
+	timeout   time.Duration
+	w         *Watchdog
+	goroutine int64
+	last      time.Time
+	stalled   bool
+	once      sync.Once
+	stalledCh chan struct{}
+}
+
+// Register starts watching a heartbeat of the calling goroutine, it counts
+// as beaten now. Stop it when the work is done.
+func (w *Watchdog) Register(name string, timeout time.Duration) *Heartbeat {
+	h := &Heartbeat{name: name, timeout: timeout, w: w, goroutine: goroutineID(), last: time.Now(), stalledCh: make(chan struct{})}
+	w.mu.Lock()
+	w.beats[h] = struct{}{}
+	w.mu.Unlock()
+	return h
+}
+
+// Beat tells the watchdog the goroutine makes progress
+func (h *Heartbeat) Beat() {
+	h.w.mu.Lock()
+	h.last = time.Now()
+	h.stalled = false
+	h.w.mu.Unlock()
+}
+
+// Stop stops watching the heartbeat
+func (h *Heartbeat) Stop() {
+	h.w.mu.Lock()
+	delete(h.w.beats, h)
+	h.w.mu.Unlock()
+}
+
+// Stalled is closed at the first stall of the heartbeat
+func (h *Heartbeat) Stalled() <-chan struct{} {
+	return h.stalledCh
+}
+
+// Check reports the heartbeats that became overdue since the last Check,
+// alerting for each. Watch calls it every interval.
+func (w *Watchdog) Check() []Stall {
+	now := time.Now()
+	var overdue []*Heartbeat
+	var stalls []Stall
+	w.mu.Lock()
+	for h := range w.beats {
+		if h.stalled || now.Sub(h.last) <= h.timeout {
+			continue
+		}
+		h.stalled = true
+		overdue = append(overdue, h)
+		stalls = append(stalls, Stall{Name: h.name, LastBeat: h.last, Timeout: h.timeout})
+	}
+	w.mu.Unlock()
+	for i, h := range overdue {
+		stalls[i].Stack = goroutineStack(h.goroutine)
+		w.cfg.logger.Error("heartbeat overdue", "name", h.name, "last_beat", h.last, "timeout", h.timeout, "stack", stalls[i].Stack)
+		w.cfg.alert(stalls[i])
+		h.once.Do(func() { close(h.stalledCh) })
+	}
+	return stalls
+}
+
+// Watch checks the heartbeats every interval until ctx is done
+func (w *Watchdog) Watch(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
+
+// Supervise runs fn and starts it again when its heartbeat stalls. The ctx of
+// a stalled fn is canceled and the fn abandoned, like with Run. Supervise
+// returns the result of the first fn that returns, or ctx.Err(). The stalls
+// are only noticed while Watch runs.
+func (w *Watchdog) Supervise(ctx context.Context, name string, timeout time.Duration, fn func(ctx context.Context, h *Heartbeat) error) error {
+	for restarts := 0; ; restarts++ {
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		registered := make(chan *Heartbeat, 1)
+		go func() {
+			h := w.Register(name, timeout)
+			registered <- h
+			done <- fn(runCtx, h)
+		}()
+		h := <-registered
+		select {
+		case err := <-done:
+			h.Stop()
+			cancel()
+			return err
+		case <-ctx.Done():
+			h.Stop()
+			cancel()
+			return ctx.Err()
+		case <-h.Stalled():
+			h.Stop()
+			cancel()
+			w.cfg.logger.Warn("restarting stalled work", "name", name, "restarts", restarts+1)
+		}
+	}
+}
+
+// goroutineID parses the id of the calling goroutine from its stack header
+func goroutineID() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseInt(string(buf), 10, 64)
+	return id
+}
+
+// goroutineStack returns the stack of the goroutine id, empty when it exited
+func goroutineStack(id int64) string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	prefix := []byte("goroutine " + strconv.FormatInt(id, 10) + " ")
+	for _, block := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(block, prefix) {
+			return string(block)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func blockForever(release chan struct{}) {
+	<-release
+}
+
+func TestRun(t *testing.T) {
+	if err := Run(context.Background(), time.Second, func(ctx context.Context) error { return errors.New("failed") }); err == nil || err.Error() != "failed" {
+		t.Errorf("Run() error got = %v, want failed", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	start := time.Now()
+	err := Run(context.Background(), 20*time.Millisecond, func(ctx context.Context) error {
+		// ignores ctx on purpose
+		blockForever(release)
+		return nil
+	})
+	var timeoutErr *TimeoutError
+	if !errors.Is(err, ErrTimeout) || !errors.As(err, &timeoutErr) || time.Since(start) > time.Second {
+		t.Fatalf("Run() error got = %v, want %v", err, ErrTimeout)
+	}
+	if !strings.Contains(timeoutErr.Stack, "blockForever") {
+		t.Errorf("Stack got = %v, want blockForever", timeoutErr.Stack)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var mu sync.Mutex
+	var alerts []Stall
+	w, err := New(WithAlert(func(s Stall) {
+		mu.Lock()
+		defer mu.Unlock()
+		alerts = append(alerts, s)
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	release := make(chan struct{})
+	registered := make(chan *Heartbeat)
+	go func() {
+		registered <- w.Register("worker", 10*time.Millisecond)
+		blockForever(release)
+	}()
+	h := <-registered
+	defer close(release)
+	healthy := w.Register("healthy", time.Hour)
+	defer healthy.Stop()
+
+	if stalls := w.Check(); stalls != nil {
+		t.Errorf("Check() got = %v, want no stalls", stalls)
+	}
+	time.Sleep(20 * time.Millisecond)
+	stalls := w.Check()
+	if len(stalls) != 1 || stalls[0].Name != "worker" || !strings.Contains(stalls[0].Stack, "blockForever") {
+		t.Fatalf("Check() got = %+v, want worker with its stack", stalls)
+	}
+	if stalls := w.Check(); stalls != nil {
+		t.Errorf("Check() again got = %v, want one alert per stall", stalls)
+	}
+	select {
+	case <-h.Stalled():
+	default:
+		t.Errorf("Stalled() not closed")
+	}
+
+	h.Beat()
+	if stalls := w.Check(); stalls != nil {
+		t.Errorf("Check() after Beat got = %v", stalls)
+	}
+	h.Stop()
+	time.Sleep(20 * time.Millisecond)
+	if stalls := w.Check(); stalls != nil || len(alerts) != 1 {
+		t.Errorf("Check() after Stop got = %v, alerts %v", stalls, len(alerts))
+	}
+}
+
+func TestSupervise(t *testing.T) {
+	w, _ := New(WithInterval(5 * time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Watch(ctx)
+
+	var runs int32
+	err := w.Supervise(ctx, "job", 20*time.Millisecond, func(ctx context.Context, h *Heartbeat) error {
+		if atomic.AddInt32(&runs, 1) < 3 {
+			// stalls until the watchdog cancels it
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		for i := 0; i < 5; i++ {
+			time.Sleep(5 * time.Millisecond)
+			h.Beat()
+		}
+		return nil
+	})
+	if err != nil || atomic.LoadInt32(&runs) != 3 {
+		t.Errorf("Supervise() got = %v after %v runs, want nil after 3", err, runs)
+	}
+
+	cancel()
+	if err := w.Supervise(ctx, "canceled", time.Hour, func(ctx context.Context, h *Heartbeat) error {
+		<-ctx.Done()
+		return nil
+	}); err != nil && !errors.Is(err, context.Canceled) {
+		t.Errorf("Supervise() canceled got = %v", err)
+	}
+}